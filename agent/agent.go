@@ -2,25 +2,38 @@ package nexagent
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
-	"path"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/cloudevents/sdk-go/pkg/cloudevents"
+	"github.com/google/uuid"
 	"github.com/nats-io/nats.go"
 	"github.com/synadia-io/nex/agent/providers"
+	controlapi "github.com/synadia-io/nex/control-api"
 	agentapi "github.com/synadia-io/nex/internal/agent-api"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const defaultAgentHandshakeTimeoutMillis = 500
@@ -35,42 +48,82 @@ type Agent struct {
 	agentLogs chan *agentapi.LogEntry
 	eventLogs chan *cloudevents.Event
 
-	cancelF context.CancelFunc
-	closing uint32
-	ctx     context.Context
-	sigs    chan os.Signal
+	dispatchCancel context.CancelFunc
+	dispatchDone   chan struct{} // closed once the log and event dispatchers have exited
+	droppedEvents  uint64
+	droppedLogs    uint64
 
-	provider providers.ExecutionProvider
+	cancelF   context.CancelFunc
+	closing   uint32
+	replacing uint32 // set once the agent is being replaced by an updated binary
+	ctx       context.Context
+	sigs      chan os.Signal
+
+	// Workloads deployed to the agent, keyed by workload id
+	workloads      map[string]*deployedWorkload
+	workloadsMutex sync.Mutex
+
+	// Artifact staged by a prepare request, consumed by a deployment of the same artifact
+	prepared *preparedArtifact
 
 	cacheBucket nats.ObjectStore
+	js          nats.JetStreamContext
 	md          *agentapi.MachineMetadata
 	nc          *nats.Conn
 	started     time.Time
 
+	// Time at which the deployed function was last executed (unix ns); zero until it first is
+	lastExec atomic.Int64
+
 	sandboxed bool
+
+	// Forwards recorded spans to the node; nil unless the node exports traces
+	tracerProvider *tracesdk.TracerProvider
 }
 
 // Initialize a new agent to facilitate communications with the host
 func NewAgent(ctx context.Context, cancelF context.CancelFunc) (*Agent, error) {
-	var metadata *agentapi.MachineMetadata
-	var err error
+	if isSandboxed() {
+		// the network must be configured before metadata can be retrieved from MMDS
+		err := configureIPv6Network(readBootArgs())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to configure IPv6 network: %s\n", err)
+			return nil, err
+		}
+	}
 
-	if !isSandboxed() {
-		metadata, err = GetMachineMetadataFromEnv()
-	} else {
-		metadata, err = GetMachineMetadata()
+	source, err := ResolveMetadataSource()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to resolve metadata source: %s\n", err)
+		return nil, err
 	}
+
+	metadata, err := source.GetMachineMetadata()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to get machien metadata: %s\n", err)
-		return nil, fmt.Errorf("failed to get machine metadata: %s", err)
+		fmt.Fprintf(os.Stderr, "failed to get machine metadata from %s: %s\n", source.Name(), err)
+		return nil, fmt.Errorf("failed to get machine metadata from %s: %s", source.Name(), err)
 	}
 
 	if !metadata.Validate() {
-		fmt.Fprintf(os.Stderr, "invalid metadata: %v\n", metadata.Errors)
-		return nil, fmt.Errorf("invalid metadata: %v", metadata.Errors)
+		fmt.Fprintf(os.Stderr, "invalid metadata from %s: %v\n", source.Name(), errors.Join(metadata.Errors...))
+		return nil, fmt.Errorf("invalid metadata from %s: %v", source.Name(), errors.Join(metadata.Errors...))
+	}
+
+	if len(metadata.EntropySeed) > 0 && isSandboxed() {
+		err = seedEntropy(metadata.EntropySeed)
+		if err != nil {
+			// a cold entropy pool only slows workloads down, so this is not fatal
+			fmt.Fprintf(os.Stderr, "failed to seed entropy pool: %s\n", err)
+		}
 	}
 
-	nc, err := nats.Connect(fmt.Sprintf("nats://%s:%d", *metadata.NodeNatsHost, *metadata.NodeNatsPort))
+	// the agent's credentials only permit it to subscribe to inboxes under its own prefix
+	natsOpts := []nats.Option{nats.CustomInboxPrefix(agentapi.AgentInboxPrefix(*metadata.VmID))}
+	if metadata.NodeNatsToken != nil {
+		natsOpts = append(natsOpts, nats.Token(*metadata.NodeNatsToken))
+	}
+
+	nc, err := nats.Connect(fmt.Sprintf("nats://%s", net.JoinHostPort(*metadata.NodeNatsHost, strconv.Itoa(*metadata.NodeNatsPort))), natsOpts...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to connect to shared NATS: %s", err)
 		return nil, err
@@ -91,14 +144,18 @@ func NewAgent(ctx context.Context, cancelF context.CancelFunc) (*Agent, error) {
 	return &Agent{
 		agentLogs: make(chan *agentapi.LogEntry, 64),
 		eventLogs: make(chan *cloudevents.Event, 64),
+
+		dispatchDone: make(chan struct{}),
 		// sandbox defaults to true, only way to override that is with an explicit 'false'
 		cancelF:     cancelF,
 		ctx:         ctx,
 		sandboxed:   isSandboxed(),
 		cacheBucket: bucket,
+		js:          js,
 		md:          metadata,
 		nc:          nc,
 		started:     time.Now().UTC(),
+		workloads:   make(map[string]*deployedWorkload),
 	}, nil
 }
 
@@ -144,10 +201,12 @@ func (a *Agent) Start() {
 // NOTE: the agent process will request a VM shutdown if this fails
 func (a *Agent) requestHandshake() error {
 	a.LogInfo("Requesting handshake from host")
+	up := agentapi.AgentPhaseUp
 	msg := agentapi.HandshakeRequest{
 		ID:        a.md.VmID,
 		StartTime: a.started,
 		Message:   a.md.Message,
+		Phase:     &up,
 	}
 	raw, _ := json.Marshal(msg)
 
@@ -180,27 +239,72 @@ func (a *Agent) Version() string {
 }
 
 // cacheExecutableArtifact uses the underlying agent configuration to fetch
-// the executable workload artifact from the cache bucket, write it to a
-// temporary file and make it executable; this method returns the full
-// path to the cached artifact if successful
-func (a *Agent) cacheExecutableArtifact(req *agentapi.DeployRequest) (*string, error) {
-	fileName := fmt.Sprintf("workload-%s", *a.md.VmID)
-	tempFile := path.Join(os.TempDir(), fileName)
-
-	if strings.EqualFold(runtime.GOOS, "windows") && strings.EqualFold(*req.WorkloadType, "elf") {
-		tempFile = fmt.Sprintf("%s.exe", tempFile)
+// the workload artifact from the cache bucket, or from the location from which
+// the node directed the agent to retrieve it, write it to a temporary file and
+// set its permissions for the workload type; artifacts stored encrypted at rest
+// are decrypted into the temporary file. This method returns the full path to
+// the cached artifact if successful
+func (a *Agent) cacheExecutableArtifact(ctx context.Context, req *agentapi.DeployRequest) (*string, error) {
+	if req.ArtifactDecryption != nil {
+		return a.fetchEncryptedArtifact(req.CacheBucket(), *req.WorkloadName, *req.WorkloadType, req.Hash, req.ArtifactDecryption)
 	}
 
-	err := a.cacheBucket.GetFile(*req.WorkloadName, tempFile)
+	location, err := artifactLocation(req)
+	if err != nil {
+		a.LogError(err.Error())
+		return nil, err
+	}
+
+	tempFile, err := a.resolveArtifact(ctx, location, *req.WorkloadName, *req.WorkloadType)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := hashFile(*tempFile)
+	if err == nil {
+		err = verifyArtifactDigest(req.Hash, digest)
+	} else {
+		err = fmt.Errorf("Failed to hash workload artifact: %s", err)
+	}
+	if err != nil {
+		_ = os.Remove(*tempFile)
+		a.LogError(err.Error())
+		return nil, err
+	}
+
+	return tempFile, nil
+}
+
+// fetchArtifact writes the artifact with the given key in the given internal
+// bucket to a temporary file, as per resolveArtifact
+func (a *Agent) fetchArtifact(bucketName, key, workloadType string) (*string, error) {
+	return a.resolveArtifact(a.ctx, objectStoreLocation(bucketName, key), key, workloadType)
+}
+
+// resolveArtifact writes the artifact of the named workload at the given location
+// to a temporary file, using the resolver of the location's scheme, making it
+// executable only if the workload type requires it
+func (a *Agent) resolveArtifact(ctx context.Context, location *url.URL, name, workloadType string) (*string, error) {
+	resolver, err := a.artifactResolver(location.Scheme)
 	if err != nil {
+		a.LogError(err.Error())
+		return nil, err
+	}
+
+	tempFile := artifactTempFile(name, workloadType)
+
+	err = resolver.Resolve(ctx, location, tempFile)
+	if err != nil {
+		_ = os.Remove(tempFile)
 		msg := fmt.Sprintf("Failed to write workload artifact to temp dir: %s", err)
 		a.LogError(msg)
 		return nil, errors.New(msg)
 	}
 
-	err = os.Chmod(tempFile, 0777)
+	err = os.Chmod(tempFile, artifactFileMode(workloadType))
 	if err != nil {
-		msg := fmt.Sprintf("Failed to set workload artifact as executable: %s", err)
+		_ = os.Remove(tempFile)
+		msg := fmt.Sprintf("Failed to set workload artifact permissions: %s", err)
 		a.LogError(msg)
 		return nil, errors.New(msg)
 	}
@@ -208,44 +312,188 @@ func (a *Agent) cacheExecutableArtifact(req *agentapi.DeployRequest) (*string, e
 	return &tempFile, nil
 }
 
-// Run inside a goroutine to pull event entries and publish them to the node host.
-func (a *Agent) dispatchEvents() {
-	for !a.shuttingDown() {
-		entry := <-a.eventLogs
-		bytes, err := json.Marshal(entry)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to marshal event log to json: %s", err.Error())
-			continue
-		}
+// fetchEncryptedArtifact decrypts the artifact with the given key in the given
+// internal bucket into a temporary file, as per fetchArtifact, once the digest
+// of the artifact as stored has been verified
+func (a *Agent) fetchEncryptedArtifact(bucketName, key, workloadType, hash string, decryption *agentapi.ArtifactDecryption) (*string, error) {
+	bucket, err := a.artifactBucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
 
-		subject := fmt.Sprintf("agentint.%s.events.%s", *a.md.VmID, entry.Type())
-		err = a.nc.Publish(subject, bytes)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to publish event: %s", err.Error())
-			continue
-		}
+	ciphertext, err := bucket.GetBytes(key)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to retrieve encrypted workload artifact: %s", err)
+		a.LogError(msg)
+		return nil, errors.New(msg)
+	}
 
-		a.nc.Flush()
+	digest := sha256.Sum256(ciphertext)
+	err = verifyArtifactDigest(hash, hex.EncodeToString(digest[:]))
+	if err != nil {
+		a.LogError(err.Error())
+		return nil, err
 	}
+
+	return a.decryptArtifact(ciphertext, key, workloadType, decryption)
 }
 
-// This is run inside a goroutine to pull log entries off the channel and publish to the
-// node host via internal NATS
-func (a *Agent) dispatchLogs() {
-	for !a.shuttingDown() {
-		entry := <-a.agentLogs
-		bytes, err := json.Marshal(entry)
-		if err != nil {
-			continue
+// decryptArtifact decrypts the given artifact, stored encrypted at rest, and
+// verifies its hash before writing it to a temporary file, as per fetchArtifact.
+// The decrypted artifact is written nowhere else
+func (a *Agent) decryptArtifact(ciphertext []byte, name, workloadType string, decryption *agentapi.ArtifactDecryption) (*string, error) {
+	if decryption.Algorithm != controlapi.ArtifactEncryptionAES256GCM {
+		msg := fmt.Sprintf("Unsupported workload artifact encryption algorithm: %s", decryption.Algorithm)
+		a.LogError(msg)
+		return nil, errors.New(msg)
+	}
+
+	artifact, err := controlapi.DecryptArtifact(decryption.Key, ciphertext)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to decrypt workload artifact: %s", err)
+		a.LogError(msg)
+		return nil, errors.New(msg)
+	}
+
+	hash := sha256.Sum256(artifact)
+	if !strings.EqualFold(hex.EncodeToString(hash[:]), decryption.Hash) {
+		msg := "Decrypted workload artifact does not match its expected hash"
+		a.LogError(msg)
+		return nil, errors.New(msg)
+	}
+
+	tempFile := artifactTempFile(name, workloadType)
+	err = os.WriteFile(tempFile, artifact, artifactFileMode(workloadType))
+	if err == nil {
+		err = os.Chmod(tempFile, artifactFileMode(workloadType))
+	}
+	if err != nil {
+		_ = os.Remove(tempFile)
+		msg := fmt.Sprintf("Failed to write decrypted workload artifact to temp dir: %s", err)
+		a.LogError(msg)
+		return nil, errors.New(msg)
+	}
+
+	return &tempFile, nil
+}
+
+// artifactTempFile returns the path of a new temporary file to which the artifact
+// of the named workload of the given type is written. Each artifact is written to
+// its own file, so that overlapping deployments can't clobber each other's artifact
+func artifactTempFile(name, workloadType string) string {
+	tempFile := filepath.Join(os.TempDir(), fmt.Sprintf("workload-%s-%s", filepath.Base(name), uuid.NewString()))
+	if strings.EqualFold(runtime.GOOS, "windows") && strings.EqualFold(workloadType, "elf") {
+		tempFile = fmt.Sprintf("%s.exe", tempFile)
+	}
+
+	return tempFile
+}
+
+// artifactBucket returns the internal bucket with the given name
+func (a *Agent) artifactBucket(bucketName string) (nats.ObjectStore, error) {
+	if bucketName == agentapi.WorkloadCacheBucket {
+		return a.cacheBucket, nil
+	}
+
+	bucket, err := a.js.ObjectStore(bucketName)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to get reference to artifact bucket %s: %s", bucketName, err)
+		a.LogError(msg)
+		return nil, errors.New(msg)
+	}
+
+	return bucket, nil
+}
+
+// artifactFileMode returns the permissions of a fetched artifact of the given
+// workload type: native executables are executable, while artifacts which are
+// interpreted or loaded by a runtime are read-only. Only the artifact's owner, the
+// agent or the workload's user, may read it
+func artifactFileMode(workloadType string) os.FileMode {
+	if strings.EqualFold(workloadType, providers.NexExecutionProviderELF) {
+		return 0500
+	}
+
+	return 0400
+}
+
+// ownsArtifact returns true if the artifact of the given deploy request was written
+// to a temporary file by the agent, rather than read from an attached artifact device
+func ownsArtifact(request *agentapi.DeployRequest) bool {
+	return request.ArtifactDevice == nil || request.ArtifactDecryption != nil
+}
+
+// mountArtifactDevice mounts the read-only block device to which the node attached
+// the workload artifact, returning the full path to the artifact within the mount
+func (a *Agent) mountArtifactDevice(device string) (*string, error) {
+	artifactPath, err := mountArtifactDevice(device)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to mount workload artifact device %s: %s", device, err)
+		a.LogError(msg)
+		return nil, errors.New(msg)
+	}
+
+	a.LogDebug(fmt.Sprintf("Mounted workload artifact device: %s", device))
+	return &artifactPath, nil
+}
+
+// decryptArtifactDevice decrypts the artifact, stored encrypted at rest, mounted
+// at the given path into a temporary file, as per decryptArtifact
+func (a *Agent) decryptArtifactDevice(artifactPath, name, workloadType string, decryption *agentapi.ArtifactDecryption) (*string, error) {
+	ciphertext, err := os.ReadFile(artifactPath)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to read encrypted workload artifact: %s", err)
+		a.LogError(msg)
+		return nil, errors.New(msg)
+	}
+
+	return a.decryptArtifact(ciphertext, name, workloadType, decryption)
+}
+
+// prepareWorkingDirectory creates the working directory in which the workload is run if it
+// does not already exist, handing ownership of a newly created directory to the workload's user
+func (a *Agent) prepareWorkingDirectory(dir string, uid, gid *int) error {
+	info, err := os.Stat(dir)
+	if err == nil {
+		if !info.IsDir() {
+			msg := fmt.Sprintf("Workload working directory %s is not a directory", dir)
+			a.LogError(msg)
+			return errors.New(msg)
 		}
 
-		subject := fmt.Sprintf("agentint.%s.logs", *a.md.VmID)
-		err = a.nc.Publish(subject, bytes)
+		return nil
+	}
+
+	err = os.MkdirAll(dir, 0750)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to create workload working directory %s: %s", dir, err)
+		a.LogError(msg)
+		return errors.New(msg)
+	}
+
+	if uid != nil {
+		err = os.Chown(dir, *uid, *gid)
 		if err != nil {
-			continue
+			msg := fmt.Sprintf("Failed to set owner of workload working directory %s to %d:%d: %s", dir, *uid, *gid, err)
+			a.LogError(msg)
+			return errors.New(msg)
 		}
+	}
+
+	a.LogDebug(fmt.Sprintf("Created workload working directory: %s", dir))
+	return nil
+}
+
+// loadPlugins registers the execution provider plugins in the given directory, logging
+// the workload types registered by each plugin and any plugins which failed to load
+func (a *Agent) loadPlugins(path string) {
+	loaded, err := providers.LoadPlugins(path)
+	if err != nil {
+		a.LogError(fmt.Sprintf("Failed to load execution provider plugins: %s", err))
+	}
 
-		a.nc.Flush()
+	for name, workloadTypes := range loaded {
+		a.LogInfo(fmt.Sprintf("Registered execution provider plugin %s for workload types: %s", name, strings.Join(workloadTypes, ", ")))
 	}
 }
 
@@ -262,32 +510,174 @@ func (a *Agent) handleDeploy(m *nats.Msg) {
 		return
 	}
 
+	// continue the trace of the host's deploy request, if any
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(m.Header))
+	ctx, span := otel.Tracer(agentapi.AgentTracerName).Start(ctx, "deploy",
+		trace.WithSpanKind(trace.SpanKindServer),
+	)
+	defer span.End()
+
+	fail := func(msg string) {
+		span.SetStatus(codes.Error, msg)
+		_ = a.workAck(m, false, msg)
+	}
+
 	err = request.Validate()
 	if err != nil {
-		_ = a.workAck(m, false, fmt.Sprintf("%v", err)) // FIXME-- this message can be formatted prettier
+		fail(fmt.Sprintf("%v", err)) // FIXME-- this message can be formatted prettier
 		return
 	}
 
-	tmpFile, err := a.cacheExecutableArtifact(&request)
+	err = a.openEnvironment(&request)
 	if err != nil {
-		_ = a.workAck(m, false, err.Error())
+		a.LogError(err.Error())
+		fail(err.Error())
 		return
 	}
 
-	params, err := a.newExecutionProviderParams(&request, *tmpFile)
+	err = a.checkWorkloadCapacity(request.ResolveWorkloadID(*a.md.VmID))
 	if err != nil {
-		_ = a.workAck(m, false, err.Error())
+		a.LogError(err.Error())
+		fail(err.Error())
 		return
 	}
 
+	if request.WorkingDirectory != nil {
+		err = a.prepareWorkingDirectory(*request.WorkingDirectory, request.Uid, request.RunAsGid())
+		if err != nil {
+			fail(err.Error())
+			return
+		}
+	}
+
+	var tmpFile *string
+	var artifactCached *bool
+	if request.ArtifactDevice != nil {
+		tmpFile, err = a.mountArtifactDevice(*request.ArtifactDevice)
+		if err == nil && request.ArtifactDecryption != nil {
+			tmpFile, err = a.decryptArtifactDevice(*tmpFile, *request.WorkloadName, *request.WorkloadType, request.ArtifactDecryption)
+		}
+		if err != nil {
+			fail(err.Error())
+			return
+		}
+	} else if request.ArtifactDecryption == nil && a.prepared.matches(request.Hash, *request.WorkloadType) {
+		a.LogDebug(fmt.Sprintf("Deploying workload from prepared artifact: %s", a.prepared.hash))
+		tmpFile = &a.prepared.tmpFile
+		cached := true
+		artifactCached = &cached
+	} else {
+		tmpFile, err = a.cacheExecutableArtifact(ctx, &request)
+		if err != nil {
+			fail(err.Error())
+			return
+		}
+		cached := false
+		artifactCached = &cached
+	}
+
+	if a.prepared != nil && a.prepared.tmpFile != *tmpFile {
+		// the staged artifact was not the one deployed
+		_ = os.Remove(a.prepared.tmpFile)
+	}
+	a.prepared = nil
+
+	if request.Uid != nil && ownsArtifact(&request) {
+		err = os.Chown(*tmpFile, *request.Uid, *request.RunAsGid())
+		if err != nil {
+			_ = os.Remove(*tmpFile)
+			msg := fmt.Sprintf("Failed to set owner of workload artifact to %d:%d: %s", *request.Uid, *request.RunAsGid(), err)
+			a.LogError(msg)
+			fail(msg)
+			return
+		}
+	}
+
+	workload, err := a.deployWorkload(ctx, &request, *tmpFile)
+	if err != nil {
+		if ownsArtifact(&request) {
+			_ = os.Remove(*tmpFile)
+		}
+		fail(err.Error())
+		return
+	}
+
+	reporter, awaitingReady := workload.provider.(providers.ReadinessReporter)
+	_ = a.respondDeploy(m, &agentapi.DeployResponse{
+		Accepted:       true,
+		Message:        agentapi.StringOrNil("Workload deployed"),
+		ArtifactCached: artifactCached,
+		AwaitingReady:  awaitingReady,
+	})
+
+	_ = a.reportPhase(agentapi.AgentPhaseWorkloadDeployed)
+	if awaitingReady {
+		go a.awaitWorkloadReady(reporter)
+	} else {
+		_ = a.reportPhase(agentapi.AgentPhaseWorkloadReady)
+	}
+}
+
+// Waits for the execution provider to report the deployed workload ready, then
+// reports the workload ready to the host so that it is routed triggers
+func (a *Agent) awaitWorkloadReady(reporter providers.ReadinessReporter) {
+	err := reporter.AwaitReady(a.ctx)
+	if err != nil {
+		a.LogError(fmt.Sprintf("Workload did not become ready: %s", err))
+		return
+	}
+
+	_ = a.reportPhase(agentapi.AgentPhaseWorkloadReady)
+}
+
+// Report the lifecycle phase reached by the agent to the host through the
+// handshake subject
+func (a *Agent) reportPhase(phase string) error {
+	msg := agentapi.HandshakeRequest{
+		ID:        a.md.VmID,
+		StartTime: a.started,
+		Phase:     &phase,
+	}
+	raw, _ := json.Marshal(msg)
+
+	_, err := a.nc.Request(fmt.Sprintf("agentint.%s.handshake", *a.md.VmID), raw, time.Millisecond*defaultAgentHandshakeTimeoutMillis)
+	if err != nil {
+		a.LogError(fmt.Sprintf("Failed to report lifecycle phase %s: %s", phase, err))
+		return err
+	}
+
+	return nil
+}
+
+// Initialize the execution provider for the given request and artifact, then
+// validate and deploy the workload alongside any others deployed to the agent
+func (a *Agent) deployWorkload(ctx context.Context, request *agentapi.DeployRequest, tmpFile string) (*deployedWorkload, error) {
+	workload := &deployedWorkload{
+		id:           request.ResolveWorkloadID(*a.md.VmID),
+		request:      request,
+		artifactPath: tmpFile,
+	}
+
+	params, err := a.newExecutionProviderParams(workload)
+	if err != nil {
+		return nil, err
+	}
+
+	_, span := otel.Tracer(agentapi.AgentTracerName).Start(ctx, "initialize-provider",
+		trace.WithAttributes(
+			attribute.String("workload_name", *request.WorkloadName),
+			attribute.String("workload_type", *request.WorkloadType),
+		))
+
 	provider, err := providers.NewExecutionProvider(params)
 	if err != nil {
+		endSpan(span, err)
+		a.finishLogCapture(workload)
 		msg := fmt.Sprintf("Failed to initialize workload execution provider; %s", err)
 		a.LogError(msg)
-		_ = a.workAck(m, false, msg)
-		return
+		return nil, errors.New(msg)
 	}
-	a.provider = provider
+	workload.provider = provider
 
 	shouldValidate := true
 	if !a.sandboxed && strings.EqualFold(*request.WorkloadType, agentapi.NexExecutionProviderELF) {
@@ -295,36 +685,109 @@ func (a *Agent) handleDeploy(m *nats.Msg) {
 	}
 
 	if shouldValidate {
-		err = a.provider.Validate()
+		err = provider.Validate()
 		if err != nil {
+			endSpan(span, err)
+			a.finishLogCapture(workload)
 			msg := fmt.Sprintf("Failed to validate workload: %s", err)
 			a.LogError(msg)
-			_ = a.workAck(m, false, msg)
-			return
+			return nil, errors.New(msg)
 		}
 	}
 
-	err = a.provider.Deploy()
+	endSpan(span, nil)
+
+	err = provider.Deploy()
 	if err != nil {
-		a.LogError(fmt.Sprintf("Failed to deploy workload: %s", err))
-	} else {
-		_ = a.workAck(m, true, "Workload deployed")
+		a.finishLogCapture(workload)
+		msg := fmt.Sprintf("Failed to deploy workload: %s", err)
+		a.LogError(msg)
+		return nil, errors.New(msg)
 	}
+
+	workload.deployedAt = time.Now().UTC()
+	a.addWorkload(workload)
+
+	return workload, nil
 }
 
+// Pull a prepare request off the wire and stage the indicated artifact from the
+// shared bucket, verifying its hash, so a subsequent deploy of the same artifact
+// can skip fetching it
+func (a *Agent) handlePrepare(m *nats.Msg) {
+	var request agentapi.PrepareRequest
+	err := json.Unmarshal(m.Data, &request)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to unmarshal prepare request: %s", err)
+		a.LogError(msg)
+		_ = a.workAck(m, false, msg)
+		return
+	}
+
+	err = request.Validate()
+	if err != nil {
+		_ = a.workAck(m, false, fmt.Sprintf("%v", err))
+		return
+	}
+
+	if len(a.deployedWorkloads()) >= a.maxWorkloads() {
+		_ = a.workAck(m, false, fmt.Sprintf("Agent already hosts the maximum of %d workloads", a.maxWorkloads()))
+		return
+	}
+
+	tmpFile, err := a.fetchArtifact(request.CacheBucket(), request.ArtifactKey, request.WorkloadType)
+	if err != nil {
+		_ = a.workAck(m, false, err.Error())
+		return
+	}
+
+	hash, err := hashFile(*tmpFile)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to hash prepared artifact: %s", err)
+		a.LogError(msg)
+		_ = a.workAck(m, false, msg)
+		return
+	}
+
+	if hash != request.Hash {
+		_ = os.Remove(*tmpFile)
+		msg := fmt.Sprintf("Prepared artifact hash %s does not match requested hash %s", hash, request.Hash)
+		a.LogError(msg)
+		_ = a.workAck(m, false, msg)
+		return
+	}
+
+	if a.prepared != nil {
+		_ = os.Remove(a.prepared.tmpFile)
+	}
+	a.prepared = &preparedArtifact{
+		hash:         hash,
+		tmpFile:      *tmpFile,
+		workloadType: request.WorkloadType,
+	}
+
+	a.LogInfo(fmt.Sprintf("Prepared %s artifact %s", request.WorkloadType, hash))
+	_ = a.workAck(m, true, "Workload prepared")
+}
+
+// Undeploys the workload named by the undeploy request, or else the workload known by
+// the VM's own id
 func (a *Agent) handleUndeploy(m *nats.Msg) {
-	if a.provider == nil {
+	workload := a.workload(a.requestedWorkloadID(m))
+	if workload == nil {
 		a.LogDebug("Received undeploy workload request on agent without deployed workload")
 		_ = m.Respond([]byte{})
 		return
 	}
 
-	err := a.provider.Undeploy()
+	err := a.undeployWorkload(workload)
 	if err != nil {
 		// don't return an error here so worst-case scenario is an ungraceful shutdown,
 		// not a failure
 		a.LogError(fmt.Sprintf("Failed to undeploy workload: %s", err))
 	}
+	a.removeArtifact(workload)
+	a.removeWorkload(workload.id)
 
 	_ = m.Respond([]byte{})
 }
@@ -350,6 +813,7 @@ func (a *Agent) init() error {
 		propagation.TraceContext{},
 		propagation.Baggage{},
 	))
+	a.initTracerProvider()
 
 	err := a.requestHandshake()
 	if err != nil {
@@ -357,6 +821,10 @@ func (a *Agent) init() error {
 		return err
 	}
 
+	if a.md.PluginPath != nil {
+		a.loadPlugins(*a.md.PluginPath)
+	}
+
 	subject := fmt.Sprintf("agentint.%s.deploy", *a.md.VmID)
 	_, err = a.nc.Subscribe(subject, a.handleDeploy)
 	if err != nil {
@@ -364,6 +832,13 @@ func (a *Agent) init() error {
 		return err
 	}
 
+	psubject := fmt.Sprintf("agentint.%s.prepare", *a.md.VmID)
+	_, err = a.nc.Subscribe(psubject, a.handlePrepare)
+	if err != nil {
+		a.LogError(fmt.Sprintf("Failed to subscribe to agent prepare subject: %s", err))
+		return err
+	}
+
 	udsubject := fmt.Sprintf("agentint.%s.undeploy", *a.md.VmID)
 	_, err = a.nc.Subscribe(udsubject, a.handleUndeploy)
 	if err != nil {
@@ -371,9 +846,32 @@ func (a *Agent) init() error {
 		return err
 	}
 
+	_, err = a.nc.Subscribe(agentapi.PingSubject(*a.md.VmID), a.handlePing)
+	if err != nil {
+		a.LogError(fmt.Sprintf("Failed to subscribe to agent ping subject: %s", err))
+		return err
+	}
+
+	if a.md.AgentUpdatePublicKey != nil {
+		usubject := fmt.Sprintf("agentint.%s.update", *a.md.VmID)
+		_, err = a.nc.Subscribe(usubject, a.handleUpdate)
+		if err != nil {
+			a.LogError(fmt.Sprintf("Failed to subscribe to agent update subject: %s", err))
+			return err
+		}
+	}
+
 	go a.startDiagnosticEndpoint()
-	go a.dispatchEvents()
-	go a.dispatchLogs()
+	a.startDispatchers()
+
+	if a.md.HeartbeatIntervalMillisecond != nil && *a.md.HeartbeatIntervalMillisecond > 0 {
+		go a.publishHeartbeats(time.Duration(*a.md.HeartbeatIntervalMillisecond) * time.Millisecond)
+	}
+
+	err = a.restoreUpdateState()
+	if err != nil {
+		a.LogError(fmt.Sprintf("Failed to redeploy workload following agent update: %s", err))
+	}
 
 	return nil
 }
@@ -386,8 +884,11 @@ func (a *Agent) installSignalHandlers() {
 }
 
 // newExecutionProviderParams initializes new execution provider params
-// for the given work request and starts a goroutine listening
-func (a *Agent) newExecutionProviderParams(req *agentapi.DeployRequest, tmpFile string) (*agentapi.ExecutionProviderParams, error) {
+// for the given workload and starts a goroutine listening
+func (a *Agent) newExecutionProviderParams(workload *deployedWorkload) (*agentapi.ExecutionProviderParams, error) {
+	req := workload.request
+	tmpFile := workload.artifactPath
+
 	if a.md.VmID == nil {
 		return nil, errors.New("vm id is required to initialize execution provider params")
 	}
@@ -396,10 +897,21 @@ func (a *Agent) newExecutionProviderParams(req *agentapi.DeployRequest, tmpFile
 		return nil, errors.New("workload name is required to initialize execution provider params")
 	}
 
+	stderr := &logEmitter{stderr: true, name: *req.WorkloadName, logs: a.agentLogs, done: a.dispatchDone}
+	stdout := &logEmitter{stderr: false, name: *req.WorkloadName, logs: a.agentLogs, done: a.dispatchDone}
+
+	// the workload's stdout and stderr share its rate limit, and drops are reported as stderr
+	stderr.limiter = newLogLimiter(a.md.LogRateLimit, a.md.LogBurst, stderr.reportDropped)
+	stdout.limiter = stderr.limiter
+
+	workload.capture = a.startLogCapture(req)
+	stderr.capture = workload.capture
+	stdout.capture = workload.capture
+
 	params := &agentapi.ExecutionProviderParams{
-		DeployRequest: *req,
-		Stderr:        &logEmitter{stderr: true, name: *req.WorkloadName, logs: a.agentLogs},
-		Stdout:        &logEmitter{stderr: false, name: *req.WorkloadName, logs: a.agentLogs},
+		DeployRequest: a.workloadDeployRequest(req),
+		Stderr:        stderr,
+		Stdout:        stdout,
 		TmpFilename:   &tmpFile,
 		VmID:          *a.md.VmID,
 
@@ -409,25 +921,54 @@ func (a *Agent) newExecutionProviderParams(req *agentapi.DeployRequest, tmpFile
 
 		NATSConn:        a.nc,
 		TriggerSubjects: req.TriggerSubjects,
+
+		Executed: func() { a.lastExec.Store(time.Now().UTC().UnixNano()) },
 	}
 
 	go func() {
 		sleepMillis := agentapi.DefaultRunloopSleepTimeoutMillis
+		var startedAt time.Time
 
 		for {
 			select {
 			case <-params.Fail:
-				msg := fmt.Sprintf("Failed to start workload: %s; vm: %s", *params.WorkloadName, params.VmID)
-				a.PublishWorkloadExited(params.VmID, *params.WorkloadName, msg, true, -1)
+				a.finishLogCapture(workload)
+				a.PublishWorkloadExited(params.VmID, agentapi.WorkloadStatusEvent{
+					WorkloadName: *params.WorkloadName,
+					Code:         -1,
+					Message:      fmt.Sprintf("Failed to start workload: %s; vm: %s", *params.WorkloadName, params.VmID),
+					StdoutBytes:  stdout.written.Load(),
+					StderrBytes:  stderr.written.Load(),
+				})
 				return
 
 			case <-params.Run:
+				startedAt = time.Now()
 				a.PublishWorkloadDeployed(params.VmID, *params.WorkloadName, params.TotalBytes)
 				sleepMillis = workloadExecutionSleepTimeoutMillis
 
 			case exit := <-params.Exit:
-				msg := fmt.Sprintf("Exited workload: %s; vm: %s; status: %d", *params.WorkloadName, params.VmID, exit)
-				a.PublishWorkloadExited(params.VmID, *params.WorkloadName, msg, exit != 0, exit)
+				if exit == controlapi.ExitCodeOutOfMemory && params.MemoryLimitMib != nil {
+					a.PublishWorkloadOutOfMemory(params.VmID, *params.WorkloadName, *params.MemoryLimitMib)
+				}
+
+				status := agentapi.WorkloadStatusEvent{
+					WorkloadName: *params.WorkloadName,
+					Code:         exit,
+					Message:      fmt.Sprintf("Exited workload: %s; vm: %s; status: %d", *params.WorkloadName, params.VmID, exit),
+					StdoutBytes:  stdout.written.Load(),
+					StderrBytes:  stderr.written.Load(),
+				}
+				if !startedAt.IsZero() {
+					status.RuntimeMillisecond = time.Since(startedAt).Milliseconds()
+				}
+				if reporter, ok := workload.provider.(providers.SignalReporter); ok {
+					status.Signal = reporter.ExitSignal()
+				}
+
+				// the captured logs are complete by the time the node learns of the exit
+				a.finishLogCapture(workload)
+				a.PublishWorkloadExited(params.VmID, status)
 				return
 			default:
 				// no-op
@@ -441,14 +982,20 @@ func (a *Agent) newExecutionProviderParams(req *agentapi.DeployRequest, tmpFile
 }
 
 func (a *Agent) shutdown() {
+	if atomic.LoadUint32(&a.replacing) > 0 {
+		// the agent re-executes or halts once replaced
+		return
+	}
+
 	if atomic.AddUint32(&a.closing, 1) == 1 {
-		if a.provider != nil {
-			err := a.provider.Undeploy()
-			if err != nil {
-				fmt.Printf("failed to undeploy workload: %s", err)
-			}
+		err := a.undeployWorkloads()
+		if err != nil {
+			fmt.Printf("failed to undeploy workload: %s", err)
 		}
 
+		a.stopDispatchers()
+		a.shutdownTracerProvider()
+
 		_ = a.nc.Drain()
 		for !a.nc.IsClosed() {
 			time.Sleep(time.Millisecond * 25)
@@ -469,22 +1016,24 @@ func (a *Agent) startDiagnosticEndpoint() {
 }
 
 func (a *Agent) submitLog(msg string, lvl agentapi.LogLevel) {
-	a.agentLogs <- &agentapi.LogEntry{
+	a.enqueueLog(&agentapi.LogEntry{
 		Source: NexEventSourceNexAgent,
 		Level:  lvl,
 		Text:   msg,
-	}
+	})
 }
 
 // workAck ACKs the provided NATS message by responding with the
 // accepted status of the attempted work request and associated message
 func (a *Agent) workAck(m *nats.Msg, accepted bool, msg string) error {
-	ack := agentapi.DeployResponse{
+	return a.respondDeploy(m, &agentapi.DeployResponse{
 		Accepted: accepted,
 		Message:  agentapi.StringOrNil(msg),
-	}
+	})
+}
 
-	bytes, err := json.Marshal(&ack)
+func (a *Agent) respondDeploy(m *nats.Msg, ack *agentapi.DeployResponse) error {
+	bytes, err := json.Marshal(ack)
 	if err != nil {
 		return err
 	}
@@ -498,6 +1047,44 @@ func (a *Agent) workAck(m *nats.Msg, accepted bool, msg string) error {
 	return nil
 }
 
+// An artifact staged on the agent ahead of deployment
+type preparedArtifact struct {
+	hash         string
+	tmpFile      string
+	workloadType string
+}
+
+func (p *preparedArtifact) matches(hash, workloadType string) bool {
+	return p != nil && p.hash == hash && strings.EqualFold(p.workloadType, workloadType)
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyArtifactDigest returns an error naming both digests if the actual
+// SHA-256 digest of a workload artifact does not match its expected digest,
+// in which case the artifact is corrupt or truncated and must not be executed
+func verifyArtifactDigest(expected, actual string) error {
+	if !strings.EqualFold(expected, actual) {
+		return fmt.Errorf("Workload artifact digest mismatch: expected sha256 %s, got %s", expected, actual)
+	}
+
+	return nil
+}
+
 func isSandboxed() bool {
 	return !strings.EqualFold(strings.ToLower(os.Getenv(nexEnvSandbox)), "false")
 }
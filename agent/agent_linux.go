@@ -1,12 +1,27 @@
 package nexagent
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"net"
+	"net/netip"
 	"os"
 	"os/signal"
+	"path"
 	"syscall"
+	"unsafe"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 )
 
+const artifactMountPoint = "/mnt/nex-artifact"
+
+// _IOW('R', 0x03, int[2]); adds entropy to the kernel's pool and credits it
+const ioctlRndAddEntropy = 0x40085203
+
 func HaltVM(err error) {
 	code := 0
 	if err != nil {
@@ -27,3 +42,98 @@ func HaltVM(err error) {
 func resetSIGUSR() {
 	signal.Reset(syscall.SIGUSR1, syscall.SIGUSR2)
 }
+
+func mountArtifactDevice(device string) (string, error) {
+	err := os.MkdirAll(artifactMountPoint, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	err = syscall.Mount(device, artifactMountPoint, "ext4", syscall.MS_RDONLY, "")
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(artifactMountPoint, agentapi.ArtifactDeviceWorkloadFile), nil
+}
+
+// Mixes the given seed into the kernel's entropy pool, crediting it in full so that readers
+// blocking on entropy right after boot are released
+func seedEntropy(seed []byte) error {
+	f, err := os.OpenFile("/dev/urandom", os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// struct rand_pool_info { int entropy_count; int buf_size; __u32 buf[]; }
+	info := make([]byte, 8+len(seed))
+	binary.NativeEndian.PutUint32(info[0:4], uint32(len(seed)*8))
+	binary.NativeEndian.PutUint32(info[4:8], uint32(len(seed)))
+	copy(info[8:], seed)
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlRndAddEntropy, uintptr(unsafe.Pointer(&info[0])))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// Brings up the machine's network interface with the given IPv6 address and a default route via the
+// given gateway, along with a link-local IPv4 address through which firecracker's MMDS is reached
+func configureInterface(address netip.Prefix, gateway netip.Addr) error {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return err
+	}
+
+	var link netlink.Link
+	for _, l := range links {
+		if l.Attrs().Flags&net.FlagLoopback == 0 {
+			link = l
+			break
+		}
+	}
+	if link == nil {
+		return errors.New("no network interface found")
+	}
+
+	err = netlink.LinkSetUp(link)
+	if err != nil {
+		return fmt.Errorf("failed to bring up %s: %s", link.Attrs().Name, err)
+	}
+
+	// duplicate address detection would leave the address unusable for a time after boot, and
+	// the address is assigned by the node
+	err = netlink.AddrAdd(link, &netlink.Addr{
+		IPNet: &net.IPNet{IP: address.Addr().AsSlice(), Mask: net.CIDRMask(address.Bits(), 128)},
+		Flags: unix.IFA_F_NODAD,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add address %s to %s: %s", address, link.Attrs().Name, err)
+	}
+
+	mmdsAddress, err := netlink.ParseAddr(mmdsLinkLocalAddress)
+	if err != nil {
+		return err
+	}
+
+	err = netlink.AddrAdd(link, mmdsAddress)
+	if err != nil {
+		return fmt.Errorf("failed to add address %s to %s: %s", mmdsLinkLocalAddress, link.Attrs().Name, err)
+	}
+
+	err = netlink.RouteAdd(&netlink.Route{LinkIndex: link.Attrs().Index, Gw: gateway.AsSlice()})
+	if err != nil {
+		return fmt.Errorf("failed to add default route via %s: %s", gateway, err)
+	}
+
+	return nil
+}
+
+// Replaces the running agent with the binary at the given path, preserving its pid, which
+// within a sandbox is init
+func reexec(binaryPath string) error {
+	return syscall.Exec(binaryPath, os.Args, os.Environ())
+}
@@ -3,7 +3,9 @@
 package nexagent
 
 import (
+	"errors"
 	"fmt"
+	"net/netip"
 	"os"
 )
 
@@ -18,3 +20,19 @@ func HaltVM(err error) {
 }
 
 func resetSIGUSR() {}
+
+func mountArtifactDevice(_ string) (string, error) {
+	return "", errors.New("artifact block devices are only supported on linux")
+}
+
+func seedEntropy(_ []byte) error {
+	return nil
+}
+
+func reexec(_ string) error {
+	return errors.New("agent updates are only supported on linux")
+}
+
+func configureInterface(_ netip.Prefix, _ netip.Addr) error {
+	return errors.New("IPv6 networks are only supported on linux")
+}
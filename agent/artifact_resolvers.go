@@ -0,0 +1,280 @@
+package nexagent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+// Media types of the image manifests from which OCI artifacts are retrieved
+var ociManifestMediaTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// ArtifactResolver implementations retrieve workload artifacts from the locations of a
+// particular scheme, e.g. the node's internal object store, an HTTP(S) server or an OCI
+// registry. Resolved artifacts are verified against their expected digest by the agent
+type ArtifactResolver interface {
+	// Writes the artifact at the given location to the file at the given path
+	Resolve(ctx context.Context, location *url.URL, path string) error
+}
+
+// artifactResolver returns the resolver of artifacts at locations of the given scheme
+func (a *Agent) artifactResolver(scheme string) (ArtifactResolver, error) {
+	switch strings.ToLower(scheme) {
+	case controlapi.ArtifactSchemeNATS:
+		return &objectStoreResolver{agent: a}, nil
+	case controlapi.ArtifactSchemeHTTP, controlapi.ArtifactSchemeHTTPS:
+		return &httpResolver{client: http.DefaultClient}, nil
+	case controlapi.ArtifactSchemeOCI:
+		return &ociResolver{client: http.DefaultClient}, nil
+	default:
+		return nil, fmt.Errorf("Unsupported workload artifact location scheme: %s", scheme)
+	}
+}
+
+// artifactLocation returns the location of the given request's artifact: the location from which
+// the node directed the agent to retrieve it, if any, or else its key in the internal cache bucket
+func artifactLocation(req *agentapi.DeployRequest) (*url.URL, error) {
+	if req.ArtifactLocation != nil {
+		location, err := url.Parse(*req.ArtifactLocation)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid workload artifact location: %s", err)
+		}
+
+		return location, nil
+	}
+
+	return objectStoreLocation(req.CacheBucket(), *req.WorkloadName), nil
+}
+
+// objectStoreLocation returns the location of the object with the given key in the given bucket
+func objectStoreLocation(bucketName, key string) *url.URL {
+	return &url.URL{Scheme: controlapi.ArtifactSchemeNATS, Host: bucketName, Path: "/" + key}
+}
+
+// Resolves artifacts in the node's internal object store buckets, at nats://BUCKET/key
+type objectStoreResolver struct {
+	agent *Agent
+}
+
+func (r *objectStoreResolver) Resolve(ctx context.Context, location *url.URL, path string) error {
+	bucket, err := r.agent.artifactBucket(location.Host)
+	if err != nil {
+		return err
+	}
+
+	return bucket.GetFile(strings.Trim(location.Path, "/"), path, nats.Context(ctx))
+}
+
+// Resolves artifacts at HTTP(S) URLs
+type httpResolver struct {
+	client *http.Client
+}
+
+func (r *httpResolver) Resolve(ctx context.Context, location *url.URL, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", location.Redacted(), resp.Status)
+	}
+
+	return writeArtifact(resp.Body, path)
+}
+
+// Resolves single-layer artifacts in OCI registries, e.g. as pushed by oras, at
+// oci://REGISTRY/repository:tag or oci://REGISTRY/repository@digest. Registries are accessed over
+// HTTPS, anonymously or with an anonymous bearer token where the registry requires one
+type ociResolver struct {
+	client *http.Client
+}
+
+type ociManifest struct {
+	Layers []struct {
+		Digest    string `json:"digest"`
+		MediaType string `json:"mediaType"`
+	} `json:"layers"`
+}
+
+func (r *ociResolver) Resolve(ctx context.Context, location *url.URL, path string) error {
+	repository, reference := parseOCIReference(strings.TrimPrefix(location.Path, "/"))
+	if repository == "" {
+		return fmt.Errorf("invalid OCI artifact reference: %s", location)
+	}
+
+	registry := &url.URL{Scheme: "https", Host: location.Host}
+
+	manifestURL := registry.JoinPath("v2", repository, "manifests", reference)
+	resp, err := r.get(ctx, manifestURL, strings.Join(ociManifestMediaTypes, ", "))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var manifest ociManifest
+	err = json.NewDecoder(resp.Body).Decode(&manifest)
+	if err != nil {
+		return fmt.Errorf("failed to decode OCI manifest: %s", err)
+	}
+
+	if len(manifest.Layers) != 1 {
+		return fmt.Errorf("expected OCI artifact %s to have a single layer, found %d", location, len(manifest.Layers))
+	}
+
+	blob, err := r.get(ctx, registry.JoinPath("v2", repository, "blobs", manifest.Layers[0].Digest), "")
+	if err != nil {
+		return err
+	}
+	defer blob.Body.Close()
+
+	return writeArtifact(blob.Body, path)
+}
+
+// get requests the given registry URL, authorizing the request with an anonymous bearer token
+// if the registry requires one
+func (r *ociResolver) get(ctx context.Context, u *url.URL, accept string) (*http.Response, error) {
+	var token string
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && token == "" {
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+
+			token, err = r.anonymousToken(ctx, challenge)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to retrieve %s: %s", u, resp.Status)
+		}
+
+		return resp, nil
+	}
+}
+
+// anonymousToken requests an anonymous bearer token as per the given WWW-Authenticate challenge
+func (r *ociResolver) anonymousToken(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "bearer") {
+		return "", fmt.Errorf("unsupported registry authentication challenge: %q", challenge)
+	}
+
+	var realm string
+	query := url.Values{}
+	for _, param := range strings.Split(params, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok {
+			continue
+		}
+
+		value = strings.Trim(value, `"`)
+		if key == "realm" {
+			realm = value
+		} else {
+			query.Set(key, value)
+		}
+	}
+
+	realmURL, err := url.Parse(realm)
+	if err != nil || realm == "" {
+		return "", fmt.Errorf("invalid registry authentication realm: %q", realm)
+	}
+	realmURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realmURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to acquire registry token: %s", resp.Status)
+	}
+
+	var tokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&tokenResponse)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode registry token: %s", err)
+	}
+
+	if tokenResponse.Token != "" {
+		return tokenResponse.Token, nil
+	}
+	if tokenResponse.AccessToken != "" {
+		return tokenResponse.AccessToken, nil
+	}
+
+	return "", errors.New("registry returned no token")
+}
+
+// parseOCIReference splits the given reference into its repository and its tag or digest,
+// defaulting to the latest tag
+func parseOCIReference(reference string) (string, string) {
+	if repository, digest, ok := strings.Cut(reference, "@"); ok {
+		return repository, digest
+	}
+
+	if i := strings.LastIndex(reference, ":"); i > strings.LastIndex(reference, "/") {
+		return reference[:i], reference[i+1:]
+	}
+
+	return reference, "latest"
+}
+
+// writeArtifact writes the given artifact to a new file at the given path, readable only by the agent
+func writeArtifact(artifact io.Reader, path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, artifact)
+	return errors.Join(err, f.Close())
+}
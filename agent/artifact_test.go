@@ -0,0 +1,171 @@
+package nexagent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyArtifactDigest(t *testing.T) {
+	artifact := []byte("workload artifact")
+	path := filepath.Join(t.TempDir(), "workload")
+	err := os.WriteFile(path, artifact, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256(artifact)
+	expected := hex.EncodeToString(sum[:])
+
+	digest, err := hashFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyArtifactDigest(strings.ToUpper(expected), digest); err != nil {
+		t.Fatalf("expected digest to verify, got %s", err)
+	}
+
+	// a truncated artifact must not verify
+	err = os.WriteFile(path, artifact[:len(artifact)-1], 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err = hashFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = verifyArtifactDigest(expected, digest)
+	if err == nil {
+		t.Fatal("expected digest mismatch")
+	}
+	if !strings.Contains(err.Error(), expected) || !strings.Contains(err.Error(), digest) {
+		t.Fatalf("expected error to name both digests, got %s", err)
+	}
+}
+
+func TestArtifactTempFilesAreUnique(t *testing.T) {
+	first := artifactTempFile("echoservice", "elf")
+	second := artifactTempFile("echoservice", "elf")
+	if first == second {
+		t.Fatalf("expected distinct temp files, got %s twice", first)
+	}
+
+	for _, tempFile := range []string{first, second} {
+		if filepath.Dir(tempFile) != filepath.Clean(os.TempDir()) {
+			t.Fatalf("expected temp file in %s, got %s", os.TempDir(), tempFile)
+		}
+		if !strings.Contains(filepath.Base(tempFile), "echoservice") {
+			t.Fatalf("expected temp file to be named for its workload, got %s", tempFile)
+		}
+	}
+
+	// a workload name can't place the artifact outside the temp dir
+	tempFile := artifactTempFile("../../etc/echoservice", "elf")
+	if filepath.Dir(tempFile) != filepath.Clean(os.TempDir()) {
+		t.Fatalf("expected temp file in %s, got %s", os.TempDir(), tempFile)
+	}
+}
+
+func TestHTTPArtifactResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/echoservice" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("workload artifact"))
+	}))
+	defer server.Close()
+
+	resolver := &httpResolver{client: server.Client()}
+	path := filepath.Join(t.TempDir(), "workload")
+
+	location, _ := url.Parse(server.URL + "/echoservice")
+	err := resolver.Resolve(context.Background(), location, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	artifact, _ := os.ReadFile(path)
+	if string(artifact) != "workload artifact" {
+		t.Fatalf("unexpected artifact: %s", artifact)
+	}
+
+	location, _ = url.Parse(server.URL + "/missing")
+	err = resolver.Resolve(context.Background(), location, filepath.Join(t.TempDir(), "workload"))
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected a missing artifact to fail, got %v", err)
+	}
+}
+
+func TestOCIArtifactResolver(t *testing.T) {
+	artifact := []byte("workload artifact")
+	sum := sha256.Sum256(artifact)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") != "repository:nex/echoservice:pull" {
+				http.Error(w, "bad scope", http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"token": "anonymous"}`))
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer anonymous" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:nex/echoservice:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/nex/echoservice/manifests/1.0":
+			w.Header().Set("Content-Type", ociManifestMediaTypes[0])
+			_, _ = fmt.Fprintf(w, `{"schemaVersion": 2, "layers": [{"mediaType": "application/octet-stream", "digest": "%s"}]}`, digest)
+		case "/v2/nex/echoservice/blobs/" + digest:
+			_, _ = w.Write(artifact)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	resolver := &ociResolver{client: server.Client()}
+	path := filepath.Join(t.TempDir(), "workload")
+
+	location, _ := url.Parse(fmt.Sprintf("oci://%s/nex/echoservice:1.0", server.Listener.Addr()))
+	err := resolver.Resolve(context.Background(), location, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, _ := os.ReadFile(path)
+	if string(resolved) != string(artifact) {
+		t.Fatalf("unexpected artifact: %s", resolved)
+	}
+}
+
+func TestParseOCIReference(t *testing.T) {
+	for reference, expected := range map[string][2]string{
+		"nex/echoservice":                {"nex/echoservice", "latest"},
+		"nex/echoservice:1.0":            {"nex/echoservice", "1.0"},
+		"nex/echoservice@sha256:abc123":  {"nex/echoservice", "sha256:abc123"},
+		"nex.v1/echoservice":             {"nex.v1/echoservice", "latest"},
+		"nex/echoservice:1.0@sha256:abc": {"nex/echoservice:1.0", "sha256:abc"},
+	} {
+		repository, ref := parseOCIReference(reference)
+		if repository != expected[0] || ref != expected[1] {
+			t.Fatalf("expected %s to parse as %v, got %s and %s", reference, expected, repository, ref)
+		}
+	}
+}
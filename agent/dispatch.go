@@ -0,0 +1,217 @@
+package nexagent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudevents/sdk-go/pkg/cloudevents"
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+const (
+	dispatchMaxAttempts     = 3
+	dispatchRetryInterval   = 50 * time.Millisecond
+	dispatchShutdownTimeout = 2 * time.Second
+
+	// Log entries are dispatched to the node in batches of those received within the window, of up
+	// to the max entries
+	logBatchWindow     = 50 * time.Millisecond
+	logBatchMaxEntries = 64
+)
+
+// Starts the log and event dispatchers. The dispatchers run until stopDispatchers is
+// called or the agent's context is cancelled, at which point any entries remaining on
+// their channels are flushed to the node before they exit
+func (a *Agent) startDispatchers() {
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.dispatchCancel = cancel
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		a.dispatchEvents(ctx)
+	}()
+
+	go func() {
+		defer wg.Done()
+		a.dispatchLogs(ctx)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(a.dispatchDone)
+	}()
+}
+
+// Stops the log and event dispatchers, waiting (up to a timeout) for them to flush
+// remaining entries and exit
+func (a *Agent) stopDispatchers() {
+	if a.dispatchCancel == nil {
+		return
+	}
+
+	a.dispatchCancel()
+
+	select {
+	case <-a.dispatchDone:
+	case <-time.After(dispatchShutdownTimeout):
+		fmt.Fprintf(os.Stderr, "timed out waiting for log and event dispatchers to stop\n")
+	}
+
+	dropped := atomic.LoadUint64(&a.droppedLogs) + atomic.LoadUint64(&a.droppedEvents)
+	if dropped > 0 {
+		fmt.Fprintf(os.Stderr, "dropped %d log and event entries which could not be delivered to the node\n", dropped)
+	}
+}
+
+// Enqueues the given log entry for dispatch to the node, stamped with the time it was logged unless
+// already; once the dispatchers have stopped, entries are dropped rather than blocking the caller
+func (a *Agent) enqueueLog(entry *agentapi.LogEntry) {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+
+	select {
+	case a.agentLogs <- entry:
+	case <-a.dispatchDone:
+		atomic.AddUint64(&a.droppedLogs, 1)
+	}
+}
+
+// Enqueues the given event for dispatch to the node; once the dispatchers
+// have stopped, events are dropped rather than blocking the caller
+func (a *Agent) enqueueEvent(evt *cloudevents.Event) {
+	select {
+	case a.eventLogs <- evt:
+	case <-a.dispatchDone:
+		atomic.AddUint64(&a.droppedEvents, 1)
+	}
+}
+
+// Pulls event entries off the channel and publishes them to the node host via internal NATS
+func (a *Agent) dispatchEvents(ctx context.Context) {
+	for {
+		select {
+		case entry := <-a.eventLogs:
+			a.dispatchEvent(entry)
+		case <-ctx.Done():
+			for {
+				select {
+				case entry := <-a.eventLogs:
+					a.dispatchEvent(entry)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (a *Agent) dispatchEvent(entry *cloudevents.Event) {
+	bytes, err := json.Marshal(entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to marshal event log to json: %s\n", err.Error())
+		atomic.AddUint64(&a.droppedEvents, 1)
+		return
+	}
+
+	subject := fmt.Sprintf("agentint.%s.events.%s", *a.md.VmID, entry.Type())
+	if !a.publishWithRetry(subject, bytes) {
+		atomic.AddUint64(&a.droppedEvents, 1)
+	}
+}
+
+// Pulls log entries off the channel and publishes them to the node host via internal NATS, batching
+// the entries received within the batch window into a single message
+func (a *Agent) dispatchLogs(ctx context.Context) {
+	batch := &logBatch{}
+	var window <-chan time.Time
+
+	for {
+		select {
+		case entry := <-a.agentLogs:
+			a.batchLog(batch, entry)
+			if batch.len() >= logBatchMaxEntries {
+				a.dispatchLogBatch(batch)
+			}
+
+			if batch.len() == 0 {
+				window = nil
+			} else if window == nil {
+				window = time.After(logBatchWindow)
+			}
+		case <-window:
+			a.dispatchLogBatch(batch)
+			window = nil
+		case <-ctx.Done():
+			for {
+				select {
+				case entry := <-a.agentLogs:
+					a.batchLog(batch, entry)
+				default:
+					a.dispatchLogBatch(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// Adds the given entry to the batch, first dispatching the batch if the entry would take it beyond
+// the connection's max payload
+func (a *Agent) batchLog(batch *logBatch, entry *agentapi.LogEntry) {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		atomic.AddUint64(&a.droppedLogs, 1)
+		return
+	}
+
+	if !batch.fits(raw, int(a.nc.MaxPayload())) {
+		a.dispatchLogBatch(batch)
+	}
+	batch.add(raw)
+}
+
+func (a *Agent) dispatchLogBatch(batch *logBatch) {
+	if batch.len() == 0 {
+		return
+	}
+
+	subject := fmt.Sprintf("agentint.%s.logs", *a.md.VmID)
+	if !a.publishWithRetry(subject, batch.encode()) {
+		atomic.AddUint64(&a.droppedLogs, uint64(batch.len()))
+	}
+	batch.reset()
+}
+
+// Publishes the given payload to the node, retrying transient failures a bounded number of
+// times; returns false if the payload was dropped
+func (a *Agent) publishWithRetry(subject string, data []byte) bool {
+	var err error
+
+	for attempt := 1; attempt <= dispatchMaxAttempts; attempt++ {
+		err = a.nc.Publish(subject, data)
+		if err == nil {
+			_ = a.nc.Flush()
+			return true
+		}
+
+		if errors.Is(err, nats.ErrConnectionClosed) {
+			break
+		}
+
+		time.Sleep(dispatchRetryInterval)
+	}
+
+	fmt.Fprintf(os.Stderr, "failed to publish to %s: %s\n", subject, err)
+	return false
+}
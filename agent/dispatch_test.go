@@ -0,0 +1,130 @@
+package nexagent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/pkg/cloudevents"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+func startTestNATS(t *testing.T) (*server.Server, *nats.Conn) {
+	ns, err := server.NewServer(&server.Options{
+		Host:  "127.0.0.1",
+		Port:  -1,
+		NoLog: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create NATS server: %s", err)
+	}
+	ns.Start()
+
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server failed to start")
+	}
+
+	nc, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		t.Fatalf("failed to connect to NATS server: %s", err)
+	}
+
+	return ns, nc
+}
+
+func TestDispatchersFlushAndStopCleanly(t *testing.T) {
+	ns, nc := startTestNATS(t)
+	defer ns.Shutdown()
+	defer nc.Close()
+
+	vmID := "testvm"
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := &Agent{
+		agentLogs:    make(chan *agentapi.LogEntry, 64),
+		eventLogs:    make(chan *cloudevents.Event, 64),
+		dispatchDone: make(chan struct{}),
+		cancelF:      cancel,
+		ctx:          ctx,
+		md:           &agentapi.MachineMetadata{VmID: &vmID},
+		nc:           nc,
+		sandboxed:    true,
+	}
+
+	var received int32
+	var outOfOrder int32
+	var lastLogged time.Time
+	sub, err := nc.Subscribe(fmt.Sprintf("agentint.%s.logs", vmID), func(m *nats.Msg) {
+		var entries []agentapi.LogEntry
+		err := json.Unmarshal(m.Data, &entries)
+		if err != nil {
+			t.Errorf("expected log entries to be dispatched in batches: %s", err)
+			return
+		}
+
+		for _, entry := range entries {
+			if entry.Timestamp.Before(lastLogged) {
+				atomic.AddInt32(&outOfOrder, 1)
+			}
+			lastLogged = entry.Timestamp
+		}
+		atomic.AddInt32(&received, int32(len(entries)))
+	})
+	if err != nil {
+		t.Fatalf("failed to subscribe to agent logs: %s", err)
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+	_ = nc.Flush()
+
+	baseline := runtime.NumGoroutine()
+
+	a.startDispatchers()
+
+	const numLogs = 500
+	for i := 0; i < numLogs; i++ {
+		a.submitLog(fmt.Sprintf("log entry %d", i), agentapi.LogLevelInfo)
+	}
+	a.PublishWorkloadDeployed(vmID, "testworkload", 0)
+
+	a.stopDispatchers()
+
+	select {
+	case <-a.dispatchDone:
+	default:
+		t.Fatal("expected dispatchers to have exited")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&received) < numLogs+1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := atomic.LoadInt32(&received); got != numLogs+1 {
+		t.Fatalf("expected %d flushed log entries, got %d", numLogs+1, got)
+	}
+	if atomic.LoadInt32(&outOfOrder) > 0 {
+		t.Fatal("expected batched log entries to be dispatched in the order they were logged")
+	}
+
+	// logs submitted after shutdown must not block the caller
+	a.submitLog("after shutdown", agentapi.LogLevelInfo)
+	for i := 0; i < cap(a.agentLogs)+1; i++ {
+		a.submitLog("after shutdown", agentapi.LogLevelInfo)
+	}
+
+	deadline = time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := runtime.NumGoroutine(); n > baseline {
+		t.Fatalf("expected no leaked goroutines; baseline %d, now %d", baseline, n)
+	}
+}
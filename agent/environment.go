@@ -0,0 +1,54 @@
+package nexagent
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"strconv"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+// Opens the environment sealed by the node with this agent's environment key into the given deploy
+// request. When the node requires sealed environments, requests carrying a plaintext environment
+// are rejected
+func (a *Agent) openEnvironment(request *agentapi.DeployRequest) error {
+	if request.SealedEnvironment == nil {
+		if a.md.RequireSealedEnvironment && len(request.Environment) > 0 {
+			return errors.New("Rejected plaintext workload environment; the node requires sealed environments")
+		}
+		return nil
+	}
+
+	env, err := agentapi.OpenEnvironment(a.md.EnvironmentKey, *request.SealedEnvironment)
+	if err != nil {
+		return fmt.Errorf("Failed to open sealed workload environment: %s", err)
+	}
+
+	request.Environment = env
+	return nil
+}
+
+// Returns a copy of the given deploy request whose environment also tells the workload where to
+// publish its metric samples; the request's own environment is left untouched
+func (a *Agent) workloadDeployRequest(req *agentapi.DeployRequest) agentapi.DeployRequest {
+	dispatched := *req
+
+	dispatched.Environment = maps.Clone(req.Environment)
+	if dispatched.Environment == nil {
+		dispatched.Environment = make(map[string]string)
+	}
+
+	natsURL := &url.URL{Scheme: "nats", Host: net.JoinHostPort(*a.md.NodeNatsHost, strconv.Itoa(*a.md.NodeNatsPort))}
+	if a.md.WorkloadNatsToken != nil {
+		// the workload's token only permits it to publish metric samples
+		natsURL.User = url.User(*a.md.WorkloadNatsToken)
+	}
+
+	dispatched.Environment[agentapi.MetricsSubjectEnvVar] = agentapi.MetricsSubject(*a.md.VmID)
+	dispatched.Environment[agentapi.MetricsNatsURLEnvVar] = natsURL.String()
+
+	return dispatched
+}
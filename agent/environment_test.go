@@ -0,0 +1,72 @@
+package nexagent
+
+import (
+	"testing"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+func TestOpenEnvironment(t *testing.T) {
+	key, _ := agentapi.NewEnvironmentKey()
+	a := &Agent{md: &agentapi.MachineMetadata{EnvironmentKey: key, RequireSealedEnvironment: true}}
+
+	sealed, err := agentapi.SealEnvironment(key, map[string]string{"SECRET": "hunter2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := &agentapi.DeployRequest{SealedEnvironment: &sealed}
+	err = a.openEnvironment(request)
+	if err != nil || request.Environment["SECRET"] != "hunter2" {
+		t.Fatalf("expected the sealed environment to be opened: %v %v", request.Environment, err)
+	}
+
+	other, _ := agentapi.NewEnvironmentKey()
+	sealed, _ = agentapi.SealEnvironment(other, map[string]string{"SECRET": "hunter2"})
+	if err := a.openEnvironment(&agentapi.DeployRequest{SealedEnvironment: &sealed}); err == nil {
+		t.Fatal("expected an environment sealed with another agent's key to be rejected")
+	}
+
+	plaintext := &agentapi.DeployRequest{Environment: map[string]string{"SECRET": "hunter2"}}
+	if err := a.openEnvironment(plaintext); err == nil {
+		t.Fatal("expected a plaintext environment to be rejected when sealed environments are required")
+	}
+
+	a.md.RequireSealedEnvironment = false
+	if err := a.openEnvironment(plaintext); err != nil {
+		t.Fatalf("expected a plaintext environment to be accepted when sealing is not required, got %s", err)
+	}
+}
+
+func TestWorkloadDeployRequest(t *testing.T) {
+	vmID := "vm1"
+	host := "192.168.127.1"
+	port := 9222
+	a := &Agent{md: &agentapi.MachineMetadata{VmID: &vmID, NodeNatsHost: &host, NodeNatsPort: &port}}
+
+	request := &agentapi.DeployRequest{Environment: map[string]string{"SECRET": "hunter2"}}
+	dispatched := a.workloadDeployRequest(request)
+
+	if dispatched.Environment["SECRET"] != "hunter2" {
+		t.Fatalf("expected the workload environment to be retained: %v", dispatched.Environment)
+	}
+	if dispatched.Environment[agentapi.MetricsSubjectEnvVar] != "agentint.vm1.metrics" {
+		t.Fatalf("expected the metrics subject to be given to the workload: %v", dispatched.Environment)
+	}
+	if dispatched.Environment[agentapi.MetricsNatsURLEnvVar] != "nats://192.168.127.1:9222" {
+		t.Fatalf("expected the internal NATS url to be given to the workload: %v", dispatched.Environment)
+	}
+	if _, ok := request.Environment[agentapi.MetricsSubjectEnvVar]; ok {
+		t.Fatal("expected the deploy request's own environment to be left untouched")
+	}
+
+	if dispatched = a.workloadDeployRequest(&agentapi.DeployRequest{}); dispatched.Environment[agentapi.MetricsSubjectEnvVar] == "" {
+		t.Fatal("expected the metrics subject to be given to workloads deployed without an environment")
+	}
+
+	token := "s3cr3t"
+	a.md.WorkloadNatsToken = &token
+	if dispatched = a.workloadDeployRequest(request); dispatched.Environment[agentapi.MetricsNatsURLEnvVar] != "nats://s3cr3t@192.168.127.1:9222" {
+		t.Fatalf("expected the internal NATS url to carry the workload's token: %v", dispatched.Environment)
+	}
+}
@@ -0,0 +1,49 @@
+package nexagent
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+// Publishes a heartbeat to the node at the given interval until the agent shuts down, so that
+// the node can detect an agent which has stopped responding
+func (a *Agent) publishHeartbeats(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+			if a.shuttingDown() {
+				return
+			}
+
+			err := agentapi.PublishHeartbeat(a.nc, *a.md.VmID, a.heartbeat())
+			if err != nil {
+				a.LogError(fmt.Sprintf("Failed to publish heartbeat: %s", err))
+			}
+		}
+	}
+}
+
+func (a *Agent) heartbeat() *agentapi.AgentHeartbeat {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	now := time.Now().UTC()
+	return &agentapi.AgentHeartbeat{
+		ID:                *a.md.VmID,
+		Timestamp:         now,
+		UptimeMillisecond: now.Sub(a.started).Milliseconds(),
+		Goroutines:        runtime.NumGoroutine(),
+		HeapAllocBytes:    mem.HeapAlloc,
+		DroppedLogs:       atomic.LoadUint64(&a.droppedLogs),
+		DroppedEvents:     atomic.LoadUint64(&a.droppedEvents),
+	}
+}
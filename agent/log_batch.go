@@ -0,0 +1,37 @@
+package nexagent
+
+import "bytes"
+
+// Log entries encoded for dispatch to the node together, in order, as a single JSON array
+type logBatch struct {
+	entries [][]byte
+	size    int
+}
+
+func (b *logBatch) len() int {
+	return len(b.entries)
+}
+
+// Returns true if the given encoded entry can be added to the batch without its encoding exceeding
+// the given size. An empty batch accepts any entry
+func (b *logBatch) fits(raw []byte, max int) bool {
+	// the entries are enclosed in brackets and separated by commas
+	return len(b.entries) == 0 || b.size+len(raw)+len(b.entries)+2 <= max
+}
+
+func (b *logBatch) add(raw []byte) {
+	b.entries = append(b.entries, raw)
+	b.size += len(raw)
+}
+
+func (b *logBatch) encode() []byte {
+	buf := make([]byte, 0, b.size+len(b.entries)+2)
+	buf = append(buf, '[')
+	buf = append(buf, bytes.Join(b.entries, []byte{','})...)
+	return append(buf, ']')
+}
+
+func (b *logBatch) reset() {
+	b.entries = nil
+	b.size = 0
+}
@@ -0,0 +1,119 @@
+package nexagent
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+const logCaptureTruncatedNotice = "\n[captured logs truncated at %d bytes]\n"
+
+// Captures a workload's stdout and stderr into an object in the node's internal workload logs
+// bucket, streaming the output into the object as it is written. Output beyond the maximum size
+// is discarded, and the object is only complete once the capture is closed
+type logCapture struct {
+	maxBytes int64
+
+	mutex     sync.Mutex
+	writer    *io.PipeWriter
+	written   int64
+	truncated bool
+
+	closeOnce sync.Once
+	done      chan struct{}
+	err       error
+}
+
+// Starts capturing the stdout and stderr of the workload deployed by the given request, if the
+// request asks for them. A failure to start capturing is logged rather than failing the deploy
+func (a *Agent) startLogCapture(request *agentapi.DeployRequest) *logCapture {
+	if request.LogCaptureKey == nil {
+		return nil
+	}
+
+	bucket, err := a.js.ObjectStore(agentapi.WorkloadLogsBucket)
+	if err != nil {
+		a.LogError(fmt.Sprintf("Failed to open workload logs bucket; logs will not be captured: %s", err))
+		return nil
+	}
+
+	return newLogCapture(bucket, *request.LogCaptureKey, request.LogCaptureMaxBytes)
+}
+
+// Completes the capture of the given workload's stdout and stderr, if any
+func (a *Agent) finishLogCapture(w *deployedWorkload) {
+	if w.capture == nil {
+		return
+	}
+
+	err := w.capture.Close()
+	if err != nil {
+		a.LogError(fmt.Sprintf("Failed to capture logs of workload %s: %s", w.id, err))
+	}
+}
+
+func newLogCapture(bucket nats.ObjectStore, key string, maxBytes int64) *logCapture {
+	reader, writer := io.Pipe()
+	c := &logCapture{
+		maxBytes: maxBytes,
+		writer:   writer,
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(c.done)
+
+		_, c.err = bucket.Put(&nats.ObjectMeta{
+			Name:        key,
+			Description: "Captured workload stdout and stderr",
+		}, reader)
+
+		// fails any further writes rather than blocking them, should the object not be written
+		_ = reader.CloseWithError(c.err)
+	}()
+
+	return c
+}
+
+// Writes the given output into the captured object, discarding whatever exceeds its maximum size.
+// Writes block until the output is read into the object
+func (c *logCapture) Write(p []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.truncated {
+		return len(p), nil
+	}
+
+	if c.maxBytes > 0 && c.written+int64(len(p)) > c.maxBytes {
+		c.truncated = true
+		_, _ = c.writer.Write(p[:c.maxBytes-c.written])
+		_, _ = fmt.Fprintf(c.writer, logCaptureTruncatedNotice, c.maxBytes)
+		c.written = c.maxBytes
+		return len(p), nil
+	}
+
+	n, err := c.writer.Write(p)
+	c.written += int64(n)
+	return n, err
+}
+
+// Ends the captured output and waits for its object to be written, returning the failure to write
+// it, if any, to the first caller only
+func (c *logCapture) Close() error {
+	closed := false
+	c.closeOnce.Do(func() {
+		closed = true
+		_ = c.writer.Close()
+	})
+
+	<-c.done
+	if !closed {
+		return nil
+	}
+
+	return c.err
+}
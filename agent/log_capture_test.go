@@ -0,0 +1,130 @@
+package nexagent
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+func startLogCaptureTestBucket(t *testing.T) nats.ObjectStore {
+	ns, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create NATS server: %s", err)
+	}
+	ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server failed to start")
+	}
+
+	nc, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		t.Fatalf("failed to connect to NATS server: %s", err)
+	}
+	t.Cleanup(func() {
+		nc.Close()
+		ns.Shutdown()
+	})
+
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bucket, err := js.CreateObjectStore(&nats.ObjectStoreConfig{Bucket: agentapi.WorkloadLogsBucket})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bucket
+}
+
+func TestLogCaptureWritesWorkloadOutputToObject(t *testing.T) {
+	bucket := startLogCaptureTestBucket(t)
+
+	capture := newLogCapture(bucket, "default/vm1", 1024)
+	stdout := &logEmitter{name: "echo", logs: make(chan *agentapi.LogEntry, 10), capture: capture}
+	stderr := &logEmitter{name: "echo", stderr: true, logs: make(chan *agentapi.LogEntry, 10), capture: capture}
+
+	_, _ = stdout.Write([]byte("hello\n"))
+	_, _ = stderr.Write([]byte("oops\n"))
+	_, _ = stdout.Write([]byte("goodbye\n"))
+
+	err := capture.Close()
+	if err != nil {
+		t.Fatalf("expected the capture to complete, got %s", err)
+	}
+	if capture.Close() != nil {
+		t.Fatal("expected closing the capture again to be a no-op")
+	}
+
+	data, err := bucket.GetBytes("default/vm1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello\noops\ngoodbye\n" {
+		t.Fatalf("expected the workload's interleaved output to be captured, got %q", data)
+	}
+	if len(stdout.logs) != 2 || len(stderr.logs) != 1 {
+		t.Fatal("expected the output to still be forwarded as log entries")
+	}
+}
+
+func TestLogCaptureTruncatesOutputBeyondMaximum(t *testing.T) {
+	bucket := startLogCaptureTestBucket(t)
+
+	capture := newLogCapture(bucket, "default/vm1", 16)
+	for i := 0; i < 10; i++ {
+		n, err := capture.Write([]byte("0123456789"))
+		if err != nil || n != 10 {
+			t.Fatalf("expected writes beyond the maximum to be discarded quietly, got %d, %v", n, err)
+		}
+	}
+
+	err := capture.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := bucket.GetBytes("default/vm1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "0123456789012345" + fmt.Sprintf(logCaptureTruncatedNotice, 16)
+	if string(data) != expected {
+		t.Fatalf("expected output to be truncated at 16 bytes, got %q", data)
+	}
+}
+
+func TestLogCaptureDoesNotBlockWorkloadWhenObjectCannotBeWritten(t *testing.T) {
+	bucket := startLogCaptureTestBucket(t)
+
+	// object names may not be empty, so the object is never written
+	capture := newLogCapture(bucket, "", 1024)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = capture.Write([]byte(strings.Repeat("x", 128)))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected writes to fail rather than block once the object cannot be written")
+	}
+
+	if capture.Close() == nil {
+		t.Fatal("expected the failure to write the object to be returned")
+	}
+}
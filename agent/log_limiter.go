@@ -0,0 +1,80 @@
+package nexagent
+
+import (
+	"sync"
+	"time"
+)
+
+// Interval after which the log lines dropped by a workload's log rate limit are reported
+const logDropReportInterval = time.Second
+
+// Token bucket limiting the rate at which a workload's log lines are forwarded to the node. Lines
+// beyond the rate are dropped, and the number dropped is reported once per report interval while
+// lines are being dropped
+type logLimiter struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	dropped   uint64
+	reporting bool
+	report    func(dropped uint64)
+}
+
+// Returns a limiter forwarding the given number of lines per second, and a burst of the given
+// number above that rate, defaulting to the rate itself. Returns nil if no rate is given, in which
+// case every line is forwarded
+func newLogLimiter(rate, burst *int, report func(dropped uint64)) *logLimiter {
+	if rate == nil || *rate <= 0 {
+		return nil
+	}
+
+	size := *rate
+	if burst != nil && *burst > 0 {
+		size = *burst
+	}
+
+	return &logLimiter{
+		rate:   float64(*rate),
+		burst:  float64(size),
+		tokens: float64(size),
+		report: report,
+	}
+}
+
+// Takes a token for a log line written at the given time, returning false if the line must be
+// dropped. The first line dropped since drops were last reported schedules the next report
+func (l *logLimiter) allow(now time.Time) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return true
+	}
+
+	l.dropped++
+	if !l.reporting {
+		l.reporting = true
+		time.AfterFunc(logDropReportInterval, l.reportDropped)
+	}
+
+	return false
+}
+
+func (l *logLimiter) reportDropped() {
+	l.mutex.Lock()
+	dropped := l.dropped
+	l.dropped = 0
+	l.reporting = false
+	l.mutex.Unlock()
+
+	l.report(dropped)
+}
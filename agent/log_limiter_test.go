@@ -0,0 +1,45 @@
+package nexagent
+
+import (
+	"testing"
+	"time"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+func TestLogLimiterDropsLinesBeyondRateAndReportsThem(t *testing.T) {
+	if newLogLimiter(nil, nil, nil) != nil {
+		t.Fatal("expected no limiter without a rate")
+	}
+
+	rate, burst := 10, 3
+	logs := make(chan *agentapi.LogEntry, 16)
+	emitter := &logEmitter{name: "echo", stderr: true, logs: logs, done: make(chan struct{})}
+	emitter.limiter = newLogLimiter(&rate, &burst, emitter.reportDropped)
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		_, _ = emitter.Write([]byte("line"))
+	}
+	if len(logs) != burst {
+		t.Fatalf("expected a burst of %d lines to be forwarded, got %d", burst, len(logs))
+	}
+
+	// a second later the bucket has been refilled at the configured rate
+	if !emitter.limiter.allow(now.Add(time.Second)) {
+		t.Fatal("expected tokens to be refilled at the configured rate")
+	}
+
+	for len(logs) > 0 {
+		<-logs
+	}
+
+	select {
+	case entry := <-logs:
+		if entry.Dropped != 2 || entry.Level != agentapi.LogLevelWarn || entry.Source != "echo" {
+			t.Fatalf("unexpected drop notice %+v", entry)
+		}
+	case <-time.After(2 * logDropReportInterval):
+		t.Fatal("expected the dropped lines to be reported")
+	}
+}
@@ -3,7 +3,10 @@ package nexagent
 import (
 	"fmt"
 	"os"
+	"sync/atomic"
+	"time"
 
+	controlapi "github.com/synadia-io/nex/control-api"
 	agentapi "github.com/synadia-io/nex/internal/agent-api"
 )
 
@@ -16,10 +19,30 @@ type logEmitter struct {
 	stderr bool
 
 	logs chan *agentapi.LogEntry
+	done <-chan struct{}
+
+	// Rate limit shared by the workload's stdout and stderr, if any
+	limiter *logLimiter
+
+	// Capture of the workload's stdout and stderr, which receives all of its output regardless of
+	// its rate limit, if any
+	capture *logCapture
+
+	written atomic.Int64 // bytes written by the workload
 }
 
 // Write arbitrary bytes to the underlying log emitter
 func (l *logEmitter) Write(bytes []byte) (int, error) {
+	l.written.Add(int64(len(bytes)))
+
+	if l.capture != nil {
+		_, _ = l.capture.Write(bytes)
+	}
+
+	if l.limiter != nil && !l.limiter.allow(time.Now()) {
+		return len(bytes), nil
+	}
+
 	var lvl agentapi.LogLevel
 	if l.stderr {
 		lvl = agentapi.LogLevelError
@@ -27,16 +50,35 @@ func (l *logEmitter) Write(bytes []byte) (int, error) {
 		lvl = agentapi.LogLevelInfo
 	}
 
-	l.logs <- &agentapi.LogEntry{
-		Level:  lvl,
-		Source: l.name,
-		Text:   string(bytes),
+	select {
+	case l.logs <- &agentapi.LogEntry{
+		Level:     lvl,
+		Source:    l.name,
+		Text:      string(bytes),
+		Timestamp: time.Now().UTC(),
+	}:
+	case <-l.done:
+		// the agent is shutting down and logs can no longer be dispatched
 	}
 
 	// FIXME-- this never returns an error
 	return len(bytes), nil
 }
 
+// Forwards a notice of the number of the workload's log lines dropped by its log rate limit
+func (l *logEmitter) reportDropped(dropped uint64) {
+	select {
+	case l.logs <- &agentapi.LogEntry{
+		Level:     agentapi.LogLevelWarn,
+		Source:    l.name,
+		Text:      fmt.Sprintf("%d log lines dropped by rate limit", dropped),
+		Timestamp: time.Now().UTC(),
+		Dropped:   dropped,
+	}:
+	case <-l.done:
+	}
+}
+
 func (a *Agent) LogDebug(msg string) {
 	fmt.Fprintln(os.Stdout, msg)
 	if a.sandboxed {
@@ -60,36 +102,48 @@ func (a *Agent) LogInfo(msg string) {
 
 // FIXME-- revisit error handling
 func (a *Agent) PublishWorkloadDeployed(vmID, workloadName string, totalBytes int64) {
-	a.agentLogs <- &agentapi.LogEntry{
+	a.enqueueLog(&agentapi.LogEntry{
 		Source: NexEventSourceNexAgent,
 		Level:  agentapi.LogLevelInfo,
 		Text:   fmt.Sprintf("Workload %s deployed", workloadName),
-	}
+	})
 
 	evt := agentapi.NewAgentEvent(vmID, agentapi.WorkloadStartedEventType, agentapi.WorkloadStatusEvent{WorkloadName: workloadName})
-	a.eventLogs <- &evt
+	a.enqueueEvent(&evt)
+}
+
+// PublishWorkloadOutOfMemory publishes that the workload was killed for exceeding its memory limit
+func (a *Agent) PublishWorkloadOutOfMemory(vmID, workloadName string, memoryLimitMib int) {
+	a.enqueueLog(&agentapi.LogEntry{
+		Source: NexEventSourceNexAgent,
+		Level:  agentapi.LogLevelError,
+		Text:   fmt.Sprintf("Workload %s killed for exceeding its memory limit of %d MiB", workloadName, memoryLimitMib),
+	})
+
+	evt := agentapi.NewAgentEvent(vmID, agentapi.WorkloadOutOfMemoryEventType, controlapi.WorkloadOutOfMemoryEvent{Name: workloadName, MemoryLimitMib: memoryLimitMib})
+	a.enqueueEvent(&evt)
 }
 
-// PublishWorkloadExited publishes a workload failed or stopped message
+// PublishWorkloadExited publishes a workload failed or stopped message, carrying the given status
 // FIXME-- revisit error handling
-func (a *Agent) PublishWorkloadExited(vmID, workloadName, message string, err bool, code int) {
+func (a *Agent) PublishWorkloadExited(vmID string, status agentapi.WorkloadStatusEvent) {
 	level := agentapi.LogLevelInfo
-	if err {
+	if status.Code != 0 {
 		level = agentapi.LogLevelError
 	}
 
 	// FIXME-- this hack is here to get things working... refactor me
-	txt := fmt.Sprintf("Workload %s exited", workloadName)
-	if code == -1 {
-		txt = fmt.Sprintf("Workload %s failed to deploy", workloadName)
+	txt := fmt.Sprintf("Workload %s exited", status.WorkloadName)
+	if status.Code == -1 {
+		txt = fmt.Sprintf("Workload %s failed to deploy", status.WorkloadName)
 	}
 
-	a.agentLogs <- &agentapi.LogEntry{
+	a.enqueueLog(&agentapi.LogEntry{
 		Source: NexEventSourceNexAgent,
 		Level:  agentapi.LogLevel(level),
 		Text:   txt,
-	}
+	})
 
-	evt := agentapi.NewAgentEvent(vmID, agentapi.WorkloadStoppedEventType, agentapi.WorkloadStatusEvent{WorkloadName: workloadName, Code: code, Message: message})
-	a.eventLogs <- &evt
+	evt := agentapi.NewAgentEvent(vmID, agentapi.WorkloadStoppedEventType, status)
+	a.enqueueEvent(&evt)
 }
@@ -1,6 +1,7 @@
 package nexagent
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	agentapi "github.com/synadia-io/nex/internal/agent-api"
@@ -21,10 +23,109 @@ const nexEnvSandbox = "NEX_SANDBOX"
 const nexEnvWorkloadID = "NEX_WORKLOADID"
 const nexEnvNodeNatsHost = "NEX_NODE_NATS_HOST"
 const nexEnvNodeNatsPort = "NEX_NODE_NATS_PORT"
+const nexEnvNodeNatsToken = "NEX_NODE_NATS_TOKEN"
+const nexEnvWorkloadNatsToken = "NEX_WORKLOAD_NATS_TOKEN"
+const nexEnvPluginPath = "NEX_PLUGIN_PATH"
+const nexEnvTracesEnabled = "NEX_TRACES_ENABLED"
+const nexEnvAgentUpdatePublicKey = "NEX_AGENT_UPDATE_PUBLIC_KEY"
+const nexEnvHeartbeatInterval = "NEX_HEARTBEAT_INTERVAL_MS"
+const nexEnvStopGracePeriod = "NEX_STOP_GRACE_PERIOD_MS"
+const nexEnvMaxWorkloads = "NEX_MAX_WORKLOADS"
+const nexEnvEnvironmentKey = "NEX_ENVIRONMENT_KEY"
+const nexEnvRequireSealedEnvironment = "NEX_REQUIRE_SEALED_ENVIRONMENT"
+const nexEnvLogRateLimit = "NEX_LOG_RATE_LIMIT"
+const nexEnvLogBurst = "NEX_LOG_BURST"
+const nexEnvMetadataSource = "NEX_METADATA_SOURCE"
+const nexEnvMetadataFile = "NEX_METADATA_FILE"
+
+// Kernel boot args selecting the metadata source, consulted when the corresponding
+// environment variables are unset
+const bootArgMetadataSource = "nex.metadata_source"
+const bootArgMetadataFile = "nex.metadata_file"
+
+const (
+	MetadataSourceEnv  = "env"
+	MetadataSourceFile = "file"
+	MetadataSourceMMDS = "mmds"
+)
+
+// Path of the metadata file read by the file metadata source when none is specified
+const DefaultMetadataFile = "/etc/nex/metadata.json"
 
 const metadataClientTimeoutMillis = 50
 const metadataPollingTimeoutMillis = 5000
 
+// A metadata source supplies the agent with the metadata of the machine in which it is running,
+// such as the address of the node's internal NATS server
+type MetadataSource interface {
+	// Name of the metadata source, e.g. mmds
+	Name() string
+
+	// Retrieves the machine metadata; the metadata is validated by the caller
+	GetMachineMetadata() (*agentapi.MachineMetadata, error)
+}
+
+type mmdsMetadataSource struct{}
+
+func (mmdsMetadataSource) Name() string { return MetadataSourceMMDS }
+
+func (mmdsMetadataSource) GetMachineMetadata() (*agentapi.MachineMetadata, error) {
+	return GetMachineMetadata()
+}
+
+type envMetadataSource struct{}
+
+func (envMetadataSource) Name() string { return MetadataSourceEnv }
+
+func (envMetadataSource) GetMachineMetadata() (*agentapi.MachineMetadata, error) {
+	return GetMachineMetadataFromEnv()
+}
+
+type fileMetadataSource struct {
+	path string
+}
+
+func (f fileMetadataSource) Name() string { return MetadataSourceFile }
+
+func (f fileMetadataSource) GetMachineMetadata() (*agentapi.MachineMetadata, error) {
+	return GetMachineMetadataFromFile(f.path)
+}
+
+// ResolveMetadataSource selects the metadata source named by the NEX_METADATA_SOURCE environment
+// variable or, failing that, the nex.metadata_source kernel boot arg. When neither is set, metadata
+// is read from the environment of an agent running without a sandbox and from firecracker's MMDS otherwise
+func ResolveMetadataSource() (MetadataSource, error) {
+	bootArgs := readBootArgs()
+
+	source := os.Getenv(nexEnvMetadataSource)
+	if source == "" {
+		source = bootArgs[bootArgMetadataSource]
+	}
+
+	switch strings.ToLower(source) {
+	case "":
+		if !isSandboxed() {
+			return envMetadataSource{}, nil
+		}
+		return mmdsMetadataSource{}, nil
+	case MetadataSourceMMDS:
+		return mmdsMetadataSource{}, nil
+	case MetadataSourceEnv:
+		return envMetadataSource{}, nil
+	case MetadataSourceFile:
+		path := os.Getenv(nexEnvMetadataFile)
+		if path == "" {
+			path = bootArgs[bootArgMetadataFile]
+		}
+		if path == "" {
+			path = DefaultMetadataFile
+		}
+		return fileMetadataSource{path: path}, nil
+	default:
+		return nil, fmt.Errorf("unknown metadata source %q; must be one of %s, %s or %s", source, MetadataSourceMMDS, MetadataSourceFile, MetadataSourceEnv)
+	}
+}
+
 // GetMachineMetadata attempts to retrieve metadata from firecracker's MMDS.
 // Version of 2 this service requires the acuisition of a token and the use
 // of that token for all requests. Note that metadata is PUT into a running
@@ -72,20 +173,120 @@ func GetMachineMetadataFromEnv() (*agentapi.MachineMetadata, error) {
 	host := os.Getenv(nexEnvNodeNatsHost)
 	port := os.Getenv(nexEnvNodeNatsPort)
 	msg := "Metadata obtained from no-sandbox environment"
-	p, err := strconv.Atoi(port)
+
+	var p *int
+	if port != "" {
+		portNum, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", nexEnvNodeNatsPort, err)
+		}
+		p = &portNum
+	}
+
+	heartbeatInterval, err := intFromEnv(nexEnvHeartbeatInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	stopGracePeriod, err := intFromEnv(nexEnvStopGracePeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	maxWorkloads, err := intFromEnv(nexEnvMaxWorkloads)
+	if err != nil {
+		return nil, err
+	}
+
+	logRateLimit, err := intFromEnv(nexEnvLogRateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	logBurst, err := intFromEnv(nexEnvLogBurst)
 	if err != nil {
-		fmt.Println("Bad port number for internat NATS server")
 		return nil, err
 	}
 
+	var environmentKey []byte
+	if value := os.Getenv(nexEnvEnvironmentKey); value != "" {
+		environmentKey, err = base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", nexEnvEnvironmentKey, err)
+		}
+	}
+
 	return &agentapi.MachineMetadata{
-		VmID:         &vmid,
-		NodeNatsHost: &host,
-		NodeNatsPort: &p,
-		Message:      &msg,
+		VmID:                         agentapi.StringOrNil(vmid),
+		NodeNatsHost:                 agentapi.StringOrNil(host),
+		NodeNatsPort:                 p,
+		NodeNatsToken:                agentapi.StringOrNil(os.Getenv(nexEnvNodeNatsToken)),
+		WorkloadNatsToken:            agentapi.StringOrNil(os.Getenv(nexEnvWorkloadNatsToken)),
+		Message:                      &msg,
+		PluginPath:                   agentapi.StringOrNil(os.Getenv(nexEnvPluginPath)),
+		TracesEnabled:                strings.EqualFold(os.Getenv(nexEnvTracesEnabled), "true"),
+		AgentUpdatePublicKey:         agentapi.StringOrNil(os.Getenv(nexEnvAgentUpdatePublicKey)),
+		HeartbeatIntervalMillisecond: heartbeatInterval,
+		StopGracePeriodMillisecond:   stopGracePeriod,
+		MaxWorkloads:                 maxWorkloads,
+		EnvironmentKey:               environmentKey,
+		RequireSealedEnvironment:     strings.EqualFold(os.Getenv(nexEnvRequireSealedEnvironment), "true"),
+		LogRateLimit:                 logRateLimit,
+		LogBurst:                     logBurst,
 	}, nil
 }
 
+// Returns the integer held by the given environment variable, or nil if it is not set
+func intFromEnv(name string) (*int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", name, err)
+	}
+
+	return &i, nil
+}
+
+// GetMachineMetadataFromFile reads metadata from the JSON file at the given path, in the
+// same format in which it is served by firecracker's MMDS
+func GetMachineMetadataFromFile(path string) (*agentapi.MachineMetadata, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata file: %s", err)
+	}
+
+	var metadata agentapi.MachineMetadata
+	err = json.Unmarshal(raw, &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize metadata file %s: %s", path, err)
+	}
+
+	return &metadata, nil
+}
+
+// Returns the key=value kernel boot args, or none if the kernel command line cannot be read
+func readBootArgs() map[string]string {
+	args := make(map[string]string)
+
+	cmdline, err := os.ReadFile("/proc/cmdline")
+	if err != nil {
+		return args
+	}
+
+	for _, field := range strings.Fields(string(cmdline)) {
+		key, value, ok := strings.Cut(field, "=")
+		if ok {
+			args[key] = value
+		}
+	}
+
+	return args
+}
+
 func performMetadataQuery(req *http.Request, client *http.Client) (*agentapi.MachineMetadata, error) {
 	resp, err := client.Do(req)
 	if err != nil {
@@ -0,0 +1,34 @@
+package nexagent
+
+import (
+	"fmt"
+	"net/netip"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+// Link-local IPv4 address given to the interface of a machine on an IPv6 network, through which
+// the agent reaches firecracker's MMDS at MmdsAddress
+const mmdsLinkLocalAddress = "169.254.0.2/16"
+
+// Configures the machine's network interface with the IPv6 address and gateway passed by the node
+// in the given boot args, if any. The interfaces of machines on IPv4 networks are configured by the
+// kernel, as per the ip= boot arg
+func configureIPv6Network(bootArgs map[string]string) error {
+	address, ok := bootArgs[agentapi.BootArgIPv6Address]
+	if !ok {
+		return nil
+	}
+
+	prefix, err := netip.ParsePrefix(address)
+	if err != nil || !prefix.Addr().Is6() {
+		return fmt.Errorf("invalid IPv6 address %q", address)
+	}
+
+	gateway, err := netip.ParseAddr(bootArgs[agentapi.BootArgIPv6Gateway])
+	if err != nil || !gateway.Is6() {
+		return fmt.Errorf("invalid IPv6 gateway %q", bootArgs[agentapi.BootArgIPv6Gateway])
+	}
+
+	return configureInterface(prefix, gateway)
+}
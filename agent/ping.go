@@ -0,0 +1,35 @@
+package nexagent
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+// Answers a ping from the node with the agent's uptime, the uptime of each of its workloads and
+// the time at which its deployed function was last executed, proving that the agent is still alive and responsive
+func (a *Agent) handlePing(msg *nats.Msg) {
+	raw, _ := json.Marshal(a.pong(time.Now().UTC()))
+
+	err := msg.Respond(raw)
+	if err != nil {
+		a.LogError(fmt.Sprintf("Failed to respond to ping: %s", err))
+	}
+}
+
+func (a *Agent) pong(now time.Time) *agentapi.AgentPingResponse {
+	pong := &agentapi.AgentPingResponse{
+		UptimeMillisecond:         now.Sub(a.started).Milliseconds(),
+		WorkloadUptimeMillisecond: a.workloadUptimes(now),
+	}
+
+	if at := a.lastExec.Load(); at != 0 {
+		lastExec := time.Unix(0, at).UTC()
+		pong.LastExecAt = &lastExec
+	}
+
+	return pong
+}
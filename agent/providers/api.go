@@ -3,6 +3,7 @@ package providers
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/synadia-io/nex/agent/providers/lib"
 	agentapi "github.com/synadia-io/nex/internal/agent-api"
@@ -38,6 +39,34 @@ type ExecutionProvider interface {
 	Validate() error
 }
 
+// ReadinessReporter is implemented by execution providers whose workloads
+// become ready to receive triggers some time after they are deployed. The
+// agent reports the workload ready once AwaitReady returns without error;
+// workloads of other providers are ready as soon as they are deployed
+type ReadinessReporter interface {
+	// Block until the deployed workload is ready, or the given context is done
+	AwaitReady(ctx context.Context) error
+}
+
+// GracefulStopper is implemented by execution providers whose workloads can be
+// asked to stop and given time to exit cleanly, e.g. to flush state or close
+// connections, before they are killed. The agent stops such workloads in place
+// of undeploying them
+type GracefulStopper interface {
+	// Signal the deployed workload to stop, killing it if it has not exited once
+	// the given grace period has elapsed, and release its resources as per Undeploy
+	Stop(gracePeriod time.Duration) error
+}
+
+// SignalReporter is implemented by execution providers whose workloads may be
+// terminated by a signal, e.g. native processes, so that the agent can report
+// why such a workload exited
+type SignalReporter interface {
+	// Returns the name of the signal which terminated the workload once it has
+	// exited, e.g. SIGKILL, or an empty string if it exited on its own
+	ExitSignal() string
+}
+
 // NewExecutionProvider initializes and returns an execution provider for a given work request
 func NewExecutionProvider(params *agentapi.ExecutionProviderParams) (ExecutionProvider, error) {
 	if params.WorkloadType == nil {
@@ -55,7 +84,9 @@ func NewExecutionProvider(params *agentapi.ExecutionProviderParams) (ExecutionPr
 	case NexExecutionProviderWasm:
 		return lib.InitNexExecutionProviderWasm(params)
 	default:
-		break
+		if p, ok := registeredPlugins[*params.WorkloadType]; ok {
+			return p.NewExecutionProvider(params)
+		}
 	}
 
 	return nil, errors.New("invalid execution provider specified")
@@ -8,10 +8,12 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
 
+	controlapi "github.com/synadia-io/nex/control-api"
 	agentapi "github.com/synadia-io/nex/internal/agent-api"
 )
 
@@ -23,10 +25,22 @@ type ELF struct {
 	tmpFilename string
 	totalBytes  int64
 	vmID        string
+	workloadID  string
+
+	// optional user and working directory as and in which the workload is run
+	uid        *int
+	gid        *int
+	workingDir *string
+
+	// optional limit on the memory used by the workload, enforced by the cgroup in which it is run
+	memoryLimitMib *int
+	cgroup         *memoryCgroup
 
 	fail     chan bool
 	run      chan bool
 	exit     chan int
+	exited   chan struct{} // closed once the workload process has exited
+	signal   string        // name of the signal which terminated the workload process, if any
 	undeploy sync.Once
 
 	cmd *exec.Cmd
@@ -40,7 +54,26 @@ func (e *ELF) Deploy() error {
 	cmd := exec.Command(e.tmpFilename, e.argv...)
 	cmd.Stdout = e.stdout
 	cmd.Stderr = e.stderr
-	cmd.SysProcAttr = e.sysProcAttr()
+
+	attr, err := e.sysProcAttr()
+	if err != nil {
+		e.fail <- true
+		return err
+	}
+
+	if e.memoryLimitMib != nil {
+		e.cgroup, err = newMemoryCgroup(e.workloadID, *e.memoryLimitMib)
+		if err != nil {
+			e.fail <- true
+			return err
+		}
+		e.cgroup.apply(attr)
+	}
+	cmd.SysProcAttr = attr
+
+	if e.workingDir != nil {
+		cmd.Dir = *e.workingDir
+	}
 
 	cmd.Env = make([]string, len(e.environment))
 	for k, v := range e.environment {
@@ -48,8 +81,11 @@ func (e *ELF) Deploy() error {
 		cmd.Env = append(cmd.Env, item)
 	}
 
-	err := cmd.Start()
+	err = cmd.Start()
 	if err != nil {
+		if e.cgroup != nil {
+			e.cgroup.remove()
+		}
 		e.fail <- true
 		return err
 	}
@@ -71,18 +107,60 @@ func (e *ELF) Deploy() error {
 		}()
 
 		// This has to be backgrounded because the workload could be a long-running process/service
-		if err = cmd.Wait(); err != nil { // blocking until exit
-			if exitError, ok := err.(*exec.ExitError); ok {
-				e.exit <- exitError.ExitCode() // this is here for now for review but can likely be simplified to one line: `e.exit <- cmd.ProcessState.ExitCode()``
+		_ = cmd.Wait() // blocking until exit
+		close(e.exited)
+		if cmd.ProcessState != nil {
+			code := exitCode(cmd.ProcessState)
+			e.signal = exitSignal(cmd.ProcessState)
+			if e.cgroup != nil {
+				if e.cgroup.oomKilled() {
+					code = controlapi.ExitCodeOutOfMemory
+				}
+				e.cgroup.remove()
 			}
-		} else {
-			e.exit <- cmd.ProcessState.ExitCode()
+
+			e.exit <- code
 		}
 	}()
 
 	return nil
 }
 
+// Stop the ELF binary, asking its process to terminate and killing it if it has
+// not exited once the given grace period has elapsed
+func (e *ELF) Stop(gracePeriod time.Duration) error {
+	var err error
+	e.undeploy.Do(func() {
+		defer e.removeWorkload()
+
+		if e.cmd == nil || e.cmd.Process == nil {
+			return
+		}
+
+		err = e.terminate()
+		if err == nil {
+			select {
+			case <-e.exited:
+				return
+			case <-time.After(gracePeriod):
+				_, _ = e.stderr.Write([]byte(fmt.Sprintf("elf binary process did not exit within %s; killing it", gracePeriod)))
+			}
+		}
+
+		err = e.cmd.Process.Kill()
+		if errors.Is(err, os.ErrProcessDone) {
+			err = nil
+		}
+	})
+
+	return err
+}
+
+// Returns the name of the signal which terminated the workload process, if any, once it has exited
+func (e *ELF) ExitSignal() string {
+	return e.signal
+}
+
 func (e *ELF) removeWorkload() {
 	_ = os.Remove(e.tmpFilename)
 }
@@ -92,8 +170,12 @@ func (e *ELF) Execute(ctx context.Context, payload []byte) ([]byte, error) {
 }
 
 // Validate the underlying artifact to be a 64-bit linux native ELF
-// binary that is statically-linked
+// binary that is statically-linked or, on a darwin host, a Mach-O executable
 func (e *ELF) Validate() error {
+	if strings.EqualFold(runtime.GOOS, "darwin") {
+		return validateMachOBinary(e.tmpFilename)
+	}
+
 	return validateNativeBinary(e.tmpFilename)
 }
 
@@ -118,13 +200,21 @@ func InitNexExecutionProviderELF(params *agentapi.ExecutionProviderParams) (*ELF
 		tmpFilename: *params.TmpFilename,
 		totalBytes:  params.TotalBytes,
 		vmID:        params.VmID,
+		workloadID:  params.ResolveWorkloadID(params.VmID),
+
+		uid:        params.Uid,
+		gid:        params.RunAsGid(),
+		workingDir: params.WorkingDirectory,
+
+		memoryLimitMib: params.MemoryLimitMib,
 
 		stderr: params.Stderr,
 		stdout: params.Stdout,
 
-		fail: params.Fail,
-		run:  params.Run,
-		exit: params.Exit,
+		fail:   params.Fail,
+		run:    params.Run,
+		exit:   params.Exit,
+		exited: make(chan struct{}),
 	}, nil
 }
 
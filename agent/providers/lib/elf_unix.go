@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"syscall"
+
+	"golang.org/x/sys/unix"
 )
 
 // Undeploy the ELF binary
@@ -22,6 +24,41 @@ func (e *ELF) Undeploy() error {
 	return nil
 }
 
-func (e *ELF) sysProcAttr() *syscall.SysProcAttr {
-	return &syscall.SysProcAttr{}
+// Asks the workload process to terminate
+func (e *ELF) terminate() error {
+	return e.cmd.Process.Signal(syscall.SIGTERM)
+}
+
+// Returns the process attributes of the workload, which drops to the requested
+// uid and gid, without any supplementary groups, when a user is specified
+func (e *ELF) sysProcAttr() (*syscall.SysProcAttr, error) {
+	attr := &syscall.SysProcAttr{}
+	if e.uid != nil {
+		attr.Credential = &syscall.Credential{
+			Uid:    uint32(*e.uid),
+			Gid:    uint32(*e.gid),
+			Groups: []uint32{},
+		}
+	}
+
+	return attr, nil
+}
+
+// Returns the exit status of the exited process; processes terminated by a signal
+// (e.g. SIGKILL from the OOM killer) follow the shell convention of 128+signal
+func exitCode(state *os.ProcessState) int {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+
+	return state.ExitCode()
+}
+
+// Returns the name of the signal which terminated the exited process, if any
+func exitSignal(state *os.ProcessState) string {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return unix.SignalName(status.Signal())
+	}
+
+	return ""
 }
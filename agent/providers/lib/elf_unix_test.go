@@ -0,0 +1,106 @@
+//go:build !windows
+
+package lib
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Returns an elf execution provider running a copy of sh with the given script, as
+// the provider removes its binary when stopped
+func shellELF(t *testing.T, script string) *ELF {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+
+	binary, err := os.ReadFile(sh)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpFilename := filepath.Join(t.TempDir(), "workload")
+	err = os.WriteFile(tmpFilename, binary, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &ELF{
+		argv:        []string{"-c", script},
+		environment: map[string]string{},
+		tmpFilename: tmpFilename,
+		stderr:      io.Discard,
+		stdout:      io.Discard,
+		fail:        make(chan bool, 1),
+		run:         make(chan bool, 1),
+		exit:        make(chan int, 1),
+		exited:      make(chan struct{}),
+	}
+}
+
+func TestELFStopTerminatesWorkload(t *testing.T) {
+	e := shellELF(t, "trap 'exit 3' TERM; while :; do sleep 0.01; done")
+	err := e.Deploy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-e.run
+	time.Sleep(100 * time.Millisecond) // until the trap is installed
+
+	err = e.Stop(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case code := <-e.exit:
+		if code != 3 {
+			t.Fatalf("expected the workload to exit cleanly with 3, got %d", code)
+		}
+		if e.ExitSignal() != "" {
+			t.Fatalf("expected the workload to have exited on its own, got signal %s", e.ExitSignal())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the workload to have exited")
+	}
+
+	if _, err := os.Stat(e.tmpFilename); !os.IsNotExist(err) {
+		t.Fatal("expected the workload binary to be removed")
+	}
+}
+
+func TestELFStopKillsWorkloadAfterGracePeriod(t *testing.T) {
+	e := shellELF(t, "trap '' TERM; while :; do :; done")
+	err := e.Deploy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-e.run
+	time.Sleep(100 * time.Millisecond)
+
+	started := time.Now()
+	err = e.Stop(200 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed < 200*time.Millisecond {
+		t.Fatalf("expected the workload to be given its grace period, stopped after %s", elapsed)
+	}
+
+	select {
+	case code := <-e.exit:
+		if code != 128+9 {
+			t.Fatalf("expected the workload to be killed, got exit code %d", code)
+		}
+		if e.ExitSignal() != "SIGKILL" {
+			t.Fatalf("expected the workload to have been killed by SIGKILL, got %q", e.ExitSignal())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the workload to have been killed")
+	}
+}
@@ -3,7 +3,9 @@
 package lib
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"syscall"
 
 	"golang.org/x/sys/windows"
@@ -16,33 +18,53 @@ func (e *ELF) Undeploy() error {
 			e.removeWorkload()
 		}()
 
-		dll, err := syscall.LoadDLL("kernel32.dll")
+		err := e.terminate()
 		if err != nil {
 			fmt.Printf("Failed to terminate elf binary process; %s\n", err.Error())
 			e.fail <- true
-			return
 		}
+	})
 
-		p, err := dll.FindProc("GenerateConsoleCtrlEvent")
-		if err != nil {
-			fmt.Printf("Failed to terminate elf binary process; %s\n", err.Error())
-			e.fail <- true
-			return
-		}
+	return nil
+}
 
-		_, _, err = p.Call(syscall.CTRL_BREAK_EVENT, uintptr(e.cmd.Process.Pid)) // err is always non-nil
-		if err != syscall.Errno(0) {
-			fmt.Printf("Failed to terminate elf binary process; %s\n", err.Error())
-			e.fail <- true
-			return
-		}
-	})
+// Asks the workload process, started in its own process group, to terminate by sending
+// it a ctrl+break event
+func (e *ELF) terminate() error {
+	dll, err := syscall.LoadDLL("kernel32.dll")
+	if err != nil {
+		return err
+	}
+
+	p, err := dll.FindProc("GenerateConsoleCtrlEvent")
+	if err != nil {
+		return err
+	}
+
+	_, _, err = p.Call(syscall.CTRL_BREAK_EVENT, uintptr(e.cmd.Process.Pid)) // err is always non-nil
+	if err != syscall.Errno(0) {
+		return err
+	}
 
 	return nil
 }
 
-func (e *ELF) sysProcAttr() *syscall.SysProcAttr {
+func (e *ELF) sysProcAttr() (*syscall.SysProcAttr, error) {
+	if e.uid != nil {
+		return nil, errors.New("running the workload as a given uid is not supported on windows")
+	}
+
 	return &windows.SysProcAttr{
 		CreationFlags: windows.CREATE_NEW_PROCESS_GROUP,
-	}
+	}, nil
+}
+
+// Returns the exit status of the exited process
+func exitCode(state *os.ProcessState) int {
+	return state.ExitCode()
+}
+
+// Processes are not terminated by signals on windows
+func exitSignal(state *os.ProcessState) string {
+	return ""
 }
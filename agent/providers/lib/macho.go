@@ -0,0 +1,29 @@
+package lib
+
+import (
+	"debug/macho"
+	"errors"
+	"fmt"
+)
+
+// Validates that the indicated file is a 64-bit Mach-O executable. Agents only run on darwin
+// hosts without a sandbox, where native workloads are built for the host rather than for linux.
+// Mach-O executables always link the system library dynamically, so they aren't checked for
+// static linking
+func validateMachOBinary(path string) error {
+	machoFile, err := macho.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open source binary: %s", err)
+	}
+	defer machoFile.Close()
+
+	if machoFile.Type != macho.TypeExec {
+		return errors.New("mach-o binary is not an executable")
+	}
+
+	if machoFile.Magic != macho.Magic64 {
+		return errors.New("mach-o binary is not 64-bit")
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package lib
+
+import (
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestValidateMachOBinaryAcceptsOnlyMachOExecutables(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the test binary is built for the host, so it is a Mach-O executable only on darwin
+	err = validateMachOBinary(exe)
+	if runtime.GOOS == "darwin" && err != nil {
+		t.Fatalf("expected a darwin executable to be accepted: %s", err)
+	} else if runtime.GOOS != "darwin" && err == nil {
+		t.Fatalf("expected a %s executable to be rejected", runtime.GOOS)
+	}
+}
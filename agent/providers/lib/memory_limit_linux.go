@@ -0,0 +1,119 @@
+//go:build linux
+
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// A cgroup v2 limiting the memory used by a workload
+type memoryCgroup struct {
+	path string
+	dir  *os.File
+}
+
+// Creates a cgroup for the workload with the given name, limiting its memory to the given size.
+// Swap is disabled so that a workload exceeding its limit is killed by the OOM killer rather
+// than left to thrash, and the whole workload is killed rather than a single one of its processes
+func newMemoryCgroup(name string, limitMib int) (*memoryCgroup, error) {
+	err := mountCgroup2()
+	if err != nil {
+		return nil, fmt.Errorf("failed to mount cgroup2 filesystem: %s", err)
+	}
+
+	err = os.WriteFile(filepath.Join(cgroupRoot, "cgroup.subtree_control"), []byte("+memory"), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable memory controller: %s", err)
+	}
+
+	path := filepath.Join(cgroupRoot, fmt.Sprintf("nex-workload-%s", name))
+	err = os.Mkdir(path, 0755)
+	if err != nil && !errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("failed to create workload cgroup: %s", err)
+	}
+
+	limit := strconv.FormatInt(int64(limitMib)*1024*1024, 10)
+	err = os.WriteFile(filepath.Join(path, "memory.max"), []byte(limit), 0)
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, fmt.Errorf("failed to set workload memory limit: %s", err)
+	}
+
+	// absent when the kernel does not account for swap, in which case there is none to disable
+	err = os.WriteFile(filepath.Join(path, "memory.swap.max"), []byte("0"), 0)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		_ = os.Remove(path)
+		return nil, fmt.Errorf("failed to disable workload swap: %s", err)
+	}
+
+	_ = os.WriteFile(filepath.Join(path, "memory.oom.group"), []byte("1"), 0)
+
+	dir, err := os.Open(path)
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+
+	return &memoryCgroup{path: path, dir: dir}, nil
+}
+
+// Mounts the cgroup2 filesystem unless it is already mounted
+func mountCgroup2() error {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err == nil {
+		return nil
+	}
+
+	err := os.MkdirAll(cgroupRoot, 0755)
+	if err != nil {
+		return err
+	}
+
+	return syscall.Mount("cgroup2", cgroupRoot, "cgroup2", 0, "")
+}
+
+// Starts the process with the given attributes within the cgroup, so that it is limited from
+// its first instruction
+func (c *memoryCgroup) apply(attr *syscall.SysProcAttr) {
+	attr.UseCgroupFD = true
+	attr.CgroupFD = int(c.dir.Fd())
+}
+
+// Returns true if the OOM killer has killed a process within the cgroup
+func (c *memoryCgroup) oomKilled() bool {
+	events, err := os.ReadFile(filepath.Join(c.path, "memory.events"))
+	if err != nil {
+		return false
+	}
+
+	return oomKills(events) > 0
+}
+
+// Removes the cgroup once the workload has exited
+func (c *memoryCgroup) remove() {
+	_ = c.dir.Close()
+	_ = os.Remove(c.path)
+}
+
+// Returns the number of processes killed by the OOM killer recorded in the given memory.events
+func oomKills(events []byte) int {
+	scanner := bufio.NewScanner(bytes.NewReader(events))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if ok && key == "oom_kill" {
+			kills, _ := strconv.Atoi(value)
+			return kills
+		}
+	}
+
+	return 0
+}
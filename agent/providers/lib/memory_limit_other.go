@@ -0,0 +1,23 @@
+//go:build !linux
+
+package lib
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Workload memory limits are enforced with cgroups, which are only available on linux
+type memoryCgroup struct{}
+
+func newMemoryCgroup(string, int) (*memoryCgroup, error) {
+	return nil, errors.New("workload memory limits are only supported on linux")
+}
+
+func (c *memoryCgroup) apply(*syscall.SysProcAttr) {}
+
+func (c *memoryCgroup) oomKilled() bool {
+	return false
+}
+
+func (c *memoryCgroup) remove() {}
@@ -12,15 +12,20 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 
 	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
 	hostservices "github.com/synadia-io/nex/host-services"
 	"github.com/synadia-io/nex/host-services/builtins"
 	agentapi "github.com/synadia-io/nex/internal/agent-api"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	v8 "rogchap.com/v8go"
 )
 
@@ -44,6 +49,9 @@ const (
 	// NOTE: individual timeout values will be supported again after we add
 	// per-service configuration details to the machine config file
 
+	hostServicesMetricsObjectName         = "metrics"
+	hostServicesMetricsRecordFunctionName = "record"
+
 	hostServicesMessagingObjectName              = "messaging"
 	hostServicesMessagingPublishFunctionName     = "publish"
 	hostServicesMessagingRequestFunctionName     = "request"
@@ -55,6 +63,9 @@ const (
 	hostServicesObjectStoreDeleteFunctionName = "delete"
 	hostServicesObjectStoreListFunctionName   = "list"
 
+	hostServicesTriggerObjectName       = "trigger"
+	hostServicesTriggerEmitFunctionName = "emit"
+
 	v8FunctionArrayAppend        = "array-append"
 	v8FunctionArrayInit          = "array-init"
 	v8FunctionUInt8ArrayInit     = "uint8-array-init"
@@ -65,6 +76,8 @@ const (
 	v8MaxFileSizeBytes       = int64(12288) // arbitrarily ~12K, for now
 )
 
+var errV8ExecutionTimedOut = errors.New("v8 execution timed out")
+
 // V8 execution provider implementation
 type V8 struct {
 	environment map[string]string
@@ -73,11 +86,16 @@ type V8 struct {
 	tmpFilename string
 	totalBytes  int32
 	vmID        string
+	workloadID  string
+
+	allowedTriggerSubjects []string
 
 	fail chan bool
 	run  chan bool
 	exit chan int
 
+	executed func() // called after each execution, if set
+
 	stderr io.Writer
 	stdout io.Writer
 
@@ -85,27 +103,83 @@ type V8 struct {
 
 	nc *nats.Conn // agent NATS connection
 
+	sub        *nats.Subscription // trigger subscription, once deployed
+	executions sync.WaitGroup     // executions in flight
+
 	ctx   *v8.Context // default context for internal use only
 	iso   *v8.Isolate
 	ubs   *v8.UnboundScript
 	utils map[string]*v8.Function //v8.UnboundScript
 }
 
+// Returns true if the workload may be triggered on the subject on which the given trigger was received
+func (v *V8) permitsTrigger(msg *nats.Msg) bool {
+	return agentapi.TriggerSubjectAllowed(v.allowedTriggerSubjects, msg.Header.Get(agentapi.NexTriggerSubject))
+}
+
 // Deploy expects a `Validate` to have succeeded and `ubs` to be non-nil
 func (v *V8) Deploy() error {
 	if v.ubs == nil {
 		return fmt.Errorf("invalid state for execution; no compiled code available for vm: %s", v.name)
 	}
 
-	subject := fmt.Sprintf("agentint.%s.trigger", v.vmID)
-	_, err := v.nc.Subscribe(subject, func(msg *nats.Msg) {
+	subject := agentapi.TriggerSubject(v.vmID, v.workloadID)
+	sub, err := v.nc.Subscribe(subject, func(msg *nats.Msg) {
+		v.executions.Add(1)
+		defer v.executions.Done()
+
+		if !v.permitsTrigger(msg) {
+			_, _ = v.stderr.Write([]byte(fmt.Sprintf("refused trigger on subject %s not allowed for workload", msg.Header.Get(agentapi.NexTriggerSubject))))
+			_ = agentapi.RefuseTrigger(msg, fmt.Sprintf("not authorized to trigger workload on subject %s", msg.Header.Get(agentapi.NexTriggerSubject)))
+			return
+		}
+
 		ctx := context.WithValue(context.Background(), agentapi.NexTriggerSubject, msg.Header.Get(agentapi.NexTriggerSubject)) //nolint:all
 		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(msg.Header))
+		ctx = context.WithValue(ctx, agentapi.NexTriggerPartialInbox, msg.Header.Get(agentapi.NexTriggerPartialInbox)) //nolint:all
+
+		ctx, cancel := agentapi.TriggerContext(ctx, msg)
+		defer cancel()
+
+		var stream *agentapi.TriggerStreamWriter
+		if agentapi.StreamingTrigger(msg) {
+			stream = agentapi.NewTriggerStreamWriter(v.nc, msg.Reply)
+			ctx = context.WithValue(ctx, controlapi.TriggerStreamHeader, stream) //nolint:all
+		}
+
+		ctx, span := otel.Tracer(agentapi.AgentTracerName).Start(ctx, "execute",
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attribute.String("workload_type", agentapi.NexExecutionProviderV8)),
+		)
+		defer span.End()
+
+		payload, err := agentapi.TriggerPayload(v.nc, msg)
+		if err != nil {
+			_, _ = v.stderr.Write([]byte(fmt.Sprintf("failed to read payload on trigger subject %s: %s", subject, err.Error())))
+			if stream != nil {
+				_ = stream.End(nil, err)
+			} else {
+				_ = agentapi.FailTrigger(msg, err, false)
+			}
+			return
+		}
 
 		startTime := time.Now()
-		val, err := v.Execute(ctx, msg.Data)
+		val, err := v.Execute(ctx, payload)
+		if v.executed != nil {
+			v.executed()
+		}
 		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
 			_, _ = v.stderr.Write([]byte(fmt.Sprintf("failed to execute function on trigger subject %s: %s", subject, err.Error())))
+
+			if stream != nil {
+				_ = stream.End(nil, err)
+				return
+			}
+
+			// report the failure, or timeout, so the node need not await its own timeout
+			_ = agentapi.FailTrigger(msg, err, errors.Is(err, errV8ExecutionTimedOut))
 			return
 		}
 
@@ -116,6 +190,18 @@ func (v *V8) Deploy() error {
 		}
 		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
 
+		// the values emitted by the function have been streamed ahead of its return value
+		if stream != nil {
+			_, err = stream.Write(val)
+			if err == nil {
+				err = stream.End(header, nil)
+			}
+			if err != nil {
+				_, _ = v.stderr.Write([]byte(fmt.Sprintf("failed to stream %d-byte response: %s", len(val), err.Error())))
+			}
+			return
+		}
+
 		err = msg.RespondMsg(&nats.Msg{
 			Data:   val,
 			Header: header,
@@ -128,6 +214,7 @@ func (v *V8) Deploy() error {
 	if err != nil {
 		return fmt.Errorf("failed to subscribe to trigger: %s", err)
 	}
+	v.sub = sub
 
 	v.run <- true
 	return nil
@@ -154,6 +241,13 @@ func (v *V8) Execute(ctx context.Context, payload []byte) ([]byte, error) {
 		return nil, fmt.Errorf("failed to initialize context in vm: %s", err.Error())
 	}
 
+	// executions are bounded by the trigger timeout, if the node gave one
+	timeout := time.Millisecond * v8ExecutionTimeoutMillis
+	if d, ok := ctx.Deadline(); ok {
+		timeout = time.Until(d)
+	}
+	deadline := time.After(timeout)
+
 	vals := make(chan *v8.Value, 1)
 	errs := make(chan error, 1)
 
@@ -204,12 +298,34 @@ func (v *V8) Execute(ctx context.Context, payload []byte) ([]byte, error) {
 	case err := <-errs:
 		_, _ = v.stderr.Write([]byte(fmt.Sprintf("v8 execution failed with error: %s", err.Error())))
 		return nil, err
-	case <-time.After(time.Millisecond * v8ExecutionTimeoutMillis):
-		// if err != nil {
-		// }
+	case <-deadline:
+		return nil, fmt.Errorf("%w after %s", errV8ExecutionTimedOut, timeout)
+	}
+}
 
-		return nil, fmt.Errorf("v8 execution timed out after %dms", v8ExecutionTimeoutMillis)
+// Stop the deployed function from receiving triggers, giving executions in flight the given
+// grace period to complete before the isolate is told to terminate any still running
+func (v *V8) Stop(gracePeriod time.Duration) error {
+	if v.sub != nil {
+		_ = v.sub.Unsubscribe()
 	}
+
+	done := make(chan struct{})
+	go func() {
+		v.executions.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(gracePeriod):
+		_, _ = v.stderr.Write([]byte(fmt.Sprintf("v8 executions did not complete within %s; terminating them", gracePeriod)))
+		if v.iso != nil {
+			v.iso.TerminateExecution()
+		}
+	}
+
+	return v.Undeploy()
 }
 
 func (v *V8) Undeploy() error {
@@ -321,11 +437,21 @@ func (v *V8) newHostServicesTemplate(ctx context.Context) (*v8.ObjectTemplate, e
 		return nil, err
 	}
 
+	err = hostServices.Set(hostServicesMetricsObjectName, v.newMetricsObjectTemplate())
+	if err != nil {
+		return nil, err
+	}
+
 	err = hostServices.Set(hostServicesObjectStoreObjectName, v.newObjectStoreObjectTemplate(ctx))
 	if err != nil {
 		return nil, err
 	}
 
+	err = hostServices.Set(hostServicesTriggerObjectName, v.newTriggerObjectTemplate(ctx))
+	if err != nil {
+		return nil, err
+	}
+
 	return hostServices, nil
 }
 
@@ -681,6 +807,55 @@ func (v *V8) newMessagingObjectTemplate(ctx context.Context) *v8.ObjectTemplate
 	return messaging
 }
 
+// The metrics object allows a function to record named counter, gauge and histogram samples,
+// optionally with a set of string attributes, which the node re-exports alongside its own metrics
+func (v *V8) newMetricsObjectTemplate() *v8.ObjectTemplate {
+	metrics := v8.NewObjectTemplate(v.iso)
+
+	_ = metrics.Set(hostServicesMetricsRecordFunctionName, v8.NewFunctionTemplate(v.iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		args := info.Args()
+		if len(args) < 3 || len(args) > 4 {
+			val, _ := v8.NewValue(v.iso, "name, kind and value are required")
+			return v.iso.ThrowException(val)
+		}
+
+		if !args[2].IsNumber() {
+			val, _ := v8.NewValue(v.iso, "value must be a number")
+			return v.iso.ThrowException(val)
+		}
+
+		sample := &agentapi.MetricSample{
+			Name:  args[0].String(),
+			Kind:  args[1].String(),
+			Value: args[2].Number(),
+		}
+
+		if len(args) == 4 && !args[3].IsNullOrUndefined() {
+			attrs, err := v8.JSONStringify(v.ctx, args[3])
+			if err != nil {
+				val, _ := v8.NewValue(v.iso, err.Error())
+				return v.iso.ThrowException(val)
+			}
+
+			err = json.Unmarshal([]byte(attrs), &sample.Attributes)
+			if err != nil {
+				val, _ := v8.NewValue(v.iso, "attributes must be an object of string values")
+				return v.iso.ThrowException(val)
+			}
+		}
+
+		err := agentapi.PublishMetricSample(v.nc, v.vmID, sample)
+		if err != nil {
+			val, _ := v8.NewValue(v.iso, err.Error())
+			return v.iso.ThrowException(val)
+		}
+
+		return nil
+	}))
+
+	return metrics
+}
+
 func (v *V8) newObjectStoreObjectTemplate(ctx context.Context) *v8.ObjectTemplate {
 	objectStore := v8.NewObjectTemplate(v.iso)
 
@@ -781,6 +956,48 @@ func (v *V8) newObjectStoreObjectTemplate(ctx context.Context) *v8.ObjectTemplat
 	return objectStore
 }
 
+// The trigger object allows a function to emit partial results, which are returned to the
+// caller in place of a response if the function times out. Partial results are only kept when
+// the caller requested them; otherwise they are discarded. When the caller requested a streamed
+// response, each result is streamed to the caller as it is emitted
+func (v *V8) newTriggerObjectTemplate(ctx context.Context) *v8.ObjectTemplate {
+	trigger := v8.NewObjectTemplate(v.iso)
+
+	_ = trigger.Set(hostServicesTriggerEmitFunctionName, v8.NewFunctionTemplate(v.iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		args := info.Args()
+		if len(args) != 1 {
+			val, _ := v8.NewValue(v.iso, "payload is required")
+			return v.iso.ThrowException(val)
+		}
+
+		stream, _ := ctx.Value(controlapi.TriggerStreamHeader).(*agentapi.TriggerStreamWriter)
+		inbox, _ := ctx.Value(agentapi.NexTriggerPartialInbox).(string)
+		if stream == nil && inbox == "" {
+			return nil
+		}
+
+		payload, err := v.marshalValue(args[0])
+		if err != nil {
+			val, _ := v8.NewValue(v.iso, err.Error())
+			return v.iso.ThrowException(val)
+		}
+
+		if stream != nil {
+			_, err = stream.Write(payload)
+		} else {
+			err = v.nc.Publish(inbox, payload)
+		}
+		if err != nil {
+			val, _ := v8.NewValue(v.iso, err.Error())
+			return v.iso.ThrowException(val)
+		}
+
+		return nil
+	}))
+
+	return trigger
+}
+
 // marshal the given v8 value to an array of bytes that can be sent over the wire
 func (v *V8) marshalValue(val *v8.Value) ([]byte, error) {
 	if val.IsUint8Array() {
@@ -862,6 +1079,9 @@ func InitNexExecutionProviderV8(params *agentapi.ExecutionProviderParams) (*V8,
 		tmpFilename: *params.TmpFilename,
 		totalBytes:  0, // FIXME
 		vmID:        params.VmID,
+		workloadID:  params.ResolveWorkloadID(params.VmID),
+
+		allowedTriggerSubjects: params.AllowedTriggerSubjects,
 
 		stderr: params.Stderr,
 		stdout: params.Stdout,
@@ -870,6 +1090,8 @@ func InitNexExecutionProviderV8(params *agentapi.ExecutionProviderParams) (*V8,
 		run:  params.Run,
 		exit: params.Exit,
 
+		executed: params.Executed,
+
 		builtins: builtins,
 
 		nc:  params.NATSConn,
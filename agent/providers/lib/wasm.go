@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -8,39 +9,108 @@ import (
 	"os"
 
 	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
 	agentapi "github.com/synadia-io/nex/internal/agent-api"
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 	"github.com/tetratelabs/wazero/sys"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Returned when an execution is aborted because it exceeded the trigger timeout
+var errWasmExecutionTimedOut = errors.New("wasm execution timed out")
+
+// Magic number and version with which every binary-encoded WebAssembly module begins
+var wasmHeader = []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+
 // Wasm execution provider implementation
 type Wasm struct {
 	vmID          string
+	workloadID    string
 	wasmFile      []byte
 	env           map[string]string
 	runtime       wazero.Runtime
 	runtimeConfig wazero.ModuleConfig
 	module        wazero.CompiledModule
 
+	allowedTriggerSubjects []string
+
 	fail chan bool
 	run  chan bool
 	exit chan int
 
+	executed func() // called after each execution, if set
+
+	stderr io.Writer
+
 	nc *nats.Conn // agent NATS connection
 }
 
+// Returns true if the workload may be triggered on the subject on which the given trigger was received
+func (e *Wasm) permitsTrigger(msg *nats.Msg) bool {
+	return agentapi.TriggerSubjectAllowed(e.allowedTriggerSubjects, msg.Header.Get(agentapi.NexTriggerSubject))
+}
+
 func (e *Wasm) Deploy() error {
-	subject := fmt.Sprintf("agentint.%s.trigger", e.vmID)
+	subject := agentapi.TriggerSubject(e.vmID, e.workloadID)
 	_, err := e.nc.Subscribe(subject, func(msg *nats.Msg) {
+		if !e.permitsTrigger(msg) {
+			_, _ = e.stderr.Write([]byte(fmt.Sprintf("refused trigger on subject %s not allowed for workload", msg.Header.Get(agentapi.NexTriggerSubject))))
+			_ = agentapi.RefuseTrigger(msg, fmt.Sprintf("not authorized to trigger workload on subject %s", msg.Header.Get(agentapi.NexTriggerSubject)))
+			return
+		}
+
 		ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(msg.Header))
-		ctx = context.WithValue(ctx, agentapi.NexTriggerSubject, subject) //nolint:all
+		ctx = context.WithValue(ctx, agentapi.NexTriggerSubject, msg.Header.Get(agentapi.NexTriggerSubject)) //nolint:all
+
+		ctx, cancel := agentapi.TriggerContext(ctx, msg)
+		defer cancel()
+
+		var stream *agentapi.TriggerStreamWriter
+		if agentapi.StreamingTrigger(msg) {
+			stream = agentapi.NewTriggerStreamWriter(e.nc, msg.Reply)
+			ctx = context.WithValue(ctx, controlapi.TriggerStreamHeader, stream) //nolint:all
+		}
+
+		ctx, span := otel.Tracer(agentapi.AgentTracerName).Start(ctx, "execute",
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attribute.String("workload_type", agentapi.NexExecutionProviderWasm)),
+		)
+		defer span.End()
+
+		payload, err := agentapi.TriggerPayload(e.nc, msg)
+		if err != nil {
+			_, _ = e.stderr.Write([]byte(fmt.Sprintf("failed to read payload on trigger subject %s: %s", subject, err.Error())))
+			if stream != nil {
+				_ = stream.End(nil, err)
+			} else {
+				_ = agentapi.FailTrigger(msg, err, false)
+			}
+			return
+		}
 
-		val, err := e.Execute(ctx, msg.Data)
+		val, err := e.Execute(ctx, payload)
+		if e.executed != nil {
+			e.executed()
+		}
 		if err != nil {
-			// TODO-- propagate this error to agent logs
+			span.SetStatus(codes.Error, err.Error())
+			_, _ = e.stderr.Write([]byte(fmt.Sprintf("failed to execute function on trigger subject %s: %s", subject, err.Error())))
+		}
+
+		// the function's stdout has been streamed as it was written
+		if stream != nil {
+			_ = stream.End(nil, err)
+			return
+		}
+
+		// report the failure, or timeout, so the node need not await its own timeout
+		if err != nil {
+			_ = agentapi.FailTrigger(msg, err, errors.Is(err, errWasmExecutionTimedOut))
 			return
 		}
 
@@ -56,6 +126,10 @@ func (e *Wasm) Deploy() error {
 	return nil
 }
 
+// Trigger execution of the deployed function, which is instantiated afresh for each execution.
+// The trigger payload is passed to the function on stdin along with the trigger subject as its
+// first argument, and whatever the function writes to stdout is returned as its reply, or
+// streamed to the caller as it is written if the caller requested a streamed response
 func (e *Wasm) Execute(ctx context.Context, payload []byte) ([]byte, error) {
 	var subject string
 	sub, ok := ctx.Value(agentapi.NexTriggerSubject).(string)
@@ -69,38 +143,59 @@ func (e *Wasm) Execute(ctx context.Context, payload []byte) ([]byte, error) {
 	in := newStdInBuf()
 	in.Reset(payload)
 
-	// clone runtimeConfig for each execution
+	// a streamed response receives the function's stdout as it is written
+	var stdout io.Writer = out
+	if stream, ok := ctx.Value(controlapi.TriggerStreamHeader).(*agentapi.TriggerStreamWriter); ok {
+		stdout = stream
+	}
+
+	// clone runtimeConfig for each execution; instances are anonymous so that executions may overlap
 	cfg := e.runtimeConfig.
+		WithName("").
 		WithStdin(in).
-		WithStdout(out).
+		WithStdout(stdout).
 		WithArgs("nexfunction", subject)
 
-	_, err := e.runtime.InstantiateModule(ctx, e.module, cfg)
+	mod, err := e.runtime.InstantiateModule(ctx, e.module, cfg)
+	if mod != nil {
+		defer mod.Close(ctx)
+	}
 	if err != nil {
-		if exitErr, ok := err.(*sys.ExitError); ok && exitErr.ExitCode() != 0 {
-			// TODO: log error
-			return nil, err
-		} else if !ok {
-			// TODO: log failure
-			return nil, errors.New("failed to execute WASI function")
+		var exitErr *sys.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("failed to execute WASI function: %s", err)
+		} else if exitErr.ExitCode() == sys.ExitCodeDeadlineExceeded {
+			return nil, errWasmExecutionTimedOut
+		} else if exitErr.ExitCode() != 0 {
+			return nil, fmt.Errorf("WASI function exited with code %d", exitErr.ExitCode())
 		}
-	} else {
-		return out.buf, err
 	}
 
-	return nil, errors.New("unknown")
+	// a function exiting with code 0 has returned normally
+	return out.buf, nil
 }
 
+// Undeploy the function, closing the runtime in which it was compiled; the wasm "owns" no other resources
 func (e *Wasm) Undeploy() error {
-	// We shouldn't have to do anything here since the wasm "owns" no resources
+	if e.runtime != nil {
+		return e.runtime.Close(context.Background())
+	}
+
 	return nil
 }
 
+// Validate the artifact to be a binary-encoded WebAssembly module, and compile it
+// along with the WASI preview1 host module it may import
 func (e *Wasm) Validate() error {
+	if !bytes.HasPrefix(e.wasmFile, wasmHeader) {
+		return errors.New("artifact is not a WebAssembly binary module")
+	}
+
 	ctx := context.Background()
-	e.runtime = wazero.NewRuntime(ctx)
+	// executions are closed once their trigger times out
+	e.runtime = wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
 	e.runtimeConfig = wazero.NewModuleConfig().
-		WithStderr(os.Stderr)
+		WithStderr(e.stderr)
 
 	for key, val := range e.env {
 		e.runtimeConfig = e.runtimeConfig.WithEnv(key, val)
@@ -149,14 +244,21 @@ func InitNexExecutionProviderWasm(params *agentapi.ExecutionProviderParams) (*Wa
 	}
 
 	return &Wasm{
-		vmID:     params.VmID,
-		wasmFile: bytes,
-		env:      params.Environment,
+		vmID:       params.VmID,
+		workloadID: params.ResolveWorkloadID(params.VmID),
+		wasmFile:   bytes,
+		env:        params.Environment,
+
+		allowedTriggerSubjects: params.AllowedTriggerSubjects,
 
 		fail: params.Fail,
 		run:  params.Run,
 		exit: params.Exit,
 
+		executed: params.Executed,
+
+		stderr: params.Stderr,
+
 		nc: params.NATSConn,
 	}, nil
 }
@@ -176,7 +278,7 @@ func newStdOutBuf() *stdOutBuf {
 }
 
 func (i *stdOutBuf) Write(p []byte) (n int, err error) {
-	i.buf = p
+	i.buf = append(i.buf, p...)
 
 	return len(p), nil
 }
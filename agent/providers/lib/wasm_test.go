@@ -0,0 +1,90 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+func TestWasmValidateRejectsNonWasmArtifacts(t *testing.T) {
+	e := &Wasm{
+		wasmFile: []byte{0x7f, 'E', 'L', 'F', 0x02, 0x01, 0x01, 0x00},
+		stderr:   io.Discard,
+	}
+
+	err := e.Validate()
+	if err == nil {
+		t.Fatal("expected an elf binary to be rejected")
+	}
+}
+
+func TestWasmExecuteEmptyModule(t *testing.T) {
+	e := &Wasm{
+		wasmFile: wasmHeader,
+		env:      map[string]string{"NEX_TEST": "true"},
+		stderr:   io.Discard,
+	}
+
+	err := e.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = e.Undeploy() }()
+
+	ctx := context.WithValue(context.Background(), agentapi.NexTriggerSubject, "hello.world") //nolint:all
+
+	// an anonymous instance is created for each execution, so a module can be executed repeatedly
+	for i := 0; i < 2; i++ {
+		out, err := e.Execute(ctx, []byte("payload"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != 0 {
+			t.Fatalf("expected no output, got %q", out)
+		}
+	}
+}
+
+func TestStdOutBufCollectsEveryWrite(t *testing.T) {
+	out := newStdOutBuf()
+	_, _ = out.Write([]byte("hello, "))
+	_, _ = out.Write([]byte("world"))
+
+	if string(out.buf) != "hello, world" {
+		t.Fatalf("expected both writes to be collected, got %q", out.buf)
+	}
+}
+
+func TestWasmExecuteAbortsAtTriggerTimeout(t *testing.T) {
+	// a module whose start function loops forever
+	module := append(append([]byte{}, wasmHeader...),
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00, // type section: func() -> ()
+		0x03, 0x02, 0x01, 0x00, // function section
+		0x07, 0x0a, 0x01, 0x06, '_', 's', 't', 'a', 'r', 't', 0x00, 0x00, // export section: _start
+		0x0a, 0x09, 0x01, 0x07, 0x00, 0x03, 0x40, 0x0c, 0x00, 0x0b, 0x0b, // code section: loop br 0
+	)
+
+	e := &Wasm{
+		wasmFile: module,
+		stderr:   io.Discard,
+	}
+
+	err := e.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = e.Undeploy() }()
+
+	ctx := context.WithValue(context.Background(), agentapi.NexTriggerSubject, "hello.world") //nolint:all
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	_, err = e.Execute(ctx, nil)
+	if !errors.Is(err, errWasmExecutionTimedOut) {
+		t.Fatalf("expected the execution to be aborted at the trigger timeout, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+	"slices"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+// Version of the plugin contract; a plugin built against a different version is not loaded
+const PluginAPIVersion = 1
+
+// Name of the symbol a plugin must export, which must be an ExecutionProviderPlugin
+const PluginSymbol = "NexExecutionProviderPlugin"
+
+// Execution provider plugins are Go plugins loaded by the agent from its plugin path at
+// startup, each handling one or more workload types not built into the agent
+type ExecutionProviderPlugin interface {
+	// Name of the plugin
+	Name() string
+
+	// Version of the plugin contract the plugin implements, which must equal PluginAPIVersion
+	APIVersion() int
+
+	// Workload types handled by the plugin
+	WorkloadTypes() []string
+
+	// Initializes an execution provider for the given work request, as NewExecutionProvider
+	// does for the built-in workload types
+	NewExecutionProvider(params *agentapi.ExecutionProviderParams) (ExecutionProvider, error)
+}
+
+// Plugins registered for each workload type
+var registeredPlugins = make(map[string]ExecutionProviderPlugin)
+
+// LoadPlugins loads every plugin (*.so) in the given directory, registering each as the
+// execution provider for the workload types it declares. Plugins that fail to load or are
+// incompatible are skipped; the returned error describes each. Returns the workload types
+// registered by each loaded plugin, keyed by plugin name
+func LoadPlugins(path string) (map[string][]string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("invalid plugin path: %s", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.so"))
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make(map[string][]string)
+	for _, file := range files {
+		p, _err := loadPlugin(file)
+		if _err != nil {
+			err = errors.Join(err, fmt.Errorf("failed to load plugin %s: %s", file, _err))
+			continue
+		}
+
+		for _, workloadType := range p.WorkloadTypes() {
+			registeredPlugins[workloadType] = p
+		}
+		loaded[p.Name()] = p.WorkloadTypes()
+	}
+
+	return loaded, err
+}
+
+// Opens the plugin at the given path and validates its compatibility with this agent
+func loadPlugin(path string) (ExecutionProviderPlugin, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sym, err := p.Lookup(PluginSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, ok := sym.(ExecutionProviderPlugin)
+	if !ok {
+		// exported variables are looked up as pointers
+		ptr, isPtr := sym.(*ExecutionProviderPlugin)
+		if !isPtr || *ptr == nil {
+			return nil, fmt.Errorf("symbol %s does not implement the execution provider plugin interface", PluginSymbol)
+		}
+		provider = *ptr
+	}
+
+	if provider.APIVersion() != PluginAPIVersion {
+		return nil, fmt.Errorf("plugin %s implements plugin API version %d; agent requires version %d", provider.Name(), provider.APIVersion(), PluginAPIVersion)
+	}
+
+	if len(provider.WorkloadTypes()) == 0 {
+		return nil, fmt.Errorf("plugin %s does not declare any workload types", provider.Name())
+	}
+
+	for _, workloadType := range provider.WorkloadTypes() {
+		if slices.Contains(agentapi.BuiltinWorkloadTypes, workloadType) {
+			return nil, fmt.Errorf("plugin %s declares built-in workload type %s", provider.Name(), workloadType)
+		}
+
+		if existing, ok := registeredPlugins[workloadType]; ok {
+			return nil, fmt.Errorf("plugin %s declares workload type %s, already registered by plugin %s", provider.Name(), workloadType, existing.Name())
+		}
+	}
+
+	return provider, nil
+}
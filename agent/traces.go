@@ -0,0 +1,72 @@
+package nexagent
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Maximum number of spans published to the node in a single message
+const spanExportBatchSize = 64
+
+// Maximum time allowed to flush recorded spans to the node when the agent shuts down
+const spanFlushTimeout = 500 * time.Millisecond
+
+// Exports the spans recorded by the agent to the node via the internal NATS connection, as the
+// agent may have no network access to the trace collector
+type natsSpanExporter struct {
+	nc   *nats.Conn
+	vmID string
+}
+
+func (e *natsSpanExporter) ExportSpans(_ context.Context, spans []tracesdk.ReadOnlySpan) error {
+	return agentapi.PublishSpans(e.nc, e.vmID, spans)
+}
+
+func (e *natsSpanExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+// Installs a tracer provider which forwards the spans recorded by the agent and its execution
+// providers to the node, if the node exports traces
+func (a *Agent) initTracerProvider() {
+	if !a.md.TracesEnabled {
+		return
+	}
+
+	a.tracerProvider = tracesdk.NewTracerProvider(
+		tracesdk.WithBatcher(
+			&natsSpanExporter{nc: a.nc, vmID: *a.md.VmID},
+			tracesdk.WithMaxExportBatchSize(spanExportBatchSize),
+		),
+	)
+	otel.SetTracerProvider(a.tracerProvider)
+}
+
+// Flushes any spans not yet forwarded to the node
+func (a *Agent) shutdownTracerProvider() {
+	if a.tracerProvider == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), spanFlushTimeout)
+	defer cancel()
+
+	_ = a.tracerProvider.Shutdown(ctx)
+}
+
+// Ends the given span, marking it as failed if an error is given
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,238 @@
+package nexagent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+// State carried across an in-place update, from which the restarted agent redeploys its workloads.
+// The first workload is also saved in the fields read by agents hosting a single workload
+type updateState struct {
+	DeployRequest *agentapi.DeployRequest `json:"deploy_request"`
+	ArtifactPath  string                  `json:"artifact_path"`
+
+	Workloads []updatedWorkload `json:"workloads,omitempty"`
+}
+
+// A workload saved across an in-place update
+type updatedWorkload struct {
+	DeployRequest *agentapi.DeployRequest `json:"deploy_request"`
+	ArtifactPath  string                  `json:"artifact_path"`
+}
+
+// Path of the file in which the agent's state is carried across an in-place update
+func (a *Agent) updateStatePath() string {
+	return path.Join(os.TempDir(), fmt.Sprintf("nex-agent-%s.state", *a.md.VmID))
+}
+
+// Path to which an agent binary pushed by the node is written. The running binary is never
+// overwritten, as agents running outside of a sandbox share it
+func (a *Agent) updateBinaryPath() string {
+	return path.Join(os.TempDir(), fmt.Sprintf("nex-agent-%s", *a.md.VmID))
+}
+
+// Pull an update request off the wire, fetch the pushed agent binary from the shared bucket and
+// verify its hash and signature, then replace this agent with it in place. The request is
+// acknowledged before the agent is replaced; the node awaits the handshake of the new agent
+func (a *Agent) handleUpdate(m *nats.Msg) {
+	var request agentapi.AgentUpdateRequest
+	err := json.Unmarshal(m.Data, &request)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to unmarshal agent update request: %s", err)
+		a.LogError(msg)
+		_ = a.workAck(m, false, msg)
+		return
+	}
+
+	err = request.Validate()
+	if err != nil {
+		_ = a.workAck(m, false, fmt.Sprintf("%v", err))
+		return
+	}
+
+	if a.shuttingDown() || atomic.LoadUint32(&a.replacing) > 0 {
+		_ = a.workAck(m, false, "Agent is shutting down")
+		return
+	}
+
+	binaryPath := a.updateBinaryPath()
+	err = a.cacheBucket.GetFile(request.ArtifactKey, binaryPath)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to write agent binary to temp dir: %s", err)
+		a.LogError(msg)
+		_ = a.workAck(m, false, msg)
+		return
+	}
+
+	err = a.verifyAgentBinary(binaryPath, &request)
+	if err != nil {
+		_ = os.Remove(binaryPath)
+		a.LogError(err.Error())
+		_ = a.workAck(m, false, err.Error())
+		return
+	}
+
+	err = os.Chmod(binaryPath, 0755)
+	if err != nil {
+		_ = os.Remove(binaryPath)
+		msg := fmt.Sprintf("Failed to set agent binary permissions: %s", err)
+		a.LogError(msg)
+		_ = a.workAck(m, false, msg)
+		return
+	}
+
+	err = a.saveUpdateState()
+	if err != nil {
+		_ = os.Remove(binaryPath)
+		msg := fmt.Sprintf("Failed to save agent state: %s", err)
+		a.LogError(msg)
+		_ = a.workAck(m, false, msg)
+		return
+	}
+
+	a.LogInfo(fmt.Sprintf("Replacing agent with updated binary: %s", request.Hash))
+	_ = a.workAck(m, true, "Agent update accepted")
+	_ = a.nc.Flush()
+
+	go a.replace(binaryPath)
+}
+
+// Verifies the hash and signature of the agent binary at the given path
+func (a *Agent) verifyAgentBinary(binaryPath string, request *agentapi.AgentUpdateRequest) error {
+	hash, err := hashFile(binaryPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash agent binary: %s", err)
+	}
+
+	if hash != request.Hash {
+		return fmt.Errorf("agent binary hash %s does not match requested hash %s", hash, request.Hash)
+	}
+
+	binary, err := os.ReadFile(binaryPath)
+	if err != nil {
+		return fmt.Errorf("failed to read agent binary: %s", err)
+	}
+
+	return controlapi.VerifyAgentBinary(*a.md.AgentUpdatePublicKey, binary, request.Signature)
+}
+
+// Saves the deployed workloads, if any, so that the replacement agent can redeploy them. Workload
+// artifacts are linked aside, as undeploying the workloads may remove them
+func (a *Agent) saveUpdateState() error {
+	workloads := a.deployedWorkloads()
+	if len(workloads) == 0 {
+		return nil
+	}
+
+	var state updateState
+	for _, w := range workloads {
+		saved := updatedWorkload{
+			DeployRequest: w.request,
+			ArtifactPath:  w.artifactPath,
+		}
+
+		// sealed environments are saved as sealed, and opened again by the replacement agent
+		if w.request.SealedEnvironment != nil {
+			request := *w.request
+			request.Environment = nil
+			saved.DeployRequest = &request
+		}
+
+		preserved := w.artifactPath + ".preserved"
+		_ = os.Remove(preserved)
+		if err := os.Link(w.artifactPath, preserved); err == nil {
+			saved.ArtifactPath = preserved
+		}
+
+		state.Workloads = append(state.Workloads, saved)
+	}
+
+	state.DeployRequest = state.Workloads[0].DeployRequest
+	state.ArtifactPath = state.Workloads[0].ArtifactPath
+
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	// the state includes the workloads' environments, unless sealed
+	return os.WriteFile(a.updateStatePath(), raw, 0600)
+}
+
+// Stops the workloads and releases the connection to the node, then re-executes the agent from
+// the given binary. Should re-exec fail, the agent halts so that the node replaces its machine
+func (a *Agent) replace(binaryPath string) {
+	if a.shuttingDown() || !atomic.CompareAndSwapUint32(&a.replacing, 0, 1) {
+		_ = os.Remove(a.updateStatePath())
+		_ = os.Remove(binaryPath)
+		return
+	}
+
+	// events published by the stopping workload must not reach the node, which would otherwise
+	// consider the workload to have exited
+	a.stopDispatchers()
+
+	err := a.undeployWorkloads()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to undeploy workload: %s\n", err)
+	}
+
+	a.shutdownTracerProvider()
+
+	_ = a.nc.Drain()
+	for !a.nc.IsClosed() {
+		time.Sleep(time.Millisecond * 25)
+	}
+
+	err = reexec(binaryPath)
+	fmt.Fprintf(os.Stderr, "failed to re-exec updated agent: %s\n", err)
+	_ = os.Remove(a.updateStatePath())
+	HaltVM(err)
+}
+
+// Redeploys the workloads saved by the agent this agent replaced, if any
+func (a *Agent) restoreUpdateState() error {
+	raw, err := os.ReadFile(a.updateStatePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	_ = os.Remove(a.updateStatePath())
+
+	var state updateState
+	err = json.Unmarshal(raw, &state)
+	if err != nil {
+		return err
+	}
+
+	workloads := state.Workloads
+	if len(workloads) == 0 && state.DeployRequest != nil {
+		// saved by an agent hosting a single workload
+		workloads = append(workloads, updatedWorkload{
+			DeployRequest: state.DeployRequest,
+			ArtifactPath:  state.ArtifactPath,
+		})
+	}
+
+	for _, w := range workloads {
+		a.LogInfo(fmt.Sprintf("Redeploying workload following agent update: %s", *w.DeployRequest.WorkloadName))
+		deployErr := a.openEnvironment(w.DeployRequest)
+		if deployErr == nil {
+			_, deployErr = a.deployWorkload(context.Background(), w.DeployRequest, w.ArtifactPath)
+		}
+		err = errors.Join(err, deployErr)
+	}
+
+	return err
+}
@@ -0,0 +1,163 @@
+package nexagent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/synadia-io/nex/agent/providers"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+// Number of workloads an agent hosts at once unless its metadata says otherwise
+const defaultMaxWorkloads = 1
+
+// A workload deployed to the agent, keyed by its workload id
+type deployedWorkload struct {
+	id       string
+	provider providers.ExecutionProvider
+
+	// Request and artifact of the workload, redeployed following an in-place update
+	request      *agentapi.DeployRequest
+	artifactPath string
+
+	// Capture of the workload's stdout and stderr into the internal object store, if requested
+	capture *logCapture
+
+	deployedAt time.Time
+}
+
+// Returns the maximum number of workloads the agent hosts at once
+func (a *Agent) maxWorkloads() int {
+	if a.md.MaxWorkloads == nil || *a.md.MaxWorkloads <= 0 {
+		return defaultMaxWorkloads
+	}
+
+	return *a.md.MaxWorkloads
+}
+
+// Returns an error if the workload with the given id cannot be deployed to the agent, either
+// because it is already deployed or because the agent hosts as many workloads as it can
+func (a *Agent) checkWorkloadCapacity(workloadID string) error {
+	a.workloadsMutex.Lock()
+	defer a.workloadsMutex.Unlock()
+
+	if _, ok := a.workloads[workloadID]; ok {
+		return fmt.Errorf("Workload %s is already deployed to this agent", workloadID)
+	}
+
+	if len(a.workloads) >= a.maxWorkloads() {
+		return fmt.Errorf("Agent already hosts the maximum of %d workloads", a.maxWorkloads())
+	}
+
+	return nil
+}
+
+// Returns the deployed workload with the given id, if any
+func (a *Agent) workload(workloadID string) *deployedWorkload {
+	a.workloadsMutex.Lock()
+	defer a.workloadsMutex.Unlock()
+
+	return a.workloads[workloadID]
+}
+
+// Returns the deployed workloads in the order in which they were deployed
+func (a *Agent) deployedWorkloads() []*deployedWorkload {
+	a.workloadsMutex.Lock()
+	defer a.workloadsMutex.Unlock()
+
+	workloads := make([]*deployedWorkload, 0, len(a.workloads))
+	for _, w := range a.workloads {
+		workloads = append(workloads, w)
+	}
+
+	sort.Slice(workloads, func(i, j int) bool {
+		return workloads[i].deployedAt.Before(workloads[j].deployedAt)
+	})
+
+	return workloads
+}
+
+func (a *Agent) addWorkload(w *deployedWorkload) {
+	a.workloadsMutex.Lock()
+	defer a.workloadsMutex.Unlock()
+
+	a.workloads[w.id] = w
+}
+
+func (a *Agent) removeWorkload(workloadID string) {
+	a.workloadsMutex.Lock()
+	defer a.workloadsMutex.Unlock()
+
+	delete(a.workloads, workloadID)
+}
+
+// Returns the id of the workload to which the given request applies: the workload named by its
+// header, if any, otherwise the workload known by the VM's own id
+func (a *Agent) requestedWorkloadID(m *nats.Msg) string {
+	if m.Header != nil {
+		if workloadID := m.Header.Get(agentapi.NexWorkloadID); workloadID != "" {
+			return workloadID
+		}
+	}
+
+	return *a.md.VmID
+}
+
+// undeployWorkload stops the given workload, giving it its grace period to exit cleanly
+// if its execution provider supports graceful stops, or else undeploys it
+func (a *Agent) undeployWorkload(w *deployedWorkload) error {
+	defer a.finishLogCapture(w)
+
+	stopper, ok := w.provider.(providers.GracefulStopper)
+	if !ok || a.md.StopGracePeriodMillisecond == nil {
+		return w.provider.Undeploy()
+	}
+
+	gracePeriod := w.request.ResolveStopGracePeriod(*a.md.StopGracePeriodMillisecond)
+	a.LogDebug(fmt.Sprintf("Stopping workload %s with a grace period of %s", w.id, gracePeriod))
+	return stopper.Stop(gracePeriod)
+}
+
+// undeployWorkloads stops every deployed workload, as per undeployWorkload
+func (a *Agent) undeployWorkloads() error {
+	var err error
+	for _, w := range a.deployedWorkloads() {
+		err = errors.Join(err, a.undeployWorkload(w))
+	}
+
+	return err
+}
+
+// removeArtifact removes the temporary file to which the given workload's artifact was
+// written, if any
+func (a *Agent) removeArtifact(w *deployedWorkload) {
+	if !ownsArtifact(w.request) {
+		return
+	}
+
+	err := os.Remove(w.artifactPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		a.LogError(fmt.Sprintf("Failed to remove workload artifact %s: %s", w.artifactPath, err))
+	}
+}
+
+// Returns the uptime of each deployed workload, keyed by workload id
+func (a *Agent) workloadUptimes(now time.Time) map[string]int64 {
+	a.workloadsMutex.Lock()
+	defer a.workloadsMutex.Unlock()
+
+	if len(a.workloads) == 0 {
+		return nil
+	}
+
+	uptimes := make(map[string]int64, len(a.workloads))
+	for id, w := range a.workloads {
+		uptimes[id] = now.Sub(w.deployedAt).Milliseconds()
+	}
+
+	return uptimes
+}
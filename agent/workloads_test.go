@@ -0,0 +1,84 @@
+package nexagent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+type recordingProvider struct {
+	undeployed bool
+}
+
+func (p *recordingProvider) Deploy() error { return nil }
+
+func (p *recordingProvider) Execute(context.Context, []byte) ([]byte, error) { return nil, nil }
+
+func (p *recordingProvider) Undeploy() error {
+	p.undeployed = true
+	return nil
+}
+
+func (p *recordingProvider) Validate() error { return nil }
+
+func TestAgentHostsMultipleWorkloads(t *testing.T) {
+	vmID := "testvm"
+	maxWorkloads := 2
+
+	a := &Agent{
+		md:        &agentapi.MachineMetadata{VmID: &vmID, MaxWorkloads: &maxWorkloads},
+		workloads: make(map[string]*deployedWorkload),
+	}
+
+	workloadName := "echo"
+	deployedAt := time.Now().UTC()
+	for _, id := range []string{vmID, "second"} {
+		if err := a.checkWorkloadCapacity(id); err != nil {
+			t.Fatalf("expected workload %s to be accepted, got %s", id, err)
+		}
+
+		workloadID := id
+		a.addWorkload(&deployedWorkload{
+			id:         id,
+			provider:   &recordingProvider{},
+			request:    &agentapi.DeployRequest{WorkloadID: &workloadID, WorkloadName: &workloadName, ArtifactDevice: &workloadID},
+			deployedAt: deployedAt,
+		})
+	}
+
+	if err := a.checkWorkloadCapacity("second"); err == nil {
+		t.Fatal("expected redeployment of a deployed workload to be rejected")
+	}
+	if err := a.checkWorkloadCapacity("third"); err == nil {
+		t.Fatal("expected a workload beyond the agent's capacity to be rejected")
+	}
+
+	uptimes := a.pong(deployedAt.Add(time.Second)).WorkloadUptimeMillisecond
+	if len(uptimes) != 2 || uptimes["second"] != 1000 {
+		t.Fatalf("expected the uptime of each workload, got %v", uptimes)
+	}
+
+	second := a.workload("second").provider.(*recordingProvider)
+	first := a.workload(vmID).provider.(*recordingProvider)
+
+	// an undeploy request naming a workload undeploys only that workload
+	msg := nats.NewMsg("agentint.testvm.undeploy")
+	msg.Header.Set(agentapi.NexWorkloadID, "second")
+	a.handleUndeploy(msg)
+
+	if !second.undeployed || first.undeployed {
+		t.Fatal("expected only the named workload to be undeployed")
+	}
+	if a.workload("second") != nil {
+		t.Fatal("expected the undeployed workload to be removed")
+	}
+
+	// an undeploy request naming no workload undeploys the workload known by the VM's id
+	a.handleUndeploy(nats.NewMsg("agentint.testvm.undeploy"))
+	if !first.undeployed || len(a.deployedWorkloads()) != 0 {
+		t.Fatal("expected the workload known by the VM's id to be undeployed")
+	}
+}
@@ -0,0 +1,46 @@
+package controlapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Names of the constraints a node checks before admitting a workload deployment
+const (
+	ConstraintAgentPool       = "agent_pool"
+	ConstraintArtifactBucket  = "artifact_bucket"
+	ConstraintGitSource       = "git_source"
+	ConstraintHostResources   = "host_resources"
+	ConstraintIssuer          = "issuer"
+	ConstraintLameDuck        = "lame_duck"
+	ConstraintMaxWorkloads    = "max_workloads"
+	ConstraintNamespaceQuota  = "namespace_quota"
+	ConstraintObjectStore     = "object_store"
+	ConstraintTriggerSubjects = "trigger_subjects"
+	ConstraintVMPinning       = "vm_pinning"
+	ConstraintWorkloadType    = "workload_type"
+)
+
+// A single admission constraint that a deploy request failed to satisfy
+type UnsatisfiedConstraint struct {
+	Constraint string `json:"constraint"`
+	Reason     string `json:"reason"`
+}
+
+// Explains why a node could not schedule a workload, listing every constraint the deploy
+// request failed rather than only the first. Returned as the data of a failed run response,
+// and as the error from Client.StartWorkload
+type UnschedulableError struct {
+	NodeId      string                  `json:"node_id"`
+	Constraints []UnsatisfiedConstraint `json:"constraints"`
+}
+
+func (e *UnschedulableError) Error() string {
+	reasons := make([]string, len(e.Constraints))
+	for i, c := range e.Constraints {
+		reasons[i] = fmt.Sprintf("%s: %s", c.Constraint, c.Reason)
+	}
+
+	return fmt.Sprintf("workload unschedulable on node %s; %d constraint(s) not satisfied: %s",
+		e.NodeId, len(e.Constraints), strings.Join(reasons, "; "))
+}
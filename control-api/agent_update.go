@@ -0,0 +1,91 @@
+package controlapi
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/nats-io/nkeys"
+)
+
+// Requests that a node push the agent binary at the given location to each of its agents, which
+// verify its signature and replace themselves with it in place, redeploying their workloads. Agents
+// which fail to update are replaced by new machines. Nodes only accept agent updates when explicitly
+// allowed by their configuration
+type AgentUpdateRequest struct {
+	Location *url.URL `json:"location"`
+
+	// If the location indicates an object store bucket & key, JS domain can be supplied
+	JsDomain *string `json:"jsdomain,omitempty"`
+
+	// Signature of the agent binary by the node's configured agent update key, as produced by SignAgentBinary
+	Signature string `json:"signature"`
+}
+
+type AgentUpdateResponse struct {
+	Hash string `json:"hash"`
+	// Agents which replaced themselves with the pushed binary
+	Updated []string `json:"updated"`
+	// Agents which failed to update and were replaced by new machines
+	Replaced []string `json:"replaced"`
+}
+
+func NewAgentUpdateRequest(location string, signature string) (*AgentUpdateRequest, error) {
+	loc, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AgentUpdateRequest{
+		Location:  loc,
+		Signature: signature,
+	}, nil
+}
+
+func (r *AgentUpdateRequest) Validate() error {
+	var err error
+
+	if r.Location == nil {
+		err = errors.Join(err, errors.New("agent binary location is required"))
+	} else if !strings.EqualFold(r.Location.Scheme, "nats") {
+		err = errors.Join(err, errors.New("agent binary location must be a nats object store url"))
+	}
+
+	if strings.TrimSpace(r.Signature) == "" {
+		err = errors.Join(err, errors.New("agent binary signature is required"))
+	}
+
+	return err
+}
+
+// Signs the given agent binary with the given key, returning the base64-encoded signature
+func SignAgentBinary(kp nkeys.KeyPair, binary []byte) (string, error) {
+	sig, err := kp.Sign(binary)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// Verifies the given base64-encoded signature of the given agent binary against the given public key
+func VerifyAgentBinary(publicKey string, binary []byte, signature string) error {
+	kp, err := nkeys.FromPublicKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("invalid agent update public key: %s", err)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid agent binary signature: %s", err)
+	}
+
+	err = kp.Verify(binary, sig)
+	if err != nil {
+		return errors.New("agent binary signature verification failed")
+	}
+
+	return nil
+}
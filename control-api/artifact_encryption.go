@@ -0,0 +1,78 @@
+package controlapi
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Object store metadata marking a workload artifact stored encrypted at rest. Nodes resolve the
+// named key, which the workload must be authorized to use, and the agent decrypts the artifact
+// just before running it, verifying the hash of the plaintext. Artifacts without the encryption
+// marker are treated as plaintext
+const (
+	ArtifactEncryptionMetadata = "nex-artifact-encryption"
+	ArtifactKeyMetadata        = "nex-artifact-key"
+	ArtifactHashMetadata       = "nex-artifact-sha256"
+
+	ArtifactEncryptionAES256GCM = "aes-256-gcm"
+)
+
+// Size of the keys with which artifacts are encrypted
+const ArtifactKeySize = 32
+
+// Encrypts the given artifact with the given 32-byte key, returning the ciphertext, prefixed with
+// its nonce, along with the object store metadata with which it should be stored
+func EncryptArtifact(keyName string, key []byte, artifact []byte) ([]byte, map[string]string, error) {
+	aead, err := artifactCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hash := sha256.Sum256(artifact)
+	metadata := map[string]string{
+		ArtifactEncryptionMetadata: ArtifactEncryptionAES256GCM,
+		ArtifactKeyMetadata:        keyName,
+		ArtifactHashMetadata:       hex.EncodeToString(hash[:]),
+	}
+
+	return aead.Seal(nonce, nonce, artifact, nil), metadata, nil
+}
+
+// Decrypts an artifact encrypted by EncryptArtifact with the given key
+func DecryptArtifact(key []byte, ciphertext []byte) ([]byte, error) {
+	aead, err := artifactCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("encrypted artifact is truncated")
+	}
+
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, nil)
+}
+
+func artifactCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != ArtifactKeySize {
+		return nil, fmt.Errorf("artifact key must be %d bytes", ArtifactKeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,15 @@
+package controlapi
+
+const (
+	// Set to "true" on the empty message ending a workload's captured logs
+	CapturedLogsEndHeader = "x-nex-captured-logs-end"
+	// Set on the message ending a workload's captured logs, explaining why they could not be read
+	CapturedLogsErrorHeader = "x-nex-captured-logs-error"
+)
+
+// Requests the stdout and stderr captured for a workload deployed with CaptureLogs. The node
+// responds with a stream of chunks published to the request's reply subject, ended by a message
+// carrying the CapturedLogsEndHeader
+type CapturedLogsRequest struct {
+	WorkloadId string `json:"workload_id"`
+}
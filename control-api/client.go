@@ -3,7 +3,9 @@ package controlapi
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"strings"
 	"time"
@@ -18,7 +20,17 @@ import (
 // $NEX.INFO.{namespace}.{node}
 // $NEX.RUN.{namespace}.{node}
 // $NEX.STOP.{namespace}.{node}
+// $NEX.MIGRATE.{namespace}.{node}
 // $NEX.LAMEDUCK.{node}
+// $NEX.TRAFFIC.{namespace}.{node}
+// $NEX.METRICS.{node}
+// $NEX.PREWARM.{namespace}.{node}
+// $NEX.STOPSELECTOR.{node}
+// $NEX.POOL.{node}
+// $NEX.HISTORY.{namespace}.{node}
+// $NEX.INVENTORY.{node}
+// $NEX.AGENTUPDATE.{node}
+// $NEX.CAPTUREDLOGS.{namespace}.{node}
 
 // A control API client communicates with a "Nexus" of nodes by virtue of the $NEX.> subject space. This
 // client should be used to communicate with Nex nodes whenever possible, and its patterns should be copied
@@ -64,10 +76,26 @@ func (api *Client) StopWorkload(stopRequest *StopRequest) (*StopResponse, error)
 
 // Attempts to start a workload. The workload URI, at the moment, must always point to a NATS object store
 // bucket in the form of `nats://{bucket}/{key}`. Note that JetStream domains can be supplied on the workload
-// request and aren't part of the bucket+key URL.
+// request and aren't part of the bucket+key URL. When the node fails to deploy the workload, the returned error
+// is a *DeployError; when it fails one or more admission constraints, the *DeployError wraps an
+// *UnschedulableError listing each of them
 func (api *Client) StartWorkload(request *DeployRequest) (*RunResponse, error) {
 	subject := fmt.Sprintf("%s.DEPLOY.%s.%s", APIPrefix, api.namespace, *request.TargetNode)
-	bytes, err := api.performRequest(subject, request)
+	env, err := api.performEnvelopeRequest(subject, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if env.Error != nil {
+		var response RunResponse
+		data, _ := json.Marshal(env.Data)
+		if json.Unmarshal(data, &response) == nil && response.Error != nil {
+			return nil, response.Error
+		}
+		return nil, fmt.Errorf("%v", env.Error)
+	}
+
+	bytes, err := json.Marshal(env.Data)
 	if err != nil {
 		return nil, err
 	}
@@ -80,6 +108,57 @@ func (api *Client) StartWorkload(request *DeployRequest) (*RunResponse, error) {
 	return &response, nil
 }
 
+// Attempts to migrate a running workload from its source node to the target node. The workload
+// keeps running on the source node if the target node fails to deploy it
+func (api *Client) MigrateWorkload(request *MigrateRequest) (*MigrateResponse, error) {
+	subject := fmt.Sprintf("%s.MIGRATE.%s.%s", APIPrefix, api.namespace, request.SourceNode)
+	bytes, err := api.performRequest(subject, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var response MigrateResponse
+	err = json.Unmarshal(bytes, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Requests a manifest of every workload running on the given node, exported for redeployment onto
+// the request's target node with ImportWorkloads
+func (api *Client) ExportWorkloads(nodeId string, request *ExportRequest) (*WorkloadManifest, error) {
+	subject := fmt.Sprintf("%s.EXPORT.%s", APIPrefix, nodeId)
+	bytes, err := api.performRequest(subject, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var response WorkloadManifest
+	err = json.Unmarshal(bytes, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Requests that the given node deploy each of the workloads in the given manifest, reporting the
+// outcome for each. The client's timeout should allow for the deployment of every workload
+func (api *Client) ImportWorkloads(nodeId string, manifest *WorkloadManifest) (*ImportResponse, error) {
+	subject := fmt.Sprintf("%s.IMPORT.%s", APIPrefix, nodeId)
+	bytes, err := api.performRequest(subject, &ImportRequest{Manifest: *manifest})
+	if err != nil {
+		return nil, err
+	}
+
+	var response ImportResponse
+	err = json.Unmarshal(bytes, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
 // Requests information for a given node within the client's namespace
 func (api *Client) NodeInfo(nodeId string) (*InfoResponse, error) {
 	subject := fmt.Sprintf("%s.INFO.%s.%s", APIPrefix, api.namespace, nodeId)
@@ -96,6 +175,254 @@ func (api *Client) NodeInfo(nodeId string) (*InfoResponse, error) {
 	return &response, nil
 }
 
+// Adjusts the weighted routing of triggers among the workloads sharing a trigger subject on the
+// given node, e.g., to progressively shift traffic from a stable workload to a canary
+func (api *Client) SetTrafficSplit(request *TrafficSplitRequest) (*TrafficSplitResponse, error) {
+	subject := fmt.Sprintf("%s.TRAFFIC.%s.%s", APIPrefix, api.namespace, request.TargetNode)
+	bytes, err := api.performRequest(subject, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var response TrafficSplitResponse
+	err = json.Unmarshal(bytes, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// Stages the artifact indicated by the request on idle agents of the target node, so that
+// subsequent deployments of the artifact in the client's namespace start faster
+func (api *Client) PrewarmWorkload(request *PrewarmRequest) (*PrewarmResponse, error) {
+	subject := fmt.Sprintf("%s.PREWARM.%s.%s", APIPrefix, api.namespace, request.TargetNode)
+	bytes, err := api.performRequest(subject, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var response PrewarmResponse
+	err = json.Unmarshal(bytes, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// Stops every workload on the given node whose tags match the selector, regardless of namespace
+func (api *Client) StopBySelector(request *StopSelectorRequest) (*StopSelectorResponse, error) {
+	subject := fmt.Sprintf("%s.STOPSELECTOR.%s", APIPrefix, request.TargetNode)
+	bytes, err := api.performRequest(subject, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var response StopSelectorResponse
+	err = json.Unmarshal(bytes, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// Returns the number of idle agents the given node keeps in its warm pool
+func (api *Client) GetPoolTarget(nodeId string) (*PoolTargetResponse, error) {
+	return api.poolTarget(nodeId, &PoolTargetRequest{})
+}
+
+// Sets the number of idle agents the given node keeps in its warm pool. The target must lie
+// within the node's configured pool bounds
+func (api *Client) SetPoolTarget(nodeId string, target int) (*PoolTargetResponse, error) {
+	return api.poolTarget(nodeId, &PoolTargetRequest{Target: &target})
+}
+
+// Sets the minimum interval between agent creations while the given node fills its warm pool,
+// pacing the fill to avoid boot storms. A zero interval disables pacing
+func (api *Client) SetPoolCreateInterval(nodeId string, interval time.Duration) (*PoolTargetResponse, error) {
+	millis := int(interval.Milliseconds())
+	return api.poolTarget(nodeId, &PoolTargetRequest{CreateIntervalMillisecond: &millis})
+}
+
+func (api *Client) poolTarget(nodeId string, request *PoolTargetRequest) (*PoolTargetResponse, error) {
+	subject := fmt.Sprintf("%s.POOL.%s", APIPrefix, nodeId)
+	bytes, err := api.performRequest(subject, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var response PoolTargetResponse
+	err = json.Unmarshal(bytes, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// Replays the events the given node most recently published to the client's namespace, allowing
+// a subscriber to catch up on events published before it subscribed. Replay is best-effort
+func (api *Client) EventHistory(nodeId string, request *EventHistoryRequest) (*EventHistoryResponse, error) {
+	subject := fmt.Sprintf("%s.HISTORY.%s.%s", APIPrefix, api.namespace, nodeId)
+	bytes, err := api.performRequest(subject, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var response EventHistoryResponse
+	err = json.Unmarshal(bytes, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// Lists the workloads in the client's namespace which the given node was running when it last
+// stopped, so that they may be redeployed
+func (api *Client) LostWorkloads(nodeId string) (*LostWorkloadsResponse, error) {
+	subject := fmt.Sprintf("%s.LOST.%s.%s", APIPrefix, api.namespace, nodeId)
+	bytes, err := api.performRequest(subject, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response LostWorkloadsResponse
+	err = json.Unmarshal(bytes, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// Writes the stdout and stderr captured for the given workload, which must have been deployed with
+// CaptureLogs, to the given writer. Captured logs are available once the workload has stopped, until
+// the node's retention period for them expires
+func (api *Client) CapturedLogs(nodeId string, workloadId string, w io.Writer) error {
+	subject := fmt.Sprintf("%s.CAPTUREDLOGS.%s.%s", APIPrefix, api.namespace, nodeId)
+	req, err := json.Marshal(CapturedLogsRequest{WorkloadId: workloadId})
+	if err != nil {
+		return err
+	}
+
+	inbox := nats.NewInbox()
+	sub, err := api.nc.SubscribeSync(inbox)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+
+	err = api.nc.PublishRequest(subject, inbox, req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		msg, err := sub.NextMsg(api.timeout)
+		if err != nil {
+			return err
+		}
+
+		if reason := msg.Header.Get(CapturedLogsErrorHeader); reason != "" {
+			return errors.New(reason)
+		}
+		if msg.Header.Get(CapturedLogsEndHeader) == "true" {
+			return nil
+		}
+
+		_, err = w.Write(msg.Data)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Requests an inventory snapshot of the given node's hardware, configuration, pool and
+// running workloads across all namespaces
+func (api *Client) Inventory(nodeId string, request *InventoryRequest) (*InventoryResponse, error) {
+	subject := fmt.Sprintf("%s.INVENTORY.%s", APIPrefix, nodeId)
+	bytes, err := api.performRequest(subject, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var response InventoryResponse
+	err = json.Unmarshal(bytes, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// Requests that the given node update its agents in place with the signed agent binary at the
+// requested location, replacing any agents which fail to update with new machines
+func (api *Client) UpdateAgents(nodeId string, request *AgentUpdateRequest) (*AgentUpdateResponse, error) {
+	subject := fmt.Sprintf("%s.AGENTUPDATE.%s", APIPrefix, nodeId)
+	bytes, err := api.performRequest(subject, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var response AgentUpdateResponse
+	err = json.Unmarshal(bytes, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// Requests a credential from the given node permitting its holder only to subscribe to the logs and
+// events published to the client's namespace, e.g. for a tenant to follow its own workloads
+func (api *Client) IssueEventToken(nodeId string, request *EventTokenRequest) (*EventTokenResponse, error) {
+	subject := fmt.Sprintf("%s.EVENTTOKEN.%s.%s", APIPrefix, api.namespace, nodeId)
+	bytes, err := api.performRequest(subject, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var response EventTokenResponse
+	err = json.Unmarshal(bytes, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// Lists the unexpired event tokens the given node has issued for the client's namespace
+func (api *Client) ListEventTokens(nodeId string) (*EventTokensResponse, error) {
+	return api.eventTokens(nodeId, &EventTokensRequest{})
+}
+
+// Revokes the event tokens with the given ids issued by the given node for the client's namespace,
+// returning the node's remaining unexpired tokens. Revocation is advisory: the node only marks the
+// tokens revoked and announces it, and they remain usable until they expire unless an operator adds
+// them to the account's revocations
+func (api *Client) RevokeEventTokens(nodeId string, ids ...string) (*EventTokensResponse, error) {
+	return api.eventTokens(nodeId, &EventTokensRequest{Revoke: ids})
+}
+
+func (api *Client) eventTokens(nodeId string, request *EventTokensRequest) (*EventTokensResponse, error) {
+	subject := fmt.Sprintf("%s.EVENTTOKENS.%s.%s", APIPrefix, api.namespace, nodeId)
+	bytes, err := api.performRequest(subject, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var response EventTokensResponse
+	err = json.Unmarshal(bytes, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
 func (api *Client) EnterLameDuck(nodeId string) (*LameDuckResponse, error) {
 	subject := fmt.Sprintf("%s.LAMEDUCK.%s", APIPrefix, nodeId)
 	bytes, err := api.performRequest(subject, nil)
@@ -112,6 +439,23 @@ func (api *Client) EnterLameDuck(nodeId string) (*LameDuckResponse, error) {
 	return &response, nil
 }
 
+// Requests a point-in-time OpenMetrics snapshot of the given node's metrics
+func (api *Client) MetricsSnapshot(nodeId string) (*MetricsSnapshotResponse, error) {
+	subject := fmt.Sprintf("%s.METRICS.%s", APIPrefix, nodeId)
+	bytes, err := api.performRequest(subject, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response MetricsSnapshotResponse
+	err = json.Unmarshal(bytes, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
 // This is a filtered node ping that returns only matching workloads.
 // A workloadId of "" will not filter by workload, and only
 // filter by the client's namespace. If a workload ID/name is supplied, the filter
@@ -311,15 +655,20 @@ func handleLogEntry(api *Client, ch chan EmittedLog) func(m *nats.Msg) {
 		var logEntry RawLog
 		err := json.Unmarshal(m.Data, &logEntry)
 		if err != nil {
-			api.log.Error("Log entry deserialization failure", err)
+			api.log.Error("Log entry deserialization failure", slog.Any("err", err))
 			return
 		}
 
+		timestamp := logEntry.LoggedAt
+		if timestamp.IsZero() {
+			timestamp = time.Now().UTC()
+		}
+
 		ch <- EmittedLog{
 			Namespace: tokens[2],
 			NodeId:    tokens[3],
 			Workload:  tokens[4],
-			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Timestamp: timestamp.Format(time.RFC3339),
 			RawLog:    logEntry,
 		}
 	}
@@ -329,6 +678,18 @@ func handleLogEntry(api *Client, ch chan EmittedLog) func(m *nats.Msg) {
 // Helper that submits data, gets a standard envelope back, and returns the inner data
 // payload as JSON
 func (api *Client) performRequest(subject string, raw interface{}) ([]byte, error) {
+	env, err := api.performEnvelopeRequest(subject, raw)
+	if err != nil {
+		return nil, err
+	}
+	if env.Error != nil {
+		return nil, fmt.Errorf("%v", env.Error)
+	}
+	return json.Marshal(env.Data)
+}
+
+// Helper that submits data and returns the standard envelope received in response
+func (api *Client) performEnvelopeRequest(subject string, raw interface{}) (*Envelope, error) {
 	var bytes []byte
 	var err error
 	if raw == nil {
@@ -344,14 +705,7 @@ func (api *Client) performRequest(subject string, raw interface{}) ([]byte, erro
 	if err != nil {
 		return nil, err
 	}
-	env, err := extractEnvelope(resp.Data)
-	if err != nil {
-		return nil, err
-	}
-	if env.Error != nil {
-		return nil, fmt.Errorf("%v", env.Error)
-	}
-	return json.Marshal(env.Data)
+	return extractEnvelope(resp.Data)
 }
 
 func extractEnvelope(data []byte) (*Envelope, error) {
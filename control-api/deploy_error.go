@@ -0,0 +1,97 @@
+package controlapi
+
+// Classifies why a node failed to deploy a workload, so that clients can branch on the failure
+// without parsing its message
+type DeployErrorCode string
+
+const (
+	// The deploy request is malformed or invalid
+	DeployErrorInvalidRequest DeployErrorCode = "invalid_request"
+	// The deploy request's environment could not be decrypted, its signature was rejected, its
+	// trigger subjects are not allowed, or the workload is not authorized to decrypt its artifact
+	DeployErrorUnauthorized DeployErrorCode = "unauthorized"
+	// The node does not satisfy one or more of the request's admission constraints
+	DeployErrorUnschedulable DeployErrorCode = "unschedulable"
+	// The workloads on which the workload depends are not running on the node
+	DeployErrorDependencies DeployErrorCode = "dependencies_not_running"
+	// The workload artifact could not be retrieved or staged
+	DeployErrorArtifact DeployErrorCode = "artifact_unavailable"
+	// A workload of the same name is already running in the namespace
+	DeployErrorConflict DeployErrorCode = "conflict"
+	// The agent to which the workload was submitted failed to deploy it
+	DeployErrorAgent DeployErrorCode = "agent_failure"
+	// Any other failure on the node
+	DeployErrorInternal DeployErrorCode = "internal"
+)
+
+// Machine-readable reasons refining a deploy error code
+const (
+	DeployReasonMalformedRequest        = "malformed_request"
+	DeployReasonInvalidField            = "invalid_field"
+	DeployReasonEnvironmentDecryption   = "environment_decryption_failed"
+	DeployReasonEnvironmentSealing      = "environment_sealing_failed"
+	DeployReasonArtifactDecryption      = "artifact_decryption_failed"
+	DeployReasonInvalidSignature        = "invalid_signature"
+	DeployReasonTriggerSubjectDenied    = "trigger_subject_not_allowed"
+	DeployReasonConstraintsNotSatisfied = "constraints_not_satisfied"
+	DeployReasonDependencyTimeout       = "dependency_timeout"
+	DeployReasonDuplicateWorkload       = "duplicate_workload"
+	DeployReasonNodeStopping            = "node_stopping"
+	DeployReasonArtifactFetchFailed     = "artifact_fetch_failed"
+	DeployReasonArtifactAttachFailed    = "artifact_attach_failed"
+	DeployReasonKeyValueProvisioning    = "key_value_provisioning_failed"
+	DeployReasonAgentPreparationFailed  = "agent_preparation_failed"
+	DeployReasonAgentNotReady           = "agent_not_ready"
+	DeployReasonAgentUnreachable        = "agent_unreachable"
+	DeployReasonAgentRejected           = "agent_rejected"
+	DeployReasonTriggerSubscription     = "trigger_subscription_failed"
+	DeployReasonDeploymentFailed        = "deployment_failed"
+)
+
+// Explains why a node failed to deploy a workload. Returned as the error of a failed run
+// response and from Client.StartWorkload, carrying the human-readable message alongside a code,
+// a machine-readable reason and, where applicable, the invalid field, unsatisfied constraints or
+// dependencies which are not running
+type DeployError struct {
+	Code         DeployErrorCode         `json:"code"`
+	Reason       string                  `json:"reason"`
+	Message      string                  `json:"message"`
+	NodeId       string                  `json:"node_id,omitempty"`
+	Field        string                  `json:"field,omitempty"`
+	Constraints  []UnsatisfiedConstraint `json:"constraints,omitempty"`
+	Dependencies []string                `json:"dependencies,omitempty"`
+}
+
+func NewDeployError(code DeployErrorCode, reason string, message string) *DeployError {
+	return &DeployError{
+		Code:    code,
+		Reason:  reason,
+		Message: message,
+	}
+}
+
+func (e *DeployError) Error() string {
+	return e.Message
+}
+
+// Unwraps an unschedulable deploy error to the *UnschedulableError listing its unsatisfied constraints
+func (e *DeployError) Unwrap() error {
+	if e.Code != DeployErrorUnschedulable || len(e.Constraints) == 0 {
+		return nil
+	}
+
+	return &UnschedulableError{
+		NodeId:      e.NodeId,
+		Constraints: e.Constraints,
+	}
+}
+
+// Returned when validating a request finds the named field invalid
+type InvalidFieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *InvalidFieldError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,67 @@
+package controlapi
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Requests a time-limited NATS credential which permits its holder only to subscribe to the logs and
+// events published to the namespace in which the request is made, e.g. so that a tenant can follow its
+// own workloads without broader access to the bus. Nodes only issue event tokens when configured with
+// an account key with which to sign them
+type EventTokenRequest struct {
+	// Name of the tenant or user to which the token is issued, recorded in the credential
+	Name string `json:"name,omitempty"`
+
+	// Time to live of the token; the node's maximum when not given
+	TTLMillisecond int `json:"ttl_ms,omitempty"`
+}
+
+// An event token issued by a node, identified by the public key of the NATS user to which it was issued
+type EventToken struct {
+	Id        string    `json:"id"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name,omitempty"`
+	Subjects  []string  `json:"subjects"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// Whether the token has been revoked on the node. This is advisory; the NATS servers continue to
+	// accept the credential until it expires or is added to the account's revocations
+	Revoked bool `json:"revoked,omitempty"`
+}
+
+type EventTokenResponse struct {
+	NodeId string     `json:"node_id"`
+	Token  EventToken `json:"token"`
+
+	// The credential, in the format of a NATS .creds file containing the user JWT and seed. The node
+	// does not keep the seed, so the credential cannot be retrieved again
+	Creds string `json:"creds"`
+}
+
+// Lists the unexpired event tokens a node has issued for the namespace in which the request is made,
+// first revoking those with the given ids
+type EventTokensRequest struct {
+	Revoke []string `json:"revoke,omitempty"`
+}
+
+type EventTokensResponse struct {
+	NodeId string       `json:"node_id"`
+	Tokens []EventToken `json:"tokens"`
+}
+
+func (r *EventTokenRequest) Validate() error {
+	var err error
+
+	if r.TTLMillisecond < 0 {
+		err = errors.Join(err, errors.New("event token ttl must be >= 0"))
+	}
+
+	if strings.ContainsAny(r.Name, "\r\n") {
+		err = errors.Join(err, errors.New("event token name must be a single line"))
+	}
+
+	return err
+}
@@ -1,14 +1,28 @@
 package controlapi
 
+import "time"
+
 const (
-	AgentStartedEventType    = "agent_started"
-	AgentStoppedEventType    = "agent_stopped"
-	NodeStartedEventType     = "node_started"
-	NodeStoppedEventType     = "node_stopped"
-	LameDuckEnteredEventType = "node_entered_lameduck"
-	HeartbeatEventType       = "heartbeat"
-	WorkloadStartedEventType = "workload_started" // FIXME-- should this be WorkloadDeployed?
-	WorkloadStoppedEventType = "workload_stopped" // FIXME-- should this be in addition to WorkloadUndeployed (likely yes, in case of something bad happening...)
+	AgentHealthChangedEventType      = "agent_health_changed"
+	AgentReapedIdleEventType         = "agent_reaped_idle"
+	AgentStartedEventType            = "agent_started"
+	AgentStoppedEventType            = "agent_stopped"
+	ArtifactCacheMissEventType       = "artifact_cache_miss"
+	EventTokenRevokedEventType       = "event_token_revoked"
+	NodeHealthChangedEventType       = "node_health_changed"
+	NodeStartedEventType             = "node_started"
+	NodeStoppedEventType             = "node_stopped"
+	PoolRefillChangedEventType       = "pool_refill_changed"
+	PoolStarvedEventType             = "pool_starved"
+	LameDuckEnteredEventType         = "node_entered_lameduck"
+	LameDuckDrainedEventType         = "node_lameduck_drained"
+	HeartbeatEventType               = "heartbeat"
+	WorkloadStartedEventType         = "workload_started" // FIXME-- should this be WorkloadDeployed?
+	WorkloadMigratedEventType        = "workload_migrated"
+	WorkloadOutOfMemoryEventType     = "workload_out_of_memory"
+	WorkloadSelectorStoppedEventType = "workload_selector_stopped"
+	WorkloadsLostEventType           = "workloads_lost"
+	WorkloadStoppedEventType         = "workload_stopped" // FIXME-- should this be in addition to WorkloadUndeployed (likely yes, in case of something bad happening...)
 	// FIXME-- where is WorkloadDeployedEventType? (likely just need to rename WorkloadStartedEventType -> WorkloadDeployedEventType)
 	// FIXME-- where is WorkloadStoppedEventType?
 )
@@ -17,15 +31,90 @@ type AgentStartedEvent struct {
 	AgentVersion string `json:"agent_version"`
 }
 
+// Published when deploying a workload misses the given artifact cache, i.e. the node's
+// internal cache or an agent's prepared artifact, and the artifact is fetched instead
+type ArtifactCacheMissEvent struct {
+	Name         string `json:"workload_name"`
+	WorkloadType string `json:"workload_type"`
+	Cache        string `json:"cache"`
+}
+
 type WorkloadStartedEvent struct {
 	Name       string `json:"workload_name"`
 	TotalBytes int    `json:"total_bytes"`
 }
 
+// Reasons given on the stopped events published by the node, distinguishing workloads stopped
+// individually from those stopped because their node is shutting down or because they were replaced
+// by a workload of the same name or drained from a node in lame duck mode
+const (
+	WorkloadStopReasonRequested    = "Workload shutdown requested"
+	WorkloadStopReasonNodeShutdown = "Node shutdown"
+	WorkloadStopReasonReplaced     = "Workload replaced"
+	WorkloadStopReasonRestarted    = "Workload restarted"
+	WorkloadStopReasonLameDuck     = "Node entered lame duck mode"
+	WorkloadStopReasonDrainExpired = "Node lame duck drain deadline passed"
+)
+
 type WorkloadStoppedEvent struct {
 	Name    string `json:"workload_name"`
 	Code    int    `json:"code"`
 	Message string `json:"message"`
+	// Exit code of the workload process, set on the stopped event published by the node once
+	// the workload has been stopped
+	ExitCode *int `json:"exit_code,omitempty"`
+
+	// Set on the stopped event published by the agent once the workload has exited: the signal
+	// which terminated the workload, if any, the time for which it ran, and the number of bytes it
+	// wrote to stdout and stderr
+	Signal             string `json:"signal,omitempty"`
+	RuntimeMillisecond int64  `json:"runtime_ms,omitempty"`
+	StdoutBytes        int64  `json:"stdout_bytes,omitempty"`
+	StderrBytes        int64  `json:"stderr_bytes,omitempty"`
+}
+
+// Exit code reported for a workload killed for exceeding its memory limit, outside the range of
+// codes reported for workloads terminated by a signal
+const ExitCodeOutOfMemory = 251
+
+// Published by an agent when its workload is killed for exceeding its memory limit, ahead of the
+// workload's stopped event
+type WorkloadOutOfMemoryEvent struct {
+	Name           string `json:"workload_name"`
+	MemoryLimitMib int    `json:"memory_limit_mib"`
+}
+
+type WorkloadMigratedEvent struct {
+	Name             string `json:"workload_name"`
+	SourceNode       string `json:"source_node"`
+	SourceWorkloadId string `json:"source_workload_id"`
+	TargetNode       string `json:"target_node"`
+	TargetWorkloadId string `json:"target_workload_id"`
+}
+
+// Published for each workload stopped by a tag selector, carrying the selector as context
+type WorkloadSelectorStoppedEvent struct {
+	Name       string            `json:"workload_name"`
+	WorkloadId string            `json:"workload_id"`
+	Selector   map[string]string `json:"selector"`
+}
+
+// Published when an agent misses the configured number of consecutive heartbeats, marking the
+// agent and its workload, if any, degraded, and again once the agent's heartbeats resume
+type AgentHealthChangedEvent struct {
+	Id               string `json:"id"`
+	WorkloadName     string `json:"workload_name,omitempty"`
+	Degraded         bool   `json:"degraded"`
+	MissedHeartbeats int    `json:"missed_heartbeats,omitempty"`
+	LastHeartbeat    string `json:"last_heartbeat"`
+}
+
+// Published when an agent which sat idle in the warm pool longer than the node's idle threshold is
+// stopped, shrinking the warm pool to the given target
+type AgentReapedIdleEvent struct {
+	Id              string `json:"id"`
+	IdleMillisecond int64  `json:"idle_ms"`
+	PoolTarget      int    `json:"pool_target"`
 }
 
 type AgentStoppedEvent struct {
@@ -33,9 +122,44 @@ type AgentStoppedEvent struct {
 	Code    int    `json:"code"`
 }
 
+// Published in the system namespace when an event token is revoked. The node cannot itself revoke
+// a credential from the NATS server, so the user identified by the token's id should be added to the
+// revocations of the signing account until the token expires
+type EventTokenRevokedEvent struct {
+	Id        string    `json:"id"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 type NodeStartedEvent struct {
 	Version string `json:"version"`
 	Id      string `json:"id"`
+	NodeAdvertisement
+}
+
+// Capabilities and free capacity a node advertises when it starts and in each of its heartbeats,
+// from which a scheduler may select the nodes able to run a workload. A node not heard from within
+// a few heartbeat intervals may be considered gone
+type NodeAdvertisement struct {
+	Tags          map[string]string `json:"tags,omitempty"`
+	WorkloadTypes []string          `json:"workload_types,omitempty"`
+	OS            string            `json:"os,omitempty"`
+	Arch          string            `json:"arch,omitempty"`
+	LameDuck      bool              `json:"lame_duck,omitempty"`
+	// Free capacity as of the event; nil when the node could not determine it
+	Capacity *NodeFreeCapacity `json:"capacity,omitempty"`
+
+	HeartbeatIntervalMillisecond int `json:"heartbeat_interval_ms,omitempty"`
+}
+
+// Resources of a node not yet allocated to the machines running workloads. Memory is omitted when
+// the host's memory cannot be read, and the number of workloads when the node accepts any number
+type NodeFreeCapacity struct {
+	Workloads  *int `json:"workloads,omitempty"`
+	Vcpu       int  `json:"vcpu"`
+	MemoryMib  int  `json:"memory_mib,omitempty"`
+	IdleAgents int  `json:"idle_agents"`
 }
 
 type LameDuckEnteredEvent struct {
@@ -43,15 +167,52 @@ type LameDuckEnteredEvent struct {
 	Id      string `json:"id"`
 }
 
+// Published once every workload on a node in lame duck mode has stopped, including the number
+// still running at the drain deadline which were stopped by the node
+type LameDuckDrainedEvent struct {
+	Id          string `json:"id"`
+	ForcedStops int    `json:"forced_stops"`
+}
+
+// Published when a node starts and finds workloads it was running when it last stopped, which can
+// be listed with LostWorkloads so that they may be redeployed
+type WorkloadsLostEvent struct {
+	Id    string `json:"id"`
+	Count int    `json:"count"`
+}
+
+// Published when a node becomes unhealthy, e.g. because its internal object store is unavailable,
+// and again when it recovers
+type NodeHealthChangedEvent struct {
+	Id      string `json:"id"`
+	Healthy bool   `json:"healthy"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Published when a node pauses refilling its warm pool because its machine quota is exhausted,
+// and again when it resumes refilling once capacity is freed
+type PoolRefillChangedEvent struct {
+	Id     string `json:"id"`
+	Paused bool   `json:"paused"`
+}
+
+// Published when a deployment finds no idle agent in the warm pool, and so either waits for a
+// machine to be created or is rejected
+type PoolStarvedEvent struct {
+	Id         string `json:"id"`
+	PoolTarget int    `json:"pool_target"`
+	Rejected   bool   `json:"rejected"`
+}
+
 type NodeStoppedEvent struct {
 	Id       string `json:"id"`
 	Graceful bool   `json:"graceful"`
 }
 
 type HeartbeatEvent struct {
-	Version         string            `json:"version"`
-	NodeId          string            `json:"node_id"`
-	Uptime          string            `json:"uptime"`
-	Tags            map[string]string `json:"tags,omitempty"`
-	RunningMachines int               `json:"running_machines"`
+	Version         string `json:"version"`
+	NodeId          string `json:"node_id"`
+	Uptime          string `json:"uptime"`
+	RunningMachines int    `json:"running_machines"`
+	NodeAdvertisement
 }
@@ -0,0 +1,64 @@
+package controlapi
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var validCommitSHA = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// A git source from which a node clones and (optionally) builds a workload artifact. Sources are
+// pinned by commit SHA for reproducibility; the node verifies the checked out commit after cloning
+type GitSource struct {
+	// URL of the git repository to clone
+	Repository string `json:"repository"`
+	// Optional branch or tag to clone, which must contain the pinned commit
+	Ref string `json:"ref,omitempty"`
+	// Full SHA of the commit to check out
+	Commit string `json:"commit"`
+	// Optional path within the repository in which the build command is run
+	Subpath string `json:"subpath,omitempty"`
+	// Optional shell command used to build the artifact
+	BuildCommand *string `json:"build_command,omitempty"`
+	// Path of the resulting artifact, relative to the subpath
+	Artifact string `json:"artifact"`
+}
+
+func (g *GitSource) Validate() error {
+	var err error
+
+	if strings.TrimSpace(g.Repository) == "" {
+		err = errors.Join(err, errors.New("git repository is required"))
+	}
+
+	if !validCommitSHA.MatchString(g.Commit) {
+		err = errors.Join(err, errors.New("git commit must be a full commit SHA"))
+	}
+
+	if strings.HasPrefix(g.Ref, "-") {
+		err = errors.Join(err, errors.New("invalid git ref"))
+	}
+
+	if !isRelativeSubpath(g.Subpath) {
+		err = errors.Join(err, errors.New("git subpath must be a relative path within the repository"))
+	}
+
+	if strings.TrimSpace(g.Artifact) == "" {
+		err = errors.Join(err, errors.New("git artifact path is required"))
+	} else if !isRelativeSubpath(g.Artifact) {
+		err = errors.Join(err, errors.New("git artifact must be a relative path within the repository"))
+	}
+
+	return err
+}
+
+func isRelativeSubpath(p string) bool {
+	if p == "" {
+		return true
+	}
+
+	clean := filepath.Clean(p)
+	return !filepath.IsAbs(clean) && clean != ".." && !strings.HasPrefix(clean, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,25 @@
+package controlapi
+
+import (
+	cloudevents "github.com/cloudevents/sdk-go"
+)
+
+// Requests a replay of the events a node most recently published to the client's namespace.
+// Event history is best-effort and held in memory: it does not survive a node restart, and
+// the oldest events are evicted once the node's configured history size is reached
+type EventHistoryRequest struct {
+	// Maximum number of events to replay, newest retained; zero replays every recorded event
+	Count int `json:"count,omitempty"`
+	// Optional name of the workload to which replayed events must pertain
+	Workload string `json:"workload,omitempty"`
+	// Optional type of the events to replay, e.g. workload_started
+	EventType string `json:"event_type,omitempty"`
+}
+
+type EventHistoryResponse struct {
+	NodeId string `json:"node_id"`
+	// Replayed events, oldest first
+	Events []cloudevents.Event `json:"events"`
+	// True if older matching events were omitted to fit the response in a single message
+	Truncated bool `json:"truncated"`
+}
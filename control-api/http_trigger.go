@@ -0,0 +1,35 @@
+package controlapi
+
+import (
+	"encoding/json"
+)
+
+// Trigger payload with which a node's HTTP gateway invokes the workload to which a request's path
+// is routed. Bodies are carried as text
+type HTTPTriggerRequest struct {
+	Method  string              `json:"method"`
+	Path    string              `json:"path"`
+	Query   string              `json:"query,omitempty"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    string              `json:"body,omitempty"`
+}
+
+// Response with which a workload triggered through a node's HTTP gateway describes the HTTP response.
+// A workload responding with anything else has its response returned as the body of a 200 response
+type HTTPTriggerResponse struct {
+	Status  int                 `json:"status"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    string              `json:"body,omitempty"`
+}
+
+// Parses a workload's response to an HTTP trigger, returning nil if it does not describe an HTTP
+// response
+func ParseHTTPTriggerResponse(data []byte) *HTTPTriggerResponse {
+	var resp HTTPTriggerResponse
+	err := json.Unmarshal(data, &resp)
+	if err != nil || resp.Status < 100 || resp.Status > 599 {
+		return nil
+	}
+
+	return &resp
+}
@@ -0,0 +1,98 @@
+package controlapi
+
+import "time"
+
+// Requests a complete inventory snapshot of a node, spanning all namespaces, for use by
+// fleet-wide collectors and capacity planning
+type InventoryRequest struct {
+	// When true, the usage the node records for each running workload is included
+	IncludeUsage bool `json:"include_usage,omitempty"`
+}
+
+type InventoryResponse struct {
+	NodeId        string              `json:"node_id"`
+	Version       string              `json:"version"`
+	Timestamp     time.Time           `json:"timestamp"`
+	Healthy       bool                `json:"healthy"`
+	LameDuck      bool                `json:"lame_duck"`
+	Hardware      InventoryHardware   `json:"hardware"`
+	Capacity      InventoryCapacity   `json:"capacity"`
+	Config        InventoryConfig     `json:"config"`
+	WorkloadTypes []string            `json:"workload_types"`
+	Pool          InventoryPool       `json:"pool"`
+	Workloads     []InventoryWorkload `json:"workloads"`
+}
+
+// Host resources of a node
+type InventoryHardware struct {
+	Cpus   int         `json:"cpus"`
+	Memory *MemoryStat `json:"memory,omitempty"`
+}
+
+// Host resources which may be allocated to machines running workloads: the host's total resources
+// less those reserved for the host OS, the node and telemetry. Memory figures are omitted when the
+// host's memory cannot be read
+type InventoryCapacity struct {
+	TotalVcpu            int `json:"total_vcpu"`
+	TotalMemoryMib       int `json:"total_memory_mib,omitempty"`
+	ReservedVcpu         int `json:"reserved_vcpu"`
+	ReservedMemoryMib    int `json:"reserved_memory_mib"`
+	AllocatableVcpu      int `json:"allocatable_vcpu"`
+	AllocatableMemoryMib int `json:"allocatable_memory_mib,omitempty"`
+	// Resources allocated to the machines running workloads; zero on a node without a sandbox
+	AllocatedVcpu      int `json:"allocated_vcpu"`
+	AllocatedMemoryMib int `json:"allocated_memory_mib"`
+	// Resources allocated to the machines running workloads, by namespace
+	Namespaces map[string]NamespaceAllocation `json:"namespaces,omitempty"`
+}
+
+// Resources allocated to the machines running the workloads of a namespace
+type NamespaceAllocation struct {
+	Machines   int `json:"machines"`
+	VcpuCount  int `json:"vcpu_count"`
+	MemSizeMib int `json:"mem_size_mib"`
+}
+
+// Summary of the configuration relevant to a node's capacity
+type InventoryConfig struct {
+	Sandboxed    bool              `json:"sandboxed"`
+	VcpuCount    int               `json:"vcpu_count,omitempty"`
+	MemSizeMib   int               `json:"mem_size_mib,omitempty"`
+	HostServices bool              `json:"host_services"`
+	Tags         map[string]string `json:"tags,omitempty"`
+	// Maximum number of workloads the node accepts; zero is unlimited
+	MaxWorkloads int `json:"max_workloads,omitempty"`
+	// Environment merged beneath that of every workload; sensitive values are redacted
+	DefaultWorkloadEnvironment map[string]string `json:"default_workload_environment,omitempty"`
+}
+
+// State of a node's warm pool of idle agents
+type InventoryPool struct {
+	Target int `json:"target"`
+	Min    int `json:"min"`
+	Max    int `json:"max"`
+	Idle   int `json:"idle"`
+}
+
+type InventoryWorkload struct {
+	Id           string              `json:"id"`
+	Namespace    string              `json:"namespace"`
+	Name         string              `json:"name"`
+	WorkloadType string              `json:"type"`
+	Hash         string              `json:"hash"`
+	Allocation   *WorkloadAllocation `json:"allocation,omitempty"`
+	Usage        *WorkloadUsage      `json:"usage,omitempty"`
+}
+
+// Resources allocated to the machine running a workload. Workloads on a node without a
+// sandbox share the host's resources and report no allocation
+type WorkloadAllocation struct {
+	VcpuCount  int `json:"vcpu_count"`
+	MemSizeMib int `json:"mem_size_mib"`
+}
+
+// Usage recorded by the node for a running workload
+type WorkloadUsage struct {
+	UptimeMillisecond  int64 `json:"uptime_ms"`
+	ExecTimeNanosecond int64 `json:"exec_time_ns"`
+}
@@ -0,0 +1,50 @@
+package controlapi
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+var validKeyValueBucketName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// A key/value bucket required by a workload, which the node provisions on deploy if it does not
+// already exist. Buckets are named by the node's key/value host service bucket name template, and
+// are therefore scoped to the workload's namespace
+type KeyValueBucket struct {
+	// Optional name substituted for the workload name in the bucket name template; when empty, the
+	// bucket is the workload's own key/value host service bucket
+	Name string `json:"name,omitempty"`
+	// Optional maximum age of the bucket's entries, in milliseconds
+	TTLMillisecond int64 `json:"ttl_ms,omitempty"`
+	// Optional number of historical values kept per key
+	History uint8 `json:"history,omitempty"`
+	// Optional maximum size of the bucket, in bytes
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+	// Optional maximum size of a single value, in bytes
+	MaxValueSize int32 `json:"max_value_size,omitempty"`
+	// When true, the bucket is deleted when the workload is stopped
+	DeleteOnUndeploy bool `json:"delete_on_undeploy,omitempty"`
+}
+
+func (b *KeyValueBucket) Validate() error {
+	var err error
+
+	if b.Name != "" && !validKeyValueBucketName.MatchString(b.Name) {
+		err = errors.Join(err, fmt.Errorf("invalid key/value bucket name: %s", b.Name))
+	}
+
+	if b.TTLMillisecond < 0 {
+		err = errors.Join(err, errors.New("key/value bucket ttl must be >= 0"))
+	}
+
+	if b.History > 64 {
+		err = errors.Join(err, errors.New("key/value bucket history must be <= 64"))
+	}
+
+	if b.MaxBytes < 0 || b.MaxValueSize < 0 {
+		err = errors.Join(err, errors.New("key/value bucket limits must be >= 0"))
+	}
+
+	return err
+}
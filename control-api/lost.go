@@ -0,0 +1,19 @@
+package controlapi
+
+import "time"
+
+// A workload which was running on a node when the node last stopped, as recorded when the workload
+// was deployed. Its deploy request omits the workload's environment, which nodes do not persist,
+// and must be given one before the workload can be redeployed
+type LostWorkload struct {
+	Id         string         `json:"id"`
+	Name       string         `json:"name"`
+	Namespace  string         `json:"namespace"`
+	DeployedAt time.Time      `json:"deployed_at"`
+	Request    *DeployRequest `json:"request"`
+}
+
+type LostWorkloadsResponse struct {
+	NodeId    string         `json:"node_id"`
+	Workloads []LostWorkload `json:"workloads"`
+}
@@ -0,0 +1,58 @@
+package controlapi
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nkeys"
+)
+
+// Requests that the source node hand a running workload off to the target node. The source node
+// redeploys the workload's artifact, environment and trigger subjects on the target, and only
+// stops its own copy of the workload once the target has confirmed the deployment
+type MigrateRequest struct {
+	WorkloadId  string `json:"workload_id"`
+	WorkloadJwt string `json:"workload_jwt"`
+	SourceNode  string `json:"source_node"`
+	TargetNode  string `json:"target_node"`
+}
+
+type MigrateResponse struct {
+	Migrated         bool   `json:"migrated"`
+	Name             string `json:"name"`
+	Issuer           string `json:"issuer"`
+	SourceNode       string `json:"source_node"`
+	SourceWorkloadId string `json:"source_workload_id"`
+	TargetNode       string `json:"target_node"`
+	TargetWorkloadId string `json:"target_workload_id"`
+}
+
+func NewMigrateRequest(workloadId string, name string, sourceNode string, targetNode string, issuer nkeys.KeyPair) (*MigrateRequest, error) {
+	claims := jwt.NewGenericClaims(name)
+	jwtText, err := claims.Encode(issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MigrateRequest{
+		WorkloadId:  workloadId,
+		WorkloadJwt: jwtText,
+		SourceNode:  sourceNode,
+		TargetNode:  targetNode,
+	}, nil
+}
+
+// Validates the migration request against the claims with which the workload was originally
+// started; only the issuer that started a workload may migrate it
+func (request *MigrateRequest) Validate(originalClaims *jwt.GenericClaims) error {
+	if strings.TrimSpace(request.TargetNode) == "" {
+		return errors.New("target node is required")
+	}
+
+	if request.TargetNode == request.SourceNode {
+		return errors.New("target node must differ from the source node")
+	}
+
+	return validateIssuerClaims("migration", "migrate", request.WorkloadJwt, originalClaims)
+}
@@ -0,0 +1,17 @@
+package controlapi
+
+// Queries or, when a target is given, sets the number of idle agents a node keeps in its warm pool.
+// When a create interval is given, sets the minimum interval between agent creations while the node
+// fills its warm pool
+type PoolTargetRequest struct {
+	Target                    *int `json:"target,omitempty"`
+	CreateIntervalMillisecond *int `json:"create_interval_ms,omitempty"`
+}
+
+type PoolTargetResponse struct {
+	NodeId                    string `json:"node_id"`
+	Target                    int    `json:"target"`
+	Min                       int    `json:"min"`
+	Max                       int    `json:"max"`
+	CreateIntervalMillisecond int64  `json:"create_interval_ms"`
+}
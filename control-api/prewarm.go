@@ -0,0 +1,77 @@
+package controlapi
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// Requests that a node stage the given artifact on idle agents in its pool, so that subsequent
+// deployments of that artifact skip fetching it. Prepared agents that are not claimed by a
+// deployment within the idle timeout are reaped
+type PrewarmRequest struct {
+	WorkloadType string   `json:"type"`
+	Location     *url.URL `json:"location"`
+	TargetNode   string   `json:"target_node"`
+
+	// If the location indicates an object store bucket & key, JS domain can be supplied
+	JsDomain *string `json:"jsdomain,omitempty"`
+
+	// Number of agents to prepare; defaults to 1
+	Count int `json:"count,omitempty"`
+
+	// Optional override of the node's configured timeout after which unclaimed prepared agents are reaped
+	IdleTimeoutMillisecond *int `json:"idle_timeout_ms,omitempty"`
+
+	// Optional id or IP address of the idle VM to prepare, rather than whichever VMs the node
+	// selects. A debugging aid, only honored by nodes which allow VM pinning
+	TargetVM *string `json:"target_vm,omitempty"`
+}
+
+type PrewarmResponse struct {
+	Hash         string   `json:"hash"`
+	WorkloadType string   `json:"type"`
+	Prepared     []string `json:"prepared"`
+}
+
+func NewPrewarmRequest(workloadType string, location string, targetNode string, count int) (*PrewarmRequest, error) {
+	loc, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PrewarmRequest{
+		WorkloadType: workloadType,
+		Location:     loc,
+		TargetNode:   targetNode,
+		Count:        count,
+	}, nil
+}
+
+func (r *PrewarmRequest) Validate() error {
+	var err error
+
+	if strings.TrimSpace(r.WorkloadType) == "" {
+		err = errors.Join(err, errors.New("workload type is required"))
+	}
+
+	if r.Location == nil {
+		err = errors.Join(err, errors.New("artifact location is required"))
+	} else if !strings.EqualFold(r.Location.Scheme, "nats") {
+		err = errors.Join(err, errors.New("artifact location must be a nats object store url"))
+	}
+
+	if r.Count < 0 {
+		err = errors.Join(err, errors.New("count must be >= 0"))
+	}
+
+	if r.TargetVM != nil && r.Count > 1 {
+		err = errors.Join(err, errors.New("count must be 1 when a target vm is given"))
+	}
+
+	if r.IdleTimeoutMillisecond != nil && *r.IdleTimeoutMillisecond <= 0 {
+		err = errors.Join(err, errors.New("idle timeout must be > 0"))
+	}
+
+	return err
+}
@@ -0,0 +1,124 @@
+package controlapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nkeys"
+)
+
+const (
+	// Claim of a deploy request's signature carrying the hash of the signed request
+	requestHashClaim = "request_hash"
+	// Claim of a deploy request's signature carrying the nonce with which replays are detected
+	requestNonceClaim = "nonce"
+)
+
+// Claims of a verified deploy request signature
+type RequestSignatureClaims struct {
+	// Public key of the signer
+	Issuer    string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Nonce     string
+}
+
+// Signs the deploy request with the given key, carrying the time at which it was signed, a nonce and
+// an expiry after the given time to live. The request must not be modified once signed
+func (request *DeployRequest) Sign(kp nkeys.KeyPair, ttl time.Duration) error {
+	if ttl <= 0 {
+		return errors.New("signed deploy requests must expire")
+	}
+
+	hash, err := request.signingHash()
+	if err != nil {
+		return err
+	}
+
+	claims := jwt.NewGenericClaims(hash)
+	claims.Expires = time.Now().UTC().Add(ttl).Unix()
+	claims.Data[requestHashClaim] = hash
+	claims.Data[requestNonceClaim] = uuid.NewString()
+
+	token, err := claims.Encode(kp)
+	if err != nil {
+		return fmt.Errorf("failed to sign deploy request: %s", err)
+	}
+
+	request.RequestJwt = &token
+	return nil
+}
+
+// Verifies the deploy request's signature and expiry, returning its claims. Whether the signer is
+// trusted, and whether the nonce has been seen before, is left to the recipient
+func (request *DeployRequest) VerifySignature() (*RequestSignatureClaims, error) {
+	if request.RequestJwt == nil {
+		return nil, errors.New("deploy request is not signed")
+	}
+
+	claims, err := jwt.DecodeGeneric(*request.RequestJwt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deploy request signature: %s", err)
+	}
+
+	if claims.Expires == 0 {
+		return nil, errors.New("deploy request signature does not expire")
+	}
+
+	if time.Now().UTC().Unix() > claims.Expires {
+		return nil, errors.New("deploy request signature has expired")
+	}
+
+	var vr jwt.ValidationResults
+	claims.Validate(&vr)
+	if vr.IsBlocking(true) {
+		var errs []error
+		for _, issue := range vr.Issues {
+			if issue.Blocking || issue.TimeCheck {
+				errs = append(errs, errors.New(issue.Description))
+			}
+		}
+		return nil, fmt.Errorf("invalid deploy request signature: %w", errors.Join(errs...))
+	}
+
+	hash, err := request.signingHash()
+	if err != nil {
+		return nil, err
+	}
+
+	if signed, _ := claims.Data[requestHashClaim].(string); signed != hash {
+		return nil, errors.New("deploy request does not match its signature")
+	}
+
+	nonce, _ := claims.Data[requestNonceClaim].(string)
+	if nonce == "" {
+		return nil, errors.New("deploy request signature has no nonce")
+	}
+
+	return &RequestSignatureClaims{
+		Issuer:    claims.Issuer,
+		IssuedAt:  time.Unix(claims.IssuedAt, 0).UTC(),
+		ExpiresAt: time.Unix(claims.Expires, 0).UTC(),
+		Nonce:     nonce,
+	}, nil
+}
+
+// Returns the hex-encoded sha256 hash of the request as serialized without its signature
+func (request *DeployRequest) signingHash() (string, error) {
+	unsigned := *request
+	unsigned.RequestJwt = nil
+
+	raw, err := json.Marshal(&unsigned)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(raw)
+	return hex.EncodeToString(hash[:]), nil
+}
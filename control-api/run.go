@@ -3,10 +3,10 @@ package controlapi
 import (
 	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/url"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/nats-io/jwt/v2"
@@ -23,27 +23,127 @@ type DeployRequest struct {
 	// Contains claims for the workload: name, hash
 	WorkloadJwt *string `json:"workload_jwt"`
 
+	// Optional signature of the request, carrying the time at which it was issued, its expiry and a
+	// nonce, as produced by Sign. Nodes may be configured to reject unsigned requests
+	RequestJwt *string `json:"request_jwt,omitempty"`
+
 	// A base64-encoded byte array that contains an encrypted json-serialized map[string]string.
 	Environment *string `json:"environment"`
 
 	// If the payload indicates an object store bucket & key, JS domain can be supplied
 	JsDomain *string `json:"jsdomain,omitempty"`
 
+	// Optional git source from which the node clones and builds the workload artifact, used
+	// in place of the location
+	GitSource *GitSource `json:"git_source,omitempty"`
+
+	// Optional name of the node's internal artifact bucket in which the workload is cached; must be
+	// one of the artifact buckets allowed by the target node's configuration
+	ArtifactBucket *string `json:"artifact_bucket,omitempty"`
+
 	SenderPublicKey *string  `json:"sender_public_key"`
 	TargetNode      *string  `json:"target_node"`
 	TriggerSubjects []string `json:"trigger_subjects,omitempty"`
 
+	// Optional subjects, which may contain wildcards, on which the workload may be triggered. When
+	// set, each trigger subject must fall within them, and the workload is never triggered on any
+	// other subject, e.g. one matched by a wildcard trigger subject
+	AllowedTriggerSubjects []string `json:"allowed_trigger_subjects,omitempty"`
+
 	RetryCount *uint      `json:"retry_count,omitempty"`
 	RetriedAt  *time.Time `json:"retried_at,omitempty"`
 
+	// Optional override of the node's configured grace period given to the workload to shut
+	// down cleanly when stopped, before it is forcibly terminated
+	StopGracePeriodMillisecond *int `json:"stop_grace_period_ms,omitempty"`
+
+	// Optional time within which each trigger of a function workload must complete, or its
+	// streamed response begin, before it fails as timed out; 10 seconds when not set
+	TriggerTimeoutMillisecond *int `json:"trigger_timeout_ms,omitempty"`
+
+	// Optional tags describing the workload, against which bulk operations such as stopping
+	// by tag selector are matched
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// Optional uid and gid as which the workload is run within the agent; when not set the workload
+	// is run as root. Only supported by elf workloads
+	Uid *int `json:"uid,omitempty"`
+	Gid *int `json:"gid,omitempty"`
+
+	// Optional absolute path of the working directory in which the workload is run, created within
+	// the agent if it does not exist. Only supported by elf workloads
+	WorkingDirectory *string `json:"working_directory,omitempty"`
+
+	// Optional limit on the memory used by the workload, enforced within the agent independently of
+	// the size of its machine; a workload exceeding it is killed and exits with ExitCodeOutOfMemory.
+	// Only supported by elf workloads
+	MemoryLimitMib *int `json:"memory_limit_mib,omitempty"`
+
+	// Optional key/value buckets required by the workload, provisioned by the node on deploy
+	KeyValueBuckets []KeyValueBucket `json:"kv_buckets,omitempty"`
+
+	// Optional resources required by the workload, used by the node to place it on the smallest
+	// idle machine which satisfies them
+	Resources *WorkloadResources `json:"resources,omitempty"`
+
+	// Optional fraction (0.0-1.0) of the workload's triggers which are traced, and for which
+	// per-trigger logs and events are emitted; when not set the node's sampling rate applies
+	TraceSamplingRate *float64 `json:"trace_sampling_rate,omitempty"`
+
+	// Optional names of workloads in the same namespace which must be running on the node before
+	// this workload is deployed; the node holds the deployment until they are, up to its
+	// configured dependency timeout
+	Dependencies []string `json:"dependencies,omitempty"`
+
+	// Optional id or IP address of the idle VM on the target node to which the workload is deployed,
+	// bypassing normal agent selection. A debugging aid, only honored by nodes which allow VM pinning
+	TargetVM *string `json:"target_vm,omitempty"`
+
+	// When true, the workload's complete stdout and stderr are also captured into the node's internal
+	// object store, up to the node's configured size cap, and may be fetched after the workload is gone
+	CaptureLogs bool `json:"capture_logs,omitempty"`
+
+	// Values may reference ${nex.workload_id}, ${nex.workload_name}, ${nex.namespace}, ${nex.node_id},
+	// ${nex.node_name} and ${nex.vm_ip}, resolved by the node when the workload is placed
 	WorkloadEnvironment map[string]string `json:"-"`
 	DecodedClaims       jwt.GenericClaims `json:"-"`
 }
 
+// Resources required by a workload; a zero value places no requirement on the machine
+type WorkloadResources struct {
+	VcpuCount  int `json:"vcpu_count,omitempty"`
+	MemSizeMib int `json:"mem_size_mib,omitempty"`
+}
+
+// Schemes of workload artifact locations. Artifacts in NATS object stores are cached by the node
+// for its agents, while artifacts at HTTP(S) URLs and in OCI registries are retrieved by the agent
+// directly, e.g. https://example.com/echoservice or oci://registry.example.com/echoservice:1.0
+const (
+	ArtifactSchemeNATS  = "nats"
+	ArtifactSchemeHTTP  = "http"
+	ArtifactSchemeHTTPS = "https"
+	ArtifactSchemeOCI   = "oci"
+)
+
 var (
 	validWorkloadName = regexp.MustCompile(`^[a-z]+$`)
 )
 
+// Returns true if the artifact at the given location is retrieved by the agent to which the
+// workload is deployed, rather than cached by the node
+func ArtifactRetrievedByAgent(location *url.URL) bool {
+	if location == nil {
+		return false
+	}
+
+	switch strings.ToLower(location.Scheme) {
+	case ArtifactSchemeHTTP, ArtifactSchemeHTTPS, ArtifactSchemeOCI:
+		return true
+	default:
+		return false
+	}
+}
+
 // Creates a new deploy request based on the supplied options. Note that there is a fluent API function
 // for each available option
 func NewDeployRequest(opts ...RequestOption) (*DeployRequest, error) {
@@ -78,6 +178,71 @@ func NewDeployRequest(opts ...RequestOption) (*DeployRequest, error) {
 		TargetNode:      &reqOpts.targetNode,
 		TriggerSubjects: reqOpts.triggerSubjects,
 		JsDomain:        &reqOpts.jsDomain,
+		Tags:            reqOpts.tags,
+
+		AllowedTriggerSubjects: reqOpts.allowedTriggerSubjects,
+	}
+
+	if reqOpts.stopGracePeriod != nil {
+		millis := int(reqOpts.stopGracePeriod.Milliseconds())
+		req.StopGracePeriodMillisecond = &millis
+	}
+
+	if reqOpts.triggerTimeout != nil {
+		millis := int(reqOpts.triggerTimeout.Milliseconds())
+		req.TriggerTimeoutMillisecond = &millis
+	}
+
+	if reqOpts.gitSource != nil {
+		req.GitSource = reqOpts.gitSource
+	}
+
+	if reqOpts.artifactBucket != "" {
+		req.ArtifactBucket = &reqOpts.artifactBucket
+	}
+
+	if reqOpts.uid != nil {
+		req.Uid = reqOpts.uid
+		req.Gid = reqOpts.gid
+	}
+
+	if len(reqOpts.kvBuckets) > 0 {
+		req.KeyValueBuckets = reqOpts.kvBuckets
+	}
+
+	if reqOpts.workingDirectory != "" {
+		req.WorkingDirectory = &reqOpts.workingDirectory
+	}
+
+	if reqOpts.memoryLimitMib != nil {
+		req.MemoryLimitMib = reqOpts.memoryLimitMib
+	}
+
+	if reqOpts.resources != nil {
+		req.Resources = reqOpts.resources
+	}
+
+	if reqOpts.traceSamplingRate != nil {
+		req.TraceSamplingRate = reqOpts.traceSamplingRate
+	}
+
+	if len(reqOpts.dependencies) > 0 {
+		req.Dependencies = reqOpts.dependencies
+	}
+
+	if reqOpts.targetVM != "" {
+		req.TargetVM = &reqOpts.targetVM
+	}
+
+	if reqOpts.captureLogs {
+		req.CaptureLogs = true
+	}
+
+	if reqOpts.signatureTTL > 0 {
+		err = req.Sign(reqOpts.claimsIssuer, reqOpts.signatureTTL)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return req, nil
@@ -88,18 +253,51 @@ func NewDeployRequest(opts ...RequestOption) (*DeployRequest, error) {
 func (request *DeployRequest) Validate() (*jwt.GenericClaims, error) {
 	claims, err := jwt.DecodeGeneric(*request.WorkloadJwt)
 	if err != nil {
-		return nil, fmt.Errorf("could not decode workload JWT: %s", err)
+		return nil, &InvalidFieldError{Field: "workload_jwt", Message: fmt.Sprintf("could not decode workload JWT: %s", err)}
 	}
 
 	request.DecodedClaims = *claims
+	if request.Resources != nil && (request.Resources.VcpuCount < 0 || request.Resources.MemSizeMib < 0) {
+		return nil, &InvalidFieldError{Field: "resources", Message: "workload resources must be >= 0"}
+	}
+
+	if request.MemoryLimitMib != nil && *request.MemoryLimitMib <= 0 {
+		return nil, &InvalidFieldError{Field: "memory_limit_mib", Message: "workload memory limit must be > 0"}
+	}
+
+	if request.MemoryLimitMib != nil && request.Resources != nil && request.Resources.MemSizeMib > 0 && *request.MemoryLimitMib > request.Resources.MemSizeMib {
+		return nil, &InvalidFieldError{Field: "memory_limit_mib", Message: "workload memory limit must not exceed the required machine memory"}
+	}
+
+	if request.TraceSamplingRate != nil && (*request.TraceSamplingRate < 0 || *request.TraceSamplingRate > 1) {
+		return nil, &InvalidFieldError{Field: "trace_sampling_rate", Message: "trace sampling rate must be between 0.0 and 1.0"}
+	}
+
 	if !validWorkloadName.MatchString(claims.Subject) {
-		return nil, fmt.Errorf("workload name claim ('%s') does not match requirements of all lowercase letters", claims.Subject)
+		return nil, &InvalidFieldError{Field: "workload_jwt", Message: fmt.Sprintf("workload name claim ('%s') does not match requirements of all lowercase letters", claims.Subject)}
+	}
+
+	for _, dependency := range request.Dependencies {
+		if !validWorkloadName.MatchString(dependency) {
+			return nil, &InvalidFieldError{Field: "dependencies", Message: fmt.Sprintf("dependency ('%s') is not a valid workload name", dependency)}
+		}
+
+		if dependency == claims.Subject {
+			return nil, &InvalidFieldError{Field: "dependencies", Message: "workload cannot depend on itself"}
+		}
 	}
 
 	var vr jwt.ValidationResults
 	claims.Validate(&vr)
 	if len(vr.Issues) > 0 || len(vr.Errors()) > 0 {
-		return nil, errors.New("standard claims within JWT are not valid")
+		return nil, &InvalidFieldError{Field: "workload_jwt", Message: "standard claims within JWT are not valid"}
+	}
+
+	if request.GitSource == nil && ArtifactRetrievedByAgent(request.Location) {
+		// the artifact is verified by the agent against the hash claimed for it
+		if hash, _ := claims.Data["hash"].(string); hash == "" {
+			return nil, &InvalidFieldError{Field: "workload_jwt", Message: fmt.Sprintf("workload hash claim is required for artifacts at %s locations", request.Location.Scheme)}
+		}
 	}
 
 	return claims, nil
@@ -143,20 +341,37 @@ func (request *DeployRequest) DecryptRequestEnvironment(recipientXKey nkeys.KeyP
 }
 
 type requestOptions struct {
-	argv                []string
-	workloadName        string
-	workloadType        string
-	workloadDescription string
-	location            url.URL
-	env                 map[string]string
-	essential           bool
-	senderXkey          nkeys.KeyPair
-	claimsIssuer        nkeys.KeyPair
-	targetPublicXKey    string
-	jsDomain            string
-	hash                string
-	targetNode          string
-	triggerSubjects     []string
+	argv                   []string
+	workloadName           string
+	workloadType           string
+	workloadDescription    string
+	location               url.URL
+	env                    map[string]string
+	essential              bool
+	senderXkey             nkeys.KeyPair
+	claimsIssuer           nkeys.KeyPair
+	targetPublicXKey       string
+	jsDomain               string
+	artifactBucket         string
+	gitSource              *GitSource
+	stopGracePeriod        *time.Duration
+	hash                   string
+	kvBuckets              []KeyValueBucket
+	tags                   map[string]string
+	targetNode             string
+	triggerSubjects        []string
+	allowedTriggerSubjects []string
+	triggerTimeout         *time.Duration
+	uid                    *int
+	gid                    *int
+	workingDirectory       string
+	memoryLimitMib         *int
+	resources              *WorkloadResources
+	traceSamplingRate      *float64
+	dependencies           []string
+	signatureTTL           time.Duration
+	targetVM               string
+	captureLogs            bool
 }
 
 type RequestOption func(o requestOptions) requestOptions
@@ -201,6 +416,15 @@ func TriggerSubjects(triggerSubjects []string) RequestOption {
 	}
 }
 
+// Restricts the subjects on which the workload may be triggered to the given subjects, which may
+// contain wildcards
+func AllowedTriggerSubjects(subjects []string) RequestOption {
+	return func(o requestOptions) requestOptions {
+		o.allowedTriggerSubjects = subjects
+		return o
+	}
+}
+
 // Location of the workload. For files in NATS object stores, use nats://BUCKET/key
 func Location(fileUrl string) RequestOption {
 	return func(o requestOptions) requestOptions {
@@ -272,6 +496,130 @@ func JsDomain(domain string) RequestOption {
 	}
 }
 
+// Optionally set the node's internal artifact bucket in which the workload will be cached. When not
+// set, the default workload cache bucket is used
+func ArtifactBucket(bucket string) RequestOption {
+	return func(o requestOptions) requestOptions {
+		o.artifactBucket = bucket
+		return o
+	}
+}
+
+// Deploy the workload from the given git source rather than from a location. The node clones the
+// repository, verifies the pinned commit and runs the optional build command to produce the artifact
+func FromGitSource(source GitSource) RequestOption {
+	return func(o requestOptions) requestOptions {
+		o.gitSource = &source
+		return o
+	}
+}
+
+// Overrides the grace period given to the workload to shut down cleanly when stopped
+func StopGracePeriod(gracePeriod time.Duration) RequestOption {
+	return func(o requestOptions) requestOptions {
+		o.stopGracePeriod = &gracePeriod
+		return o
+	}
+}
+
+// Overrides the time within which each trigger of a function workload must complete
+func TriggerTimeout(timeout time.Duration) RequestOption {
+	return func(o requestOptions) requestOptions {
+		o.triggerTimeout = &timeout
+		return o
+	}
+}
+
+// Runs the workload as the given non-root uid and gid rather than as root
+func RunAs(uid int, gid int) RequestOption {
+	return func(o requestOptions) requestOptions {
+		o.uid = &uid
+		o.gid = &gid
+		return o
+	}
+}
+
+// Sets the absolute path of the working directory in which the workload is run
+func WorkingDirectory(dir string) RequestOption {
+	return func(o requestOptions) requestOptions {
+		o.workingDirectory = dir
+		return o
+	}
+}
+
+// Sets the resources required by the workload, which the node uses to place it on the
+// smallest idle machine which satisfies them
+func Resources(vcpuCount int, memSizeMib int) RequestOption {
+	return func(o requestOptions) requestOptions {
+		o.resources = &WorkloadResources{
+			VcpuCount:  vcpuCount,
+			MemSizeMib: memSizeMib,
+		}
+		return o
+	}
+}
+
+// Captures the workload's complete stdout and stderr into the node's internal object store, from
+// which they may be fetched after the workload is gone
+func CaptureLogs() RequestOption {
+	return func(o requestOptions) requestOptions {
+		o.captureLogs = true
+		return o
+	}
+}
+
+// Sets the limit on the memory used by the workload, below the memory of the machine running it,
+// beyond which the workload is killed rather than left to thrash
+func MemoryLimit(memoryLimitMib int) RequestOption {
+	return func(o requestOptions) requestOptions {
+		o.memoryLimitMib = &memoryLimitMib
+		return o
+	}
+}
+
+// Sets the fraction (0.0-1.0) of the workload's triggers which are traced, overriding the
+// node's sampling rate
+func TraceSamplingRate(rate float64) RequestOption {
+	return func(o requestOptions) requestOptions {
+		o.traceSamplingRate = &rate
+		return o
+	}
+}
+
+// Deploys the workload to the idle VM on the target node with the given id or IP address rather
+// than to whichever VM the node selects; for debugging only
+func TargetVM(vm string) RequestOption {
+	return func(o requestOptions) requestOptions {
+		o.targetVM = vm
+		return o
+	}
+}
+
+// Signs the request with the claims issuer, expiring after the given time to live
+func Signed(ttl time.Duration) RequestOption {
+	return func(o requestOptions) requestOptions {
+		o.signatureTTL = ttl
+		return o
+	}
+}
+
+// Declares workloads in the same namespace which must be running on the target node before
+// the workload is deployed
+func DependsOn(names ...string) RequestOption {
+	return func(o requestOptions) requestOptions {
+		o.dependencies = append(o.dependencies, names...)
+		return o
+	}
+}
+
+// Declares a key/value bucket required by the workload, provisioned by the node on deploy
+func KeyValueBucketRequired(bucket KeyValueBucket) RequestOption {
+	return func(o requestOptions) requestOptions {
+		o.kvBuckets = append(o.kvBuckets, bucket)
+		return o
+	}
+}
+
 // Sets a single environment value
 func EnvironmentValue(key string, value string) RequestOption {
 	return func(o requestOptions) requestOptions {
@@ -283,6 +631,17 @@ func EnvironmentValue(key string, value string) RequestOption {
 	}
 }
 
+// Sets a single tag on the workload
+func Tag(key string, value string) RequestOption {
+	return func(o requestOptions) requestOptions {
+		if o.tags == nil {
+			o.tags = make(map[string]string)
+		}
+		o.tags[key] = value
+		return o
+	}
+}
+
 // Sets the hash of the workload payload for verification purposes
 func Checksum(hash string) RequestOption {
 	return func(o requestOptions) requestOptions {
@@ -0,0 +1,27 @@
+package controlapi
+
+import (
+	"errors"
+)
+
+// Requests that the target node stop every workload whose tags match all of the key/value
+// pairs in the selector, regardless of namespace
+type StopSelectorRequest struct {
+	Selector   map[string]string `json:"selector"`
+	TargetNode string            `json:"target_node"`
+}
+
+type StopSelectorResponse struct {
+	NodeId   string            `json:"node_id"`
+	Selector map[string]string `json:"selector"`
+	Stopped  int               `json:"stopped"`
+}
+
+func (request *StopSelectorRequest) Validate() error {
+	if len(request.Selector) == 0 {
+		// an empty selector would match every workload on the node
+		return errors.New("tag selector must not be empty")
+	}
+
+	return nil
+}
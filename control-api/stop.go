@@ -35,19 +35,25 @@ func NewStopRequest(workloadId string, name string, targetNode string, issuer nk
 }
 
 func (request *StopRequest) Validate(originalClaims *jwt.GenericClaims) error {
-	claims, err := jwt.DecodeGeneric(request.WorkloadJwt)
+	return validateIssuerClaims("stop", "terminate", request.WorkloadJwt, originalClaims)
+}
+
+// Validates that the given claims were freshly issued by the issuer of the original start claims,
+// authorizing the named operation on the workload
+func validateIssuerClaims(operation string, action string, workloadJwt string, originalClaims *jwt.GenericClaims) error {
+	claims, err := jwt.DecodeGeneric(workloadJwt)
 	if err != nil {
 		return fmt.Errorf("could not decode workload JWT: %s", err)
 	}
 	if claims.ID == originalClaims.ID ||
 		claims.IssuedAt == originalClaims.IssuedAt {
-		return fmt.Errorf("stop claims appear to be cloned or captured from the original start claims. Rejecting for security reasons")
+		return fmt.Errorf("%s claims appear to be cloned or captured from the original start claims. Rejecting for security reasons", operation)
 	}
 	if claims.Subject != originalClaims.Subject {
-		return fmt.Errorf("stop claims subject does not match original start claims subject")
+		return fmt.Errorf("%s claims subject does not match original start claims subject", operation)
 	}
 	if claims.Issuer != originalClaims.Issuer {
-		return fmt.Errorf("the only entity allowed to terminate a workload is the issuer that originally started it")
+		return fmt.Errorf("the only entity allowed to %s a workload is the issuer that originally started it", action)
 	}
 
 	return nil
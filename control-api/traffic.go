@@ -0,0 +1,58 @@
+package controlapi
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Adjusts how triggers on a logical trigger subject are routed among the workloads that share it.
+// Weights are relative; a workload with a weight of 0 receives no triggers unless it is the fallback
+type TrafficSplitRequest struct {
+	TriggerSubject string         `json:"trigger_subject"`
+	TargetNode     string         `json:"target_node"`
+	Weights        map[string]int `json:"weights"`
+
+	// When set, triggers that fail on any other workload sharing the subject are retried on this workload
+	FallbackWorkloadId *string `json:"fallback_workload_id,omitempty"`
+}
+
+type TrafficSplitResponse struct {
+	TriggerSubject     string         `json:"trigger_subject"`
+	Weights            map[string]int `json:"weights"`
+	FallbackWorkloadId *string        `json:"fallback_workload_id,omitempty"`
+}
+
+func NewTrafficSplitRequest(triggerSubject string, targetNode string, weights map[string]int, fallbackWorkloadId *string) *TrafficSplitRequest {
+	return &TrafficSplitRequest{
+		TriggerSubject:     triggerSubject,
+		TargetNode:         targetNode,
+		Weights:            weights,
+		FallbackWorkloadId: fallbackWorkloadId,
+	}
+}
+
+func (request *TrafficSplitRequest) Validate() error {
+	var err error
+
+	if request.TriggerSubject == "" {
+		err = errors.Join(err, errors.New("trigger subject is required"))
+	}
+
+	if len(request.Weights) == 0 {
+		err = errors.Join(err, errors.New("at least one workload weight is required"))
+	}
+
+	total := 0
+	for id, weight := range request.Weights {
+		if weight < 0 {
+			err = errors.Join(err, fmt.Errorf("weight for workload %s must be non-negative", id))
+		}
+		total += weight
+	}
+
+	if len(request.Weights) > 0 && total == 0 {
+		err = errors.Join(err, errors.New("at least one workload must have a positive weight"))
+	}
+
+	return err
+}
@@ -2,6 +2,7 @@ package controlapi
 
 import (
 	"log/slog"
+	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go"
 )
@@ -11,11 +12,24 @@ const (
 )
 
 const (
-	InfoResponseType     = "io.nats.nex.v1.info_response"
-	PingResponseType     = "io.nats.nex.v1.ping_response"
-	RunResponseType      = "io.nats.nex.v1.run_response"
-	StopResponseType     = "io.nats.nex.v1.stop_response"
-	LameDuckResponseType = "io.nats.nex.v1.lameduck_response"
+	InfoResponseType        = "io.nats.nex.v1.info_response"
+	PingResponseType        = "io.nats.nex.v1.ping_response"
+	RunResponseType         = "io.nats.nex.v1.run_response"
+	StopResponseType        = "io.nats.nex.v1.stop_response"
+	LameDuckResponseType    = "io.nats.nex.v1.lameduck_response"
+	TrafficResponseType     = "io.nats.nex.v1.traffic_response"
+	MetricsResponseType     = "io.nats.nex.v1.metrics_response"
+	PrewarmResponseType     = "io.nats.nex.v1.prewarm_response"
+	MigrateResponseType     = "io.nats.nex.v1.migrate_response"
+	SelectorResponseType    = "io.nats.nex.v1.selector_response"
+	PoolResponseType        = "io.nats.nex.v1.pool_response"
+	HistoryResponseType     = "io.nats.nex.v1.history_response"
+	InventoryResponseType   = "io.nats.nex.v1.inventory_response"
+	AgentUpdateResponseType = "io.nats.nex.v1.agent_update_response"
+	EventTokenResponseType  = "io.nats.nex.v1.event_token_response"
+	ExportResponseType      = "io.nats.nex.v1.export_response"
+	ImportResponseType      = "io.nats.nex.v1.import_response"
+	LostResponseType        = "io.nats.nex.v1.lost_response"
 
 	TagOS       = "nex.os"
 	TagArch     = "nex.arch"
@@ -24,11 +38,35 @@ const (
 	TagLameDuck = "nex.lameduck"
 )
 
+const (
+	// Set to "true" on a message triggering a function workload to receive the partial results the
+	// workload emitted before timing out, rather than no response
+	TriggerPartialResultsHeader = "x-nex-trigger-partial-results"
+	// Set to "true" on a trigger response carrying partial results because the workload timed out
+	TriggerTimedOutHeader = "x-nex-trigger-timed-out"
+	// Set on the response to a trigger which was refused, explaining why, e.g. because no workload
+	// on the subject is allowed to be triggered on it
+	TriggerErrorHeader = "x-nex-trigger-error"
+	// Set to "true" on a message triggering a function workload to receive its response as a stream
+	// of chunks published to the message's reply subject, rather than a single response
+	TriggerStreamHeader = "x-nex-trigger-stream"
+	// Set to "true" on the empty message ending a streamed trigger response
+	TriggerStreamEndHeader = "x-nex-trigger-stream-end"
+	// Set on the message ending a streamed trigger response, explaining why the workload failed
+	// partway through the stream
+	TriggerStreamErrorHeader = "x-nex-trigger-stream-error"
+)
+
 type RunResponse struct {
 	Started bool   `json:"started"`
 	ID      string `json:"id"`
 	Issuer  string `json:"issuer"`
 	Name    string `json:"name"`
+	// Set when the workload was not deployed, explaining the failure
+	Error *DeployError `json:"error,omitempty"`
+	// Key of the object in the node's internal workload logs bucket into which the workload's stdout
+	// and stderr are captured, when requested; fetched through the captured logs API
+	LogObjectKey *string `json:"log_object_key,omitempty"`
 }
 
 type PingResponse struct {
@@ -38,6 +76,10 @@ type PingResponse struct {
 	TargetXkey      string            `json:"target_xkey"`
 	Tags            map[string]string `json:"tags,omitempty"`
 	RunningMachines int               `json:"running_machines"`
+	// Maximum number of workloads the node accepts; zero is unlimited
+	MaxWorkloads int `json:"max_workloads,omitempty"`
+	// False while the node's internal object store is failing its liveness probe
+	Healthy bool `json:"healthy"`
 }
 
 type WorkloadPingResponse struct {
@@ -56,9 +98,24 @@ type WorkloadPingMachineSummary struct {
 	WorkloadType string `json:"type"`
 }
 
+// Returned each time a node is asked to enter lame duck mode, reporting the progress of its
+// drain: the number of workloads still running and whether all have stopped
 type LameDuckResponse struct {
-	NodeId  string `json:"node_id"`
-	Success bool   `json:"success"`
+	NodeId    string `json:"node_id"`
+	Success   bool   `json:"success"`
+	Remaining int    `json:"remaining"`
+	Drained   bool   `json:"drained"`
+}
+
+// A point-in-time snapshot of a node's metrics in the OpenMetrics text exposition format.
+// Every series is labeled with the node's public key
+type MetricsSnapshotResponse struct {
+	NodeId      string    `json:"node_id"`
+	Timestamp   time.Time `json:"timestamp"`
+	ContentType string    `json:"content_type"`
+	Exposition  string    `json:"exposition"`
+	// Indicates that metric families were omitted to keep the response within the maximum payload size
+	Truncated bool `json:"truncated"`
 }
 
 type MemoryStat struct {
@@ -78,11 +135,28 @@ type InfoResponse struct {
 }
 
 type MachineSummary struct {
-	Id        string          `json:"id"`
-	Healthy   bool            `json:"healthy"`
-	Uptime    string          `json:"uptime"`
-	Namespace string          `json:"namespace,omitempty"`
-	Workload  WorkloadSummary `json:"workload,omitempty"`
+	Id        string `json:"id"`
+	Healthy   bool   `json:"healthy"`
+	Uptime    string `json:"uptime"`
+	Namespace string `json:"namespace,omitempty"`
+	// Lifecycle phase reached by the workload's agent, e.g. workload_deployed while the workload is
+	// not yet ready to receive triggers
+	Phase    string          `json:"phase,omitempty"`
+	Workload WorkloadSummary `json:"workload,omitempty"`
+	// Latest sample of the machine's network counters, present only when the node samples them
+	Network *NetworkStats `json:"network,omitempty"`
+}
+
+// Cumulative network counters of a workload's machine, from the workload's point of view: received
+// traffic is traffic sent to the workload, transmitted traffic is traffic sent by it
+type NetworkStats struct {
+	RxBytes   uint64    `json:"rx_bytes"`
+	RxPackets uint64    `json:"rx_packets"`
+	RxErrors  uint64    `json:"rx_errors"`
+	TxBytes   uint64    `json:"tx_bytes"`
+	TxPackets uint64    `json:"tx_packets"`
+	TxErrors  uint64    `json:"tx_errors"`
+	SampledAt time.Time `json:"sampled_at"`
 }
 
 type WorkloadSummary struct {
@@ -112,6 +186,12 @@ type RawLog struct {
 	Text  string     `json:"text"`
 	Level slog.Level `json:"level"`
 	ID    string     `json:"id"`
+	// Time at which the agent received the line from the workload, unset for lines logged by agents
+	// which predate recording it
+	LoggedAt time.Time `json:"logged_at"`
+	// Number of the workload's log lines dropped by the agent's log rate limit, set on the notice
+	// reporting them
+	Dropped uint64 `json:"dropped,omitempty"`
 }
 
 // Note this a wrapper to add context to a cloud event
@@ -0,0 +1,97 @@
+package controlapi
+
+import (
+	"errors"
+	"time"
+)
+
+// Requests a manifest of every workload running on a node, to be replayed onto the target node
+// with an import request, e.g. when replacing a node. Each workload's environment is re-encrypted
+// for the target node, so that no secrets appear in the manifest in plaintext, and each deploy
+// request is signed by the exporting node. The manifest must therefore be imported by the target
+// node, which must trust the exporting node if it requires signed requests, before the signatures
+// expire
+type ExportRequest struct {
+	TargetNode string `json:"target_node"`
+
+	// Time to live of the signatures of the exported deploy requests; the exporting node's maximum
+	// signed request time to live when not given
+	SignatureTTLMillisecond int `json:"signature_ttl_ms,omitempty"`
+}
+
+// A portable snapshot of the workloads running on a node
+type WorkloadManifest struct {
+	NodeId     string             `json:"node_id"`
+	TargetNode string             `json:"target_node"`
+	ExportedAt time.Time          `json:"exported_at"`
+	ExpiresAt  time.Time          `json:"expires_at"`
+	Workloads  []ManifestWorkload `json:"workloads"`
+}
+
+// A workload in a manifest, along with the signed deploy request with which it is redeployed
+type ManifestWorkload struct {
+	Id        string         `json:"id"`
+	Name      string         `json:"name"`
+	Namespace string         `json:"namespace"`
+	Request   *DeployRequest `json:"request"`
+}
+
+// Requests that a node deploy each of the workloads in the given manifest, exported for it
+type ImportRequest struct {
+	Manifest WorkloadManifest `json:"manifest"`
+}
+
+// Reports the outcome of importing each of the workloads of a manifest, in manifest order
+type ImportResponse struct {
+	NodeId  string         `json:"node_id"`
+	Results []ImportResult `json:"results"`
+}
+
+type ImportResult struct {
+	// Id of the workload on the node from which it was exported
+	SourceId   string       `json:"source_id"`
+	Name       string       `json:"name"`
+	Namespace  string       `json:"namespace"`
+	Imported   bool         `json:"imported"`
+	WorkloadId string       `json:"workload_id,omitempty"`
+	Error      *DeployError `json:"error,omitempty"`
+}
+
+func (r *ExportRequest) Validate() error {
+	var err error
+
+	if r.TargetNode == "" {
+		err = errors.Join(err, errors.New("target node is required"))
+	}
+
+	if r.SignatureTTLMillisecond < 0 {
+		err = errors.Join(err, errors.New("signature ttl must be >= 0"))
+	}
+
+	return err
+}
+
+func (r *ImportRequest) Validate() error {
+	var err error
+
+	for _, workload := range r.Manifest.Workloads {
+		if workload.Namespace == "" || workload.Request == nil {
+			err = errors.Join(err, errors.New("manifest workloads require a namespace and deploy request"))
+			break
+		}
+	}
+
+	return err
+}
+
+// Returns the number of workloads which were imported
+func (r *ImportResponse) Imported() int {
+	imported := 0
+	for _, result := range r.Results {
+		if result.Imported {
+			imported++
+		}
+	}
+
+	return imported
+}
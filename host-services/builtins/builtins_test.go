@@ -44,7 +44,7 @@ func TestKvBuiltin(t *testing.T) {
 	client := hostservices.NewHostServicesClient(nc, 2*time.Second, testNamespace, testWorkload, testWorkloadId)
 	bClient := NewBuiltinServicesClient(client)
 
-	service, _ := NewKeyValueService(nc, slog.Default())
+	service, _ := NewKeyValueService(hostservices.SingleConnection(nc), slog.Default())
 	err := server.AddService("kv", service, nil)
 	if err != nil {
 		t.Fatalf("Failed to add service: %s", err)
@@ -85,7 +85,7 @@ func TestMessagingBuiltin(t *testing.T) {
 	client := hostservices.NewHostServicesClient(nc, 2*time.Second, testNamespace, testWorkload, testWorkloadId)
 	bClient := NewBuiltinServicesClient(client)
 
-	service, _ := NewMessagingService(nc, slog.Default())
+	service, _ := NewMessagingService(hostservices.SingleConnection(nc), slog.Default())
 	_ = server.AddService("messaging", service, nil)
 	_ = server.Start()
 
@@ -111,7 +111,7 @@ func TestObjectBuiltin(t *testing.T) {
 	client := hostservices.NewHostServicesClient(nc, 2*time.Second, testNamespace, testWorkload, testWorkloadId)
 	bClient := NewBuiltinServicesClient(client)
 
-	service, _ := NewObjectStoreService(nc, slog.Default())
+	service, _ := NewObjectStoreService(hostservices.SingleConnection(nc), slog.Default())
 	_ = server.AddService("objectstore", service, []byte{})
 	_ = server.Start()
 
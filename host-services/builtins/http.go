@@ -6,7 +6,6 @@ import (
 	"log/slog"
 	"net/url"
 
-	"github.com/nats-io/nats.go"
 	hostservices "github.com/synadia-io/nex/host-services"
 	agentapi "github.com/synadia-io/nex/internal/agent-api"
 	"github.com/synadia-io/nex/internal/node/services/util"
@@ -22,14 +21,14 @@ const httpServiceMethodHead = "head"
 const defaultHTTPRequestTimeoutMillis = 2500
 
 type HTTPService struct {
-	log *slog.Logger
-	nc  *nats.Conn
+	log   *slog.Logger
+	conns hostservices.ConnectionSource
 }
 
-func NewHTTPService(nc *nats.Conn, log *slog.Logger) (*HTTPService, error) {
+func NewHTTPService(conns hostservices.ConnectionSource, log *slog.Logger) (*HTTPService, error) {
 	http := &HTTPService{
-		log: log,
-		nc:  nc,
+		log:   log,
+		conns: conns,
 	}
 
 	return http, nil
@@ -19,7 +19,7 @@ const kvServiceMethodKeys = "keys"
 
 type KeyValueService struct {
 	log    *slog.Logger
-	nc     *nats.Conn
+	conns  hostservices.ConnectionSource
 	config kvConfig
 }
 
@@ -29,10 +29,10 @@ type kvConfig struct {
 	JitProvision bool   `json:"jit_provision"`
 }
 
-func NewKeyValueService(nc *nats.Conn, log *slog.Logger) (*KeyValueService, error) {
+func NewKeyValueService(conns hostservices.ConnectionSource, log *slog.Logger) (*KeyValueService, error) {
 	kv := &KeyValueService{
-		log: log,
-		nc:  nc,
+		log:   log,
+		conns: conns,
 	}
 
 	return kv, nil
@@ -183,18 +183,24 @@ func (k *KeyValueService) handleKeys(_, workload string,
 	return hostservices.ServiceResultPass(200, "", resp), nil
 }
 
+// Returns the name of the key value store used by the given workload in the given namespace,
+// as resolved from the configured bucket name template
+func (k *KeyValueService) BucketName(namespace, workload string) string {
+	reWorkload := regexp.MustCompile(`(?i)\$\{workload_name\}`)
+	reNamespace := regexp.MustCompile(`(?i)\$\{namespace\}`)
+
+	kvStoreName := reWorkload.ReplaceAllString(k.config.BucketName, workload)
+	return reNamespace.ReplaceAllString(kvStoreName, namespace)
+}
+
 // resolve the key value store for this workload; initialize it if necessary
 func (k *KeyValueService) resolveKeyValueStore(namespace, workload string) (nats.KeyValue, error) {
-	js, err := k.nc.JetStream()
+	js, err := k.conns.Conn().JetStream()
 	if err != nil {
 		return nil, err
 	}
 
-	reWorkload := regexp.MustCompile(`(?i)\$\{workload_name\}`)
-	reNamespace := regexp.MustCompile(`(?i)\$\{namespace\}`)
-
-	kvStoreName := reWorkload.ReplaceAllString(k.config.BucketName, workload)
-	kvStoreName = reNamespace.ReplaceAllString(kvStoreName, namespace)
+	kvStoreName := k.BucketName(namespace, workload)
 
 	kvStore, err := js.KeyValue(kvStoreName)
 	if err != nil {
@@ -6,7 +6,6 @@ import (
 	"log/slog"
 	"time"
 
-	"github.com/nats-io/nats.go"
 	hostservices "github.com/synadia-io/nex/host-services"
 	agentapi "github.com/synadia-io/nex/internal/agent-api"
 )
@@ -21,8 +20,8 @@ const (
 )
 
 type MessagingService struct {
-	log *slog.Logger
-	nc  *nats.Conn
+	log   *slog.Logger
+	conns hostservices.ConnectionSource
 
 	config messagingConfig
 }
@@ -32,10 +31,10 @@ type messagingConfig struct {
 	RequestManyTimeoutMs int64 `json:"request_many_timeout_ms"`
 }
 
-func NewMessagingService(nc *nats.Conn, log *slog.Logger) (*MessagingService, error) {
+func NewMessagingService(conns hostservices.ConnectionSource, log *slog.Logger) (*MessagingService, error) {
 	messaging := &MessagingService{
-		log: log,
-		nc:  nc,
+		log:   log,
+		conns: conns,
 	}
 
 	return messaging, nil
@@ -88,7 +87,7 @@ func (m *MessagingService) handlePublish(_, _ string,
 		return hostservices.ServiceResultFail(500, "subject is required"), nil
 	}
 
-	err := m.nc.Publish(subject, data)
+	err := m.conns.Conn().Publish(subject, data)
 	if err != nil {
 		m.log.Warn(fmt.Sprintf("failed to publish %d-byte message on subject %s: %s", len(data), subject, err.Error()))
 		return hostservices.ServiceResultFail(500, "failed to publish message"), nil
@@ -109,7 +108,7 @@ func (m *MessagingService) handleRequest(_, _ string,
 		return hostservices.ServiceResultFail(400, "subject is required"), nil
 	}
 
-	resp, err := m.nc.Request(subject, data, time.Duration(m.config.RequestTimeoutMs*int64(time.Millisecond)))
+	resp, err := m.conns.Conn().Request(subject, data, time.Duration(m.config.RequestTimeoutMs*int64(time.Millisecond)))
 	if err != nil {
 		m.log.Debug(fmt.Sprintf("failed to send %d-byte request on subject %s: %s", len(data), subject, err.Error()))
 		return hostservices.ServiceResultFail(500, "failed to send request"), nil
@@ -28,7 +28,7 @@ const (
 
 type ObjectStoreService struct {
 	log    *slog.Logger
-	nc     *nats.Conn
+	conns  hostservices.ConnectionSource
 	config objectStoreConfig
 }
 
@@ -38,10 +38,10 @@ type objectStoreConfig struct {
 	JitProvision bool   `json:"jit_provision"`
 }
 
-func NewObjectStoreService(nc *nats.Conn, log *slog.Logger) (*ObjectStoreService, error) {
+func NewObjectStoreService(conns hostservices.ConnectionSource, log *slog.Logger) (*ObjectStoreService, error) {
 	objectStore := &ObjectStoreService{
-		log: log,
-		nc:  nc,
+		log:   log,
+		conns: conns,
 	}
 
 	return objectStore, nil
@@ -208,7 +208,7 @@ func (o *ObjectStoreService) handleList(_, workload string,
 
 // resolve the object store for the given workload; initialize it if necessary & configured to do so
 func (o *ObjectStoreService) resolveObjectStore(namespace, workload string) (nats.ObjectStore, error) {
-	js, err := o.nc.JetStream()
+	js, err := o.conns.Conn().JetStream()
 	if err != nil {
 		return nil, err
 	}
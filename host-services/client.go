@@ -8,9 +8,15 @@ import (
 
 	"github.com/nats-io/nats.go"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Name of the tracer with which host services RPCs are recorded; matches the agent's tracer so that
+// the spans of RPCs performed by workloads are forwarded to the node
+const clientTracerName = "nex-agent"
+
 type HostServicesClient struct {
 	nc           *nats.Conn
 	namespace    string
@@ -38,6 +44,11 @@ func (c *HostServicesClient) PerformRPC(ctx context.Context, service string, met
 		method,
 	)
 
+	ctx, span := otel.Tracer(clientTracerName).Start(ctx, fmt.Sprintf("%s.%s", service, method),
+		trace.WithSpanKind(trace.SpanKindClient),
+	)
+	defer span.End()
+
 	msg := nats.NewMsg(subject)
 	msg.Data = payload
 
@@ -49,6 +60,7 @@ func (c *HostServicesClient) PerformRPC(ctx context.Context, service string, met
 
 	result, err := c.nc.RequestMsg(msg, c.timeout)
 	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		return ServiceResult{}, err
 	}
 	code := result.Header.Get(headerCode)
@@ -0,0 +1,182 @@
+package hostservices
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Supplies the NATS connection on which a host service performs a single request
+type ConnectionSource interface {
+	Conn() *nats.Conn
+}
+
+type singleConnection struct {
+	nc *nats.Conn
+}
+
+// Wraps a single NATS connection as a connection source, on which all
+// host service requests are performed
+func SingleConnection(nc *nats.Conn) ConnectionSource {
+	return &singleConnection{nc: nc}
+}
+
+func (s *singleConnection) Conn() *nats.Conn {
+	return s.nc
+}
+
+// A fixed-size pool of NATS connections across which host service requests are
+// round-robined. Connections are periodically checked, and any that have been
+// closed are replaced by newly dialed connections
+type ConnectionPool struct {
+	closing  uint32
+	dial     func() (*nats.Conn, error)
+	done     chan struct{}
+	interval time.Duration
+	log      *slog.Logger
+
+	conns []*nats.Conn
+	mutex sync.RWMutex
+	next  atomic.Uint64
+}
+
+// Dials a pool of the given size using the given dial function, checking the health of
+// the pooled connections at the given interval
+func NewConnectionPool(size int, interval time.Duration, dial func() (*nats.Conn, error), log *slog.Logger) (*ConnectionPool, error) {
+	if size < 1 {
+		return nil, errors.New("connection pool size must be >= 1")
+	}
+
+	pool := &ConnectionPool{
+		dial:     dial,
+		done:     make(chan struct{}),
+		interval: interval,
+		log:      log,
+		conns:    make([]*nats.Conn, size),
+	}
+
+	for i := range pool.conns {
+		nc, err := dial()
+		if err != nil {
+			pool.closeConns()
+			return nil, err
+		}
+		pool.conns[i] = nc
+	}
+
+	go pool.checkHealth()
+
+	return pool, nil
+}
+
+// Returns the next connected connection in the pool, falling back to the next connection
+// in turn if none are currently connected
+func (p *ConnectionPool) Conn() *nats.Conn {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	start := p.next.Add(1)
+	for i := 0; i < len(p.conns); i++ {
+		nc := p.conns[(start+uint64(i))%uint64(len(p.conns))]
+		if nc.IsConnected() {
+			return nc
+		}
+	}
+
+	return p.conns[start%uint64(len(p.conns))]
+}
+
+// Returns the number of connections in the pool
+func (p *ConnectionPool) Size() int {
+	return len(p.conns)
+}
+
+// Stops checking the health of the pool and drains all of its connections
+func (p *ConnectionPool) Close() {
+	if atomic.AddUint32(&p.closing, 1) == 1 {
+		close(p.done)
+
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+
+		p.closeConns()
+	}
+}
+
+func (p *ConnectionPool) checkHealth() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.replaceClosed()
+		}
+	}
+}
+
+// Replaces connections which have been closed, e.g. after exhausting their reconnect
+// attempts, with newly dialed connections. Connections are dialed without holding the
+// pool's lock, so that requests are not blocked on a slow dial
+func (p *ConnectionPool) replaceClosed() {
+	p.mutex.RLock()
+	closed := make(map[int]*nats.Conn)
+	for i, nc := range p.conns {
+		if nc.IsClosed() {
+			closed[i] = nc
+		}
+	}
+	p.mutex.RUnlock()
+
+	for i, nc := range closed {
+		if p.stopping() {
+			return
+		}
+
+		replacement, err := p.dial()
+		if err != nil {
+			p.log.Warn("Failed to replace closed host services connection", slog.Int("index", i), slog.Any("err", err))
+			continue
+		}
+
+		if !p.swapClosed(i, nc, replacement) {
+			// the pool was closed or the slot was replaced while dialing
+			replacement.Close()
+			continue
+		}
+
+		p.log.Info("Replaced closed host services connection", slog.Int("index", i))
+	}
+}
+
+// Swaps the given replacement into the slot at the given index if it still holds the given
+// closed connection and the pool is not being closed, returning true if it was swapped in
+func (p *ConnectionPool) swapClosed(i int, closed, replacement *nats.Conn) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.stopping() || p.conns[i] != closed {
+		return false
+	}
+
+	p.conns[i] = replacement
+	return true
+}
+
+func (p *ConnectionPool) closeConns() {
+	for _, nc := range p.conns {
+		if nc != nil {
+			_ = nc.Drain()
+		}
+	}
+}
+
+func (p *ConnectionPool) stopping() bool {
+	return (atomic.LoadUint32(&p.closing) > 0)
+}
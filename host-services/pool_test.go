@@ -0,0 +1,93 @@
+package hostservices
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestConnectionPoolReplacesClosedConnections(t *testing.T) {
+	nc, teardownSuite := setupSuite(t, 4449)
+	defer teardownSuite(t)
+
+	url := nc.ConnectedUrl()
+	pool, err := NewConnectionPool(3, 50*time.Millisecond, func() (*nats.Conn, error) {
+		return nats.Connect(url)
+	}, slog.Default())
+	if err != nil {
+		t.Fatalf("expected no error creating pool, got %s", err)
+	}
+	defer pool.Close()
+
+	seen := make(map[*nats.Conn]bool)
+	for i := 0; i < pool.Size(); i++ {
+		seen[pool.Conn()] = true
+	}
+	if len(seen) != pool.Size() {
+		t.Fatalf("expected requests to be round-robined across %d connections, got %d", pool.Size(), len(seen))
+	}
+
+	dead := pool.Conn()
+	dead.Close()
+
+	time.Sleep(200 * time.Millisecond)
+
+	for i := 0; i < pool.Size(); i++ {
+		conn := pool.Conn()
+		if conn == dead || conn.IsClosed() {
+			t.Fatal("expected closed connection to have been replaced")
+		}
+	}
+}
+
+func TestConnectionPoolDialDoesNotBlockRequests(t *testing.T) {
+	nc, teardownSuite := setupSuite(t, 4450)
+	defer teardownSuite(t)
+
+	url := nc.ConnectedUrl()
+	dialing := make(chan struct{}, 1)
+	release := make(chan struct{})
+	dials := 0
+
+	pool, err := NewConnectionPool(2, 20*time.Millisecond, func() (*nats.Conn, error) {
+		dials++
+		if dials > 2 {
+			// replacement dials block until released
+			select {
+			case dialing <- struct{}{}:
+			default:
+			}
+			<-release
+		}
+		return nats.Connect(url)
+	}, slog.Default())
+	if err != nil {
+		t.Fatalf("expected no error creating pool, got %s", err)
+	}
+	defer pool.Close()
+	// released ahead of closing the pool, which waits for the swap of a dialed connection
+	defer close(release)
+
+	dead := pool.Conn()
+	dead.Close()
+
+	select {
+	case <-dialing:
+	case <-time.After(time.Second):
+		t.Fatal("expected closed connection to be redialed")
+	}
+
+	got := make(chan *nats.Conn)
+	go func() { got <- pool.Conn() }()
+
+	select {
+	case conn := <-got:
+		if !conn.IsConnected() {
+			t.Fatal("expected the remaining open connection to be returned while redialing")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected requests not to block on a connection being redialed")
+	}
+}
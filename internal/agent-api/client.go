@@ -1,19 +1,23 @@
 package agentapi
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go"
 	"github.com/nats-io/nats.go"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -21,10 +25,23 @@ import (
 type HandshakeCallback func(string)
 type EventCallback func(string, cloudevents.Event)
 type LogCallback func(string, LogEntry)
+type MetricCallback func(string, MetricSample)
+type TraceCallback func(string, []AgentSpan)
+type WorkloadExitedCallback func(string, WorkloadStatusEvent)
 
 const (
-	NexTriggerSubject = "x-nex-trigger-subject"
-	NexRuntimeNs      = "x-nex-runtime-ns"
+	NexTriggerSubject      = "x-nex-trigger-subject"
+	NexTriggerPartialInbox = "x-nex-trigger-partial-inbox"
+	NexRuntimeNs           = "x-nex-runtime-ns"
+
+	// Time in milliseconds from its receipt within which the agent must complete a trigger, beyond
+	// which the agent aborts the execution and reports the timeout
+	NexTriggerTimeoutMs = "x-nex-trigger-timeout-ms"
+	// Set by the agent on the response to a trigger whose execution failed, explaining why
+	NexTriggerFailure = "x-nex-trigger-failure"
+
+	// Identifies the workload to which an undeploy request applies, when the agent hosts several
+	NexWorkloadID = "x-nex-workload-id"
 
 	HttpURLHeader = "x-http-url"
 
@@ -35,22 +52,67 @@ const (
 	ObjectStoreObjectNameHeader = "x-object-name"
 )
 
+// Preparation includes fetching the artifact, so it is given longer to complete than a deployment
+const prepareTimeout = 5 * time.Second
+
+// Updating includes fetching and verifying the agent binary, which is typically much larger than a workload
+const updateTimeout = 30 * time.Second
+
+// Time within which a triggered workload must respond or, when streaming, emit its first chunk,
+// unless its deploy request gives its own trigger timeout
+const DefaultTriggerTimeout = 10 * time.Second
+
+// Returned by the methods of an agent client which has been drained
+var ErrAgentClientClosed = errors.New("agent client closed")
+
 type AgentClient struct {
 	nc                *nats.Conn
 	log               *slog.Logger
 	agentID           string
 	handshakeTimeout  time.Duration
+	deployTimeout     time.Duration
+	undeployTimeout   time.Duration
 	handshakeReceived *atomic.Bool
 	stopping          uint32
 
+	// Set once the client has been drained, after which it can no longer be used. The mutex
+	// serializes draining with the subscriptions made by Start
+	closed    atomic.Bool
+	subzMutex sync.Mutex
+
+	// Maximum size of a trigger payload forwarded inline, and whether larger payloads are
+	// spilled to the internal cache rather than rejected
+	maxTriggerPayload    int
+	spillTriggerPayloads bool
+
 	handshakeTimedOut  HandshakeCallback
 	handshakeSucceeded HandshakeCallback
 	eventReceived      EventCallback
-	logReceived        LogCallback
+	metricReceived     MetricCallback
+	spansReceived      TraceCallback
+	workloadExited     WorkloadExitedCallback
+
+	// Sinks to which each log entry forwarded by the agent is written, in the order in which they
+	// were registered
+	logSinks      []LogSink
+	logSinksMutex sync.RWMutex
 
 	execTotalNanos    int64
 	workloadStartedAt time.Time
 
+	// Most recent heartbeat from the agent, and the time at which it, or the agent's handshake,
+	// was last received
+	lastHeartbeat   atomic.Pointer[AgentHeartbeat]
+	lastHeartbeatAt atomic.Int64
+	degraded        atomic.Bool
+
+	// Reply to the most recent successful ping of the agent, and its round-trip latency (ns)
+	lastPing        atomic.Pointer[AgentPingResponse]
+	lastPingLatency atomic.Int64
+
+	// Lifecycle phase most recently reached by the agent; nil until it has handshaked
+	phase atomic.Pointer[string]
+
 	subz []*nats.Subscription
 }
 
@@ -58,22 +120,50 @@ func NewAgentClient(
 	nc *nats.Conn,
 	log *slog.Logger,
 	handshakeTimeout time.Duration,
+	deployTimeout time.Duration,
+	undeployTimeout time.Duration,
+	maxTriggerPayload int,
+	spillTriggerPayloads bool,
 	onTimedOut HandshakeCallback,
 	onSuccess HandshakeCallback,
 	onEvent EventCallback,
 	onLog LogCallback,
+	onMetric MetricCallback,
+	onSpans TraceCallback,
+	onWorkloadExited WorkloadExitedCallback,
 ) *AgentClient {
-	return &AgentClient{
-		eventReceived:      onEvent,
-		handshakeReceived:  &atomic.Bool{},
-		handshakeTimeout:   handshakeTimeout,
-		handshakeTimedOut:  onTimedOut,
-		handshakeSucceeded: onSuccess,
-		log:                log,
-		logReceived:        onLog,
-		nc:                 nc,
-		subz:               make([]*nats.Subscription, 0),
+	client := &AgentClient{
+		eventReceived:        onEvent,
+		handshakeReceived:    &atomic.Bool{},
+		handshakeTimeout:     handshakeTimeout,
+		deployTimeout:        deployTimeout,
+		undeployTimeout:      undeployTimeout,
+		handshakeTimedOut:    onTimedOut,
+		handshakeSucceeded:   onSuccess,
+		log:                  log,
+		maxTriggerPayload:    maxTriggerPayload,
+		metricReceived:       onMetric,
+		nc:                   nc,
+		spansReceived:        onSpans,
+		spillTriggerPayloads: spillTriggerPayloads,
+		subz:                 make([]*nats.Subscription, 0),
+		workloadExited:       onWorkloadExited,
+	}
+
+	if onLog != nil {
+		client.AddLogSink(onLog)
 	}
+
+	return client
+}
+
+// Registers a sink to which each log entry subsequently forwarded by the agent is written, after
+// the sinks already registered
+func (a *AgentClient) AddLogSink(sink LogSink) {
+	a.logSinksMutex.Lock()
+	defer a.logSinksMutex.Unlock()
+
+	a.logSinks = append(a.logSinks, sink)
 }
 
 // Returns the ID of this agent client, which corresponds to a workload process identifier
@@ -81,7 +171,16 @@ func (a *AgentClient) ID() string {
 	return a.agentID
 }
 
+// Subscribes to the internal subjects of the agent with the given id and awaits its handshake.
+// Fails with ErrAgentClientClosed if the client has been drained
 func (a *AgentClient) Start(agentID string) error {
+	a.subzMutex.Lock()
+	defer a.subzMutex.Unlock()
+
+	if a.closed.Load() {
+		return ErrAgentClientClosed
+	}
+
 	a.log.Info("Agent client starting", slog.String("agent_id", agentID))
 	a.agentID = agentID
 
@@ -106,12 +205,36 @@ func (a *AgentClient) Start(agentID string) error {
 	}
 	a.subz = append(a.subz, sub)
 
+	sub, err = a.nc.Subscribe(MetricsSubject(agentID), a.handleAgentMetric)
+	if err != nil {
+		return err
+	}
+	a.subz = append(a.subz, sub)
+
+	sub, err = a.nc.Subscribe(TracesSubject(agentID), a.handleAgentSpans)
+	if err != nil {
+		return err
+	}
+	a.subz = append(a.subz, sub)
+
+	sub, err = a.nc.Subscribe(HeartbeatSubject(agentID), a.handleHeartbeat)
+	if err != nil {
+		return err
+	}
+	a.subz = append(a.subz, sub)
+
 	go a.awaitHandshake(agentID)
 
 	return nil
 }
 
-func (a *AgentClient) DeployWorkload(request *DeployRequest) (*DeployResponse, error) {
+// Submits the given deploy request to the agent, propagating the trace context of the given context
+// so that the agent's deployment spans continue the trace of the request which caused it
+func (a *AgentClient) DeployWorkload(ctx context.Context, tracer trace.Tracer, request *DeployRequest) (*DeployResponse, error) {
+	if a.closed.Load() {
+		return nil, ErrAgentClientClosed
+	}
+
 	bytes, err := json.Marshal(request)
 	if err != nil {
 		return nil, err
@@ -122,11 +245,26 @@ func (a *AgentClient) DeployWorkload(request *DeployRequest) (*DeployResponse, e
 		slog.String("agent_id", a.agentID),
 		slog.String("status", status.String()))
 
-	subject := fmt.Sprintf("agentint.%s.deploy", a.agentID)
-	resp, err := a.nc.Request(subject, bytes, 1*time.Second)
+	intmsg := nats.NewMsg(fmt.Sprintf("agentint.%s.deploy", a.agentID))
+	intmsg.Data = bytes
+
+	cctx, childSpan := tracer.Start(
+		ctx,
+		"internal deploy request",
+		trace.WithSpanKind(trace.SpanKindClient),
+	)
+
+	otel.GetTextMapPropagator().Inject(cctx, propagation.HeaderCarrier(intmsg.Header))
+
+	resp, err := a.nc.RequestMsg(intmsg, a.deployTimeout)
 	if err != nil {
-		if errors.Is(err, os.ErrDeadlineExceeded) {
-			return nil, errors.New("timed out waiting for acknowledgement of workload deployment")
+		childSpan.SetStatus(codes.Error, err.Error())
+	}
+	childSpan.End()
+
+	if err != nil {
+		if timedOut(err) {
+			return nil, fmt.Errorf("timed out waiting for acknowledgement of workload deployment after the configured deploy timeout of %s", a.deployTimeout)
 		} else {
 			return nil, fmt.Errorf("failed to submit request for workload deployment: %s", err)
 		}
@@ -139,12 +277,90 @@ func (a *AgentClient) DeployWorkload(request *DeployRequest) (*DeployResponse, e
 		return nil, err
 	}
 	a.workloadStartedAt = time.Now().UTC()
+
+	if deployResponse.Accepted {
+		// agents which do not report readiness explicitly are ready once they acknowledge deployment
+		if deployResponse.AwaitingReady {
+			a.advancePhase(AgentPhaseWorkloadDeployed)
+		} else {
+			a.advancePhase(AgentPhaseWorkloadReady)
+		}
+	}
+
 	return &deployResponse, nil
 }
 
+// Returns the lifecycle phase most recently reached by the agent, or an empty string if the agent
+// has not yet handshaked
+func (a *AgentClient) Phase() string {
+	if phase := a.phase.Load(); phase != nil {
+		return *phase
+	}
+
+	return ""
+}
+
+// Returns true if the agent's workload is ready to receive triggers
+func (a *AgentClient) WorkloadReady() bool {
+	return a.Phase() == AgentPhaseWorkloadReady
+}
+
+// Moves the agent to the given lifecycle phase unless it has already reached a later phase, since
+// phases reported by the agent may be received out of order with its deploy acknowledgement
+func (a *AgentClient) advancePhase(phase string) {
+	for {
+		current := a.phase.Load()
+		if current != nil && agentPhaseOrder(*current) >= agentPhaseOrder(phase) {
+			return
+		}
+
+		if a.phase.CompareAndSwap(current, &phase) {
+			return
+		}
+	}
+}
+
+// Asks the idle agent to stage the given artifact so that it is prepared for a subsequent deployment
+func (a *AgentClient) PrepareWorkload(request *PrepareRequest) (*DeployResponse, error) {
+	if a.closed.Load() {
+		return nil, ErrAgentClientClosed
+	}
+
+	bytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := fmt.Sprintf("agentint.%s.prepare", a.agentID)
+	resp, err := a.nc.Request(subject, bytes, prepareTimeout)
+	if err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return nil, errors.New("timed out waiting for acknowledgement of workload preparation")
+		} else {
+			return nil, fmt.Errorf("failed to submit request for workload preparation: %s", err)
+		}
+	}
+
+	var prepareResponse DeployResponse
+	err = json.Unmarshal(resp.Data, &prepareResponse)
+	if err != nil {
+		a.log.Error("Failed to deserialize preparation response", slog.Any("error", err))
+		return nil, err
+	}
+
+	return &prepareResponse, nil
+}
+
 // Draining subscriptions and release other resources associated
-// with the agent client
+// with the agent client, which is closed to further use
 func (a *AgentClient) Drain() error {
+	a.subzMutex.Lock()
+	defer a.subzMutex.Unlock()
+
+	if a.closed.Swap(true) {
+		return nil
+	}
+
 	for _, sub := range a.subz {
 		err := sub.Drain()
 		if err != nil {
@@ -176,7 +392,13 @@ func (a *AgentClient) Stop() error {
 	return errors.New("agent client already stopping")
 }
 
-func (a *AgentClient) Undeploy() error {
+// Requests that the agent undeploy its workload, waiting for the given grace period, within which
+// the workload is to exit, in addition to the configured undeploy timeout
+func (a *AgentClient) Undeploy(gracePeriod time.Duration) error {
+	if a.closed.Load() {
+		return ErrAgentClientClosed
+	}
+
 	subject := fmt.Sprintf("agentint.%s.undeploy", a.agentID)
 
 	a.log.Debug("sending undeploy request to agent via internal NATS connection",
@@ -184,7 +406,10 @@ func (a *AgentClient) Undeploy() error {
 		slog.String("agent_id", a.agentID),
 	)
 
-	_, err := a.nc.Request(subject, []byte{}, 500*time.Millisecond) // FIXME-- allow this timeout to be configurable... 500ms is likely not enough
+	_, err := a.nc.Request(subject, []byte{}, a.undeployTimeout+gracePeriod)
+	if timedOut(err) {
+		err = fmt.Errorf("timed out waiting for acknowledgement of workload undeployment after the configured undeploy timeout of %s and grace period of %s", a.undeployTimeout, gracePeriod)
+	}
 	if err != nil {
 		a.log.Warn("request to undeploy workload via internal NATS connection failed", slog.String("agent_id", a.agentID), slog.String("error", err.Error()))
 		return err
@@ -205,10 +430,22 @@ func (a *AgentClient) UptimeMillis() time.Duration {
 	return time.Since(a.workloadStartedAt)
 }
 
-func (a *AgentClient) RunTrigger(ctx context.Context, tracer trace.Tracer, subject string, data []byte) (*nats.Msg, error) {
-	intmsg := nats.NewMsg(fmt.Sprintf("agentint.%s.trigger", a.agentID))
+// Forwards the given trigger payload to the agent, returning ErrTriggerPayloadTooLarge without
+// making the request if the payload exceeds the maximum size and cannot be spilled. When partial
+// results are requested, the results emitted by the workload before it timed out are returned in
+// place of a timeout error
+func (a *AgentClient) RunTrigger(ctx context.Context, tracer trace.Tracer, subject string, data []byte, partialResults bool, timeout time.Duration) (*nats.Msg, error) {
+	if a.closed.Load() {
+		return nil, ErrAgentClientClosed
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultTriggerTimeout
+	}
+
+	intmsg := nats.NewMsg(TriggerSubject(a.agentID, a.agentID))
 	intmsg.Header.Add(NexTriggerSubject, subject)
-	intmsg.Data = data
+	intmsg.Header.Add(NexTriggerTimeoutMs, strconv.FormatInt(timeout.Milliseconds(), 10))
 
 	cctx, childSpan := tracer.Start(
 		ctx,
@@ -218,12 +455,91 @@ func (a *AgentClient) RunTrigger(ctx context.Context, tracer trace.Tracer, subje
 
 	otel.GetTextMapPropagator().Inject(cctx, propagation.HeaderCarrier(intmsg.Header))
 
-	resp, err := a.nc.RequestMsg(intmsg, time.Millisecond*10000) // FIXME-- make timeout configurable
+	cleanup, err := a.attachTriggerPayload(intmsg, data)
+	if err != nil {
+		childSpan.End()
+		return nil, err
+	}
+	defer cleanup()
+
+	var partial *nats.Subscription
+	if partialResults {
+		var err error
+		partial, err = a.nc.SubscribeSync(a.nc.NewRespInbox())
+		if err != nil {
+			childSpan.End()
+			return nil, fmt.Errorf("failed to subscribe to partial trigger results: %s", err)
+		}
+		defer func() { _ = partial.Unsubscribe() }()
+
+		intmsg.Header.Add(NexTriggerPartialInbox, partial.Subject)
+	}
+
+	resp, err := a.nc.RequestMsg(intmsg, timeout)
 	childSpan.End()
 
+	if triggerTimedOut(resp, err) {
+		if partial != nil {
+			return partialTriggerResults(partial), nil
+		}
+		return nil, fmt.Errorf("%w after %s", ErrTriggerTimedOut, timeout)
+	}
+
+	if err == nil {
+		if reason := resp.Header.Get(NexTriggerFailure); reason != "" {
+			return nil, fmt.Errorf("%w: %s", ErrTriggerFailed, reason)
+		}
+	}
+
 	return resp, err
 }
 
+// Requests that the agent replace itself in place with the agent binary indicated by the given
+// request, awaiting the handshake of the restarted agent within the given timeout
+func (a *AgentClient) UpdateAgent(request *AgentUpdateRequest, restartTimeout time.Duration) error {
+	if a.closed.Load() {
+		return ErrAgentClientClosed
+	}
+
+	bytes, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	a.handshakeReceived.Store(false)
+
+	subject := fmt.Sprintf("agentint.%s.update", a.agentID)
+	resp, err := a.nc.Request(subject, bytes, updateTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to submit agent update request: %s", err)
+	}
+
+	var updateResponse DeployResponse
+	err = json.Unmarshal(resp.Data, &updateResponse)
+	if err != nil {
+		return fmt.Errorf("failed to deserialize agent update response: %s", err)
+	}
+
+	if !updateResponse.Accepted {
+		msg := "agent rejected update"
+		if updateResponse.Message != nil {
+			msg = fmt.Sprintf("%s: %s", msg, *updateResponse.Message)
+		}
+		return errors.New(msg)
+	}
+
+	timeoutAt := time.Now().UTC().Add(restartTimeout)
+	for !a.handshakeReceived.Load() {
+		if a.shuttingDown() || time.Now().UTC().After(timeoutAt) {
+			return errors.New("timed out waiting for updated agent to handshake")
+		}
+
+		time.Sleep(time.Millisecond * DefaultRunloopSleepTimeoutMillis)
+	}
+
+	return nil
+}
+
 func (a *AgentClient) awaitHandshake(agentID string) {
 	timeoutAt := time.Now().UTC().Add(a.handshakeTimeout)
 
@@ -247,6 +563,11 @@ func (a *AgentClient) handleHandshake(msg *nats.Msg) {
 		return
 	}
 
+	if phase := req.ReportedPhase(); phase != AgentPhaseUp {
+		a.handlePhase(msg, *req.ID, phase)
+		return
+	}
+
 	a.log.Info("Received agent handshake", slog.String("agent_id", *req.ID), slog.String("message", *req.Message))
 
 	resp, _ := json.Marshal(&HandshakeResponse{})
@@ -257,10 +578,29 @@ func (a *AgentClient) handleHandshake(msg *nats.Msg) {
 		return
 	}
 
+	a.lastHeartbeatAt.Store(time.Now().UTC().UnixNano())
+	up := AgentPhaseUp
+	a.phase.Store(&up)
 	a.handshakeReceived.Store(true)
 	a.handshakeSucceeded(*req.ID)
 }
 
+// Records a lifecycle phase reported by the agent after its initial handshake
+func (a *AgentClient) handlePhase(msg *nats.Msg, agentID string, phase string) {
+	if agentPhaseOrder(phase) < 0 {
+		a.log.Warn("Agent reported unknown lifecycle phase", slog.String("agent_id", agentID), slog.String("phase", phase))
+	} else {
+		a.log.Info("Agent reached lifecycle phase", slog.String("agent_id", agentID), slog.String("phase", phase))
+		a.advancePhase(phase)
+	}
+
+	resp, _ := json.Marshal(&HandshakeResponse{})
+	err := msg.Respond(resp)
+	if err != nil {
+		a.log.Error("Failed to reply to agent lifecycle phase", slog.Any("err", err))
+	}
+}
+
 func (a *AgentClient) handleAgentEvent(msg *nats.Msg) {
 	// agentint.{agentID}.events.{type}
 	tokens := strings.Split(msg.Subject, ".")
@@ -275,23 +615,139 @@ func (a *AgentClient) handleAgentEvent(msg *nats.Msg) {
 
 	a.log.Info("Received agent event", slog.String("agent_id", agentID), slog.String("type", evt.Type()))
 	a.eventReceived(agentID, evt)
+
+	if evt.Type() == WorkloadStoppedEventType && a.workloadExited != nil {
+		var status WorkloadStatusEvent
+		err = evt.DataAs(&status)
+		if err != nil {
+			a.log.Error("Failed to unmarshal workload status from agent event", slog.String("agent_id", agentID), slog.Any("err", err))
+			return
+		}
+
+		a.workloadExited(agentID, status)
+	}
 }
 
 func (a *AgentClient) handleAgentLog(msg *nats.Msg) {
 	tokens := strings.Split(msg.Subject, ".")
 	agentID := tokens[1]
 
-	var logentry LogEntry
-	err := json.Unmarshal(msg.Data, &logentry)
+	entries, err := decodeLogEntries(msg.Data)
 	if err != nil {
 		a.log.Error("Failed to unmarshal log entry from agent", slog.Any("err", err))
 		return
 	}
 
-	a.log.Debug("Received agent log", slog.String("agent_id", agentID), slog.String("log", logentry.Text))
-	a.logReceived(agentID, logentry)
+	a.logSinksMutex.RLock()
+	sinks := a.logSinks
+	a.logSinksMutex.RUnlock()
+
+	for _, logentry := range entries {
+		if logentry.Dropped > 0 {
+			a.log.Warn("Agent dropped workload log lines exceeding its log rate limit", slog.String("agent_id", agentID), slog.Uint64("dropped", logentry.Dropped))
+		}
+
+		a.log.Debug("Received agent log", slog.String("agent_id", agentID), slog.String("log", logentry.Text))
+		for _, sink := range sinks {
+			sink.Write(agentID, logentry)
+		}
+	}
+}
+
+// Decodes the log entries in a message published by an agent, which carries a batch of entries as
+// an array, or a single entry when published by an agent which predates batching
+func decodeLogEntries(data []byte) ([]LogEntry, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var entries []LogEntry
+		err := json.Unmarshal(trimmed, &entries)
+		return entries, err
+	}
+
+	var entry LogEntry
+	err := json.Unmarshal(data, &entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return []LogEntry{entry}, nil
+}
+
+func (a *AgentClient) handleAgentMetric(msg *nats.Msg) {
+	tokens := strings.Split(msg.Subject, ".")
+	agentID := tokens[1]
+
+	var sample MetricSample
+	err := json.Unmarshal(msg.Data, &sample)
+	if err != nil {
+		a.log.Error("Failed to unmarshal metric sample from agent", slog.Any("err", err))
+		return
+	}
+
+	err = sample.Validate()
+	if err != nil {
+		a.log.Warn("Received invalid metric sample from agent", slog.String("agent_id", agentID), slog.Any("err", err))
+		return
+	}
+
+	if a.metricReceived != nil {
+		a.metricReceived(agentID, sample)
+	}
+}
+
+func (a *AgentClient) handleAgentSpans(msg *nats.Msg) {
+	tokens := strings.Split(msg.Subject, ".")
+	agentID := tokens[1]
+
+	var spans []AgentSpan
+	err := json.Unmarshal(msg.Data, &spans)
+	if err != nil {
+		a.log.Error("Failed to unmarshal spans from agent", slog.Any("err", err))
+		return
+	}
+
+	if a.spansReceived != nil {
+		a.spansReceived(agentID, spans)
+	}
+}
+
+func (a *AgentClient) handleHeartbeat(msg *nats.Msg) {
+	var heartbeat AgentHeartbeat
+	err := json.Unmarshal(msg.Data, &heartbeat)
+	if err != nil {
+		a.log.Error("Failed to unmarshal heartbeat from agent", slog.Any("err", err))
+		return
+	}
+
+	a.lastHeartbeat.Store(&heartbeat)
+	a.lastHeartbeatAt.Store(time.Now().UTC().UnixNano())
+}
+
+// Returns the most recent heartbeat received from the agent, if any, and the time at which the
+// agent was last heard from, i.e. when its last heartbeat or handshake was received
+func (a *AgentClient) LastHeartbeat() (*AgentHeartbeat, time.Time) {
+	at := a.lastHeartbeatAt.Load()
+	if at == 0 {
+		return a.lastHeartbeat.Load(), time.Time{}
+	}
+
+	return a.lastHeartbeat.Load(), time.Unix(0, at).UTC()
+}
+
+// Returns true if the agent has been marked degraded for missing heartbeats
+func (a *AgentClient) Degraded() bool {
+	return a.degraded.Load()
+}
+
+// Marks the agent degraded, or no longer degraded, returning true if this changed its state
+func (a *AgentClient) SetDegraded(degraded bool) bool {
+	return a.degraded.CompareAndSwap(!degraded, degraded)
 }
 
 func (a *AgentClient) shuttingDown() bool {
-	return (atomic.LoadUint32(&a.stopping) > 0)
+	return (atomic.LoadUint32(&a.stopping) > 0) || a.closed.Load()
+}
+
+// Returns true if the given error is the result of a request timing out
+func timedOut(err error) bool {
+	return errors.Is(err, nats.ErrTimeout) || errors.Is(err, os.ErrDeadlineExceeded)
 }
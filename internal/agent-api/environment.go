@@ -0,0 +1,91 @@
+package agentapi
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Size of the per-agent keys with which workload environments are sealed
+const EnvironmentKeySize = 32
+
+// Generates a random key with which the workload environments dispatched to a single agent are sealed
+func NewEnvironmentKey() ([]byte, error) {
+	key := make([]byte, EnvironmentKeySize)
+	_, err := rand.Read(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// Encrypts the given workload environment with the given agent's key using AES-256-GCM, returning
+// the base64-encoded ciphertext prefixed with its nonce
+func SealEnvironment(key []byte, env map[string]string) (string, error) {
+	aead, err := environmentCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := json.Marshal(env)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// Decrypts a workload environment sealed by SealEnvironment with the given key
+func OpenEnvironment(key []byte, sealed string) (map[string]string, error) {
+	aead, err := environmentCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("sealed environment is truncated")
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var env map[string]string
+	err = json.Unmarshal(plaintext, &env)
+	if err != nil {
+		return nil, err
+	}
+
+	return env, nil
+}
+
+func environmentCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != EnvironmentKeySize {
+		return nil, fmt.Errorf("environment key must be %d bytes", EnvironmentKeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -12,6 +12,7 @@ const (
 	AgentStoppedEventType          = "agent_stopped"
 	FunctionExecutionFailedType    = "function_exec_failed"
 	FunctionExecutionSucceededType = "function_exec_succeeded"
+	WorkloadOutOfMemoryEventType   = "workload_out_of_memory"
 	WorkloadStartedEventType       = "workload_started" // FIXME-- should this be WorkloadDeployed?
 	WorkloadStoppedEventType       = "workload_stopped" // FIXME-- should this be in addition to WorkloadUndeployed (likely yes, in case of something bad happening...)
 	// FIXME-- where is WorkloadDeployedEventType? (likely just need to rename WorkloadStartedEventType -> WorkloadDeployedEventType)
@@ -26,6 +27,16 @@ type WorkloadStatusEvent struct {
 	WorkloadName string `json:"workload_name"`
 	Code         int    `json:"code"`
 	Message      string `json:"message,omitempty"`
+
+	// Name of the signal which terminated the workload, if it was killed by one
+	Signal string `json:"signal,omitempty"`
+
+	// Time for which the workload ran before it exited; zero if it failed to start
+	RuntimeMillisecond int64 `json:"runtime_ms,omitempty"`
+
+	// Number of bytes written by the workload to stdout and stderr
+	StdoutBytes int64 `json:"stdout_bytes,omitempty"`
+	StderrBytes int64 `json:"stderr_bytes,omitempty"`
 }
 
 type AgentStoppedEvent struct {
@@ -0,0 +1,36 @@
+package agentapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// AgentHeartbeat is published periodically by a running agent so that the node can tell a
+// wedged agent from a healthy one, independent of the health of its workload
+type AgentHeartbeat struct {
+	ID                string    `json:"id"`
+	Timestamp         time.Time `json:"timestamp"`
+	UptimeMillisecond int64     `json:"uptime_ms"`
+	Goroutines        int       `json:"goroutines"`
+	HeapAllocBytes    uint64    `json:"heap_alloc_bytes"`
+	DroppedLogs       uint64    `json:"dropped_logs"`
+	DroppedEvents     uint64    `json:"dropped_events"`
+}
+
+// Returns the internal subject on which the agent running in the given VM publishes heartbeats
+func HeartbeatSubject(vmID string) string {
+	return fmt.Sprintf("agentint.%s.heartbeat", vmID)
+}
+
+// Publishes the given heartbeat to the node on behalf of the agent running in the given VM
+func PublishHeartbeat(nc *nats.Conn, vmID string, heartbeat *AgentHeartbeat) error {
+	raw, err := json.Marshal(heartbeat)
+	if err != nil {
+		return err
+	}
+
+	return nc.Publish(HeartbeatSubject(vmID), raw)
+}
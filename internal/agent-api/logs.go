@@ -9,3 +9,15 @@ const (
 	LogLevelDebug = 5
 	LogLevelTrace = 6
 )
+
+// Receives the log entries an agent forwards to its agent client, e.g. to write them to a file or
+// forward them to an external aggregator. Sinks must not block, since entries are written to them
+// as they are received
+type LogSink interface {
+	Write(agentID string, entry LogEntry)
+}
+
+// Writes the entry to the log callback, so that a callback may be registered as a log sink
+func (f LogCallback) Write(agentID string, entry LogEntry) {
+	f(agentID, entry)
+}
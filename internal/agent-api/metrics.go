@@ -0,0 +1,81 @@
+package agentapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Workload-defined metric sample that is incremented/added to a monotonic counter
+const MetricKindCounter = "counter"
+
+// Workload-defined metric sample representing the current value of a gauge
+const MetricKindGauge = "gauge"
+
+// Workload-defined metric sample recorded into a histogram
+const MetricKindHistogram = "histogram"
+
+// Environment variable giving workloads the internal subject on which to publish metric samples
+const MetricsSubjectEnvVar = "NEX_METRICS_SUBJECT"
+
+// Environment variable giving workloads the URL of the internal NATS server to publish metric samples to
+const MetricsNatsURLEnvVar = "NEX_METRICS_NATS_URL"
+
+var metricNameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.\-/]{0,199}$`)
+
+// MetricSample is a single named metric value published by a workload (via its execution
+// provider) on the internal NATS connection, which the node re-exports as an OTel metric
+type MetricSample struct {
+	Name       string            `json:"name"`
+	Kind       string            `json:"kind"`
+	Value      float64           `json:"value"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+func (s *MetricSample) Validate() error {
+	var err error
+
+	if !metricNameRegex.MatchString(s.Name) {
+		err = errors.Join(err, fmt.Errorf("invalid metric name: %s", s.Name))
+	}
+
+	if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+		err = errors.Join(err, errors.New("metric sample values must be finite"))
+	}
+
+	switch s.Kind {
+	case MetricKindCounter:
+		if s.Value < 0 {
+			err = errors.Join(err, errors.New("counter metric samples must be non-negative"))
+		}
+	case MetricKindGauge, MetricKindHistogram:
+	default:
+		err = errors.Join(err, fmt.Errorf("unsupported metric kind: %s", s.Kind))
+	}
+
+	return err
+}
+
+// Returns the internal subject on which metric samples for the given VM are published
+func MetricsSubject(vmID string) string {
+	return fmt.Sprintf("agentint.%s.metrics", vmID)
+}
+
+// Publishes the given metric sample to the node on behalf of the workload running in the given VM
+func PublishMetricSample(nc *nats.Conn, vmID string, sample *MetricSample) error {
+	err := sample.Validate()
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(sample)
+	if err != nil {
+		return err
+	}
+
+	return nc.Publish(MetricsSubject(vmID), raw)
+}
@@ -0,0 +1,54 @@
+package agentapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AgentPingResponse is the reply of a running agent to a ping from its node, proving that the
+// agent is still alive and responsive
+type AgentPingResponse struct {
+	UptimeMillisecond int64      `json:"uptime_ms"`
+	LastExecAt        *time.Time `json:"last_exec_at,omitempty"`
+
+	// Uptime of each workload deployed to the agent, keyed by workload id
+	WorkloadUptimeMillisecond map[string]int64 `json:"workload_uptime_ms,omitempty"`
+}
+
+// Returns the internal subject on which the agent running in the given VM answers pings
+func PingSubject(vmID string) string {
+	return fmt.Sprintf("agentint.%s.ping", vmID)
+}
+
+// Pings the agent, waiting up to the given timeout for its reply. The reply and the round-trip
+// latency of the ping are recorded, and available from LastPing
+func (a *AgentClient) Ping(timeout time.Duration) error {
+	if a.closed.Load() {
+		return ErrAgentClientClosed
+	}
+
+	start := time.Now()
+
+	msg, err := a.nc.Request(PingSubject(a.agentID), []byte{}, timeout)
+	if err != nil {
+		return err
+	}
+
+	latency := time.Since(start)
+
+	var pong AgentPingResponse
+	err = json.Unmarshal(msg.Data, &pong)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal ping response from agent: %s", err)
+	}
+
+	a.lastPing.Store(&pong)
+	a.lastPingLatency.Store(int64(latency))
+	return nil
+}
+
+// Returns the reply to the most recent successful ping of the agent, if any, and its round-trip latency
+func (a *AgentClient) LastPing() (*AgentPingResponse, time.Duration) {
+	return a.lastPing.Load(), time.Duration(a.lastPingLatency.Load())
+}
@@ -0,0 +1,175 @@
+package agentapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Name of the tracer with which the agent and its execution providers record spans
+const AgentTracerName = "nex-agent"
+
+// AgentSpan is a completed span recorded by the agent, published on the internal NATS connection
+// so that the node can export it on behalf of an agent without access to the trace collector
+type AgentSpan struct {
+	Name              string            `json:"name"`
+	TraceID           string            `json:"trace_id"`
+	SpanID            string            `json:"span_id"`
+	ParentSpanID      string            `json:"parent_span_id,omitempty"`
+	Kind              int               `json:"kind"`
+	StartTime         time.Time         `json:"start_time"`
+	EndTime           time.Time         `json:"end_time"`
+	Attributes        map[string]string `json:"attributes,omitempty"`
+	Events            []AgentSpanEvent  `json:"events,omitempty"`
+	StatusCode        uint32            `json:"status_code"`
+	StatusDescription string            `json:"status_description,omitempty"`
+	Scope             string            `json:"scope"`
+}
+
+type AgentSpanEvent struct {
+	Name       string            `json:"name"`
+	Time       time.Time         `json:"time"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Converts the given completed span for publication to the node. Attribute values are
+// converted to strings
+func NewAgentSpan(span tracesdk.ReadOnlySpan) AgentSpan {
+	s := AgentSpan{
+		Name:              span.Name(),
+		TraceID:           span.SpanContext().TraceID().String(),
+		SpanID:            span.SpanContext().SpanID().String(),
+		Kind:              int(span.SpanKind()),
+		StartTime:         span.StartTime(),
+		EndTime:           span.EndTime(),
+		Attributes:        spanAttributes(span.Attributes()),
+		StatusCode:        uint32(span.Status().Code),
+		StatusDescription: span.Status().Description,
+		Scope:             span.InstrumentationScope().Name,
+	}
+
+	if span.Parent().HasSpanID() {
+		s.ParentSpanID = span.Parent().SpanID().String()
+	}
+
+	for _, evt := range span.Events() {
+		s.Events = append(s.Events, AgentSpanEvent{
+			Name:       evt.Name,
+			Time:       evt.Time,
+			Attributes: spanAttributes(evt.Attributes),
+		})
+	}
+
+	return s
+}
+
+// Reconstructs the span for export, attributing it to the given resource
+func (s *AgentSpan) Snapshot(res *resource.Resource) (tracesdk.ReadOnlySpan, error) {
+	traceID, err := trace.TraceIDFromHex(s.TraceID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trace id: %s", err)
+	}
+
+	spanID, err := trace.SpanIDFromHex(s.SpanID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid span id: %s", err)
+	}
+
+	var parent trace.SpanContext
+	if s.ParentSpanID != "" {
+		parentID, err := trace.SpanIDFromHex(s.ParentSpanID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parent span id: %s", err)
+		}
+
+		parent = trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     parentID,
+			TraceFlags: trace.FlagsSampled,
+			Remote:     true,
+		})
+	}
+
+	events := make([]tracesdk.Event, len(s.Events))
+	for i, evt := range s.Events {
+		events[i] = tracesdk.Event{
+			Name:       evt.Name,
+			Time:       evt.Time,
+			Attributes: keyValues(evt.Attributes),
+		}
+	}
+
+	stub := tracetest.SpanStub{
+		Name: s.Name,
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     spanID,
+			TraceFlags: trace.FlagsSampled,
+		}),
+		Parent:     parent,
+		SpanKind:   trace.SpanKind(s.Kind),
+		StartTime:  s.StartTime,
+		EndTime:    s.EndTime,
+		Attributes: keyValues(s.Attributes),
+		Events:     events,
+		Status: tracesdk.Status{
+			Code:        codes.Code(s.StatusCode),
+			Description: s.StatusDescription,
+		},
+		Resource:               res,
+		InstrumentationLibrary: instrumentation.Scope{Name: s.Scope},
+	}
+
+	return stub.Snapshot(), nil
+}
+
+// Returns the internal subject on which spans recorded by the agent in the given VM are published
+func TracesSubject(vmID string) string {
+	return fmt.Sprintf("agentint.%s.traces", vmID)
+}
+
+// Publishes the given completed spans to the node on behalf of the agent running in the given VM
+func PublishSpans(nc *nats.Conn, vmID string, spans []tracesdk.ReadOnlySpan) error {
+	agentSpans := make([]AgentSpan, len(spans))
+	for i, span := range spans {
+		agentSpans[i] = NewAgentSpan(span)
+	}
+
+	raw, err := json.Marshal(agentSpans)
+	if err != nil {
+		return err
+	}
+
+	return nc.Publish(TracesSubject(vmID), raw)
+}
+
+func spanAttributes(kvs []attribute.KeyValue) map[string]string {
+	if len(kvs) == 0 {
+		return nil
+	}
+
+	attrs := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+
+	return attrs
+}
+
+func keyValues(attrs map[string]string) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for k, v := range attrs {
+		kvs = append(kvs, attribute.String(k, v))
+	}
+
+	return kvs
+}
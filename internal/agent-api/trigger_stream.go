@@ -0,0 +1,226 @@
+package agentapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Time within which each chunk of a streamed trigger response must follow the previous one
+const triggerStreamIdleTimeout = 5 * time.Second
+
+// Returned when the first chunk of a streamed trigger response is not received within the trigger
+// timeout, or a subsequent chunk within the idle timeout
+var ErrTriggerStreamTimeout = fmt.Errorf("%w waiting for streamed response", ErrTriggerTimedOut)
+
+// Returned when the workload fails partway through streaming its response
+var ErrTriggerStreamFailed = errors.New("streamed trigger failed")
+
+// Triggers the agent's workload on the given subject, returning its response as a stream of the
+// chunks the agent publishes to a reply inbox until it ends the stream. The first chunk must be
+// received within the given trigger timeout, within which the agent must also complete the
+// execution, and each subsequent chunk within the idle timeout. The stream must be closed once read
+func (a *AgentClient) RunTriggerStream(ctx context.Context, tracer trace.Tracer, subject string, data []byte, timeout time.Duration) (*TriggerStream, error) {
+	if a.closed.Load() {
+		return nil, ErrAgentClientClosed
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultTriggerTimeout
+	}
+
+	intmsg := nats.NewMsg(TriggerSubject(a.agentID, a.agentID))
+	intmsg.Header.Add(NexTriggerSubject, subject)
+	intmsg.Header.Add(NexTriggerTimeoutMs, strconv.FormatInt(timeout.Milliseconds(), 10))
+	intmsg.Header.Add(controlapi.TriggerStreamHeader, "true")
+
+	cctx, childSpan := tracer.Start(
+		ctx,
+		"internal stream request",
+		trace.WithSpanKind(trace.SpanKindClient),
+	)
+	defer childSpan.End()
+
+	otel.GetTextMapPropagator().Inject(cctx, propagation.HeaderCarrier(intmsg.Header))
+
+	cleanup, err := a.attachTriggerPayload(intmsg, data)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := a.nc.SubscribeSync(a.nc.NewRespInbox())
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to subscribe to streamed trigger response: %s", err)
+	}
+
+	intmsg.Reply = sub.Subject
+	err = a.nc.PublishMsg(intmsg)
+	if err != nil {
+		_ = sub.Unsubscribe()
+		cleanup()
+		return nil, err
+	}
+
+	return &TriggerStream{
+		sub:              sub,
+		cleanup:          cleanup,
+		firstByteTimeout: timeout,
+		idleTimeout:      triggerStreamIdleTimeout,
+	}, nil
+}
+
+// Response of a streamed trigger, read as the chunks published by the agent arrive
+type TriggerStream struct {
+	sub     *nats.Subscription
+	cleanup func()
+	once    sync.Once
+
+	firstByteTimeout time.Duration
+	idleTimeout      time.Duration
+
+	pending  []byte
+	received bool
+	ended    bool
+	trailer  nats.Header
+}
+
+// Reads the next chunks of the response, returning io.EOF once the agent has ended the stream,
+// ErrTriggerStreamFailed if the workload failed partway through it, or ErrTriggerStreamTimeout if
+// the next chunk was not received in time
+func (s *TriggerStream) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		if s.ended {
+			return 0, io.EOF
+		}
+
+		timeout := s.idleTimeout
+		if !s.received {
+			timeout = s.firstByteTimeout
+		}
+
+		msg, err := s.sub.NextMsg(timeout)
+		if err != nil {
+			if errors.Is(err, nats.ErrTimeout) {
+				return 0, ErrTriggerStreamTimeout
+			}
+			return 0, err
+		}
+		s.received = true
+
+		// a refused trigger is answered with a single message rather than a stream
+		if streamEnded(msg) {
+			s.ended = true
+			s.trailer = msg.Header
+
+			if reason := msg.Header.Get(controlapi.TriggerStreamErrorHeader); reason != "" {
+				return 0, fmt.Errorf("%w: %s", ErrTriggerStreamFailed, reason)
+			}
+			continue
+		}
+
+		s.pending = msg.Data
+	}
+
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// Returns the header of the message with which the agent ended the stream, e.g. carrying the
+// workload's runtime or the reason it refused the trigger; nil until the stream has ended
+func (s *TriggerStream) Trailer() nats.Header {
+	return s.trailer
+}
+
+// Stops receiving the response and deletes any spilled trigger payload
+func (s *TriggerStream) Close() error {
+	var err error
+	s.once.Do(func() {
+		err = s.sub.Unsubscribe()
+		s.cleanup()
+	})
+
+	return err
+}
+
+func streamEnded(msg *nats.Msg) bool {
+	return strings.EqualFold(msg.Header.Get(controlapi.TriggerStreamEndHeader), "true") ||
+		msg.Header.Get(controlapi.TriggerErrorHeader) != ""
+}
+
+// Returns true if the given trigger message requests that the response be streamed to its reply subject
+func StreamingTrigger(msg *nats.Msg) bool {
+	return msg.Reply != "" && strings.EqualFold(msg.Header.Get(controlapi.TriggerStreamHeader), "true")
+}
+
+// Publishes a streamed trigger response to a reply subject in chunks of at most the connection's
+// max payload, buffering writes until a chunk fills or the stream is ended
+type TriggerStreamWriter struct {
+	mutex     *sync.Mutex
+	nc        *nats.Conn
+	subject   string
+	chunkSize int
+	buf       []byte
+}
+
+func NewTriggerStreamWriter(nc *nats.Conn, subject string) *TriggerStreamWriter {
+	return &TriggerStreamWriter{
+		mutex:     &sync.Mutex{},
+		nc:        nc,
+		subject:   subject,
+		chunkSize: int(nc.MaxPayload()),
+	}
+}
+
+func (w *TriggerStreamWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for len(w.buf) >= w.chunkSize {
+		err := w.nc.Publish(w.subject, w.buf[:w.chunkSize])
+		if err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[w.chunkSize:]
+	}
+
+	return len(p), nil
+}
+
+// Publishes any buffered output followed by the message ending the stream, carrying the given
+// header, if any, and the reason the workload failed, if it did
+func (w *TriggerStreamWriter) End(header nats.Header, failure error) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.buf) > 0 {
+		err := w.nc.Publish(w.subject, w.buf)
+		if err != nil {
+			return err
+		}
+		w.buf = nil
+	}
+
+	if header == nil {
+		header = nats.Header{}
+	}
+	header.Set(controlapi.TriggerStreamEndHeader, "true")
+	if failure != nil {
+		header.Set(controlapi.TriggerStreamErrorHeader, failure.Error())
+	}
+
+	return w.nc.PublishMsg(&nats.Msg{Subject: w.subject, Header: header})
+}
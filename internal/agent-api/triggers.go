@@ -0,0 +1,265 @@
+package agentapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+// Header referencing the key in the internal cache to which an oversized trigger payload was spilled
+const NexTriggerPayloadRef = "x-nex-trigger-payload-ref"
+
+// Prefix of the keys to which oversized trigger payloads are spilled. Workload names cannot contain
+// a dash, so these never collide with cached workloads
+const triggerPayloadKeyPrefix = "trigger-"
+
+// Returned when a trigger payload exceeds the maximum size which can be forwarded to an agent
+var ErrTriggerPayloadTooLarge = errors.New("trigger payload too large")
+
+// Returned when a triggered workload does not respond within its trigger timeout
+var ErrTriggerTimedOut = errors.New("trigger timed out")
+
+// Returned when a triggered workload reports that its execution failed
+var ErrTriggerFailed = errors.New("trigger failed")
+
+// Returns the internal subject on which the workload with the given id, deployed to the agent
+// running in the given VM, is triggered. The workload known by the VM's own id, as when the agent
+// hosts only the one workload, is triggered on the agent's trigger subject
+func TriggerSubject(vmID, workloadID string) string {
+	if workloadID == "" || workloadID == vmID {
+		return fmt.Sprintf("agentint.%s.trigger", vmID)
+	}
+
+	return fmt.Sprintf("agentint.%s.trigger.%s", vmID, workloadID)
+}
+
+// Returns true if the request permits the workload to be triggered on the given subject. A
+// request which does not restrict its trigger subjects permits any subject
+func (request *DeployRequest) PermitsTriggerSubject(subject string) bool {
+	return TriggerSubjectAllowed(request.AllowedTriggerSubjects, subject)
+}
+
+// Returns true if the given subject falls within the given allowed trigger subjects, or if no
+// trigger subjects are allowed in particular
+func TriggerSubjectAllowed(allowed []string, subject string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, filter := range allowed {
+		if subjectWithin(subject, filter) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the request's trigger subjects which are not permitted by its allowed trigger subjects
+func (request *DeployRequest) DeniedTriggerSubjects() []string {
+	denied := make([]string, 0)
+	for _, subject := range request.TriggerSubjects {
+		if !request.PermitsTriggerSubject(subject) {
+			denied = append(denied, subject)
+		}
+	}
+
+	return denied
+}
+
+// Returns true if every subject matched by the given subject is also matched by the given filter.
+// A wildcard in the subject is only covered by the same wildcard, or by a full wildcard, in the filter
+func subjectWithin(subject, filter string) bool {
+	subjectTokens := strings.Split(subject, ".")
+	filterTokens := strings.Split(filter, ".")
+
+	for i, ft := range filterTokens {
+		if i >= len(subjectTokens) {
+			return false
+		}
+
+		st := subjectTokens[i]
+		switch {
+		case ft == ">":
+			return true
+		case st == ">":
+			return false
+		case ft == "*":
+			continue
+		case st != ft:
+			return false
+		}
+	}
+
+	return len(subjectTokens) == len(filterTokens)
+}
+
+// Returns a context of the given one bounded by the trigger timeout carried by the given trigger
+// message, if any, measured from its receipt
+func TriggerContext(ctx context.Context, msg *nats.Msg) (context.Context, context.CancelFunc) {
+	millis, err := strconv.ParseInt(msg.Header.Get(NexTriggerTimeoutMs), 10, 64)
+	if err != nil || millis <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, time.Duration(millis)*time.Millisecond)
+}
+
+// Responds to the given trigger message, whose execution failed, with the reason it failed or, if
+// it timed out, the timeout
+func FailTrigger(msg *nats.Msg, failure error, timedOut bool) error {
+	header := nats.Header{NexTriggerFailure: []string{failure.Error()}}
+	if timedOut {
+		header = nats.Header{controlapi.TriggerTimedOutHeader: []string{"true"}}
+	}
+
+	return msg.RespondMsg(&nats.Msg{Header: header})
+}
+
+// Responds to the given trigger message, without executing the workload, with the reason the
+// trigger was refused
+func RefuseTrigger(msg *nats.Msg, reason string) error {
+	return msg.RespondMsg(&nats.Msg{
+		Header: nats.Header{controlapi.TriggerErrorHeader: []string{reason}},
+	})
+}
+
+// Returns the payload of the given trigger message, retrieving it from the internal cache
+// if the payload was spilled there rather than forwarded inline
+func TriggerPayload(nc *nats.Conn, msg *nats.Msg) ([]byte, error) {
+	key := msg.Header.Get(NexTriggerPayloadRef)
+	if key == "" {
+		return msg.Data, nil
+	}
+
+	store, err := triggerPayloadStore(nc)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := store.GetBytes(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve spilled trigger payload %s: %s", key, err)
+	}
+
+	return payload, nil
+}
+
+// Returns true if the given trigger request timed out, either on the node or, as indicated by
+// the response, on the agent
+func triggerTimedOut(resp *nats.Msg, err error) bool {
+	if err != nil {
+		return errors.Is(err, nats.ErrTimeout)
+	}
+
+	return strings.EqualFold(resp.Header.Get(controlapi.TriggerTimedOutHeader), "true")
+}
+
+// Returns a trigger response accumulating the partial results emitted to the given subscription.
+// Partial results are emitted by the agent ahead of its response on the same connection, so every
+// partial result has been delivered by the time the trigger request has timed out
+func partialTriggerResults(sub *nats.Subscription) *nats.Msg {
+	resp := nats.NewMsg("")
+	resp.Header.Set(controlapi.TriggerPartialResultsHeader, "true")
+	resp.Header.Set(controlapi.TriggerTimedOutHeader, "true")
+
+	for {
+		msg, err := sub.NextMsg(0)
+		if err != nil {
+			break
+		}
+		resp.Data = append(resp.Data, msg.Data...)
+	}
+
+	return resp
+}
+
+// Attaches the given payload to the given trigger message, spilling it to the internal cache if it
+// is too large to be forwarded inline and spilling is enabled. The returned function deletes any
+// spilled payload once the trigger has completed
+func (a *AgentClient) attachTriggerPayload(msg *nats.Msg, data []byte) (func(), error) {
+	maxPayload := a.maxTriggerPayloadSize(msg.Header)
+	if len(data) <= maxPayload {
+		msg.Data = data
+		return func() {}, nil
+	}
+
+	if !a.spillTriggerPayloads {
+		return nil, fmt.Errorf("%w: %d bytes exceeds the maximum of %d bytes", ErrTriggerPayloadTooLarge, len(data), maxPayload)
+	}
+
+	key, err := a.spillTriggerPayload(data)
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Header.Add(NexTriggerPayloadRef, key)
+	return func() { a.deleteSpilledTriggerPayload(key) }, nil
+}
+
+// Returns the maximum size of a trigger payload forwarded inline with a message carrying the
+// given header: the configured maximum, if any, bounded by the internal NATS server's max payload
+func (a *AgentClient) maxTriggerPayloadSize(header nats.Header) int {
+	limit := int(a.nc.MaxPayload()) - headerSize(header)
+	if a.maxTriggerPayload > 0 && a.maxTriggerPayload < limit {
+		limit = a.maxTriggerPayload
+	}
+
+	return limit
+}
+
+// Writes the given trigger payload to the internal cache, returning the key to which it was written
+func (a *AgentClient) spillTriggerPayload(data []byte) (string, error) {
+	store, err := triggerPayloadStore(a.nc)
+	if err != nil {
+		return "", err
+	}
+
+	key := triggerPayloadKeyPrefix + uuid.NewString()
+	_, err = store.PutBytes(key, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to spill trigger payload: %s", err)
+	}
+
+	return key, nil
+}
+
+func (a *AgentClient) deleteSpilledTriggerPayload(key string) {
+	store, err := triggerPayloadStore(a.nc)
+	if err == nil {
+		err = store.Delete(key)
+	}
+
+	if err != nil {
+		a.log.Warn("Failed to delete spilled trigger payload", slog.String("key", key), slog.Any("err", err))
+	}
+}
+
+func triggerPayloadStore(nc *nats.Conn) (nats.ObjectStore, error) {
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, err
+	}
+
+	return js.ObjectStore(WorkloadCacheBucket)
+}
+
+// Returns the size of the given header as encoded on the wire
+func headerSize(header nats.Header) int {
+	size := len("NATS/1.0\r\n\r\n")
+	for k, values := range header {
+		for _, v := range values {
+			size += len(k) + len(": \r\n") + len(v)
+		}
+	}
+
+	return size
+}
@@ -3,13 +3,18 @@ package agentapi
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/url"
+	"path"
+	"runtime"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/nats-io/jwt/v2"
 	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
 )
 
 // Executable Linkable Format execution provider
@@ -24,9 +29,42 @@ const NexExecutionProviderOCI = "oci"
 // Wasm execution provider
 const NexExecutionProviderWasm = "wasm"
 
+// Workload types with an execution provider built into the agent, whether or not it can be
+// built on the current platform
+var BuiltinWorkloadTypes = []string{
+	NexExecutionProviderELF,
+	NexExecutionProviderV8,
+	NexExecutionProviderOCI,
+	NexExecutionProviderWasm,
+}
+
+// Returns the built-in workload types whose execution provider the agent can build on the
+// current platform. The V8 provider is only built for linux/amd64, and the OCI provider is
+// not yet implemented
+func SupportedWorkloadTypes() []string {
+	types := []string{NexExecutionProviderELF}
+	if runtime.GOOS == "linux" && runtime.GOARCH == "amd64" {
+		types = append(types, NexExecutionProviderV8)
+	}
+
+	return append(types, NexExecutionProviderWasm)
+}
+
+// Kernel boot args with which the node passes a machine on an IPv6 network its address, with the
+// prefix length of its subnet, and its gateway; the kernel's ip= boot arg only supports IPv4
+const BootArgIPv6Address = "nex.ipv6_address"
+const BootArgIPv6Gateway = "nex.ipv6_gateway"
+
 // Name of the internal, non-public bucket for sharing files between host and agent
 const WorkloadCacheBucket = "NEXCACHE"
 
+// Name of the internal, non-public bucket into which agents capture the stdout and stderr of
+// workloads whose deploy requests ask for them
+const WorkloadLogsBucket = "NEXLOGS"
+
+// Name of the workload artifact within an image attached to an agent as a block device
+const ArtifactDeviceWorkloadFile = "workload"
+
 // DefaultRunloopSleepTimeoutMillis default number of milliseconds to sleep during execution runloops
 const DefaultRunloopSleepTimeoutMillis = 25
 
@@ -52,38 +90,126 @@ type ExecutionProviderParams struct {
 
 	// NATS connection which be injected into the execution provider
 	NATSConn *nats.Conn `json:"-"`
+
+	// Called each time the deployed function has been executed, if applicable
+	Executed func() `json:"-"`
 }
 
 // DeployRequest processed by the agent
 type DeployRequest struct {
-	Argv            []string          `json:"argv,omitempty"`
-	DecodedClaims   jwt.GenericClaims `json:"-"`
-	Description     *string           `json:"description"`
-	Environment     map[string]string `json:"environment"`
-	Essential       *bool             `json:"essential,omitempty"`
-	Hash            string            `json:"hash,omitempty"`
-	Namespace       *string           `json:"namespace,omitempty"`
-	RetriedAt       *time.Time        `json:"retried_at,omitempty"`
-	RetryCount      *uint             `json:"retry_count,omitempty"`
-	TotalBytes      int64             `json:"total_bytes,omitempty"`
-	TriggerSubjects []string          `json:"trigger_subjects"`
-	WorkloadName    *string           `json:"workload_name,omitempty"`
-	WorkloadType    *string           `json:"workload_type,omitempty"`
+	AllowedTriggerSubjects     []string            `json:"allowed_trigger_subjects,omitempty"`
+	ArtifactBucket             *string             `json:"artifact_bucket,omitempty"`
+	ArtifactDecryption         *ArtifactDecryption `json:"artifact_decryption,omitempty"`
+	ArtifactDevice             *string             `json:"artifact_device,omitempty"`
+	ArtifactLocation           *string             `json:"artifact_location,omitempty"`
+	Argv                       []string            `json:"argv,omitempty"`
+	DecodedClaims              jwt.GenericClaims   `json:"-"`
+	Description                *string             `json:"description"`
+	Environment                map[string]string   `json:"environment"`
+	Essential                  *bool               `json:"essential,omitempty"`
+	ExitCode                   *int                `json:"exit_code,omitempty"`
+	Gid                        *int                `json:"gid,omitempty"`
+	Hash                       string              `json:"hash,omitempty"`
+	LogCaptureKey              *string             `json:"log_capture_key,omitempty"`
+	LogCaptureMaxBytes         int64               `json:"log_capture_max_bytes,omitempty"`
+	MemoryLimitMib             *int                `json:"memory_limit_mib,omitempty"`
+	Namespace                  *string             `json:"namespace,omitempty"`
+	RetriedAt                  *time.Time          `json:"retried_at,omitempty"`
+	RetryCount                 *uint               `json:"retry_count,omitempty"`
+	SealedEnvironment          *string             `json:"sealed_environment,omitempty"`
+	StopGracePeriodMillisecond *int                `json:"stop_grace_period_ms,omitempty"`
+	Tags                       map[string]string   `json:"tags,omitempty"`
+	TotalBytes                 int64               `json:"total_bytes,omitempty"`
+	TriggerSubjects            []string            `json:"trigger_subjects"`
+	TriggerTimeoutMillisecond  *int                `json:"trigger_timeout_ms,omitempty"`
+	Uid                        *int                `json:"uid,omitempty"`
+	WorkingDirectory           *string             `json:"working_directory,omitempty"`
+	WorkloadID                 *string             `json:"workload_id,omitempty"`
+	WorkloadName               *string             `json:"workload_name,omitempty"`
+	WorkloadType               *string             `json:"workload_type,omitempty"`
 
 	Stderr      io.Writer `json:"-"`
 	Stdout      io.Writer `json:"-"`
 	TmpFilename *string   `json:"-"`
 
-	EncryptedEnvironment *string  `json:"-"`
-	JsDomain             *string  `json:"-"`
-	Location             *url.URL `json:"-"`
-	SenderPublicKey      *string  `json:"-"`
-	TargetNode           *string  `json:"-"`
-	WorkloadJwt          *string  `json:"-"`
+	CaptureLogs          bool                          `json:"-"`
+	Dependencies         []string                      `json:"-"`
+	EncryptedEnvironment *string                       `json:"-"`
+	GitSource            *controlapi.GitSource         `json:"-"`
+	JsDomain             *string                       `json:"-"`
+	KeyValueBuckets      []controlapi.KeyValueBucket   `json:"-"`
+	Location             *url.URL                      `json:"-"`
+	OriginalWorkloadID   *string                       `json:"-"`
+	Resources            *controlapi.WorkloadResources `json:"-"`
+	SenderPublicKey      *string                       `json:"-"`
+	TargetNode           *string                       `json:"-"`
+	TargetVM             *string                       `json:"-"`
+	TraceSamplingRate    *float64                      `json:"-"`
+	WorkloadJwt          *string                       `json:"-"`
 
 	Errors []error `json:"errors,omitempty"`
 }
 
+// Instructs the agent to decrypt a workload artifact stored encrypted at rest just before it is
+// run, verifying the decrypted artifact against the given hash
+type ArtifactDecryption struct {
+	Algorithm string `json:"algorithm"`
+	Key       []byte `json:"key"`
+	// SHA-256 hash of the decrypted artifact
+	Hash string `json:"hash"`
+}
+
+// Returns the name of the internal bucket from which the workload artifact should be retrieved
+func (request *DeployRequest) CacheBucket() string {
+	if request.ArtifactBucket != nil && *request.ArtifactBucket != "" {
+		return *request.ArtifactBucket
+	}
+
+	return WorkloadCacheBucket
+}
+
+// Returns the id to which the telemetry of the workload deployed to the agent with the given id is
+// attributed: the id with which it was first deployed if it has since been restarted on another
+// agent, so that its telemetry is continuous across restarts
+func (request *DeployRequest) TelemetryWorkloadID(workloadID string) string {
+	if request.OriginalWorkloadID != nil {
+		return *request.OriginalWorkloadID
+	}
+
+	return workloadID
+}
+
+// Returns the id by which the workload is known to the agent running in the VM with the given id:
+// the request's own workload id, if given, otherwise the VM's id, as when the agent hosts only
+// the one workload
+func (request *DeployRequest) ResolveWorkloadID(vmID string) string {
+	if request.WorkloadID != nil && *request.WorkloadID != "" {
+		return *request.WorkloadID
+	}
+
+	return vmID
+}
+
+// Returns the grace period given to the workload to exit when it is stopped: the request's own
+// grace period, if given, otherwise the given default
+func (request *DeployRequest) ResolveStopGracePeriod(defaultMillis int) time.Duration {
+	if request.StopGracePeriodMillisecond != nil {
+		return time.Duration(*request.StopGracePeriodMillisecond) * time.Millisecond
+	}
+
+	return time.Duration(defaultMillis) * time.Millisecond
+}
+
+// Returns the time within which each trigger of the workload must complete: the request's own
+// trigger timeout, if given, otherwise the default
+func (request *DeployRequest) ResolveTriggerTimeout() time.Duration {
+	if request.TriggerTimeoutMillisecond != nil {
+		return time.Duration(*request.TriggerTimeoutMillisecond) * time.Millisecond
+	}
+
+	return DefaultTriggerTimeout
+}
+
 func (request *DeployRequest) IsEssential() bool {
 	return request.Essential != nil && *request.Essential
 }
@@ -94,6 +220,31 @@ func (request *DeployRequest) SupportsEssential() bool {
 		strings.EqualFold(*request.WorkloadType, "oci")
 }
 
+// Returns true if the run request supports running the workload as a given user
+// and in a given working directory
+func (request *DeployRequest) SupportsRunAs() bool {
+	return strings.EqualFold(*request.WorkloadType, NexExecutionProviderELF)
+}
+
+// Returns true if the run request supports limiting the memory used by the workload
+func (request *DeployRequest) SupportsMemoryLimit() bool {
+	return strings.EqualFold(*request.WorkloadType, NexExecutionProviderELF)
+}
+
+// Returns the gid as which the workload is run, defaulting to the uid when no gid is specified
+func (request *DeployRequest) RunAsGid() *int {
+	if request.Gid != nil {
+		return request.Gid
+	}
+
+	return request.Uid
+}
+
+// Returns true if the run request specifies a user or working directory for the workload
+func (request *DeployRequest) SpecifiesRunAs() bool {
+	return request.Uid != nil || request.Gid != nil || request.WorkingDirectory != nil
+}
+
 // Returns true if the run request supports trigger subjects
 func (request *DeployRequest) SupportsTriggerSubjects() bool {
 	return (strings.EqualFold(*request.WorkloadType, "v8") ||
@@ -116,6 +267,26 @@ func (r *DeployRequest) Validate() error {
 		err = errors.Join(err, errors.New("essential flag is not supported for workload type"))
 	}
 
+	if r.Gid != nil && r.Uid == nil {
+		err = errors.Join(err, errors.New("uid is required when gid is specified"))
+	}
+
+	if (r.Uid != nil && *r.Uid < 0) || (r.Gid != nil && *r.Gid < 0) {
+		err = errors.Join(err, errors.New("uid and gid must be >= 0"))
+	}
+
+	if r.MemoryLimitMib != nil && *r.MemoryLimitMib <= 0 {
+		err = errors.Join(err, errors.New("memory limit must be > 0"))
+	}
+
+	if r.TriggerTimeoutMillisecond != nil && *r.TriggerTimeoutMillisecond <= 0 {
+		err = errors.Join(err, errors.New("trigger timeout must be > 0"))
+	}
+
+	if r.WorkingDirectory != nil && !path.IsAbs(*r.WorkingDirectory) {
+		err = errors.Join(err, errors.New("working directory must be an absolute path"))
+	}
+
 	if r.Hash == "" { // FIXME--- this should probably be checked against *string
 		err = errors.Join(err, errors.New("hash is required"))
 	}
@@ -132,18 +303,123 @@ func (r *DeployRequest) Validate() error {
 		err = errors.Join(err, errors.New("at least one trigger subject is required for this workload type"))
 	}
 
+	if denied := r.DeniedTriggerSubjects(); len(denied) > 0 {
+		err = errors.Join(err, fmt.Errorf("trigger subjects not allowed for workload: %s", strings.Join(denied, ", ")))
+	}
+
+	if r.WorkloadType != nil && r.SpecifiesRunAs() && !r.SupportsRunAs() {
+		err = errors.Join(err, errors.New("uid, gid and working directory are not supported for workload type"))
+	}
+
+	if r.WorkloadType != nil && r.MemoryLimitMib != nil && !r.SupportsMemoryLimit() {
+		err = errors.Join(err, errors.New("memory limit is not supported for workload type"))
+	}
+
+	return err
+}
+
+// Request for an idle agent to stage a workload artifact ahead of any deployment, so that
+// a subsequent deploy request for the same artifact does not need to fetch it
+type PrepareRequest struct {
+	ArtifactBucket *string `json:"artifact_bucket,omitempty"`
+	ArtifactKey    string  `json:"artifact_key"`
+	Hash           string  `json:"hash"`
+	WorkloadType   string  `json:"workload_type"`
+}
+
+// Returns the name of the internal bucket from which the artifact should be retrieved
+func (r *PrepareRequest) CacheBucket() string {
+	if r.ArtifactBucket != nil && *r.ArtifactBucket != "" {
+		return *r.ArtifactBucket
+	}
+
+	return WorkloadCacheBucket
+}
+
+func (r *PrepareRequest) Validate() error {
+	var err error
+
+	if r.ArtifactKey == "" {
+		err = errors.Join(err, errors.New("artifact key is required"))
+	}
+
+	if r.Hash == "" {
+		err = errors.Join(err, errors.New("hash is required"))
+	}
+
+	if r.WorkloadType == "" {
+		err = errors.Join(err, errors.New("workload type is required"))
+	}
+
+	return err
+}
+
+// Request for an agent to replace itself in place with the signed agent binary staged in the
+// internal cache, redeploying its workload, if any, once restarted
+type AgentUpdateRequest struct {
+	ArtifactKey string `json:"artifact_key"`
+	Hash        string `json:"hash"`
+	Signature   string `json:"signature"`
+}
+
+func (r *AgentUpdateRequest) Validate() error {
+	var err error
+
+	if r.ArtifactKey == "" {
+		err = errors.Join(err, errors.New("artifact key is required"))
+	}
+
+	if r.Hash == "" {
+		err = errors.Join(err, errors.New("hash is required"))
+	}
+
+	if r.Signature == "" {
+		err = errors.Join(err, errors.New("signature is required"))
+	}
+
 	return err
 }
 
 type DeployResponse struct {
 	Accepted bool    `json:"accepted"`
 	Message  *string `json:"message"`
+	// Indicates whether the agent deployed the workload from an artifact staged ahead of the
+	// deployment rather than fetching it; not set when the artifact was attached as a device
+	ArtifactCached *bool `json:"artifact_cached,omitempty"`
+	// Indicates that the agent will report the workload ready once its execution provider says so,
+	// rather than it being ready as soon as it is deployed
+	AwaitingReady bool `json:"awaiting_ready,omitempty"`
 }
 
+// Lifecycle phases reported by an agent through its handshake subject. An agent first handshakes
+// when it is up, then reports its workload deployed and, once it is able to receive triggers, ready
+const (
+	AgentPhaseUp               = "agent_up"
+	AgentPhaseWorkloadDeployed = "workload_deployed"
+	AgentPhaseWorkloadReady    = "workload_ready"
+)
+
 type HandshakeRequest struct {
 	ID        *string   `json:"id"`
 	StartTime time.Time `json:"start_time"`
 	Message   *string   `json:"message,omitempty"`
+	// Lifecycle phase reached by the agent; a handshake without a phase, as sent by agents predating
+	// phases, indicates that the agent is up
+	Phase *string `json:"phase,omitempty"`
+}
+
+// Returns the lifecycle phase reported by the handshake
+func (r *HandshakeRequest) ReportedPhase() string {
+	if r.Phase == nil || *r.Phase == "" {
+		return AgentPhaseUp
+	}
+
+	return *r.Phase
+}
+
+// Orders the lifecycle phases, returning -1 for unknown phases
+func agentPhaseOrder(phase string) int {
+	return slices.Index([]string{AgentPhaseUp, AgentPhaseWorkloadDeployed, AgentPhaseWorkloadReady}, phase)
 }
 
 type HandshakeResponse struct {
@@ -197,31 +473,90 @@ type MachineMetadata struct {
 	NodeNatsPort *int    `json:"node_nats_port"`
 	Message      *string `json:"message"`
 
+	// Token with which the agent authenticates to the node's internal NATS server, which permits it
+	// only the VM's own subjects
+	NodeNatsToken *string `json:"node_nats_token,omitempty"`
+
+	// Token given to the agent's workloads with which they publish metric samples to the internal
+	// NATS server, which permits them nothing else
+	WorkloadNatsToken *string `json:"workload_nats_token,omitempty"`
+
+	// Random bytes read from the host's entropy source, with which the agent seeds the guest's
+	// entropy pool at boot
+	EntropySeed []byte `json:"entropy_seed,omitempty"`
+
+	// Optional directory from which the agent loads execution provider plugins at startup
+	PluginPath *string `json:"plugin_path,omitempty"`
+
+	// Indicates that the node exports traces, so that the agent forwards the spans it records
+	TracesEnabled bool `json:"traces_enabled,omitempty"`
+
+	// Public key with which agent binaries pushed by the node must be signed; when not set, the
+	// agent does not accept updates
+	AgentUpdatePublicKey *string `json:"agent_update_public_key,omitempty"`
+
+	// Interval at which the agent publishes heartbeats to the node; when not set, the agent does
+	// not publish heartbeats
+	HeartbeatIntervalMillisecond *int `json:"heartbeat_interval_ms,omitempty"`
+
+	// Grace period given to a stopped workload to exit before it is killed, unless overridden by its
+	// deploy request; when not set, the agent does not wait for the workload to exit
+	StopGracePeriodMillisecond *int `json:"stop_grace_period_ms,omitempty"`
+
+	// Maximum number of workloads the agent hosts at once; when not set, the agent hosts a single
+	// workload
+	MaxWorkloads *int `json:"max_workloads,omitempty"`
+
+	// Key with which the node seals the environments of the workloads it deploys to the agent, so
+	// that they never cross the internal NATS server in plaintext
+	EnvironmentKey []byte `json:"environment_key,omitempty"`
+
+	// Indicates that the agent must reject deploy requests carrying a plaintext environment
+	RequireSealedEnvironment bool `json:"require_sealed_environment,omitempty"`
+
+	// Number of log lines per second the agent forwards to the node, and the number it may forward
+	// in a burst above that rate; when not set, the agent forwards every log line
+	LogRateLimit *int `json:"log_rate_limit,omitempty"`
+	LogBurst     *int `json:"log_burst,omitempty"`
+
 	Errors []error `json:"errors,omitempty"`
 }
 
 func (m *MachineMetadata) Validate() bool {
-	var err error
+	m.Errors = make([]error, 0)
 
-	if m.VmID == nil {
-		err = errors.Join(err, errors.New("vm id is required"))
+	if m.VmID == nil || *m.VmID == "" {
+		m.Errors = append(m.Errors, errors.New("vm id is required"))
 	}
 
-	if m.NodeNatsHost == nil {
-		err = errors.Join(err, errors.New("node NATS host is required"))
+	if m.NodeNatsHost == nil || *m.NodeNatsHost == "" {
+		m.Errors = append(m.Errors, errors.New("node NATS host is required"))
 	}
 
 	if m.NodeNatsPort == nil {
-		err = errors.Join(err, errors.New("node NATS port is required"))
+		m.Errors = append(m.Errors, errors.New("node NATS port is required"))
+	} else if *m.NodeNatsPort < 1 || *m.NodeNatsPort > 65535 {
+		m.Errors = append(m.Errors, fmt.Errorf("node NATS port must be between 1 and 65535, got %d", *m.NodeNatsPort))
 	}
 
-	return err == nil
+	return len(m.Errors) == 0
+}
+
+// Returns the prefix of the inboxes on which the agent running in the given VM receives replies,
+// which only that agent may subscribe to on the internal NATS server
+func AgentInboxPrefix(vmID string) string {
+	return fmt.Sprintf("_INBOX_%s", vmID)
 }
 
 type LogEntry struct {
 	Source string   `json:"source,omitempty"`
 	Level  LogLevel `json:"level,omitempty"`
 	Text   string   `json:"text,omitempty"`
+	// Time at which the line was logged, preserved as entries are batched for dispatch to the node
+	Timestamp time.Time `json:"timestamp"`
+	// Number of log lines the agent has dropped since it last reported any, set on the entries
+	// with which it periodically reports the lines its log rate limit has dropped
+	Dropped uint64 `json:"dropped,omitempty"`
 }
 
 type LogLevel int32
@@ -78,6 +78,20 @@ type RunOptions struct {
 	Essential         bool
 	DevMode           bool
 	TriggerSubjects   []string
+	AllowedTriggers   []string
+	TriggerTimeout    time.Duration
+	ArtifactBucket    string
+	Uid               int
+	Gid               int
+	WorkingDirectory  string
+	VcpuCount         int
+	MemSizeMib        int
+	MemoryLimitMib    int
+	TraceSamplingRate float64
+	Dependencies      []string
+	SignatureTTL      time.Duration
+	TargetVM          string
+	CaptureLogs       bool
 }
 
 type StopOptions struct {
@@ -87,6 +101,13 @@ type StopOptions struct {
 	ClaimsIssuerFile string
 }
 
+type ManifestOptions struct {
+	TargetNode    string
+	ManifestFile  string
+	SignatureTTL  time.Duration
+	ImportTimeout time.Duration
+}
+
 type WatchOptions struct {
 	NodeId       string
 	WorkloadId   string
@@ -115,6 +136,7 @@ type NodeOptions struct {
 	OtelTracesExporter  string `json:"-"`
 
 	PreflightInit string `json:"-"`
+	SafeMode      bool   `json:"-"`
 
 	Errors []error `json:"errors,omitempty"`
 }
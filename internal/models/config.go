@@ -1,66 +1,196 @@
 package models
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/netip"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nkeys"
 	"github.com/splode/fname"
+	controlapi "github.com/synadia-io/nex/control-api"
 	agentapi "github.com/synadia-io/nex/internal/agent-api"
 )
 
 const (
-	DefaultCNINetworkName                   = "fcnet"
-	DefaultCNIInterfaceName                 = "veth0"
-	DefaultCNISubnet                        = "192.168.127.0/24"
-	DefaultInternalNodeHost                 = "192.168.127.1"
-	DefaultInternalNodePort                 = 9222
-	DefaultNodeMemSizeMib                   = 256
-	DefaultNodeVcpuCount                    = 1
-	DefaultOtelExporterUrl                  = "127.0.0.1:14532"
-	DefaultAgentHandshakeTimeoutMillisecond = 5000
+	DefaultCNINetworkName                      = "fcnet"
+	DefaultCNIInterfaceName                    = "veth0"
+	DefaultCNISubnet                           = "192.168.127.0/24"
+	DefaultInternalNodeHost                    = "192.168.127.1"
+	DefaultNoSandboxInternalNodeBindHost       = "127.0.0.1"
+	DefaultInternalNodePort                    = 9222
+	DefaultNodeMemSizeMib                      = 256
+	DefaultNodeVcpuCount                       = 1
+	DefaultOtelExporterUrl                     = "127.0.0.1:14532"
+	DefaultAgentHandshakeTimeoutMillisecond    = 5000
+	DefaultAgentDeployTimeoutMillisecond       = 5000
+	DefaultAgentUndeployTimeoutMillisecond     = 2000
+	DefaultStopGracePeriodMillisecond          = 3000
+	DefaultShutdownTimeoutMillisecond          = 30000
+	DefaultPrewarmIdleTimeoutMillisecond       = 300000
+	DefaultEntropySource                       = "/dev/urandom"
+	DefaultEventHistorySize                    = 256
+	DefaultPoolFillLogIntervalMillisecond      = 30000
+	DefaultPoolRefillBackoffMillisecond        = 1000
+	DefaultPoolCreateBackoffMaxMillisecond     = 30000
+	DefaultPoolCreateMaxAttempts               = 10
+	DefaultStoreProbeIntervalMillisecond       = 15000
+	DefaultDependencyTimeoutMillisecond        = 30000
+	DefaultSignedRequestMaxTTLMillisecond      = 300000
+	DefaultAgentHeartbeatIntervalMillisecond   = 5000
+	DefaultAgentHeartbeatMissedThreshold       = 3
+	DefaultAgentHandshakeFailureThreshold      = 5
+	DefaultEventTokenMaxTTLMillisecond         = 3600000
+	DefaultIdleAgentReapIntervalMillisecond    = 30000
+	DefaultHTTPGatewayTimeoutMillisecond       = 10000
+	DefaultLivenessProbeTimeoutMillisecond     = 2000
+	DefaultLivenessProbeFailureThreshold       = 3
+	DefaultInternalNatsStoreDirName            = "pnats"
+	DefaultInternalNatsStoreMinFreeMib         = 64
+	DefaultMachinePoolBurstCooldownMillisecond = 60000
+	DefaultLameDuckDrainTimeoutMillisecond     = 300000
+	DefaultLogCaptureMaxBytes                  = 10 * 1024 * 1024
+	DefaultLogCaptureBucketMaxBytes            = 256 * 1024 * 1024
+	DefaultLogCaptureTTLMillisecond            = 86400000
+
+	// Policies for deploying a workload named the same as a workload already running in its namespace
+	DuplicateWorkloadPolicyReject  = "reject"
+	DuplicateWorkloadPolicyReplace = "replace"
+	DuplicateWorkloadPolicyAllow   = "allow"
+
+	// Upper bound on the number of entropy bytes injected into each VM at boot
+	MaxEntropySeedBytes = 4096
+
+	// Upper bound on the max payload of the internal NATS server, as imposed by NATS
+	MaxInternalNatsPayloadBytes = 64 * 1024 * 1024
+
+	// Replaces the values of sensitive workload environment variables in events and status output
+	redactedValue = "[REDACTED]"
 )
 
 var (
-	// docker/OCI needs to be explicitly enabled in node configuration
-	DefaultWorkloadTypes = []string{"elf", "v8", "wasm"}
+	// docker/OCI needs to be explicitly enabled in node configuration; types the agent cannot
+	// run on this platform, e.g. v8 other than on linux/amd64, are not enabled by default
+	DefaultWorkloadTypes = agentapi.SupportedWorkloadTypes()
 
 	DefaultBinPath = append([]string{"/usr/local/bin"}, filepath.SplitList(os.Getenv("PATH"))...)
 
 	// check the default cni bin path first, otherwise look in the rest of the PATH
 	DefaultCNIBinPath = append([]string{"/opt/cni/bin"}, filepath.SplitList(os.Getenv("PATH"))...)
+
+	validBucketName   = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	validArtifactHash = regexp.MustCompile(`^[a-fA-F0-9]{64}$`)
 )
 
 // Node configuration is used to configure the node process as well
 // as the virtual machines it produces
 type NodeConfiguration struct {
-	AgentHandshakeTimeoutMillisecond int                 `json:"agent_handshake_timeout_ms,omitempty"`
-	BinPath                          []string            `json:"bin_path"`
-	CNI                              CNIDefinition       `json:"cni"`
-	DefaultResourceDir               string              `json:"default_resource_dir"`
-	ForceDepInstall                  bool                `json:"-"`
-	InternalNodeHost                 *string             `json:"internal_node_host,omitempty"`
-	InternalNodePort                 *int                `json:"internal_node_port"`
-	KernelFilepath                   string              `json:"kernel_filepath"`
-	MachinePoolSize                  int                 `json:"machine_pool_size"`
-	MachineTemplate                  MachineTemplate     `json:"machine_template"`
-	NoSandbox                        bool                `json:"no_sandbox,omitempty"`
-	OtlpExporterUrl                  string              `json:"otlp_exporter_url,omitempty"`
-	OtelMetrics                      bool                `json:"otel_metrics"`
-	OtelMetricsPort                  int                 `json:"otel_metrics_port"`
-	OtelMetricsExporter              string              `json:"otel_metrics_exporter"`
-	OtelTraces                       bool                `json:"otel_traces"`
-	OtelTracesExporter               string              `json:"otel_traces_exporter"`
-	PreserveNetwork                  bool                `json:"preserve_network,omitempty"`
-	RateLimiters                     *Limiters           `json:"rate_limiters,omitempty"`
-	RootFsFilepath                   string              `json:"rootfs_filepath"`
-	Tags                             map[string]string   `json:"tags,omitempty"`
-	ValidIssuers                     []string            `json:"valid_issuers,omitempty"`
-	WorkloadTypes                    []string            `json:"workload_types,omitempty"`
-	HostServicesConfiguration        *HostServicesConfig `json:"host_services,omitempty"`
+	AgentDeployTimeoutMillisecond       int                              `json:"agent_deploy_timeout_ms,omitempty"`
+	AgentHandshakeFailureThreshold      int                              `json:"agent_handshake_failure_threshold,omitempty"`
+	AgentHandshakeTimeoutMillisecond    int                              `json:"agent_handshake_timeout_ms,omitempty"`
+	AgentHeartbeatIntervalMillisecond   int                              `json:"agent_heartbeat_interval_ms,omitempty"`
+	AgentHeartbeatMissedThreshold       int                              `json:"agent_heartbeat_missed_threshold,omitempty"`
+	AgentLogBurst                       int                              `json:"agent_log_burst,omitempty"`
+	AgentLogRateLimit                   int                              `json:"agent_log_rate_limit,omitempty"`
+	AgentPluginPath                     string                           `json:"agent_plugin_path,omitempty"`
+	AgentUndeployTimeoutMillisecond     int                              `json:"agent_undeploy_timeout_ms,omitempty"`
+	AgentUpdatePublicKey                string                           `json:"agent_update_public_key,omitempty"`
+	AllowAgentUpdates                   bool                             `json:"allow_agent_updates,omitempty"`
+	AllowGitSources                     bool                             `json:"allow_git_sources,omitempty"`
+	AllowVMPinning                      bool                             `json:"allow_vm_pinning,omitempty"`
+	ArtifactBlockDevice                 bool                             `json:"artifact_block_device,omitempty"`
+	ArtifactBuckets                     []string                         `json:"artifact_buckets,omitempty"`
+	ArtifactDecryptionKeys              map[string]ArtifactDecryptionKey `json:"artifact_decryption_keys,omitempty"`
+	BinPath                             []string                         `json:"bin_path"`
+	CNI                                 CNIDefinition                    `json:"cni"`
+	DefaultResourceDir                  string                           `json:"default_resource_dir"`
+	DefaultWorkloadEnvironment          map[string]string                `json:"default_workload_environment,omitempty"`
+	DependencyTimeoutMillisecond        int                              `json:"dependency_timeout_ms,omitempty"`
+	DuplicateWorkloadPolicy             string                           `json:"duplicate_workload_policy,omitempty"`
+	EntropyDevice                       bool                             `json:"entropy_device,omitempty"`
+	EntropySeedBytes                    int                              `json:"entropy_seed_bytes,omitempty"`
+	EntropySource                       string                           `json:"entropy_source,omitempty"`
+	EventHistorySize                    int                              `json:"event_history_size"`
+	EventTokenAccount                   string                           `json:"event_token_account,omitempty"`
+	EventTokenMaxTTLMillisecond         int                              `json:"event_token_max_ttl_ms,omitempty"`
+	EventTokenSigningSeed               string                           `json:"event_token_signing_seed,omitempty"`
+	ForceDepInstall                     bool                             `json:"-"`
+	IdleAgentReapAfterMillisecond       int                              `json:"idle_agent_reap_after_ms,omitempty"`
+	IdleAgentReapIntervalMillisecond    int                              `json:"idle_agent_reap_interval_ms,omitempty"`
+	InternalNatsMaxPayloadBytes         int                              `json:"internal_nats_max_payload_bytes,omitempty"`
+	InternalNatsMaxPendingBytes         int                              `json:"internal_nats_max_pending_bytes,omitempty"`
+	InternalNatsFileStorage             bool                             `json:"internal_nats_file_storage,omitempty"`
+	InternalNatsStoreDir                string                           `json:"internal_nats_store_dir,omitempty"`
+	InternalNatsStoreMinFreeMib         int                              `json:"internal_nats_store_min_free_mib,omitempty"`
+	InternalNodeBindHost                *string                          `json:"internal_node_bind_host,omitempty"`
+	InternalNodeHost                    *string                          `json:"internal_node_host,omitempty"`
+	InternalNodePort                    *int                             `json:"internal_node_port"`
+	KernelFilepath                      string                           `json:"kernel_filepath"`
+	LameDuckDrainTimeoutMillisecond     int                              `json:"lame_duck_drain_timeout_ms,omitempty"`
+	LivenessProbeFailureThreshold       int                              `json:"liveness_probe_failure_threshold,omitempty"`
+	LivenessProbeIntervalMillisecond    int                              `json:"liveness_probe_interval_ms,omitempty"`
+	LivenessProbeTimeoutMillisecond     int                              `json:"liveness_probe_timeout_ms,omitempty"`
+	LogCaptureBucketMaxBytes            int64                            `json:"log_capture_bucket_max_bytes,omitempty"`
+	LogCaptureMaxBytes                  int64                            `json:"log_capture_max_bytes,omitempty"`
+	LogCaptureTTLMillisecond            int                              `json:"log_capture_ttl_ms,omitempty"`
+	MachinePoolBurstCooldownMillisecond int                              `json:"machine_pool_burst_cooldown_ms,omitempty"`
+	MachinePoolLowWatermark             int                              `json:"machine_pool_low_watermark,omitempty"`
+	MachinePoolMax                      int                              `json:"machine_pool_max,omitempty"`
+	MachinePoolMin                      int                              `json:"machine_pool_min,omitempty"`
+	MachinePoolSize                     int                              `json:"machine_pool_size"`
+	MachineMemoryQuotaMib               int                              `json:"machine_memory_quota_mib,omitempty"`
+	MachineTemplate                     MachineTemplate                  `json:"machine_template"`
+	MachineVcpuQuota                    int                              `json:"machine_vcpu_quota,omitempty"`
+	MaxWorkloads                        int                              `json:"max_workloads,omitempty"`
+	NamespaceQuotas                     map[string]int                   `json:"namespace_quotas,omitempty"`
+	NatsConnectionNamePrefix            string                           `json:"nats_connection_name_prefix,omitempty"`
+	NetworkStatsIntervalMillisecond     int                              `json:"network_stats_interval_ms,omitempty"`
+	NoSandbox                           bool                             `json:"no_sandbox,omitempty"`
+	NoSandboxFallback                   bool                             `json:"no_sandbox_fallback,omitempty"`
+	OtlpExporterUrl                     string                           `json:"otlp_exporter_url,omitempty"`
+	OtelMetrics                         bool                             `json:"otel_metrics"`
+	OtelMetricsPort                     int                              `json:"otel_metrics_port"`
+	OtelMetricsExporter                 string                           `json:"otel_metrics_exporter"`
+	OtelTraces                          bool                             `json:"otel_traces"`
+	OtelTracesExporter                  string                           `json:"otel_traces_exporter"`
+	OtelTraceSamplingRate               *float64                         `json:"otel_trace_sampling_rate,omitempty"`
+	PoolCreateBackoffMaxMillisecond     int                              `json:"pool_create_backoff_max_ms,omitempty"`
+	PoolCreateIntervalMillisecond       int                              `json:"pool_create_interval_ms"`
+	PoolCreateMaxAttempts               int                              `json:"pool_create_max_attempts,omitempty"`
+	PoolFillLogIntervalMillisecond      int                              `json:"pool_fill_log_interval_ms"`
+	PoolRefillBackoffMillisecond        int                              `json:"pool_refill_backoff_ms"`
+	PrepullArtifacts                    []PrepullArtifact                `json:"prepull_artifacts,omitempty"`
+	PrewarmIdleTimeoutMillisecond       int                              `json:"prewarm_idle_timeout_ms,omitempty"`
+	PreserveNetwork                     bool                             `json:"preserve_network,omitempty"`
+	RateLimiters                        *Limiters                        `json:"rate_limiters,omitempty"`
+	RequireEnvironmentEncryption        bool                             `json:"require_environment_encryption,omitempty"`
+	RequireSignedRequests               bool                             `json:"require_signed_requests,omitempty"`
+	ReservedHostMemoryMib               int                              `json:"reserved_host_memory_mib,omitempty"`
+	ReservedHostVcpu                    int                              `json:"reserved_host_vcpu,omitempty"`
+	RootFsFilepath                      string                           `json:"rootfs_filepath"`
+	SafeMode                            bool                             `json:"safe_mode,omitempty"`
+	SensitiveWorkloadEnvironment        []string                         `json:"sensitive_workload_environment,omitempty"`
+	ShutdownTimeoutMillisecond          int                              `json:"shutdown_timeout_ms,omitempty"`
+	SignedRequestMaxTTLMillisecond      int                              `json:"signed_request_max_ttl_ms,omitempty"`
+	StopGracePeriodMillisecond          int                              `json:"stop_grace_period_ms,omitempty"`
+	StoreProbeIntervalMillisecond       int                              `json:"store_probe_interval_ms"`
+	StoreProbeLameDuck                  bool                             `json:"store_probe_lame_duck,omitempty"`
+	Tags                                map[string]string                `json:"tags,omitempty"`
+	TriggerMaxPayloadBytes              int                              `json:"trigger_max_payload_bytes,omitempty"`
+	TriggerPayloadSpill                 bool                             `json:"trigger_payload_spill,omitempty"`
+	ValidIssuers                        []string                         `json:"valid_issuers,omitempty"`
+	WorkloadTypes                       []string                         `json:"workload_types,omitempty"`
+	HostServicesConfiguration           *HostServicesConfig              `json:"host_services,omitempty"`
 
 	// Public NATS server options; when non-nil, a public "userland" NATS server is started during node init
 	PublicNATSServer *server.Options `json:"public_nats_server,omitempty"`
@@ -69,10 +199,57 @@ type NodeConfiguration struct {
 }
 
 type HostServicesConfig struct {
-	NatsUrl      string                   `json:"nats_url"`
-	NatsUserJwt  string                   `json:"nats_user_jwt"`
-	NatsUserSeed string                   `json:"nats_user_seed"`
-	Services     map[string]ServiceConfig `json:"services"`
+	// Number of connections to the host services NATS server across which host service
+	// requests are round-robined; only applies when a NATS URL is given
+	ConnectionPoolSize int                      `json:"connection_pool_size,omitempty"`
+	NatsUrl            string                   `json:"nats_url"`
+	NatsUserJwt        string                   `json:"nats_user_jwt"`
+	NatsUserSeed       string                   `json:"nats_user_seed"`
+	Services           map[string]ServiceConfig `json:"services"`
+}
+
+// Configuration of the http host service, given as its service configuration
+type HTTPServiceConfig struct {
+	// When given, the node serves HTTP requests by triggering the workloads to which their paths are
+	// routed, acting as a simple function gateway
+	Gateway *HTTPGatewayConfig `json:"gateway,omitempty"`
+}
+
+type HTTPGatewayConfig struct {
+	// Address on which the gateway listens, e.g. 0.0.0.0:8080
+	Listen string `json:"listen"`
+	// Maximum time to wait for a triggered workload to respond
+	TimeoutMillisecond int                `json:"timeout_ms,omitempty"`
+	Routes             []HTTPGatewayRoute `json:"routes"`
+}
+
+// Routes requests whose path matches the given pattern to the workloads registered on the given
+// trigger subject. Patterns ending in a slash or /* match every path beneath them, e.g. /a/*;
+// other patterns match the path exactly. Requests are routed by the most specific matching pattern
+type HTTPGatewayRoute struct {
+	Path           string `json:"path"`
+	TriggerSubject string `json:"trigger_subject"`
+}
+
+// An artifact downloaded into the internal cache when the node starts, so that the first
+// deployment of it after startup does not wait on the download
+type PrepullArtifact struct {
+	// Object store location of the artifact, e.g. nats://bucket/key
+	Location string `json:"location"`
+	// Optional SHA-256 hash the downloaded artifact must match
+	Hash     string  `json:"hash,omitempty"`
+	JsDomain *string `json:"js_domain,omitempty"`
+}
+
+// A key with which artifacts stored encrypted at rest are decrypted by the agent just before they
+// are run, named by the artifact's key metadata
+type ArtifactDecryptionKey struct {
+	// Base64-encoded 256-bit key
+	Key string `json:"key"`
+	// Namespaces whose workloads may use the key; any namespace when empty
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Issuers whose workloads may use the key; any issuer when empty
+	Issuers []string `json:"issuers,omitempty"`
 }
 
 type ServiceConfig struct {
@@ -87,6 +264,237 @@ func (c *NodeConfiguration) Validate() bool {
 		c.Errors = append(c.Errors, errors.New("machine pool size must be >= 1"))
 	}
 
+	if c.MachinePoolMin < 0 || c.MachinePoolMax < 0 {
+		c.Errors = append(c.Errors, errors.New("machine pool bounds must be >= 0"))
+	} else if poolMin, poolMax := c.ResolveMachinePoolBounds(); c.MachinePoolSize < poolMin || c.MachinePoolSize > poolMax {
+		c.Errors = append(c.Errors, fmt.Errorf("machine pool size must be between the pool bounds of %d and %d", poolMin, poolMax))
+	} else if c.MachinePoolLowWatermark > 0 && poolMax <= c.MachinePoolSize {
+		c.Errors = append(c.Errors, errors.New("machine pool low watermark requires a machine pool max above the pool size to grow into"))
+	}
+
+	if c.MachinePoolLowWatermark < 0 || c.MachinePoolBurstCooldownMillisecond < 0 {
+		c.Errors = append(c.Errors, errors.New("machine pool low watermark and burst cooldown must be >= 0"))
+	}
+
+	if c.StopGracePeriodMillisecond < 0 {
+		c.Errors = append(c.Errors, errors.New("stop grace period must be >= 0"))
+	}
+
+	if c.AgentLogRateLimit < 0 || c.AgentLogBurst < 0 {
+		c.Errors = append(c.Errors, errors.New("agent log rate limit and burst must be >= 0"))
+	}
+
+	if c.LogCaptureMaxBytes < 0 || c.LogCaptureBucketMaxBytes < 0 || c.LogCaptureTTLMillisecond < 0 {
+		c.Errors = append(c.Errors, errors.New("log capture limits must be >= 0"))
+	}
+
+	if c.ShutdownTimeoutMillisecond < 0 {
+		c.Errors = append(c.Errors, errors.New("shutdown timeout must be >= 0"))
+	}
+
+	if c.NetworkStatsIntervalMillisecond < 0 {
+		c.Errors = append(c.Errors, errors.New("network stats interval must be >= 0"))
+	}
+
+	if c.PoolCreateIntervalMillisecond < 0 {
+		c.Errors = append(c.Errors, errors.New("pool create interval must be >= 0"))
+	}
+
+	if c.PoolFillLogIntervalMillisecond < 0 {
+		c.Errors = append(c.Errors, errors.New("pool fill log interval must be >= 0"))
+	}
+
+	if c.PoolCreateBackoffMaxMillisecond < 0 || c.PoolCreateMaxAttempts < 0 {
+		c.Errors = append(c.Errors, errors.New("pool create backoff and max attempts must be >= 0"))
+	}
+
+	if c.PoolRefillBackoffMillisecond < 0 {
+		c.Errors = append(c.Errors, errors.New("pool refill backoff must be >= 0"))
+	}
+
+	if c.MachineVcpuQuota < 0 || c.MachineMemoryQuotaMib < 0 {
+		c.Errors = append(c.Errors, errors.New("machine quotas must be >= 0"))
+	} else if (c.MachineVcpuQuota > 0 || c.MachineMemoryQuotaMib > 0) && c.NoSandbox {
+		c.Errors = append(c.Errors, errors.New("machine quotas require a sandbox"))
+	} else if c.MachineVcpuQuota > 0 && c.MachineTemplate.VcpuCount != nil && c.MachineVcpuQuota < *c.MachineTemplate.VcpuCount {
+		c.Errors = append(c.Errors, errors.New("machine vcpu quota must admit at least one machine"))
+	} else if c.MachineMemoryQuotaMib > 0 && c.MachineTemplate.MemSizeMib != nil && c.MachineMemoryQuotaMib < *c.MachineTemplate.MemSizeMib {
+		c.Errors = append(c.Errors, errors.New("machine memory quota must admit at least one machine"))
+	}
+
+	if c.AllowAgentUpdates {
+		if c.AgentUpdatePublicKey == "" {
+			c.Errors = append(c.Errors, errors.New("agent updates require an agent update public key"))
+		} else if _, err := nkeys.FromPublicKey(c.AgentUpdatePublicKey); err != nil {
+			c.Errors = append(c.Errors, fmt.Errorf("invalid agent update public key: %s", err))
+		}
+	}
+
+	if unsupported := c.unsupportedWorkloadTypes(); len(unsupported) > 0 {
+		c.Errors = append(c.Errors, fmt.Errorf("workload types not supported on %s/%s: %s", runtime.GOOS, runtime.GOARCH, strings.Join(unsupported, ", ")))
+	}
+
+	if c.MaxWorkloads < 0 {
+		c.Errors = append(c.Errors, errors.New("max workloads must be >= 0"))
+	}
+
+	for namespace, quota := range c.NamespaceQuotas {
+		if quota < 0 {
+			c.Errors = append(c.Errors, fmt.Errorf("namespace quota for %s must be >= 0", namespace))
+		}
+	}
+
+	if c.StoreProbeIntervalMillisecond < 0 {
+		c.Errors = append(c.Errors, errors.New("store probe interval must be >= 0"))
+	}
+
+	if c.PrewarmIdleTimeoutMillisecond < 0 {
+		c.Errors = append(c.Errors, errors.New("prewarm idle timeout must be >= 0"))
+	}
+
+	if c.IdleAgentReapAfterMillisecond < 0 || c.IdleAgentReapIntervalMillisecond < 0 {
+		c.Errors = append(c.Errors, errors.New("idle agent reap threshold and interval must be >= 0"))
+	}
+
+	if c.HostServicesConfiguration != nil && c.HostServicesConfiguration.ConnectionPoolSize < 0 {
+		c.Errors = append(c.Errors, errors.New("host services connection pool size must be >= 0"))
+	}
+
+	if gateway, err := c.ResolveHTTPGateway(); err != nil {
+		c.Errors = append(c.Errors, fmt.Errorf("invalid http host service configuration: %s", err))
+	} else if gateway != nil {
+		c.Errors = append(c.Errors, gateway.validate()...)
+	}
+
+	if c.SignedRequestMaxTTLMillisecond < 0 {
+		c.Errors = append(c.Errors, errors.New("signed request max ttl must be >= 0"))
+	}
+
+	if c.DependencyTimeoutMillisecond < 0 {
+		c.Errors = append(c.Errors, errors.New("dependency timeout must be >= 0"))
+	}
+
+	if c.EventTokenSigningSeed != "" {
+		if kp, err := nkeys.FromSeed([]byte(c.EventTokenSigningSeed)); err != nil {
+			c.Errors = append(c.Errors, fmt.Errorf("invalid event token signing seed: %s", err))
+		} else if pub, _ := kp.PublicKey(); !nkeys.IsValidPublicAccountKey(pub) {
+			c.Errors = append(c.Errors, errors.New("event token signing seed must be an account seed"))
+		}
+	}
+
+	if c.EventTokenAccount != "" && !nkeys.IsValidPublicAccountKey(c.EventTokenAccount) {
+		c.Errors = append(c.Errors, errors.New("event token account must be a public account key"))
+	}
+
+	if c.EventTokenMaxTTLMillisecond < 0 {
+		c.Errors = append(c.Errors, errors.New("event token max ttl must be >= 0"))
+	}
+
+	if c.ReservedHostVcpu < 0 || c.ReservedHostMemoryMib < 0 {
+		c.Errors = append(c.Errors, errors.New("reserved host resources must be >= 0"))
+	}
+
+	if c.AgentHandshakeFailureThreshold < 0 {
+		c.Errors = append(c.Errors, errors.New("agent handshake failure threshold must be >= 0"))
+	}
+
+	if c.AgentDeployTimeoutMillisecond < 0 || c.AgentUndeployTimeoutMillisecond < 0 {
+		c.Errors = append(c.Errors, errors.New("agent deploy and undeploy timeouts must be >= 0"))
+	}
+
+	if c.AgentHeartbeatIntervalMillisecond < 0 {
+		c.Errors = append(c.Errors, errors.New("agent heartbeat interval must be >= 0"))
+	}
+
+	if c.AgentHeartbeatMissedThreshold < 0 {
+		c.Errors = append(c.Errors, errors.New("agent heartbeat missed threshold must be >= 0"))
+	}
+
+	if c.LivenessProbeIntervalMillisecond < 0 || c.LivenessProbeTimeoutMillisecond < 0 {
+		c.Errors = append(c.Errors, errors.New("liveness probe interval and timeout must be >= 0"))
+	}
+
+	if c.LivenessProbeFailureThreshold < 0 {
+		c.Errors = append(c.Errors, errors.New("liveness probe failure threshold must be >= 0"))
+	}
+
+	if c.EventHistorySize < 0 {
+		c.Errors = append(c.Errors, errors.New("event history size must be >= 0"))
+	}
+
+	if c.InternalNatsStoreDir != "" && !filepath.IsAbs(c.InternalNatsStoreDir) {
+		c.Errors = append(c.Errors, errors.New("internal NATS store dir must be an absolute path"))
+	}
+
+	if c.InternalNatsStoreMinFreeMib < 0 {
+		c.Errors = append(c.Errors, errors.New("internal NATS store min free space must be >= 0"))
+	}
+
+	if c.InternalNatsMaxPayloadBytes < 0 || c.InternalNatsMaxPendingBytes < 0 {
+		c.Errors = append(c.Errors, errors.New("internal NATS max payload and max pending bytes must be >= 0"))
+	} else if c.InternalNatsMaxPayloadBytes > MaxInternalNatsPayloadBytes {
+		c.Errors = append(c.Errors, fmt.Errorf("internal NATS max payload bytes must be <= %d", MaxInternalNatsPayloadBytes))
+	} else if maxPayload, maxPending := c.ResolveInternalNatsLimits(); maxPayload > maxPending {
+		c.Errors = append(c.Errors, fmt.Errorf("internal NATS max payload (%d bytes) must not exceed max pending (%d bytes)", maxPayload, maxPending))
+	} else if c.TriggerMaxPayloadBytes > maxPayload {
+		c.Errors = append(c.Errors, fmt.Errorf("trigger max payload bytes must not exceed the internal NATS max payload of %d bytes", maxPayload))
+	}
+
+	if c.OtelTraceSamplingRate != nil && (*c.OtelTraceSamplingRate < 0 || *c.OtelTraceSamplingRate > 1) {
+		c.Errors = append(c.Errors, errors.New("trace sampling rate must be between 0.0 and 1.0"))
+	}
+
+	if c.TriggerMaxPayloadBytes < 0 {
+		c.Errors = append(c.Errors, errors.New("trigger max payload bytes must be >= 0"))
+	}
+
+	for key := range c.DefaultWorkloadEnvironment {
+		if key == "" || strings.Contains(key, "=") {
+			c.Errors = append(c.Errors, fmt.Errorf("invalid default workload environment variable name: %q", key))
+		}
+	}
+
+	for _, key := range c.SensitiveWorkloadEnvironment {
+		if _, ok := c.DefaultWorkloadEnvironment[key]; !ok {
+			c.Errors = append(c.Errors, fmt.Errorf("sensitive workload environment variable %s has no default", key))
+		}
+	}
+
+	if c.EntropySeedBytes < 0 || c.EntropySeedBytes > MaxEntropySeedBytes {
+		c.Errors = append(c.Errors, fmt.Errorf("entropy seed bytes must be between 0 and %d", MaxEntropySeedBytes))
+	} else if c.EntropySeedBytes > 0 && !c.NoSandbox {
+		if _, err := os.Stat(c.ResolveEntropySource()); err != nil {
+			c.Errors = append(c.Errors, fmt.Errorf("invalid entropy source: %s", err))
+		}
+	}
+
+	for _, artifact := range c.PrepullArtifacts {
+		location, err := url.Parse(artifact.Location)
+		if err != nil || !strings.EqualFold(location.Scheme, "nats") || location.Host == "" {
+			c.Errors = append(c.Errors, fmt.Errorf("invalid pre-pull artifact location: %s", artifact.Location))
+		}
+
+		if artifact.Hash != "" && !validArtifactHash.MatchString(artifact.Hash) {
+			c.Errors = append(c.Errors, fmt.Errorf("invalid pre-pull artifact hash: %s", artifact.Hash))
+		}
+	}
+
+	for _, bucket := range c.ArtifactBuckets {
+		if !validBucketName.MatchString(bucket) {
+			c.Errors = append(c.Errors, fmt.Errorf("invalid artifact bucket name: %s", bucket))
+		}
+	}
+
+	if !slices.Contains([]string{DuplicateWorkloadPolicyReject, DuplicateWorkloadPolicyReplace, DuplicateWorkloadPolicyAllow}, c.ResolveDuplicateWorkloadPolicy()) {
+		c.Errors = append(c.Errors, fmt.Errorf("invalid duplicate workload policy: %s", c.DuplicateWorkloadPolicy))
+	}
+
+	for name, key := range c.ArtifactDecryptionKeys {
+		if _, err := key.Decode(); err != nil {
+			c.Errors = append(c.Errors, fmt.Errorf("invalid artifact decryption key %s: %s", name, err))
+		}
+	}
+
 	if !c.NoSandbox {
 		if _, err := os.Stat(c.KernelFilepath); errors.Is(err, os.ErrNotExist) {
 			c.Errors = append(c.Errors, err)
@@ -96,25 +504,510 @@ func (c *NodeConfiguration) Validate() bool {
 			c.Errors = append(c.Errors, err)
 		}
 
-		cniSubnet, err := netip.ParsePrefix(*c.CNI.Subnet)
-		if err != nil {
-			c.Errors = append(c.Errors, err)
+		cniSubnet, subnetErr := netip.ParsePrefix(*c.CNI.Subnet)
+		if subnetErr != nil {
+			c.Errors = append(c.Errors, subnetErr)
 		}
 
-		internalNodeHost, err := netip.ParseAddr(*c.InternalNodeHost)
-		if err != nil {
-			c.Errors = append(c.Errors, err)
+		internalNodeHost, hostErr := netip.ParseAddr(*c.InternalNodeHost)
+		if hostErr != nil {
+			c.Errors = append(c.Errors, hostErr)
 		}
 
-		hostInSubnet := cniSubnet.Contains(internalNodeHost)
-		if !hostInSubnet {
-			c.Errors = append(c.Errors, errors.New("internal node host must be in the CNI subnet"))
+		if subnetErr == nil && hostErr == nil {
+			if addressFamily(internalNodeHost) != addressFamily(cniSubnet.Addr()) {
+				c.Errors = append(c.Errors, fmt.Errorf("internal node host %s is an %s address but the CNI subnet %s is %s", internalNodeHost, addressFamily(internalNodeHost), cniSubnet, addressFamily(cniSubnet.Addr())))
+			} else if !cniSubnet.Contains(internalNodeHost.Unmap()) {
+				c.Errors = append(c.Errors, errors.New("internal node host must be in the CNI subnet"))
+			}
+		}
+
+		if c.InternalNodeBindHost != nil {
+			bindHost, err := netip.ParseAddr(*c.InternalNodeBindHost)
+			if err != nil {
+				c.Errors = append(c.Errors, err)
+			} else if subnetErr == nil {
+				// an IPv6 listener on all interfaces also accepts IPv4 connections, but not the converse
+				dualStack := bindHost.IsUnspecified() && addressFamily(bindHost) == "IPv6"
+
+				if addressFamily(bindHost) != addressFamily(cniSubnet.Addr()) && !dualStack {
+					c.Errors = append(c.Errors, fmt.Errorf("internal node bind host %s is an %s address but the CNI subnet %s is %s", bindHost, addressFamily(bindHost), cniSubnet, addressFamily(cniSubnet.Addr())))
+				} else if !bindHost.IsUnspecified() && !cniSubnet.Contains(bindHost.Unmap()) {
+					c.Errors = append(c.Errors, errors.New("internal node bind host must be in the CNI subnet to be reachable by agents"))
+				}
+			}
+		}
+	} else if c.InternalNodeBindHost != nil {
+		if _, err := netip.ParseAddr(*c.InternalNodeBindHost); err != nil {
+			c.Errors = append(c.Errors, err)
 		}
 	}
 
 	return len(c.Errors) == 0
 }
 
+// Returns the family of the given address, IPv4 or IPv6; IPv4-mapped IPv6 addresses are IPv4
+func addressFamily(addr netip.Addr) string {
+	if addr.Unmap().Is4() {
+		return "IPv4"
+	}
+
+	return "IPv6"
+}
+
+// Returns the configured workload types for which the agent has no execution provider on this
+// platform. Types which are not built in may be provided by agent plugins, so are only reported
+// when no agent plugin path is configured
+func (c *NodeConfiguration) unsupportedWorkloadTypes() []string {
+	supported := agentapi.SupportedWorkloadTypes()
+
+	unsupported := make([]string, 0)
+	for _, workloadType := range c.WorkloadTypes {
+		if slices.Contains(supported, workloadType) {
+			continue
+		}
+
+		if c.AgentPluginPath != "" && !slices.Contains(agentapi.BuiltinWorkloadTypes, workloadType) {
+			continue
+		}
+
+		unsupported = append(unsupported, workloadType)
+	}
+
+	return unsupported
+}
+
+// Returns true if the CNI subnet from which machines are addressed is an IPv6 subnet, in which
+// case the node and its agents communicate over IPv6
+func (c *NodeConfiguration) CNIUsesIPv6() bool {
+	if c.CNI.Subnet == nil {
+		return false
+	}
+
+	subnet, err := netip.ParsePrefix(*c.CNI.Subnet)
+	return err == nil && addressFamily(subnet.Addr()) == "IPv6"
+}
+
+// Returns the first address of the CNI subnet after its network address, which is the address of
+// the gateway through which machines reach the node, and so the default internal node host
+func (c *NodeConfiguration) ResolveCNIGateway() (netip.Addr, error) {
+	if c.CNI.Subnet == nil {
+		return netip.Addr{}, errors.New("no CNI subnet configured")
+	}
+
+	subnet, err := netip.ParsePrefix(*c.CNI.Subnet)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	return subnet.Masked().Addr().Next(), nil
+}
+
+// Returns the address on which the internal NATS server should listen. Unless explicitly configured,
+// this is the internal node host (the CNI gateway as seen by agents) or the loopback address when
+// running without a sandbox, rather than all interfaces
+func (c *NodeConfiguration) ResolveInternalNodeBindHost() string {
+	if c.InternalNodeBindHost != nil {
+		return *c.InternalNodeBindHost
+	}
+
+	if c.NoSandbox || c.InternalNodeHost == nil {
+		return DefaultNoSandboxInternalNodeBindHost
+	}
+
+	return *c.InternalNodeHost
+}
+
+// Returns the maximum message payload and the maximum pending outbound bytes per connection of the
+// internal NATS server, through which agents receive trigger payloads and workload artifacts. Unless
+// configured, the server's defaults apply
+func (c *NodeConfiguration) ResolveInternalNatsLimits() (int, int) {
+	maxPayload := server.MAX_PAYLOAD_SIZE
+	if c.InternalNatsMaxPayloadBytes > 0 {
+		maxPayload = c.InternalNatsMaxPayloadBytes
+	}
+
+	maxPending := server.MAX_PENDING_SIZE
+	if c.InternalNatsMaxPendingBytes > 0 {
+		maxPending = c.InternalNatsMaxPendingBytes
+	}
+
+	return maxPayload, maxPending
+}
+
+// Returns the directory in which the internal NATS server stores JetStream data, by default a
+// directory within the temp dir
+func (c *NodeConfiguration) ResolveInternalNatsStoreDir() string {
+	if c.InternalNatsStoreDir != "" {
+		return c.InternalNatsStoreDir
+	}
+
+	return filepath.Join(os.TempDir(), DefaultInternalNatsStoreDirName)
+}
+
+// Returns the free space, in MiB, which the internal NATS store dir must have at startup
+func (c *NodeConfiguration) ResolveInternalNatsStoreMinFreeMib() int {
+	if c.InternalNatsStoreMinFreeMib <= 0 {
+		return DefaultInternalNatsStoreMinFreeMib
+	}
+
+	return c.InternalNatsStoreMinFreeMib
+}
+
+// Returns the name of the node's NATS connection with the given role, identifying the node by its
+// public key and tags in NATS server monitoring. Names are prefixed with the configured prefix, if
+// any, or otherwise with the given default prefix
+func (c *NodeConfiguration) ResolveConnectionName(defaultPrefix, role, nodeID string) string {
+	prefix := defaultPrefix
+	if c.NatsConnectionNamePrefix != "" {
+		prefix = c.NatsConnectionNamePrefix
+	}
+
+	name := fmt.Sprintf("%s-%s-%s", prefix, role, nodeID)
+	if len(c.Tags) == 0 {
+		return name
+	}
+
+	tags := make([]string, 0, len(c.Tags))
+	for k, v := range c.Tags {
+		tags = append(tags, fmt.Sprintf("%s=%s", k, v))
+	}
+	slices.Sort(tags)
+
+	return fmt.Sprintf("%s [%s]", name, strings.Join(tags, ","))
+}
+
+// Returns the longest time to live a signed deploy request may be issued with; the nonces of signed
+// requests are tracked for at most this long
+func (c *NodeConfiguration) ResolveSignedRequestMaxTTL() time.Duration {
+	millis := c.SignedRequestMaxTTLMillisecond
+	if millis <= 0 {
+		millis = DefaultSignedRequestMaxTTLMillisecond
+	}
+
+	return time.Duration(millis) * time.Millisecond
+}
+
+// Returns true if vcpus or memory are reserved for the host, in which case the node does not
+// allocate those resources to machines running workloads
+func (c *NodeConfiguration) ReservesHostResources() bool {
+	return c.ReservedHostVcpu > 0 || c.ReservedHostMemoryMib > 0
+}
+
+// Returns the number of machines which may run workloads of the given namespace at once, and whether
+// the namespace is limited at all
+func (c *NodeConfiguration) NamespaceQuota(namespace string) (int, bool) {
+	quota, ok := c.NamespaceQuotas[namespace]
+	return quota, ok
+}
+
+// Returns the longest time to live with which the node issues event tokens
+func (c *NodeConfiguration) ResolveEventTokenMaxTTL() time.Duration {
+	millis := c.EventTokenMaxTTLMillisecond
+	if millis <= 0 {
+		millis = DefaultEventTokenMaxTTLMillisecond
+	}
+
+	return time.Duration(millis) * time.Millisecond
+}
+
+// Returns the interval at which agents publish heartbeats to the node
+func (c *NodeConfiguration) ResolveAgentHeartbeatInterval() time.Duration {
+	millis := c.AgentHeartbeatIntervalMillisecond
+	if millis <= 0 {
+		millis = DefaultAgentHeartbeatIntervalMillisecond
+	}
+
+	return time.Duration(millis) * time.Millisecond
+}
+
+// Returns the time the node waits for an agent to acknowledge the deployment of a workload
+func (c *NodeConfiguration) ResolveAgentDeployTimeout() time.Duration {
+	millis := c.AgentDeployTimeoutMillisecond
+	if millis <= 0 {
+		millis = DefaultAgentDeployTimeoutMillisecond
+	}
+
+	return time.Duration(millis) * time.Millisecond
+}
+
+// Returns the time the node waits for an agent to acknowledge the undeployment of a workload, in
+// addition to the grace period given to the workload to exit
+func (c *NodeConfiguration) ResolveAgentUndeployTimeout() time.Duration {
+	millis := c.AgentUndeployTimeoutMillisecond
+	if millis <= 0 {
+		millis = DefaultAgentUndeployTimeoutMillisecond
+	}
+
+	return time.Duration(millis) * time.Millisecond
+}
+
+// Returns the number of consecutive agents which may fail to complete their handshake before the
+// node shuts down, the host being deemed incapable of booting agents
+func (c *NodeConfiguration) ResolveAgentHandshakeFailureThreshold() int {
+	if c.AgentHandshakeFailureThreshold <= 0 {
+		return DefaultAgentHandshakeFailureThreshold
+	}
+
+	return c.AgentHandshakeFailureThreshold
+}
+
+// Returns the number of consecutive heartbeats an agent may miss before the node considers it degraded
+func (c *NodeConfiguration) ResolveAgentHeartbeatMissedThreshold() int {
+	if c.AgentHeartbeatMissedThreshold <= 0 {
+		return DefaultAgentHeartbeatMissedThreshold
+	}
+
+	return c.AgentHeartbeatMissedThreshold
+}
+
+// Returns the number of log lines per second each agent forwards to the node and the number it may
+// forward in a burst above that rate, which is the rate itself unless configured. Agents forward
+// every log line when no rate is configured, in which case the rate returned is zero
+func (c *NodeConfiguration) ResolveAgentLogRateLimit() (int, int) {
+	if c.AgentLogRateLimit <= 0 {
+		return 0, 0
+	}
+
+	if c.AgentLogBurst <= 0 {
+		return c.AgentLogRateLimit, c.AgentLogRateLimit
+	}
+
+	return c.AgentLogRateLimit, c.AgentLogBurst
+}
+
+// Returns the upper bound of the backoff between attempts to create an agent process for the pool
+// after consecutive failures
+func (c *NodeConfiguration) ResolvePoolCreateBackoffMax() time.Duration {
+	millis := c.PoolCreateBackoffMaxMillisecond
+	if millis <= 0 {
+		millis = DefaultPoolCreateBackoffMaxMillisecond
+	}
+
+	return time.Duration(millis) * time.Millisecond
+}
+
+// Returns the number of consecutive failed attempts to create an agent process for the pool after
+// which the process manager gives up
+func (c *NodeConfiguration) ResolvePoolCreateMaxAttempts() int {
+	if c.PoolCreateMaxAttempts <= 0 {
+		return DefaultPoolCreateMaxAttempts
+	}
+
+	return c.PoolCreateMaxAttempts
+}
+
+// Returns the interval at which the agents running workloads are pinged to prove that they are
+// still responsive, or 0 if liveness probes are disabled
+func (c *NodeConfiguration) ResolveLivenessProbeInterval() time.Duration {
+	return time.Duration(max(c.LivenessProbeIntervalMillisecond, 0)) * time.Millisecond
+}
+
+// Returns how long the node waits for an agent to answer a liveness probe
+func (c *NodeConfiguration) ResolveLivenessProbeTimeout() time.Duration {
+	millis := c.LivenessProbeTimeoutMillisecond
+	if millis <= 0 {
+		millis = DefaultLivenessProbeTimeoutMillisecond
+	}
+
+	return time.Duration(millis) * time.Millisecond
+}
+
+// Returns the number of consecutive liveness probes an agent may fail before its workload is restarted
+func (c *NodeConfiguration) ResolveLivenessProbeFailureThreshold() int {
+	if c.LivenessProbeFailureThreshold <= 0 {
+		return DefaultLivenessProbeFailureThreshold
+	}
+
+	return c.LivenessProbeFailureThreshold
+}
+
+// Returns how long a deployment is held awaiting the workloads on which it depends
+func (c *NodeConfiguration) ResolveDependencyTimeout() time.Duration {
+	millis := c.DependencyTimeoutMillisecond
+	if millis <= 0 {
+		millis = DefaultDependencyTimeoutMillisecond
+	}
+
+	return time.Duration(millis) * time.Millisecond
+}
+
+// Returns the configuration of the HTTP gateway, or nil if the http host service is not enabled or
+// configures no gateway
+func (c *NodeConfiguration) ResolveHTTPGateway() (*HTTPGatewayConfig, error) {
+	if c.HostServicesConfiguration == nil {
+		return nil, nil
+	}
+
+	service, ok := c.HostServicesConfiguration.Services["http"]
+	if !ok || !service.Enabled || len(service.Configuration) == 0 {
+		return nil, nil
+	}
+
+	var config HTTPServiceConfig
+	err := json.Unmarshal(service.Configuration, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.Gateway, nil
+}
+
+// Returns how long an agent may sit idle in the warm pool before it is reaped, or zero if idle
+// agents are never reaped
+func (c *NodeConfiguration) ResolveIdleAgentReapAfter() time.Duration {
+	return time.Duration(max(c.IdleAgentReapAfterMillisecond, 0)) * time.Millisecond
+}
+
+// Returns how often the warm pool is checked for agents which have sat idle too long
+func (c *NodeConfiguration) ResolveIdleAgentReapInterval() time.Duration {
+	millis := c.IdleAgentReapIntervalMillisecond
+	if millis <= 0 {
+		millis = DefaultIdleAgentReapIntervalMillisecond
+	}
+
+	return time.Duration(millis) * time.Millisecond
+}
+
+// Returns the interval at which the network counters of the machines running workloads are sampled,
+// or zero if they are not sampled
+func (c *NodeConfiguration) ResolveNetworkStatsInterval() time.Duration {
+	return time.Duration(max(c.NetworkStatsIntervalMillisecond, 0)) * time.Millisecond
+}
+
+// Returns the maximum time the node waits for its machines to stop when it shuts down, after which
+// any machine still running is killed
+func (c *NodeConfiguration) ResolveShutdownTimeout() time.Duration {
+	millis := c.ShutdownTimeoutMillisecond
+	if millis <= 0 {
+		millis = DefaultShutdownTimeoutMillisecond
+	}
+
+	return time.Duration(millis) * time.Millisecond
+}
+
+// Returns the policy for deploying a workload named the same as a workload already running in its
+// namespace, rejecting such deployments unless configured otherwise
+func (c *NodeConfiguration) ResolveDuplicateWorkloadPolicy() string {
+	if c.DuplicateWorkloadPolicy == "" {
+		return DuplicateWorkloadPolicyReject
+	}
+
+	return strings.ToLower(c.DuplicateWorkloadPolicy)
+}
+
+// Returns the fraction of triggers traced for workloads which do not specify their own sampling
+// rate. Unless configured, every trigger is traced
+func (c *NodeConfiguration) ResolveTraceSamplingRate() float64 {
+	if c.OtelTraceSamplingRate == nil {
+		return 1
+	}
+
+	return *c.OtelTraceSamplingRate
+}
+
+// Returns the public key with which agent binaries pushed to agents must be signed, or nil if
+// agent updates are not allowed
+func (c *NodeConfiguration) ResolveAgentUpdatePublicKey() *string {
+	if !c.AllowAgentUpdates || c.AgentUpdatePublicKey == "" {
+		return nil
+	}
+
+	return &c.AgentUpdatePublicKey
+}
+
+// Returns the host path from which entropy injected into VMs at boot is read
+func (c *NodeConfiguration) ResolveEntropySource() string {
+	if c.EntropySource != "" {
+		return c.EntropySource
+	}
+
+	return DefaultEntropySource
+}
+
+// Returns the default workload environment with the values of sensitive variables redacted,
+// suitable for inclusion in events and status output
+func (c *NodeConfiguration) RedactedDefaultWorkloadEnvironment() map[string]string {
+	if len(c.DefaultWorkloadEnvironment) == 0 {
+		return nil
+	}
+
+	env := make(map[string]string, len(c.DefaultWorkloadEnvironment))
+	for key, value := range c.DefaultWorkloadEnvironment {
+		if slices.Contains(c.SensitiveWorkloadEnvironment, key) {
+			value = redactedValue
+		}
+		env[key] = value
+	}
+
+	return env
+}
+
+// Returns the maximum time a node in lame duck mode waits for its remaining workloads to exit,
+// after which any workload still running is stopped
+func (c *NodeConfiguration) ResolveLameDuckDrainTimeout() time.Duration {
+	millis := c.LameDuckDrainTimeoutMillisecond
+	if millis <= 0 {
+		millis = DefaultLameDuckDrainTimeoutMillisecond
+	}
+
+	return time.Duration(millis) * time.Millisecond
+}
+
+// Returns the maximum number of bytes of a workload's stdout and stderr captured into the internal
+// object store when its deploy request asks for them; output beyond it is discarded
+func (c *NodeConfiguration) ResolveLogCaptureMaxBytes() int64 {
+	if c.LogCaptureMaxBytes <= 0 {
+		return DefaultLogCaptureMaxBytes
+	}
+
+	return c.LogCaptureMaxBytes
+}
+
+// Returns the maximum size of, and the time objects are retained in, the internal object store into
+// which workload logs are captured
+func (c *NodeConfiguration) ResolveLogCaptureRetention() (int64, time.Duration) {
+	maxBytes := c.LogCaptureBucketMaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultLogCaptureBucketMaxBytes
+	}
+
+	millis := c.LogCaptureTTLMillisecond
+	if millis <= 0 {
+		millis = DefaultLogCaptureTTLMillisecond
+	}
+
+	return maxBytes, time.Duration(millis) * time.Millisecond
+}
+
+// Returns how long the machine pool target is held above its configured size after last growing
+// below the low watermark before the burst slots are trimmed
+func (c *NodeConfiguration) ResolveMachinePoolBurstCooldown() time.Duration {
+	millis := c.MachinePoolBurstCooldownMillisecond
+	if millis <= 0 {
+		millis = DefaultMachinePoolBurstCooldownMillisecond
+	}
+
+	return time.Duration(millis) * time.Millisecond
+}
+
+// Returns the bounds within which the machine pool target may be adjusted at runtime. Unless
+// explicitly configured, the pool may shrink to a single machine but never grow beyond its
+// configured size
+func (c *NodeConfiguration) ResolveMachinePoolBounds() (int, int) {
+	poolMin := 1
+	if c.MachinePoolMin > 0 {
+		poolMin = c.MachinePoolMin
+	}
+
+	poolMax := c.MachinePoolSize
+	if c.MachinePoolMax > 0 {
+		poolMax = c.MachinePoolMax
+	}
+
+	return poolMin, poolMax
+}
+
 func DefaultNodeConfiguration() NodeConfiguration {
 	defaultNodePort := DefaultInternalNodePort
 	defaultVcpuCount := DefaultNodeVcpuCount
@@ -139,10 +1032,16 @@ func DefaultNodeConfiguration() NodeConfiguration {
 			VcpuCount:  &defaultVcpuCount,
 			MemSizeMib: &defaultMemSizeMib,
 		},
-		OtlpExporterUrl: DefaultOtelExporterUrl,
-		RateLimiters:    nil,
-		Tags:            tags,
-		WorkloadTypes:   DefaultWorkloadTypes,
+		OtlpExporterUrl:                DefaultOtelExporterUrl,
+		PrewarmIdleTimeoutMillisecond:  DefaultPrewarmIdleTimeoutMillisecond,
+		PoolFillLogIntervalMillisecond: DefaultPoolFillLogIntervalMillisecond,
+		PoolRefillBackoffMillisecond:   DefaultPoolRefillBackoffMillisecond,
+		EventHistorySize:               DefaultEventHistorySize,
+		RateLimiters:                   nil,
+		StopGracePeriodMillisecond:     DefaultStopGracePeriodMillisecond,
+		StoreProbeIntervalMillisecond:  DefaultStoreProbeIntervalMillisecond,
+		Tags:                           tags,
+		WorkloadTypes:                  DefaultWorkloadTypes,
 		HostServicesConfiguration: &HostServicesConfig{
 			NatsUrl:      "", // this will trigger logic to re-use the main connection
 			NatsUserJwt:  "",
@@ -179,3 +1078,68 @@ func DefaultNodeConfiguration() NodeConfiguration {
 
 	return config
 }
+
+// Returns the raw key
+func (k ArtifactDecryptionKey) Decode() ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(k.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(key) != controlapi.ArtifactKeySize {
+		return nil, fmt.Errorf("key must be %d bytes", controlapi.ArtifactKeySize)
+	}
+
+	return key, nil
+}
+
+// Returns true if a workload deployed into the given namespace by the given issuer may use the key
+func (k ArtifactDecryptionKey) Authorizes(namespace string, issuer string) bool {
+	return (len(k.Namespaces) == 0 || slices.Contains(k.Namespaces, namespace)) &&
+		(len(k.Issuers) == 0 || slices.Contains(k.Issuers, issuer))
+}
+
+// Returns the maximum time to wait for a triggered workload to respond
+func (g *HTTPGatewayConfig) ResolveTimeout() time.Duration {
+	millis := g.TimeoutMillisecond
+	if millis <= 0 {
+		millis = DefaultHTTPGatewayTimeoutMillisecond
+	}
+
+	return time.Duration(millis) * time.Millisecond
+}
+
+// Returns the pattern with which the route is registered with an http.ServeMux
+func (r HTTPGatewayRoute) Pattern() string {
+	return strings.TrimSuffix(r.Path, "*")
+}
+
+func (g *HTTPGatewayConfig) validate() []error {
+	errs := make([]error, 0)
+
+	if g.Listen == "" {
+		errs = append(errs, errors.New("http gateway listen address is required"))
+	}
+
+	if g.TimeoutMillisecond < 0 {
+		errs = append(errs, errors.New("http gateway timeout must be >= 0"))
+	}
+
+	patterns := make([]string, 0, len(g.Routes))
+	for _, route := range g.Routes {
+		if !strings.HasPrefix(route.Path, "/") || strings.ContainsAny(strings.TrimSuffix(route.Path, "/*"), "*{} ") {
+			errs = append(errs, fmt.Errorf("invalid http gateway route path: %s", route.Path))
+		}
+
+		if route.TriggerSubject == "" || strings.ContainsAny(route.TriggerSubject, " *>") {
+			errs = append(errs, fmt.Errorf("http gateway route %s requires a trigger subject without wildcards", route.Path))
+		}
+
+		if slices.Contains(patterns, route.Pattern()) {
+			errs = append(errs, fmt.Errorf("duplicate http gateway route path: %s", route.Path))
+		}
+		patterns = append(patterns, route.Pattern())
+	}
+
+	return errs
+}
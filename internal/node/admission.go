@@ -0,0 +1,100 @@
+package nexnode
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+	"github.com/synadia-io/nex/internal/node/processmanager"
+)
+
+// Checks the given deploy request against every admission constraint of this node, returning all of
+// the constraints it fails to satisfy rather than stopping at the first. The request's claims must
+// already have been decoded
+func (api *ApiListener) unsatisfiedConstraints(request *controlapi.DeployRequest) []controlapi.UnsatisfiedConstraint {
+	unsatisfied := make([]controlapi.UnsatisfiedConstraint, 0)
+	fail := func(constraint string, reason string) {
+		unsatisfied = append(unsatisfied, controlapi.UnsatisfiedConstraint{
+			Constraint: constraint,
+			Reason:     reason,
+		})
+	}
+
+	if api.node.IsLameDuck() {
+		fail(controlapi.ConstraintLameDuck, "node is in lame duck mode and not accepting new workloads")
+	}
+
+	if !api.node.IsHealthy() {
+		fail(controlapi.ConstraintObjectStore, "node's internal object store is unavailable")
+	}
+
+	if request.WorkloadType == nil {
+		fail(controlapi.ConstraintWorkloadType, "no workload type specified")
+	} else {
+		if !slices.Contains(api.node.config.WorkloadTypes, *request.WorkloadType) {
+			fail(controlapi.ConstraintWorkloadType, fmt.Sprintf("unsupported workload type on this node: %s", *request.WorkloadType))
+		}
+
+		if len(request.TriggerSubjects) > 0 && (!strings.EqualFold(*request.WorkloadType, "v8") &&
+			!strings.EqualFold(*request.WorkloadType, "wasm")) { // FIXME -- workload type comparison
+			fail(controlapi.ConstraintTriggerSubjects, fmt.Sprintf("unsupported workload type for trigger subject registration: %s", *request.WorkloadType))
+		}
+	}
+
+	if request.ArtifactBucket != nil && *request.ArtifactBucket != WorkloadCacheBucketName &&
+		!slices.Contains(api.node.config.ArtifactBuckets, *request.ArtifactBucket) {
+		fail(controlapi.ConstraintArtifactBucket, fmt.Sprintf("artifact bucket not allowed on this node: %s", *request.ArtifactBucket))
+	}
+
+	if request.GitSource != nil && !api.node.config.AllowGitSources {
+		fail(controlapi.ConstraintGitSource, "deployment from git sources is not allowed on this node")
+	}
+
+	if request.TargetVM != nil && !api.node.config.AllowVMPinning {
+		fail(controlapi.ConstraintVMPinning, "pinning workloads to VMs is not allowed on this node")
+	}
+
+	if !validateIssuer(request.DecodedClaims.Issuer, api.node.config.ValidIssuers) {
+		fail(controlapi.ConstraintIssuer, fmt.Sprintf("invalid workload issuer: %s", request.DecodedClaims.Issuer))
+	}
+
+	if max := api.node.config.MaxWorkloads; max > 0 {
+		if count := api.mgr.workloadCount(); count >= max {
+			fail(controlapi.ConstraintMaxWorkloads, fmt.Sprintf("node at workload capacity (%d of %d workloads)", count, max))
+		}
+	}
+
+	if err := api.checkHostCapacity(request.Resources); err != nil {
+		fail(controlapi.ConstraintHostResources, err.Error())
+	}
+
+	if !api.mgr.hasAvailableAgent() {
+		fail(controlapi.ConstraintAgentPool, "no available agent in the pool")
+	}
+
+	return unsatisfied
+}
+
+// Returns an error if running a workload with the given resources would allocate more of the host's
+// vcpus or memory to machines running workloads than it can allocate. Only checked on nodes which
+// reserve host resources and whose process manager reports its capacity
+func (api *ApiListener) checkHostCapacity(resources *controlapi.WorkloadResources) error {
+	if !api.node.config.ReservesHostResources() {
+		return nil
+	}
+
+	reporter, ok := api.mgr.procMan.(processmanager.ProcessCapacityReporter)
+	if !ok {
+		return nil
+	}
+
+	summary, err := reporter.Capacity()
+	if err != nil {
+		api.log.Warn("Failed to determine host capacity", slog.Any("err", err))
+		return nil
+	}
+
+	return summary.Admits(api.node.config.MachineTemplate, resources)
+}
@@ -0,0 +1,58 @@
+package nexnode
+
+import (
+	"log/slog"
+	"runtime"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+// Assembles the capabilities and free capacity the node advertises in its started event and
+// heartbeats. Capacity is omitted should the running workloads not be listed
+func (n *Node) advertisement() controlapi.NodeAdvertisement {
+	ad := controlapi.NodeAdvertisement{
+		Tags:          n.config.Tags,
+		WorkloadTypes: n.config.WorkloadTypes,
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		LameDuck:      n.IsLameDuck(),
+
+		HeartbeatIntervalMillisecond: int(heartbeatInterval.Milliseconds()),
+	}
+
+	workloads, err := n.manager.inventoryWorkloads(false)
+	if err != nil {
+		n.log.Warn("Failed to list running workloads; free capacity will not be advertised", slog.Any("err", err))
+		return ad
+	}
+
+	memory, _ := ReadMemoryStats()
+	ad.Capacity = n.freeCapacity(n.capacity(memory, workloads), len(workloads), n.manager.idleAgents())
+	return ad
+}
+
+// Returns the resources of the given capacity not yet allocated, and the number of further
+// workloads the node accepts given the number running
+func (n *Node) freeCapacity(capacity controlapi.InventoryCapacity, running int, idleAgents int) *controlapi.NodeFreeCapacity {
+	free := &controlapi.NodeFreeCapacity{
+		Vcpu:       max(capacity.AllocatableVcpu-capacity.AllocatedVcpu, 0),
+		IdleAgents: idleAgents,
+	}
+
+	if capacity.AllocatableMemoryMib > 0 {
+		free.MemoryMib = max(capacity.AllocatableMemoryMib-capacity.AllocatedMemoryMib, 0)
+	}
+
+	if n.config.MaxWorkloads > 0 {
+		workloads := max(n.config.MaxWorkloads-running, 0)
+		free.Workloads = &workloads
+	}
+
+	// a node in lame duck mode accepts no further workloads
+	if n.IsLameDuck() {
+		workloads := 0
+		free.Workloads = &workloads
+	}
+
+	return free
+}
@@ -0,0 +1,44 @@
+package nexnode
+
+import (
+	"testing"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+func TestFreeCapacitySubtractsAllocatedResources(t *testing.T) {
+	n := &Node{config: &models.NodeConfiguration{MaxWorkloads: 3}}
+
+	capacity := controlapi.InventoryCapacity{
+		AllocatableVcpu:      4,
+		AllocatableMemoryMib: 2048,
+		AllocatedVcpu:        2,
+		AllocatedMemoryMib:   512,
+	}
+
+	free := n.freeCapacity(capacity, 2, 1)
+	if free.Vcpu != 2 || free.MemoryMib != 1536 || free.IdleAgents != 1 {
+		t.Fatalf("unexpected free capacity: %+v", free)
+	}
+	if free.Workloads == nil || *free.Workloads != 1 {
+		t.Fatalf("expected room for one more workload, got %v", free.Workloads)
+	}
+
+	// overcommitted resources are advertised as none free rather than negative
+	capacity.AllocatedVcpu = 6
+	free = n.freeCapacity(capacity, 5, 0)
+	if free.Vcpu != 0 || *free.Workloads != 0 {
+		t.Fatalf("expected no free capacity, got %+v", free)
+	}
+
+	n.config.MaxWorkloads = 0
+	if free = n.freeCapacity(capacity, 5, 0); free.Workloads != nil {
+		t.Fatal("expected the number of workloads not to be advertised when unlimited")
+	}
+
+	n.lameduck = 1
+	if free = n.freeCapacity(capacity, 0, 0); free.Workloads == nil || *free.Workloads != 0 {
+		t.Fatal("expected a node in lame duck mode to advertise room for no workloads")
+	}
+}
@@ -0,0 +1,110 @@
+package nexnode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"go.opentelemetry.io/otel"
+)
+
+func TestAgentClientRejectsUseAfterDrain(t *testing.T) {
+	svr, _ := startObjectStoreTestServer(t, t.TempDir())
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	// an agent which accepts every request
+	accepted, _ := json.Marshal(agentapi.DeployResponse{Accepted: true})
+	sub, err := nc.Subscribe("agentint.vm1.>", func(m *nats.Msg) {
+		if m.Reply != "" {
+			_ = m.Respond(accepted)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	noop := func(string) {}
+
+	agentClient := agentapi.NewAgentClient(nc, log, time.Minute, time.Second, time.Second, 0, false, noop, noop, nil, nil, nil, nil, nil)
+	err = agentClient.Start("vm1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = agentClient.Stop() }()
+
+	tracer := otel.Tracer("nex-test")
+	workloadName := "echo"
+
+	// requests racing the drain either complete or are rejected, never failing otherwise
+	var wg sync.WaitGroup
+	errs := make(chan error, 30)
+	for i := 0; i < 10; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			_, err := agentClient.DeployWorkload(context.Background(), tracer, &agentapi.DeployRequest{WorkloadName: &workloadName})
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := agentClient.RunTrigger(context.Background(), tracer, "echo", []byte("hi"), false, time.Second)
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			errs <- agentClient.Undeploy(0)
+		}()
+	}
+
+	err = agentClient.Drain()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil && !errors.Is(err, agentapi.ErrAgentClientClosed) {
+			t.Fatalf("expected request racing drain to complete or be rejected, got %v", err)
+		}
+	}
+
+	_, err = agentClient.DeployWorkload(context.Background(), tracer, &agentapi.DeployRequest{WorkloadName: &workloadName})
+	if !errors.Is(err, agentapi.ErrAgentClientClosed) {
+		t.Fatalf("expected deploy after drain to be rejected, got %v", err)
+	}
+
+	_, err = agentClient.RunTrigger(context.Background(), tracer, "echo", []byte("hi"), false, time.Second)
+	if !errors.Is(err, agentapi.ErrAgentClientClosed) {
+		t.Fatalf("expected trigger after drain to be rejected, got %v", err)
+	}
+
+	err = agentClient.Undeploy(0)
+	if !errors.Is(err, agentapi.ErrAgentClientClosed) {
+		t.Fatalf("expected undeploy after drain to be rejected, got %v", err)
+	}
+
+	err = agentClient.Start("vm1")
+	if !errors.Is(err, agentapi.ErrAgentClientClosed) {
+		t.Fatalf("expected start after drain to be rejected, got %v", err)
+	}
+
+	err = agentClient.Drain()
+	if err != nil {
+		t.Fatalf("expected repeated drain to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package nexnode
+
+import (
+	"log/slog"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go"
+	"github.com/google/uuid"
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+// Checks the heartbeats of the node's agents at the configured heartbeat interval until the
+// workload manager stops
+func (w *WorkloadManager) monitorAgentHeartbeats() {
+	ticker := time.NewTicker(w.config.ResolveAgentHeartbeatInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			if w.stopping() {
+				return
+			}
+
+			w.checkAgentHeartbeats(time.Now().UTC())
+		}
+	}
+}
+
+// Marks each agent which has missed the configured number of consecutive heartbeats as of the
+// given time degraded, and each degraded agent which has since been heard from healthy again,
+// publishing an event on each transition
+func (w *WorkloadManager) checkAgentHeartbeats(now time.Time) {
+	interval := w.config.ResolveAgentHeartbeatInterval()
+	threshold := w.config.ResolveAgentHeartbeatMissedThreshold()
+
+	for id, agentClient := range w.heartbeatingAgents() {
+		_, lastSeen := agentClient.LastHeartbeat()
+		if lastSeen.IsZero() {
+			// the agent has not yet handshaken
+			continue
+		}
+
+		missed := int(now.Sub(lastSeen) / interval)
+		if missed >= threshold {
+			if agentClient.SetDegraded(true) {
+				w.log.Warn("Agent missed heartbeats; marking degraded",
+					slog.String("workload_id", id),
+					slog.Int("missed_heartbeats", missed),
+					slog.Time("last_heartbeat", lastSeen),
+				)
+				_ = w.publishAgentHealthChanged(id, true, missed, lastSeen)
+			}
+			continue
+		}
+
+		if agentClient.SetDegraded(false) {
+			w.log.Info("Agent heartbeats resumed; no longer degraded", slog.String("workload_id", id))
+			_ = w.publishAgentHealthChanged(id, false, 0, lastSeen)
+		}
+	}
+}
+
+// Returns the agents in the pool and those running workloads, keyed by id
+func (w *WorkloadManager) heartbeatingAgents() map[string]*agentapi.AgentClient {
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
+	agents := make(map[string]*agentapi.AgentClient, len(w.pendingAgents)+len(w.activeAgents))
+	for id, agentClient := range w.pendingAgents {
+		agents[id] = agentClient
+	}
+	for id, agentClient := range w.activeAgents {
+		agents[id] = agentClient
+	}
+
+	return agents
+}
+
+// Publishes a change in the health of the given agent in the namespace of its workload, or the
+// system namespace if it is idle
+func (w *WorkloadManager) publishAgentHealthChanged(id string, degraded bool, missed int, lastSeen time.Time) error {
+	evt := controlapi.AgentHealthChangedEvent{
+		Id:               id,
+		Degraded:         degraded,
+		MissedHeartbeats: missed,
+		LastHeartbeat:    lastSeen.Format(time.RFC3339),
+	}
+
+	namespace := systemNamespace
+	deployRequest, _ := w.procMan.Lookup(id)
+	if deployRequest != nil {
+		namespace = *deployRequest.Namespace
+		evt.WorkloadName = *deployRequest.WorkloadName
+	}
+
+	cloudevent := cloudevents.NewEvent()
+	cloudevent.SetSource(w.publicKey)
+	cloudevent.SetID(uuid.NewString())
+	cloudevent.SetTime(time.Now().UTC())
+	cloudevent.SetType(controlapi.AgentHealthChangedEventType)
+	cloudevent.SetDataContentType(cloudevents.ApplicationJSON)
+	_ = cloudevent.SetData(evt)
+
+	return w.publishCloudEvent(namespace, cloudevent)
+}
@@ -0,0 +1,97 @@
+package nexnode
+
+import (
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/models"
+	"github.com/synadia-io/nex/internal/node/processmanager"
+)
+
+// Process manager with no workloads deployed
+type idleProcessManager struct {
+	processmanager.ProcessManager
+}
+
+func (idleProcessManager) Lookup(string) (*agentapi.DeployRequest, error) {
+	return nil, nil
+}
+
+func TestAgentMarkedDegradedAfterMissedHeartbeats(t *testing.T) {
+	svr, _ := startObjectStoreTestServer(t, t.TempDir())
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	noop := func(string) {}
+
+	agentClient := agentapi.NewAgentClient(nc, log, time.Minute, time.Second, time.Second, 0, false, noop, noop, nil, nil, nil, nil, nil)
+	err = agentClient.Start("vm1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = agentClient.Stop() }()
+
+	err = agentapi.PublishHeartbeat(nc, "vm1", &agentapi.AgentHeartbeat{ID: "vm1", Timestamp: time.Now().UTC()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lastSeen time.Time
+	for deadline := time.Now().Add(2 * time.Second); lastSeen.IsZero(); {
+		if time.Now().After(deadline) {
+			t.Fatal("expected heartbeat to be received")
+		}
+		_, lastSeen = agentClient.LastHeartbeat()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	w := &WorkloadManager{
+		config: &models.NodeConfiguration{
+			AgentHeartbeatIntervalMillisecond: 1000,
+			AgentHeartbeatMissedThreshold:     3,
+		},
+		events:        newEventHistory(models.DefaultEventHistorySize),
+		log:           log,
+		nc:            nc,
+		poolMutex:     &sync.Mutex{},
+		procMan:       idleProcessManager{},
+		activeAgents:  make(map[string]*agentapi.AgentClient),
+		pendingAgents: map[string]*agentapi.AgentClient{"vm1": agentClient},
+	}
+
+	w.checkAgentHeartbeats(lastSeen.Add(2 * time.Second))
+	if agentClient.Degraded() {
+		t.Fatal("expected agent which missed fewer heartbeats than the threshold not to be degraded")
+	}
+
+	w.checkAgentHeartbeats(lastSeen.Add(3 * time.Second))
+	if !agentClient.Degraded() {
+		t.Fatal("expected agent which missed the threshold number of heartbeats to be degraded")
+	}
+
+	events := w.events.replay(systemNamespace, "", controlapi.AgentHealthChangedEventType, 0)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 agent health changed event, got %d", len(events))
+	}
+
+	w.checkAgentHeartbeats(lastSeen)
+	if agentClient.Degraded() {
+		t.Fatal("expected agent to recover once heard from")
+	}
+
+	events = w.events.replay(systemNamespace, "", controlapi.AgentHealthChangedEventType, 0)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 agent health changed events, got %d", len(events))
+	}
+}
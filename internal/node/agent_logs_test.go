@@ -0,0 +1,121 @@
+package nexnode
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+func TestAgentLogBatchesAreReceivedInOrder(t *testing.T) {
+	svr, _ := startObjectStoreTestServer(t, t.TempDir())
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	noop := func(string) {}
+
+	received := make(chan agentapi.LogEntry, 8)
+	onLog := func(_ string, entry agentapi.LogEntry) {
+		received <- entry
+	}
+
+	agentClient := agentapi.NewAgentClient(nc, log, time.Minute, time.Second, time.Second, 0, false, noop, noop, nil, onLog, nil, nil, nil)
+	err = agentClient.Start("vm1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = agentClient.Stop() }()
+
+	err = nc.Publish("agentint.vm1.logs", []byte(`[{"text":"first","timestamp":"2024-05-01T00:00:00Z"},{"text":"second","timestamp":"2024-05-01T00:00:01Z"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// agents which predate batching publish each entry on its own
+	err = nc.Publish("agentint.vm1.logs", []byte(`{"text":"third"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []agentapi.LogEntry
+	for len(entries) < 3 {
+		select {
+		case entry := <-received:
+			entries = append(entries, entry)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected each entry to be received, got %+v", entries)
+		}
+	}
+
+	if entries[0].Text != "first" || entries[1].Text != "second" || entries[2].Text != "third" {
+		t.Fatalf("expected entries to be received in the order they were logged, got %+v", entries)
+	}
+	if !entries[0].Timestamp.Before(entries[1].Timestamp) {
+		t.Fatal("expected the original timestamps to be preserved")
+	}
+}
+
+type recordingLogSink struct {
+	name     string
+	received chan<- string
+}
+
+func (s *recordingLogSink) Write(agentID string, entry agentapi.LogEntry) {
+	s.received <- s.name + ":" + agentID + ":" + entry.Text
+}
+
+func TestAgentLogsAreWrittenToEachSinkInOrder(t *testing.T) {
+	svr, _ := startObjectStoreTestServer(t, t.TempDir())
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	noop := func(string) {}
+
+	received := make(chan string, 8)
+	onLog := func(agentID string, entry agentapi.LogEntry) {
+		received <- "callback:" + agentID + ":" + entry.Text
+	}
+
+	agentClient := agentapi.NewAgentClient(nc, log, time.Minute, time.Second, time.Second, 0, false, noop, noop, nil, onLog, nil, nil, nil)
+	agentClient.AddLogSink(&recordingLogSink{name: "file", received: received})
+	agentClient.AddLogSink(&recordingLogSink{name: "aggregator", received: received})
+
+	err = agentClient.Start("vm1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = agentClient.Stop() }()
+
+	err = nc.Publish("agentint.vm1.logs", []byte(`[{"text":"first"},{"text":"second"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"callback:vm1:first", "file:vm1:first", "aggregator:vm1:first",
+		"callback:vm1:second", "file:vm1:second", "aggregator:vm1:second",
+	}
+	for _, want := range expected {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("expected %q, got %q", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected %q to be written", want)
+		}
+	}
+}
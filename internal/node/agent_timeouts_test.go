@@ -0,0 +1,52 @@
+package nexnode
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"go.opentelemetry.io/otel"
+)
+
+func TestAgentClientReportsConfiguredTimeouts(t *testing.T) {
+	svr, _ := startObjectStoreTestServer(t, t.TempDir())
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	// an agent which never acknowledges requests
+	sub, err := nc.Subscribe("agentint.vm1.>", func(*nats.Msg) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	noop := func(string) {}
+
+	agentClient := agentapi.NewAgentClient(nc, log, time.Minute, 50*time.Millisecond, 30*time.Millisecond, 0, false, noop, noop, nil, nil, nil, nil, nil)
+	err = agentClient.Start("vm1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = agentClient.Stop() }()
+
+	workloadName := "echo"
+	_, err = agentClient.DeployWorkload(context.Background(), otel.Tracer("nex-test"), &agentapi.DeployRequest{WorkloadName: &workloadName})
+	if err == nil || !strings.Contains(err.Error(), "deploy timeout of 50ms") {
+		t.Fatalf("expected deploy to time out after the configured timeout, got %v", err)
+	}
+
+	err = agentClient.Undeploy(20 * time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "undeploy timeout of 30ms and grace period of 20ms") {
+		t.Fatalf("expected undeploy to time out after the configured timeout, got %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package nexnode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+// Prefix of the keys to which agent binaries pushed to agents are written in the internal cache.
+// Workload names cannot contain a dash, so these never collide with cached workloads
+const agentUpdateKeyPrefix = "agent-update-"
+
+// Pushes the agent binary indicated by the given request to each of this node's agents, which
+// replace themselves with it in place and redeploy their workloads. Agents which fail to update
+// are replaced by new machines: idle agents are stopped for the pool to replenish, and workloads
+// are redeployed to new agents
+func (w *WorkloadManager) UpdateAgents(request *controlapi.AgentUpdateRequest) (*controlapi.AgentUpdateResponse, error) {
+	if !w.config.AllowAgentUpdates {
+		return nil, errors.New("agent updates are not allowed by this node's configuration")
+	}
+
+	binary, _, err := w.downloadArtifact(request.Location, request.JsDomain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download agent binary: %s", err)
+	}
+
+	err = controlapi.VerifyAgentBinary(w.config.AgentUpdatePublicKey, binary, request.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(binary)
+	hashString := hex.EncodeToString(hash[:])
+	key := agentUpdateKeyPrefix + hashString
+
+	js, err := w.ncInternal.JetStream()
+	if err != nil {
+		return nil, err
+	}
+
+	cache, err := js.ObjectStore(agentapi.WorkloadCacheBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = cache.PutBytes(key, binary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write agent binary to internal cache: %s", err)
+	}
+	defer func() {
+		_ = cache.Delete(key)
+	}()
+
+	agentRequest := &agentapi.AgentUpdateRequest{
+		ArtifactKey: key,
+		Hash:        hashString,
+		Signature:   request.Signature,
+	}
+
+	resp := &controlapi.AgentUpdateResponse{
+		Hash:     hashString,
+		Updated:  make([]string, 0),
+		Replaced: make([]string, 0),
+	}
+
+	active, pending := w.updatableAgents()
+	for id, agentClient := range pending {
+		err := agentClient.UpdateAgent(agentRequest, w.handshakeTimeout)
+		if err == nil {
+			resp.Updated = append(resp.Updated, id)
+			continue
+		}
+
+		w.log.Warn("Failed to update idle agent; replacing machine", slog.String("workload_id", id), slog.Any("err", err))
+		w.replaceIdleAgent(id)
+		resp.Replaced = append(resp.Replaced, id)
+	}
+
+	for id, agentClient := range active {
+		err := agentClient.UpdateAgent(agentRequest, w.handshakeTimeout)
+		if err == nil {
+			resp.Updated = append(resp.Updated, id)
+			continue
+		}
+
+		w.log.Warn("Failed to update agent; redeploying workload to a new machine", slog.String("workload_id", id), slog.Any("err", err))
+		w.replaceActiveAgent(id)
+		resp.Replaced = append(resp.Replaced, id)
+	}
+
+	w.log.Info("Agent update completed",
+		slog.String("hash", hashString),
+		slog.Int("updated", len(resp.Updated)),
+		slog.Int("replaced", len(resp.Replaced)),
+	)
+
+	return resp, nil
+}
+
+// Returns the agents to which an update is pushed, keyed by id. An updated agent no longer holds
+// any artifact it prepared, so idle agents are no longer considered prewarmed
+func (w *WorkloadManager) updatableAgents() (active, pending map[string]*agentapi.AgentClient) {
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
+	active = make(map[string]*agentapi.AgentClient, len(w.activeAgents))
+	for id, agentClient := range w.activeAgents {
+		active[id] = agentClient
+	}
+
+	pending = make(map[string]*agentapi.AgentClient, len(w.pendingAgents))
+	for id, agentClient := range w.pendingAgents {
+		pending[id] = agentClient
+		delete(w.prewarmed, id)
+	}
+
+	return active, pending
+}
+
+// Removes the given idle agent from the pool and stops it, leaving the process manager to
+// replenish the pool with a new machine
+func (w *WorkloadManager) replaceIdleAgent(id string) {
+	w.poolMutex.Lock()
+	agentClient, ok := w.pendingAgents[id]
+	if ok {
+		_ = agentClient.Drain()
+		delete(w.pendingAgents, id)
+		delete(w.stopMutex, id)
+		delete(w.handshakes, id)
+	}
+	w.poolMutex.Unlock()
+
+	if !ok {
+		// the agent was deployed to or stopped while the update was in progress
+		return
+	}
+
+	err := w.procMan.StopProcess(id)
+	if err != nil {
+		w.log.Warn("Failed to stop idle agent", slog.String("workload_id", id), slog.Any("err", err))
+	}
+}
+
+// Stops the workload running on the given agent and redeploys it to a new agent
+func (w *WorkloadManager) replaceActiveAgent(id string) {
+	deployRequest, err := w.procMan.Lookup(id)
+	if err != nil || deployRequest == nil {
+		// the workload was stopped while the update was in progress
+		return
+	}
+
+	err = w.StopWorkload(id, true)
+	if err != nil {
+		w.log.Warn("Failed to stop workload", slog.String("workload_id", id), slog.Any("err", err))
+	}
+
+	err = w.requestRedeploy(deployRequest)
+	if err != nil {
+		w.log.Error("Failed to redeploy workload", slog.String("workload_id", id), slog.Any("err", err))
+	}
+}
@@ -0,0 +1,56 @@
+package nexnode
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/nats-io/nkeys"
+	controlapi "github.com/synadia-io/nex/control-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+func TestUpdateAgentsRequiresAllowAgentUpdates(t *testing.T) {
+	w := &WorkloadManager{
+		config: &models.NodeConfiguration{},
+		log:    slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+	}
+
+	request, err := controlapi.NewAgentUpdateRequest("nats://agents/nex-agent", "c2lnbmF0dXJl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.UpdateAgents(request)
+	if err == nil {
+		t.Fatal("expected agent update to be rejected when agent updates are not allowed")
+	}
+}
+
+func TestAgentBinarySignature(t *testing.T) {
+	kp, _ := nkeys.CreateAccount()
+	pub, _ := kp.PublicKey()
+
+	binary := []byte("nex-agent")
+	signature, err := controlapi.SignAgentBinary(kp, binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controlapi.VerifyAgentBinary(pub, binary, signature)
+	if err != nil {
+		t.Fatalf("expected signature to verify: %s", err)
+	}
+
+	err = controlapi.VerifyAgentBinary(pub, []byte("tampered"), signature)
+	if err == nil {
+		t.Fatal("expected signature of tampered binary to fail verification")
+	}
+
+	other, _ := nkeys.CreateAccount()
+	otherPub, _ := other.PublicKey()
+	err = controlapi.VerifyAgentBinary(otherPub, binary, signature)
+	if err == nil {
+		t.Fatal("expected signature to fail verification against another key")
+	}
+}
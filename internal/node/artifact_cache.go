@@ -0,0 +1,63 @@
+package nexnode
+
+import (
+	"log/slog"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go"
+	"github.com/google/uuid"
+	controlapi "github.com/synadia-io/nex/control-api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// The node's internal cache of artifacts staged by pre-pulls and prewarms
+	artifactCacheNode = "node"
+	// The artifact staged on an idle agent ahead of deployment
+	artifactCacheAgent = "agent"
+)
+
+// Records a hit or miss of the given artifact cache when deploying a workload, publishing
+// an event on a miss so that cache effectiveness can be observed
+func (w *WorkloadManager) recordArtifactCache(cache, namespace, name, workloadType string, hit bool) {
+	attrs := metric.WithAttributes(
+		attribute.String("cache", cache),
+		attribute.String("namespace", namespace),
+		attribute.String("workload_type", workloadType),
+	)
+
+	if hit {
+		w.t.ArtifactCacheHits.Add(w.ctx, 1, attrs)
+		return
+	}
+
+	w.t.ArtifactCacheMisses.Add(w.ctx, 1, attrs)
+
+	w.log.Debug("Workload artifact cache miss",
+		slog.String("cache", cache),
+		slog.String("namespace", namespace),
+		slog.String("workload", name),
+		slog.String("workload_type", workloadType),
+	)
+
+	_ = w.publishArtifactCacheMiss(cache, namespace, name, workloadType)
+}
+
+func (w *WorkloadManager) publishArtifactCacheMiss(cache, namespace, name, workloadType string) error {
+	evt := controlapi.ArtifactCacheMissEvent{
+		Name:         name,
+		WorkloadType: workloadType,
+		Cache:        cache,
+	}
+
+	cloudevent := cloudevents.NewEvent()
+	cloudevent.SetSource(w.publicKey)
+	cloudevent.SetID(uuid.NewString())
+	cloudevent.SetTime(time.Now().UTC())
+	cloudevent.SetType(controlapi.ArtifactCacheMissEventType)
+	cloudevent.SetDataContentType(cloudevents.ApplicationJSON)
+	_ = cloudevent.SetData(evt)
+
+	return w.publishCloudEvent(namespace, cloudevent)
+}
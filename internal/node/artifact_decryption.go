@@ -0,0 +1,60 @@
+package nexnode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+// Returns the means by which the agent is to decrypt the given workload artifact, or nil if the
+// artifact carries no encryption marker and is therefore stored in plaintext. Fails if the artifact
+// is encrypted with an unsupported algorithm or with a key unknown to this node or which the
+// workload is not authorized to use
+func (m *WorkloadManager) artifactDecryption(namespace string, request *controlapi.DeployRequest, info *nats.ObjectInfo) (*agentapi.ArtifactDecryption, error) {
+	if info == nil {
+		return nil, nil
+	}
+
+	algorithm, ok := info.Metadata[controlapi.ArtifactEncryptionMetadata]
+	if !ok {
+		return nil, nil
+	}
+
+	if algorithm != controlapi.ArtifactEncryptionAES256GCM {
+		return nil, artifactDecryptionError("unsupported artifact encryption algorithm: %s", algorithm)
+	}
+
+	hash := info.Metadata[controlapi.ArtifactHashMetadata]
+	if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+		return nil, artifactDecryptionError("encrypted artifact does not carry a valid sha256 hash")
+	}
+
+	keyName := info.Metadata[controlapi.ArtifactKeyMetadata]
+	key, ok := m.config.ArtifactDecryptionKeys[keyName]
+	if !ok {
+		return nil, artifactDecryptionError("unknown artifact decryption key: %s", keyName)
+	}
+
+	if !key.Authorizes(namespace, request.DecodedClaims.Issuer) {
+		return nil, artifactDecryptionError("workload is not authorized to use artifact decryption key %s", keyName)
+	}
+
+	raw, err := key.Decode()
+	if err != nil {
+		return nil, artifactDecryptionError("invalid artifact decryption key %s: %s", keyName, err)
+	}
+
+	return &agentapi.ArtifactDecryption{
+		Algorithm: algorithm,
+		Key:       raw,
+		Hash:      hash,
+	}, nil
+}
+
+func artifactDecryptionError(format string, args ...any) *controlapi.DeployError {
+	return controlapi.NewDeployError(controlapi.DeployErrorUnauthorized, controlapi.DeployReasonArtifactDecryption, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,70 @@
+package nexnode
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+func TestArtifactDecryptionRequiresAuthorizedKey(t *testing.T) {
+	key := make([]byte, controlapi.ArtifactKeySize)
+	_, _ = rand.Read(key)
+
+	w := &WorkloadManager{
+		config: &models.NodeConfiguration{
+			ArtifactDecryptionKeys: map[string]models.ArtifactDecryptionKey{
+				"payments": {
+					Key:        base64.StdEncoding.EncodeToString(key),
+					Namespaces: []string{"payments"},
+				},
+			},
+		},
+	}
+
+	artifact := []byte("workload")
+	ciphertext, metadata, err := controlapi.EncryptArtifact("payments", key, artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := &nats.ObjectInfo{ObjectMeta: nats.ObjectMeta{Metadata: metadata}}
+	request := &controlapi.DeployRequest{DecodedClaims: jwt.GenericClaims{}}
+
+	decryption, err := w.artifactDecryption("payments", request, &nats.ObjectInfo{})
+	if err != nil || decryption != nil {
+		t.Fatalf("expected artifact without encryption marker to be treated as plaintext: %v", err)
+	}
+
+	decryption, err = w.artifactDecryption("payments", request, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := controlapi.DecryptArtifact(decryption.Key, ciphertext)
+	if err != nil || !bytes.Equal(decrypted, artifact) {
+		t.Fatalf("expected artifact to decrypt with resolved key: %v", err)
+	}
+
+	if decryption.Hash != metadata[controlapi.ArtifactHashMetadata] {
+		t.Fatalf("expected hash of decrypted artifact, got %s", decryption.Hash)
+	}
+
+	var deployErr *controlapi.DeployError
+	_, err = w.artifactDecryption("default", request, info)
+	if !errors.As(err, &deployErr) || deployErr.Reason != controlapi.DeployReasonArtifactDecryption {
+		t.Fatalf("expected workload in another namespace to be refused the key: %v", err)
+	}
+
+	metadata[controlapi.ArtifactKeyMetadata] = "unknown"
+	_, err = w.artifactDecryption("payments", request, info)
+	if !errors.As(err, &deployErr) || deployErr.Code != controlapi.DeployErrorUnauthorized {
+		t.Fatalf("expected unknown key to be refused: %v", err)
+	}
+}
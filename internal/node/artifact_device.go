@@ -0,0 +1,54 @@
+package nexnode
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/node/processmanager"
+)
+
+// Free space, in MiB, reserved in artifact images for filesystem metadata
+const artifactImageOverheadMib = 4
+
+// Returns the process manager as an artifact device attacher if workload artifacts can be attached
+// to agents as block devices on this node, or nil if artifacts must be staged in the internal cache
+func (w *WorkloadManager) artifactDevices() processmanager.ArtifactDeviceAttacher {
+	attacher, ok := w.procMan.(processmanager.ArtifactDeviceAttacher)
+	if !ok || !attacher.SupportsArtifactDevices() {
+		return nil
+	}
+
+	return attacher
+}
+
+// Path at which the image for the artifact with the given hash is staged until it is attached
+func stagedArtifactImagePath(hash string) string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("nex-artifact-%s.ext4", hash))
+}
+
+// Builds a read-only ext4 image at the given path containing the workload artifact as an
+// executable file, suitable for attaching to an agent as a block device
+func buildArtifactImage(workload []byte, imagePath string) error {
+	stagingDir, err := os.MkdirTemp("", "nex-artifact-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stagingDir)
+
+	err = os.WriteFile(filepath.Join(stagingDir, agentapi.ArtifactDeviceWorkloadFile), workload, 0755)
+	if err != nil {
+		return err
+	}
+
+	sizeMib := len(workload)/(1024*1024) + len(workload)/(10*1024*1024) + artifactImageOverheadMib
+	out, err := exec.Command("mkfs.ext4", "-q", "-F", "-d", stagingDir, imagePath, fmt.Sprintf("%dM", sizeMib)).CombinedOutput()
+	if err != nil {
+		_ = os.Remove(imagePath)
+		return fmt.Errorf("failed to build artifact image: %s: %s", err, string(out))
+	}
+
+	return nil
+}
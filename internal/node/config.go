@@ -3,12 +3,16 @@ package nexnode
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
 	"github.com/synadia-io/nex/internal/models"
+	"github.com/synadia-io/nex/internal/node/processmanager"
 )
 
 // Reads the node configuration from the specified configuration file path
@@ -28,8 +32,18 @@ func LoadNodeConfiguration(configFilepath string) (*models.NodeConfiguration, er
 		config.WorkloadTypes = models.DefaultWorkloadTypes
 	}
 
-	if strings.EqualFold(runtime.GOOS, "windows") && !config.NoSandbox {
-		return nil, errors.New("windows host must be configured to run in no sandbox mode")
+	// the default internal node host is the gateway of the default, IPv4, CNI subnet, so the gateway
+	// of an IPv6 subnet is used in its place unless another host is configured
+	if config.CNIUsesIPv6() && config.InternalNodeHost != nil && *config.InternalNodeHost == models.DefaultInternalNodeHost {
+		gateway, err := config.ResolveCNIGateway()
+		if err != nil {
+			return nil, err
+		}
+		config.InternalNodeHost = agentapi.StringOrNil(gateway.String())
+	}
+
+	if !sandboxSupported() && !config.NoSandbox && !config.NoSandboxFallback {
+		return nil, fmt.Errorf("%s host must be configured to run in no sandbox mode", runtime.GOOS)
 	}
 
 	if config.KernelFilepath == "" && config.DefaultResourceDir != "" {
@@ -50,3 +64,23 @@ func LoadNodeConfiguration(configFilepath string) (*models.NodeConfiguration, er
 
 	return &config, nil
 }
+
+// Returns true if workloads can be sandboxed in firecracker VMs on the host's platform; nodes on
+// other platforms must run in no sandbox mode
+func sandboxSupported() bool {
+	return strings.EqualFold(runtime.GOOS, "linux")
+}
+
+// Falls back to running in no sandbox mode when the configuration allows it and firecracker is
+// unavailable on this host, so that the node starts rather than refusing to run any workloads
+func (n *Node) applyNoSandboxFallback() {
+	if n.config.NoSandbox || !n.config.NoSandboxFallback {
+		return
+	}
+
+	err := processmanager.CheckFirecrackerAvailable(n.config)
+	if err != nil {
+		n.log.Warn("⚠️  Firecracker is unavailable on this host; falling back to no sandbox mode", slog.Any("err", err))
+		n.config.NoSandbox = true
+	}
+}
@@ -1,7 +1,16 @@
 package nexnode
 
 import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/models"
 )
 
 func TestNodeConfigResolution(t *testing.T) {
@@ -27,3 +36,160 @@ func TestNodeConfigResolution(t *testing.T) {
 		t.Fatal("in custom config http service should be disabled")
 	}
 }
+
+func TestResolveConnectionName(t *testing.T) {
+	config := &models.NodeConfiguration{
+		Tags: map[string]string{"zone": "b", "region": "us"},
+	}
+
+	name := config.ResolveConnectionName("nex", "node", "NABC")
+	if name != "nex-node-NABC [region=us,zone=b]" {
+		t.Fatalf("unexpected connection name: %s", name)
+	}
+
+	config.NatsConnectionNamePrefix = "edge"
+	config.Tags = nil
+	name = config.ResolveConnectionName("nex", "hostservices", "NABC")
+	if name != "edge-hostservices-NABC" {
+		t.Fatalf("unexpected connection name: %s", name)
+	}
+}
+
+func TestNodeConfigRejectsUnsupportedWorkloadTypes(t *testing.T) {
+	workloadTypesError := func(config models.NodeConfiguration) string {
+		config.Validate()
+		for _, err := range config.Errors {
+			if strings.HasPrefix(err.Error(), "workload types not supported") {
+				return err.Error()
+			}
+		}
+		return ""
+	}
+
+	config := models.DefaultNodeConfiguration()
+	if err := workloadTypesError(config); err != "" {
+		t.Fatalf("expected the default workload types to be supported, got %s", err)
+	}
+
+	unsupported := "oci, python"
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		unsupported = "v8, oci, python"
+	}
+
+	config.WorkloadTypes = []string{"elf", "v8", "oci", "python"}
+	expected := fmt.Sprintf("workload types not supported on %s/%s: %s", runtime.GOOS, runtime.GOARCH, unsupported)
+	if err := workloadTypesError(config); err != expected {
+		t.Fatalf("expected %q, got %q", expected, err)
+	}
+
+	// workload types which are not built in may be provided by agent plugins
+	config.AgentPluginPath = t.TempDir()
+	if err := workloadTypesError(config); err == "" || strings.Contains(err, "python") {
+		t.Fatalf("expected only built-in workload types to be rejected, got %q", err)
+	}
+}
+
+func TestNodeConfigSupportsIPv6Subnets(t *testing.T) {
+	addressErrors := func(config models.NodeConfiguration) []string {
+		config.Validate()
+
+		errs := make([]string, 0)
+		for _, err := range config.Errors {
+			if strings.Contains(err.Error(), "internal node") {
+				errs = append(errs, err.Error())
+			}
+		}
+		return errs
+	}
+
+	config := models.DefaultNodeConfiguration()
+	config.CNI.Subnet = agentapi.StringOrNil("fd00:7::/64")
+	config.InternalNodeHost = agentapi.StringOrNil("fd00:7::1")
+	if errs := addressErrors(config); len(errs) > 0 {
+		t.Fatalf("expected an IPv6 internal node host in an IPv6 subnet to be accepted, got %v", errs)
+	}
+	if !config.CNIUsesIPv6() {
+		t.Fatal("expected an IPv6 subnet to be reported")
+	}
+
+	config.InternalNodeBindHost = agentapi.StringOrNil("::")
+	if errs := addressErrors(config); len(errs) > 0 {
+		t.Fatalf("expected an IPv6 bind host on all interfaces to be accepted, got %v", errs)
+	}
+
+	config.InternalNodeBindHost = agentapi.StringOrNil("0.0.0.0")
+	if errs := addressErrors(config); len(errs) != 1 || errs[0] != "internal node bind host 0.0.0.0 is an IPv4 address but the CNI subnet fd00:7::/64 is IPv6" {
+		t.Fatalf("expected an IPv4 bind host to be rejected, got %v", errs)
+	}
+
+	config.InternalNodeBindHost = nil
+	config.InternalNodeHost = agentapi.StringOrNil("fd00:8::1")
+	if errs := addressErrors(config); len(errs) != 1 || errs[0] != "internal node host must be in the CNI subnet" {
+		t.Fatalf("expected an internal node host outside the subnet to be rejected, got %v", errs)
+	}
+
+	config.CNI.Subnet = agentapi.StringOrNil(models.DefaultCNISubnet)
+	config.InternalNodeHost = agentapi.StringOrNil("fd00:7::1")
+	if errs := addressErrors(config); len(errs) != 1 || errs[0] != "internal node host fd00:7::1 is an IPv6 address but the CNI subnet 192.168.127.0/24 is IPv4" {
+		t.Fatalf("expected a mixed-family configuration to be rejected, got %v", errs)
+	}
+
+	// the internal node host defaults to the gateway of an IPv6 subnet
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{"default_resource_dir": "/tmp", "cni": {"subnet": "fd00:7::/64"}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadNodeConfiguration(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *loaded.InternalNodeHost != "fd00:7::1" {
+		t.Fatalf("expected the internal node host to default to the subnet's gateway, got %s", *loaded.InternalNodeHost)
+	}
+}
+
+func TestNodeConfigRequiresPoolHeadroomForLowWatermark(t *testing.T) {
+	watermarkErrors := func(config models.NodeConfiguration) []string {
+		config.Validate()
+
+		errs := make([]string, 0)
+		for _, err := range config.Errors {
+			if strings.Contains(err.Error(), "low watermark") {
+				errs = append(errs, err.Error())
+			}
+		}
+		return errs
+	}
+
+	config := models.DefaultNodeConfiguration()
+	config.MachinePoolLowWatermark = 1
+	if errs := watermarkErrors(config); len(errs) != 1 {
+		t.Fatalf("expected a low watermark without pool headroom to be rejected, got %v", errs)
+	}
+
+	config.MachinePoolMax = config.MachinePoolSize + 2
+	if errs := watermarkErrors(config); len(errs) > 0 {
+		t.Fatalf("expected a low watermark with pool headroom to be accepted, got %v", errs)
+	}
+}
+
+func TestNoSandboxFallbackWhenFirecrackerIsUnavailable(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	config := models.DefaultNodeConfiguration()
+	config.KernelFilepath = filepath.Join(t.TempDir(), "vmlinux")
+
+	node := &Node{config: &config, log: log}
+	node.applyNoSandboxFallback()
+	if config.NoSandbox {
+		t.Fatal("expected the node to remain sandboxed unless the fallback is enabled")
+	}
+
+	config.NoSandboxFallback = true
+	node.applyNoSandboxFallback()
+	if !config.NoSandbox {
+		t.Fatal("expected the node to fall back to no sandbox mode when firecracker is unavailable")
+	}
+}
@@ -1,6 +1,7 @@
 package nexnode
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -15,8 +16,17 @@ import (
 	"github.com/pkg/errors"
 	controlapi "github.com/synadia-io/nex/control-api"
 	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/node/observability"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Space reserved within the maximum payload for the envelope wrapping a metrics snapshot
+const metricsSnapshotEnvelopeOverhead = 1024
+
 // The API listener is the command and control interface for the node server
 type ApiListener struct {
 	node  *Node
@@ -25,6 +35,12 @@ type ApiListener struct {
 	start time.Time
 	xk    nkeys.KeyPair
 
+	// Nonces of accepted signed deploy requests
+	nonces *requestNonces
+
+	// Event tokens issued by the node
+	eventTokens *eventTokens
+
 	subz []*nats.Subscription
 }
 
@@ -53,12 +69,14 @@ func NewApiListener(log *slog.Logger, mgr *WorkloadManager, node *Node) *ApiList
 	log.Info("Use this key as the recipient for encrypted run requests", slog.String("public_xkey", xkPub))
 
 	return &ApiListener{
-		mgr:   mgr,
-		log:   log,
-		xk:    kp,
-		start: time.Now().UTC(),
-		node:  node,
-		subz:  make([]*nats.Subscription, 0),
+		mgr:         mgr,
+		log:         log,
+		xk:          kp,
+		start:       time.Now().UTC(),
+		node:        node,
+		nonces:      newRequestNonces(),
+		eventTokens: newEventTokens(),
+		subz:        make([]*nats.Subscription, 0),
 	}
 }
 
@@ -133,12 +151,102 @@ func (api *ApiListener) Start() error {
 	}
 	api.subz = append(api.subz, sub)
 
+	sub, err = api.node.nc.Subscribe(controlapi.APIPrefix+".MIGRATE.*."+api.PublicKey(), api.handleMigrate)
+	if err != nil {
+		api.log.Error("Failed to subscribe to migrate subject", slog.Any("err", err), slog.String("id", api.PublicKey()))
+	}
+	api.subz = append(api.subz, sub)
+
 	sub, err = api.node.nc.Subscribe(controlapi.APIPrefix+".LAMEDUCK."+api.PublicKey(), api.handleLameDuck)
 	if err != nil {
 		api.log.Error("Failed to subscribe to lame duck subject", slog.Any("error", err), slog.String("id", api.PublicKey()))
 	}
 	api.subz = append(api.subz, sub)
 
+	sub, err = api.node.nc.Subscribe(controlapi.APIPrefix+".TRAFFIC.*."+api.PublicKey(), api.handleTrafficSplit)
+	if err != nil {
+		api.log.Error("Failed to subscribe to traffic subject", slog.Any("err", err), slog.String("id", api.PublicKey()))
+	}
+	api.subz = append(api.subz, sub)
+
+	sub, err = api.node.nc.Subscribe(controlapi.APIPrefix+".PREWARM.*."+api.PublicKey(), api.handlePrewarm)
+	if err != nil {
+		api.log.Error("Failed to subscribe to prewarm subject", slog.Any("err", err), slog.String("id", api.PublicKey()))
+	}
+	api.subz = append(api.subz, sub)
+
+	sub, err = api.node.nc.Subscribe(controlapi.APIPrefix+".METRICS."+api.PublicKey(), api.handleMetricsSnapshot)
+	if err != nil {
+		api.log.Error("Failed to subscribe to metrics subject", slog.Any("err", err), slog.String("id", api.PublicKey()))
+	}
+	api.subz = append(api.subz, sub)
+
+	sub, err = api.node.nc.Subscribe(controlapi.APIPrefix+".STOPSELECTOR."+api.PublicKey(), api.handleStopSelector)
+	if err != nil {
+		api.log.Error("Failed to subscribe to stop selector subject", slog.Any("err", err), slog.String("id", api.PublicKey()))
+	}
+	api.subz = append(api.subz, sub)
+
+	sub, err = api.node.nc.Subscribe(controlapi.APIPrefix+".POOL."+api.PublicKey(), api.handlePoolTarget)
+	if err != nil {
+		api.log.Error("Failed to subscribe to pool subject", slog.Any("err", err), slog.String("id", api.PublicKey()))
+	}
+	api.subz = append(api.subz, sub)
+
+	sub, err = api.node.nc.Subscribe(controlapi.APIPrefix+".HISTORY.*."+api.PublicKey(), api.handleEventHistory)
+	if err != nil {
+		api.log.Error("Failed to subscribe to history subject", slog.Any("err", err), slog.String("id", api.PublicKey()))
+	}
+	api.subz = append(api.subz, sub)
+
+	sub, err = api.node.nc.Subscribe(controlapi.APIPrefix+".INVENTORY."+api.PublicKey(), api.handleInventory)
+	if err != nil {
+		api.log.Error("Failed to subscribe to inventory subject", slog.Any("err", err), slog.String("id", api.PublicKey()))
+	}
+	api.subz = append(api.subz, sub)
+
+	sub, err = api.node.nc.Subscribe(controlapi.APIPrefix+".AGENTUPDATE."+api.PublicKey(), api.handleAgentUpdate)
+	if err != nil {
+		api.log.Error("Failed to subscribe to agent update subject", slog.Any("err", err), slog.String("id", api.PublicKey()))
+	}
+	api.subz = append(api.subz, sub)
+
+	sub, err = api.node.nc.Subscribe(controlapi.APIPrefix+".EXPORT."+api.PublicKey(), api.handleExport)
+	if err != nil {
+		api.log.Error("Failed to subscribe to export subject", slog.Any("err", err), slog.String("id", api.PublicKey()))
+	}
+	api.subz = append(api.subz, sub)
+
+	sub, err = api.node.nc.Subscribe(controlapi.APIPrefix+".IMPORT."+api.PublicKey(), api.handleImport)
+	if err != nil {
+		api.log.Error("Failed to subscribe to import subject", slog.Any("err", err), slog.String("id", api.PublicKey()))
+	}
+	api.subz = append(api.subz, sub)
+
+	sub, err = api.node.nc.Subscribe(controlapi.APIPrefix+".EVENTTOKEN.*."+api.PublicKey(), api.handleEventToken)
+	if err != nil {
+		api.log.Error("Failed to subscribe to event token subject", slog.Any("err", err), slog.String("id", api.PublicKey()))
+	}
+	api.subz = append(api.subz, sub)
+
+	sub, err = api.node.nc.Subscribe(controlapi.APIPrefix+".EVENTTOKENS.*."+api.PublicKey(), api.handleEventTokens)
+	if err != nil {
+		api.log.Error("Failed to subscribe to event tokens subject", slog.Any("err", err), slog.String("id", api.PublicKey()))
+	}
+	api.subz = append(api.subz, sub)
+
+	sub, err = api.node.nc.Subscribe(controlapi.APIPrefix+".LOST.*."+api.PublicKey(), api.handleLostWorkloads)
+	if err != nil {
+		api.log.Error("Failed to subscribe to lost workloads subject", slog.Any("err", err), slog.String("id", api.PublicKey()))
+	}
+	api.subz = append(api.subz, sub)
+
+	sub, err = api.node.nc.Subscribe(controlapi.APIPrefix+".CAPTUREDLOGS.*."+api.PublicKey(), api.handleCapturedLogs)
+	if err != nil {
+		api.log.Error("Failed to subscribe to captured logs subject", slog.Any("err", err), slog.String("id", api.PublicKey()))
+	}
+	api.subz = append(api.subz, sub)
+
 	api.log.Info("NATS execution engine awaiting commands", slog.String("id", api.PublicKey()), slog.String("version", VERSION))
 	return nil
 }
@@ -187,6 +295,8 @@ func (api *ApiListener) handleStop(m *nats.Msg) {
 	if err != nil {
 		api.log.Error("Failed to stop workload", slog.Any("err", err))
 		respondFail(controlapi.StopResponseType, m, fmt.Sprintf("Failed to stop workload: %s", err))
+	} else {
+		api.mgr.teardownKeyValueBuckets(deployRequest)
 	}
 
 	res := controlapi.NewEnvelope(controlapi.StopResponseType, controlapi.StopResponse{
@@ -203,37 +313,67 @@ func (api *ApiListener) handleStop(m *nats.Msg) {
 	}
 }
 
-func (api *ApiListener) handleDeploy(m *nats.Msg) {
+func (api *ApiListener) handleMigrate(m *nats.Msg) {
 	namespace, err := extractNamespace(m.Subject)
 	if err != nil {
-		api.log.Error("Invalid subject for workload deployment", slog.Any("err", err))
-		respondFail(controlapi.RunResponseType, m, "Invalid subject for workload deployment")
+		api.log.Error("Invalid subject for workload migration", slog.Any("err", err))
+		respondFail(controlapi.MigrateResponseType, m, "Invalid subject for workload migration")
 		return
 	}
 
-	if api.node.IsLameDuck() {
-		respondFail(controlapi.RunResponseType, m, "Node is in lame duck mode. Workload deploy request rejected")
+	var request controlapi.MigrateRequest
+	err = json.Unmarshal(m.Data, &request)
+	if err != nil {
+		api.log.Error("Failed to deserialize migrate request", slog.Any("err", err))
+		respondFail(controlapi.MigrateResponseType, m, fmt.Sprintf("Unable to deserialize migrate request: %s", err))
 		return
 	}
 
-	var request controlapi.DeployRequest
-	err = json.Unmarshal(m.Data, &request)
+	deployRequest, _ := api.mgr.LookupWorkload(request.WorkloadId)
+	if deployRequest == nil || *deployRequest.Namespace != namespace {
+		api.log.Error("Migrate request: no such workload", slog.String("workload_id", request.WorkloadId))
+		respondFail(controlapi.MigrateResponseType, m, "No such workload") // do not expose ID existence to avoid existence probes
+		return
+	}
+
+	request.SourceNode = api.PublicKey()
+	err = request.Validate(&deployRequest.DecodedClaims)
 	if err != nil {
-		api.log.Error("Failed to deserialize deploy request", slog.Any("err", err))
-		respondFail(controlapi.RunResponseType, m, fmt.Sprintf("Unable to deserialize deploy request: %s", err))
+		api.log.Error("Failed to validate migrate request", slog.Any("err", err))
+		respondFail(controlapi.MigrateResponseType, m, fmt.Sprintf("Invalid migrate request: %s", err))
+		return
+	}
+
+	resp, err := api.migrateWorkload(request.WorkloadId, deployRequest, request.TargetNode)
+	if err != nil {
+		api.log.Error("Failed to migrate workload", slog.String("workload_id", request.WorkloadId), slog.Any("err", err))
+		respondFail(controlapi.MigrateResponseType, m, fmt.Sprintf("Failed to migrate workload: %s", err))
 		return
 	}
 
-	if !slices.Contains(api.node.config.WorkloadTypes, *request.WorkloadType) {
-		api.log.Error("This node does not support the given workload type", slog.String("workload_type", *request.WorkloadType))
-		respondFail(controlapi.RunResponseType, m, fmt.Sprintf("Unsupported workload type on this node: %s", *request.WorkloadType))
+	res := controlapi.NewEnvelope(controlapi.MigrateResponseType, resp, nil)
+	raw, err := json.Marshal(res)
+	if err != nil {
+		api.log.Error("Failed to serialize response", slog.Any("error", err))
+		respondFail(controlapi.MigrateResponseType, m, "Serialization failure")
+	} else {
+		_ = m.Respond(raw)
+	}
+}
+
+func (api *ApiListener) handleDeploy(m *nats.Msg) {
+	namespace, err := extractNamespace(m.Subject)
+	if err != nil {
+		api.log.Error("Invalid subject for workload deployment", slog.Any("err", err))
+		api.respondDeployFail(m, controlapi.NewDeployError(controlapi.DeployErrorInvalidRequest, controlapi.DeployReasonMalformedRequest, "Invalid subject for workload deployment"))
 		return
 	}
 
-	if len(request.TriggerSubjects) > 0 && (!strings.EqualFold(*request.WorkloadType, "v8") &&
-		!strings.EqualFold(*request.WorkloadType, "wasm")) { // FIXME -- workload type comparison
-		api.log.Error("Workload type does not support trigger subject registration", slog.String("trigger_subjects", *request.WorkloadType))
-		respondFail(controlapi.RunResponseType, m, fmt.Sprintf("Unsupported workload type for trigger subject registration: %s", *request.WorkloadType))
+	var request controlapi.DeployRequest
+	err = json.Unmarshal(m.Data, &request)
+	if err != nil {
+		api.log.Error("Failed to deserialize deploy request", slog.Any("err", err))
+		api.respondDeployFail(m, controlapi.NewDeployError(controlapi.DeployErrorInvalidRequest, controlapi.DeployReasonMalformedRequest, fmt.Sprintf("Unable to deserialize deploy request: %s", err)))
 		return
 	}
 
@@ -241,51 +381,177 @@ func (api *ApiListener) handleDeploy(m *nats.Msg) {
 	if err != nil {
 		publicKey, _ := api.xk.PublicKey()
 		api.log.Error("Failed to decrypt environment for deploy request", slog.String("public_key", publicKey), slog.Any("err", err))
-		respondFail(controlapi.RunResponseType, m, fmt.Sprintf("Failed to decrypt environment for deploy request: %s", err))
+		api.respondDeployFail(m, controlapi.NewDeployError(controlapi.DeployErrorUnauthorized, controlapi.DeployReasonEnvironmentDecryption, fmt.Sprintf("Failed to decrypt environment for deploy request: %s", err)))
 		return
 	}
 
 	decodedClaims, err := request.Validate()
 	if err != nil {
 		api.log.Error("Invalid deploy request", slog.Any("err", err))
-		respondFail(controlapi.RunResponseType, m, fmt.Sprintf("Invalid deploy request: %s", err))
+		api.respondDeployFail(m, invalidRequestError(err, "Invalid deploy request"))
 		return
 	}
 
 	request.DecodedClaims = *decodedClaims
-	if !validateIssuer(request.DecodedClaims.Issuer, api.node.config.ValidIssuers) {
-		err := fmt.Errorf("invalid workload issuer: %s", request.DecodedClaims.Issuer)
-		api.log.Error("Workload validation failed", slog.Any("err", err))
-		respondFail(controlapi.RunResponseType, m, fmt.Sprintf("%s", err))
+
+	err = api.verifyRequestSignature(&request)
+	if err != nil {
+		api.log.Error("Deploy request signature rejected", slog.Any("err", err))
+		api.respondDeployFail(m, controlapi.NewDeployError(controlapi.DeployErrorUnauthorized, controlapi.DeployReasonInvalidSignature, fmt.Sprintf("Invalid deploy request signature: %s", err)))
+		return
+	}
+
+	denied := deniedTriggerSubjects(&request)
+	if len(denied) > 0 {
+		api.log.Error("Deploy request trigger subjects not allowed", slog.Any("trigger_subjects", denied))
+		deployErr := controlapi.NewDeployError(controlapi.DeployErrorUnauthorized, controlapi.DeployReasonTriggerSubjectDenied, fmt.Sprintf("Trigger subjects not allowed for workload: %s", strings.Join(denied, ", ")))
+		deployErr.Field = "trigger_subjects"
+		api.respondDeployFail(m, deployErr)
+		return
+	}
+
+	unsatisfied := api.unsatisfiedConstraints(&request)
+	if len(unsatisfied) > 0 {
+		unschedulable := &controlapi.UnschedulableError{
+			NodeId:      api.PublicKey(),
+			Constraints: unsatisfied,
+		}
+		api.log.Error("Workload deploy request rejected", slog.Any("err", unschedulable))
+		deployErr := controlapi.NewDeployError(controlapi.DeployErrorUnschedulable, controlapi.DeployReasonConstraintsNotSatisfied, unschedulable.Error())
+		deployErr.Constraints = unsatisfied
+		api.respondDeployFail(m, deployErr)
+
+		if slices.ContainsFunc(unsatisfied, func(c controlapi.UnsatisfiedConstraint) bool {
+			return c.Constraint == controlapi.ConstraintAgentPool
+		}) {
+			api.mgr.recordPoolStarvationRejected()
+		}
+		return
+	}
+
+	err = api.mgr.checkDuplicateWorkload(namespace, request.DecodedClaims.Subject)
+	if err != nil {
+		api.log.Error("Workload deploy request rejected", slog.Any("err", err))
+		api.respondDeployFail(m, deployError(err, controlapi.DeployErrorInternal, controlapi.DeployReasonDeploymentFailed, "Failed to deploy workload"))
+		return
+	}
+
+	if request.GitSource != nil {
+		err = request.GitSource.Validate()
+		if err != nil {
+			api.log.Error("Invalid git source", slog.Any("err", err))
+			api.respondDeployFail(m, invalidRequestError(&controlapi.InvalidFieldError{Field: "git_source", Message: err.Error()}, "Invalid git source"))
+			return
+		}
+	}
+
+	for _, bucket := range request.KeyValueBuckets {
+		err = bucket.Validate()
+		if err != nil {
+			api.log.Error("Invalid key/value bucket", slog.Any("err", err))
+			api.respondDeployFail(m, invalidRequestError(&controlapi.InvalidFieldError{Field: "key_value_buckets", Message: err.Error()}, "Invalid key/value bucket"))
+			return
+		}
+	}
+
+	if len(request.Dependencies) > 0 {
+		// held deployments must not block this subscription, through which their dependencies are deployed
+		go func() {
+			err := api.mgr.awaitDependencies(namespace, request.Dependencies, api.node.config.ResolveDependencyTimeout())
+			if err != nil {
+				api.log.Error("Workload dependencies not satisfied", slog.String("workload", request.DecodedClaims.Subject), slog.Any("err", err))
+				api.respondDeployFail(m, deployError(err, controlapi.DeployErrorDependencies, controlapi.DeployReasonDependencyTimeout, "Failed to deploy workload"))
+				return
+			}
+
+			api.deploy(m, namespace, &request)
+		}()
+		return
+	}
+
+	api.deploy(m, namespace, &request)
+}
+
+// Starts the span covering the deployment of the given request, continuing the trace of the
+// control plane's deploy request if its message carries one
+func (api *ApiListener) startDeploySpan(m *nats.Msg, namespace string, request *controlapi.DeployRequest) (context.Context, trace.Span) {
+	ctx := api.mgr.ctx
+	if request.TraceSamplingRate != nil {
+		ctx = observability.WithTraceSamplingRate(ctx, *request.TraceSamplingRate)
+	}
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(m.Header))
+
+	return api.mgr.t.Tracer.Start(
+		ctx,
+		"workload-deploy",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("name", request.DecodedClaims.Subject),
+			attribute.String("namespace", namespace),
+		))
+}
+
+// Returns the location from which the agent retrieves the artifact of the given deploy request
+// itself, if any; other artifacts are cached by the node for the agent
+func agentArtifactLocation(request *controlapi.DeployRequest) *string {
+	if request.GitSource != nil || !controlapi.ArtifactRetrievedByAgent(request.Location) {
+		return nil
 	}
 
-	numBytes, workloadHash, err := api.mgr.CacheWorkload(&request)
+	return agentapi.StringOrNil(request.Location.String())
+}
+
+// Caches the workload indicated by the given validated deploy request and deploys it to an agent
+func (api *ApiListener) deploy(m *nats.Msg, namespace string, request *controlapi.DeployRequest) {
+	ctx, span := api.startDeploySpan(m, namespace, request)
+	defer span.End()
+
+	numBytes, workloadHash, artifactDecryption, err := api.mgr.CacheWorkload(namespace, request)
 	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		api.log.Error("Failed to cache workload bytes", slog.Any("err", err))
-		respondFail(controlapi.RunResponseType, m, fmt.Sprintf("Failed to cache workload bytes: %s", err))
+		api.respondDeployFail(m, deployError(err, controlapi.DeployErrorArtifact, controlapi.DeployReasonArtifactFetchFailed, "Failed to cache workload bytes"))
 		return
 	}
 
 	deployRequest := &agentapi.DeployRequest{
-		Argv:                 request.Argv,
-		DecodedClaims:        request.DecodedClaims,
-		Description:          request.Description,
-		EncryptedEnvironment: request.Environment,
-		Environment:          request.WorkloadEnvironment,
-		Essential:            request.Essential,
-		Hash:                 *workloadHash,
-		JsDomain:             request.JsDomain,
-		Location:             request.Location,
-		Namespace:            &namespace,
-		RetryCount:           request.RetryCount,
-		RetriedAt:            request.RetriedAt,
-		SenderPublicKey:      request.SenderPublicKey,
-		TargetNode:           request.TargetNode,
-		TotalBytes:           int64(numBytes),
-		TriggerSubjects:      request.TriggerSubjects,
-		WorkloadName:         &request.DecodedClaims.Subject,
-		WorkloadType:         request.WorkloadType, // FIXME-- audit all types for string -> *string, and validate...
-		WorkloadJwt:          request.WorkloadJwt,
+		AllowedTriggerSubjects:     request.AllowedTriggerSubjects,
+		Argv:                       request.Argv,
+		ArtifactBucket:             request.ArtifactBucket,
+		ArtifactDecryption:         artifactDecryption,
+		ArtifactLocation:           agentArtifactLocation(request),
+		CaptureLogs:                request.CaptureLogs,
+		DecodedClaims:              request.DecodedClaims,
+		Dependencies:               request.Dependencies,
+		Description:                request.Description,
+		EncryptedEnvironment:       request.Environment,
+		GitSource:                  request.GitSource,
+		Environment:                request.WorkloadEnvironment,
+		Essential:                  request.Essential,
+		Gid:                        request.Gid,
+		Hash:                       *workloadHash,
+		KeyValueBuckets:            request.KeyValueBuckets,
+		JsDomain:                   request.JsDomain,
+		Location:                   request.Location,
+		MemoryLimitMib:             request.MemoryLimitMib,
+		Resources:                  request.Resources,
+		Namespace:                  &namespace,
+		RetryCount:                 request.RetryCount,
+		RetriedAt:                  request.RetriedAt,
+		SenderPublicKey:            request.SenderPublicKey,
+		StopGracePeriodMillisecond: request.StopGracePeriodMillisecond,
+		TriggerTimeoutMillisecond:  request.TriggerTimeoutMillisecond,
+		Tags:                       request.Tags,
+		TargetNode:                 request.TargetNode,
+		TargetVM:                   request.TargetVM,
+		TraceSamplingRate:          request.TraceSamplingRate,
+		TotalBytes:                 int64(numBytes),
+		TriggerSubjects:            request.TriggerSubjects,
+		Uid:                        request.Uid,
+		WorkingDirectory:           request.WorkingDirectory,
+		WorkloadName:               &request.DecodedClaims.Subject,
+		WorkloadType:               request.WorkloadType, // FIXME-- audit all types for string -> *string, and validate...
+		WorkloadJwt:                request.WorkloadJwt,
 	}
 
 	api.log.
@@ -297,20 +563,21 @@ func (api *ApiListener) handleDeploy(m *nats.Msg) {
 			slog.String("type", *request.WorkloadType),
 		)
 
-	workloadID, err := api.mgr.DeployWorkload(deployRequest)
+	workloadID, err := api.mgr.DeployWorkload(ctx, deployRequest)
 	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		api.log.Error("Failed to deploy workload",
 			slog.String("error", err.Error()),
 		)
-		respondFail(controlapi.RunResponseType, m, fmt.Sprintf("Failed to deploy workload: %s", err))
+		api.respondDeployFail(m, deployError(err, controlapi.DeployErrorInternal, controlapi.DeployReasonDeploymentFailed, "Failed to deploy workload"))
 		return
 	}
 
-	if _, ok := api.mgr.handshakes[*workloadID]; !ok {
+	if !api.mgr.handshakeCompleted(*workloadID) {
 		api.log.Error("Attempted to deploy workload into bad process (no handshake)",
 			slog.String("workload_id", *workloadID),
 		)
-		respondFail(controlapi.RunResponseType, m, "Could not deploy workload, agent pool did not initialize properly")
+		api.respondDeployFail(m, controlapi.NewDeployError(controlapi.DeployErrorAgent, controlapi.DeployReasonAgentNotReady, "Could not deploy workload, agent pool did not initialize properly"))
 		return
 	}
 	workloadName := request.DecodedClaims.Subject
@@ -318,10 +585,11 @@ func (api *ApiListener) handleDeploy(m *nats.Msg) {
 	api.log.Info("Workload deployed", slog.String("workload", workloadName), slog.String("workload_id", *workloadID))
 
 	res := controlapi.NewEnvelope(controlapi.RunResponseType, controlapi.RunResponse{
-		Started: true,
-		Name:    workloadName,
-		Issuer:  request.DecodedClaims.Issuer,
-		ID:      *workloadID, // FIXME-- rename to match
+		Started:      true,
+		Name:         workloadName,
+		Issuer:       request.DecodedClaims.Issuer,
+		ID:           *workloadID, // FIXME-- rename to match
+		LogObjectKey: deployRequest.LogCaptureKey,
 	}, nil)
 
 	raw, err := json.Marshal(res)
@@ -346,7 +614,9 @@ func (api *ApiListener) handlePing(m *nats.Msg) {
 		TargetXkey:      api.PublicXKey(),
 		Uptime:          myUptime(now.Sub(api.start)),
 		RunningMachines: len(machines),
+		MaxWorkloads:    api.node.config.MaxWorkloads,
 		Tags:            api.node.config.Tags,
+		Healthy:         api.node.IsHealthy(),
 	}, nil)
 
 	raw, err := json.Marshal(res)
@@ -403,6 +673,474 @@ func (api *ApiListener) handleWorkloadPing(m *nats.Msg) {
 	// silence if there were no matching machines
 }
 
+func (api *ApiListener) handleTrafficSplit(m *nats.Msg) {
+	namespace, err := extractNamespace(m.Subject)
+	if err != nil {
+		api.log.Error("Invalid subject for traffic split", slog.Any("err", err))
+		respondFail(controlapi.TrafficResponseType, m, "Invalid subject for traffic split")
+		return
+	}
+
+	var request controlapi.TrafficSplitRequest
+	err = json.Unmarshal(m.Data, &request)
+	if err != nil {
+		api.log.Error("Failed to deserialize traffic split request", slog.Any("err", err))
+		respondFail(controlapi.TrafficResponseType, m, fmt.Sprintf("Unable to deserialize traffic split request: %s", err))
+		return
+	}
+
+	err = request.Validate()
+	if err != nil {
+		api.log.Error("Invalid traffic split request", slog.Any("err", err))
+		respondFail(controlapi.TrafficResponseType, m, fmt.Sprintf("Invalid traffic split request: %s", err))
+		return
+	}
+
+	resp, err := api.mgr.SetTrafficSplit(namespace, &request)
+	if err != nil {
+		api.log.Error("Failed to update traffic split", slog.Any("err", err))
+		respondFail(controlapi.TrafficResponseType, m, fmt.Sprintf("Failed to update traffic split: %s", err))
+		return
+	}
+
+	res := controlapi.NewEnvelope(controlapi.TrafficResponseType, resp, nil)
+	raw, err := json.Marshal(res)
+	if err != nil {
+		api.log.Error("Failed to serialize response", slog.Any("error", err))
+		respondFail(controlapi.TrafficResponseType, m, "Serialization failure")
+	} else {
+		_ = m.Respond(raw)
+	}
+}
+
+func (api *ApiListener) handlePrewarm(m *nats.Msg) {
+	namespace, err := extractNamespace(m.Subject)
+	if err != nil {
+		api.log.Error("Invalid subject for prewarm", slog.Any("err", err))
+		respondFail(controlapi.PrewarmResponseType, m, "Invalid subject for prewarm")
+		return
+	}
+
+	if api.node.IsLameDuck() {
+		respondFail(controlapi.PrewarmResponseType, m, "Node is in lame duck mode. Prewarm request rejected")
+		return
+	}
+
+	var request controlapi.PrewarmRequest
+	err = json.Unmarshal(m.Data, &request)
+	if err != nil {
+		api.log.Error("Failed to deserialize prewarm request", slog.Any("err", err))
+		respondFail(controlapi.PrewarmResponseType, m, fmt.Sprintf("Unable to deserialize prewarm request: %s", err))
+		return
+	}
+
+	err = request.Validate()
+	if err != nil {
+		api.log.Error("Invalid prewarm request", slog.Any("err", err))
+		respondFail(controlapi.PrewarmResponseType, m, fmt.Sprintf("Invalid prewarm request: %s", err))
+		return
+	}
+
+	resp, err := api.mgr.PrewarmWorkload(namespace, &request)
+	if err != nil {
+		api.log.Error("Failed to prewarm agents", slog.Any("err", err))
+		respondFail(controlapi.PrewarmResponseType, m, fmt.Sprintf("Failed to prewarm agents: %s", err))
+		return
+	}
+
+	res := controlapi.NewEnvelope(controlapi.PrewarmResponseType, resp, nil)
+	raw, err := json.Marshal(res)
+	if err != nil {
+		api.log.Error("Failed to serialize response", slog.Any("error", err))
+		respondFail(controlapi.PrewarmResponseType, m, "Serialization failure")
+	} else {
+		_ = m.Respond(raw)
+	}
+}
+
+func (api *ApiListener) handleStopSelector(m *nats.Msg) {
+	var request controlapi.StopSelectorRequest
+	err := json.Unmarshal(m.Data, &request)
+	if err != nil {
+		api.log.Error("Failed to deserialize stop selector request", slog.Any("err", err))
+		respondFail(controlapi.SelectorResponseType, m, fmt.Sprintf("Unable to deserialize stop selector request: %s", err))
+		return
+	}
+
+	err = request.Validate()
+	if err != nil {
+		respondFail(controlapi.SelectorResponseType, m, fmt.Sprintf("Invalid stop selector request: %s", err))
+		return
+	}
+
+	stopped, err := api.mgr.StopByTagSelector(request.Selector)
+	if err != nil {
+		api.log.Error("Failed to stop workloads by tag selector", slog.Any("selector", request.Selector), slog.Any("err", err))
+		respondFail(controlapi.SelectorResponseType, m, fmt.Sprintf("Failed to stop workloads by tag selector (%d stopped): %s", stopped, err))
+		return
+	}
+
+	res := controlapi.NewEnvelope(controlapi.SelectorResponseType, controlapi.StopSelectorResponse{
+		NodeId:   api.PublicKey(),
+		Selector: request.Selector,
+		Stopped:  stopped,
+	}, nil)
+	raw, err := json.Marshal(res)
+	if err != nil {
+		api.log.Error("Failed to serialize response", slog.Any("error", err))
+		respondFail(controlapi.SelectorResponseType, m, "Serialization failure")
+	} else {
+		_ = m.Respond(raw)
+	}
+}
+
+func (api *ApiListener) handlePoolTarget(m *nats.Msg) {
+	var request controlapi.PoolTargetRequest
+	if len(m.Data) > 0 {
+		err := json.Unmarshal(m.Data, &request)
+		if err != nil {
+			api.log.Error("Failed to deserialize pool target request", slog.Any("err", err))
+			respondFail(controlapi.PoolResponseType, m, fmt.Sprintf("Unable to deserialize pool target request: %s", err))
+			return
+		}
+	}
+
+	if request.Target != nil {
+		err := api.mgr.SetPoolTarget(*request.Target)
+		if err != nil {
+			api.log.Error("Failed to set pool target", slog.Int("target", *request.Target), slog.Any("err", err))
+			respondFail(controlapi.PoolResponseType, m, fmt.Sprintf("Failed to set pool target: %s", err))
+			return
+		}
+	}
+
+	if request.CreateIntervalMillisecond != nil {
+		interval := time.Duration(*request.CreateIntervalMillisecond) * time.Millisecond
+		err := api.mgr.SetPoolCreateInterval(interval)
+		if err != nil {
+			api.log.Error("Failed to set pool create interval", slog.Duration("interval", interval), slog.Any("err", err))
+			respondFail(controlapi.PoolResponseType, m, fmt.Sprintf("Failed to set pool create interval: %s", err))
+			return
+		}
+	}
+
+	poolMin, poolMax := api.node.config.ResolveMachinePoolBounds()
+	res := controlapi.NewEnvelope(controlapi.PoolResponseType, controlapi.PoolTargetResponse{
+		NodeId: api.PublicKey(),
+		Target: api.mgr.GetPoolTarget(),
+		Min:    poolMin,
+		Max:    poolMax,
+
+		CreateIntervalMillisecond: api.mgr.GetPoolCreateInterval().Milliseconds(),
+	}, nil)
+	raw, err := json.Marshal(res)
+	if err != nil {
+		api.log.Error("Failed to serialize response", slog.Any("error", err))
+		respondFail(controlapi.PoolResponseType, m, "Serialization failure")
+	} else {
+		_ = m.Respond(raw)
+	}
+}
+
+func (api *ApiListener) handleEventHistory(m *nats.Msg) {
+	namespace, err := extractNamespace(m.Subject)
+	if err != nil {
+		api.log.Error("Invalid subject for event history", slog.Any("err", err))
+		respondFail(controlapi.HistoryResponseType, m, "Invalid subject for event history")
+		return
+	}
+
+	var request controlapi.EventHistoryRequest
+	if len(m.Data) > 0 {
+		err = json.Unmarshal(m.Data, &request)
+		if err != nil {
+			api.log.Error("Failed to deserialize event history request", slog.Any("err", err))
+			respondFail(controlapi.HistoryResponseType, m, fmt.Sprintf("Unable to deserialize event history request: %s", err))
+			return
+		}
+	}
+
+	events := api.node.events.replay(namespace, request.Workload, request.EventType, request.Count)
+	maxPayload := int(api.node.nc.MaxPayload())
+	truncated := false
+
+	// drop the oldest events until the response fits in a single message
+	for {
+		res := controlapi.NewEnvelope(controlapi.HistoryResponseType, controlapi.EventHistoryResponse{
+			NodeId:    api.PublicKey(),
+			Events:    events,
+			Truncated: truncated,
+		}, nil)
+
+		raw, err := json.Marshal(res)
+		if err != nil {
+			api.log.Error("Failed to serialize response", slog.Any("error", err))
+			respondFail(controlapi.HistoryResponseType, m, "Serialization failure")
+			return
+		}
+
+		if len(raw) <= maxPayload || len(events) == 0 {
+			_ = m.Respond(raw)
+			return
+		}
+
+		events = events[1:]
+		truncated = true
+	}
+}
+
+func (api *ApiListener) handleExport(m *nats.Msg) {
+	var request controlapi.ExportRequest
+	err := json.Unmarshal(m.Data, &request)
+	if err != nil {
+		api.log.Error("Failed to deserialize export request", slog.Any("err", err))
+		respondFail(controlapi.ExportResponseType, m, fmt.Sprintf("Unable to deserialize export request: %s", err))
+		return
+	}
+
+	err = request.Validate()
+	if err != nil {
+		respondFail(controlapi.ExportResponseType, m, fmt.Sprintf("Invalid export request: %s", err))
+		return
+	}
+
+	manifest, err := api.exportWorkloads(&request)
+	if err != nil {
+		api.log.Error("Failed to export workloads", slog.String("target_node", request.TargetNode), slog.Any("err", err))
+		respondFail(controlapi.ExportResponseType, m, fmt.Sprintf("Failed to export workloads: %s", err))
+		return
+	}
+
+	api.log.Info("Exported workloads", slog.String("target_node", request.TargetNode), slog.Int("workloads", len(manifest.Workloads)))
+
+	res := controlapi.NewEnvelope(controlapi.ExportResponseType, manifest, nil)
+	raw, err := json.Marshal(res)
+	if err != nil {
+		api.log.Error("Failed to serialize response", slog.Any("error", err))
+		respondFail(controlapi.ExportResponseType, m, "Serialization failure")
+	} else {
+		_ = m.Respond(raw)
+	}
+}
+
+func (api *ApiListener) handleImport(m *nats.Msg) {
+	var request controlapi.ImportRequest
+	err := json.Unmarshal(m.Data, &request)
+	if err != nil {
+		api.log.Error("Failed to deserialize import request", slog.Any("err", err))
+		respondFail(controlapi.ImportResponseType, m, fmt.Sprintf("Unable to deserialize import request: %s", err))
+		return
+	}
+
+	err = request.Validate()
+	if err != nil {
+		respondFail(controlapi.ImportResponseType, m, fmt.Sprintf("Invalid import request: %s", err))
+		return
+	}
+
+	// each workload is deployed through this node's control API, so this subscription must not block
+	go func() {
+		resp, err := api.importWorkloads(&request.Manifest)
+		if err != nil {
+			api.log.Error("Failed to import workloads", slog.String("source_node", request.Manifest.NodeId), slog.Any("err", err))
+			respondFail(controlapi.ImportResponseType, m, fmt.Sprintf("Failed to import workloads: %s", err))
+			return
+		}
+
+		api.log.Info("Imported workloads",
+			slog.String("source_node", request.Manifest.NodeId),
+			slog.Int("imported", resp.Imported()),
+			slog.Int("workloads", len(resp.Results)),
+		)
+
+		res := controlapi.NewEnvelope(controlapi.ImportResponseType, resp, nil)
+		raw, err := json.Marshal(res)
+		if err != nil {
+			api.log.Error("Failed to serialize response", slog.Any("error", err))
+			respondFail(controlapi.ImportResponseType, m, "Serialization failure")
+		} else {
+			_ = m.Respond(raw)
+		}
+	}()
+}
+
+func (api *ApiListener) handleEventToken(m *nats.Msg) {
+	namespace, err := extractNamespace(m.Subject)
+	if err != nil {
+		api.log.Error("Invalid subject for event token", slog.Any("err", err))
+		respondFail(controlapi.EventTokenResponseType, m, "Invalid subject for event token")
+		return
+	}
+
+	var request controlapi.EventTokenRequest
+	if len(m.Data) > 0 {
+		err = json.Unmarshal(m.Data, &request)
+		if err != nil {
+			api.log.Error("Failed to deserialize event token request", slog.Any("err", err))
+			respondFail(controlapi.EventTokenResponseType, m, fmt.Sprintf("Unable to deserialize event token request: %s", err))
+			return
+		}
+	}
+
+	err = request.Validate()
+	if err != nil {
+		respondFail(controlapi.EventTokenResponseType, m, fmt.Sprintf("Invalid event token request: %s", err))
+		return
+	}
+
+	token, err := api.issueEventToken(namespace, &request)
+	if err != nil {
+		api.log.Warn("Failed to issue event token", slog.String("namespace", namespace), slog.Any("err", err))
+		respondFail(controlapi.EventTokenResponseType, m, fmt.Sprintf("Failed to issue event token: %s", err))
+		return
+	}
+
+	api.log.Info("Issued event token",
+		slog.String("namespace", namespace),
+		slog.String("id", token.Token.Id),
+		slog.String("name", token.Token.Name),
+		slog.Time("expires_at", token.Token.ExpiresAt),
+	)
+
+	res := controlapi.NewEnvelope(controlapi.EventTokenResponseType, token, nil)
+	raw, err := json.Marshal(res)
+	if err != nil {
+		api.log.Error("Failed to serialize response", slog.Any("error", err))
+		respondFail(controlapi.EventTokenResponseType, m, "Serialization failure")
+	} else {
+		_ = m.Respond(raw)
+	}
+}
+
+func (api *ApiListener) handleLostWorkloads(m *nats.Msg) {
+	namespace, err := extractNamespace(m.Subject)
+	if err != nil {
+		api.log.Error("Invalid subject for lost workloads", slog.Any("err", err))
+		respondFail(controlapi.LostResponseType, m, "Invalid subject for lost workloads")
+		return
+	}
+
+	res := controlapi.NewEnvelope(controlapi.LostResponseType, controlapi.LostWorkloadsResponse{
+		NodeId:    api.PublicKey(),
+		Workloads: api.mgr.LostWorkloads(namespace),
+	}, nil)
+	raw, err := json.Marshal(res)
+	if err != nil {
+		api.log.Error("Failed to serialize response", slog.Any("error", err))
+		respondFail(controlapi.LostResponseType, m, "Serialization failure")
+	} else {
+		_ = m.Respond(raw)
+	}
+}
+
+func (api *ApiListener) handleEventTokens(m *nats.Msg) {
+	namespace, err := extractNamespace(m.Subject)
+	if err != nil {
+		api.log.Error("Invalid subject for event tokens", slog.Any("err", err))
+		respondFail(controlapi.EventTokenResponseType, m, "Invalid subject for event tokens")
+		return
+	}
+
+	var request controlapi.EventTokensRequest
+	if len(m.Data) > 0 {
+		err = json.Unmarshal(m.Data, &request)
+		if err != nil {
+			api.log.Error("Failed to deserialize event tokens request", slog.Any("err", err))
+			respondFail(controlapi.EventTokenResponseType, m, fmt.Sprintf("Unable to deserialize event tokens request: %s", err))
+			return
+		}
+	}
+
+	err = api.revokeEventTokens(namespace, request.Revoke)
+	if err != nil {
+		respondFail(controlapi.EventTokenResponseType, m, fmt.Sprintf("Failed to revoke event tokens: %s", err))
+		return
+	}
+
+	res := controlapi.NewEnvelope(controlapi.EventTokenResponseType, controlapi.EventTokensResponse{
+		NodeId: api.PublicKey(),
+		Tokens: api.eventTokens.list(namespace),
+	}, nil)
+	raw, err := json.Marshal(res)
+	if err != nil {
+		api.log.Error("Failed to serialize response", slog.Any("error", err))
+		respondFail(controlapi.EventTokenResponseType, m, "Serialization failure")
+	} else {
+		_ = m.Respond(raw)
+	}
+}
+
+func (api *ApiListener) handleInventory(m *nats.Msg) {
+	var request controlapi.InventoryRequest
+	if len(m.Data) > 0 {
+		err := json.Unmarshal(m.Data, &request)
+		if err != nil {
+			api.log.Error("Failed to deserialize inventory request", slog.Any("err", err))
+			respondFail(controlapi.InventoryResponseType, m, fmt.Sprintf("Unable to deserialize inventory request: %s", err))
+			return
+		}
+	}
+
+	inventory, err := api.node.inventory(&request)
+	if err != nil {
+		api.log.Error("Failed to gather node inventory", slog.Any("err", err))
+		respondFail(controlapi.InventoryResponseType, m, fmt.Sprintf("Failed to gather node inventory: %s", err))
+		return
+	}
+
+	res := controlapi.NewEnvelope(controlapi.InventoryResponseType, inventory, nil)
+	raw, err := json.Marshal(res)
+	if err != nil {
+		api.log.Error("Failed to serialize response", slog.Any("error", err))
+		respondFail(controlapi.InventoryResponseType, m, "Serialization failure")
+	} else {
+		_ = m.Respond(raw)
+	}
+}
+
+func (api *ApiListener) handleMetricsSnapshot(m *nats.Msg) {
+	maxPayload := int(api.node.nc.MaxPayload())
+	budget := maxPayload - metricsSnapshotEnvelopeOverhead
+
+	// escaping the exposition as JSON can grow it beyond the budget, so shrink the
+	// budget by the overflow and gather again until the response fits
+	for budget > 0 {
+		exposition, truncated, err := api.node.telemetry.MetricsSnapshot(budget)
+		if err != nil {
+			api.log.Error("Failed to gather metrics snapshot", slog.Any("err", err))
+			respondFail(controlapi.MetricsResponseType, m, fmt.Sprintf("Failed to gather metrics snapshot: %s", err))
+			return
+		}
+
+		res := controlapi.NewEnvelope(controlapi.MetricsResponseType, controlapi.MetricsSnapshotResponse{
+			NodeId:      api.PublicKey(),
+			Timestamp:   time.Now().UTC(),
+			ContentType: observability.MetricsSnapshotContentType,
+			Exposition:  string(exposition),
+			Truncated:   truncated,
+		}, nil)
+
+		raw, err := json.Marshal(res)
+		if err != nil {
+			api.log.Error("Failed to serialize response", slog.Any("err", err))
+			respondFail(controlapi.MetricsResponseType, m, "Serialization failure")
+			return
+		}
+
+		if len(raw) <= maxPayload {
+			if truncated {
+				api.log.Warn("Metrics snapshot truncated to fit maximum payload size", slog.Int("max_payload", maxPayload))
+			}
+			_ = m.Respond(raw)
+			return
+		}
+
+		budget -= len(raw) - maxPayload
+	}
+
+	respondFail(controlapi.MetricsResponseType, m, "Metrics snapshot does not fit within the maximum payload size")
+}
+
 func (api *ApiListener) handleLameDuck(m *nats.Msg) {
 	err := api.node.EnterLameDuck()
 	if err != nil {
@@ -410,9 +1148,13 @@ func (api *ApiListener) handleLameDuck(m *nats.Msg) {
 		respondFail(controlapi.LameDuckResponseType, m, "Failed to enter lame duck mode")
 		return
 	}
+
+	remaining, drained := api.node.LameDuckStatus()
 	res := controlapi.NewEnvelope(controlapi.LameDuckResponseType, controlapi.LameDuckResponse{
-		Success: true,
-		NodeId:  api.PublicKey(),
+		Success:   true,
+		NodeId:    api.PublicKey(),
+		Remaining: remaining,
+		Drained:   drained,
 	}, nil)
 	raw, err := json.Marshal(res)
 	if err != nil {
@@ -550,3 +1292,35 @@ func extractNamespace(subject string) (string, error) {
 	}
 	return tokens[2], nil
 }
+
+func (api *ApiListener) handleAgentUpdate(m *nats.Msg) {
+	var request controlapi.AgentUpdateRequest
+	err := json.Unmarshal(m.Data, &request)
+	if err != nil {
+		api.log.Error("Failed to deserialize agent update request", slog.Any("err", err))
+		respondFail(controlapi.AgentUpdateResponseType, m, fmt.Sprintf("Unable to deserialize agent update request: %s", err))
+		return
+	}
+
+	err = request.Validate()
+	if err != nil {
+		respondFail(controlapi.AgentUpdateResponseType, m, fmt.Sprintf("Invalid agent update request: %s", err))
+		return
+	}
+
+	resp, err := api.mgr.UpdateAgents(&request)
+	if err != nil {
+		api.log.Error("Failed to update agents", slog.Any("err", err))
+		respondFail(controlapi.AgentUpdateResponseType, m, fmt.Sprintf("Failed to update agents: %s", err))
+		return
+	}
+
+	res := controlapi.NewEnvelope(controlapi.AgentUpdateResponseType, resp, nil)
+	raw, err := json.Marshal(res)
+	if err != nil {
+		api.log.Error("Failed to serialize response", slog.Any("error", err))
+		respondFail(controlapi.AgentUpdateResponseType, m, "Serialization failure")
+	} else {
+		_ = m.Respond(raw)
+	}
+}
@@ -2,9 +2,12 @@ package nexnode
 
 import (
 	"slices"
+	"sync"
 	"testing"
 
 	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/models"
 )
 
 func TestSummarizeMachinesForPing(t *testing.T) {
@@ -79,3 +82,51 @@ func TestSummarizeMachinesForPing(t *testing.T) {
 		t.Fatalf("Should've returned 0 results, got %d", len(results))
 	}
 }
+
+func TestUnsatisfiedConstraintsReportsEveryConstraint(t *testing.T) {
+	api := &ApiListener{
+		node: &Node{
+			config: &models.NodeConfiguration{
+				WorkloadTypes: []string{"v8"},
+				ValidIssuers:  []string{"ACME"},
+				MaxWorkloads:  1,
+			},
+			lameduck: 1,
+		},
+		mgr: &WorkloadManager{
+			poolMutex:     &sync.Mutex{},
+			activeAgents:  map[string]*agentapi.AgentClient{"running": nil},
+			pendingAgents: make(map[string]*agentapi.AgentClient),
+		},
+	}
+
+	workloadType := "elf"
+	targetVM := "vm"
+	request := &controlapi.DeployRequest{
+		WorkloadType:    &workloadType,
+		TriggerSubjects: []string{"hello.world"},
+		TargetVM:        &targetVM,
+	}
+	request.DecodedClaims.Issuer = "EVIL"
+
+	unsatisfied := api.unsatisfiedConstraints(request)
+
+	expected := []string{
+		controlapi.ConstraintLameDuck,
+		controlapi.ConstraintWorkloadType,
+		controlapi.ConstraintTriggerSubjects,
+		controlapi.ConstraintVMPinning,
+		controlapi.ConstraintIssuer,
+		controlapi.ConstraintMaxWorkloads,
+		controlapi.ConstraintAgentPool,
+	}
+	if len(unsatisfied) != len(expected) {
+		t.Fatalf("expected %d unsatisfied constraints, got %d: %+v", len(expected), len(unsatisfied), unsatisfied)
+	}
+
+	for i, c := range unsatisfied {
+		if c.Constraint != expected[i] {
+			t.Fatalf("expected constraint %s at %d, got %s", expected[i], i, c.Constraint)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package nexnode
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+// Interval at which a held deployment checks whether its dependencies are running
+const dependencyPollInterval = 250 * time.Millisecond
+
+// Holds until every named workload in the given namespace is running on this node, returning an
+// error naming those which are still not running once the given timeout elapses
+func (w *WorkloadManager) awaitDependencies(namespace string, dependencies []string, timeout time.Duration) error {
+	ticker := time.NewTicker(dependencyPollInterval)
+	defer ticker.Stop()
+
+	pending := w.pendingDependencies(namespace, dependencies)
+	if len(pending) == 0 {
+		return nil
+	}
+
+	w.log.Info("Holding deployment until dependencies are running", slog.String("namespace", namespace), slog.Any("dependencies", pending))
+
+	timeoutAt := time.Now().UTC().Add(timeout)
+	for {
+
+		if time.Now().UTC().After(timeoutAt) {
+			deployErr := controlapi.NewDeployError(controlapi.DeployErrorDependencies, controlapi.DeployReasonDependencyTimeout, fmt.Sprintf("dependencies not running within %s: %s", timeout, strings.Join(pending, ", ")))
+			deployErr.Dependencies = pending
+			return deployErr
+		}
+
+		select {
+		case <-w.ctx.Done():
+			deployErr := controlapi.NewDeployError(controlapi.DeployErrorDependencies, controlapi.DeployReasonNodeStopping, fmt.Sprintf("node stopped while awaiting dependencies: %s", strings.Join(pending, ", ")))
+			deployErr.Dependencies = pending
+			return deployErr
+		case <-ticker.C:
+		}
+
+		pending = w.pendingDependencies(namespace, dependencies)
+		if len(pending) == 0 {
+			return nil
+		}
+	}
+}
+
+// Returns the named workloads in the given namespace which are not running on this node
+func (w *WorkloadManager) pendingDependencies(namespace string, dependencies []string) []string {
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
+	running := make([]string, 0, len(w.activeAgents))
+	for id := range w.activeAgents {
+		deployRequest, err := w.procMan.Lookup(id)
+		if err != nil || deployRequest == nil {
+			continue
+		}
+
+		if *deployRequest.Namespace == namespace {
+			running = append(running, *deployRequest.WorkloadName)
+		}
+	}
+
+	pending := make([]string, 0)
+	for _, dependency := range dependencies {
+		if !slices.Contains(running, dependency) {
+			pending = append(pending, dependency)
+		}
+	}
+
+	return pending
+}
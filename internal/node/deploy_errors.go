@@ -0,0 +1,54 @@
+package nexnode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+// Returns a copy of the deploy error produced along the deploy path which caused the given error,
+// or a new deploy error with the given code and reason if there is none, prefixing its message
+func deployError(err error, code controlapi.DeployErrorCode, reason string, prefix string) *controlapi.DeployError {
+	var deployErr *controlapi.DeployError
+	if errors.As(err, &deployErr) {
+		clone := *deployErr
+		clone.Message = fmt.Sprintf("%s: %s", prefix, err)
+		return &clone
+	}
+
+	return controlapi.NewDeployError(code, reason, fmt.Sprintf("%s: %s", prefix, err))
+}
+
+// Returns a deploy error reporting a request which failed validation, naming the invalid field
+// if known
+func invalidRequestError(err error, prefix string) *controlapi.DeployError {
+	deployErr := controlapi.NewDeployError(controlapi.DeployErrorInvalidRequest, controlapi.DeployReasonInvalidField, fmt.Sprintf("%s: %s", prefix, err))
+
+	var invalid *controlapi.InvalidFieldError
+	if errors.As(err, &invalid) {
+		deployErr.Field = invalid.Field
+	}
+
+	return deployErr
+}
+
+// Returns a deploy error reporting that the given admission constraint was not satisfied
+func unschedulableError(constraint string, reason string) *controlapi.DeployError {
+	deployErr := controlapi.NewDeployError(controlapi.DeployErrorUnschedulable, controlapi.DeployReasonConstraintsNotSatisfied, reason)
+	deployErr.Constraints = []controlapi.UnsatisfiedConstraint{{Constraint: constraint, Reason: reason}}
+	return deployErr
+}
+
+// Responds to a failed deploy request with a run response carrying the given error, the message
+// of which is also given as the error of the response envelope
+func (api *ApiListener) respondDeployFail(m *nats.Msg, deployErr *controlapi.DeployError) {
+	deployErr.NodeId = api.PublicKey()
+
+	reason := deployErr.Message
+	env := controlapi.NewEnvelope(controlapi.RunResponseType, controlapi.RunResponse{Error: deployErr}, &reason)
+	jenv, _ := json.Marshal(env)
+	_ = m.Respond(jenv)
+}
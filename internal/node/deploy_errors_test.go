@@ -0,0 +1,99 @@
+package nexnode
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/nats-io/nkeys"
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+func TestDeployErrorPreservesTypedError(t *testing.T) {
+	cause := controlapi.NewDeployError(controlapi.DeployErrorAgent, controlapi.DeployReasonAgentRejected, "workload rejected by agent: boom")
+
+	deployErr := deployError(fmt.Errorf("wrapped: %w", cause), controlapi.DeployErrorInternal, controlapi.DeployReasonDeploymentFailed, "Failed to deploy workload")
+	if deployErr.Code != controlapi.DeployErrorAgent || deployErr.Reason != controlapi.DeployReasonAgentRejected {
+		t.Fatalf("expected code and reason of the typed error, got %s/%s", deployErr.Code, deployErr.Reason)
+	}
+	if deployErr.Message != "Failed to deploy workload: wrapped: workload rejected by agent: boom" {
+		t.Fatalf("unexpected message: %s", deployErr.Message)
+	}
+	if cause.Message != "workload rejected by agent: boom" {
+		t.Fatal("expected the typed error to be left unmodified")
+	}
+
+	deployErr = deployError(errors.New("boom"), controlapi.DeployErrorArtifact, controlapi.DeployReasonArtifactFetchFailed, "Failed to cache workload bytes")
+	if deployErr.Code != controlapi.DeployErrorArtifact || deployErr.Reason != controlapi.DeployReasonArtifactFetchFailed {
+		t.Fatalf("expected the given code and reason for an untyped error, got %s/%s", deployErr.Code, deployErr.Reason)
+	}
+}
+
+func TestInvalidRequestErrorNamesField(t *testing.T) {
+	issuer, _ := nkeys.CreateAccount()
+	workloadJwt, err := controlapi.CreateWorkloadJwt("abc123", "echo", issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rate := 2.0
+	request := &controlapi.DeployRequest{
+		WorkloadJwt:       &workloadJwt,
+		TraceSamplingRate: &rate,
+	}
+
+	_, err = request.Validate()
+	if err == nil {
+		t.Fatal("expected validation to fail")
+	}
+
+	deployErr := invalidRequestError(err, "Invalid deploy request")
+	if deployErr.Code != controlapi.DeployErrorInvalidRequest || deployErr.Field != "trace_sampling_rate" {
+		t.Fatalf("expected invalid trace_sampling_rate field, got %s (%s)", deployErr.Code, deployErr.Field)
+	}
+}
+
+func TestUnschedulableDeployErrorUnwrapsConstraints(t *testing.T) {
+	var err error = unschedulableError(controlapi.ConstraintMaxWorkloads, "node at workload capacity")
+
+	var unschedulable *controlapi.UnschedulableError
+	if !errors.As(err, &unschedulable) {
+		t.Fatal("expected unschedulable deploy error to unwrap to an unschedulable error")
+	}
+	if len(unschedulable.Constraints) != 1 || unschedulable.Constraints[0].Constraint != controlapi.ConstraintMaxWorkloads {
+		t.Fatalf("unexpected constraints: %+v", unschedulable.Constraints)
+	}
+}
+
+func TestArtifactsRetrievedByAgentRequireHashClaim(t *testing.T) {
+	issuer, _ := nkeys.CreateAccount()
+	location, _ := url.Parse("oci://registry.example.com/nex/echoservice:1.0")
+
+	workloadJwt, err := controlapi.CreateWorkloadJwt("", "echo", issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := &controlapi.DeployRequest{WorkloadJwt: &workloadJwt, Location: location}
+	_, err = request.Validate()
+	if deployErr := invalidRequestError(err, "Invalid deploy request"); err == nil || deployErr.Field != "workload_jwt" {
+		t.Fatalf("expected an artifact retrieved by the agent to require a hash claim, got %v", err)
+	}
+
+	workloadJwt, _ = controlapi.CreateWorkloadJwt("abc123", "echo", issuer)
+	request.WorkloadJwt = &workloadJwt
+	_, err = request.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if agentLocation := agentArtifactLocation(request); agentLocation == nil || *agentLocation != location.String() {
+		t.Fatalf("expected the agent to retrieve the artifact from %s, got %v", location, agentLocation)
+	}
+
+	request.Location, _ = url.Parse("nats://NEXCLIFILES/echoservice")
+	if agentArtifactLocation(request) != nil {
+		t.Fatal("expected an artifact in an object store to be cached by the node")
+	}
+}
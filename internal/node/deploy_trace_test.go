@@ -0,0 +1,74 @@
+package nexnode
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestAgentClientPropagatesDeployTraceContext(t *testing.T) {
+	svr, _ := startObjectStoreTestServer(t, t.TempDir())
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(previous)
+
+	// an agent which continues the trace of the deploy request it receives
+	received := make(chan trace.SpanContext, 1)
+	sub, err := nc.Subscribe("agentint.vm1.deploy", func(m *nats.Msg) {
+		ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(m.Header))
+		received <- trace.SpanContextFromContext(ctx)
+
+		resp, _ := json.Marshal(agentapi.DeployResponse{Accepted: true})
+		_ = m.Respond(resp)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	noop := func(string) {}
+
+	agentClient := agentapi.NewAgentClient(nc, log, time.Minute, time.Second, time.Second, 0, false, noop, noop, nil, nil, nil, nil, nil)
+	err = agentClient.Start("vm1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = agentClient.Stop() }()
+
+	tracer := tracesdk.NewTracerProvider().Tracer("nex-test")
+	ctx, span := tracer.Start(context.Background(), "workload-deploy")
+	defer span.End()
+
+	workloadName := "echo"
+	_, err = agentClient.DeployWorkload(ctx, tracer, &agentapi.DeployRequest{WorkloadName: &workloadName})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case spanCtx := <-received:
+		if !spanCtx.IsValid() || spanCtx.TraceID() != span.SpanContext().TraceID() {
+			t.Fatalf("expected deploy request to carry trace %s, got %s", span.SpanContext().TraceID(), spanCtx.TraceID())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("agent did not receive deploy request")
+	}
+}
@@ -0,0 +1,155 @@
+package nexnode
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+// Internal key-value bucket in which the node records each workload deployed to it, keyed by
+// workload id, so that the workloads it was running can be reported once it restarts. The bucket
+// is always file-backed, surviving restarts for as long as the internal NATS store dir does
+const DeploymentsBucketName = "NEXDEPLOYMENTS"
+
+// Opens the bucket of recorded deployments, creating it if it does not yet exist
+func ensureDeploymentsBucket(js nats.JetStreamContext) (nats.KeyValue, error) {
+	kv, err := js.KeyValue(DeploymentsBucketName)
+	if err == nil {
+		return kv, nil
+	}
+	if !errors.Is(err, nats.ErrBucketNotFound) {
+		return nil, err
+	}
+
+	return js.CreateKeyValue(&nats.KeyValueConfig{
+		Bucket:      DeploymentsBucketName,
+		Description: "Workloads deployed to this nex-node",
+		Storage:     nats.FileStorage,
+	})
+}
+
+// Opens the bucket of recorded deployments and takes from it the workloads which were running when
+// the node last stopped, which are reported as lost until the node next restarts. Deployments are
+// not recorded if the bucket cannot be opened
+func (w *WorkloadManager) restoreDeployments() {
+	js, err := w.ncInternal.JetStream()
+	if err == nil {
+		w.deployments, err = ensureDeploymentsBucket(js)
+	}
+	if err != nil {
+		w.log.Warn("Failed to open deployments bucket; deployments will not be recorded", slog.Any("err", err))
+		return
+	}
+
+	keys, err := w.deployments.Keys()
+	if errors.Is(err, nats.ErrNoKeysFound) {
+		return
+	} else if err != nil {
+		w.log.Warn("Failed to list recorded deployments", slog.Any("err", err))
+		return
+	}
+
+	for _, key := range keys {
+		entry, err := w.deployments.Get(key)
+		if err != nil {
+			w.log.Warn("Failed to read recorded deployment", slog.String("workload_id", key), slog.Any("err", err))
+			continue
+		}
+
+		var lost controlapi.LostWorkload
+		err = json.Unmarshal(entry.Value(), &lost)
+		if err != nil {
+			w.log.Warn("Failed to deserialize recorded deployment", slog.String("workload_id", key), slog.Any("err", err))
+		} else {
+			w.lostWorkloads = append(w.lostWorkloads, lost)
+		}
+
+		// agent ids are never reused, so none of the recorded workloads can still be running
+		err = w.deployments.Purge(key)
+		if err != nil {
+			w.log.Warn("Failed to purge recorded deployment", slog.String("workload_id", key), slog.Any("err", err))
+		}
+	}
+
+	slices.SortFunc(w.lostWorkloads, func(a, b controlapi.LostWorkload) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Name, b.Name), cmp.Compare(a.Id, b.Id))
+	})
+
+	if len(w.lostWorkloads) > 0 {
+		w.log.Warn("Found workloads which were running when the node last stopped", slog.Int("count", len(w.lostWorkloads)))
+	}
+}
+
+// Records the deployment of the workload with the given id, omitting its environment
+func (w *WorkloadManager) recordDeployment(id string, request *agentapi.DeployRequest) {
+	if w.deployments == nil {
+		return
+	}
+
+	raw, err := json.Marshal(controlapi.LostWorkload{
+		Id:         id,
+		Name:       *request.WorkloadName,
+		Namespace:  *request.Namespace,
+		DeployedAt: time.Now().UTC(),
+		Request:    redeployableRequest(request),
+	})
+	if err != nil {
+		w.log.Warn("Failed to serialize deployment record", slog.String("workload_id", id), slog.Any("err", err))
+		return
+	}
+
+	_, err = w.deployments.Put(id, raw)
+	if err != nil {
+		w.log.Warn("Failed to record deployment", slog.String("workload_id", id), slog.Any("err", err))
+	}
+}
+
+// Removes the record of the deployment of the workload with the given id once it has been stopped
+func (w *WorkloadManager) forgetDeployment(id string) {
+	if w.deployments == nil {
+		return
+	}
+
+	err := w.deployments.Purge(id)
+	if err != nil {
+		w.log.Warn("Failed to remove deployment record", slog.String("workload_id", id), slog.Any("err", err))
+	}
+}
+
+// Returns the workloads in the given namespace which were running when the node last stopped
+func (w *WorkloadManager) LostWorkloads(namespace string) []controlapi.LostWorkload {
+	lost := make([]controlapi.LostWorkload, 0)
+	for _, workload := range w.lostWorkloads {
+		if workload.Namespace == namespace {
+			lost = append(lost, workload)
+		}
+	}
+
+	return lost
+}
+
+func (w *WorkloadManager) publishWorkloadsLost() error {
+	evt := controlapi.WorkloadsLostEvent{
+		Id:    w.publicKey,
+		Count: len(w.lostWorkloads),
+	}
+
+	cloudevent := cloudevents.NewEvent()
+	cloudevent.SetSource(w.publicKey)
+	cloudevent.SetID(uuid.NewString())
+	cloudevent.SetTime(time.Now().UTC())
+	cloudevent.SetType(controlapi.WorkloadsLostEventType)
+	cloudevent.SetDataContentType(cloudevents.ApplicationJSON)
+	_ = cloudevent.SetData(evt)
+
+	return w.publishCloudEvent(systemNamespace, cloudevent)
+}
@@ -0,0 +1,88 @@
+package nexnode
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+func deploymentsTestManager(t *testing.T, storeDir string) (*WorkloadManager, *server.Server) {
+	svr, _ := startObjectStoreTestServer(t, storeDir)
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		nc.Close()
+		svr.Shutdown()
+		svr.WaitForShutdown()
+	})
+
+	w := &WorkloadManager{
+		log:        slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError})),
+		ncInternal: nc,
+	}
+	w.restoreDeployments()
+
+	return w, svr
+}
+
+func TestDeploymentsRunningWhenNodeStoppedAreReportedLost(t *testing.T) {
+	dir := t.TempDir()
+
+	w, svr := deploymentsTestManager(t, dir)
+	if len(w.LostWorkloads("default")) != 0 {
+		t.Fatal("expected no lost workloads before any were deployed")
+	}
+
+	for _, id := range []string{"vm1", "vm2", "vm3"} {
+		namespace := "default"
+		if id == "vm3" {
+			namespace = "other"
+		}
+		name := "echo-" + id
+		workloadType := "native"
+
+		w.recordDeployment(id, &agentapi.DeployRequest{
+			Namespace:    &namespace,
+			WorkloadName: &name,
+			WorkloadType: &workloadType,
+			Environment:  map[string]string{"SECRET": "hunter2"},
+		})
+	}
+	w.forgetDeployment("vm2")
+
+	// stop the internal NATS server, as though the node had stopped
+	w.ncInternal.Close()
+	svr.Shutdown()
+	svr.WaitForShutdown()
+
+	w, _ = deploymentsTestManager(t, dir)
+	lost := w.LostWorkloads("default")
+	if len(lost) != 1 || lost[0].Id != "vm1" || lost[0].Name != "echo-vm1" {
+		t.Fatalf("expected only the workload still running to be reported lost, got %+v", lost)
+	}
+	if lost[0].Request == nil || *lost[0].Request.WorkloadType != "native" {
+		t.Fatalf("expected the lost workload's deploy request to be recorded, got %+v", lost[0].Request)
+	}
+	if lost[0].Request.Environment != nil {
+		t.Fatal("expected the workload's environment not to be recorded")
+	}
+	if other := w.LostWorkloads("other"); len(other) != 1 || other[0].Id != "vm3" {
+		t.Fatalf("expected lost workloads to be listed by namespace, got %+v", other)
+	}
+
+	keys, err := w.deployments.Keys()
+	if err != nil && !errors.Is(err, nats.ErrNoKeysFound) {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("expected lost workloads to be taken from the deployments bucket, got %v", keys)
+	}
+}
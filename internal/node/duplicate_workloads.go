@@ -0,0 +1,67 @@
+package nexnode
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+// Rejects the deployment of a workload named the same as a workload already running in its
+// namespace when the node's duplicate workload policy is to reject such deployments. Admission
+// checks ahead of time, before the workload is cached or held for its dependencies; deployment
+// checks again, since concurrent deployments may since have claimed the name
+func (w *WorkloadManager) checkDuplicateWorkload(namespace, name string) error {
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
+	_, err := w.duplicateWorkloads(namespace, name)
+	return err
+}
+
+// Returns the ids of the workloads running in the given namespace with the given name which are to be
+// replaced by the deployment of a workload of the same name, failing if the node's duplicate workload
+// policy is to reject such deployments. The caller must hold the pool mutex
+func (w *WorkloadManager) duplicateWorkloads(namespace, name string) ([]string, error) {
+	policy := w.config.ResolveDuplicateWorkloadPolicy()
+	if policy == models.DuplicateWorkloadPolicyAllow {
+		return nil, nil
+	}
+
+	procs, err := w.procMan.ListProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0)
+	for _, proc := range procs {
+		// workloads which have exited are about to be stopped
+		if proc.ExitCode == nil && proc.Namespace == namespace && proc.Name == name {
+			ids = append(ids, proc.ID)
+		}
+	}
+
+	if len(ids) > 0 && policy == models.DuplicateWorkloadPolicyReject {
+		return nil, controlapi.NewDeployError(controlapi.DeployErrorConflict, controlapi.DeployReasonDuplicateWorkload,
+			fmt.Sprintf("workload %s is already running in namespace %s: %s", name, namespace, strings.Join(ids, ", ")))
+	}
+
+	return ids, nil
+}
+
+// Stops the given workloads, replaced by the newly deployed workload of the same name
+func (w *WorkloadManager) replaceWorkloads(replacementID string, ids []string) {
+	for _, id := range ids {
+		w.log.Info("Stopping workload replaced by newly deployed workload",
+			slog.String("workload_id", id),
+			slog.String("replacement_workload_id", replacementID),
+		)
+
+		err := w.stopWorkload(id, true, controlapi.WorkloadStopReasonReplaced)
+		if err != nil {
+			w.log.Warn("Failed to stop replaced workload", slog.String("workload_id", id), slog.Any("err", err))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package nexnode
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+	"github.com/synadia-io/nex/internal/models"
+	"github.com/synadia-io/nex/internal/node/processmanager"
+)
+
+// Process manager listing a fixed set of deployed workloads
+type listedProcessManager struct {
+	idleProcessManager
+	procs []processmanager.ProcessInfo
+}
+
+func (m listedProcessManager) ListProcesses() ([]processmanager.ProcessInfo, error) {
+	return m.procs, nil
+}
+
+func TestDuplicateWorkloadPolicy(t *testing.T) {
+	exitCode := 1
+	procMan := listedProcessManager{procs: []processmanager.ProcessInfo{
+		{ID: "vm1", Name: "echo", Namespace: "default"},
+		{ID: "vm2", Name: "echo", Namespace: "other"},
+		{ID: "vm3", Name: "echo", Namespace: "default", ExitCode: &exitCode},
+	}}
+
+	w := &WorkloadManager{config: &models.NodeConfiguration{}, procMan: procMan, poolMutex: &sync.Mutex{}}
+
+	var deployErr *controlapi.DeployError
+	err := w.checkDuplicateWorkload("default", "echo")
+	if !errors.As(err, &deployErr) || deployErr.Code != controlapi.DeployErrorConflict {
+		t.Fatalf("expected duplicate workload to be rejected by default: %v", err)
+	}
+
+	err = w.checkDuplicateWorkload("default", "ping")
+	if err != nil {
+		t.Fatalf("expected workload with a distinct name to be admitted: %v", err)
+	}
+
+	w.config.DuplicateWorkloadPolicy = models.DuplicateWorkloadPolicyReplace
+	replaced, err := w.duplicateWorkloads("default", "echo")
+	if err != nil || len(replaced) != 1 || replaced[0] != "vm1" {
+		t.Fatalf("expected only the running workload in the namespace to be replaced: %v %v", replaced, err)
+	}
+
+	w.config.DuplicateWorkloadPolicy = models.DuplicateWorkloadPolicyAllow
+	replaced, err = w.duplicateWorkloads("default", "echo")
+	if err != nil || len(replaced) != 0 {
+		t.Fatalf("expected duplicate workload to be allowed alongside the existing one: %v %v", replaced, err)
+	}
+}
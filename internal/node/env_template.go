@@ -0,0 +1,64 @@
+package nexnode
+
+import (
+	"os"
+	"regexp"
+
+	"github.com/synadia-io/nex/internal/node/processmanager"
+)
+
+// Variables which may be referenced as ${nex.<variable>} in workload environment values, resolved
+// when the workload is dispatched to its agent
+const (
+	envVarNamespace    = "namespace"
+	envVarNodeID       = "node_id"
+	envVarNodeName     = "node_name"
+	envVarVmIP         = "vm_ip"
+	envVarWorkloadID   = "workload_id"
+	envVarWorkloadName = "workload_name"
+)
+
+var envVarReference = regexp.MustCompile(`\$\{nex\.([a-z_]+)\}`)
+
+// Resolves the variables which may be referenced in the environment of the given workload. Variables
+// which cannot be resolved on this node, such as the VM IP when workloads are not sandboxed, are omitted
+func (w *WorkloadManager) environmentVariables(workloadID, namespace, workloadName string) map[string]string {
+	vars := map[string]string{
+		envVarNamespace:    namespace,
+		envVarNodeID:       w.publicKey,
+		envVarWorkloadID:   workloadID,
+		envVarWorkloadName: workloadName,
+	}
+
+	// nodes are named by their node_name tag, falling back to the hostname when the tag is removed
+	if name, ok := w.config.Tags[envVarNodeName]; ok {
+		vars[envVarNodeName] = name
+	} else if hostname, err := os.Hostname(); err == nil {
+		vars[envVarNodeName] = hostname
+	}
+
+	if resolver, ok := w.procMan.(processmanager.ProcessAddressResolver); ok {
+		if ip, ok := resolver.ProcessIP(workloadID); ok {
+			vars[envVarVmIP] = ip
+		}
+	}
+
+	return vars
+}
+
+// Substitutes each ${nex.<variable>} reference in the values of the given environment with the
+// value of the referenced variable, returning a copy. References to unknown or unresolved variables
+// are left untouched
+func expandEnvironment(env map[string]string, vars map[string]string) map[string]string {
+	expanded := make(map[string]string, len(env))
+	for key, value := range env {
+		expanded[key] = envVarReference.ReplaceAllStringFunc(value, func(ref string) string {
+			if resolved, ok := vars[envVarReference.FindStringSubmatch(ref)[1]]; ok {
+				return resolved
+			}
+			return ref
+		})
+	}
+
+	return expanded
+}
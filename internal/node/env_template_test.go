@@ -0,0 +1,37 @@
+package nexnode
+
+import (
+	"testing"
+)
+
+func TestExpandEnvironment(t *testing.T) {
+	env := map[string]string{
+		"ID":      "${nex.workload_id}",
+		"ADDR":    "http://${nex.vm_ip}:8080/${nex.workload_id}",
+		"UNKNOWN": "${nex.nope}",
+		"OTHER":   "${HOME}/${nex.node_name}",
+		"PLAIN":   "hello",
+	}
+
+	expanded := expandEnvironment(env, map[string]string{
+		envVarWorkloadID: "abc123",
+		envVarVmIP:       "192.168.127.2",
+	})
+
+	expected := map[string]string{
+		"ID":      "abc123",
+		"ADDR":    "http://192.168.127.2:8080/abc123",
+		"UNKNOWN": "${nex.nope}",
+		"OTHER":   "${HOME}/${nex.node_name}",
+		"PLAIN":   "hello",
+	}
+	for key, value := range expected {
+		if expanded[key] != value {
+			t.Fatalf("expected %s to be %q, got %q", key, value, expanded[key])
+		}
+	}
+
+	if env["ID"] != "${nex.workload_id}" {
+		t.Fatal("expected the given environment to be left unmodified")
+	}
+}
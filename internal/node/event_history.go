@@ -0,0 +1,122 @@
+package nexnode
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	cloudevents "github.com/cloudevents/sdk-go"
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+// A bounded, in-memory ring of the events most recently published by this node, which allows
+// tooling that starts after an event was published to catch up. The history is best-effort: it
+// is lost when the node restarts and the oldest events are evicted once the ring is full
+type eventHistory struct {
+	mutex   sync.Mutex
+	entries []historyEntry
+	next    int
+	full    bool
+}
+
+type historyEntry struct {
+	namespace string
+	workload  string
+	event     cloudevents.Event
+}
+
+// Creates an event history holding at most the given number of events; a size of zero
+// disables the history
+func newEventHistory(size int) *eventHistory {
+	return &eventHistory{
+		entries: make([]historyEntry, size),
+	}
+}
+
+// Records the given event, published to the given namespace, evicting the oldest recorded
+// event if the history is full. Heartbeats are not recorded, as they would quickly evict
+// every other event
+func (h *eventHistory) record(namespace string, event cloudevents.Event) {
+	if h == nil || len(h.entries) == 0 || event.Type() == controlapi.HeartbeatEventType {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.entries[h.next] = historyEntry{
+		namespace: namespace,
+		workload:  eventWorkloadName(event),
+		event:     event,
+	}
+
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Returns up to count of the most recent events published to the given namespace, oldest first,
+// optionally filtered by workload name and event type. A count of zero returns every match
+func (h *eventHistory) replay(namespace string, workload string, eventType string, count int) []cloudevents.Event {
+	events := make([]cloudevents.Event, 0)
+	if h == nil || len(h.entries) == 0 {
+		return events
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	size := h.next
+	if h.full {
+		size = len(h.entries)
+	}
+
+	// walk backwards from the most recent event so the count keeps the newest matches
+	for i := 1; i <= size; i++ {
+		if count > 0 && len(events) == count {
+			break
+		}
+
+		entry := h.entries[(h.next-i+len(h.entries))%len(h.entries)]
+		if !strings.EqualFold(entry.namespace, namespace) {
+			continue
+		}
+
+		if workload != "" && entry.workload != workload {
+			continue
+		}
+
+		if eventType != "" && entry.event.Type() != eventType {
+			continue
+		}
+
+		events = append(events, entry.event)
+	}
+
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+
+	return events
+}
+
+// Extracts the name of the workload an event pertains to, if any. Workload events name the
+// workload with either a workload_name or a name field
+func eventWorkloadName(event cloudevents.Event) string {
+	var data struct {
+		WorkloadName string `json:"workload_name"`
+		Name         string `json:"name"`
+	}
+
+	raw, err := event.DataBytes()
+	if err != nil || json.Unmarshal(raw, &data) != nil {
+		return ""
+	}
+
+	if data.WorkloadName != "" {
+		return data.WorkloadName
+	}
+
+	return data.Name
+}
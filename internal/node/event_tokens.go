@@ -0,0 +1,204 @@
+package nexnode
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go"
+	"github.com/google/uuid"
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nkeys"
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+// Event tokens issued by the node, each tracked until it expires
+type eventTokens struct {
+	mutex  *sync.Mutex
+	tokens map[string]*controlapi.EventToken
+}
+
+func newEventTokens() *eventTokens {
+	return &eventTokens{
+		mutex:  &sync.Mutex{},
+		tokens: make(map[string]*controlapi.EventToken),
+	}
+}
+
+// Records the given issued token, pruning expired tokens
+func (t *eventTokens) record(token controlapi.EventToken) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.prune()
+	t.tokens[token.Id] = &token
+}
+
+// Marks the token with the given id issued for the given namespace revoked, returning the token if
+// it was not already revoked
+func (t *eventTokens) revoke(namespace string, id string) (*controlapi.EventToken, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	token, ok := t.tokens[id]
+	if !ok || token.Namespace != namespace || token.Revoked {
+		return nil, false
+	}
+
+	token.Revoked = true
+	revoked := *token
+	return &revoked, true
+}
+
+// Returns the unexpired tokens issued for the given namespace, ordered by issue time
+func (t *eventTokens) list(namespace string) []controlapi.EventToken {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.prune()
+
+	tokens := make([]controlapi.EventToken, 0)
+	for _, token := range t.tokens {
+		if token.Namespace == namespace {
+			tokens = append(tokens, *token)
+		}
+	}
+
+	slices.SortFunc(tokens, func(a, b controlapi.EventToken) int {
+		return a.IssuedAt.Compare(b.IssuedAt)
+	})
+
+	return tokens
+}
+
+// Removes expired tokens; the mutex must be held
+func (t *eventTokens) prune() {
+	now := time.Now().UTC()
+	for id, token := range t.tokens {
+		if now.After(token.ExpiresAt) {
+			delete(t.tokens, id)
+		}
+	}
+}
+
+// Returns the subjects on which the logs and events of the given namespace are published
+func namespaceEventSubjects(namespace string) []string {
+	return []string{
+		fmt.Sprintf("%s.%s.>", EventSubjectPrefix, namespace),
+		fmt.Sprintf("%s.%s.>", LogSubjectPrefix, namespace),
+	}
+}
+
+// Mints a NATS user credential, signed by the configured event token signing key, permitting only
+// subscription to the logs and events of the given namespace until the token expires
+func (api *ApiListener) issueEventToken(namespace string, request *controlapi.EventTokenRequest) (*controlapi.EventTokenResponse, error) {
+	config := api.node.config
+	if config.EventTokenSigningSeed == "" {
+		return nil, errors.New("event tokens are not enabled on this node")
+	}
+
+	if namespace == "" || strings.ContainsAny(namespace, "*>") {
+		return nil, fmt.Errorf("invalid namespace for event token: %s", namespace)
+	}
+
+	ttl := config.ResolveEventTokenMaxTTL()
+	if request.TTLMillisecond > 0 {
+		requested := time.Duration(request.TTLMillisecond) * time.Millisecond
+		if requested > ttl {
+			return nil, fmt.Errorf("event token ttl %s exceeds the maximum of %s", requested, ttl)
+		}
+		ttl = requested
+	}
+
+	signer, err := nkeys.FromSeed([]byte(config.EventTokenSigningSeed))
+	if err != nil {
+		return nil, fmt.Errorf("invalid event token signing seed: %s", err)
+	}
+
+	user, err := nkeys.CreateUser()
+	if err != nil {
+		return nil, err
+	}
+	userPublicKey, _ := user.PublicKey()
+	userSeed, _ := user.Seed()
+
+	now := time.Now().UTC()
+	token := controlapi.EventToken{
+		Id:        userPublicKey,
+		Namespace: namespace,
+		Name:      request.Name,
+		Subjects:  namespaceEventSubjects(namespace),
+		IssuedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	claims := jwt.NewUserClaims(userPublicKey)
+	claims.Name = request.Name
+	if claims.Name == "" {
+		claims.Name = fmt.Sprintf("nex-events-%s", namespace)
+	}
+	claims.IssuedAt = now.Unix()
+	claims.Expires = token.ExpiresAt.Unix()
+	claims.IssuerAccount = config.EventTokenAccount
+	claims.Sub.Allow.Add(token.Subjects...)
+	claims.Pub.Deny.Add(">")
+	claims.Tags.Add("nex_namespace:"+namespace, "nex_node:"+api.PublicKey())
+
+	userJwt, err := claims.Encode(signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign event token: %s", err)
+	}
+
+	creds, err := jwt.FormatUserConfig(userJwt, userSeed)
+	if err != nil {
+		return nil, err
+	}
+
+	api.eventTokens.record(token)
+
+	return &controlapi.EventTokenResponse{
+		NodeId: api.PublicKey(),
+		Token:  token,
+		Creds:  string(creds),
+	}, nil
+}
+
+// Revokes the event tokens with the given ids issued for the given namespace, publishing an event for
+// each so that the token's user can be added to the signing account's revocations. This revocation is
+// only advisory, since the node cannot update the account JWT which the NATS servers consult
+func (api *ApiListener) revokeEventTokens(namespace string, ids []string) error {
+	var err error
+	for _, id := range ids {
+		token, ok := api.eventTokens.revoke(namespace, id)
+		if !ok {
+			err = errors.Join(err, fmt.Errorf("no such event token: %s", id))
+			continue
+		}
+
+		_ = api.publishEventTokenRevoked(token)
+	}
+
+	return err
+}
+
+func (api *ApiListener) publishEventTokenRevoked(token *controlapi.EventToken) error {
+	evt := controlapi.EventTokenRevokedEvent{
+		Id:        token.Id,
+		Namespace: token.Namespace,
+		Name:      token.Name,
+		ExpiresAt: token.ExpiresAt,
+	}
+
+	cloudevent := cloudevents.NewEvent()
+	cloudevent.SetSource(api.PublicKey())
+	cloudevent.SetID(uuid.NewString())
+	cloudevent.SetTime(time.Now().UTC())
+	cloudevent.SetType(controlapi.EventTokenRevokedEventType)
+	cloudevent.SetDataContentType(cloudevents.ApplicationJSON)
+	_ = cloudevent.SetData(evt)
+
+	return api.node.publishCloudEvent(systemNamespace, cloudevent)
+}
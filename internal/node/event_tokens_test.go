@@ -0,0 +1,160 @@
+package nexnode
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+	controlapi "github.com/synadia-io/nex/control-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+func eventTokenTestListener(t *testing.T, config *models.NodeConfiguration) *ApiListener {
+	svr, _ := startObjectStoreTestServer(t, t.TempDir())
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+
+	return &ApiListener{
+		node: &Node{
+			config:    config,
+			events:    newEventHistory(models.DefaultEventHistorySize),
+			log:       slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError})),
+			nc:        nc,
+			publicKey: "NTESTNODE",
+		},
+		eventTokens: newEventTokens(),
+	}
+}
+
+func TestIssueEventToken(t *testing.T) {
+	account, _ := nkeys.CreateAccount()
+	accountPub, _ := account.PublicKey()
+	signingKey, _ := nkeys.CreateAccount()
+	signingSeed, _ := signingKey.Seed()
+	signingPub, _ := signingKey.PublicKey()
+
+	api := eventTokenTestListener(t, &models.NodeConfiguration{
+		EventTokenAccount:           accountPub,
+		EventTokenMaxTTLMillisecond: 60000,
+		EventTokenSigningSeed:       string(signingSeed),
+	})
+
+	_, err := api.issueEventToken("tenant1", &controlapi.EventTokenRequest{TTLMillisecond: 120000})
+	if err == nil {
+		t.Fatal("expected token ttl exceeding the maximum to be rejected")
+	}
+
+	for _, namespace := range []string{"*", ">", "tenant.*", ""} {
+		_, err = api.issueEventToken(namespace, &controlapi.EventTokenRequest{})
+		if err == nil {
+			t.Fatalf("expected token for namespace %q to be rejected", namespace)
+		}
+	}
+
+	res, err := api.issueEventToken("tenant1", &controlapi.EventTokenRequest{Name: "alice", TTLMillisecond: 30000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userJwt, err := jwt.ParseDecoratedJWT([]byte(res.Creds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := jwt.DecodeUserClaims(userJwt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.Subject != res.Token.Id || claims.Issuer != signingPub || claims.IssuerAccount != accountPub {
+		t.Fatalf("unexpected token identity: subject=%s issuer=%s account=%s", claims.Subject, claims.Issuer, claims.IssuerAccount)
+	}
+
+	if claims.Expires != res.Token.ExpiresAt.Unix() || res.Token.ExpiresAt.Sub(res.Token.IssuedAt) != 30*time.Second {
+		t.Fatalf("unexpected token expiry: %s", res.Token.ExpiresAt)
+	}
+
+	if !claims.Sub.Allow.Contains("$NEX.events.tenant1.>") || !claims.Sub.Allow.Contains("$NEX.logs.tenant1.>") || len(claims.Sub.Allow) != 2 {
+		t.Fatalf("unexpected subscribe permissions: %v", claims.Sub.Allow)
+	}
+
+	if !claims.Pub.Deny.Contains(">") {
+		t.Fatalf("expected publishing to be denied, got %v", claims.Pub.Deny)
+	}
+
+	seed, err := jwt.ParseDecoratedNKey([]byte(res.Creds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub, _ := seed.PublicKey(); pub != res.Token.Id {
+		t.Fatalf("expected credential seed to belong to the token's user, got %s", pub)
+	}
+}
+
+func TestRevokeEventTokens(t *testing.T) {
+	signingKey, _ := nkeys.CreateAccount()
+	signingSeed, _ := signingKey.Seed()
+
+	api := eventTokenTestListener(t, &models.NodeConfiguration{
+		EventTokenSigningSeed: string(signingSeed),
+	})
+
+	first, err := api.issueEventToken("tenant1", &controlapi.EventTokenRequest{Name: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = api.issueEventToken("tenant1", &controlapi.EventTokenRequest{Name: "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = api.issueEventToken("tenant2", &controlapi.EventTokenRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := api.eventTokens.list("tenant1")
+	if len(tokens) != 2 || tokens[0].Name != "first" || tokens[1].Name != "second" {
+		t.Fatalf("unexpected tokens: %+v", tokens)
+	}
+
+	err = api.revokeEventTokens("tenant2", []string{first.Token.Id})
+	if err == nil {
+		t.Fatal("expected revoking a token issued for another namespace to fail")
+	}
+
+	err = api.revokeEventTokens("tenant1", []string{first.Token.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = api.revokeEventTokens("tenant1", []string{first.Token.Id})
+	if err == nil {
+		t.Fatal("expected revoking a revoked token to fail")
+	}
+
+	tokens = api.eventTokens.list("tenant1")
+	if !tokens[0].Revoked || tokens[1].Revoked {
+		t.Fatalf("expected only the first token to be revoked: %+v", tokens)
+	}
+
+	events := api.node.events.replay(systemNamespace, "", controlapi.EventTokenRevokedEventType, 0)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event token revoked event, got %d", len(events))
+	}
+}
+
+func TestIssueEventTokenDisabled(t *testing.T) {
+	api := eventTokenTestListener(t, &models.NodeConfiguration{})
+
+	_, err := api.issueEventToken("tenant1", &controlapi.EventTokenRequest{})
+	if err == nil {
+		t.Fatal("expected event tokens to be disabled without a signing seed")
+	}
+}
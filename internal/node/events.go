@@ -3,6 +3,7 @@ package nexnode
 import (
 	"fmt"
 	"log/slog"
+	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go"
 	"github.com/nats-io/nats.go"
@@ -13,6 +14,23 @@ type emittedLog struct {
 	Text  string     `json:"text"`
 	Level slog.Level `json:"level"`
 	ID    string     `json:"id"`
+	// Time at which the agent received the line from the workload
+	LoggedAt time.Time `json:"logged_at"`
+	// Number of the workload's log lines dropped by the agent's log rate limit, set on the notice
+	// reporting them
+	Dropped uint64 `json:"dropped,omitempty"`
+}
+
+// Publishes the given event to the given namespace, recording it in the node's event history
+func (n *Node) publishCloudEvent(namespace string, event cloudevents.Event) error {
+	n.events.record(namespace, event)
+	return PublishCloudEvent(n.nc, namespace, event, n.log)
+}
+
+// Publishes the given event to the given namespace, recording it in the node's event history
+func (w *WorkloadManager) publishCloudEvent(namespace string, event cloudevents.Event) error {
+	w.events.record(namespace, event)
+	return PublishCloudEvent(w.nc, namespace, event, w.log)
 }
 
 // publish the given $NEX event to an arbitrary namespace using the given NATS connection
@@ -0,0 +1,131 @@
+package nexnode
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+const gitSourceTimeout = 10 * time.Minute
+
+// Clones the given git source, verifies the pinned commit, runs the declared build command (if any)
+// and returns the bytes of the resulting artifact. Output of git and the build command is captured
+// to the node log
+func (w *WorkloadManager) resolveGitSource(workloadName string, source *controlapi.GitSource) ([]byte, error) {
+	err := source.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid git source: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(w.ctx, gitSourceTimeout)
+	defer cancel()
+
+	dir, err := os.MkdirTemp("", fmt.Sprintf("nex-git-%s-", workloadName))
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	log := w.log.With(slog.String("workload", workloadName), slog.String("repository", source.Repository))
+	log.Info("Cloning workload git source", slog.String("ref", source.Ref), slog.String("commit", source.Commit))
+
+	cloneArgs := []string{"clone", "--quiet"}
+	if source.Ref != "" {
+		cloneArgs = append(cloneArgs, "--branch", source.Ref)
+	}
+	cloneArgs = append(cloneArgs, "--", source.Repository, dir)
+
+	_, err = runGitSourceCommand(ctx, log, "", "git", cloneArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone git source: %s", err)
+	}
+
+	tip, err := runGitSourceCommand(ctx, log, dir, "git", "rev-parse", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve cloned ref: %s", err)
+	}
+
+	_, err = runGitSourceCommand(ctx, log, dir, "git", "checkout", "--quiet", "--detach", source.Commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check out commit %s: %s", source.Commit, err)
+	}
+
+	head, err := runGitSourceCommand(ctx, log, dir, "git", "rev-parse", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify checked out commit: %s", err)
+	}
+
+	if strings.TrimSpace(head) != source.Commit {
+		return nil, fmt.Errorf("checked out commit %s does not match pinned commit %s", strings.TrimSpace(head), source.Commit)
+	}
+
+	if source.Ref != "" {
+		_, err = runGitSourceCommand(ctx, log, dir, "git", "merge-base", "--is-ancestor", source.Commit, strings.TrimSpace(tip))
+		if err != nil {
+			return nil, fmt.Errorf("pinned commit %s is not reachable from ref %s", source.Commit, source.Ref)
+		}
+	}
+
+	workDir := filepath.Join(dir, filepath.Clean(source.Subpath))
+
+	if source.BuildCommand != nil && strings.TrimSpace(*source.BuildCommand) != "" {
+		log.Info("Building workload from git source", slog.String("build_command", *source.BuildCommand))
+
+		_, err = runGitSourceCommand(ctx, log, workDir, "sh", "-c", *source.BuildCommand)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build workload from git source: %s", err)
+		}
+	}
+
+	artifact, err := os.ReadFile(filepath.Join(workDir, filepath.Clean(source.Artifact)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read built artifact: %s", err)
+	}
+
+	log.Info("Resolved workload artifact from git source", slog.String("commit", source.Commit), slog.Int("bytes", len(artifact)))
+	return artifact, nil
+}
+
+// Runs the given command in the given directory, logging its combined output and returning its stdout
+func runGitSourceCommand(ctx context.Context, log *slog.Logger, dir string, name string, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	stderr := &gitSourceOutputLogger{log: log, command: name}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stdout = &stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	stderr.logLines(stdout.Bytes())
+
+	return stdout.String(), err
+}
+
+// Writes each line of command output to the node log
+type gitSourceOutputLogger struct {
+	log     *slog.Logger
+	command string
+}
+
+func (g *gitSourceOutputLogger) Write(p []byte) (int, error) {
+	g.logLines(p)
+	return len(p), nil
+}
+
+func (g *gitSourceOutputLogger) logLines(p []byte) {
+	for _, line := range strings.Split(strings.TrimSpace(string(p)), "\n") {
+		if line != "" {
+			g.log.Info(line, slog.String("command", g.command))
+		}
+	}
+}
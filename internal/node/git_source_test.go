@@ -0,0 +1,92 @@
+package nexnode
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+func initTestGitRepo(t *testing.T) (string, string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=nex", "GIT_AUTHOR_EMAIL=nex@example.com",
+			"GIT_COMMITTER_NAME=nex", "GIT_COMMITTER_EMAIL=nex@example.com",
+		)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %s: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	git("init", "--quiet", "--initial-branch=main")
+	err := os.MkdirAll(filepath.Join(dir, "fn"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "fn", "src.js"), []byte("export default function() {}"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	git("add", ".")
+	git("commit", "--quiet", "-m", "initial")
+
+	return dir, git("rev-parse", "HEAD")
+}
+
+func TestResolveGitSource(t *testing.T) {
+	repo, commit := initTestGitRepo(t)
+
+	w := &WorkloadManager{
+		ctx: context.Background(),
+		log: slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+	}
+
+	build := "cp src.js out.js"
+	artifact, err := w.resolveGitSource("echofunction", &controlapi.GitSource{
+		Repository:   repo,
+		Ref:          "main",
+		Commit:       commit,
+		Subpath:      "fn",
+		BuildCommand: &build,
+		Artifact:     "out.js",
+	})
+	if err != nil {
+		t.Fatalf("expected git source to resolve: %s", err)
+	}
+
+	if string(artifact) != "export default function() {}" {
+		t.Fatalf("unexpected artifact contents: %s", artifact)
+	}
+
+	_, err = w.resolveGitSource("echofunction", &controlapi.GitSource{
+		Repository: repo,
+		Commit:     strings.Repeat("a", 40),
+		Artifact:   "fn/src.js",
+	})
+	if err == nil {
+		t.Fatal("expected unknown pinned commit to be rejected")
+	}
+
+	_, err = w.resolveGitSource("echofunction", &controlapi.GitSource{
+		Repository: repo,
+		Commit:     commit,
+		Artifact:   "../etc/passwd",
+	})
+	if err == nil {
+		t.Fatal("expected artifact path outside of the repository to be rejected")
+	}
+}
@@ -0,0 +1,111 @@
+package nexnode
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go"
+	"github.com/google/uuid"
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+// Key of the object written to and read back from the internal object store by the liveness probe.
+// Workload names cannot contain a dash, so this never collides with cached workloads
+const storeProbeKey = "nex-store-probe"
+
+// Returns true unless the liveness probe of the internal object store is failing
+func (n *Node) IsHealthy() bool {
+	return atomic.LoadUint32(&n.unhealthy) == 0
+}
+
+// Probes the internal object store at the configured interval until the node shuts down
+func (n *Node) probeObjectStore() {
+	ticker := time.NewTicker(time.Duration(n.config.StoreProbeIntervalMillisecond) * time.Millisecond)
+	defer ticker.Stop()
+
+	for !n.shuttingDown() {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+			n.checkObjectStore()
+		}
+	}
+}
+
+// Marks the node unhealthy when the object store probe fails, and healthy once it succeeds
+// again, publishing an event on each transition. Optionally enters lame duck mode on failure;
+// note that lame duck mode persists after the object store recovers
+func (n *Node) checkObjectStore() {
+	err := n.manager.probeObjectStore()
+	if err == nil {
+		if atomic.CompareAndSwapUint32(&n.unhealthy, 1, 0) {
+			n.log.Info("Internal object store recovered; node is healthy")
+			_ = n.publishNodeHealthChanged(nil)
+		}
+		return
+	}
+
+	if !atomic.CompareAndSwapUint32(&n.unhealthy, 0, 1) {
+		return
+	}
+
+	n.log.Error("Internal object store probe failed; node is unhealthy", slog.Any("err", err))
+	_ = n.publishNodeHealthChanged(err)
+
+	if n.config.StoreProbeLameDuck {
+		err = n.EnterLameDuck()
+		if err != nil {
+			n.log.Error("Failed to enter lame duck mode", slog.Any("err", err))
+		}
+	}
+}
+
+func (n *Node) publishNodeHealthChanged(probeErr error) error {
+	evt := controlapi.NodeHealthChangedEvent{
+		Id:      n.publicKey,
+		Healthy: probeErr == nil,
+	}
+	if probeErr != nil {
+		evt.Reason = probeErr.Error()
+	}
+
+	cloudevent := cloudevents.NewEvent()
+	cloudevent.SetSource(n.publicKey)
+	cloudevent.SetID(uuid.NewString())
+	cloudevent.SetTime(time.Now().UTC())
+	cloudevent.SetType(controlapi.NodeHealthChangedEventType)
+	cloudevent.SetDataContentType(cloudevents.ApplicationJSON)
+	_ = cloudevent.SetData(evt)
+
+	return n.publishCloudEvent(systemNamespace, cloudevent)
+}
+
+// Writes a probe object to the internal object store and reads it back, returning an error if
+// the store cannot be written to or read from
+func (w *WorkloadManager) probeObjectStore() error {
+	cache, err := w.internalCache()
+	if err != nil {
+		return err
+	}
+
+	probe := []byte(time.Now().UTC().Format(time.RFC3339Nano))
+	_, err = cache.PutBytes(storeProbeKey, probe)
+	if err != nil {
+		return err
+	}
+
+	read, err := cache.GetBytes(storeProbeKey)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(probe, read) {
+		return errors.New("object store probe read back unexpected contents")
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package nexnode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+// Maximum time given to in-flight HTTP requests to complete when the gateway is stopped
+const httpGatewayShutdownTimeout = 5 * time.Second
+
+// Serves HTTP requests by triggering the workloads to which their paths are routed, translating each
+// request into a trigger on the route's trigger subject and mapping the workload's response back.
+// Triggers are requested through the node's NATS connection, so they are routed among the workloads
+// sharing the subject exactly as any other trigger would be
+type httpGateway struct {
+	log     *slog.Logger
+	nc      *nats.Conn
+	config  *models.HTTPGatewayConfig
+	timeout time.Duration
+
+	server *http.Server
+}
+
+func newHTTPGateway(nc *nats.Conn, config *models.HTTPGatewayConfig, log *slog.Logger) *httpGateway {
+	g := &httpGateway{
+		log:     log,
+		nc:      nc,
+		config:  config,
+		timeout: config.ResolveTimeout(),
+	}
+
+	// requests matching no route are answered with a 404 by the mux
+	mux := http.NewServeMux()
+	for _, route := range config.Routes {
+		mux.HandleFunc(route.Pattern(), g.routeHandler(route))
+	}
+
+	g.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: g.timeout,
+	}
+
+	return g
+}
+
+func (g *httpGateway) start() error {
+	listener, err := net.Listen("tcp", g.config.Listen)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		err := g.server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			g.log.Error("HTTP gateway stopped unexpectedly", slog.Any("err", err))
+		}
+	}()
+
+	g.log.Info("HTTP gateway started", slog.String("listen", listener.Addr().String()), slog.Int("routes", len(g.config.Routes)))
+	return nil
+}
+
+func (g *httpGateway) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), httpGatewayShutdownTimeout)
+	defer cancel()
+
+	err := g.server.Shutdown(ctx)
+	if err != nil {
+		g.log.Warn("Failed to stop HTTP gateway gracefully", slog.Any("err", err))
+	}
+}
+
+func (g *httpGateway) routeHandler(route models.HTTPGatewayRoute) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, g.nc.MaxPayload()))
+		if err != nil {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		payload, _ := json.Marshal(&controlapi.HTTPTriggerRequest{
+			Method:  r.Method,
+			Path:    r.URL.Path,
+			Query:   r.URL.RawQuery,
+			Headers: r.Header,
+			Body:    string(body),
+		})
+
+		ctx, cancel := context.WithTimeout(r.Context(), g.timeout)
+		defer cancel()
+
+		resp, err := g.nc.RequestWithContext(ctx, route.TriggerSubject, payload)
+		if err != nil {
+			g.log.Warn("Failed to trigger workload for HTTP request",
+				slog.String("path", r.URL.Path),
+				slog.String("trigger_subject", route.TriggerSubject),
+				slog.Any("err", err),
+			)
+
+			switch {
+			case errors.Is(err, nats.ErrNoResponders):
+				http.Error(w, "no workload available", http.StatusServiceUnavailable)
+			case errors.Is(err, context.DeadlineExceeded), errors.Is(err, nats.ErrTimeout):
+				http.Error(w, "workload did not respond", http.StatusGatewayTimeout)
+			default:
+				http.Error(w, "failed to trigger workload", http.StatusBadGateway)
+			}
+			return
+		}
+
+		if reason := resp.Header.Get(controlapi.TriggerErrorHeader); reason != "" {
+			g.log.Warn("Trigger refused for HTTP request",
+				slog.String("path", r.URL.Path),
+				slog.String("trigger_subject", route.TriggerSubject),
+				slog.String("reason", reason),
+			)
+			http.Error(w, reason, http.StatusForbidden)
+			return
+		}
+
+		triggerResp := controlapi.ParseHTTPTriggerResponse(resp.Data)
+		if triggerResp == nil {
+			_, _ = w.Write(resp.Data)
+			return
+		}
+
+		for key, values := range triggerResp.Headers {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+		w.WriteHeader(triggerResp.Status)
+		_, _ = io.WriteString(w, triggerResp.Body)
+	}
+}
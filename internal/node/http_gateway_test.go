@@ -0,0 +1,106 @@
+package nexnode
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+func TestHTTPGatewayRoutesByPath(t *testing.T) {
+	svr, _ := startObjectStoreTestServer(t, t.TempDir())
+	defer svr.Shutdown()
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatalf("failed to connect to nats server: %s", err)
+	}
+	defer nc.Close()
+
+	requests := make(chan controlapi.HTTPTriggerRequest, 1)
+	_, _ = nc.Subscribe("trigger.a", func(msg *nats.Msg) {
+		var req controlapi.HTTPTriggerRequest
+		_ = json.Unmarshal(msg.Data, &req)
+		requests <- req
+
+		resp, _ := json.Marshal(&controlapi.HTTPTriggerResponse{
+			Status:  http.StatusCreated,
+			Headers: map[string][]string{"X-Workload": {"a"}},
+			Body:    "created",
+		})
+		_ = msg.Respond(resp)
+	})
+	_, _ = nc.Subscribe("trigger.b", func(msg *nats.Msg) {
+		_ = msg.Respond([]byte("hello from b"))
+	})
+	_, _ = nc.Subscribe("trigger.d", func(msg *nats.Msg) {
+		_ = agentapi.RefuseTrigger(msg, "not authorized")
+	})
+
+	gateway := newHTTPGateway(nc, &models.HTTPGatewayConfig{
+		Routes: []models.HTTPGatewayRoute{
+			{Path: "/a/*", TriggerSubject: "trigger.a"},
+			{Path: "/b", TriggerSubject: "trigger.b"},
+			{Path: "/c/*", TriggerSubject: "trigger.c"},
+			{Path: "/d/*", TriggerSubject: "trigger.d"},
+		},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	srv := httptest.NewServer(gateway.server.Handler)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/a/items/1?force=true", strings.NewReader("payload"))
+	req.Header.Set("X-Request", "r1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to request routed path: %s", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("X-Workload") != "a" || string(body) != "created" {
+		t.Fatalf("expected workload's response to be mapped back: %d %v %q", resp.StatusCode, resp.Header, body)
+	}
+
+	triggered := <-requests
+	if triggered.Method != http.MethodPut || triggered.Path != "/a/items/1" || triggered.Query != "force=true" ||
+		triggered.Body != "payload" || http.Header(triggered.Headers).Get("X-Request") != "r1" {
+		t.Fatalf("expected request to be preserved in the trigger: %+v", triggered)
+	}
+
+	resp, err = http.Get(srv.URL + "/b")
+	if err != nil {
+		t.Fatalf("failed to request routed path: %s", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || string(body) != "hello from b" {
+		t.Fatalf("expected raw workload response to be returned as the body: %d %q", resp.StatusCode, body)
+	}
+
+	for path, status := range map[string]int{
+		"/b/nested": http.StatusNotFound,
+		"/other":    http.StatusNotFound,
+		"/c/x":      http.StatusServiceUnavailable,
+		"/d/x":      http.StatusForbidden,
+	} {
+		resp, err = http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("failed to request %s: %s", path, err)
+		}
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != status {
+			t.Fatalf("expected %d for %s, got %d", status, path, resp.StatusCode)
+		}
+	}
+}
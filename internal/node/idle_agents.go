@@ -0,0 +1,141 @@
+package nexnode
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go"
+	"github.com/google/uuid"
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+// An agent reaped from the warm pool after sitting idle
+type reapedAgent struct {
+	id   string
+	idle time.Duration
+}
+
+// Periodically stops agents which have sat idle in the warm pool longer than the configured
+// threshold, shrinking the pool toward its lower bound. Disabled unless a threshold is configured
+func (w *WorkloadManager) reapIdleAgents() {
+	if w.config.ResolveIdleAgentReapAfter() == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(w.config.ResolveIdleAgentReapInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			if w.stopping() {
+				return
+			}
+
+			reaped, target := w.expiredIdleAgents(time.Now().UTC())
+			for _, agent := range reaped {
+				w.log.Info("Reaping idle agent", slog.String("workload_id", agent.id), slog.Duration("idle", agent.idle), slog.Int("pool_target", target))
+
+				err := w.procMan.StopProcess(agent.id)
+				if err != nil {
+					w.log.Warn("Failed to stop idle agent", slog.String("workload_id", agent.id), slog.Any("err", err))
+				}
+
+				_ = w.publishAgentReapedIdle(agent, target)
+			}
+		}
+	}
+}
+
+// Removes agents which have sat idle longer than the configured threshold from the pool, longest
+// idle first, and lowers the pool target so that they are not replaced. The pool target is never
+// lowered below the lower pool bound. Only unclaimed agents are considered; agents running
+// workloads, and agents prewarmed for an artifact, are left alone. Returns the reaped agents along
+// with the new pool target
+func (w *WorkloadManager) expiredIdleAgents(now time.Time) ([]reapedAgent, int) {
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
+	target := w.procMan.GetPoolTarget()
+	poolMin, _ := w.config.ResolveMachinePoolBounds()
+	threshold := w.config.ResolveIdleAgentReapAfter()
+
+	idle := make([]reapedAgent, 0)
+	for id := range w.pendingAgents {
+		if _, ok := w.prewarmed[id]; ok {
+			continue
+		}
+
+		since, err := time.Parse(time.RFC3339, w.handshakes[id])
+		if err != nil || now.Sub(since) < threshold {
+			continue
+		}
+
+		idle = append(idle, reapedAgent{id: id, idle: now.Sub(since)})
+	}
+
+	slices.SortFunc(idle, func(a, b reapedAgent) int {
+		return cmp.Compare(b.idle, a.idle)
+	})
+
+	reaped := idle[:max(min(len(idle), target-poolMin), 0)]
+	if len(reaped) == 0 {
+		return nil, target
+	}
+
+	err := w.procMan.SetPoolTarget(target - len(reaped))
+	if err != nil {
+		w.log.Warn("Failed to shrink machine pool target", slog.Any("err", err))
+		return nil, target
+	}
+
+	for _, agent := range reaped {
+		_ = w.pendingAgents[agent.id].Drain()
+		delete(w.pendingAgents, agent.id)
+		delete(w.stopMutex, agent.id)
+		delete(w.handshakes, agent.id)
+	}
+	w.reapedAgents += len(reaped)
+
+	return reaped, target - len(reaped)
+}
+
+// Restores one of the warm pool slots given up by reaping idle agents, if any, as demand returns.
+// The caller must hold the pool mutex
+func (w *WorkloadManager) restoreReapedAgent() {
+	if w.reapedAgents == 0 {
+		return
+	}
+
+	target := w.procMan.GetPoolTarget() + 1
+	err := w.procMan.SetPoolTarget(target)
+	if err != nil {
+		w.log.Warn("Failed to restore machine pool target", slog.Int("target", target), slog.Any("err", err))
+		return
+	}
+
+	w.reapedAgents--
+	w.log.Debug("Restored machine pool slot given up by idle agent", slog.Int("target", target))
+}
+
+func (w *WorkloadManager) publishAgentReapedIdle(agent reapedAgent, target int) error {
+	evt := controlapi.AgentReapedIdleEvent{
+		Id:              agent.id,
+		IdleMillisecond: agent.idle.Milliseconds(),
+		PoolTarget:      target,
+	}
+
+	cloudevent := cloudevents.NewEvent()
+	cloudevent.SetSource(w.publicKey)
+	cloudevent.SetID(uuid.NewString())
+	cloudevent.SetTime(time.Now().UTC())
+	cloudevent.SetType(controlapi.AgentReapedIdleEventType)
+	cloudevent.SetDataContentType(cloudevents.ApplicationJSON)
+	_ = cloudevent.SetData(evt)
+
+	return w.publishCloudEvent(systemNamespace, cloudevent)
+}
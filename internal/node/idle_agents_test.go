@@ -0,0 +1,95 @@
+package nexnode
+
+import (
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+// Process manager keeping a warm pool target
+type targetedProcessManager struct {
+	idleProcessManager
+	target *int
+}
+
+func (m targetedProcessManager) GetPoolTarget() int {
+	return *m.target
+}
+
+func (m targetedProcessManager) SetPoolTarget(target int) error {
+	*m.target = target
+	return nil
+}
+
+func TestIdleAgentsReapedTowardPoolMinimum(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	now := time.Now().UTC()
+
+	target := 4
+	w := &WorkloadManager{
+		config: &models.NodeConfiguration{
+			MachinePoolSize:               4,
+			MachinePoolMin:                2,
+			IdleAgentReapAfterMillisecond: int(time.Minute.Milliseconds()),
+		},
+		log:           log,
+		procMan:       targetedProcessManager{target: &target},
+		pendingAgents: make(map[string]*agentapi.AgentClient),
+		prewarmed:     map[string]*prewarmedAgent{"vm4": {}},
+		handshakes:    make(map[string]string),
+		poolMutex:     &sync.Mutex{},
+		stopMutex:     make(map[string]*sync.Mutex),
+	}
+
+	handshakes := map[string]time.Duration{
+		"vm1": 10 * time.Minute,
+		"vm2": 5 * time.Minute,
+		"vm3": 3 * time.Minute,
+		"vm4": 10 * time.Minute, // prewarmed for an artifact
+		"vm5": 30 * time.Second, // not idle long enough
+	}
+	for id, idle := range handshakes {
+		w.pendingAgents[id] = agentapi.NewAgentClient(nil, log, time.Minute, time.Second, time.Second, 0, false, nil, nil, nil, nil, nil, nil, nil)
+		w.handshakes[id] = now.Add(-idle).Format(time.RFC3339)
+	}
+
+	reaped, newTarget := w.expiredIdleAgents(now)
+	if len(reaped) != 2 || reaped[0].id != "vm1" || reaped[1].id != "vm2" {
+		t.Fatalf("expected the two longest idle agents to be reaped down to the pool minimum: %+v", reaped)
+	}
+
+	if newTarget != 2 || target != 2 {
+		t.Fatalf("expected pool target to be lowered to the pool minimum, got %d", target)
+	}
+
+	for _, id := range []string{"vm3", "vm4", "vm5"} {
+		if _, ok := w.pendingAgents[id]; !ok {
+			t.Fatalf("expected agent %s to remain in the pool", id)
+		}
+	}
+
+	for _, agent := range reaped {
+		if _, ok := w.handshakes[agent.id]; ok {
+			t.Fatalf("expected the handshake of reaped agent %s to be forgotten", agent.id)
+		}
+	}
+
+	if reaped, _ = w.expiredIdleAgents(now); len(reaped) != 0 {
+		t.Fatal("expected no agents to be reaped below the pool minimum")
+	}
+
+	w.poolMutex.Lock()
+	w.restoreReapedAgent()
+	w.restoreReapedAgent()
+	w.restoreReapedAgent()
+	w.poolMutex.Unlock()
+
+	if target != 4 {
+		t.Fatalf("expected reaped pool slots to be restored as demand returns, got target %d", target)
+	}
+}
@@ -23,6 +23,15 @@ func CmdUp(
 		return fmt.Errorf("failed to initialize node: %s", err)
 	}
 
+	if node.config.SafeMode {
+		node.Start()
+		if !node.report.Passed {
+			return ErrSelfTestFailed
+		}
+
+		return nil
+	}
+
 	go node.Start()
 
 	return nil
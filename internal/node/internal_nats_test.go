@@ -0,0 +1,40 @@
+package nexnode
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/synadia-io/nex/internal/models"
+)
+
+func TestStartInternalNATSAwaitsReadiness(t *testing.T) {
+	host := "127.0.0.1"
+	n := &Node{
+		config: &models.NodeConfiguration{
+			InternalNodeHost:     &host,
+			InternalNatsStoreDir: filepath.Join(t.TempDir(), "pnats"),
+			NoSandbox:            true,
+		},
+		log: slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError})),
+	}
+
+	err := n.startInternalNATS()
+	if err != nil {
+		t.Fatalf("expected internal NATS to start, got %s", err)
+	}
+	defer func() {
+		n.ncint.Close()
+		n.natsint.Shutdown()
+		n.natsint.WaitForShutdown()
+	}()
+
+	if n.config.InternalNodePort == nil || *n.config.InternalNodePort <= 0 {
+		t.Fatalf("expected the internal node port to be that of the listening server, got %v", n.config.InternalNodePort)
+	}
+
+	if !n.ncint.IsConnected() {
+		t.Fatal("expected the internal connection to be established once the server is ready")
+	}
+}
@@ -0,0 +1,153 @@
+package nexnode
+
+import (
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+	"github.com/synadia-io/nex/internal/node/processmanager"
+)
+
+// Assembles a snapshot of the node's hardware, configuration, pool and running workloads.
+// Workloads are ordered by id so that successive snapshots of an unchanged node are identical
+func (n *Node) inventory(request *controlapi.InventoryRequest) (*controlapi.InventoryResponse, error) {
+	workloads, err := n.manager.inventoryWorkloads(request.IncludeUsage)
+	if err != nil {
+		return nil, err
+	}
+
+	memory, _ := ReadMemoryStats()
+	poolMin, poolMax := n.config.ResolveMachinePoolBounds()
+
+	config := controlapi.InventoryConfig{
+		Sandboxed:    !n.config.NoSandbox,
+		HostServices: n.config.HostServicesConfiguration != nil,
+		Tags:         n.config.Tags,
+		MaxWorkloads: n.config.MaxWorkloads,
+
+		DefaultWorkloadEnvironment: n.config.RedactedDefaultWorkloadEnvironment(),
+	}
+	if config.Sandboxed {
+		config.VcpuCount = *n.config.MachineTemplate.VcpuCount
+		config.MemSizeMib = *n.config.MachineTemplate.MemSizeMib
+	}
+
+	return &controlapi.InventoryResponse{
+		NodeId:    n.publicKey,
+		Version:   VERSION,
+		Timestamp: time.Now().UTC(),
+		Healthy:   n.IsHealthy(),
+		LameDuck:  n.IsLameDuck(),
+		Hardware: controlapi.InventoryHardware{
+			Cpus:   runtime.NumCPU(),
+			Memory: memory,
+		},
+		Capacity:      n.capacity(memory, workloads),
+		Config:        config,
+		WorkloadTypes: n.config.WorkloadTypes,
+		Pool: controlapi.InventoryPool{
+			Target: n.manager.GetPoolTarget(),
+			Min:    poolMin,
+			Max:    poolMax,
+			Idle:   n.manager.idleAgents(),
+		},
+		Workloads: workloads,
+	}, nil
+}
+
+// Returns the host resources which may be allocated to machines running workloads, along with those
+// allocated to the given workloads
+func (n *Node) capacity(memory *controlapi.MemoryStat, workloads []controlapi.InventoryWorkload) controlapi.InventoryCapacity {
+	capacity := controlapi.InventoryCapacity{
+		TotalVcpu:         runtime.NumCPU(),
+		ReservedVcpu:      n.config.ReservedHostVcpu,
+		ReservedMemoryMib: n.config.ReservedHostMemoryMib,
+	}
+	capacity.AllocatableVcpu = max(capacity.TotalVcpu-capacity.ReservedVcpu, 0)
+
+	if memory != nil && memory.MemTotal > 0 {
+		// /proc/meminfo reports memory in kB
+		capacity.TotalMemoryMib = memory.MemTotal / 1024
+		capacity.AllocatableMemoryMib = max(capacity.TotalMemoryMib-capacity.ReservedMemoryMib, 0)
+	}
+
+	for _, workload := range workloads {
+		if workload.Allocation != nil {
+			capacity.AllocatedVcpu += workload.Allocation.VcpuCount
+			capacity.AllocatedMemoryMib += workload.Allocation.MemSizeMib
+
+			if capacity.Namespaces == nil {
+				capacity.Namespaces = make(map[string]controlapi.NamespaceAllocation)
+			}
+			allocation := capacity.Namespaces[workload.Namespace]
+			allocation.Machines++
+			allocation.VcpuCount += workload.Allocation.VcpuCount
+			allocation.MemSizeMib += workload.Allocation.MemSizeMib
+			capacity.Namespaces[workload.Namespace] = allocation
+		}
+	}
+
+	return capacity
+}
+
+// Lists the running workloads along with the resources allocated to each and, if requested,
+// the usage recorded for each
+func (w *WorkloadManager) inventoryWorkloads(includeUsage bool) ([]controlapi.InventoryWorkload, error) {
+	procs, err := w.procMan.ListProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	var allocation *controlapi.WorkloadAllocation
+	if !w.config.NoSandbox {
+		allocation = &controlapi.WorkloadAllocation{
+			VcpuCount:  *w.config.MachineTemplate.VcpuCount,
+			MemSizeMib: *w.config.MachineTemplate.MemSizeMib,
+		}
+	}
+
+	reporter, _ := w.procMan.(processmanager.ProcessResourceReporter)
+
+	workloads := make([]controlapi.InventoryWorkload, len(procs))
+	for i, p := range procs {
+		workloads[i] = controlapi.InventoryWorkload{
+			Id:           p.ID,
+			Namespace:    p.Namespace,
+			Name:         p.Name,
+			WorkloadType: *p.DeployRequest.WorkloadType,
+			Hash:         p.DeployRequest.Hash,
+			Allocation:   allocation,
+		}
+
+		// machines sized for their workload are allotted other than the template's resources
+		if reporter != nil {
+			if vcpus, memSizeMib, ok := reporter.ProcessResources(p.ID); ok {
+				workloads[i].Allocation = &controlapi.WorkloadAllocation{VcpuCount: vcpus, MemSizeMib: memSizeMib}
+			}
+		}
+
+		agentClient, ok := w.activeAgent(p.ID)
+		if includeUsage && ok {
+			workloads[i].Usage = &controlapi.WorkloadUsage{
+				UptimeMillisecond:  agentClient.UptimeMillis().Milliseconds(),
+				ExecTimeNanosecond: agentClient.ExecTimeNanos(),
+			}
+		}
+	}
+
+	slices.SortFunc(workloads, func(a, b controlapi.InventoryWorkload) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	return workloads, nil
+}
+
+// Returns the number of idle agents currently in the warm pool
+func (w *WorkloadManager) idleAgents() int {
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
+	return len(w.pendingAgents)
+}
@@ -0,0 +1,42 @@
+package nexnode
+
+import (
+	"runtime"
+	"testing"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+func TestCapacitySubtractsReservedHostResources(t *testing.T) {
+	n := &Node{
+		config: &models.NodeConfiguration{
+			ReservedHostVcpu:      1,
+			ReservedHostMemoryMib: 512,
+		},
+	}
+
+	memory := &controlapi.MemoryStat{MemTotal: 4096 * 1024}
+	workloads := []controlapi.InventoryWorkload{
+		{Id: "a", Namespace: "default", Allocation: &controlapi.WorkloadAllocation{VcpuCount: 1, MemSizeMib: 256}},
+		{Id: "b", Namespace: "default", Allocation: &controlapi.WorkloadAllocation{VcpuCount: 1, MemSizeMib: 256}},
+	}
+
+	capacity := n.capacity(memory, workloads)
+
+	if capacity.TotalVcpu != runtime.NumCPU() || capacity.ReservedVcpu != 1 || capacity.AllocatableVcpu != max(runtime.NumCPU()-1, 0) {
+		t.Fatalf("unexpected vcpu capacity: %+v", capacity)
+	}
+
+	if capacity.TotalMemoryMib != 4096 || capacity.ReservedMemoryMib != 512 || capacity.AllocatableMemoryMib != 3584 {
+		t.Fatalf("unexpected memory capacity: %+v", capacity)
+	}
+
+	if capacity.AllocatedVcpu != 2 || capacity.AllocatedMemoryMib != 512 {
+		t.Fatalf("unexpected allocated resources: %+v", capacity)
+	}
+
+	if allocation := capacity.Namespaces["default"]; allocation != (controlapi.NamespaceAllocation{Machines: 2, VcpuCount: 2, MemSizeMib: 512}) {
+		t.Fatalf("unexpected allocation to namespace default: %+v", allocation)
+	}
+}
@@ -0,0 +1,99 @@
+package nexnode
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+// Ensures the key/value buckets declared by the given deploy request exist, creating any that do
+// not with the declared limits. Existing buckets are left as they are
+func (w *WorkloadManager) provisionKeyValueBuckets(request *agentapi.DeployRequest) error {
+	if len(request.KeyValueBuckets) == 0 {
+		return nil
+	}
+
+	if w.hostServices.kv == nil {
+		return errors.New("workload requires key/value buckets but the kv host service is not enabled")
+	}
+
+	js, err := w.hostServices.ncHostServices.Conn().JetStream()
+	if err != nil {
+		return fmt.Errorf("failed to provision key/value buckets: %s", err)
+	}
+
+	for _, bucket := range request.KeyValueBuckets {
+		name := w.keyValueBucketName(request, bucket)
+
+		_, err := js.KeyValue(name)
+		if err == nil {
+			continue
+		}
+
+		if errors.Is(err, nats.ErrBucketNotFound) {
+			_, err = js.CreateKeyValue(&nats.KeyValueConfig{
+				Bucket:       name,
+				History:      bucket.History,
+				MaxBytes:     bucket.MaxBytes,
+				MaxValueSize: bucket.MaxValueSize,
+				TTL:          time.Duration(bucket.TTLMillisecond) * time.Millisecond,
+			})
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to provision key/value bucket %s: %s", name, err)
+		}
+
+		w.log.Info("Provisioned key/value bucket for workload",
+			slog.String("bucket", name),
+			slog.String("namespace", *request.Namespace),
+			slog.String("workload", *request.WorkloadName),
+		)
+	}
+
+	return nil
+}
+
+// Deletes the key/value buckets declared by the given deploy request which are to be deleted
+// when the workload is stopped
+func (w *WorkloadManager) teardownKeyValueBuckets(request *agentapi.DeployRequest) {
+	if request == nil || w.hostServices.kv == nil {
+		return
+	}
+
+	for _, bucket := range request.KeyValueBuckets {
+		if !bucket.DeleteOnUndeploy {
+			continue
+		}
+
+		name := w.keyValueBucketName(request, bucket)
+
+		js, err := w.hostServices.ncHostServices.Conn().JetStream()
+		if err == nil {
+			err = js.DeleteKeyValue(name)
+		}
+
+		if err != nil {
+			w.log.Warn("Failed to delete key/value bucket for stopped workload", slog.String("bucket", name), slog.Any("err", err))
+			continue
+		}
+
+		w.log.Info("Deleted key/value bucket for stopped workload", slog.String("bucket", name))
+	}
+}
+
+// Resolves the name of the given bucket declared by the deploy request using the kv host service's
+// bucket name template, in which the declared name takes the place of the workload name
+func (w *WorkloadManager) keyValueBucketName(request *agentapi.DeployRequest, bucket controlapi.KeyValueBucket) string {
+	name := *request.WorkloadName
+	if bucket.Name != "" {
+		name = bucket.Name
+	}
+
+	return w.hostServices.kv.BucketName(*request.Namespace, name)
+}
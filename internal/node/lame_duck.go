@@ -0,0 +1,129 @@
+package nexnode
+
+import (
+	"log/slog"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go"
+	"github.com/google/uuid"
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+const lameDuckDrainInterval = time.Second
+
+// Returns the number of workloads still running on a node in lame duck mode and whether all have
+// stopped; nothing is reported until the node has entered lame duck mode
+func (w *WorkloadManager) LameDuckStatus() (int, bool) {
+	return int(w.lameDuckRemaining.Load()), w.lameDuckDrained.Load()
+}
+
+// Records the number of workloads running as the node enters lame duck mode, returning the ids of
+// those which are not essential, captured before entering lame duck mode marks every workload
+// non-essential
+func (w *WorkloadManager) beginLameDuckDrain() []string {
+	procs, err := w.procMan.ListProcesses()
+	if err != nil {
+		w.log.Warn("Failed to list workloads to drain", slog.Any("err", err))
+		return nil
+	}
+
+	ids := make([]string, 0)
+	for _, p := range procs {
+		if !p.DeployRequest.IsEssential() {
+			ids = append(ids, p.ID)
+		}
+	}
+
+	w.lameDuckRemaining.Store(int32(len(procs)))
+	return ids
+}
+
+// Stops the given non-essential workloads once the node has entered lame duck mode, then tracks
+// the workloads remaining until all have exited, publishing an event once the node is drained. Any
+// workload still running at the configured drain deadline is stopped
+func (w *WorkloadManager) drainLameDuck(nonEssential []string) {
+	deadline := time.Now().UTC().Add(w.config.ResolveLameDuckDrainTimeout())
+
+	for _, id := range nonEssential {
+		err := w.stopWorkload(id, true, controlapi.WorkloadStopReasonLameDuck)
+		if err != nil {
+			w.log.Warn("Failed to stop non-essential workload during lame duck drain", slog.String("workload_id", id), slog.Any("err", err))
+		}
+	}
+
+	ticker := time.NewTicker(lameDuckDrainInterval)
+	defer ticker.Stop()
+
+	forced := 0
+	expired := false
+	for {
+		remaining, err := w.trackLameDuckDrain()
+		if err != nil {
+			w.log.Warn("Failed to list workloads remaining in lame duck drain", slog.Any("err", err))
+		} else if len(remaining) == 0 {
+			break
+		} else if !expired && !time.Now().UTC().Before(deadline) {
+			w.log.Warn("Lame duck drain deadline passed; stopping remaining workloads", slog.Int("remaining", len(remaining)))
+
+			for _, id := range remaining {
+				err := w.stopWorkload(id, true, controlapi.WorkloadStopReasonDrainExpired)
+				if err != nil {
+					w.log.Warn("Failed to stop workload at lame duck drain deadline", slog.String("workload_id", id), slog.Any("err", err))
+				}
+			}
+
+			forced = len(remaining)
+			expired = true
+		}
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			if w.stopping() {
+				return
+			}
+		}
+	}
+
+	w.lameDuckDrained.Store(true)
+	w.log.Info("Node drained in lame duck mode", slog.Int("forced_stops", forced))
+
+	err := w.publishLameDuckDrained(forced)
+	if err != nil {
+		w.log.Warn("Failed to publish lame duck drained event", slog.Any("err", err))
+	}
+}
+
+// Records the number of workloads still running on a node in lame duck mode, returning their ids
+func (w *WorkloadManager) trackLameDuckDrain() ([]string, error) {
+	procs, err := w.procMan.ListProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(procs))
+	for i, p := range procs {
+		ids[i] = p.ID
+	}
+
+	w.lameDuckRemaining.Store(int32(len(ids)))
+	return ids, nil
+}
+
+func (w *WorkloadManager) publishLameDuckDrained(forced int) error {
+	evt := controlapi.LameDuckDrainedEvent{
+		Id:          w.publicKey,
+		ForcedStops: forced,
+	}
+
+	cloudevent := cloudevents.NewEvent()
+	cloudevent.SetSource(w.publicKey)
+	cloudevent.SetID(uuid.NewString())
+	cloudevent.SetTime(time.Now().UTC())
+	cloudevent.SetType(controlapi.LameDuckDrainedEventType)
+	cloudevent.SetDataContentType(cloudevents.ApplicationJSON)
+	_ = cloudevent.SetData(evt)
+
+	return w.publishCloudEvent(systemNamespace, cloudevent)
+}
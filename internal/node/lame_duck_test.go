@@ -0,0 +1,125 @@
+package nexnode
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/models"
+	"github.com/synadia-io/nex/internal/node/processmanager"
+)
+
+// Process manager listing a set of running workloads which exit when removed
+type drainingProcessManager struct {
+	idleProcessManager
+	mutex *sync.Mutex
+	procs []processmanager.ProcessInfo
+}
+
+func (m *drainingProcessManager) ListProcesses() ([]processmanager.ProcessInfo, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]processmanager.ProcessInfo{}, m.procs...), nil
+}
+
+func (m *drainingProcessManager) exit(id string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i, p := range m.procs {
+		if p.ID == id {
+			m.procs = append(m.procs[:i], m.procs[i+1:]...)
+			return
+		}
+	}
+}
+
+func TestLameDuckDrainTracksRemainingWorkloadsUntilDrained(t *testing.T) {
+	svr, _ := startObjectStoreTestServer(t, t.TempDir())
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	sub, err := nc.SubscribeSync(EventSubjectPrefix + "." + systemNamespace + "." + controlapi.LameDuckDrainedEventType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	essential := true
+	procMan := &drainingProcessManager{
+		mutex: &sync.Mutex{},
+		procs: []processmanager.ProcessInfo{
+			{ID: "vm1", DeployRequest: &agentapi.DeployRequest{Essential: &essential}},
+			{ID: "vm2", DeployRequest: &agentapi.DeployRequest{Essential: &essential}},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := &WorkloadManager{
+		config:    &models.NodeConfiguration{},
+		ctx:       ctx,
+		log:       slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError})),
+		nc:        nc,
+		procMan:   procMan,
+		publicKey: "node1",
+	}
+
+	if remaining, drained := w.LameDuckStatus(); remaining != 0 || drained {
+		t.Fatalf("expected no drain to be reported before entering lame duck mode, got %d %v", remaining, drained)
+	}
+
+	nonEssential := w.beginLameDuckDrain()
+	if len(nonEssential) != 0 {
+		t.Fatalf("expected only non-essential workloads to be stopped, got %v", nonEssential)
+	}
+	if remaining, drained := w.LameDuckStatus(); remaining != 2 || drained {
+		t.Fatalf("expected both workloads to remain on entering lame duck mode, got %d %v", remaining, drained)
+	}
+
+	go w.drainLameDuck(nonEssential)
+
+	procMan.exit("vm1")
+	for deadline := time.Now().Add(3 * time.Second); ; {
+		if remaining, _ := w.LameDuckStatus(); remaining == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the exited workload to no longer be counted as remaining")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	procMan.exit("vm2")
+	msg, err := sub.NextMsg(3 * time.Second)
+	if err != nil {
+		t.Fatalf("expected a drained event once every workload exited: %s", err)
+	}
+
+	var evt struct {
+		Data controlapi.LameDuckDrainedEvent `json:"data"`
+	}
+	err = json.Unmarshal(msg.Data, &evt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evt.Data.Id != "node1" || evt.Data.ForcedStops != 0 {
+		t.Fatalf("unexpected drained event %+v", evt.Data)
+	}
+
+	if remaining, drained := w.LameDuckStatus(); remaining != 0 || !drained {
+		t.Fatalf("expected the node to report it has drained, got %d %v", remaining, drained)
+	}
+}
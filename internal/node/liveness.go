@@ -0,0 +1,107 @@
+package nexnode
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/node/processmanager"
+)
+
+// Probes the liveness of the node's workloads at the configured interval until the workload manager
+// stops, restarting each workload whose agent fails the configured number of consecutive probes.
+// Disabled unless a probe interval is configured and the process manager can check the health of
+// its agent processes
+func (w *WorkloadManager) monitorWorkloadLiveness() {
+	interval := w.config.ResolveLivenessProbeInterval()
+	checker, ok := w.procMan.(processmanager.ProcessHealthChecker)
+	if interval == 0 || !ok {
+		return
+	}
+
+	failures := make(map[string]int)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			if w.stopping() {
+				return
+			}
+
+			for _, id := range w.unresponsiveWorkloads(checker, failures) {
+				w.log.Warn("Workload agent stopped responding to liveness probes; restarting workload",
+					slog.String("workload_id", id),
+					slog.Int("failed_probes", w.config.ResolveLivenessProbeFailureThreshold()),
+				)
+				w.restartWorkloadOrRedeploy(id)
+			}
+		}
+	}
+}
+
+// Probes the health of the agent running each workload, counting the consecutive probes each has
+// failed in the given map, and returns the ids of the workloads whose agents have failed as many
+// consecutive probes as the configured threshold
+func (w *WorkloadManager) unresponsiveWorkloads(checker processmanager.ProcessHealthChecker, failures map[string]int) []string {
+	threshold := w.config.ResolveLivenessProbeFailureThreshold()
+
+	w.poolMutex.Lock()
+	ids := make([]string, 0, len(w.activeAgents))
+	for id := range w.activeAgents {
+		ids = append(ids, id)
+	}
+	for id := range failures {
+		if _, ok := w.activeAgents[id]; !ok {
+			delete(failures, id)
+		}
+	}
+	w.poolMutex.Unlock()
+
+	unresponsive := make([]string, 0)
+	for _, id := range ids {
+		status, err := checker.HealthCheck(id)
+		if err != nil {
+			// the workload was stopped while it was being probed
+			delete(failures, id)
+			continue
+		}
+
+		if status.Responsive {
+			delete(failures, id)
+			continue
+		}
+
+		failures[id]++
+		if failures[id] >= threshold {
+			delete(failures, id)
+			unresponsive = append(unresponsive, id)
+		}
+	}
+
+	return unresponsive
+}
+
+// Pings the agent running the workload with the given id on behalf of the process manager's
+// liveness probes, returning its reply and the round-trip latency of the ping
+func (w *WorkloadManager) PingProcess(id string, timeout time.Duration) (*agentapi.AgentPingResponse, time.Duration, error) {
+	w.poolMutex.Lock()
+	agentClient, ok := w.activeAgents[id]
+	w.poolMutex.Unlock()
+	if !ok {
+		return nil, 0, fmt.Errorf("no agent running workload %s", id)
+	}
+
+	err := agentClient.Ping(timeout)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pong, latency := agentClient.LastPing()
+	return pong, latency, nil
+}
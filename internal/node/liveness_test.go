@@ -0,0 +1,122 @@
+package nexnode
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/models"
+	"github.com/synadia-io/nex/internal/node/processmanager"
+)
+
+// Health checker reporting a fixed health for each agent process
+type fixedHealthChecker map[string]bool
+
+func (c fixedHealthChecker) HealthCheck(id string) (processmanager.HealthStatus, error) {
+	responsive, ok := c[id]
+	if !ok {
+		return processmanager.HealthStatus{}, errors.New("no such workload")
+	}
+
+	return processmanager.HealthStatus{Responsive: responsive}, nil
+}
+
+func TestWorkloadUnresponsiveAfterConsecutiveFailedProbes(t *testing.T) {
+	w := &WorkloadManager{
+		config:       &models.NodeConfiguration{LivenessProbeFailureThreshold: 2},
+		activeAgents: map[string]*agentapi.AgentClient{"vm1": nil, "vm2": nil},
+		poolMutex:    &sync.Mutex{},
+	}
+
+	checker := fixedHealthChecker{"vm1": false, "vm2": true}
+	failures := make(map[string]int)
+
+	if unresponsive := w.unresponsiveWorkloads(checker, failures); len(unresponsive) != 0 {
+		t.Fatalf("expected no workload to be unresponsive after a single failed probe: %v", unresponsive)
+	}
+
+	checker["vm1"] = true
+	w.unresponsiveWorkloads(checker, failures)
+	if failures["vm1"] != 0 {
+		t.Fatal("expected a successful probe to reset the failed probe count")
+	}
+
+	checker["vm1"] = false
+	w.unresponsiveWorkloads(checker, failures)
+	unresponsive := w.unresponsiveWorkloads(checker, failures)
+	if len(unresponsive) != 1 || unresponsive[0] != "vm1" {
+		t.Fatalf("expected vm1 to be unresponsive after consecutive failed probes: %v", unresponsive)
+	}
+}
+
+func TestAgentPingRecordsLatency(t *testing.T) {
+	svr, _ := startObjectStoreTestServer(t, t.TempDir())
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	noop := func(string) {}
+
+	agentClient := agentapi.NewAgentClient(nc, log, time.Minute, time.Second, time.Second, 0, false, noop, noop, nil, nil, nil, nil, nil)
+	err = agentClient.Start("vm1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = agentClient.Drain() }()
+
+	err = agentClient.Ping(100 * time.Millisecond)
+	if err == nil {
+		t.Fatal("expected ping of an agent which does not answer to fail")
+	}
+
+	_, err = nc.Subscribe(agentapi.PingSubject("vm1"), func(msg *nats.Msg) {
+		raw, _ := json.Marshal(&agentapi.AgentPingResponse{UptimeMillisecond: 1500})
+		_ = msg.Respond(raw)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = agentClient.Ping(time.Second)
+	if err != nil {
+		t.Fatalf("expected ping to be answered: %s", err)
+	}
+
+	pong, latency := agentClient.LastPing()
+	if pong == nil || pong.UptimeMillisecond != 1500 || latency <= 0 {
+		t.Fatalf("expected ping reply and latency to be recorded: %+v %s", pong, latency)
+	}
+}
+
+// Process manager recording a deploy request for every workload
+type deployedProcessManager struct {
+	idleProcessManager
+}
+
+func (deployedProcessManager) Lookup(string) (*agentapi.DeployRequest, error) {
+	return &agentapi.DeployRequest{}, nil
+}
+
+func TestRestartingWorkloadWithoutAgentFails(t *testing.T) {
+	w := &WorkloadManager{
+		procMan:      deployedProcessManager{},
+		activeAgents: make(map[string]*agentapi.AgentClient),
+		poolMutex:    &sync.Mutex{},
+		stopMutex:    make(map[string]*sync.Mutex),
+	}
+
+	err := w.RestartWorkload("vm1")
+	if err == nil {
+		t.Fatal("expected restarting a workload with no agent to fail")
+	}
+}
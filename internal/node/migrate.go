@@ -0,0 +1,154 @@
+package nexnode
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go"
+	"github.com/google/uuid"
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+// Maximum time given to the target node to deploy a migrating workload
+const migrationTimeout = 30 * time.Second
+
+// Hands the given workload off to the target node: the workload's artifact reference, environment
+// and trigger subjects are redeployed on the target via the control API, after which the workload
+// is stopped on this node. If the target fails to deploy the workload, it keeps running here
+func (api *ApiListener) migrateWorkload(workloadID string, deployRequest *agentapi.DeployRequest, targetNode string) (*controlapi.MigrateResponse, error) {
+	client := controlapi.NewApiClientWithNamespace(api.node.nc, migrationTimeout, *deployRequest.Namespace, api.log)
+
+	info, err := client.NodeInfo(targetNode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query target node: %s", err)
+	}
+
+	if !slices.Contains(info.SupportedWorkloadTypes, *deployRequest.WorkloadType) {
+		return nil, fmt.Errorf("target node does not support workload type %s", *deployRequest.WorkloadType)
+	}
+
+	request, err := api.portableRequest(deployRequest, targetNode, info.PublicXKey)
+	if err != nil {
+		return nil, err
+	}
+
+	api.log.Info("Migrating workload",
+		slog.String("workload_id", workloadID),
+		slog.String("workload", *deployRequest.WorkloadName),
+		slog.String("target_node", targetNode),
+	)
+
+	err = api.mgr.signRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	runResponse, err := client.StartWorkload(request)
+	if err != nil {
+		return nil, fmt.Errorf("target node failed to deploy workload: %s", err)
+	}
+
+	if !runResponse.Started {
+		return nil, errors.New("target node did not start workload")
+	}
+
+	err = api.mgr.StopWorkload(workloadID, true)
+	if err != nil {
+		// the handoff itself succeeded, so the migration is still reported as complete
+		api.log.Error("Failed to stop migrated workload on source node",
+			slog.String("workload_id", workloadID),
+			slog.String("target_workload_id", runResponse.ID),
+			slog.Any("err", err),
+		)
+	}
+
+	resp := &controlapi.MigrateResponse{
+		Migrated:         true,
+		Name:             deployRequest.DecodedClaims.Subject,
+		Issuer:           deployRequest.DecodedClaims.Issuer,
+		SourceNode:       api.PublicKey(),
+		SourceWorkloadId: workloadID,
+		TargetNode:       targetNode,
+		TargetWorkloadId: runResponse.ID,
+	}
+
+	_ = api.publishWorkloadMigrated(*deployRequest.Namespace, resp)
+
+	return resp, nil
+}
+
+// Returns a deploy request with which the given workload can be redeployed on the target node, with
+// the given public xkey, carrying the workload's artifact reference and trigger subjects. The
+// environment was encrypted for this node, so it is re-encrypted for the target node
+func (api *ApiListener) portableRequest(deployRequest *agentapi.DeployRequest, targetNode string, targetXKey string) (*controlapi.DeployRequest, error) {
+	env, err := controlapi.EncryptRequestEnvironment(api.xk, targetXKey, deployRequest.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt environment for target node: %s", err)
+	}
+
+	senderPublicKey, err := api.xk.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	request := redeployableRequest(deployRequest)
+	request.Environment = &env
+	request.SenderPublicKey = &senderPublicKey
+	request.TargetNode = &targetNode
+
+	return request, nil
+}
+
+// Returns a deploy request with which the workload deployed by the given request can be redeployed,
+// lacking the workload's environment, its sender and its target node
+func redeployableRequest(deployRequest *agentapi.DeployRequest) *controlapi.DeployRequest {
+	return &controlapi.DeployRequest{
+		Argv:                       deployRequest.Argv,
+		ArtifactBucket:             deployRequest.ArtifactBucket,
+		CaptureLogs:                deployRequest.CaptureLogs,
+		Dependencies:               deployRequest.Dependencies,
+		Description:                deployRequest.Description,
+		WorkloadType:               deployRequest.WorkloadType,
+		Location:                   deployRequest.Location,
+		MemoryLimitMib:             deployRequest.MemoryLimitMib,
+		Resources:                  deployRequest.Resources,
+		WorkloadJwt:                deployRequest.WorkloadJwt,
+		GitSource:                  deployRequest.GitSource,
+		Essential:                  deployRequest.Essential,
+		StopGracePeriodMillisecond: deployRequest.StopGracePeriodMillisecond,
+		Tags:                       deployRequest.Tags,
+		TraceSamplingRate:          deployRequest.TraceSamplingRate,
+		TriggerSubjects:            deployRequest.TriggerSubjects,
+		TriggerTimeoutMillisecond:  deployRequest.TriggerTimeoutMillisecond,
+		AllowedTriggerSubjects:     deployRequest.AllowedTriggerSubjects,
+		JsDomain:                   deployRequest.JsDomain,
+		KeyValueBuckets:            deployRequest.KeyValueBuckets,
+		Uid:                        deployRequest.Uid,
+		Gid:                        deployRequest.Gid,
+		WorkingDirectory:           deployRequest.WorkingDirectory,
+	}
+}
+
+func (api *ApiListener) publishWorkloadMigrated(namespace string, resp *controlapi.MigrateResponse) error {
+	evt := controlapi.WorkloadMigratedEvent{
+		Name:             resp.Name,
+		SourceNode:       resp.SourceNode,
+		SourceWorkloadId: resp.SourceWorkloadId,
+		TargetNode:       resp.TargetNode,
+		TargetWorkloadId: resp.TargetWorkloadId,
+	}
+
+	cloudevent := cloudevents.NewEvent()
+	cloudevent.SetSource(api.PublicKey())
+	cloudevent.SetID(uuid.NewString())
+	cloudevent.SetTime(time.Now().UTC())
+	cloudevent.SetType(controlapi.WorkloadMigratedEventType)
+	cloudevent.SetDataContentType(cloudevents.ApplicationJSON)
+	_ = cloudevent.SetData(evt)
+
+	return api.node.publishCloudEvent(namespace, cloudevent)
+}
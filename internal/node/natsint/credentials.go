@@ -0,0 +1,188 @@
+package natsint
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/nats-io/nats-server/v2/server"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+const tokenBytes = 32
+
+// Credentials issued for a VM, with which its agent and the workloads it runs connect to the
+// node's internal NATS server
+type Credentials struct {
+	// Token of the agent, permitted only the VM's own internal subjects
+	AgentToken string
+
+	// Token of the agent's workloads, permitted only to publish the VM's metric samples
+	WorkloadToken string
+}
+
+// Authenticates connections to the node's internal NATS server by the token each presents, granting
+// the connection the permissions with which its token was issued. The node's own connections are
+// unrestricted, while the credentials issued for each VM confine its agent to the VM's own subjects,
+// so that subject isolation between workloads is enforced by the server rather than by convention
+type Authenticator struct {
+	mutex *sync.Mutex
+
+	nodeToken string
+	users     map[string]*server.User
+	issued    map[string][]string
+}
+
+func NewAuthenticator() (*Authenticator, error) {
+	nodeToken, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Authenticator{
+		mutex:     &sync.Mutex{},
+		nodeToken: nodeToken,
+		users: map[string]*server.User{
+			nodeToken: {Username: "node"},
+		},
+		issued: make(map[string][]string),
+	}, nil
+}
+
+// Returns the token with which the node connects to its internal NATS server without restriction
+func (a *Authenticator) NodeToken() string {
+	return a.nodeToken
+}
+
+// Check implements server.Authentication, admitting connections presenting a token issued by the
+// authenticator which has not been revoked
+func (a *Authenticator) Check(c server.ClientAuthentication) bool {
+	token := c.GetOpts().Token
+	if token == "" {
+		return false
+	}
+
+	a.mutex.Lock()
+	user, ok := a.users[token]
+	a.mutex.Unlock()
+	if !ok {
+		return false
+	}
+
+	c.RegisterUser(user)
+	return true
+}
+
+// Issues the credentials with which the agent running in the VM with the given id, and its
+// workloads, connect to the internal NATS server, replacing any previously issued for the VM
+func (a *Authenticator) CreateCredentials(vmID string) (*Credentials, error) {
+	agentToken, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	workloadToken, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.revoke(vmID)
+	a.users[agentToken] = &server.User{
+		Username:    fmt.Sprintf("agent-%s", vmID),
+		Permissions: AgentPermissions(vmID),
+	}
+	a.users[workloadToken] = &server.User{
+		Username:    fmt.Sprintf("workload-%s", vmID),
+		Permissions: WorkloadPermissions(vmID),
+	}
+	a.issued[vmID] = []string{agentToken, workloadToken}
+
+	return &Credentials{
+		AgentToken:    agentToken,
+		WorkloadToken: workloadToken,
+	}, nil
+}
+
+// Revokes the credentials issued for the VM with the given id, so that they can no longer be used
+// to connect. Connections already established with them are unaffected
+func (a *Authenticator) RevokeCredentials(vmID string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.revoke(vmID)
+}
+
+// Removes the tokens issued for the given VM; the mutex must be held
+func (a *Authenticator) revoke(vmID string) {
+	for _, token := range a.issued[vmID] {
+		delete(a.users, token)
+	}
+	delete(a.issued, vmID)
+}
+
+// Returns the permissions of the agent running in the VM with the given id. The agent may publish
+// and subscribe only on the VM's own internal subjects, subscribe to its own inboxes, and reply to
+// the node's requests. It may read from the internal object stores, except for the captured logs
+// of workloads, which it may only write, and may not administer the streams backing them
+func AgentPermissions(vmID string) *server.Permissions {
+	subjects := fmt.Sprintf("agentint.%s.>", vmID)
+	logsStream := fmt.Sprintf("OBJ_%s", agentapi.WorkloadLogsBucket)
+
+	return &server.Permissions{
+		Publish: &server.SubjectPermission{
+			Allow: []string{
+				subjects,
+				// replies to the node's requests, including streamed and partial trigger results
+				"_INBOX.>",
+				"$JS.API.INFO",
+				"$JS.API.STREAM.INFO.*",
+				"$JS.API.STREAM.MSG.GET.*",
+				"$JS.API.DIRECT.GET.>",
+				"$JS.API.CONSUMER.CREATE.>",
+				"$JS.API.CONSUMER.DELETE.>",
+				"$JS.API.CONSUMER.INFO.>",
+				"$JS.FC.>",
+				fmt.Sprintf("$O.%s.>", agentapi.WorkloadLogsBucket),
+			},
+			// writing an object reads its metadata, but the chunks of captured logs are never read
+			Deny: []string{
+				fmt.Sprintf("$JS.API.CONSUMER.CREATE.%s", logsStream),
+				fmt.Sprintf("$JS.API.CONSUMER.CREATE.%s.>", logsStream),
+				fmt.Sprintf("$JS.API.DIRECT.GET.%s.$O.%s.C.>", logsStream, agentapi.WorkloadLogsBucket),
+			},
+		},
+		Subscribe: &server.SubjectPermission{
+			Allow: []string{
+				subjects,
+				fmt.Sprintf("%s.>", agentapi.AgentInboxPrefix(vmID)),
+			},
+		},
+	}
+}
+
+// Returns the permissions of the workloads run by the agent in the VM with the given id, which
+// may only publish their metric samples
+func WorkloadPermissions(vmID string) *server.Permissions {
+	return &server.Permissions{
+		Publish: &server.SubjectPermission{
+			Allow: []string{agentapi.MetricsSubject(vmID)},
+		},
+		Subscribe: &server.SubjectPermission{
+			Deny: []string{">"},
+		},
+	}
+}
+
+func newToken() (string, error) {
+	token := make([]byte, tokenBytes)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate internal NATS token: %s", err)
+	}
+
+	return hex.EncodeToString(token), nil
+}
@@ -0,0 +1,235 @@
+package natsint
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+func startTestServer(t *testing.T, auth *Authenticator) *server.Server {
+	svr, err := server.NewServer(&server.Options{
+		Port:                       -1,
+		JetStream:                  true,
+		NoLog:                      true,
+		StoreDir:                   t.TempDir(),
+		CustomClientAuthentication: auth,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svr.Start()
+	if !svr.ReadyForConnections(5 * time.Second) {
+		t.Fatal("server not ready for connections")
+	}
+	t.Cleanup(func() {
+		svr.Shutdown()
+		svr.WaitForShutdown()
+	})
+
+	return svr
+}
+
+func connect(t *testing.T, svr *server.Server, opts ...nats.Option) *nats.Conn {
+	// permission violations are expected, and asserted on by their effect rather than reported
+	opts = append(opts, nats.ErrorHandler(func(*nats.Conn, *nats.Subscription, error) {}))
+
+	nc, err := nats.Connect(svr.ClientURL(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+
+	return nc
+}
+
+// Returns true if a message published on the given subject by the given connection is received
+// by a subscription of the node's connection
+func delivered(t *testing.T, node *nats.Conn, nc *nats.Conn, subject string) bool {
+	sub, err := node.SubscribeSync(subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+	_ = node.Flush()
+
+	_ = nc.Publish(subject, []byte("hello"))
+	_ = nc.Flush()
+
+	_, err = sub.NextMsg(250 * time.Millisecond)
+	return err == nil
+}
+
+// Returns true if the given connection receives a message published by the node's connection on
+// the given subject, to which it subscribes
+func received(t *testing.T, node *nats.Conn, nc *nats.Conn, subject string) bool {
+	sub, err := nc.SubscribeSync(subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+	_ = nc.Flush()
+
+	_ = node.Publish(subject, []byte("hello"))
+	_ = node.Flush()
+
+	_, err = sub.NextMsg(250 * time.Millisecond)
+	return err == nil
+}
+
+func TestCredentialsAreRequiredToConnect(t *testing.T) {
+	auth, _ := NewAuthenticator()
+	svr := startTestServer(t, auth)
+
+	_, err := nats.Connect(svr.ClientURL())
+	if err == nil {
+		t.Fatal("expected a connection without credentials to be rejected")
+	}
+
+	creds, err := auth.CreateCredentials("vm1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nc, err := nats.Connect(svr.ClientURL(), nats.Token(creds.AgentToken))
+	if err != nil {
+		t.Fatalf("expected a connection with the agent's credentials to be accepted, got %s", err)
+	}
+	nc.Close()
+
+	auth.RevokeCredentials("vm1")
+	_, err = nats.Connect(svr.ClientURL(), nats.Token(creds.AgentToken))
+	if err == nil {
+		t.Fatal("expected a connection with revoked credentials to be rejected")
+	}
+}
+
+func TestAgentCredentialsAreScopedToTheirVM(t *testing.T) {
+	auth, _ := NewAuthenticator()
+	svr := startTestServer(t, auth)
+	node := connect(t, svr, nats.Token(auth.NodeToken()))
+
+	creds, _ := auth.CreateCredentials("vm1")
+	agent := connect(t, svr, nats.Token(creds.AgentToken), nats.CustomInboxPrefix(agentapi.AgentInboxPrefix("vm1")))
+
+	if !delivered(t, node, agent, "agentint.vm1.logs") {
+		t.Fatal("expected the agent to publish on its own subjects")
+	}
+	if delivered(t, node, agent, "agentint.vm2.logs") {
+		t.Fatal("expected the agent not to publish on another VM's subjects")
+	}
+	if !received(t, node, agent, "agentint.vm1.deploy") {
+		t.Fatal("expected the agent to subscribe to its own subjects")
+	}
+	if received(t, node, agent, "agentint.vm2.deploy") {
+		t.Fatal("expected the agent not to subscribe to another VM's subjects")
+	}
+	if received(t, node, agent, "_INBOX.node") {
+		t.Fatal("expected the agent not to subscribe to the inboxes of other connections")
+	}
+
+	// the agent requests of the node, and replies to the node's requests, including with streams
+	sub, _ := node.Subscribe("agentint.vm1.handshake", func(m *nats.Msg) { _ = m.Respond([]byte("ok")) })
+	defer func() { _ = sub.Unsubscribe() }()
+	_ = node.Flush()
+	_, err := agent.Request("agentint.vm1.handshake", nil, time.Second)
+	if err != nil {
+		t.Fatalf("expected the agent's request to be answered, got %s", err)
+	}
+
+	sub, _ = agent.Subscribe("agentint.vm1.trigger", func(m *nats.Msg) {
+		_ = agent.Publish(m.Reply, []byte("chunk"))
+		_ = m.Respond([]byte("end"))
+	})
+	defer func() { _ = sub.Unsubscribe() }()
+	_ = agent.Flush()
+
+	inbox, _ := node.SubscribeSync(node.NewRespInbox())
+	_ = node.PublishRequest("agentint.vm1.trigger", inbox.Subject, nil)
+	for _, expected := range []string{"chunk", "end"} {
+		msg, err := inbox.NextMsg(time.Second)
+		if err != nil || string(msg.Data) != expected {
+			t.Fatalf("expected the agent to stream its reply to the node, got %v %v", msg, err)
+		}
+	}
+}
+
+func TestAgentCredentialsPermitObjectStores(t *testing.T) {
+	auth, _ := NewAuthenticator()
+	svr := startTestServer(t, auth)
+	node := connect(t, svr, nats.Token(auth.NodeToken()))
+
+	js, _ := node.JetStream()
+	cache, err := js.CreateObjectStore(&nats.ObjectStoreConfig{Bucket: agentapi.WorkloadCacheBucket})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = js.CreateObjectStore(&nats.ObjectStoreConfig{Bucket: agentapi.WorkloadLogsBucket})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	artifact := bytes.Repeat([]byte("a"), 512*1024)
+	_, err = cache.PutBytes("vm1", artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creds, _ := auth.CreateCredentials("vm1")
+	agent := connect(t, svr, nats.Token(creds.AgentToken), nats.CustomInboxPrefix(agentapi.AgentInboxPrefix("vm1")))
+	ajs, _ := agent.JetStream(nats.MaxWait(time.Second))
+
+	bucket, err := ajs.ObjectStore(agentapi.WorkloadCacheBucket)
+	if err != nil {
+		t.Fatalf("expected the agent to open the artifact cache, got %s", err)
+	}
+	fetched, err := bucket.GetBytes("vm1")
+	if err != nil || !bytes.Equal(fetched, artifact) {
+		t.Fatalf("expected the agent to fetch its artifact, got %s", err)
+	}
+	_, err = bucket.PutBytes("vm2", []byte("poisoned"))
+	if err == nil {
+		t.Fatal("expected the agent not to write to the artifact cache")
+	}
+
+	logs, err := ajs.ObjectStore(agentapi.WorkloadLogsBucket)
+	if err != nil {
+		t.Fatalf("expected the agent to open the workload logs bucket, got %s", err)
+	}
+	_, err = logs.PutBytes("ns/vm1", []byte("captured"))
+	if err != nil {
+		t.Fatalf("expected the agent to capture its workload's logs, got %s", err)
+	}
+	_, err = logs.GetBytes("ns/vm1")
+	if err == nil {
+		t.Fatal("expected the agent not to read captured logs")
+	}
+
+	err = ajs.DeleteObjectStore(agentapi.WorkloadCacheBucket)
+	if err == nil {
+		t.Fatal("expected the agent not to delete the artifact cache")
+	}
+}
+
+func TestWorkloadCredentialsOnlyPublishMetrics(t *testing.T) {
+	auth, _ := NewAuthenticator()
+	svr := startTestServer(t, auth)
+	node := connect(t, svr, nats.Token(auth.NodeToken()))
+
+	creds, _ := auth.CreateCredentials("vm1")
+	workload := connect(t, svr, nats.Token(creds.WorkloadToken))
+
+	if !delivered(t, node, workload, agentapi.MetricsSubject("vm1")) {
+		t.Fatal("expected the workload to publish its metric samples")
+	}
+	if delivered(t, node, workload, "agentint.vm1.logs") {
+		t.Fatal("expected the workload not to publish on its agent's other subjects")
+	}
+	if received(t, node, workload, "agentint.vm1.trigger") {
+		t.Fatal("expected the workload not to subscribe to anything")
+	}
+}
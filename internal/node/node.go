@@ -5,10 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/url"
 	"os"
 	"os/signal"
-	"path"
 	"path/filepath"
 	"runtime"
 	"strconv"
@@ -24,29 +24,34 @@ import (
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nkeys"
 	controlapi "github.com/synadia-io/nex/control-api"
+	hs "github.com/synadia-io/nex/host-services"
 	"github.com/synadia-io/nex/internal/models"
+	"github.com/synadia-io/nex/internal/node/natsint"
 	"github.com/synadia-io/nex/internal/node/observability"
 )
 
 const (
-	systemNamespace              = "system"
-	defaultInternalNatsStoreDir  = "pnats"
-	heartbeatInterval            = 30 * time.Second
-	publicNATSServerStartTimeout = 50 * time.Millisecond
-	runloopSleepInterval         = 100 * time.Millisecond
-	runloopTickInterval          = 2500 * time.Millisecond
+	systemNamespace            = "system"
+	heartbeatInterval          = 30 * time.Second
+	hostServicesHealthInterval = 5 * time.Second
+	natsServerReadyTimeout     = 5 * time.Second
+	runloopSleepInterval       = 100 * time.Millisecond
+	runloopTickInterval        = 2500 * time.Millisecond
 )
 
 // Nex node process
 type Node struct {
 	api     *ApiListener
+	events  *eventHistory
+	gateway *httpGateway
 	manager *WorkloadManager
 
-	cancelF  context.CancelFunc
-	closing  uint32
-	lameduck uint32
-	ctx      context.Context
-	sigs     chan os.Signal
+	cancelF   context.CancelFunc
+	closing   uint32
+	lameduck  uint32
+	unhealthy uint32
+	ctx       context.Context
+	sigs      chan os.Signal
 
 	log *slog.Logger
 
@@ -63,10 +68,13 @@ type Node struct {
 	natspub *server.Server
 	nc      *nats.Conn
 
-	natsint        *server.Server
-	ncint          *nats.Conn
-	ncHostServices *nats.Conn
+	natsint          *server.Server
+	natsintAuth      *natsint.Authenticator
+	ncint            *nats.Conn
+	ncHostServices   hs.ConnectionSource
+	hostServicesPool *hs.ConnectionPool
 
+	report    *capabilityReport
 	startedAt time.Time
 	telemetry *observability.Telemetry
 }
@@ -118,6 +126,13 @@ func (n *Node) Start() {
 	n.log.Debug("Starting node", slog.String("public_key", n.publicKey))
 
 	err := n.init()
+	if n.config.SafeMode {
+		n.report = n.selfTest(err)
+		n.shutdown()
+		n.cancelF()
+		return
+	}
+
 	if err != nil {
 		n.shutdown()
 		n.cancelF()
@@ -127,6 +142,10 @@ func (n *Node) Start() {
 	n.startedAt = time.Now()
 	_ = n.publishNodeStarted()
 
+	if n.config.StoreProbeIntervalMillisecond > 0 {
+		go n.probeObjectStore()
+	}
+
 	timer := time.NewTicker(runloopTickInterval)
 	defer timer.Stop()
 
@@ -161,12 +180,14 @@ func (n *Node) Stop() {
 func (n *Node) EnterLameDuck() error {
 	if atomic.AddUint32(&n.lameduck, 1) == 1 {
 		n.config.Tags[controlapi.TagLameDuck] = "true"
+		nonEssential := n.manager.beginLameDuckDrain()
 		err := n.manager.procMan.EnterLameDuck()
 		if err != nil {
 			return err
 		}
 
 		_ = n.publishNodeLameDuckEntered()
+		go n.manager.drainLameDuck(nonEssential)
 	}
 
 	return nil
@@ -176,6 +197,12 @@ func (n *Node) IsLameDuck() bool {
 	return n.lameduck > 0
 }
 
+// Returns the number of workloads still running since the node entered lame duck mode and whether
+// all have stopped
+func (n *Node) LameDuckStatus() (int, bool) {
+	return n.manager.LameDuckStatus()
+}
+
 func (n *Node) createPid() error {
 	n.pidFilepath = filepath.Join(os.TempDir(), "nex.pid")
 
@@ -252,7 +279,9 @@ func (n *Node) init() error {
 		}
 
 		// setup NATS connection
-		n.nc, _err = models.GenerateConnectionFromOpts(n.opts, n.log)
+		opts := *n.opts
+		opts.ConnectionName = n.config.ResolveConnectionName(n.opts.ConnectionName, "node", n.publicKey)
+		n.nc, _err = models.GenerateConnectionFromOpts(&opts, n.log)
 		if _err != nil {
 			n.log.Error("Failed to connect to NATS server", slog.Any("err", _err))
 			err = errors.Join(err, fmt.Errorf("failed to connect to NATS server: %s", _err))
@@ -265,7 +294,7 @@ func (n *Node) init() error {
 			n.log.Error("Failed to start host services connection", slog.Any("error", _err))
 			err = errors.Join(err, fmt.Errorf("failed to start host services NATS connection: %s", _err))
 		} else {
-			n.log.Info("Established host services NATS connection", slog.String("server", n.ncHostServices.Servers()[0]))
+			n.log.Info("Established host services NATS connection", slog.String("server", n.ncHostServices.Conn().Servers()[0]))
 		}
 
 		// start internal NATS server
@@ -277,10 +306,12 @@ func (n *Node) init() error {
 			n.log.Info("Internal NATS server started", slog.String("client_url", n.natsint.ClientURL()))
 		}
 
+		n.events = newEventHistory(n.config.EventHistorySize)
+
 		n.manager, _err = NewWorkloadManager(n.ctx, n.cancelF,
 			n.keypair, n.publicKey,
-			n.nc, n.ncint, n.ncHostServices,
-			n.config, n.log, n.telemetry)
+			n.nc, n.ncint, n.ncHostServices, n.natsintAuth,
+			n.config, n.log, n.telemetry, n.events)
 		if _err != nil {
 			n.log.Error("Failed to initialize machine manager", slog.Any("err", _err))
 			err = errors.Join(err, _err)
@@ -288,7 +319,10 @@ func (n *Node) init() error {
 
 		if err == nil {
 			go n.manager.Start()
+		}
 
+		// nodes in safe mode never accept deploys
+		if err == nil && !n.config.SafeMode {
 			// init API listener
 			n.api = NewApiListener(n.log, n.manager, n)
 			_err = n.api.Start()
@@ -298,16 +332,34 @@ func (n *Node) init() error {
 			}
 		}
 
+		if err == nil && !n.config.SafeMode {
+			_err = n.startHTTPGateway()
+			if _err != nil {
+				n.log.Error("Failed to start HTTP gateway", slog.Any("err", _err))
+				err = errors.Join(err, _err)
+			}
+		}
+
 		n.installSignalHandlers()
 	})
 
 	return err
 }
 
+func (n *Node) startHTTPGateway() error {
+	config, err := n.config.ResolveHTTPGateway()
+	if err != nil || config == nil {
+		return err
+	}
+
+	n.gateway = newHTTPGateway(n.nc, config, n.log)
+	return n.gateway.start()
+}
+
 func (n *Node) startHostServicesConnection(defaultConnection *nats.Conn) error {
 	if n.config.HostServicesConfiguration != nil {
 		natsOpts := []nats.Option{
-			nats.Name("nex-hostservices"),
+			nats.Name(n.config.ResolveConnectionName(n.opts.ConnectionName, "hostservices", n.publicKey)),
 		}
 		if len(n.config.HostServicesConfiguration.NatsUserJwt) > 0 {
 			natsOpts = append(natsOpts,
@@ -320,16 +372,30 @@ func (n *Node) startHostServicesConnection(defaultConnection *nats.Conn) error {
 
 		if len(n.config.HostServicesConfiguration.NatsUrl) == 0 {
 			n.config.HostServicesConfiguration.NatsUrl = defaultConnection.Servers()[0]
-			n.ncHostServices = n.nc
+			n.ncHostServices = hs.SingleConnection(n.nc)
+		} else if n.config.HostServicesConfiguration.ConnectionPoolSize > 1 {
+			pool, err := hs.NewConnectionPool(
+				n.config.HostServicesConfiguration.ConnectionPoolSize,
+				hostServicesHealthInterval,
+				func() (*nats.Conn, error) {
+					return nats.Connect(n.config.HostServicesConfiguration.NatsUrl, natsOpts...)
+				},
+				n.log,
+			)
+			if err != nil {
+				return err
+			}
+			n.hostServicesPool = pool
+			n.ncHostServices = pool
 		} else {
 			nc, err := nats.Connect(n.config.HostServicesConfiguration.NatsUrl, natsOpts...)
 			if err != nil {
 				return err
 			}
-			n.ncHostServices = nc
+			n.ncHostServices = hs.SingleConnection(nc)
 		}
 	} else {
-		n.ncHostServices = n.nc
+		n.ncHostServices = hs.SingleConnection(n.nc)
 	}
 	return nil
 }
@@ -337,18 +403,55 @@ func (n *Node) startHostServicesConnection(defaultConnection *nats.Conn) error {
 func (n *Node) startInternalNATS() error {
 	var err error
 
+	bindHost := n.config.ResolveInternalNodeBindHost()
+	if !n.config.NoSandbox && !isLocalAddress(bindHost) {
+		// the CNI gateway address is only assigned to a host interface once the first VM's network
+		// has been created, so it may not yet be possible to bind to it
+		n.log.Warn("Internal NATS bind host is not assigned to a local interface; listening on all interfaces",
+			slog.String("bind_host", bindHost),
+		)
+		bindHost = "0.0.0.0"
+		if n.config.CNIUsesIPv6() {
+			bindHost = "::"
+		}
+	}
+
+	maxPayload, maxPending := n.config.ResolveInternalNatsLimits()
+
+	storeDir := n.config.ResolveInternalNatsStoreDir()
+	err = checkStoreDir(storeDir, n.config.ResolveInternalNatsStoreMinFreeMib())
+	if err != nil {
+		return fmt.Errorf("invalid internal NATS store dir: %s", err)
+	}
+
+	// connections must present the node's token or the credentials issued for a VM, which confine
+	// its agent and workloads to the VM's own subjects
+	n.natsintAuth, err = natsint.NewAuthenticator()
+	if err != nil {
+		return err
+	}
+
 	n.natsint, err = server.NewServer(&server.Options{
-		Host:      "0.0.0.0",
-		Port:      -1,
-		JetStream: true,
-		NoLog:     true,
-		StoreDir:  path.Join(os.TempDir(), defaultInternalNatsStoreDir),
+		Host:                       bindHost,
+		Port:                       -1,
+		JetStream:                  true,
+		NoLog:                      true,
+		StoreDir:                   storeDir,
+		MaxPayload:                 int32(maxPayload),
+		MaxPending:                 int64(maxPending),
+		CustomClientAuthentication: n.natsintAuth,
 	})
 	if err != nil {
 		return err
 	}
 	n.natsint.Start()
 
+	// the server accepts connections asynchronously once started; wait for it rather than racing it
+	if !n.natsint.ReadyForConnections(natsServerReadyTimeout) {
+		n.natsint.Shutdown()
+		return fmt.Errorf("internal NATS server not ready for connections within %s", natsServerReadyTimeout)
+	}
+
 	clientUrl, err := url.Parse(n.natsint.ClientURL())
 	if err != nil {
 		return fmt.Errorf("failed to parse internal NATS client URL: %s", err)
@@ -360,7 +463,7 @@ func (n *Node) startInternalNATS() error {
 	}
 	n.config.InternalNodePort = &p
 
-	n.ncint, err = nats.Connect("", nats.InProcessServer(n.natsint))
+	n.ncint, err = nats.Connect("", nats.InProcessServer(n.natsint), nats.Token(n.natsintAuth.NodeToken()))
 	if err != nil {
 		n.log.Error("Failed to connect to internal nats", slog.Any("err", err), slog.Any("internal_url", clientUrl), slog.Bool("with_jetstream", n.natsint.JetStreamEnabled()))
 		return fmt.Errorf("failed to connect to internal nats: %s", err)
@@ -378,18 +481,74 @@ func (n *Node) startInternalNATS() error {
 		return fmt.Errorf("failed to establish jetstream connection to internal nats: %s", err)
 	}
 
-	_, err = jsCtx.CreateObjectStore(&nats.ObjectStoreConfig{
+	// artifacts are held in memory unless configured otherwise, limiting the cache to the node's memory
+	storage := nats.MemoryStorage
+	if n.config.InternalNatsFileStorage {
+		storage = nats.FileStorage
+	}
+
+	_, err = ensureObjectStore(jsCtx, &nats.ObjectStoreConfig{
 		Bucket:      WorkloadCacheBucketName,
 		Description: "Object store cache for nex-node workloads",
-		Storage:     nats.MemoryStorage,
+		Storage:     storage,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create internal object store: %s", err)
 	}
 
+	logsMaxBytes, logsTTL := n.config.ResolveLogCaptureRetention()
+	_, err = ensureObjectStore(jsCtx, &nats.ObjectStoreConfig{
+		Bucket:      WorkloadLogsBucketName,
+		Description: "Object store for captured nex-node workload logs",
+		Storage:     storage,
+		MaxBytes:    logsMaxBytes,
+		TTL:         logsTTL,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create internal workload logs bucket: %s", err)
+	}
+
+	// shared artifact buckets hold curated artifacts retained beyond any one deployment, so unlike
+	// the cache they are always kept in the store dir, surviving restarts and sparing the node's memory
+	for _, bucket := range n.config.ArtifactBuckets {
+		_, err = ensureObjectStore(jsCtx, &nats.ObjectStoreConfig{
+			Bucket:      bucket,
+			Description: "Object store for shared nex-node workload artifacts",
+			Storage:     nats.FileStorage,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create internal artifact bucket %s: %s", bucket, err)
+		}
+	}
+
 	return nil
 }
 
+// Returns true if the given address is unspecified or assigned to one of this host's interfaces
+func isLocalAddress(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	if ip.IsUnspecified() || ip.IsLoopback() {
+		return true
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (n *Node) startPublicNATS() error {
 	if n.config.PublicNATSServer == nil {
 		// no-op
@@ -405,9 +564,9 @@ func (n *Node) startPublicNATS() error {
 	n.log.Debug("Starting public NATS server")
 	n.natspub.Start()
 
-	ports := n.natspub.PortsInfo(publicNATSServerStartTimeout)
-	if ports == nil {
-		return fmt.Errorf("failed to start public NATS server")
+	if !n.natspub.ReadyForConnections(natsServerReadyTimeout) {
+		n.natspub.Shutdown()
+		return fmt.Errorf("public NATS server not ready for connections within %s", natsServerReadyTimeout)
 	}
 
 	return nil
@@ -428,6 +587,9 @@ func (n *Node) loadNodeConfig() error {
 		n.config.OtelMetricsPort = n.nodeOpts.OtelMetricsPort
 		n.config.OtelTraces = n.nodeOpts.OtelTraces
 		n.config.OtelTracesExporter = n.nodeOpts.OtelTracesExporter
+
+		n.applySafeMode()
+		n.applyNoSandboxFallback()
 	}
 
 	return nil
@@ -448,7 +610,7 @@ func (n *Node) publishNodeLameDuckEntered() error {
 	_ = cloudevent.SetData(nodeLameDuck)
 
 	n.log.Info("Publishing node lame duck entered event")
-	return PublishCloudEvent(n.nc, "system", cloudevent, n.log)
+	return n.publishCloudEvent(systemNamespace, cloudevent)
 }
 
 func (n *Node) publishHeartbeat() error {
@@ -461,11 +623,11 @@ func (n *Node) publishHeartbeat() error {
 	now := time.Now().UTC()
 
 	evt := controlapi.HeartbeatEvent{
-		NodeId:          n.publicKey,
-		Version:         Version(),
-		Uptime:          myUptime(now.Sub(n.startedAt)),
-		RunningMachines: len(machines),
-		Tags:            n.config.Tags,
+		NodeId:            n.publicKey,
+		Version:           Version(),
+		Uptime:            myUptime(now.Sub(n.startedAt)),
+		RunningMachines:   len(machines),
+		NodeAdvertisement: n.advertisement(),
 	}
 
 	cloudevent := cloudevents.NewEvent()
@@ -476,13 +638,14 @@ func (n *Node) publishHeartbeat() error {
 	cloudevent.SetDataContentType(cloudevents.ApplicationJSON)
 	_ = cloudevent.SetData(evt)
 
-	return PublishCloudEvent(n.nc, systemNamespace, cloudevent, n.log)
+	return n.publishCloudEvent(systemNamespace, cloudevent)
 }
 
 func (n *Node) publishNodeStarted() error {
 	nodeStart := controlapi.NodeStartedEvent{
-		Version: VERSION,
-		Id:      n.publicKey,
+		Version:           VERSION,
+		Id:                n.publicKey,
+		NodeAdvertisement: n.advertisement(),
 	}
 
 	cloudevent := cloudevents.NewEvent()
@@ -494,7 +657,7 @@ func (n *Node) publishNodeStarted() error {
 	_ = cloudevent.SetData(nodeStart)
 
 	n.log.Info("Publishing node started event")
-	return PublishCloudEvent(n.nc, "system", cloudevent, n.log)
+	return n.publishCloudEvent(systemNamespace, cloudevent)
 }
 
 func (n *Node) publishNodeStopped() error {
@@ -512,7 +675,7 @@ func (n *Node) publishNodeStopped() error {
 	_ = cloudevent.SetData(evt)
 
 	n.log.Info("Publishing node stopped event")
-	return PublishCloudEvent(n.nc, "system", cloudevent, n.log)
+	return n.publishCloudEvent(systemNamespace, cloudevent)
 }
 
 func (n *Node) validateConfig() error {
@@ -529,7 +692,12 @@ func (n *Node) validateConfig() error {
 func (n *Node) shutdown() {
 	if atomic.AddUint32(&n.closing, 1) == 1 {
 		n.log.Debug("shutting down")
-		_ = n.api.Drain()
+		if n.gateway != nil {
+			n.gateway.stop()
+		}
+		if n.api != nil {
+			_ = n.api.Drain()
+		}
 		_ = n.manager.Stop()
 
 		if !n.startedAt.IsZero() {
@@ -541,6 +709,10 @@ func (n *Node) shutdown() {
 			time.Sleep(time.Millisecond * 25)
 		}
 
+		if n.hostServicesPool != nil {
+			n.hostServicesPool.Close()
+		}
+
 		_ = n.nc.Drain()
 		for !n.nc.IsClosed() {
 			time.Sleep(time.Millisecond * 25)
@@ -548,7 +720,7 @@ func (n *Node) shutdown() {
 
 		n.natsint.Shutdown()
 		n.natsint.WaitForShutdown()
-		_ = os.Remove(path.Join(os.TempDir(), defaultInternalNatsStoreDir))
+		_ = os.Remove(n.config.ResolveInternalNatsStoreDir())
 
 		if n.natspub != nil {
 			n.natspub.Shutdown()
@@ -1,4 +1,4 @@
-//go:build linux
+//go:build linux || darwin
 
 package nexnode
 
@@ -10,7 +10,7 @@ import (
 
 func (n *Node) installSignalHandlers() {
 	n.log.Debug("installing signal handlers")
-	// both firecracker and the embedded NATS server(s) register signal handlers... wipe those so ours are the ones being used
+	// both firecracker (on linux) and the embedded NATS server(s) register signal handlers... wipe those so ours are the ones being used
 	signal.Reset(syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR1, syscall.SIGUSR2, syscall.SIGHUP)
 	n.sigs = make(chan os.Signal, 1)
 	signal.Notify(n.sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
@@ -0,0 +1,64 @@
+package nexnode
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Number of attempts made to set up each of the node's internal object stores
+const objectStoreSetupAttempts = 5
+
+// Delay before the first retry of an internal object store's setup, doubled after each attempt
+const objectStoreSetupBackoff = 100 * time.Millisecond
+
+// Opens the object store described by the given config, creating it if it does not exist. A store
+// which already exists, e.g. when the node restarts against an existing store directory, or which is
+// created concurrently is opened as is. Setup is retried with backoff while JetStream is unavailable,
+// as it may be momentarily while the internal NATS server recovers existing streams on startup
+func ensureObjectStore(js nats.JetStreamContext, config *nats.ObjectStoreConfig) (nats.ObjectStore, error) {
+	var store nats.ObjectStore
+	var err error
+
+	backoff := objectStoreSetupBackoff
+	for attempt := 1; attempt <= objectStoreSetupAttempts; attempt++ {
+		store, err = openOrCreateObjectStore(js, config)
+		if err == nil || !retryableObjectStoreErr(err) {
+			return store, err
+		}
+
+		if attempt < objectStoreSetupAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	return nil, err
+}
+
+func openOrCreateObjectStore(js nats.JetStreamContext, config *nats.ObjectStoreConfig) (nats.ObjectStore, error) {
+	store, err := js.ObjectStore(config.Bucket)
+	if err == nil {
+		return store, nil
+	}
+
+	if !errors.Is(err, nats.ErrStreamNotFound) && !errors.Is(err, nats.ErrBucketNotFound) {
+		return nil, err
+	}
+
+	store, err = js.CreateObjectStore(config)
+	if errors.Is(err, nats.ErrStreamNameAlreadyInUse) {
+		// lost a race to create the store
+		return js.ObjectStore(config.Bucket)
+	}
+
+	return store, err
+}
+
+// Returns true if the given error may be resolved by retrying object store setup
+func retryableObjectStoreErr(err error) bool {
+	return errors.Is(err, nats.ErrTimeout) ||
+		errors.Is(err, nats.ErrJetStreamNotEnabled) ||
+		errors.Is(err, nats.ErrNoResponders)
+}
@@ -0,0 +1,99 @@
+package nexnode
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+func startObjectStoreTestServer(t *testing.T, storeDir string) (*server.Server, nats.JetStreamContext) {
+	svr, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		NoLog:     true,
+		StoreDir:  storeDir,
+	})
+	if err != nil {
+		t.Fatalf("failed to create nats server: %s", err)
+	}
+	svr.Start()
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatalf("failed to connect to nats server: %s", err)
+	}
+
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("failed to establish jetstream context: %s", err)
+	}
+
+	t.Cleanup(func() {
+		nc.Close()
+		svr.Shutdown()
+		svr.WaitForShutdown()
+	})
+
+	return svr, js
+}
+
+func TestEnsureObjectStoreFreshStoreDir(t *testing.T) {
+	_, js := startObjectStoreTestServer(t, t.TempDir())
+
+	config := &nats.ObjectStoreConfig{
+		Bucket:  WorkloadCacheBucketName,
+		Storage: nats.MemoryStorage,
+	}
+
+	_, err := ensureObjectStore(js, config)
+	if err != nil {
+		t.Fatalf("expected object store to be created, got %s", err)
+	}
+
+	// setting up the store again is a no-op
+	_, err = ensureObjectStore(js, config)
+	if err != nil {
+		t.Fatalf("expected existing object store to be opened, got %s", err)
+	}
+}
+
+func TestEnsureObjectStoreExistingStoreDir(t *testing.T) {
+	storeDir := t.TempDir()
+
+	config := &nats.ObjectStoreConfig{
+		Bucket:  WorkloadCacheBucketName,
+		Storage: nats.FileStorage,
+	}
+
+	svr, js := startObjectStoreTestServer(t, storeDir)
+	store, err := ensureObjectStore(js, config)
+	if err != nil {
+		t.Fatalf("expected object store to be created, got %s", err)
+	}
+
+	_, err = store.PutBytes("workload", []byte("hello"))
+	if err != nil {
+		t.Fatalf("failed to write to object store: %s", err)
+	}
+
+	svr.Shutdown()
+	svr.WaitForShutdown()
+
+	// restart against the same store directory, in which the store already exists
+	_, js = startObjectStoreTestServer(t, storeDir)
+	store, err = ensureObjectStore(js, config)
+	if err != nil {
+		t.Fatalf("expected existing object store to be opened, got %s", err)
+	}
+
+	data, err := store.GetBytes("workload")
+	if err != nil {
+		t.Fatalf("expected object to survive restart, got %s", err)
+	}
+
+	if string(data) != "hello" {
+		t.Fatalf("expected object data %q, got %q", "hello", string(data))
+	}
+}
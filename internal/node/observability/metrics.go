@@ -1,6 +1,7 @@
 package observability
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -84,6 +85,89 @@ func (t *Telemetry) initMetrics() error {
 	if e != nil {
 		err = errors.Join(err, e)
 	}
+	t.FunctionActiveTriggers, e = t.meter.
+		Int64UpDownCounter("nex-function-active-triggers",
+			metric.WithDescription("Number of function triggers currently in flight"),
+		)
+	if e != nil {
+		err = errors.Join(err, e)
+	}
+
+	t.ArtifactCacheHits, e = t.meter.
+		Int64Counter("nex-artifact-cache-hit",
+			metric.WithDescription("Total number of deployments served a cached workload artifact"),
+		)
+	if e != nil {
+		err = errors.Join(err, e)
+	}
+	t.ArtifactCacheMisses, e = t.meter.
+		Int64Counter("nex-artifact-cache-miss",
+			metric.WithDescription("Total number of deployments which fetched the workload artifact"),
+		)
+	if e != nil {
+		err = errors.Join(err, e)
+	}
+
+	t.HandshakeFailureCounter, e = t.meter.
+		Int64Counter("nex-agent-handshake-failures",
+			metric.WithDescription("Total number of agents which failed to complete their handshake in time"),
+		)
+	if e != nil {
+		err = errors.Join(err, e)
+	}
+
+	t.PoolStarvationCounter, e = t.meter.
+		Int64Counter("nex-pool-starvation",
+			metric.WithDescription("Total number of deployments which found no idle agent in the warm pool"),
+		)
+	if e != nil {
+		err = errors.Join(err, e)
+	}
+	t.PoolStarvationWait, e = t.meter.
+		Int64Histogram("nex-pool-starvation-wait-ms",
+			metric.WithDescription("Time deployments which found no idle agent in the warm pool waited for one"),
+			metric.WithUnit("ms"),
+		)
+	if e != nil {
+		err = errors.Join(err, e)
+	}
+
+	t.VmNetworkBytes, e = t.meter.
+		Int64Counter("nex-vm-network-bytes",
+			metric.WithDescription("Total number of bytes received (rx) or transmitted (tx) by a workload's VM"),
+		)
+	if e != nil {
+		err = errors.Join(err, e)
+	}
+	t.VmNetworkPackets, e = t.meter.
+		Int64Counter("nex-vm-network-packets",
+			metric.WithDescription("Total number of packets received (rx) or transmitted (tx) by a workload's VM"),
+		)
+	if e != nil {
+		err = errors.Join(err, e)
+	}
+	t.VmNetworkErrors, e = t.meter.
+		Int64Counter("nex-vm-network-errors",
+			metric.WithDescription("Total number of network errors receiving (rx) or transmitting (tx) by a workload's VM"),
+		)
+	if e != nil {
+		err = errors.Join(err, e)
+	}
+
+	return err
+}
+
+// Reports the number of idle agents in the warm pool, as returned by the given function, each time
+// metrics are collected
+func (t *Telemetry) ObserveWarmPool(warm func() int) error {
+	_, err := t.meter.
+		Int64ObservableGauge("nex-warm-pool-count",
+			metric.WithDescription("Number of idle agents in the warm pool"),
+			metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+				o.Observe(int64(warm()))
+				return nil
+			}),
+		)
 
 	return err
 }
@@ -115,11 +199,20 @@ func (t *Telemetry) initMeterProvider() error {
 			return err
 		}
 
+		snapshotReader, err := t.newSnapshotReader()
+		if err != nil {
+			t.log.Warn("failed to create OTel metrics snapshot reader", slog.Any("err", err))
+			return err
+		}
+
 		t.meterProvider = metricsdk.NewMeterProvider(
 			metricsdk.WithResource(resource),
 			metricsdk.WithReader(
 				metricReader,
 			),
+			metricsdk.WithReader(
+				snapshotReader,
+			),
 		)
 	}
 
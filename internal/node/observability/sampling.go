@@ -0,0 +1,39 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+)
+
+type traceSamplingRateKey struct{}
+
+// Returns a context in which root spans are sampled at the given rate (0.0-1.0) rather than
+// at the node's sampling rate
+func WithTraceSamplingRate(ctx context.Context, rate float64) context.Context {
+	return context.WithValue(ctx, traceSamplingRateKey{}, rate)
+}
+
+// Samples root spans at the rate carried by the context in which they are started, if any,
+// falling back to the node's sampling rate
+type rateSampler struct {
+	rate float64
+}
+
+func newTraceSampler(rate float64) tracesdk.Sampler {
+	return tracesdk.ParentBased(rateSampler{rate: rate})
+}
+
+func (s rateSampler) ShouldSample(p tracesdk.SamplingParameters) tracesdk.SamplingResult {
+	rate := s.rate
+	if r, ok := p.ParentContext.Value(traceSamplingRateKey{}).(float64); ok {
+		rate = r
+	}
+
+	return tracesdk.TraceIDRatioBased(rate).ShouldSample(p)
+}
+
+func (s rateSampler) Description() string {
+	return fmt.Sprintf("NexRateSampler{%g}", s.rate)
+}
@@ -0,0 +1,51 @@
+package observability
+
+import (
+	"context"
+	"testing"
+
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func sampledSpans(ctx context.Context, rate float64, n int) int {
+	tp := tracesdk.NewTracerProvider(tracesdk.WithSampler(newTraceSampler(rate)))
+	tracer := tp.Tracer("test")
+
+	sampled := 0
+	for i := 0; i < n; i++ {
+		_, span := tracer.Start(ctx, "trigger")
+		if span.SpanContext().IsSampled() {
+			sampled++
+		}
+		span.End()
+	}
+
+	return sampled
+}
+
+func TestTraceSamplerNodeRate(t *testing.T) {
+	if sampled := sampledSpans(context.Background(), 1, 100); sampled != 100 {
+		t.Fatalf("expected every span to be sampled at rate 1.0; got %d", sampled)
+	}
+
+	if sampled := sampledSpans(context.Background(), 0, 100); sampled != 0 {
+		t.Fatalf("expected no span to be sampled at rate 0.0; got %d", sampled)
+	}
+}
+
+func TestTraceSamplerWorkloadRate(t *testing.T) {
+	ctx := WithTraceSamplingRate(context.Background(), 0)
+	if sampled := sampledSpans(ctx, 1, 100); sampled != 0 {
+		t.Fatalf("expected workload rate 0.0 to override node rate; got %d sampled", sampled)
+	}
+
+	ctx = WithTraceSamplingRate(context.Background(), 1)
+	if sampled := sampledSpans(ctx, 0, 100); sampled != 100 {
+		t.Fatalf("expected workload rate 1.0 to override node rate; got %d sampled", sampled)
+	}
+
+	ctx = WithTraceSamplingRate(context.Background(), 0.1)
+	if sampled := sampledSpans(ctx, 1, 1000); sampled == 0 || sampled > 200 {
+		t.Fatalf("expected roughly 10%% of spans to be sampled; got %d of 1000", sampled)
+	}
+}
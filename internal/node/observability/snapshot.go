@@ -0,0 +1,82 @@
+package observability
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
+	metricsdk "go.opentelemetry.io/otel/sdk/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Content type of the exposition returned by MetricsSnapshot
+const MetricsSnapshotContentType = `application/openmetrics-text; version=1.0.0; charset=utf-8`
+
+var ErrMetricsDisabled = errors.New("metrics are not enabled on this node")
+
+// Creates a reader backed by a dedicated registry from which point-in-time snapshots
+// of the node's instruments can be gathered, independent of the configured exporter.
+// Node identity resource attributes are added as labels on every series
+func (t *Telemetry) newSnapshotReader() (metricsdk.Reader, error) {
+	t.snapshotRegistry = prometheus.NewRegistry()
+
+	return otelprom.New(
+		otelprom.WithRegisterer(t.snapshotRegistry),
+		otelprom.WithResourceAsConstantLabels(
+			attribute.NewAllowKeysFilter("node_pub_key", semconv.ServiceNameKey),
+		),
+	)
+}
+
+// Gathers the current value of all node instruments and encodes them using the
+// OpenMetrics text exposition format. Metric families are omitted once the encoded
+// snapshot would exceed maxBytes, in which case truncated is true
+func (t *Telemetry) MetricsSnapshot(maxBytes int) (snapshot []byte, truncated bool, err error) {
+	if t.snapshotRegistry == nil {
+		return nil, false, ErrMetricsDisabled
+	}
+
+	families, err := t.snapshotRegistry.Gather()
+	if err != nil {
+		return nil, false, err
+	}
+
+	format := expfmt.NewFormat(expfmt.TypeOpenMetrics)
+	eof := encodedTrailer(format)
+
+	var out bytes.Buffer
+	var family bytes.Buffer
+	for _, mf := range families {
+		family.Reset()
+
+		err = expfmt.NewEncoder(&family, format).Encode(mf)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if maxBytes > 0 && out.Len()+family.Len()+len(eof) > maxBytes {
+			truncated = true
+			continue
+		}
+
+		out.Write(family.Bytes())
+	}
+
+	out.Write(eof)
+	return out.Bytes(), truncated, nil
+}
+
+// Returns the bytes written when closing an encoder of the given format (the
+// OpenMetrics "# EOF" marker)
+func encodedTrailer(format expfmt.Format) []byte {
+	var buf bytes.Buffer
+	if closer, ok := expfmt.NewEncoder(&buf, format).(io.Closer); ok {
+		_ = closer.Close()
+	}
+	return buf.Bytes()
+}
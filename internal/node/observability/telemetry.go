@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/synadia-io/nex/internal/models"
 
 	"go.opentelemetry.io/otel/metric"
@@ -16,6 +17,9 @@ import (
 
 const defaultServiceName = "nex-node"
 
+// Service name to which spans recorded by agents and exported by the node are attributed
+const agentServiceName = "nex-agent"
+
 type Telemetry struct {
 	ctx     context.Context
 	log     *slog.Logger
@@ -28,11 +32,15 @@ type Telemetry struct {
 	metricsPort     int
 	meter           metric.Meter
 	meterProvider   metric.MeterProvider
-	traceProvider   trace.TracerProvider
+	// Registry backing on-demand metrics snapshots; nil when metrics are disabled
+	snapshotRegistry *prometheus.Registry
+	traceProvider    trace.TracerProvider
 
-	tracesEnabled  bool
-	tracesExporter string
-	traceExporter  tracesdk.SpanExporter
+	tracesEnabled     bool
+	tracesExporter    string
+	traceExporter     tracesdk.SpanExporter
+	traceSamplingRate float64
+	spanProcessor     tracesdk.SpanProcessor
 
 	serviceName string
 	nodePubKey  string
@@ -44,28 +52,46 @@ type Telemetry struct {
 	VmCounter       metric.Int64UpDownCounter
 	WorkloadCounter metric.Int64UpDownCounter
 
+	HandshakeFailureCounter metric.Int64Counter
+
+	PoolStarvationCounter metric.Int64Counter
+	PoolStarvationWait    metric.Int64Histogram
+
 	FunctionTriggers       metric.Int64Counter
 	FunctionFailedTriggers metric.Int64Counter
 	FunctionRunTimeNano    metric.Int64Counter
+	FunctionActiveTriggers metric.Int64UpDownCounter
+
+	ArtifactCacheHits   metric.Int64Counter
+	ArtifactCacheMisses metric.Int64Counter
+
+	VmNetworkBytes   metric.Int64Counter
+	VmNetworkPackets metric.Int64Counter
+	VmNetworkErrors  metric.Int64Counter
+
+	// Instruments lazily created on behalf of workload-defined metrics
+	workloadMetrics *workloadMetrics
 
 	Tracer trace.Tracer
 }
 
 func NewTelemetry(ctx context.Context, log *slog.Logger, config *models.NodeConfiguration, nodePubKey string) (*Telemetry, error) {
 	t := &Telemetry{
-		ctx:             ctx,
-		log:             log,
-		meter:           nil,
-		otelExporterUrl: config.OtlpExporterUrl,
-		metricsEnabled:  config.OtelMetrics,
-		metricsExporter: config.OtelMetricsExporter,
-		metricsPort:     config.OtelMetricsPort,
-		tracesEnabled:   config.OtelTraces,
-		tracesExporter:  config.OtelTracesExporter,
-		serviceName:     defaultServiceName,
-		nodePubKey:      nodePubKey,
-		meterProvider:   noop.NewMeterProvider(),
-		traceProvider:   tnoop.NewTracerProvider(),
+		ctx:               ctx,
+		log:               log,
+		meter:             nil,
+		otelExporterUrl:   config.OtlpExporterUrl,
+		metricsEnabled:    config.OtelMetrics,
+		metricsExporter:   config.OtelMetricsExporter,
+		metricsPort:       config.OtelMetricsPort,
+		tracesEnabled:     config.OtelTraces,
+		tracesExporter:    config.OtelTracesExporter,
+		traceSamplingRate: config.ResolveTraceSamplingRate(),
+		serviceName:       defaultServiceName,
+		nodePubKey:        nodePubKey,
+		meterProvider:     noop.NewMeterProvider(),
+		traceProvider:     tnoop.NewTracerProvider(),
+		workloadMetrics:   newWorkloadMetrics(),
 	}
 
 	if buildData, ok := t.ctx.Value("build_data").(map[string]string); ok {
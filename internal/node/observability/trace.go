@@ -59,11 +59,11 @@ func (t *Telemetry) initTrace() error {
 		}
 	}
 
-	batchSpanProcessor := tracesdk.NewBatchSpanProcessor(t.traceExporter)
+	t.spanProcessor = tracesdk.NewBatchSpanProcessor(t.traceExporter)
 	tracerProvider := tracesdk.NewTracerProvider(
-		tracesdk.WithSampler(tracesdk.AlwaysSample()),
+		tracesdk.WithSampler(newTraceSampler(t.traceSamplingRate)),
 		tracesdk.WithResource(res),
-		tracesdk.WithSpanProcessor(batchSpanProcessor),
+		tracesdk.WithSpanProcessor(t.spanProcessor),
 	)
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
 		propagation.TraceContext{},
@@ -79,6 +79,28 @@ func (t *Telemetry) initTrace() error {
 	return nil
 }
 
+// Exports the given spans, recorded by an agent and forwarded to the node, through the node's
+// trace exporter. Spans are dropped when traces are disabled
+func (t *Telemetry) ExportAgentSpans(spans ...tracesdk.ReadOnlySpan) {
+	if !t.tracesEnabled {
+		return
+	}
+
+	for _, span := range spans {
+		t.spanProcessor.OnEnd(span)
+	}
+}
+
+// Returns the resource to which spans recorded by an agent are attributed
+func (t *Telemetry) AgentResource(attrs ...attribute.KeyValue) *resource.Resource {
+	attrs = append(attrs,
+		semconv.ServiceName(agentServiceName),
+		attribute.String("node_pub_key", t.nodePubKey),
+	)
+
+	return resource.NewSchemaless(attrs...)
+}
+
 func (t *Telemetry) newResource(ctx context.Context) (*resource.Resource, error) {
 	return resource.New(ctx,
 		resource.WithAttributes(
@@ -0,0 +1,145 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+const workloadMetricPrefix = "nex-workload-"
+
+// workloadMetrics holds the instruments created on behalf of workload-defined metrics, keyed
+// by metric name. A given metric name is bound to the kind with which it was first recorded
+type workloadMetrics struct {
+	mutex *sync.Mutex
+
+	kinds      map[string]string
+	counters   map[string]metric.Float64Counter
+	gauges     map[string]metric.Float64UpDownCounter
+	histograms map[string]metric.Float64Histogram
+
+	// last observed gauge values per workload id, used to translate absolute gauge samples
+	// into deltas for the underlying up/down counter and to retract them once the workload stops
+	gaugeValues map[string]map[gaugeKey]*gaugeValue
+}
+
+type gaugeKey struct {
+	name  string
+	attrs attribute.Distinct
+}
+
+type gaugeValue struct {
+	set   attribute.Set
+	value float64
+}
+
+func newWorkloadMetrics() *workloadMetrics {
+	return &workloadMetrics{
+		mutex:       &sync.Mutex{},
+		kinds:       make(map[string]string),
+		counters:    make(map[string]metric.Float64Counter),
+		gauges:      make(map[string]metric.Float64UpDownCounter),
+		histograms:  make(map[string]metric.Float64Histogram),
+		gaugeValues: make(map[string]map[gaugeKey]*gaugeValue),
+	}
+}
+
+// Records a workload-defined metric sample of the given kind ("counter", "gauge" or "histogram")
+// on behalf of the given workload, re-exporting it alongside the node's own metrics
+func (t *Telemetry) RecordWorkloadMetric(ctx context.Context, workloadID, name, kind string, value float64, attrs ...attribute.KeyValue) error {
+	sample := &agentapi.MetricSample{Name: name, Kind: kind, Value: value}
+	err := sample.Validate()
+	if err != nil {
+		return err
+	}
+
+	wm := t.workloadMetrics
+	wm.mutex.Lock()
+	defer wm.mutex.Unlock()
+
+	if existing, ok := wm.kinds[name]; ok && existing != kind {
+		return fmt.Errorf("workload metric %s already registered as %s", name, existing)
+	}
+
+	set := attribute.NewSet(attrs...)
+	instrumentName := workloadMetricPrefix + name
+
+	switch kind {
+	case agentapi.MetricKindCounter:
+		counter, ok := wm.counters[name]
+		if !ok {
+			counter, err = t.meter.Float64Counter(instrumentName,
+				metric.WithDescription(fmt.Sprintf("Workload-defined counter %s", name)),
+			)
+			if err != nil {
+				return err
+			}
+			wm.counters[name] = counter
+		}
+		counter.Add(ctx, value, metric.WithAttributeSet(set))
+	case agentapi.MetricKindGauge:
+		gauge, ok := wm.gauges[name]
+		if !ok {
+			gauge, err = t.meter.Float64UpDownCounter(instrumentName,
+				metric.WithDescription(fmt.Sprintf("Workload-defined gauge %s", name)),
+			)
+			if err != nil {
+				return err
+			}
+			wm.gauges[name] = gauge
+		}
+
+		values, ok := wm.gaugeValues[workloadID]
+		if !ok {
+			values = make(map[gaugeKey]*gaugeValue)
+			wm.gaugeValues[workloadID] = values
+		}
+
+		key := gaugeKey{name: name, attrs: set.Equivalent()}
+		last, ok := values[key]
+		if !ok {
+			last = &gaugeValue{set: set}
+			values[key] = last
+		}
+
+		delta := value - last.value
+		last.value = value
+		gauge.Add(ctx, delta, metric.WithAttributeSet(set))
+	case agentapi.MetricKindHistogram:
+		histogram, ok := wm.histograms[name]
+		if !ok {
+			histogram, err = t.meter.Float64Histogram(instrumentName,
+				metric.WithDescription(fmt.Sprintf("Workload-defined histogram %s", name)),
+			)
+			if err != nil {
+				return err
+			}
+			wm.histograms[name] = histogram
+		}
+		histogram.Record(ctx, value, metric.WithAttributeSet(set))
+	}
+
+	wm.kinds[name] = kind
+	return nil
+}
+
+// Retracts the gauge values last recorded by the given workload and discards its bookkeeping;
+// called once the workload stops so that neither its gauges nor their state outlive it
+func (t *Telemetry) ForgetWorkloadMetrics(ctx context.Context, workloadID string) {
+	wm := t.workloadMetrics
+	wm.mutex.Lock()
+	defer wm.mutex.Unlock()
+
+	for key, last := range wm.gaugeValues[workloadID] {
+		if last.value != 0 {
+			wm.gauges[key.name].Add(ctx, -last.value, metric.WithAttributeSet(last.set))
+		}
+	}
+
+	delete(wm.gaugeValues, workloadID)
+}
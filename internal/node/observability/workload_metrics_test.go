@@ -0,0 +1,151 @@
+package observability
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	metricsdk "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func newWorkloadMetricsTelemetry() (*Telemetry, *metricsdk.ManualReader) {
+	reader := metricsdk.NewManualReader()
+	provider := metricsdk.NewMeterProvider(metricsdk.WithReader(reader))
+
+	return &Telemetry{
+		meter:           provider.Meter("test"),
+		meterProvider:   provider,
+		workloadMetrics: newWorkloadMetrics(),
+	}, reader
+}
+
+// Returns the sum data points collected for the workload metric with the given name
+func collectSum(t *testing.T, reader *metricsdk.ManualReader, name string) []metricdata.DataPoint[float64] {
+	var rm metricdata.ResourceMetrics
+	err := reader.Collect(context.Background(), &rm)
+	if err != nil {
+		t.Fatalf("failed to collect metrics: %s", err)
+	}
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != workloadMetricPrefix+name {
+				continue
+			}
+			sum, ok := m.Data.(metricdata.Sum[float64])
+			if !ok {
+				t.Fatalf("expected %s to be a sum; got %T", m.Name, m.Data)
+			}
+			return sum.DataPoints
+		}
+	}
+
+	t.Fatalf("workload metric %s was not collected", name)
+	return nil
+}
+
+func TestRecordWorkloadMetricCounter(t *testing.T) {
+	tel, reader := newWorkloadMetricsTelemetry()
+	ctx := context.Background()
+
+	for _, v := range []float64{1, 2, 3.5} {
+		err := tel.RecordWorkloadMetric(ctx, "vm1", "requests", "counter", v, attribute.String("workload_id", "vm1"))
+		if err != nil {
+			t.Fatalf("expected counter sample to be recorded; got %s", err)
+		}
+	}
+
+	points := collectSum(t, reader, "requests")
+	if len(points) != 1 || points[0].Value != 6.5 {
+		t.Fatalf("expected a single counter point with value 6.5; got %v", points)
+	}
+}
+
+func TestRecordWorkloadMetricValidation(t *testing.T) {
+	tel, _ := newWorkloadMetricsTelemetry()
+	ctx := context.Background()
+
+	cases := map[string]struct {
+		name  string
+		kind  string
+		value float64
+	}{
+		"empty name":       {"", "gauge", 1},
+		"invalid name":     {"9lives", "gauge", 1},
+		"unsupported kind": {"requests", "summary", 1},
+		"negative counter": {"requests", "counter", -1},
+		"nan value":        {"latency", "histogram", math.NaN()},
+		"infinite value":   {"depth", "gauge", math.Inf(1)},
+	}
+
+	for label, c := range cases {
+		err := tel.RecordWorkloadMetric(ctx, "vm1", c.name, c.kind, c.value)
+		if err == nil {
+			t.Fatalf("%s: expected sample to be rejected", label)
+		}
+	}
+
+	err := tel.RecordWorkloadMetric(ctx, "vm1", "requests", "counter", 1)
+	if err != nil {
+		t.Fatalf("expected counter sample to be recorded; got %s", err)
+	}
+
+	err = tel.RecordWorkloadMetric(ctx, "vm1", "requests", "gauge", 1)
+	if err == nil {
+		t.Fatal("expected sample to be rejected once its name is bound to another kind")
+	}
+}
+
+func TestRecordWorkloadMetricGauge(t *testing.T) {
+	tel, reader := newWorkloadMetricsTelemetry()
+	ctx := context.Background()
+
+	vm1 := attribute.String("workload_id", "vm1")
+	vm2 := attribute.String("workload_id", "vm2")
+
+	_ = tel.RecordWorkloadMetric(ctx, "vm1", "queue_depth", "gauge", 10, vm1)
+	_ = tel.RecordWorkloadMetric(ctx, "vm1", "queue_depth", "gauge", 4, vm1)
+	_ = tel.RecordWorkloadMetric(ctx, "vm2", "queue_depth", "gauge", 7, vm2)
+
+	values := make(map[string]float64)
+	for _, p := range collectSum(t, reader, "queue_depth") {
+		id, _ := p.Attributes.Value("workload_id")
+		values[id.AsString()] = p.Value
+	}
+
+	if values["vm1"] != 4 || values["vm2"] != 7 {
+		t.Fatalf("expected gauges to report their last recorded values; got %v", values)
+	}
+}
+
+func TestForgetWorkloadMetrics(t *testing.T) {
+	tel, reader := newWorkloadMetricsTelemetry()
+	ctx := context.Background()
+
+	vm1 := attribute.String("workload_id", "vm1")
+	vm2 := attribute.String("workload_id", "vm2")
+
+	_ = tel.RecordWorkloadMetric(ctx, "vm1", "queue_depth", "gauge", 10, vm1)
+	_ = tel.RecordWorkloadMetric(ctx, "vm2", "queue_depth", "gauge", 7, vm2)
+
+	tel.ForgetWorkloadMetrics(ctx, "vm1")
+
+	if _, ok := tel.workloadMetrics.gaugeValues["vm1"]; ok {
+		t.Fatal("expected gauge bookkeeping to be discarded for the stopped workload")
+	}
+	if _, ok := tel.workloadMetrics.gaugeValues["vm2"]; !ok {
+		t.Fatal("expected gauge bookkeeping to be retained for the running workload")
+	}
+
+	values := make(map[string]float64)
+	for _, p := range collectSum(t, reader, "queue_depth") {
+		id, _ := p.Attributes.Value("workload_id")
+		values[id.AsString()] = p.Value
+	}
+
+	if values["vm1"] != 0 || values["vm2"] != 7 {
+		t.Fatalf("expected the stopped workload's gauge to be retracted; got %v", values)
+	}
+}
@@ -0,0 +1,110 @@
+package nexnode
+
+import (
+	"fmt"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/node/processmanager"
+)
+
+// Size of the machine running an agent process
+type machineSize struct {
+	vcpuCount  int
+	memSizeMib int
+}
+
+// Returns true if the machine satisfies the given resource requirements
+func (m machineSize) fits(resources *controlapi.WorkloadResources) bool {
+	return m.vcpuCount >= resources.VcpuCount && m.memSizeMib >= resources.MemSizeMib
+}
+
+// Returns true if the machine is smaller than the given machine, by memory and then by vCPU count
+func (m machineSize) smallerThan(other machineSize) bool {
+	if m.memSizeMib != other.memSizeMib {
+		return m.memSizeMib < other.memSizeMib
+	}
+
+	return m.vcpuCount < other.vcpuCount
+}
+
+// Returns the pending agent on the smallest machine which satisfies the resources required by the
+// given deploy request, keeping larger machines for larger workloads. Agents prewarmed with artifacts,
+// and agents on machines created for another workload, are not considered. Returns nil if the request
+// declares no resources, if the process manager does not report machine sizes, or if no pending agent
+// satisfies the request
+func (w *WorkloadManager) bestFitAgent(request *agentapi.DeployRequest) *agentapi.AgentClient {
+	if request.Resources == nil {
+		return nil
+	}
+
+	reporter, ok := w.procMan.(processmanager.ProcessResourceReporter)
+	if !ok {
+		return nil
+	}
+
+	sizes := make(map[string]machineSize, len(w.pendingAgents))
+	for id := range w.pendingAgents {
+		if _, prewarmed := w.prewarmed[id]; prewarmed {
+			continue
+		}
+		if _, sized := w.sizedAgents[id]; sized {
+			continue
+		}
+
+		vcpuCount, memSizeMib, ok := reporter.ProcessResources(id)
+		if ok {
+			sizes[id] = machineSize{vcpuCount: vcpuCount, memSizeMib: memSizeMib}
+		}
+	}
+
+	id, ok := bestFit(sizes, request.Resources)
+	if !ok {
+		return nil
+	}
+
+	return w.pendingAgents[id]
+}
+
+// Returns the id of the smallest of the given machines which satisfies the given resources
+func bestFit(sizes map[string]machineSize, resources *controlapi.WorkloadResources) (string, bool) {
+	var bestID string
+	var best *machineSize
+
+	for id, size := range sizes {
+		if !size.fits(resources) {
+			continue
+		}
+
+		if best == nil || size.smallerThan(*best) || (!best.smallerThan(size) && id < bestID) {
+			size := size
+			best = &size
+			bestID = id
+		}
+	}
+
+	return bestID, best != nil
+}
+
+// Returns an error if the memory limit of the given deploy request exceeds the memory of the machine
+// running the agent with the given id, which would leave the limit unenforceable
+func (w *WorkloadManager) checkMemoryLimit(id string, request *agentapi.DeployRequest) error {
+	if request.MemoryLimitMib == nil {
+		return nil
+	}
+
+	reporter, ok := w.procMan.(processmanager.ProcessResourceReporter)
+	if !ok {
+		return nil
+	}
+
+	_, memSizeMib, ok := reporter.ProcessResources(id)
+	if ok && *request.MemoryLimitMib > memSizeMib {
+		return &controlapi.InvalidFieldError{
+			Field:   "memory_limit_mib",
+			Message: fmt.Sprintf("workload memory limit of %d MiB exceeds the %d MiB of memory of its machine", *request.MemoryLimitMib, memSizeMib),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package nexnode
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/models"
+	"github.com/synadia-io/nex/internal/node/observability"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// Process manager reporting the size of the machine running each agent process
+type sizedProcessManager struct {
+	idleProcessManager
+	sizes map[string]machineSize
+}
+
+func (m sizedProcessManager) ProcessResources(id string) (int, int, bool) {
+	size, ok := m.sizes[id]
+	return size.vcpuCount, size.memSizeMib, ok
+}
+
+func TestBestFitPrefersSmallestSatisfyingMachine(t *testing.T) {
+	sizes := map[string]machineSize{
+		"small":  {vcpuCount: 1, memSizeMib: 128},
+		"medium": {vcpuCount: 2, memSizeMib: 512},
+		"large":  {vcpuCount: 4, memSizeMib: 2048},
+	}
+
+	id, ok := bestFit(sizes, &controlapi.WorkloadResources{VcpuCount: 2, MemSizeMib: 256})
+	if !ok || id != "medium" {
+		t.Fatalf("expected medium machine to be selected, got %q", id)
+	}
+
+	id, ok = bestFit(sizes, &controlapi.WorkloadResources{})
+	if !ok || id != "small" {
+		t.Fatalf("expected small machine to be selected for a workload without requirements, got %q", id)
+	}
+
+	_, ok = bestFit(sizes, &controlapi.WorkloadResources{MemSizeMib: 4096})
+	if ok {
+		t.Fatal("expected no machine to satisfy the requested resources")
+	}
+}
+
+func TestMemoryLimitMustFitMachine(t *testing.T) {
+	w := &WorkloadManager{
+		procMan: sizedProcessManager{sizes: map[string]machineSize{"vm1": {vcpuCount: 1, memSizeMib: 512}}},
+	}
+
+	limit := 256
+	request := &agentapi.DeployRequest{MemoryLimitMib: &limit}
+	err := w.checkMemoryLimit("vm1", request)
+	if err != nil {
+		t.Fatalf("expected memory limit below the machine's memory to be accepted: %s", err)
+	}
+
+	limit = 1024
+	err = w.checkMemoryLimit("vm1", request)
+	var invalid *controlapi.InvalidFieldError
+	if !errors.As(err, &invalid) || invalid.Field != "memory_limit_mib" {
+		t.Fatalf("expected memory limit exceeding the machine's memory to be rejected, got %v", err)
+	}
+
+	err = w.checkMemoryLimit("vm1", &agentapi.DeployRequest{})
+	if err != nil {
+		t.Fatalf("expected request without a memory limit to be accepted: %s", err)
+	}
+}
+
+func TestAgentsSizedForAWorkloadAreNotSelected(t *testing.T) {
+	w := &WorkloadManager{
+		procMan:       sizedProcessManager{sizes: map[string]machineSize{"vm1": {vcpuCount: 4, memSizeMib: 2048}}},
+		pendingAgents: map[string]*agentapi.AgentClient{"vm1": {}},
+		sizedAgents:   map[string]struct{}{"vm1": {}},
+	}
+
+	request := &agentapi.DeployRequest{Resources: &controlapi.WorkloadResources{MemSizeMib: 128}}
+	if w.bestFitAgent(request) != nil {
+		t.Fatal("expected agent sized for another workload not to be a best fit")
+	}
+
+	_, err := w.selectAgent(request)
+	if err == nil {
+		t.Fatal("expected no agent to be selected while the only agent is sized for another workload")
+	}
+
+	delete(w.sizedAgents, "vm1")
+	agentClient, err := w.selectAgent(request)
+	if err != nil || agentClient == nil {
+		t.Fatalf("expected agent to be selected once released to the pool: %v", err)
+	}
+}
+
+func TestSizedAgentHandshakeTimeoutsAreNotCountedAsFailures(t *testing.T) {
+	w := &WorkloadManager{
+		ctx:           context.Background(),
+		config:        &models.NodeConfiguration{AgentHandshakeFailureThreshold: 1},
+		log:           slog.Default(),
+		t:             &observability.Telemetry{HandshakeFailureCounter: noop.Int64Counter{}},
+		poolMutex:     &sync.Mutex{},
+		pendingAgents: map[string]*agentapi.AgentClient{"vm1": nil},
+		prewarmed:     make(map[string]*prewarmedAgent),
+		sizedAgents:   map[string]struct{}{"vm1": {}},
+		stopMutex:     map[string]*sync.Mutex{"vm1": {}},
+	}
+
+	w.agentHandshakeTimedOut("vm1")
+
+	if failures := w.handshakeFailures.Load(); failures != 0 {
+		t.Fatalf("expected the sized agent's timeout not to count toward consecutive failures, got %d", failures)
+	}
+	if _, pending := w.pendingAgents["vm1"]; pending {
+		t.Fatal("expected the sized agent to no longer be pending, failing the deployment awaiting it")
+	}
+	if _, reserved := w.sizedAgents["vm1"]; !reserved {
+		t.Fatal("expected the sized agent to remain reserved until its deployment stops its machine")
+	}
+}
@@ -0,0 +1,136 @@
+package nexnode
+
+import (
+	"log/slog"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go"
+	"github.com/google/uuid"
+	controlapi "github.com/synadia-io/nex/control-api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Returns the number of idle agents the node keeps in its warm pool
+func (w *WorkloadManager) GetPoolTarget() int {
+	return w.procMan.GetPoolTarget()
+}
+
+// Sets the number of idle agents the node keeps in its warm pool. Growing the pool is left to the
+// process manager; when shrinking, surplus idle agents are stopped, preferring those that have not
+// been prewarmed. Agents running workloads are never stopped
+func (w *WorkloadManager) SetPoolTarget(target int) error {
+	err := w.procMan.SetPoolTarget(target)
+	if err != nil {
+		return err
+	}
+
+	// an explicit target supersedes any slots given up by reaping idle agents or added for bursts
+	w.poolMutex.Lock()
+	w.reapedAgents = 0
+	w.burstSlots = 0
+	w.poolMutex.Unlock()
+
+	surplus := w.surplusIdleAgents(target)
+	for _, id := range surplus {
+		err := w.procMan.StopProcess(id)
+		if err != nil {
+			w.log.Warn("Failed to stop surplus idle agent", slog.String("workload_id", id), slog.Any("err", err))
+		}
+	}
+
+	w.log.Info("Machine pool target updated", slog.Int("target", target), slog.Int("stopped", len(surplus)))
+	return nil
+}
+
+// Returns the minimum interval between agent creations while the node fills its warm pool
+func (w *WorkloadManager) GetPoolCreateInterval() time.Duration {
+	return w.procMan.GetPoolCreateInterval()
+}
+
+// Sets the minimum interval between agent creations while the node fills its warm pool, taking
+// effect for the next creation
+func (w *WorkloadManager) SetPoolCreateInterval(interval time.Duration) error {
+	err := w.procMan.SetPoolCreateInterval(interval)
+	if err != nil {
+		return err
+	}
+
+	w.log.Info("Machine pool create interval updated", slog.Duration("interval", interval))
+	return nil
+}
+
+// Removes idle agents in excess of the given pool target from the pool, returning their ids
+func (w *WorkloadManager) surplusIdleAgents(target int) []string {
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
+	excess := len(w.pendingAgents) - target
+	if excess <= 0 {
+		return nil
+	}
+
+	candidates := make([]string, 0, len(w.pendingAgents))
+	for id := range w.pendingAgents {
+		if _, ok := w.prewarmed[id]; !ok {
+			candidates = append(candidates, id)
+		}
+	}
+	for id := range w.pendingAgents {
+		if _, ok := w.prewarmed[id]; ok {
+			candidates = append(candidates, id)
+		}
+	}
+
+	surplus := candidates[:excess]
+	for _, id := range surplus {
+		_ = w.pendingAgents[id].Drain()
+		delete(w.pendingAgents, id)
+		delete(w.prewarmed, id)
+		delete(w.stopMutex, id)
+		delete(w.handshakes, id)
+	}
+
+	return surplus
+}
+
+// Records a deployment rejected because the warm pool had no idle agent to receive it
+func (w *WorkloadManager) recordPoolStarvationRejected() {
+	w.t.PoolStarvationCounter.Add(w.ctx, 1, metric.WithAttributes(attribute.Bool("rejected", true)))
+	_ = w.publishPoolStarved(true)
+}
+
+func (w *WorkloadManager) publishPoolStarved(rejected bool) error {
+	evt := controlapi.PoolStarvedEvent{
+		Id:         w.publicKey,
+		PoolTarget: w.procMan.GetPoolTarget(),
+		Rejected:   rejected,
+	}
+
+	cloudevent := cloudevents.NewEvent()
+	cloudevent.SetSource(w.publicKey)
+	cloudevent.SetID(uuid.NewString())
+	cloudevent.SetTime(time.Now().UTC())
+	cloudevent.SetType(controlapi.PoolStarvedEventType)
+	cloudevent.SetDataContentType(cloudevents.ApplicationJSON)
+	_ = cloudevent.SetData(evt)
+
+	return w.publishCloudEvent(systemNamespace, cloudevent)
+}
+
+func (w *WorkloadManager) publishPoolRefillChanged(paused bool) error {
+	evt := controlapi.PoolRefillChangedEvent{
+		Id:     w.publicKey,
+		Paused: paused,
+	}
+
+	cloudevent := cloudevents.NewEvent()
+	cloudevent.SetSource(w.publicKey)
+	cloudevent.SetID(uuid.NewString())
+	cloudevent.SetTime(time.Now().UTC())
+	cloudevent.SetType(controlapi.PoolRefillChangedEventType)
+	cloudevent.SetDataContentType(cloudevents.ApplicationJSON)
+	_ = cloudevent.SetData(evt)
+
+	return w.publishCloudEvent(systemNamespace, cloudevent)
+}
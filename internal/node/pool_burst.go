@@ -0,0 +1,105 @@
+package nexnode
+
+import (
+	"log/slog"
+	"time"
+)
+
+const poolBurstTrimInterval = 5 * time.Second
+
+// Returns the number of idle agents in the warm pool
+func (w *WorkloadManager) warmAgents() int {
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
+	return len(w.pendingAgents)
+}
+
+// Raises the pool target by a slot, up to the upper pool bound, when claiming an agent has left
+// fewer idle agents than the configured low watermark, so that bursts of deployments find agents
+// warm rather than waiting on fresh ones. Disabled unless a low watermark is configured. The
+// caller must hold the pool mutex
+func (w *WorkloadManager) growPoolForBurst() {
+	watermark := w.config.MachinePoolLowWatermark
+	if watermark <= 0 || len(w.pendingAgents) >= watermark {
+		return
+	}
+
+	_, poolMax := w.config.ResolveMachinePoolBounds()
+	target := w.procMan.GetPoolTarget() + 1
+	if target > poolMax {
+		return
+	}
+
+	err := w.procMan.SetPoolTarget(target)
+	if err != nil {
+		w.log.Warn("Failed to grow machine pool target", slog.Int("target", target), slog.Any("err", err))
+		return
+	}
+
+	w.burstSlots++
+	w.lastBurst = time.Now().UTC()
+	w.log.Debug("Grew machine pool below low watermark", slog.Int("target", target), slog.Int("idle", len(w.pendingAgents)), slog.Int("watermark", watermark))
+}
+
+// Periodically trims the slots added to the pool while it was below its low watermark once the
+// pool has not grown for the configured cooldown, stopping the surplus idle agents
+func (w *WorkloadManager) trimPoolBurst() {
+	if w.config.MachinePoolLowWatermark <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(poolBurstTrimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			if w.stopping() {
+				return
+			}
+
+			target, trimmed := w.expiredPoolBurst(time.Now().UTC())
+			if !trimmed {
+				continue
+			}
+
+			surplus := w.surplusIdleAgents(target)
+			for _, id := range surplus {
+				err := w.procMan.StopProcess(id)
+				if err != nil {
+					w.log.Warn("Failed to stop surplus idle agent", slog.String("workload_id", id), slog.Any("err", err))
+				}
+			}
+
+			w.log.Info("Trimmed machine pool burst", slog.Int("target", target), slog.Int("stopped", len(surplus)))
+		}
+	}
+}
+
+// Lowers the pool target by the slots added while the pool was below its low watermark, never
+// below the lower pool bound, once the configured cooldown has passed since the last was added.
+// Returns the new pool target and whether it was lowered
+func (w *WorkloadManager) expiredPoolBurst(now time.Time) (int, bool) {
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
+	target := w.procMan.GetPoolTarget()
+	if w.burstSlots == 0 || now.Sub(w.lastBurst) < w.config.ResolveMachinePoolBurstCooldown() {
+		return target, false
+	}
+
+	poolMin, _ := w.config.ResolveMachinePoolBounds()
+	trimmed := max(target-w.burstSlots, poolMin)
+
+	err := w.procMan.SetPoolTarget(trimmed)
+	if err != nil {
+		w.log.Warn("Failed to trim machine pool target", slog.Int("target", trimmed), slog.Any("err", err))
+		return target, false
+	}
+
+	w.burstSlots = 0
+	return trimmed, true
+}
@@ -0,0 +1,54 @@
+package nexnode
+
+import (
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+func TestPoolGrowsBelowLowWatermarkAndTrimsAfterCooldown(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	target := 3
+	w := &WorkloadManager{
+		config: &models.NodeConfiguration{
+			MachinePoolSize:                     3,
+			MachinePoolMax:                      5,
+			MachinePoolLowWatermark:             2,
+			MachinePoolBurstCooldownMillisecond: int(time.Minute.Milliseconds()),
+		},
+		log:           log,
+		procMan:       targetedProcessManager{target: &target},
+		pendingAgents: make(map[string]*agentapi.AgentClient),
+		poolMutex:     &sync.Mutex{},
+	}
+
+	w.pendingAgents["vm1"] = nil
+	w.pendingAgents["vm2"] = nil
+	w.growPoolForBurst()
+	if target != 3 {
+		t.Fatalf("expected pool target to hold while idle agents meet the low watermark, got %d", target)
+	}
+
+	delete(w.pendingAgents, "vm2")
+	for i := 0; i < 3; i++ {
+		w.growPoolForBurst()
+	}
+	if target != 5 || w.burstSlots != 2 {
+		t.Fatalf("expected pool target to grow below the low watermark up to the pool max, got %d", target)
+	}
+
+	if _, trimmed := w.expiredPoolBurst(w.lastBurst.Add(30 * time.Second)); trimmed {
+		t.Fatal("expected burst slots to be held until the cooldown passes")
+	}
+
+	newTarget, trimmed := w.expiredPoolBurst(w.lastBurst.Add(time.Minute))
+	if !trimmed || newTarget != 3 || target != 3 || w.burstSlots != 0 {
+		t.Fatalf("expected burst slots to be trimmed once the cooldown passes, got target %d", target)
+	}
+}
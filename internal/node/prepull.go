@@ -0,0 +1,94 @@
+package nexnode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+// Downloads each artifact listed in the node configuration into the internal cache, so that the
+// first deployment of it after startup is served locally. Failures are logged per artifact and
+// never abort startup
+func (w *WorkloadManager) prepullArtifacts() {
+	for _, artifact := range w.config.PrepullArtifacts {
+		if w.stopping() {
+			return
+		}
+
+		hash, err := w.prepullArtifact(artifact)
+		if err != nil {
+			w.log.Warn("Failed to pre-pull artifact", slog.String("location", artifact.Location), slog.Any("err", err))
+			continue
+		}
+
+		w.log.Info("Pre-pulled artifact", slog.String("location", artifact.Location), slog.String("hash", hash))
+	}
+}
+
+func (w *WorkloadManager) prepullArtifact(artifact models.PrepullArtifact) (string, error) {
+	location, err := url.Parse(artifact.Location)
+	if err != nil {
+		return "", err
+	}
+
+	bytes, _, err := w.downloadArtifact(location, artifact.JsDomain)
+	if err != nil {
+		return "", err
+	}
+
+	artifactHash := sha256.Sum256(bytes)
+	hash := hex.EncodeToString(artifactHash[:])
+
+	if artifact.Hash != "" && !strings.EqualFold(hash, artifact.Hash) {
+		return "", fmt.Errorf("checksum mismatch; expected %s, got %s", artifact.Hash, hash)
+	}
+
+	cache, err := w.internalCache()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = cache.PutBytes(prewarmArtifactKey(hash), bytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to stage artifact in internal cache: %s", err)
+	}
+
+	return hash, nil
+}
+
+// Returns the artifact described by the given object info from the internal cache if it has
+// previously been staged there by a pre-pull or prewarm, or nil if it has not
+func (w *WorkloadManager) cachedArtifact(info *nats.ObjectInfo) []byte {
+	digest, err := nats.DecodeObjectDigest(info.Digest)
+	if err != nil {
+		return nil
+	}
+
+	cache, err := w.internalCache()
+	if err != nil {
+		return nil
+	}
+
+	bytes, err := cache.GetBytes(prewarmArtifactKey(hex.EncodeToString(digest)))
+	if err != nil {
+		return nil
+	}
+
+	return bytes
+}
+
+func (w *WorkloadManager) internalCache() (nats.ObjectStore, error) {
+	jsInternal, err := w.ncInternal.JetStream()
+	if err != nil {
+		return nil, err
+	}
+
+	return jsInternal.ObjectStore(agentapi.WorkloadCacheBucket)
+}
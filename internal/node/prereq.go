@@ -149,14 +149,19 @@ type fileSpec struct {
 // and required prerequisites are automatically installed to configured paths
 // if they are otherwise missing when paired with config.ForceDepInstall.
 func CheckPrerequisites(config *models.NodeConfiguration, noninteractive bool, logger *slog.Logger) error {
-	if strings.EqualFold(runtime.GOOS, "windows") {
+	if !sandboxSupported() {
+		platform := "Windows"
+		if strings.EqualFold(runtime.GOOS, "darwin") {
+			platform = "macOS"
+		}
+
 		if !config.NoSandbox {
-			fmt.Print("\t⛔ Windows host must be configured to run in no sandbox mode\n")
-			return errors.New("windows host must be configured to run in no sandbox mode")
+			fmt.Printf("\t⛔ %s host must be configured to run in no sandbox mode\n", platform)
+			return fmt.Errorf("%s host must be configured to run in no sandbox mode", runtime.GOOS)
 		}
 
 		if !noninteractive {
-			fmt.Print("\t✅ Windows host properly configured to run in no sandbox mode\n")
+			fmt.Printf("\t✅ %s host properly configured to run in no sandbox mode\n", platform)
 		}
 
 		return nil
@@ -0,0 +1,197 @@
+package nexnode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+const prewarmReapInterval = 5 * time.Second
+
+// An idle agent on which an artifact has been staged ahead of deployment
+type prewarmedAgent struct {
+	hash         string
+	namespace    string
+	workloadType string
+	expiresAt    time.Time
+}
+
+// Returns true if the given deploy request can claim the prewarmed agent
+func (p *prewarmedAgent) matches(request *agentapi.DeployRequest) bool {
+	return p.hash == request.Hash &&
+		strings.EqualFold(p.namespace, *request.Namespace) &&
+		strings.EqualFold(p.workloadType, *request.WorkloadType)
+}
+
+// Name of the object in the internal cache bucket in which a prewarmed or pre-pulled artifact is
+// staged. Workload names cannot contain a dash, so these never collide with deployed workloads
+func prewarmArtifactKey(hash string) string {
+	return fmt.Sprintf("prewarm-%s", hash)
+}
+
+// Downloads the artifact indicated by the prewarm request and stages it on idle agents in the pool,
+// which are then preferred by deployments of the same artifact in the given namespace
+func (w *WorkloadManager) PrewarmWorkload(namespace string, request *controlapi.PrewarmRequest) (*controlapi.PrewarmResponse, error) {
+	if !slices.Contains(w.config.WorkloadTypes, request.WorkloadType) {
+		return nil, fmt.Errorf("unsupported workload type on this node: %s", request.WorkloadType)
+	}
+
+	artifact, _, err := w.downloadArtifact(request.Location, request.JsDomain)
+	if err != nil {
+		return nil, err
+	}
+
+	artifactHash := sha256.Sum256(artifact)
+	hash := hex.EncodeToString(artifactHash[:])
+
+	cache, err := w.internalCache()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = cache.PutBytes(prewarmArtifactKey(hash), artifact)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage prewarm artifact in internal cache: %s", err)
+	}
+
+	count := request.Count
+	if count == 0 {
+		count = 1
+	}
+
+	idleTimeout := time.Duration(w.config.PrewarmIdleTimeoutMillisecond) * time.Millisecond
+	if request.IdleTimeoutMillisecond != nil {
+		idleTimeout = time.Duration(*request.IdleTimeoutMillisecond) * time.Millisecond
+	}
+
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
+	candidates := w.pendingAgents
+	if request.TargetVM != nil {
+		id, err := w.pinnedAgentID(*request.TargetVM)
+		if err != nil {
+			return nil, err
+		}
+
+		candidates = map[string]*agentapi.AgentClient{id: w.pendingAgents[id]}
+	}
+
+	prepared := make([]string, 0)
+	for id, agentClient := range candidates {
+		if len(prepared) == count {
+			break
+		}
+
+		if _, ok := w.prewarmed[id]; ok {
+			continue
+		}
+
+		if _, ok := w.handshakes[id]; !ok {
+			continue
+		}
+
+		resp, err := agentClient.PrepareWorkload(&agentapi.PrepareRequest{
+			ArtifactKey:  prewarmArtifactKey(hash),
+			Hash:         hash,
+			WorkloadType: request.WorkloadType,
+		})
+		if err != nil {
+			w.log.Warn("Failed to prewarm agent", slog.String("workload_id", id), slog.Any("err", err))
+			continue
+		}
+
+		if !resp.Accepted {
+			w.log.Warn("Agent rejected prewarm request", slog.String("workload_id", id), slog.String("reason", *resp.Message))
+			continue
+		}
+
+		w.prewarmed[id] = &prewarmedAgent{
+			hash:         hash,
+			namespace:    namespace,
+			workloadType: request.WorkloadType,
+			expiresAt:    time.Now().UTC().Add(idleTimeout),
+		}
+		prepared = append(prepared, id)
+	}
+
+	if len(prepared) == 0 {
+		return nil, errors.New("no idle agents available to prewarm")
+	}
+
+	w.log.Info("Prewarmed agents with workload artifact",
+		slog.String("namespace", namespace),
+		slog.String("workload_type", request.WorkloadType),
+		slog.String("hash", hash),
+		slog.Int("requested", count),
+		slog.Int("prepared", len(prepared)),
+	)
+
+	return &controlapi.PrewarmResponse{
+		Hash:         hash,
+		WorkloadType: request.WorkloadType,
+		Prepared:     prepared,
+	}, nil
+}
+
+// Periodically stops prewarmed agents that have not been claimed by a deployment within their
+// idle timeout, allowing the process manager to replace them with fresh agents
+func (w *WorkloadManager) reapPrewarmedAgents() {
+	ticker := time.NewTicker(prewarmReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			if w.stopping() {
+				return
+			}
+
+			for _, id := range w.expiredPrewarmedAgents() {
+				w.log.Info("Reaping unclaimed prewarmed agent", slog.String("workload_id", id))
+
+				err := w.procMan.StopProcess(id)
+				if err != nil {
+					w.log.Warn("Failed to stop unclaimed prewarmed agent", slog.String("workload_id", id), slog.Any("err", err))
+				}
+			}
+		}
+	}
+}
+
+// Removes expired prewarmed agents from the pool, returning their ids
+func (w *WorkloadManager) expiredPrewarmedAgents() []string {
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
+	now := time.Now().UTC()
+	expired := make([]string, 0)
+
+	for id, prewarmed := range w.prewarmed {
+		if now.Before(prewarmed.expiresAt) {
+			continue
+		}
+
+		if agentClient, ok := w.pendingAgents[id]; ok {
+			_ = agentClient.Drain()
+			delete(w.pendingAgents, id)
+		}
+
+		delete(w.prewarmed, id)
+		delete(w.stopMutex, id)
+		delete(w.handshakes, id)
+		expired = append(expired, id)
+	}
+
+	return expired
+}
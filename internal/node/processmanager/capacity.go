@@ -0,0 +1,104 @@
+package processmanager
+
+import (
+	"fmt"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+// Resources allocated to the machines running the workloads of a single namespace
+type ResourceAllocation struct {
+	Machines  int
+	Vcpu      int
+	MemoryMib int
+}
+
+// Vcpus and memory the host can allocate to agent processes, and those currently allocated
+type ResourceSummary struct {
+	// Resources which may be allocated to machines running workloads: the host's resources less
+	// those reserved for it, bounded by the configured machine quotas, if any
+	TotalVcpu      int
+	TotalMemoryMib int
+
+	// Resources allocated to every machine, whether idle in the warm pool or running a workload
+	AllocatedVcpu      int
+	AllocatedMemoryMib int
+
+	// Resources held by the warm pool once it is full: the machine template times the pool target
+	PoolVcpu      int
+	PoolMemoryMib int
+
+	// Resources allocated to the machines running workloads, by namespace
+	Namespaces map[string]ResourceAllocation
+}
+
+// A machine to which resources are allocated, running a workload of the given namespace, if any
+type machineAllocation struct {
+	namespace  *string
+	vcpus      int
+	memSizeMib int
+}
+
+// Summarizes the given machines' allocations against the given allocatable host resources
+func summarizeCapacity(config *models.NodeConfiguration, poolTarget, allocatableVcpu, allocatableMemoryMib int, machines []machineAllocation) ResourceSummary {
+	summary := ResourceSummary{
+		TotalVcpu:      allocatableVcpu,
+		TotalMemoryMib: allocatableMemoryMib,
+		PoolVcpu:       *config.MachineTemplate.VcpuCount * poolTarget,
+		PoolMemoryMib:  *config.MachineTemplate.MemSizeMib * poolTarget,
+		Namespaces:     make(map[string]ResourceAllocation),
+	}
+
+	if config.MachineVcpuQuota > 0 {
+		summary.TotalVcpu = min(summary.TotalVcpu, config.MachineVcpuQuota)
+	}
+	if config.MachineMemoryQuotaMib > 0 {
+		summary.TotalMemoryMib = min(summary.TotalMemoryMib, config.MachineMemoryQuotaMib)
+	}
+
+	for _, machine := range machines {
+		summary.AllocatedVcpu += machine.vcpus
+		summary.AllocatedMemoryMib += machine.memSizeMib
+
+		if machine.namespace != nil {
+			allocation := summary.Namespaces[*machine.namespace]
+			allocation.Machines++
+			allocation.Vcpu += machine.vcpus
+			allocation.MemoryMib += machine.memSizeMib
+			summary.Namespaces[*machine.namespace] = allocation
+		}
+	}
+
+	return summary
+}
+
+// Returns the resources allocated to the machines running workloads, across all namespaces
+func (s ResourceSummary) WorkloadAllocation() ResourceAllocation {
+	var total ResourceAllocation
+	for _, allocation := range s.Namespaces {
+		total.Machines += allocation.Machines
+		total.Vcpu += allocation.Vcpu
+		total.MemoryMib += allocation.MemoryMib
+	}
+
+	return total
+}
+
+// Returns an error wrapping ErrInsufficientHostResources if a machine sized for a workload with the
+// given resources, as per the given machine template, would allocate more vcpus or memory to machines
+// running workloads than the host can allocate
+func (s ResourceSummary) Admits(template models.MachineTemplate, resources *controlapi.WorkloadResources) error {
+	vcpus, memSizeMib := machineDimensions(template, resources)
+	allocated := s.WorkloadAllocation()
+
+	if allocated.Vcpu+int(vcpus) > s.TotalVcpu {
+		return fmt.Errorf("%w: workloads would be allocated %d of %d allocatable vcpus", ErrInsufficientHostResources, allocated.Vcpu+int(vcpus), s.TotalVcpu)
+	}
+
+	if allocated.MemoryMib+int(memSizeMib) > s.TotalMemoryMib {
+		return fmt.Errorf("%w: workloads would be allocated %d of %d allocatable MiB of memory", ErrInsufficientHostResources, allocated.MemoryMib+int(memSizeMib), s.TotalMemoryMib)
+	}
+
+	return nil
+}
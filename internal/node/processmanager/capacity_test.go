@@ -0,0 +1,53 @@
+package processmanager
+
+import (
+	"errors"
+	"testing"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+func TestCapacitySummary(t *testing.T) {
+	vcpuCount, memSizeMib := 1, 256
+	config := models.DefaultNodeConfiguration()
+	config.MachineTemplate = models.MachineTemplate{VcpuCount: &vcpuCount, MemSizeMib: &memSizeMib}
+	config.MachineMemoryQuotaMib = 2048
+
+	tenant, other := "tenant", "other"
+	summary := summarizeCapacity(&config, 2, 8, 4096, []machineAllocation{
+		{vcpus: 1, memSizeMib: 256},
+		{namespace: &tenant, vcpus: 2, memSizeMib: 1024},
+		{namespace: &tenant, vcpus: 1, memSizeMib: 256},
+		{namespace: &other, vcpus: 1, memSizeMib: 256},
+	})
+
+	if summary.TotalVcpu != 8 || summary.TotalMemoryMib != 2048 {
+		t.Fatalf("expected allocatable resources bounded by the machine quotas, got %d vcpus and %d MiB", summary.TotalVcpu, summary.TotalMemoryMib)
+	}
+
+	if summary.AllocatedVcpu != 5 || summary.AllocatedMemoryMib != 1792 {
+		t.Fatalf("expected every machine to be counted as allocated, got %d vcpus and %d MiB", summary.AllocatedVcpu, summary.AllocatedMemoryMib)
+	}
+
+	if summary.PoolVcpu != 2 || summary.PoolMemoryMib != 512 {
+		t.Fatalf("expected the warm pool to hold the template times the pool target, got %d vcpus and %d MiB", summary.PoolVcpu, summary.PoolMemoryMib)
+	}
+
+	if allocation := summary.Namespaces["tenant"]; allocation != (ResourceAllocation{Machines: 2, Vcpu: 3, MemoryMib: 1280}) {
+		t.Fatalf("unexpected allocation to namespace tenant: %+v", allocation)
+	}
+
+	if allocation := summary.WorkloadAllocation(); allocation != (ResourceAllocation{Machines: 3, Vcpu: 4, MemoryMib: 1536}) {
+		t.Fatalf("expected idle machines not to be allocated to workloads, got %+v", allocation)
+	}
+
+	if err := summary.Admits(config.MachineTemplate, nil); err != nil {
+		t.Fatalf("expected a workload of the template's size to fit: %s", err)
+	}
+
+	err := summary.Admits(config.MachineTemplate, &controlapi.WorkloadResources{MemSizeMib: 1024})
+	if !errors.Is(err, ErrInsufficientHostResources) {
+		t.Fatalf("expected a workload overcommitting memory to be rejected, got %v", err)
+	}
+}
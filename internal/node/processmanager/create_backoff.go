@@ -0,0 +1,78 @@
+package processmanager
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/synadia-io/nex/internal/models"
+)
+
+// Initial backoff following a failed attempt to create an agent process for the pool
+const poolCreateBackoffBase = 250 * time.Millisecond
+
+// Backs off a process manager's pool fill loop after consecutive failures to create an agent
+// process, so that a misconfigured host does not spin the loop hot. The backoff doubles with each
+// consecutive failure up to a cap, jittered, and the loop gives up once the configured number of
+// consecutive attempts have failed
+type poolCreateBackoff struct {
+	log         *slog.Logger
+	max         time.Duration
+	maxAttempts int
+	failures    int
+}
+
+func newPoolCreateBackoff(log *slog.Logger, config *models.NodeConfiguration) *poolCreateBackoff {
+	return &poolCreateBackoff{
+		log:         log,
+		max:         config.ResolvePoolCreateBackoffMax(),
+		maxAttempts: config.ResolvePoolCreateMaxAttempts(),
+	}
+}
+
+// Records a successful creation, resetting the backoff
+func (b *poolCreateBackoff) succeeded() {
+	b.failures = 0
+}
+
+// Records a failed creation with the given error. Returns an error once the maximum number of
+// consecutive attempts have failed; otherwise waits out the backoff, returning early if the given
+// context is done
+func (b *poolCreateBackoff) failed(ctx context.Context, err error) error {
+	b.failures++
+	if b.failures >= b.maxAttempts {
+		return fmt.Errorf("failed to create agent process after %d consecutive attempts: %w", b.failures, err)
+	}
+
+	delay := b.delay()
+	b.log.Warn("Failed to create agent process for pool; backing off",
+		slog.Int("attempt", b.failures),
+		slog.Int("max_attempts", b.maxAttempts),
+		slog.Duration("backoff", delay),
+		slog.Any("err", err),
+	)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+
+	return nil
+}
+
+// Returns the backoff following the current number of consecutive failures: the base backoff
+// doubled for each failure after the first, capped, and jittered to between half and all of it
+func (b *poolCreateBackoff) delay() time.Duration {
+	backoff := poolCreateBackoffBase
+	for i := 1; i < b.failures && backoff < b.max; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, b.max)
+
+	return backoff/2 + rand.N(backoff/2+1)
+}
@@ -0,0 +1,54 @@
+package processmanager
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/synadia-io/nex/internal/models"
+)
+
+func TestPoolCreateBackoffGivesUpAfterMaxAttempts(t *testing.T) {
+	config := models.DefaultNodeConfiguration()
+	config.PoolCreateMaxAttempts = 3
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	b := newPoolCreateBackoff(log, &config)
+
+	// a done context cuts the backoff short
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	boom := errors.New("boom")
+	for i := 0; i < 2; i++ {
+		if err := b.failed(ctx, boom); err != nil {
+			t.Fatalf("expected attempt %d to back off rather than give up: %s", i+1, err)
+		}
+	}
+
+	b.succeeded()
+	if err := b.failed(ctx, boom); err != nil {
+		t.Fatal("expected a successful creation to reset the consecutive failures")
+	}
+	_ = b.failed(ctx, boom)
+
+	err := b.failed(ctx, boom)
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected backoff to give up after max consecutive attempts, got %v", err)
+	}
+}
+
+func TestPoolCreateBackoffIsCappedAndJittered(t *testing.T) {
+	b := &poolCreateBackoff{max: time.Second}
+
+	for failures, want := range map[int]time.Duration{1: poolCreateBackoffBase, 2: 2 * poolCreateBackoffBase, 10: time.Second} {
+		b.failures = failures
+		delay := b.delay()
+		if delay < want/2 || delay > want {
+			t.Fatalf("expected backoff after %d failures between %s and %s, got %s", failures, want/2, want, delay)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package processmanager
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// Aggregates the activity of a process manager's pool fill loop, logging a periodic summary
+// of the agent processes added to the warm pool in place of a line per process
+type poolFillLog struct {
+	added    atomic.Int64
+	failed   atomic.Int64
+	interval time.Duration
+	log      *slog.Logger
+}
+
+// Creates a pool fill log summarizing at the given interval; a zero interval disables
+// the summary, logging each process added to the warm pool instead
+func newPoolFillLog(log *slog.Logger, intervalMillis int) *poolFillLog {
+	return &poolFillLog{
+		interval: time.Duration(intervalMillis) * time.Millisecond,
+		log:      log,
+	}
+}
+
+// Records an agent process added to the warm pool
+func (p *poolFillLog) recordAdded(msg string, args ...any) {
+	if p.interval == 0 {
+		p.log.Info(msg, args...)
+		return
+	}
+
+	p.added.Add(1)
+	p.log.Debug(msg, args...)
+}
+
+// Records a failure to add an agent process to the warm pool
+func (p *poolFillLog) recordFailed() {
+	p.failed.Add(1)
+}
+
+// Logs a summary of the pool fill activity at each interval until the given context is
+// done, omitting intervals in which the pool was not filled
+func (p *poolFillLog) run(ctx context.Context, depth func() int, target func() int) {
+	if p.interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			added := p.added.Swap(0)
+			failed := p.failed.Swap(0)
+			if added == 0 && failed == 0 {
+				continue
+			}
+
+			p.log.Info("Filled warm pool",
+				slog.Int64("added", added),
+				slog.Int64("failed", failed),
+				slog.Int("depth", depth()),
+				slog.Int("target", target()),
+				slog.Duration("interval", p.interval),
+			)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+//go:build linux
+
+package processmanager
+
+import "fmt"
+
+// Returns the vcpus and memory the host can allocate to machines, and those allocated to the
+// machines in the warm pool and running workloads
+func (f *FirecrackerProcessManager) Capacity() (ResourceSummary, error) {
+	allocatableVcpus, allocatableMemSizeMib, err := AllocatableHostResources(f.config)
+	if err != nil {
+		return ResourceSummary{}, fmt.Errorf("failed to determine allocatable host resources: %s", err)
+	}
+
+	f.vmsMutex.Lock()
+	machines := make([]machineAllocation, 0, len(f.allVMs))
+	for _, vm := range f.allVMs {
+		machine := machineAllocation{
+			vcpus:      int(*vm.machine.Cfg.MachineCfg.VcpuCount),
+			memSizeMib: int(*vm.machine.Cfg.MachineCfg.MemSizeMib),
+		}
+		if vm.deployRequest != nil {
+			machine.namespace = &vm.namespace
+		}
+
+		machines = append(machines, machine)
+	}
+	f.vmsMutex.Unlock()
+
+	return summarizeCapacity(f.config, f.GetPoolTarget(), allocatableVcpus, allocatableMemSizeMib, machines), nil
+}
@@ -0,0 +1,39 @@
+//go:build linux
+
+package processmanager
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Checks that the VM running the workload with the given id is still running, and that its agent
+// answers a ping within the configured liveness probe timeout
+func (f *FirecrackerProcessManager) HealthCheck(workloadID string) (HealthStatus, error) {
+	f.vmsMutex.Lock()
+	vm, exists := f.allVMs[workloadID]
+	claimed := exists && vm.deployRequest != nil
+	f.vmsMutex.Unlock()
+
+	if !claimed {
+		return HealthStatus{}, fmt.Errorf("no firecracker VM running workload %s", workloadID)
+	}
+
+	status := HealthStatus{CheckedAt: time.Now().UTC()}
+	if vm.vmmCtx.Err() != nil {
+		return status, nil
+	}
+
+	pong, latency, err := f.delegate.PingProcess(workloadID, f.config.ResolveLivenessProbeTimeout())
+	if err != nil {
+		f.log.Debug("Agent failed to answer liveness probe", slog.String("workload_id", workloadID), slog.Any("err", err))
+		return status, nil
+	}
+
+	status.Responsive = true
+	status.Latency = latency
+	status.UptimeMillisecond = pong.UptimeMillisecond
+	status.LastExecAt = pong.LastExecAt
+	return status, nil
+}
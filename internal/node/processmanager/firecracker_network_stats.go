@@ -0,0 +1,117 @@
+//go:build linux
+
+package processmanager
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+func (f *FirecrackerProcessManager) ProcessNetworkStats(workloadId string) (*controlapi.NetworkStats, bool) {
+	vm, exists := f.lookupVM(workloadId)
+	if !exists {
+		return nil, false
+	}
+
+	stats := vm.network.Load()
+	return stats, stats != nil
+}
+
+// Periodically samples the network counters of the VMs running workloads, recording their growth
+// as metrics tagged by workload. Disabled unless a sampling interval is configured
+func (f *FirecrackerProcessManager) sampleNetworkStats() {
+	interval := f.config.ResolveNetworkStatsInterval()
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.ctx.Done():
+			return
+		case <-ticker.C:
+			if f.stopping() {
+				return
+			}
+
+			// sampled outside the lock, as reading each VM's counters touches the filesystem
+			for _, running := range f.runningWorkloadVMs() {
+				err := f.sampleVMNetworkStats(running.vm, running.request)
+				if err != nil {
+					f.log.Debug("Failed to sample VM network counters", slog.String("workload_id", running.vm.vmmID), slog.Any("err", err))
+				}
+			}
+		}
+	}
+}
+
+type workloadVM struct {
+	vm      *runningFirecracker
+	request *agentapi.DeployRequest
+}
+
+// Returns a snapshot of the VMs running workloads, along with their workloads' deploy requests
+func (f *FirecrackerProcessManager) runningWorkloadVMs() []workloadVM {
+	f.vmsMutex.Lock()
+	defer f.vmsMutex.Unlock()
+
+	running := make([]workloadVM, 0, len(f.allVMs))
+	for _, vm := range f.allVMs {
+		if vm.deployRequest != nil {
+			running = append(running, workloadVM{vm: vm, request: vm.deployRequest})
+		}
+	}
+
+	return running
+}
+
+// Reads the counters of the VM's tap device, which lives in the network namespace of the VM's
+// firecracker process, and records their growth since the previous sample
+func (f *FirecrackerProcessManager) sampleVMNetworkStats(vm *runningFirecracker, request *agentapi.DeployRequest) error {
+	pid, err := vm.machine.PID()
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/net/dev", pid))
+	if err != nil {
+		return err
+	}
+
+	stats, err := parseNetDev(data, vm.hostTap, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	previous := vm.network.Swap(stats)
+	if previous == nil {
+		previous = &controlapi.NetworkStats{}
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("workload_id", request.TelemetryWorkloadID(vm.vmmID)),
+		attribute.String("namespace", *request.Namespace),
+		attribute.String("workload_name", *request.WorkloadName),
+	}
+	rx := metric.WithAttributes(append(attrs, attribute.String("direction", "rx"))...)
+	tx := metric.WithAttributes(append(attrs, attribute.String("direction", "tx"))...)
+
+	f.t.VmNetworkBytes.Add(f.ctx, counterDelta(previous.RxBytes, stats.RxBytes), rx)
+	f.t.VmNetworkBytes.Add(f.ctx, counterDelta(previous.TxBytes, stats.TxBytes), tx)
+	f.t.VmNetworkPackets.Add(f.ctx, counterDelta(previous.RxPackets, stats.RxPackets), rx)
+	f.t.VmNetworkPackets.Add(f.ctx, counterDelta(previous.TxPackets, stats.TxPackets), tx)
+	f.t.VmNetworkErrors.Add(f.ctx, counterDelta(previous.RxErrors, stats.RxErrors), rx)
+	f.t.VmNetworkErrors.Add(f.ctx, counterDelta(previous.TxErrors, stats.TxErrors), tx)
+
+	return nil
+}
@@ -0,0 +1,66 @@
+//go:build linux
+
+package processmanager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/synadia-io/nex/internal/models"
+)
+
+const kvmDevicePath = "/dev/kvm"
+
+// Verifies that this host is capable of running firecracker VMs, returning an error
+// describing each unsatisfied requirement (missing KVM, missing binary, insufficient
+// permissions) so the node can refuse to start cleanly instead of failing mid-run
+func CheckFirecrackerAvailable(config *models.NodeConfiguration) error {
+	var err error
+
+	if _, e := os.Stat(kvmDevicePath); e != nil {
+		err = errors.Join(err, fmt.Errorf("%s not found; ensure KVM is enabled on this host (nested virtualization may be required in cloud VMs)", kvmDevicePath))
+	} else if e := syscall.Access(kvmDevicePath, 0x2|0x4); e != nil { // W_OK | R_OK
+		err = errors.Join(err, fmt.Errorf("insufficient permissions to access %s; run nex as root or add this user to the kvm group", kvmDevicePath))
+	}
+
+	if _, e := resolveFirecrackerBinary(); e != nil {
+		err = errors.Join(err, e)
+	}
+
+	if _, e := os.Stat(config.KernelFilepath); e != nil {
+		err = errors.Join(err, fmt.Errorf("kernel file %q not found; run `nex node preflight` to install it", config.KernelFilepath))
+	}
+
+	if _, e := os.Stat(config.RootFsFilepath); e != nil {
+		err = errors.Join(err, fmt.Errorf("root filesystem %q not found; run `nex node preflight` to install it", config.RootFsFilepath))
+	}
+
+	return err
+}
+
+// Locates the firecracker binary on the path and ensures it is executable
+func resolveFirecrackerBinary() (string, error) {
+	firecrackerBinary, err := exec.LookPath("firecracker")
+	if err != nil {
+		return "", fmt.Errorf("firecracker binary not found on path; run `nex node preflight` to install it: %s", err)
+	}
+
+	finfo, err := os.Stat(firecrackerBinary)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("binary %q does not exist: %v", firecrackerBinary, err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to stat binary, %q: %v", firecrackerBinary, err)
+	}
+
+	if finfo.IsDir() {
+		return "", fmt.Errorf("binary, %q, is a directory", firecrackerBinary)
+	} else if finfo.Mode()&0111 == 0 {
+		return "", fmt.Errorf("binary, %q, is not executable. Check permissions of binary", firecrackerBinary)
+	}
+
+	return firecrackerBinary, nil
+}
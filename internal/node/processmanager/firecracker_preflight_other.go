@@ -0,0 +1,15 @@
+//go:build !linux
+
+package processmanager
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/synadia-io/nex/internal/models"
+)
+
+// Firecracker only runs on linux, so it is never available on this host
+func CheckFirecrackerAvailable(config *models.NodeConfiguration) error {
+	return fmt.Errorf("firecracker is not supported on %s", runtime.GOOS)
+}
@@ -4,7 +4,9 @@ package processmanager
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/exec"
@@ -12,44 +14,75 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
+	controlapi "github.com/synadia-io/nex/control-api"
 	agentapi "github.com/synadia-io/nex/internal/agent-api"
 	"github.com/synadia-io/nex/internal/models"
+	"github.com/synadia-io/nex/internal/node/natsint"
 	"github.com/synadia-io/nex/internal/node/observability"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 )
 
+// Maximum number of machines stopped at once when the process manager stops
+const maxConcurrentMachineStops = 8
+
 type FirecrackerProcessManager struct {
-	closing   uint32
-	config    *models.NodeConfiguration
-	ctx       context.Context
-	log       *slog.Logger
-	stopMutex map[string]*sync.Mutex
-	t         *observability.Telemetry
+	closing uint32
+	config  *models.NodeConfiguration
+	ctx     context.Context
+	log     *slog.Logger
+	t       *observability.Telemetry
+
+	natsintAuth *natsint.Authenticator
+
+	// Guards the machines, deploy requests and stop mutexes below, which the pool loop and sized
+	// machine creation update as the workload manager and samplers read them
+	vmsMutex       sync.Mutex
+	allVMs         map[string]*runningFirecracker
+	deployRequests map[string]*agentapi.DeployRequest
+	stopMutex      map[string]*sync.Mutex
 
-	allVMs  map[string]*runningFirecracker
-	warmVMs chan *runningFirecracker
+	poolTarget int32
+	backoff    *poolCreateBackoff
+	fillLog    *poolFillLog
+	pacer      *poolCreatePacer
+	refill     *poolRefillGate
+	warmVMs    chan *runningFirecracker
 
-	delegate       ProcessDelegate
-	deployRequests map[string]*agentapi.DeployRequest
+	delegate ProcessDelegate
 }
 
 func NewFirecrackerProcessManager(
 	log *slog.Logger,
 	config *models.NodeConfiguration,
 	telemetry *observability.Telemetry,
+	natsintAuth *natsint.Authenticator,
 	ctx context.Context,
 ) (*FirecrackerProcessManager, error) {
+	err := CheckFirecrackerAvailable(config)
+	if err != nil {
+		return nil, fmt.Errorf("firecracker is unavailable on this host; set no_sandbox, or no_sandbox_fallback to fall back to it when firecracker is unavailable, in the node configuration to run workloads without firecracker: %w", err)
+	}
+
+	_, poolMax := config.ResolveMachinePoolBounds()
+
 	return &FirecrackerProcessManager{
-		config: config,
-		t:      telemetry,
-		log:    log,
-		ctx:    ctx,
+		config:      config,
+		t:           telemetry,
+		log:         log,
+		ctx:         ctx,
+		natsintAuth: natsintAuth,
+		poolTarget:  int32(config.MachinePoolSize),
+		backoff:     newPoolCreateBackoff(log, config),
+		fillLog:     newPoolFillLog(log, config.PoolFillLogIntervalMillisecond),
+		pacer:       newPoolCreatePacer(config.PoolCreateIntervalMillisecond),
+		refill:      newPoolRefillGate(config.PoolRefillBackoffMillisecond),
 
 		allVMs:         make(map[string]*runningFirecracker),
-		warmVMs:        make(chan *runningFirecracker, config.MachinePoolSize),
+		warmVMs:        make(chan *runningFirecracker, poolMax),
 		stopMutex:      make(map[string]*sync.Mutex),
 		deployRequests: make(map[string]*agentapi.DeployRequest),
 	}, nil
@@ -57,6 +90,10 @@ func NewFirecrackerProcessManager(
 
 func (f *FirecrackerProcessManager) ListProcesses() ([]ProcessInfo, error) {
 	pinfos := make([]ProcessInfo, 0)
+	now := time.Now().UTC()
+
+	f.vmsMutex.Lock()
+	defer f.vmsMutex.Unlock()
 
 	for workloadId, vm := range f.allVMs {
 		// Ignore "pending" processes that don't have workloads on them yet
@@ -66,6 +103,9 @@ func (f *FirecrackerProcessManager) ListProcesses() ([]ProcessInfo, error) {
 				Name:          *vm.deployRequest.WorkloadName,
 				Namespace:     *vm.deployRequest.Namespace,
 				DeployRequest: vm.deployRequest,
+				ExitCode:      vm.deployRequest.ExitCode,
+				StartedAt:     vm.workloadStarted,
+				Uptime:        now.Sub(vm.workloadStarted),
 			}
 			pinfos = append(pinfos, pinfo)
 		}
@@ -75,6 +115,8 @@ func (f *FirecrackerProcessManager) ListProcesses() ([]ProcessInfo, error) {
 }
 
 func (f *FirecrackerProcessManager) EnterLameDuck() error {
+	f.vmsMutex.Lock()
+	defer f.vmsMutex.Unlock()
 
 	nope := false
 	for _, req := range f.deployRequests {
@@ -84,11 +126,27 @@ func (f *FirecrackerProcessManager) EnterLameDuck() error {
 	return nil
 }
 
-// Preparing a workload reads from the warmVMs channel
+// Preparing a workload claims the VM with the given id and reads from the warmVMs channel,
+// freeing a slot in the warm pool so that it can be replenished
 func (f *FirecrackerProcessManager) PrepareWorkload(workloadId string, deployRequest *agentapi.DeployRequest) error {
-	vm := <-f.warmVMs
-	if vm == nil {
-		return fmt.Errorf("could not prepare workload, no available firecracker VM")
+	vm, err := f.checkPreparable(workloadId, deployRequest)
+	if err != nil {
+		return err
+	}
+
+	// machines created for a single workload never entered the warm pool. Waiting on the pool
+	// happens without the lock held, as the pool loop needs it to add the machines awaited
+	if !vm.sized {
+		_, ok, err := takeWarm(f.ctx, f.t, f.delegate, f.warmVMs, 0)
+		if err != nil || !ok {
+			return fmt.Errorf("could not prepare workload, no available firecracker VM")
+		}
+	}
+
+	f.vmsMutex.Lock()
+	if current, exists := f.allVMs[workloadId]; !exists || current != vm || vm.deployRequest != nil {
+		f.vmsMutex.Unlock()
+		return fmt.Errorf("could not prepare workload, firecracker VM %s was stopped or claimed by another workload", workloadId)
 	}
 
 	vm.deployRequest = deployRequest
@@ -96,6 +154,7 @@ func (f *FirecrackerProcessManager) PrepareWorkload(workloadId string, deployReq
 	vm.workloadStarted = time.Now().UTC()
 
 	f.deployRequests[vm.vmmID] = deployRequest
+	f.vmsMutex.Unlock()
 
 	f.t.AllocatedVCPUCounter.Add(f.ctx, *vm.machine.Cfg.MachineCfg.VcpuCount)
 	f.t.AllocatedVCPUCounter.Add(f.ctx, *vm.machine.Cfg.MachineCfg.VcpuCount, metric.WithAttributes(attribute.String("namespace", vm.namespace)))
@@ -105,31 +164,231 @@ func (f *FirecrackerProcessManager) PrepareWorkload(workloadId string, deployReq
 	return nil
 }
 
+// Returns the unclaimed VM with the given id if the given deploy request may be prepared on it
+// without exceeding its namespace's quota or the host's allocatable resources
+func (f *FirecrackerProcessManager) checkPreparable(workloadId string, deployRequest *agentapi.DeployRequest) (*runningFirecracker, error) {
+	f.vmsMutex.Lock()
+	defer f.vmsMutex.Unlock()
+
+	vm, exists := f.allVMs[workloadId]
+	if !exists || vm.deployRequest != nil {
+		return nil, fmt.Errorf("could not prepare workload, no available firecracker VM with id %s", workloadId)
+	}
+
+	err := checkNamespaceQuota(f.config, *deployRequest.Namespace, f.namespaceAllocations(*deployRequest.Namespace))
+	if err != nil {
+		return nil, err
+	}
+
+	if f.config.ReservesHostResources() {
+		err = f.checkAllocatableHostResources(vm)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return vm, nil
+}
+
+// Returns the VM with the given id, if any
+func (f *FirecrackerProcessManager) lookupVM(id string) (*runningFirecracker, bool) {
+	f.vmsMutex.Lock()
+	defer f.vmsMutex.Unlock()
+
+	vm, ok := f.allVMs[id]
+	return vm, ok
+}
+
+// Returns the key with which the environments of workloads deployed to the VM with the given id are sealed
+func (f *FirecrackerProcessManager) EnvironmentKey(id string) ([]byte, bool) {
+	vm, ok := f.lookupVM(id)
+	if !ok || vm.environmentKey == nil {
+		return nil, false
+	}
+
+	return vm.environmentKey, true
+}
+
+// Artifact images can be attached when enabled in the node configuration and mkfs.ext4 is
+// available to build them
+func (f *FirecrackerProcessManager) SupportsArtifactDevices() bool {
+	if !f.config.ArtifactBlockDevice {
+		return false
+	}
+
+	_, err := exec.LookPath("mkfs.ext4")
+	return err == nil
+}
+
+func (f *FirecrackerProcessManager) AttachArtifactDevice(workloadId string, imagePath string) (string, error) {
+	vm, exists := f.lookupVM(workloadId)
+	if !exists {
+		return "", fmt.Errorf("could not attach artifact, no firecracker VM with id %s", workloadId)
+	}
+
+	return vm.attachArtifact(imagePath)
+}
+
+// Creates and starts a VM sized for a single workload with the given resources, outside the warm
+// pool. Unlike pooled VMs, the VM is not reported to the delegate
+func (f *FirecrackerProcessManager) CreateSizedProcess(resources *controlapi.WorkloadResources) (string, error) {
+	if f.stopping() {
+		return "", errors.New("firecracker process manager is stopping")
+	}
+
+	vcpus, memSizeMib := machineDimensions(f.config.MachineTemplate, resources)
+	if f.quotaExceededBy(vcpus, memSizeMib) {
+		return "", fmt.Errorf("%w: a machine with %d vcpus and %d MiB of memory would exceed the machine quota", ErrInsufficientHostResources, vcpus, memSizeMib)
+	}
+
+	vm, err := createAndStartVM(context.TODO(), f.config, resources, f.log)
+	if err != nil {
+		return "", fmt.Errorf("failed to create sized VM: %s", err)
+	}
+
+	err = f.setMetadata(vm)
+	if err != nil {
+		return "", fmt.Errorf("failed to set metadata on sized VM: %s", err)
+	}
+
+	vm.sized = true
+	f.addVM(vm)
+
+	f.t.VmCounter.Add(f.ctx, 1)
+
+	f.log.Info("Created VM sized for workload", slog.String("vmid", vm.vmmID), slog.Int64("vcpus", vcpus), slog.Int64("mem_size_mib", memSizeMib))
+	return vm.vmmID, nil
+}
+
+func (f *FirecrackerProcessManager) ProcessResources(workloadId string) (int, int, bool) {
+	vm, exists := f.lookupVM(workloadId)
+	if !exists {
+		return 0, 0, false
+	}
+
+	return int(*vm.machine.Cfg.MachineCfg.VcpuCount), int(*vm.machine.Cfg.MachineCfg.MemSizeMib), true
+}
+
+func (f *FirecrackerProcessManager) ProcessIP(workloadId string) (string, bool) {
+	vm, exists := f.lookupVM(workloadId)
+	if !exists || vm.ip == nil {
+		return "", false
+	}
+
+	return vm.ip.String(), true
+}
+
+func (f *FirecrackerProcessManager) GetPoolTarget() int {
+	return int(atomic.LoadInt32(&f.poolTarget))
+}
+
+func (f *FirecrackerProcessManager) SetPoolTarget(target int) error {
+	err := validatePoolTarget(f.config, target)
+	if err != nil {
+		return err
+	}
+
+	atomic.StoreInt32(&f.poolTarget, int32(target))
+	return nil
+}
+
+func (f *FirecrackerProcessManager) GetPoolCreateInterval() time.Duration {
+	return f.pacer.getInterval()
+}
+
+func (f *FirecrackerProcessManager) SetPoolCreateInterval(interval time.Duration) error {
+	err := validatePoolCreateInterval(interval)
+	if err != nil {
+		return err
+	}
+
+	f.pacer.setInterval(interval)
+	return nil
+}
+
 func (f *FirecrackerProcessManager) Stop() error {
 	if atomic.AddUint32(&f.closing, 1) == 1 {
 		f.log.Info("Firecracker process manager stopping")
 		close(f.warmVMs)
 
-		for vmID := range f.allVMs {
-			err := f.StopProcess(vmID)
-			if err != nil {
-				f.log.Warn("Failed to stop firecracker process", slog.String("workload_id", vmID), slog.String("error", err.Error()))
+		err := f.stopAll(f.config.ResolveShutdownTimeout())
+		f.cleanSockets()
+
+		return err
+	}
+
+	return nil
+}
+
+// Stops every machine concurrently, at most maxConcurrentMachineStops at a time, waiting up to the
+// given timeout for them to stop. Machines still running at the deadline have their firecracker
+// process killed. Returns the errors of the machines which failed to stop within the deadline
+func (f *FirecrackerProcessManager) stopAll(timeout time.Duration) error {
+	f.vmsMutex.Lock()
+	vms := make(map[string]*runningFirecracker, len(f.allVMs))
+	for vmID, vm := range f.allVMs {
+		vms[vmID] = vm
+	}
+	f.vmsMutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	sem := make(chan struct{}, maxConcurrentMachineStops)
+	stopped := make(map[string]chan struct{}, len(vms))
+	for vmID, vm := range vms {
+		done := make(chan struct{})
+		stopped[vmID] = done
+
+		go func() {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+
+			vm.shutdown(f.stopGracePeriod(vm))
+			close(done)
+		}()
+	}
+
+	var errs []error
+	for vmID, vm := range vms {
+		select {
+		case <-stopped[vmID]:
+		case <-ctx.Done():
+			select {
+			case <-stopped[vmID]:
+			default:
+				err := fmt.Errorf("machine %s did not stop within %s", vmID, timeout)
+				errs = append(errs, err)
+
+				f.log.Error("Killing firecracker process which failed to stop within shutdown timeout", slog.String("workload_id", vmID), slog.Duration("timeout", timeout))
+				killErr := f.killVM(vm)
+				if killErr != nil {
+					f.log.Error("Failed to kill firecracker process", slog.String("workload_id", vmID), slog.Any("err", killErr))
+					errs = append(errs, fmt.Errorf("failed to kill machine %s: %w", vmID, killErr))
+				}
 			}
 		}
 
-		f.cleanSockets()
+		f.releaseVM(vmID, vm)
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
-func (f *FirecrackerProcessManager) Start(delegate ProcessDelegate) error {
+func (f *FirecrackerProcessManager) Start(delegate ProcessDelegate) (err error) {
 	f.log.Info("Firecracker VM process manager starting")
 	f.delegate = delegate
 
 	defer func() {
 		if r := recover(); r != nil {
 			f.log.Debug(fmt.Sprintf("recovered: %s", r))
+			if !f.stopping() {
+				err = fmt.Errorf("firecracker process manager failed unexpectedly: %v", r)
+			}
 		}
 	}()
 
@@ -140,36 +399,66 @@ func (f *FirecrackerProcessManager) Start(delegate ProcessDelegate) error {
 		}
 	}
 
+	go f.fillLog.run(f.ctx, func() int { return len(f.warmVMs) }, f.GetPoolTarget)
+	go f.sampleNetworkStats()
+
 	for !f.stopping() {
 		select {
 		case <-f.ctx.Done():
 			return nil
 		default:
-			if len(f.warmVMs) == f.config.MachinePoolSize {
+			if len(f.warmVMs) >= f.GetPoolTarget() {
 				time.Sleep(runloopSleepInterval)
 				continue
 			}
 
-			vm, err := createAndStartVM(context.TODO(), f.config, f.log)
+			if f.quotaExhausted() {
+				if f.refill.pause() {
+					f.log.Warn("Machine quota exhausted; pausing warm pool refill")
+					go f.delegate.OnPoolRefillChanged(true)
+				}
+				f.refill.wait(f.ctx)
+				continue
+			}
+
+			if f.refill.resume() {
+				f.log.Info("Machine quota available; resuming warm pool refill")
+				go f.delegate.OnPoolRefillChanged(false)
+			}
+
+			f.pacer.wait(f.ctx)
+			if f.stopping() {
+				return nil
+			}
+
+			vm, err := createAndStartVM(context.TODO(), f.config, nil, f.log)
 			if err != nil {
-				f.log.Warn("Failed to create VMM for warming pool.", slog.Any("err", err))
+				f.fillLog.recordFailed()
+				err = f.backoff.failed(f.ctx, fmt.Errorf("failed to create VMM for warming pool: %w", err))
+				if err != nil {
+					return err
+				}
 				continue
 			}
 
 			err = f.setMetadata(vm)
 			if err != nil {
-				f.log.Warn("Failed to set metadata on VM for warming pool.", slog.Any("err", err))
+				f.fillLog.recordFailed()
+				err = f.backoff.failed(f.ctx, fmt.Errorf("failed to set metadata on VM for warming pool: %w", err))
+				if err != nil {
+					return err
+				}
 				continue
 			}
+			f.backoff.succeeded()
 
-			f.allVMs[vm.vmmID] = vm
-			f.stopMutex[vm.vmmID] = &sync.Mutex{}
+			f.addVM(vm)
 
 			f.t.VmCounter.Add(f.ctx, 1)
 
 			go f.delegate.OnProcessStarted(vm.vmmID)
 
-			f.log.Info("Adding new VM to warm pool", slog.Any("ip", vm.ip), slog.String("vmid", vm.vmmID))
+			f.fillLog.recordAdded("Adding new VM to warm pool", slog.Any("ip", vm.ip), slog.String("vmid", vm.vmmID))
 			f.warmVMs <- vm // If the pool is full, this line will block until a slot is available.
 		}
 	}
@@ -177,41 +466,192 @@ func (f *FirecrackerProcessManager) Start(delegate ProcessDelegate) error {
 	return nil
 }
 
+// Stops the agent process running the workload with the given id and hands its deploy request
+// to the delegate, which redeploys it to a fresh agent
+func (f *FirecrackerProcessManager) Restart(workloadID string) error {
+	f.vmsMutex.Lock()
+	request, ok := f.deployRequests[workloadID]
+	if !ok {
+		f.vmsMutex.Unlock()
+		return fmt.Errorf("%w: %s", ErrNoDeployRequest, workloadID)
+	}
+
+	if request.OriginalWorkloadID == nil {
+		request.OriginalWorkloadID = &workloadID
+	}
+	f.vmsMutex.Unlock()
+
+	err := f.StopProcess(workloadID)
+	if err != nil {
+		return err
+	}
+
+	go f.delegate.OnProcessRestarted(workloadID, request)
+	return nil
+}
+
 func (f *FirecrackerProcessManager) StopProcess(workloadID string) error {
+	f.vmsMutex.Lock()
 	vm, exists := f.allVMs[workloadID]
 	if !exists {
+		f.vmsMutex.Unlock()
 		return fmt.Errorf("failed to stop machine %s", workloadID)
 	}
 
 	delete(f.deployRequests, workloadID)
-
+	claimed := vm.deployRequest != nil
 	mutex := f.stopMutex[workloadID]
+	f.vmsMutex.Unlock()
+
+	if !claimed && !vm.sized {
+		// an unclaimed VM still occupies a slot in the warm pool
+		select {
+		case <-f.warmVMs:
+		default:
+		}
+	}
+
 	mutex.Lock()
 	defer mutex.Unlock()
 
 	f.log.Debug("Attempting to stop virtual machine", slog.String("workload_id", workloadID))
-	vm.shutdown()
+	vm.shutdown(f.stopGracePeriod(vm))
 
+	f.releaseVM(workloadID, vm)
+	return nil
+}
+
+// Kills the firecracker process of the given machine
+func (f *FirecrackerProcessManager) killVM(vm *runningFirecracker) error {
+	pid, err := vm.machine.PID()
+	if err != nil {
+		return err
+	}
+
+	return syscall.Kill(pid, syscall.SIGKILL)
+}
+
+// Returns the time given to the workload running on the given machine, if any, to exit before its
+// machine is stopped
+func (f *FirecrackerProcessManager) stopGracePeriod(vm *runningFirecracker) time.Duration {
+	if vm.deployRequest != nil {
+		return vm.deployRequest.ResolveStopGracePeriod(f.config.StopGracePeriodMillisecond)
+	}
+
+	return time.Duration(f.config.StopGracePeriodMillisecond) * time.Millisecond
+}
+
+// Records the given started machine, which may then be stopped or claimed by a workload
+func (f *FirecrackerProcessManager) addVM(vm *runningFirecracker) {
+	f.vmsMutex.Lock()
+	defer f.vmsMutex.Unlock()
+
+	f.allVMs[vm.vmmID] = vm
+	f.stopMutex[vm.vmmID] = &sync.Mutex{}
+}
+
+// Forgets the given stopped machine, releasing the resources allocated to it
+func (f *FirecrackerProcessManager) releaseVM(workloadID string, vm *runningFirecracker) {
+	f.vmsMutex.Lock()
 	delete(f.allVMs, workloadID)
+	delete(f.deployRequests, workloadID)
 	delete(f.stopMutex, workloadID)
+	f.vmsMutex.Unlock()
+
+	f.natsintAuth.RevokeCredentials(vm.vmmID)
 
 	if vm.deployRequest != nil {
 		f.t.WorkloadCounter.Add(f.ctx, -1, metric.WithAttributes(attribute.String("workload_type", *vm.deployRequest.WorkloadType)))
 		f.t.WorkloadCounter.Add(f.ctx, -1, metric.WithAttributes(attribute.String("workload_type", *vm.deployRequest.WorkloadType)), metric.WithAttributes(attribute.String("namespace", vm.namespace)))
 		f.t.DeployedByteCounter.Add(f.ctx, vm.deployRequest.TotalBytes*-1)
 		f.t.DeployedByteCounter.Add(f.ctx, vm.deployRequest.TotalBytes*-1, metric.WithAttributes(attribute.String("namespace", vm.namespace)))
+
+		// allocated when the workload was prepared, so idle machines are never counted
+		f.t.AllocatedVCPUCounter.Add(f.ctx, *vm.machine.Cfg.MachineCfg.VcpuCount*-1)
+		f.t.AllocatedVCPUCounter.Add(f.ctx, *vm.machine.Cfg.MachineCfg.VcpuCount*-1, metric.WithAttributes(attribute.String("namespace", vm.namespace)))
+		f.t.AllocatedMemoryCounter.Add(f.ctx, *vm.machine.Cfg.MachineCfg.MemSizeMib*-1)
+		f.t.AllocatedMemoryCounter.Add(f.ctx, *vm.machine.Cfg.MachineCfg.MemSizeMib*-1, metric.WithAttributes(attribute.String("namespace", vm.namespace)))
 	}
 
 	f.t.VmCounter.Add(f.ctx, -1)
-	f.t.AllocatedVCPUCounter.Add(f.ctx, *vm.machine.Cfg.MachineCfg.VcpuCount*-1)
-	f.t.AllocatedVCPUCounter.Add(f.ctx, *vm.machine.Cfg.MachineCfg.VcpuCount*-1, metric.WithAttributes(attribute.String("namespace", vm.namespace)))
-	f.t.AllocatedMemoryCounter.Add(f.ctx, *vm.machine.Cfg.MachineCfg.MemSizeMib*-1)
-	f.t.AllocatedMemoryCounter.Add(f.ctx, *vm.machine.Cfg.MachineCfg.MemSizeMib*-1, metric.WithAttributes(attribute.String("namespace", vm.namespace)))
+
+	f.refill.release()
+}
+
+// Returns true if starting another machine would exceed the configured vcpu or memory quota,
+// which accounts for every machine on the host, whether idle in the warm pool or running a workload
+func (f *FirecrackerProcessManager) quotaExhausted() bool {
+	return f.quotaExceededBy(int64(*f.config.MachineTemplate.VcpuCount), int64(*f.config.MachineTemplate.MemSizeMib))
+}
+
+// Returns true if starting another machine with the given vcpus and memory would exceed the
+// configured vcpu or memory quota
+func (f *FirecrackerProcessManager) quotaExceededBy(vcpus, memSizeMib int64) bool {
+	if f.config.MachineVcpuQuota == 0 && f.config.MachineMemoryQuotaMib == 0 {
+		return false
+	}
+
+	f.vmsMutex.Lock()
+	defer f.vmsMutex.Unlock()
+
+	for _, vm := range f.allVMs {
+		vcpus += *vm.machine.Cfg.MachineCfg.VcpuCount
+		memSizeMib += *vm.machine.Cfg.MachineCfg.MemSizeMib
+	}
+
+	if f.config.MachineVcpuQuota > 0 && vcpus > int64(f.config.MachineVcpuQuota) {
+		return true
+	}
+
+	return f.config.MachineMemoryQuotaMib > 0 && memSizeMib > int64(f.config.MachineMemoryQuotaMib)
+}
+
+// Returns the number of machines running workloads of the given namespace; callers hold vmsMutex
+func (f *FirecrackerProcessManager) namespaceAllocations(namespace string) int {
+	allocated := 0
+	for _, vm := range f.allVMs {
+		if vm.deployRequest != nil && vm.namespace == namespace {
+			allocated++
+		}
+	}
+
+	return allocated
+}
+
+// Returns an error wrapping ErrInsufficientHostResources if running a workload in the given machine
+// would allocate more vcpus or memory to machines running workloads than the host can allocate once
+// its reserved resources are set aside. Idle machines in the warm pool are not counted. Callers hold
+// vmsMutex
+func (f *FirecrackerProcessManager) checkAllocatableHostResources(vm *runningFirecracker) error {
+	allocatableVcpus, allocatableMemSizeMib, err := AllocatableHostResources(f.config)
+	if err != nil {
+		return fmt.Errorf("failed to determine allocatable host resources: %s", err)
+	}
+
+	vcpus := *vm.machine.Cfg.MachineCfg.VcpuCount
+	memSizeMib := *vm.machine.Cfg.MachineCfg.MemSizeMib
+	for _, running := range f.allVMs {
+		if running.deployRequest != nil {
+			vcpus += *running.machine.Cfg.MachineCfg.VcpuCount
+			memSizeMib += *running.machine.Cfg.MachineCfg.MemSizeMib
+		}
+	}
+
+	if vcpus > int64(allocatableVcpus) {
+		return fmt.Errorf("%w: workloads would be allocated %d of %d allocatable vcpus", ErrInsufficientHostResources, vcpus, allocatableVcpus)
+	}
+
+	if memSizeMib > int64(allocatableMemSizeMib) {
+		return fmt.Errorf("%w: workloads would be allocated %d of %d allocatable MiB of memory", ErrInsufficientHostResources, memSizeMib, allocatableMemSizeMib)
+	}
 
 	return nil
 }
 
 func (f *FirecrackerProcessManager) Lookup(workloadID string) (*agentapi.DeployRequest, error) {
+	f.vmsMutex.Lock()
+	defer f.vmsMutex.Unlock()
+
 	if request, ok := f.deployRequests[workloadID]; ok {
 		return request, nil
 	}
@@ -261,12 +701,70 @@ func (f *FirecrackerProcessManager) cleanSockets() {
 }
 
 func (f *FirecrackerProcessManager) setMetadata(vm *runningFirecracker) error {
-	return vm.setMetadata(&agentapi.MachineMetadata{
-		Message:      agentapi.StringOrNil("Host-supplied metadata"),
-		NodeNatsHost: vm.config.InternalNodeHost,
-		NodeNatsPort: vm.config.InternalNodePort,
-		VmID:         &vm.vmmID,
+	seed, err := f.readEntropySeed()
+	if err != nil {
+		return fmt.Errorf("failed to read entropy seed: %s", err)
+	}
+
+	vm.environmentKey, err = agentapi.NewEnvironmentKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate environment key: %s", err)
+	}
+
+	heartbeatInterval := int(f.config.ResolveAgentHeartbeatInterval().Milliseconds())
+	stopGracePeriod := f.config.StopGracePeriodMillisecond
+	logRateLimit, logBurst := f.config.ResolveAgentLogRateLimit()
+
+	creds, err := f.natsintAuth.CreateCredentials(vm.vmmID)
+	if err != nil {
+		return err
+	}
+
+	err = vm.setMetadata(&agentapi.MachineMetadata{
+		AgentUpdatePublicKey:         f.config.ResolveAgentUpdatePublicKey(),
+		EntropySeed:                  seed,
+		EnvironmentKey:               vm.environmentKey,
+		HeartbeatIntervalMillisecond: &heartbeatInterval,
+		LogBurst:                     &logBurst,
+		LogRateLimit:                 &logRateLimit,
+		Message:                      agentapi.StringOrNil("Host-supplied metadata"),
+		NodeNatsHost:                 vm.config.InternalNodeHost,
+		NodeNatsPort:                 vm.config.InternalNodePort,
+		NodeNatsToken:                &creds.AgentToken,
+		PluginPath:                   agentapi.StringOrNil(f.config.AgentPluginPath),
+		RequireSealedEnvironment:     f.config.RequireEnvironmentEncryption,
+		StopGracePeriodMillisecond:   &stopGracePeriod,
+		TracesEnabled:                f.config.OtelTraces,
+		VmID:                         &vm.vmmID,
+		WorkloadNatsToken:            &creds.WorkloadToken,
 	})
+	if err != nil {
+		f.natsintAuth.RevokeCredentials(vm.vmmID)
+	}
+
+	return err
+}
+
+// Reads the configured number of bytes from the host's entropy source, returning nil
+// when entropy seeding is disabled
+func (f *FirecrackerProcessManager) readEntropySeed() ([]byte, error) {
+	if f.config.EntropySeedBytes == 0 {
+		return nil, nil
+	}
+
+	source, err := os.Open(f.config.ResolveEntropySource())
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	seed := make([]byte, f.config.EntropySeedBytes)
+	_, err = io.ReadFull(source, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	return seed, nil
 }
 
 func (f *FirecrackerProcessManager) stopping() bool {
@@ -0,0 +1,56 @@
+package processmanager
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/synadia-io/nex/internal/models"
+)
+
+// Returned when running a workload would allocate more of the host's vcpus or memory to machines
+// than remain once the resources reserved for the host are set aside
+var ErrInsufficientHostResources = errors.New("insufficient allocatable host resources")
+
+// Returns the number of vcpus and MiB of memory of the host which may be allocated to machines
+// running workloads: the host's capacity less the resources reserved for the host OS, the node
+// process and telemetry
+func AllocatableHostResources(config *models.NodeConfiguration) (int, int, error) {
+	memoryMib, err := hostMemoryMib()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return max(runtime.NumCPU()-config.ReservedHostVcpu, 0), max(memoryMib-config.ReservedHostMemoryMib, 0), nil
+}
+
+// Returns the total memory of the host in MiB
+func hostMemoryMib() (int, error) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// MemTotal:       16314400 kB
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+
+		kb, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid MemTotal in /proc/meminfo: %s", err)
+		}
+
+		return kb / 1024, nil
+	}
+
+	return 0, errors.New("MemTotal not found in /proc/meminfo")
+}
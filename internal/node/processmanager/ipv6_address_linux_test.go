@@ -0,0 +1,16 @@
+package processmanager
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIPv6KernelArgsReplaceIPBootArg(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("fd00:7::/64")
+	address := net.IPNet{IP: net.ParseIP("fd00:7::2"), Mask: subnet.Mask}
+
+	args := ipv6KernelArgs("ro console=ttyS0 ip=fd00:7::2::fd00:7::1:ffff:ffff::veth0:off", address, net.ParseIP("fd00:7::1"))
+	if args != "ro console=ttyS0 nex.ipv6_address=fd00:7::2/64 nex.ipv6_gateway=fd00:7::1" {
+		t.Fatalf("unexpected kernel args: %s", args)
+	}
+}
@@ -0,0 +1,24 @@
+package processmanager
+
+import (
+	controlapi "github.com/synadia-io/nex/control-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+// Returns the vCPU count and memory size (MiB) of a machine created for a workload with the given
+// resources: those of the node's machine template, overridden by each dimension the resources declare
+func machineDimensions(template models.MachineTemplate, resources *controlapi.WorkloadResources) (int64, int64) {
+	vcpus := int64(*template.VcpuCount)
+	memSizeMib := int64(*template.MemSizeMib)
+
+	if resources != nil {
+		if resources.VcpuCount > 0 {
+			vcpus = int64(resources.VcpuCount)
+		}
+		if resources.MemSizeMib > 0 {
+			memSizeMib = int64(resources.MemSizeMib)
+		}
+	}
+
+	return vcpus, memSizeMib
+}
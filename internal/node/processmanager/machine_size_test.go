@@ -0,0 +1,25 @@
+package processmanager
+
+import (
+	"testing"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+func TestMachineDimensionsOverrideTemplate(t *testing.T) {
+	vcpuCount, memSizeMib := 1, 256
+	template := models.MachineTemplate{VcpuCount: &vcpuCount, MemSizeMib: &memSizeMib}
+
+	if vcpus, mem := machineDimensions(template, nil); vcpus != 1 || mem != 256 {
+		t.Fatalf("expected template dimensions without resources, got %d vcpus and %d MiB", vcpus, mem)
+	}
+
+	if vcpus, mem := machineDimensions(template, &controlapi.WorkloadResources{MemSizeMib: 2048}); vcpus != 1 || mem != 2048 {
+		t.Fatalf("expected only the declared memory to be overridden, got %d vcpus and %d MiB", vcpus, mem)
+	}
+
+	if vcpus, mem := machineDimensions(template, &controlapi.WorkloadResources{VcpuCount: 4, MemSizeMib: 128}); vcpus != 4 || mem != 128 {
+		t.Fatalf("expected both dimensions to be overridden, got %d vcpus and %d MiB", vcpus, mem)
+	}
+}
@@ -0,0 +1,22 @@
+package processmanager
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/synadia-io/nex/internal/models"
+)
+
+// Returned when a namespace already has as many machines running its workloads as its quota allows
+var ErrNamespaceQuotaExceeded = errors.New("namespace quota exceeded")
+
+// Returns an error wrapping ErrNamespaceQuotaExceeded if the given namespace, with the given number
+// of machines already allocated to it, may not be allocated another
+func checkNamespaceQuota(config *models.NodeConfiguration, namespace string, allocated int) error {
+	quota, ok := config.NamespaceQuota(namespace)
+	if !ok || allocated < quota {
+		return nil
+	}
+
+	return fmt.Errorf("%w: namespace %s has %d of %d allowed machines", ErrNamespaceQuotaExceeded, namespace, allocated, quota)
+}
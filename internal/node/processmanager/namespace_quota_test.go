@@ -0,0 +1,31 @@
+package processmanager
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/synadia-io/nex/internal/models"
+)
+
+func TestNamespaceQuota(t *testing.T) {
+	config := models.DefaultNodeConfiguration()
+	config.NamespaceQuotas = map[string]int{"tenant": 2}
+
+	if err := checkNamespaceQuota(&config, "other", 100); err != nil {
+		t.Fatalf("expected namespace without a quota to be unlimited: %s", err)
+	}
+
+	if err := checkNamespaceQuota(&config, "tenant", 1); err != nil {
+		t.Fatalf("expected namespace below its quota to be allocated a machine: %s", err)
+	}
+
+	err := checkNamespaceQuota(&config, "tenant", 2)
+	if !errors.Is(err, ErrNamespaceQuotaExceeded) {
+		t.Fatalf("expected namespace at its quota to be rejected, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "2 of 2") {
+		t.Fatalf("expected error to report allocated and allowed machines: %s", err)
+	}
+}
@@ -0,0 +1,62 @@
+package processmanager
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+// Parses the counters of the given host-side interface from the contents of /proc/net/dev, returning
+// them from the point of view of the workload at the other end of the interface: traffic received by
+// the host interface was transmitted by the workload, and vice versa
+func parseNetDev(data []byte, iface string, sampledAt time.Time) (*controlapi.NetworkStats, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		name, counters, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.TrimSpace(name) != iface {
+			continue
+		}
+
+		// receive: bytes packets errs drop fifo frame compressed multicast; transmit: bytes packets errs ...
+		fields := strings.Fields(counters)
+		if len(fields) < 11 {
+			return nil, fmt.Errorf("malformed counters for interface %s", iface)
+		}
+
+		values := make([]uint64, 11)
+		for i := range values {
+			value, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed counters for interface %s: %s", iface, err)
+			}
+			values[i] = value
+		}
+
+		return &controlapi.NetworkStats{
+			RxBytes:   values[8],
+			RxPackets: values[9],
+			RxErrors:  values[10],
+			TxBytes:   values[0],
+			TxPackets: values[1],
+			TxErrors:  values[2],
+			SampledAt: sampledAt,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("interface %s not found", iface)
+}
+
+// Returns the growth of a cumulative counter between two samples, treating a counter that went
+// backwards as having been reset
+func counterDelta(previous, current uint64) int64 {
+	if current < previous {
+		return int64(current)
+	}
+
+	return int64(current - previous)
+}
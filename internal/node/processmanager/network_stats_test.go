@@ -0,0 +1,39 @@
+package processmanager
+
+import (
+	"testing"
+	"time"
+)
+
+const sampleNetDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:       0       0    0    0    0     0          0         0        0       0    0    0    0     0       0          0
+  tap0:    1200      12    1    0    0     0          0         0     3400      34    2    0    0     0       0          0
+`
+
+func TestParseNetDevFromWorkloadPointOfView(t *testing.T) {
+	stats, err := parseNetDev([]byte(sampleNetDev), "tap0", time.Now())
+	if err != nil {
+		t.Fatalf("failed to parse counters: %s", err)
+	}
+
+	if stats.RxBytes != 3400 || stats.RxPackets != 34 || stats.RxErrors != 2 ||
+		stats.TxBytes != 1200 || stats.TxPackets != 12 || stats.TxErrors != 1 {
+		t.Fatalf("expected host interface counters to be swapped to the workload's point of view: %+v", stats)
+	}
+
+	_, err = parseNetDev([]byte(sampleNetDev), "tap1", time.Now())
+	if err == nil {
+		t.Fatal("expected missing interface to fail")
+	}
+}
+
+func TestCounterDeltaTreatsDecreaseAsReset(t *testing.T) {
+	if delta := counterDelta(100, 150); delta != 50 {
+		t.Fatalf("expected delta of 50, got %d", delta)
+	}
+
+	if delta := counterDelta(100, 30); delta != 30 {
+		t.Fatalf("expected reset counter to count from zero, got %d", delta)
+	}
+}
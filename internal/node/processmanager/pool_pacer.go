@@ -0,0 +1,64 @@
+package processmanager
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Paces a process manager's pool fill loop, holding successive agent creations at least a minimum
+// interval apart so that filling a large pool does not spike host resources. The interval may be
+// changed while the loop is waiting; a zero interval disables pacing
+type poolCreatePacer struct {
+	interval atomic.Int64
+	changed  chan struct{}
+	last     time.Time
+}
+
+func newPoolCreatePacer(intervalMillis int) *poolCreatePacer {
+	p := &poolCreatePacer{
+		changed: make(chan struct{}, 1),
+	}
+	p.interval.Store(int64(time.Duration(intervalMillis) * time.Millisecond))
+
+	return p
+}
+
+// Returns the minimum interval between agent creations
+func (p *poolCreatePacer) getInterval() time.Duration {
+	return time.Duration(p.interval.Load())
+}
+
+// Sets the minimum interval between agent creations, taking effect for a creation already waiting
+func (p *poolCreatePacer) setInterval(interval time.Duration) {
+	p.interval.Store(int64(interval))
+
+	select {
+	case p.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Waits until the minimum interval has elapsed since the previous creation, returning early if the
+// given context is done, then records the start of the next creation
+func (p *poolCreatePacer) wait(ctx context.Context) {
+	for {
+		remaining := time.Until(p.last.Add(p.getInterval()))
+		if remaining <= 0 {
+			break
+		}
+
+		timer := time.NewTimer(remaining)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-p.changed:
+			// re-evaluate against the new interval
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+
+	p.last = time.Now()
+}
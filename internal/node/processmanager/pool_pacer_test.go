@@ -0,0 +1,42 @@
+package processmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPoolCreatePacerSpacesCreations(t *testing.T) {
+	pacer := newPoolCreatePacer(50)
+
+	start := time.Now()
+	pacer.wait(context.Background())
+	if elapsed := time.Since(start); elapsed > 25*time.Millisecond {
+		t.Fatalf("expected first creation not to be paced, waited %s", elapsed)
+	}
+
+	pacer.wait(context.Background())
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("expected second creation to wait out the interval, waited %s", elapsed)
+	}
+}
+
+func TestPoolCreatePacerIntervalChangedWhileWaiting(t *testing.T) {
+	pacer := newPoolCreatePacer(int(time.Hour.Milliseconds()))
+	pacer.wait(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		pacer.wait(context.Background())
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	pacer.setInterval(0)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected waiting creation to proceed once pacing was disabled")
+	}
+}
@@ -0,0 +1,54 @@
+package processmanager
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/synadia-io/nex/internal/node/observability"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Returned when no agent process was added to an empty warm pool within the allowed wait
+var errPoolStarved = errors.New("timed out waiting for available agent process")
+
+// Takes an idle agent process from the given warm pool. When the pool is empty, the starvation is
+// recorded and reported to the delegate before waiting for a process to be added, for up to the
+// given timeout unless zero, and how long the caller waited is recorded. Returns false if the pool
+// has been closed
+func takeWarm[T any](ctx context.Context, t *observability.Telemetry, delegate ProcessDelegate, pool chan T, timeout time.Duration) (T, bool, error) {
+	select {
+	case p, ok := <-pool:
+		return p, ok, nil
+	default:
+	}
+
+	// process managers created outside of a node, e.g. in tests, have no telemetry
+	if t != nil {
+		t.PoolStarvationCounter.Add(ctx, 1, metric.WithAttributes(attribute.Bool("rejected", false)))
+	}
+	if delegate != nil {
+		go delegate.OnPoolStarved()
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
+
+	var zero T
+	started := time.Now()
+
+	select {
+	case p, ok := <-pool:
+		if t != nil {
+			t.PoolStarvationWait.Record(ctx, time.Since(started).Milliseconds())
+		}
+		return p, ok, nil
+	case <-expired:
+		return zero, false, errPoolStarved
+	case <-ctx.Done():
+		return zero, false, ctx.Err()
+	}
+}
@@ -0,0 +1,55 @@
+package processmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Process delegate counting the times the warm pool was starved
+type starvedDelegate struct {
+	ProcessDelegate
+	starved chan struct{}
+}
+
+func (d *starvedDelegate) OnPoolStarved() {
+	d.starved <- struct{}{}
+}
+
+func TestTakeWarmReportsStarvedPool(t *testing.T) {
+	delegate := &starvedDelegate{starved: make(chan struct{}, 2)}
+	pool := make(chan string, 1)
+
+	pool <- "warm"
+	p, ok, err := takeWarm(context.Background(), nil, delegate, pool, time.Second)
+	if err != nil || !ok || p != "warm" {
+		t.Fatalf("expected the warm process to be taken: %q %v %v", p, ok, err)
+	}
+	if len(delegate.starved) != 0 {
+		t.Fatal("expected no starvation to be reported while the pool holds a process")
+	}
+
+	_, _, err = takeWarm(context.Background(), nil, delegate, pool, 50*time.Millisecond)
+	if !errors.Is(err, errPoolStarved) {
+		t.Fatalf("expected taking from an empty pool to time out, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		pool <- "cold"
+	}()
+
+	p, ok, err = takeWarm(context.Background(), nil, delegate, pool, 0)
+	if err != nil || !ok || p != "cold" {
+		t.Fatalf("expected to wait for the process added to the empty pool: %q %v %v", p, ok, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-delegate.starved:
+		case <-time.After(time.Second):
+			t.Fatal("expected each take from an empty pool to be reported as starvation")
+		}
+	}
+}
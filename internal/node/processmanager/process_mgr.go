@@ -1,19 +1,32 @@
 package processmanager
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
+	controlapi "github.com/synadia-io/nex/control-api"
 	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/models"
 )
 
 const runloopSleepInterval = 100 * time.Millisecond
 
+// Returned when restarting an agent process to which no workload has been deployed
+var ErrNoDeployRequest = errors.New("no deploy request recorded for workload")
+
 // Information about an agent process without regard to the implementation of the agent process manager
 type ProcessInfo struct {
 	DeployRequest *agentapi.DeployRequest
-	ID            string
-	Name          string
-	Namespace     string
+	// Exit code reported by the agent once the workload process has exited, or nil while it is running
+	ExitCode  *int
+	ID        string
+	Name      string
+	Namespace string
+
+	// Time at which the workload was assigned to its agent process, and how long it has been running since
+	StartedAt time.Time
+	Uptime    time.Duration
 }
 
 // A process delegate is any struct that wishes to be notified when the configured agent process
@@ -22,6 +35,23 @@ type ProcessDelegate interface {
 	// Indicates that an agent process with the given id has been started and is ready for workload deployment
 	OnProcessStarted(id string)
 
+	// Indicates that the process manager has paused or resumed refilling its warm pool because
+	// the host's machine quota has been exhausted or capacity has been freed
+	OnPoolRefillChanged(paused bool)
+
+	// Indicates that a workload is being prepared while the warm pool is empty, so that it must wait
+	// for an agent process to be created
+	OnPoolStarved()
+
+	// Indicates that the workload deployed to the agent process with the given id has been restarted,
+	// its original process stopped, so that it can be dispatched to a fresh agent process from the
+	// warm pool with its original deploy request
+	OnProcessRestarted(id string, request *agentapi.DeployRequest)
+
+	// Pings the agent running in the process with the given id, waiting up to the given timeout for
+	// its reply, which is returned along with the round-trip latency of the ping
+	PingProcess(id string, timeout time.Duration) (*agentapi.AgentPingResponse, time.Duration, error)
+
 	// Indicates that an agent process with the given id should exit
 	// OnProcessExit(id string) error
 }
@@ -52,7 +82,119 @@ type ProcessManager interface {
 	// Terminate a running agent process with the given ID
 	StopProcess(id string) error
 
+	// Restart the workload deployed to the agent process with the given ID, e.g. after it has crashed,
+	// stopping its process while keeping its deploy request, which is handed to the delegate to be
+	// dispatched to a fresh agent process. The deploy request retains the workload's original ID for
+	// telemetry. Fails with ErrNoDeployRequest if no workload has been deployed to the process
+	Restart(id string) error
+
 	// Notifies the process manager that the node is in lame duck mode, so that the processes
 	// can be treated differerently (if applicable)
 	EnterLameDuck() error
+
+	// Returns the number of idle agent processes the process manager keeps in its warm pool
+	GetPoolTarget() int
+
+	// Sets the number of idle agent processes the process manager keeps in its warm pool. The pool is
+	// grown by the start loop; shrinking it is left to the caller, which stops surplus idle processes
+	SetPoolTarget(target int) error
+
+	// Returns the minimum interval between agent process creations while filling the warm pool
+	GetPoolCreateInterval() time.Duration
+
+	// Sets the minimum interval between agent process creations while filling the warm pool, pacing
+	// the start loop to avoid boot storms. A zero interval creates processes as fast as possible
+	SetPoolCreateInterval(interval time.Duration) error
+}
+
+// Implemented by process managers that can attach a workload artifact image to an agent process
+// as a read-only block device, allowing the artifact to bypass the internal object store
+type ArtifactDeviceAttacher interface {
+	// Returns true if artifact images can be attached to agent processes on this host
+	SupportsArtifactDevices() bool
+
+	// Attach the ext4 image at the given path to the agent process with the given id, returning
+	// the path of the block device as seen by the agent. On success, ownership of the image passes
+	// to the process manager, which removes it when the process stops
+	AttachArtifactDevice(id string, imagePath string) (string, error)
+}
+
+// Implemented by process managers which hand each agent process its own key, with which the
+// environments of the workloads deployed to it are sealed
+type EnvironmentKeyProvider interface {
+	// Returns the environment key of the agent process with the given id, if it has one
+	EnvironmentKey(id string) ([]byte, bool)
+}
+
+// Implemented by process managers whose agent processes are each allotted their own resources
+type ProcessResourceReporter interface {
+	// Returns the vCPU count and memory size (MiB) of the agent process with the given id, if known
+	ProcessResources(id string) (int, int, bool)
+}
+
+// Implemented by process managers which can check that the agent process running a workload is
+// still alive and responsive
+type ProcessHealthChecker interface {
+	// Returns the health of the agent process running the workload with the given id
+	HealthCheck(id string) (HealthStatus, error)
+}
+
+// Health of the agent process running a workload, as observed by a liveness probe
+type HealthStatus struct {
+	// True if the process is running and its agent answered the probe in time
+	Responsive bool
+
+	// Round-trip latency of the probe; zero unless the agent answered
+	Latency time.Duration
+
+	UptimeMillisecond int64
+	LastExecAt        *time.Time
+	CheckedAt         time.Time
+}
+
+// Implemented by process managers which can create an agent process sized for a single workload
+// when no process in the warm pool satisfies its resources
+type SizedProcessCreator interface {
+	// Create and start an agent process with the given resources, outside the warm pool, returning
+	// its id. The process is not reported to the delegate; the caller starts its agent client
+	CreateSizedProcess(resources *controlapi.WorkloadResources) (string, error)
+}
+
+// Implemented by process managers which allot each agent process its own share of the host's
+// vcpus and memory
+type ProcessCapacityReporter interface {
+	// Returns the vcpus and memory the host can allocate to agent processes and those allocated,
+	// in total and to the agent processes running the workloads of each namespace
+	Capacity() (ResourceSummary, error)
+}
+
+// Implemented by process managers whose agent processes are assigned their own IP address
+type ProcessAddressResolver interface {
+	// Returns the IP address assigned to the agent process with the given id, if any
+	ProcessIP(id string) (string, bool)
+}
+
+// Implemented by process managers that sample the network counters of their agent processes
+type ProcessNetworkReporter interface {
+	// Returns the latest sample of the network counters of the agent process with the given id, if any
+	ProcessNetworkStats(id string) (*controlapi.NetworkStats, bool)
+}
+
+// Validates that the given warm pool target lies within the configured machine pool bounds
+func validatePoolTarget(config *models.NodeConfiguration, target int) error {
+	poolMin, poolMax := config.ResolveMachinePoolBounds()
+	if target < poolMin || target > poolMax {
+		return fmt.Errorf("pool target must be between %d and %d", poolMin, poolMax)
+	}
+
+	return nil
+}
+
+// Validates that the given interval between agent process creations is not negative
+func validatePoolCreateInterval(interval time.Duration) error {
+	if interval < 0 {
+		return errors.New("pool create interval must be >= 0")
+	}
+
+	return nil
 }
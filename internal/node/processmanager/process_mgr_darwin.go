@@ -0,0 +1,31 @@
+//go:build darwin
+
+package processmanager
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/synadia-io/nex/internal/models"
+	"github.com/synadia-io/nex/internal/node/natsint"
+	"github.com/synadia-io/nex/internal/node/observability"
+)
+
+// Initialize an appropriate agent process manager instance based on the sandbox config value.
+// Firecracker is unavailable on darwin, so agents can only be spawned directly
+func NewProcessManager(
+	log *slog.Logger,
+	config *models.NodeConfiguration,
+	telemetry *observability.Telemetry,
+	natsintAuth *natsint.Authenticator,
+	ctx context.Context,
+) (ProcessManager, error) {
+	if !config.NoSandbox {
+		return nil, errors.New("sandboxed workloads are not supported on darwin; run the node with no_sandbox")
+	}
+
+	log.Warn("⚠️  Sandboxing has been disabled! Workloads are spawned directly by agents")
+	log.Warn("⚠️  Do not run untrusted workloads in this mode!")
+	return NewSpawningProcessManager(log, config, telemetry, natsintAuth, ctx)
+}
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 
 	"github.com/synadia-io/nex/internal/models"
+	"github.com/synadia-io/nex/internal/node/natsint"
 	"github.com/synadia-io/nex/internal/node/observability"
 )
 
@@ -15,13 +16,14 @@ func NewProcessManager(
 	log *slog.Logger,
 	config *models.NodeConfiguration,
 	telemetry *observability.Telemetry,
+	natsintAuth *natsint.Authenticator,
 	ctx context.Context,
 ) (ProcessManager, error) {
 	if config.NoSandbox {
 		log.Warn("⚠️  Sandboxing has been disabled! Workloads are spawned directly by agents")
 		log.Warn("⚠️  Do not run untrusted workloads in this mode!")
-		return NewSpawningProcessManager(log, config, telemetry, ctx)
+		return NewSpawningProcessManager(log, config, telemetry, natsintAuth, ctx)
 	}
 
-	return NewFirecrackerProcessManager(log, config, telemetry, ctx)
+	return NewFirecrackerProcessManager(log, config, telemetry, natsintAuth, ctx)
 }
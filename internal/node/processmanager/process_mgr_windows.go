@@ -7,6 +7,7 @@ import (
 	"log/slog"
 
 	"github.com/synadia-io/nex/internal/models"
+	"github.com/synadia-io/nex/internal/node/natsint"
 	"github.com/synadia-io/nex/internal/node/observability"
 )
 
@@ -15,7 +16,8 @@ func NewProcessManager(
 	log *slog.Logger,
 	config *models.NodeConfiguration,
 	telemetry *observability.Telemetry,
+	natsintAuth *natsint.Authenticator,
 	ctx context.Context,
 ) (ProcessManager, error) {
-	return NewSpawningProcessManager(log, config, telemetry, ctx)
+	return NewSpawningProcessManager(log, config, telemetry, natsintAuth, ctx)
 }
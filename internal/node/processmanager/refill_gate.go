@@ -0,0 +1,76 @@
+package processmanager
+
+import (
+	"context"
+	"time"
+)
+
+// Upper bound of the backoff between refill attempts while the pool fill loop is paused
+const maxPoolRefillBackoff = 30 * time.Second
+
+// Tracks whether a process manager's pool fill loop is paused because the host's machine quota
+// is exhausted, backing off between refill attempts until capacity is freed
+type poolRefillGate struct {
+	backoff time.Duration
+	base    time.Duration
+	freed   chan struct{}
+	paused  bool
+}
+
+// Creates a refill gate backing off from the given interval, doubling it on each attempt
+// made while paused; a zero interval backs off from the run loop's sleep interval
+func newPoolRefillGate(backoffMillis int) *poolRefillGate {
+	base := time.Duration(backoffMillis) * time.Millisecond
+	if base == 0 {
+		base = runloopSleepInterval
+	}
+
+	return &poolRefillGate{
+		base:  base,
+		freed: make(chan struct{}, 1),
+	}
+}
+
+// Pauses refilling, returning true if refilling was not already paused
+func (g *poolRefillGate) pause() bool {
+	if g.paused {
+		return false
+	}
+
+	g.paused = true
+	g.backoff = g.base
+	return true
+}
+
+// Resumes refilling, returning true if refilling was paused
+func (g *poolRefillGate) resume() bool {
+	if !g.paused {
+		return false
+	}
+
+	g.paused = false
+	return true
+}
+
+// Waits out the current backoff before the next refill attempt, returning early if capacity
+// is freed or the given context is done
+func (g *poolRefillGate) wait(ctx context.Context) {
+	timer := time.NewTimer(g.backoff)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-g.freed:
+	case <-timer.C:
+		g.backoff = min(g.backoff*2, maxPoolRefillBackoff)
+	}
+}
+
+// Signals that capacity may have been freed, e.g. because a process stopped, cutting any
+// backoff short
+func (g *poolRefillGate) release() {
+	select {
+	case g.freed <- struct{}{}:
+	default:
+	}
+}
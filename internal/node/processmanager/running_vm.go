@@ -6,12 +6,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -21,10 +23,23 @@ import (
 	"github.com/firecracker-microvm/firecracker-go-sdk/client/models"
 	"github.com/rs/xid"
 
+	controlapi "github.com/synadia-io/nex/control-api"
 	agentapi "github.com/synadia-io/nex/internal/agent-api"
 	nexmodels "github.com/synadia-io/nex/internal/models"
 )
 
+const (
+	// Id of the firecracker drive to which workload artifact images are attached
+	artifactDriveID = "artifact"
+
+	// Path of the artifact drive within the guest; drives are enumerated in the order
+	// they are configured, after the root device
+	artifactGuestDevice = "/dev/vdb"
+
+	// Size of the empty image backing the artifact drive until an artifact is attached
+	artifactPlaceholderSize = 1024 * 1024
+)
+
 // Represents an instance of a single firecracker VM containing the nex agent.
 type runningFirecracker struct {
 	vmmCtx    context.Context
@@ -34,11 +49,15 @@ type runningFirecracker struct {
 	closing         uint32
 	config          *nexmodels.NodeConfiguration
 	deployRequest   *agentapi.DeployRequest
+	environmentKey  []byte
+	hostTap         string
 	ip              net.IP
 	log             *slog.Logger
 	machine         *firecracker.Machine
 	machineStarted  time.Time
 	namespace       string
+	network         atomic.Pointer[controlapi.NetworkStats]
+	sized           bool // created for a single workload, outside the warm pool
 	workloadStarted time.Time
 }
 
@@ -52,19 +71,30 @@ func (vm *runningFirecracker) setMetadata(metadata *agentapi.MachineMetadata) er
 	return nil
 }
 
-func (vm *runningFirecracker) shutdown() {
+// Shuts down the VM, first asking the guest to shut down cleanly and waiting up to the given
+// grace period for the VMM to exit before forcibly stopping it
+func (vm *runningFirecracker) shutdown(gracePeriod time.Duration) {
 	if atomic.AddUint32(&vm.closing, 1) == 1 {
 		vm.log.Info("Machine stopping",
 			slog.String("vmid", vm.vmmID),
 			slog.String("ip", vm.ip.String()),
+			slog.Duration("grace_period", gracePeriod),
 		)
 
-		err := vm.machine.StopVMM()
-		if err != nil {
-			vm.log.Error("Failed to stop firecracker VM", slog.Any("err", err))
+		graceful := vm.shutdownGracefully(gracePeriod)
+		if !graceful {
+			err := vm.machine.StopVMM()
+			if err != nil {
+				vm.log.Error("Failed to stop firecracker VM", slog.Any("err", err))
+			}
 		}
 
-		err = os.Remove(getSocketPath(vm.vmmID))
+		vm.log.Info("Machine stopped",
+			slog.String("vmid", vm.vmmID),
+			slog.Bool("graceful", graceful),
+		)
+
+		err := os.Remove(getSocketPath(vm.vmmID))
 		if err != nil {
 			if !errors.Is(err, fs.ErrNotExist) {
 				vm.log.Error("Failed to remove VM socket", slog.Any("err", err))
@@ -85,14 +115,69 @@ func (vm *runningFirecracker) shutdown() {
 				vm.log.Warn("Failed to delete VM rootfs", slog.Any("err", err))
 			}
 		}
+
+		if vm.config.ArtifactBlockDevice {
+			for _, imagePath := range []string{getArtifactPlaceholderPath(vm.vmmID), getArtifactImagePath(vm.vmmID)} {
+				err = os.Remove(imagePath)
+				if err != nil {
+					if !errors.Is(err, fs.ErrNotExist) {
+						vm.log.Warn("Failed to delete VM artifact image", slog.Any("err", err))
+					}
+				}
+			}
+		}
 	}
 }
 
-// Create a VMM with a given set of options and start the VM
-func createAndStartVM(ctx context.Context, config *nexmodels.NodeConfiguration, log *slog.Logger) (*runningFirecracker, error) {
+// Attaches the ext4 image at the given path to the VM's artifact drive in place of the empty
+// placeholder image, returning the path of the drive within the guest
+func (vm *runningFirecracker) attachArtifact(imagePath string) (string, error) {
+	if !vm.config.ArtifactBlockDevice {
+		return "", errors.New("artifact block devices are not enabled")
+	}
+
+	attachedPath := getArtifactImagePath(vm.vmmID)
+	err := os.Rename(imagePath, attachedPath)
+	if err != nil {
+		return "", err
+	}
+
+	err = vm.machine.UpdateGuestDrive(vm.vmmCtx, artifactDriveID, attachedPath)
+	if err != nil {
+		_ = os.Remove(attachedPath)
+		return "", err
+	}
+
+	return artifactGuestDevice, nil
+}
+
+// Sends the guest a shutdown signal (ctrl+alt+del) and waits up to the given grace period
+// for the VMM to exit, returning true if it exited within the grace period
+func (vm *runningFirecracker) shutdownGracefully(gracePeriod time.Duration) bool {
+	if gracePeriod <= 0 || runtime.GOARCH == "arm64" {
+		// a graceful shutdown signal is not available for arm64 guests
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+
+	err := vm.machine.Shutdown(ctx)
+	if err != nil {
+		vm.log.Warn("Failed to send shutdown signal to firecracker VM", slog.String("vmid", vm.vmmID), slog.Any("err", err))
+		return false
+	}
+
+	_ = vm.machine.Wait(ctx)
+	return ctx.Err() == nil
+}
+
+// Create a VMM with a given set of options and start the VM, sized by the node's machine template
+// unless overridden by the given resources
+func createAndStartVM(ctx context.Context, config *nexmodels.NodeConfiguration, resources *controlapi.WorkloadResources, log *slog.Logger) (*runningFirecracker, error) {
 	vmmID := xid.New().String()
 
-	fcCfg, err := generateFirecrackerConfig(vmmID, config)
+	fcCfg, err := generateFirecrackerConfig(vmmID, config, resources)
 	if err != nil {
 		log.Error("Failed to generate firecracker configuration", slog.Any("config", config))
 		return nil, err
@@ -105,30 +190,24 @@ func createAndStartVM(ctx context.Context, config *nexmodels.NodeConfiguration,
 		return nil, err
 	}
 
+	if config.ArtifactBlockDevice {
+		err = createArtifactPlaceholder(getArtifactPlaceholderPath(vmmID))
+		if err != nil {
+			log.Error("Failed to create artifact placeholder image", slog.Any("err", err))
+			return nil, err
+		}
+	}
+
 	// TODO: can we please not use logrus here amazon?
 	machineOpts := []firecracker.Opt{
 		firecracker.WithLogger(log.With(slog.Bool("firecracker", true), slog.String("vmmid", vmmID))),
 	}
 
-	firecrackerBinary, err := exec.LookPath("firecracker")
+	firecrackerBinary, err := resolveFirecrackerBinary()
 	if err != nil {
 		return nil, err
 	}
 
-	finfo, err := os.Stat(firecrackerBinary)
-	if os.IsNotExist(err) {
-		return nil, fmt.Errorf("binary %q does not exist: %v", firecrackerBinary, err)
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to stat binary, %q: %v", firecrackerBinary, err)
-	}
-
-	if finfo.IsDir() {
-		return nil, fmt.Errorf("binary, %q, is a directory", firecrackerBinary)
-	} else if finfo.Mode()&0111 == 0 {
-		return nil, fmt.Errorf("binary, %q, is not executable. Check permissions of binary", firecrackerBinary)
-	}
-
 	if fcCfg.JailerCfg == nil {
 		cmd := firecracker.VMCommandBuilder{}.
 			WithBin(firecrackerBinary).
@@ -147,6 +226,14 @@ func createAndStartVM(ctx context.Context, config *nexmodels.NodeConfiguration,
 		return nil, fmt.Errorf("failed creating machine: %s", err)
 	}
 
+	if config.EntropyDevice {
+		m.Handlers.FcInit = m.Handlers.FcInit.Append(entropyDeviceHandler)
+	}
+
+	if config.CNIUsesIPv6() {
+		m.Handlers.FcInit = m.Handlers.FcInit.AppendAfter(firecracker.SetupKernelArgsHandlerName, ipv6AddressHandler)
+	}
+
 	if err := m.Start(vmmCtx); err != nil {
 		vmmCancel()
 		return nil, fmt.Errorf("failed to start machine: %v", err)
@@ -169,6 +256,7 @@ func createAndStartVM(ctx context.Context, config *nexmodels.NodeConfiguration,
 
 	return &runningFirecracker{
 		config:         config,
+		hostTap:        hosttap,
 		ip:             ip,
 		log:            log,
 		machine:        m,
@@ -188,30 +276,124 @@ func copy(src string, dst string) error {
 	return err
 }
 
-func generateFirecrackerConfig(id string, config *nexmodels.NodeConfiguration) (firecracker.Config, error) {
+// Attaches a virtio-rng device, backed by the host's entropy, to the VM before it boots. The
+// SDK does not expose the entropy device API, so the request is made directly over the API socket
+var entropyDeviceHandler = firecracker.Handler{
+	Name: "fcinit.AttachEntropyDevice",
+	Fn: func(ctx context.Context, m *firecracker.Machine) error {
+		client := &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", m.Cfg.SocketPath)
+				},
+			},
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, "http://localhost/entropy", strings.NewReader("{}"))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to attach entropy device: %s", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusNoContent {
+			body, _ := io.ReadAll(resp.Body)
+			return fmt.Errorf("failed to attach entropy device: %s: %s", resp.Status, string(body))
+		}
+
+		return nil
+	},
+}
+
+// Passes a machine on an IPv6 network the address and gateway assigned to it by CNI in boot args
+// read by the agent, which configures its interface, in place of the ip= boot arg set by the SDK,
+// which the kernel only supports for IPv4
+var ipv6AddressHandler = firecracker.Handler{
+	Name: "fcinit.SetIPv6Address",
+	Fn: func(_ context.Context, m *firecracker.Machine) error {
+		staticConfig := m.Cfg.NetworkInterfaces[0].StaticConfiguration
+		if staticConfig == nil || staticConfig.IPConfiguration == nil {
+			return errors.New("no IPv6 address was assigned to the machine by CNI")
+		}
+
+		m.Cfg.KernelArgs = ipv6KernelArgs(m.Cfg.KernelArgs, staticConfig.IPConfiguration.IPAddr, staticConfig.IPConfiguration.Gateway)
+		return nil
+	},
+}
+
+// Returns the given kernel args with the ip= boot arg replaced by the boot args passing the given
+// IPv6 address and gateway to the agent
+func ipv6KernelArgs(kernelArgs string, address net.IPNet, gateway net.IP) string {
+	args := make([]string, 0)
+	for _, arg := range strings.Fields(kernelArgs) {
+		if !strings.HasPrefix(arg, "ip=") {
+			args = append(args, arg)
+		}
+	}
+
+	args = append(args,
+		fmt.Sprintf("%s=%s", agentapi.BootArgIPv6Address, address.String()),
+		fmt.Sprintf("%s=%s", agentapi.BootArgIPv6Gateway, gateway.String()),
+	)
+
+	return strings.Join(args, " ")
+}
+
+// Creates a sparse, empty image to back the artifact drive; firecracker requires every drive
+// to be backed by a file at boot
+func createArtifactPlaceholder(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return f.Truncate(artifactPlaceholderSize)
+}
+
+func generateFirecrackerConfig(id string, config *nexmodels.NodeConfiguration, resources *controlapi.WorkloadResources) (firecracker.Config, error) {
 	socket := getSocketPath(id)
+	vcpus, memSizeMib := machineDimensions(config.MachineTemplate, resources)
 	rootPath := getRootFsPath(id)
 
+	drives := []models.Drive{{
+		DriveID:      firecracker.String("1"),
+		PathOnHost:   &rootPath,
+		IsRootDevice: firecracker.Bool(true),
+		IsReadOnly:   firecracker.Bool(false),
+		// RateLimiter: firecracker.NewRateLimiter(
+		// 	// bytes/s
+		// 	models.TokenBucket{
+		// 		OneTimeBurst: firecracker.Int64(1024 * 1024), // 1 MiB/s
+		// 		RefillTime:   firecracker.Int64(500),         // 0.5s
+		// 		Size:         firecracker.Int64(1024 * 1024),
+		// 	},
+		// 	// ops/s
+		// 	models.TokenBucket{
+		// 		OneTimeBurst: firecracker.Int64(100),  // 100 iops
+		// 		RefillTime:   firecracker.Int64(1000), // 1s
+		// 		Size:         firecracker.Int64(100),
+		// 	}),
+	}}
+
+	if config.ArtifactBlockDevice {
+		artifactPath := getArtifactPlaceholderPath(id)
+		drives = append(drives, models.Drive{
+			DriveID:      firecracker.String(artifactDriveID),
+			PathOnHost:   &artifactPath,
+			IsRootDevice: firecracker.Bool(false),
+			IsReadOnly:   firecracker.Bool(true),
+		})
+	}
+
 	return firecracker.Config{
-		Drives: []models.Drive{{
-			DriveID:      firecracker.String("1"),
-			PathOnHost:   &rootPath,
-			IsRootDevice: firecracker.Bool(true),
-			IsReadOnly:   firecracker.Bool(false),
-			// RateLimiter: firecracker.NewRateLimiter(
-			// 	// bytes/s
-			// 	models.TokenBucket{
-			// 		OneTimeBurst: firecracker.Int64(1024 * 1024), // 1 MiB/s
-			// 		RefillTime:   firecracker.Int64(500),         // 0.5s
-			// 		Size:         firecracker.Int64(1024 * 1024),
-			// 	},
-			// 	// ops/s
-			// 	models.TokenBucket{
-			// 		OneTimeBurst: firecracker.Int64(100),  // 100 iops
-			// 		RefillTime:   firecracker.Int64(1000), // 1s
-			// 		Size:         firecracker.Int64(100),
-			// 	}),
-		}},
+		Drives:          drives,
 		ForwardSignals:  make([]os.Signal, 0),
 		KernelImagePath: config.KernelFilepath,
 		LogPath:         fmt.Sprintf("%s.log", socket),
@@ -227,8 +409,8 @@ func generateFirecrackerConfig(id string, config *nexmodels.NodeConfiguration) (
 			//InRateLimiter: firecracker.NewRateLimiter(..., ...),
 		}},
 		MachineCfg: models.MachineConfiguration{
-			VcpuCount:  firecracker.Int64(int64(*config.MachineTemplate.VcpuCount)),
-			MemSizeMib: firecracker.Int64(int64(*config.MachineTemplate.MemSizeMib)),
+			VcpuCount:  firecracker.Int64(vcpus),
+			MemSizeMib: firecracker.Int64(memSizeMib),
 		},
 		MmdsVersion: firecracker.MMDSv2,
 		SocketPath:  socket,
@@ -255,6 +437,20 @@ func getRootFsPath(vmmID string) string {
 	return filepath.Join(dir, filename)
 }
 
+func getArtifactImagePath(vmmID string) string {
+	filename := fmt.Sprintf("artifact-%s.ext4", vmmID)
+	dir := os.TempDir()
+
+	return filepath.Join(dir, filename)
+}
+
+func getArtifactPlaceholderPath(vmmID string) string {
+	filename := fmt.Sprintf("artifact-placeholder-%s.ext4", vmmID)
+	dir := os.TempDir()
+
+	return filepath.Join(dir, filename)
+}
+
 func getSocketPath(vmmID string) string {
 	filename := strings.Join([]string{
 		".firecracker.sock",
@@ -2,6 +2,7 @@ package processmanager
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"log/slog"
 	"os"
@@ -14,6 +15,7 @@ import (
 	"github.com/rs/xid"
 	agentapi "github.com/synadia-io/nex/internal/agent-api"
 	"github.com/synadia-io/nex/internal/models"
+	"github.com/synadia-io/nex/internal/node/natsint"
 	"github.com/synadia-io/nex/internal/node/observability"
 )
 
@@ -24,17 +26,27 @@ const (
 // A process manager that controls the creation and deletion of `nex-agent` processes, directly
 // spawned as children of the nex node
 type SpawningProcessManager struct {
-	closing     uint32
-	config      *models.NodeConfiguration
-	ctx         context.Context
-	stopMutexes map[string]*sync.Mutex
-	t           *observability.Telemetry
+	closing uint32
+	config  *models.NodeConfiguration
+	ctx     context.Context
+	t       *observability.Telemetry
+
+	natsintAuth *natsint.Authenticator
+
+	// Guards the processes and deploy requests below, which the spawn loop updates as the
+	// workload manager reads them
+	procsMutex     sync.Mutex
+	liveProcs      map[string]*spawnedProcess
+	stopMutexes    map[string]*sync.Mutex
+	deployRequests map[string]*agentapi.DeployRequest
 
-	liveProcs map[string]*spawnedProcess
-	warmProcs chan *spawnedProcess
+	poolTarget int32
+	backoff    *poolCreateBackoff
+	fillLog    *poolFillLog
+	pacer      *poolCreatePacer
+	warmProcs  chan *spawnedProcess
 
-	delegate       ProcessDelegate
-	deployRequests map[string]*agentapi.DeployRequest
+	delegate ProcessDelegate
 
 	log *slog.Logger
 }
@@ -42,6 +54,7 @@ type SpawningProcessManager struct {
 type spawnedProcess struct {
 	cmd             *exec.Cmd
 	deployRequest   *agentapi.DeployRequest
+	environmentKey  []byte
 	workloadStarted time.Time
 
 	ID string
@@ -57,25 +70,37 @@ func NewSpawningProcessManager(
 	log *slog.Logger,
 	config *models.NodeConfiguration,
 	telemetry *observability.Telemetry,
+	natsintAuth *natsint.Authenticator,
 	ctx context.Context,
 ) (*SpawningProcessManager, error) {
+	_, poolMax := config.ResolveMachinePoolBounds()
+
 	return &SpawningProcessManager{
-		config: config,
-		t:      telemetry,
-		log:    log,
-		ctx:    ctx,
+		config:      config,
+		t:           telemetry,
+		log:         log,
+		ctx:         ctx,
+		natsintAuth: natsintAuth,
+		poolTarget:  int32(config.MachinePoolSize),
+		backoff:     newPoolCreateBackoff(log, config),
+		fillLog:     newPoolFillLog(log, config.PoolFillLogIntervalMillisecond),
+		pacer:       newPoolCreatePacer(config.PoolCreateIntervalMillisecond),
 
 		stopMutexes: make(map[string]*sync.Mutex),
 
 		deployRequests: make(map[string]*agentapi.DeployRequest),
 		liveProcs:      make(map[string]*spawnedProcess),
-		warmProcs:      make(chan *spawnedProcess, config.MachinePoolSize),
+		warmProcs:      make(chan *spawnedProcess, poolMax),
 	}, nil
 }
 
 // Returns the list of processes that have been associated with a workload via deploy request
 func (s *SpawningProcessManager) ListProcesses() ([]ProcessInfo, error) {
+	s.procsMutex.Lock()
+	defer s.procsMutex.Unlock()
+
 	pinfos := make([]ProcessInfo, 0)
+	now := time.Now().UTC()
 
 	for workloadID, proc := range s.liveProcs {
 		// Ignore pending "unprepared" processes that don't have workloads on them yet
@@ -85,6 +110,9 @@ func (s *SpawningProcessManager) ListProcesses() ([]ProcessInfo, error) {
 				Name:          *proc.deployRequest.WorkloadName,
 				Namespace:     *proc.deployRequest.Namespace,
 				DeployRequest: proc.deployRequest,
+				ExitCode:      proc.deployRequest.ExitCode,
+				StartedAt:     proc.workloadStarted,
+				Uptime:        now.Sub(proc.workloadStarted),
 			}
 			pinfos = append(pinfos, pinfo)
 		}
@@ -94,6 +122,9 @@ func (s *SpawningProcessManager) ListProcesses() ([]ProcessInfo, error) {
 }
 
 func (s *SpawningProcessManager) EnterLameDuck() error {
+	s.procsMutex.Lock()
+	defer s.procsMutex.Unlock()
+
 	nope := false
 	for _, req := range s.deployRequests {
 		req.Essential = &nope
@@ -104,19 +135,78 @@ func (s *SpawningProcessManager) EnterLameDuck() error {
 
 // Attaches a deployment request to a running process. Until a process is prepared, it's just an empty agent
 func (s *SpawningProcessManager) PrepareWorkload(workloadID string, deployRequest *agentapi.DeployRequest) error {
-	select {
-	case proc := <-s.warmProcs:
-		if proc == nil {
-			return fmt.Errorf("could not prepare workload, no agent process")
-		}
-		proc.deployRequest = deployRequest
-		proc.workloadStarted = time.Now().UTC()
+	s.procsMutex.Lock()
+	proc, exists := s.liveProcs[workloadID]
+	available := exists && proc.deployRequest == nil
+	s.procsMutex.Unlock()
+	if !available {
+		return fmt.Errorf("could not prepare workload, no available agent process with id %s", workloadID)
+	}
+
+	// the warm pool is not held while waiting for a process from it
+	p, _, err := takeWarm(s.ctx, s.t, s.delegate, s.warmProcs, 500*time.Millisecond)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return fmt.Errorf("could not prepare workload, no agent process")
+	}
+
+	s.procsMutex.Lock()
+	defer s.procsMutex.Unlock()
+
+	if proc.deployRequest != nil {
+		return fmt.Errorf("could not prepare workload, agent process %s was claimed by another workload", workloadID)
+	}
+	proc.deployRequest = deployRequest
+	proc.workloadStarted = time.Now().UTC()
+
+	s.deployRequests[proc.ID] = deployRequest
+
+	return nil
+}
+
+// Returns the key with which the environments of workloads deployed to the agent process with the
+// given id are sealed
+func (s *SpawningProcessManager) EnvironmentKey(id string) ([]byte, bool) {
+	s.procsMutex.Lock()
+	defer s.procsMutex.Unlock()
+
+	proc, ok := s.liveProcs[id]
+	if !ok || proc.environmentKey == nil {
+		return nil, false
+	}
+
+	return proc.environmentKey, true
+}
+
+// Returns the number of idle agent processes kept in the warm pool
+func (s *SpawningProcessManager) GetPoolTarget() int {
+	return int(atomic.LoadInt32(&s.poolTarget))
+}
+
+// Sets the number of idle agent processes kept in the warm pool
+func (s *SpawningProcessManager) SetPoolTarget(target int) error {
+	err := validatePoolTarget(s.config, target)
+	if err != nil {
+		return err
+	}
+
+	atomic.StoreInt32(&s.poolTarget, int32(target))
+	return nil
+}
+
+func (s *SpawningProcessManager) GetPoolCreateInterval() time.Duration {
+	return s.pacer.getInterval()
+}
 
-		s.deployRequests[proc.ID] = deployRequest
-	case <-time.After(500 * time.Millisecond):
-		return fmt.Errorf("timed out waiting for available agent process")
+func (s *SpawningProcessManager) SetPoolCreateInterval(interval time.Duration) error {
+	err := validatePoolCreateInterval(interval)
+	if err != nil {
+		return err
 	}
 
+	s.pacer.setInterval(interval)
 	return nil
 }
 
@@ -125,7 +215,14 @@ func (s *SpawningProcessManager) Stop() error {
 	if atomic.AddUint32(&s.closing, 1) == 1 {
 		s.log.Info("Spawning process manager stopping")
 
+		s.procsMutex.Lock()
+		workloadIDs := make([]string, 0, len(s.liveProcs))
 		for workloadID := range s.liveProcs {
+			workloadIDs = append(workloadIDs, workloadID)
+		}
+		s.procsMutex.Unlock()
+
+		for _, workloadID := range workloadIDs {
 			err := s.StopProcess(workloadID)
 			if err != nil {
 				s.log.Warn("Failed to stop spawned agent process",
@@ -144,29 +241,42 @@ func (s *SpawningProcessManager) Start(delegate ProcessDelegate) error {
 	s.delegate = delegate
 	s.log.Info("Spawning (no sandbox) process manager starting")
 
+	go s.fillLog.run(s.ctx, func() int { return len(s.warmProcs) }, s.GetPoolTarget)
+
 	for !s.stopping() {
 		select {
 		case <-s.ctx.Done():
 			return nil
 		default:
-			if len(s.warmProcs) == s.config.MachinePoolSize {
+			if len(s.warmProcs) >= s.GetPoolTarget() {
 				time.Sleep(runloopSleepInterval)
 				continue
 			}
 
+			s.pacer.wait(s.ctx)
+			if s.stopping() {
+				return nil
+			}
+
 			p, err := s.spawn()
 			if err != nil {
-				s.log.Error("Failed to spawn nex-agent for pool", slog.Any("error", err))
-				time.Sleep(runloopSleepInterval)
+				s.fillLog.recordFailed()
+				err = s.backoff.failed(s.ctx, fmt.Errorf("failed to spawn nex-agent for pool: %w", err))
+				if err != nil {
+					return err
+				}
 				continue
 			}
+			s.backoff.succeeded()
 
+			s.procsMutex.Lock()
 			s.liveProcs[p.ID] = p
 			s.stopMutexes[p.ID] = &sync.Mutex{}
+			s.procsMutex.Unlock()
 
 			go s.delegate.OnProcessStarted(p.ID)
 
-			s.log.Info("Adding new agent process to warm pool",
+			s.fillLog.recordAdded("Adding new agent process to warm pool",
 				slog.String("workload_id", p.ID))
 
 			s.warmProcs <- p // If the pool is full, this line will block until a slot is available.
@@ -176,16 +286,50 @@ func (s *SpawningProcessManager) Start(delegate ProcessDelegate) error {
 	return nil
 }
 
+// Stops the agent process running the workload with the given id and hands its deploy request
+// to the delegate, which redeploys it to a fresh agent
+func (s *SpawningProcessManager) Restart(workloadID string) error {
+	s.procsMutex.Lock()
+	request, ok := s.deployRequests[workloadID]
+	if ok && request.OriginalWorkloadID == nil {
+		request.OriginalWorkloadID = &workloadID
+	}
+	s.procsMutex.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoDeployRequest, workloadID)
+	}
+
+	err := s.StopProcess(workloadID)
+	if err != nil {
+		return err
+	}
+
+	go s.delegate.OnProcessRestarted(workloadID, request)
+	return nil
+}
+
 // Stops a single agent process
 func (s *SpawningProcessManager) StopProcess(workloadID string) error {
+	s.procsMutex.Lock()
 	proc, exists := s.liveProcs[workloadID]
 	if !exists {
+		s.procsMutex.Unlock()
 		return fmt.Errorf("failed to stop process %s. No such process", workloadID)
 	}
 
 	delete(s.deployRequests, workloadID)
-
+	claimed := proc.deployRequest != nil
 	mutex := s.stopMutexes[workloadID]
+	s.procsMutex.Unlock()
+
+	if !claimed {
+		// an unclaimed process still occupies a slot in the warm pool
+		select {
+		case <-s.warmProcs:
+		default:
+		}
+	}
+
 	mutex.Lock()
 	defer mutex.Unlock()
 
@@ -196,15 +340,21 @@ func (s *SpawningProcessManager) StopProcess(workloadID string) error {
 		return err
 	}
 
+	s.procsMutex.Lock()
 	delete(s.liveProcs, workloadID)
 	delete(s.stopMutexes, workloadID)
+	s.procsMutex.Unlock()
 
+	s.natsintAuth.RevokeCredentials(workloadID)
 	return nil
 }
 
 // Looks up an agent process. A non-existent agent process returns (nil, nil), not
 // an error
 func (s *SpawningProcessManager) Lookup(workloadID string) (*agentapi.DeployRequest, error) {
+	s.procsMutex.Lock()
+	defer s.procsMutex.Unlock()
+
 	if request, ok := s.deployRequests[workloadID]; ok {
 		return request, nil
 	}
@@ -223,33 +373,62 @@ func (s *SpawningProcessManager) spawn() (*spawnedProcess, error) {
 	id := xid.New()
 	workloadID := id.String()
 
+	environmentKey, err := agentapi.NewEnvironmentKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate environment key: %s", err)
+	}
+
+	logRateLimit, logBurst := s.config.ResolveAgentLogRateLimit()
+
+	creds, err := s.natsintAuth.CreateCredentials(workloadID)
+	if err != nil {
+		return nil, err
+	}
+
 	cmd := exec.Command(nexAgentBinary)
 	cmd.Env = append(os.Environ(),
 		"NEX_SANDBOX=false",
 		fmt.Sprintf("NEX_WORKLOADID=%s", workloadID),
 		// can't use the CNI host because we don't use it in no-sandbox mode
-		"NEX_NODE_NATS_HOST=0.0.0.0",
+		fmt.Sprintf("NEX_NODE_NATS_HOST=%s", s.config.ResolveInternalNodeBindHost()),
 		fmt.Sprintf("NEX_NODE_NATS_PORT=%d", *s.config.InternalNodePort),
+		fmt.Sprintf("NEX_NODE_NATS_TOKEN=%s", creds.AgentToken),
+		fmt.Sprintf("NEX_WORKLOAD_NATS_TOKEN=%s", creds.WorkloadToken),
+		fmt.Sprintf("NEX_PLUGIN_PATH=%s", s.config.AgentPluginPath),
+		fmt.Sprintf("NEX_TRACES_ENABLED=%t", s.config.OtelTraces),
+		fmt.Sprintf("NEX_HEARTBEAT_INTERVAL_MS=%d", s.config.ResolveAgentHeartbeatInterval().Milliseconds()),
+		fmt.Sprintf("NEX_STOP_GRACE_PERIOD_MS=%d", s.config.StopGracePeriodMillisecond),
+		fmt.Sprintf("NEX_ENVIRONMENT_KEY=%s", base64.StdEncoding.EncodeToString(environmentKey)),
+		fmt.Sprintf("NEX_REQUIRE_SEALED_ENVIRONMENT=%t", s.config.RequireEnvironmentEncryption),
+		fmt.Sprintf("NEX_LOG_RATE_LIMIT=%d", logRateLimit),
+		fmt.Sprintf("NEX_LOG_BURST=%d", logBurst),
 	)
 
+	if key := s.config.ResolveAgentUpdatePublicKey(); key != nil {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("NEX_AGENT_UPDATE_PUBLIC_KEY=%s", *key))
+	}
+
 	cmd.Stderr = &procLogEmitter{workloadID: workloadID, log: s.log.WithGroup(workloadID), stderr: true}
 	cmd.Stdout = &procLogEmitter{workloadID: workloadID, log: s.log.WithGroup(workloadID), stderr: false}
 	cmd.SysProcAttr = s.sysProcAttr()
 
 	newProc := &spawnedProcess{
-		ID:   workloadID,
-		cmd:  cmd,
-		log:  s.log,
-		Fail: make(chan bool),
-		Run:  make(chan bool),
-		Exit: make(chan int),
+		ID:             workloadID,
+		cmd:            cmd,
+		environmentKey: environmentKey,
+		log:            s.log,
+		Fail:           make(chan bool),
+		Run:            make(chan bool),
+		Exit:           make(chan int),
 	}
 
-	err := cmd.Start()
+	err = cmd.Start()
 	if err != nil {
+		s.natsintAuth.RevokeCredentials(workloadID)
 		s.log.Warn("Agent command failed to start", slog.Any("error", err))
 		return nil, err
 	} else if cmd.Process == nil {
+		s.natsintAuth.RevokeCredentials(workloadID)
 		s.log.Warn("Agent command failed to start")
 		return nil, fmt.Errorf("agent command failed to start")
 	}
@@ -0,0 +1,233 @@
+//go:build linux
+
+package processmanager
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/models"
+	"github.com/synadia-io/nex/internal/node/natsint"
+)
+
+type startedProcessDelegate struct {
+	started   chan string
+	restarted chan *agentapi.DeployRequest
+}
+
+func (d *startedProcessDelegate) OnProcessStarted(id string) {
+	d.started <- id
+}
+
+func (d *startedProcessDelegate) OnPoolRefillChanged(bool) {}
+
+func (d *startedProcessDelegate) OnPoolStarved() {}
+
+func (d *startedProcessDelegate) OnProcessRestarted(_ string, request *agentapi.DeployRequest) {
+	d.restarted <- request
+}
+
+func (d *startedProcessDelegate) PingProcess(string, time.Duration) (*agentapi.AgentPingResponse, time.Duration, error) {
+	return &agentapi.AgentPingResponse{}, 0, nil
+}
+
+func newTestAuthenticator(t *testing.T) *natsint.Authenticator {
+	auth, err := natsint.NewAuthenticator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return auth
+}
+
+func TestSpawningProcessManagerPoolOfOne(t *testing.T) {
+	// stand in for the agent with a process which idles until it is stopped
+	bin := t.TempDir()
+	err := os.WriteFile(filepath.Join(bin, nexAgentBinary), []byte("#!/bin/sh\nexec sleep 60\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	config := models.DefaultNodeConfiguration()
+	config.MachinePoolSize = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	s, err := NewSpawningProcessManager(log, &config, nil, newTestAuthenticator(t), ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Stop() }()
+
+	if cap(s.warmProcs) != 1 {
+		t.Fatalf("expected warm pool capacity of 1, got %d", cap(s.warmProcs))
+	}
+
+	delegate := &startedProcessDelegate{started: make(chan string, 2)}
+	go func() { _ = s.Start(delegate) }()
+
+	awaitStarted := func() string {
+		select {
+		case id := <-delegate.started:
+			return id
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the warm pool to fill")
+			return ""
+		}
+	}
+
+	id := awaitStarted()
+
+	namespace := "default"
+	workloadName := "echo"
+	err = s.PrepareWorkload(id, &agentapi.DeployRequest{Namespace: &namespace, WorkloadName: &workloadName})
+	if err != nil {
+		t.Fatalf("expected the warm agent process to be served: %s", err)
+	}
+
+	// claiming the only warm process frees its slot, so the pool is replenished
+	if replacement := awaitStarted(); replacement == id {
+		t.Fatal("expected a new agent process to replenish the pool")
+	}
+}
+
+func TestSpawningProcessManagerRestart(t *testing.T) {
+	bin := t.TempDir()
+	err := os.WriteFile(filepath.Join(bin, nexAgentBinary), []byte("#!/bin/sh\nexec sleep 60\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	config := models.DefaultNodeConfiguration()
+	config.MachinePoolSize = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	s, err := NewSpawningProcessManager(log, &config, nil, newTestAuthenticator(t), ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Stop() }()
+
+	delegate := &startedProcessDelegate{
+		started:   make(chan string, 2),
+		restarted: make(chan *agentapi.DeployRequest, 1),
+	}
+	go func() { _ = s.Start(delegate) }()
+
+	var id string
+	select {
+	case id = <-delegate.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the warm pool to fill")
+	}
+
+	err = s.Restart(id)
+	if !errors.Is(err, ErrNoDeployRequest) {
+		t.Fatalf("expected restart of an agent process without a workload to fail, got %v", err)
+	}
+
+	namespace := "default"
+	workloadName := "echo"
+	err = s.PrepareWorkload(id, &agentapi.DeployRequest{Namespace: &namespace, WorkloadName: &workloadName})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Restart(id)
+	if err != nil {
+		t.Fatalf("expected workload to be restarted: %s", err)
+	}
+
+	select {
+	case request := <-delegate.restarted:
+		if request.TelemetryWorkloadID("other") != id {
+			t.Fatalf("expected restarted request to preserve the original workload id %s", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the restarted deploy request")
+	}
+
+	s.procsMutex.Lock()
+	_, exists := s.liveProcs[id]
+	s.procsMutex.Unlock()
+	if exists {
+		t.Fatal("expected the agent process running the workload to be stopped")
+	}
+}
+
+func TestSpawningProcessManagerListsWorkloadUptime(t *testing.T) {
+	bin := t.TempDir()
+	err := os.WriteFile(filepath.Join(bin, nexAgentBinary), []byte("#!/bin/sh\nexec sleep 60\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	config := models.DefaultNodeConfiguration()
+	config.MachinePoolSize = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	s, err := NewSpawningProcessManager(log, &config, nil, newTestAuthenticator(t), ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Stop() }()
+
+	delegate := &startedProcessDelegate{started: make(chan string, 2)}
+	go func() { _ = s.Start(delegate) }()
+
+	var id string
+	select {
+	case id = <-delegate.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the warm pool to fill")
+	}
+
+	procs, err := s.ListProcesses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(procs) != 0 {
+		t.Fatalf("expected agent processes without a workload not to be listed, got %d", len(procs))
+	}
+
+	before := time.Now().UTC()
+	namespace := "default"
+	workloadName := "echo"
+	err = s.PrepareWorkload(id, &agentapi.DeployRequest{Namespace: &namespace, WorkloadName: &workloadName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	procs, err = s.ListProcesses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(procs) != 1 || procs[0].ID != id {
+		t.Fatalf("expected the workload's agent process to be listed, got %v", procs)
+	}
+
+	if procs[0].StartedAt.Before(before) {
+		t.Fatalf("expected workload to have started after %s, got %s", before, procs[0].StartedAt)
+	}
+	if procs[0].Uptime < 10*time.Millisecond {
+		t.Fatalf("expected uptime of at least 10ms, got %s", procs[0].Uptime)
+	}
+}
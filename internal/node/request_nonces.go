@@ -0,0 +1,41 @@
+package nexnode
+
+import (
+	"sync"
+	"time"
+)
+
+// Nonces of the signed deploy requests accepted by the node, each tracked until its request expires
+// so that a captured request cannot be replayed while it remains valid
+type requestNonces struct {
+	mutex *sync.Mutex
+	seen  map[string]time.Time
+}
+
+func newRequestNonces() *requestNonces {
+	return &requestNonces{
+		mutex: &sync.Mutex{},
+		seen:  make(map[string]time.Time),
+	}
+}
+
+// Records the nonce of a signed request expiring at the given time, returning false if the nonce
+// has already been seen. Nonces of expired requests are pruned
+func (n *requestNonces) claim(nonce string, expiresAt time.Time) bool {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	now := time.Now().UTC()
+	for seen, expiry := range n.seen {
+		if now.After(expiry) {
+			delete(n.seen, seen)
+		}
+	}
+
+	if _, ok := n.seen[nonce]; ok {
+		return false
+	}
+
+	n.seen[nonce] = expiresAt
+	return true
+}
@@ -0,0 +1,51 @@
+package nexnode
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+// Time to live of the deploy requests this node signs when redeploying or migrating workloads
+const nodeRequestSignatureTTL = 30 * time.Second
+
+// Verifies the signature of the given deploy request, if any: the signer must be a valid issuer or
+// this node, the request must not have expired nor be valid for longer than the node allows, and its
+// nonce must not have been seen before. Unsigned requests are rejected when the node requires signed
+// requests
+func (api *ApiListener) verifyRequestSignature(request *controlapi.DeployRequest) error {
+	if request.RequestJwt == nil {
+		if api.node.config.RequireSignedRequests {
+			return errors.New("node requires signed deploy requests")
+		}
+
+		return nil
+	}
+
+	claims, err := request.VerifySignature()
+	if err != nil {
+		return err
+	}
+
+	maxTTL := api.node.config.ResolveSignedRequestMaxTTL()
+	if claims.ExpiresAt.After(time.Now().UTC().Add(maxTTL)) {
+		return fmt.Errorf("deploy request signature expires later than the maximum of %s allowed by this node", maxTTL)
+	}
+
+	if claims.Issuer != api.node.publicKey && !validateIssuer(claims.Issuer, api.node.config.ValidIssuers) {
+		return fmt.Errorf("untrusted deploy request signer: %s", claims.Issuer)
+	}
+
+	if !api.nonces.claim(claims.Nonce, claims.ExpiresAt) {
+		return errors.New("deploy request has already been received")
+	}
+
+	return nil
+}
+
+// Signs the given deploy request, which this node submits on behalf of a workload's original requester
+func (w *WorkloadManager) signRequest(request *controlapi.DeployRequest) error {
+	return request.Sign(w.kp, min(nodeRequestSignatureTTL, w.config.ResolveSignedRequestMaxTTL()))
+}
@@ -0,0 +1,141 @@
+package nexnode
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nkeys"
+	controlapi "github.com/synadia-io/nex/control-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+func signedTestRequest(t *testing.T, kp nkeys.KeyPair, ttl time.Duration) *controlapi.DeployRequest {
+	location, _ := url.Parse("nats://myobjectstore/echofunction")
+	workloadType := "v8"
+	request := &controlapi.DeployRequest{
+		Location:        location,
+		WorkloadType:    &workloadType,
+		TriggerSubjects: []string{"hello.world"},
+		Tags:            map[string]string{"team": "edge"},
+	}
+
+	err := request.Sign(kp, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// requests are verified as received on the wire
+	raw, _ := json.Marshal(request)
+	var received controlapi.DeployRequest
+	err = json.Unmarshal(raw, &received)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &received
+}
+
+func TestDeployRequestSignature(t *testing.T) {
+	kp, _ := nkeys.CreateAccount()
+	pub, _ := kp.PublicKey()
+
+	request := signedTestRequest(t, kp, time.Minute)
+	claims, err := request.VerifySignature()
+	if err != nil {
+		t.Fatalf("expected signature to verify: %s", err)
+	}
+
+	if claims.Issuer != pub || claims.Nonce == "" {
+		t.Fatalf("unexpected signature claims: %+v", claims)
+	}
+
+	request.TriggerSubjects = append(request.TriggerSubjects, "evil.subject")
+	_, err = request.VerifySignature()
+	if err == nil {
+		t.Fatal("expected signature of modified request to fail verification")
+	}
+}
+
+func TestVerifyRequestSignature(t *testing.T) {
+	issuer, _ := nkeys.CreateAccount()
+	issuerPub, _ := issuer.PublicKey()
+	other, _ := nkeys.CreateAccount()
+
+	api := &ApiListener{
+		node: &Node{
+			config: &models.NodeConfiguration{
+				RequireSignedRequests: true,
+				ValidIssuers:          []string{issuerPub},
+			},
+			publicKey: "NODE",
+		},
+		nonces: newRequestNonces(),
+	}
+
+	err := api.verifyRequestSignature(&controlapi.DeployRequest{})
+	if err == nil {
+		t.Fatal("expected unsigned request to be rejected in strict mode")
+	}
+
+	request := signedTestRequest(t, issuer, time.Minute)
+	err = api.verifyRequestSignature(request)
+	if err != nil {
+		t.Fatalf("expected signed request to be accepted: %s", err)
+	}
+
+	err = api.verifyRequestSignature(request)
+	if err == nil {
+		t.Fatal("expected replayed request to be rejected")
+	}
+
+	err = api.verifyRequestSignature(signedTestRequest(t, other, time.Minute))
+	if err == nil {
+		t.Fatal("expected request signed by an untrusted key to be rejected")
+	}
+
+	err = api.verifyRequestSignature(signedTestRequest(t, issuer, time.Hour))
+	if err == nil {
+		t.Fatal("expected request valid for longer than the maximum ttl to be rejected")
+	}
+}
+
+// Returns the given signed request re-signed with its claims modified by the given function
+func resignedTestRequest(t *testing.T, kp nkeys.KeyPair, request *controlapi.DeployRequest, modify func(*jwt.GenericClaims)) *controlapi.DeployRequest {
+	claims, err := jwt.DecodeGeneric(*request.RequestJwt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modify(claims)
+
+	token, err := claims.Encode(kp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request.RequestJwt = &token
+	return request
+}
+
+func TestDeployRequestSignatureValidationErrors(t *testing.T) {
+	kp, _ := nkeys.CreateAccount()
+
+	request := resignedTestRequest(t, kp, signedTestRequest(t, kp, time.Minute), func(claims *jwt.GenericClaims) {
+		claims.Expires = time.Now().UTC().Add(-time.Minute).Unix()
+	})
+	_, err := request.VerifySignature()
+	if err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("expected expired signature to be reported as expired, got %v", err)
+	}
+
+	request = resignedTestRequest(t, kp, signedTestRequest(t, kp, time.Minute), func(claims *jwt.GenericClaims) {
+		claims.NotBefore = time.Now().UTC().Add(time.Hour).Unix()
+	})
+	_, err = request.VerifySignature()
+	if err == nil || strings.Contains(err.Error(), "expired") || !strings.Contains(err.Error(), "not yet valid") {
+		t.Fatalf("expected signature which is not yet valid to report its validation error, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package nexnode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Time given to the self-test machine to boot, on top of the agent handshake timeout
+const selfTestBootTimeout = time.Minute
+
+// Returned when a node started in safe mode fails its self-test
+var ErrSelfTestFailed = errors.New("node self-test failed")
+
+// Report of the capabilities of the host proven by a node started in safe mode
+type capabilityReport struct {
+	NodeId  string            `json:"node_id"`
+	Version string            `json:"version"`
+	Passed  bool              `json:"passed"`
+	Checks  []capabilityCheck `json:"checks"`
+}
+
+type capabilityCheck struct {
+	Name                string `json:"name"`
+	Passed              bool   `json:"passed"`
+	Error               string `json:"error,omitempty"`
+	DurationMillisecond int64  `json:"duration_ms"`
+}
+
+// Runs the given check, recording its outcome in the report
+func (r *capabilityReport) check(name string, check func() error) bool {
+	start := time.Now()
+	err := check()
+
+	result := capabilityCheck{
+		Name:                name,
+		Passed:              err == nil,
+		DurationMillisecond: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	r.Checks = append(r.Checks, result)
+	r.Passed = r.Passed && result.Passed
+	return result.Passed
+}
+
+// Applies safe mode, if enabled by either the node options or the node configuration. A node in safe
+// mode keeps a single machine in its pool, with which it proves that the host can boot agents
+func (n *Node) applySafeMode() {
+	n.config.SafeMode = n.config.SafeMode || n.nodeOpts.SafeMode
+	if !n.config.SafeMode {
+		return
+	}
+
+	n.config.MachinePoolSize = 1
+	n.config.MachinePoolMin = 1
+	n.config.MachinePoolMax = 1
+	n.config.IdleAgentReapAfterMillisecond = 0
+	n.config.PoolCreateIntervalMillisecond = 0
+	n.config.PrepullArtifacts = nil
+}
+
+// Proves that the host is capable of running workloads once the node has initialized, without
+// accepting deploys: the node must have connected to NATS, started its internal NATS server and
+// object store, and booted an agent which completed its handshake, which is then torn down. Writes
+// the resulting capability report to standard output
+func (n *Node) selfTest(initErr error) *capabilityReport {
+	report := &capabilityReport{
+		NodeId:  n.publicKey,
+		Version: VERSION,
+		Passed:  true,
+		Checks:  make([]capabilityCheck, 0),
+	}
+
+	if report.check("initialization", func() error { return initErr }) {
+		report.check("nats_connection", func() error {
+			if !n.nc.IsConnected() {
+				return fmt.Errorf("not connected to NATS: %s", n.nc.Status())
+			}
+			return nil
+		})
+
+		report.check("internal_nats", func() error {
+			if !n.natsint.ReadyForConnections(time.Second) {
+				return errors.New("internal NATS server is not ready for connections")
+			}
+			return nil
+		})
+
+		report.check("object_store", n.manager.probeObjectStore)
+
+		var agentID string
+		if report.check("agent_handshake", func() (err error) {
+			agentID, err = n.manager.awaitAgentHandshake(n.manager.handshakeTimeout + selfTestBootTimeout)
+			return err
+		}) {
+			report.check("agent_teardown", func() error {
+				return n.manager.teardownAgent(agentID)
+			})
+		}
+	}
+
+	if report.Passed {
+		n.log.Info("Node passed its self-test; the host is capable of running workloads")
+	} else {
+		n.log.Error("Node failed its self-test; the host is not capable of running workloads")
+	}
+
+	raw, _ := json.MarshalIndent(report, "", "  ")
+	_, _ = fmt.Fprintln(os.Stdout, string(raw))
+
+	return report
+}
+
+// Waits up to the given timeout for an agent in the pool to complete its handshake, returning its id
+func (w *WorkloadManager) awaitAgentHandshake(timeout time.Duration) (string, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	ticker := time.NewTicker(runloopSleepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return "", errors.New("agent failed to complete its handshake")
+		case <-deadline.C:
+			return "", fmt.Errorf("no agent completed its handshake within %s", timeout)
+		case <-ticker.C:
+			if id, ok := w.handshakenAgent(); ok {
+				return id, nil
+			}
+		}
+	}
+}
+
+// Returns the id of an agent in the pool which has completed its handshake, if any
+func (w *WorkloadManager) handshakenAgent() (string, bool) {
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
+	for id := range w.pendingAgents {
+		if _, ok := w.handshakes[id]; ok {
+			return id, true
+		}
+	}
+
+	return "", false
+}
+
+// Removes the idle agent with the given id from the pool and stops it
+func (w *WorkloadManager) teardownAgent(id string) error {
+	w.poolMutex.Lock()
+	if agentClient, ok := w.pendingAgents[id]; ok {
+		_ = agentClient.Drain()
+		delete(w.pendingAgents, id)
+		delete(w.stopMutex, id)
+		delete(w.handshakes, id)
+	}
+	w.poolMutex.Unlock()
+
+	err := w.procMan.StopProcess(id)
+	if err != nil {
+		w.log.Warn("Failed to stop self-test agent", slog.String("workload_id", id), slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
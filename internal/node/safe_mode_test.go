@@ -0,0 +1,74 @@
+package nexnode
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+func TestSelfTestAwaitsAgentHandshake(t *testing.T) {
+	w := &WorkloadManager{
+		ctx:           context.Background(),
+		handshakes:    make(map[string]string),
+		pendingAgents: map[string]*agentapi.AgentClient{"vm1": nil},
+		poolMutex:     &sync.Mutex{},
+	}
+
+	_, err := w.awaitAgentHandshake(250 * time.Millisecond)
+	if err == nil {
+		t.Fatal("expected self-test to fail when no agent completes its handshake")
+	}
+
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		w.poolMutex.Lock()
+		w.handshakes["vm1"] = time.Now().UTC().Format(time.RFC3339)
+		w.poolMutex.Unlock()
+	}()
+
+	id, err := w.awaitAgentHandshake(5 * time.Second)
+	if err != nil || id != "vm1" {
+		t.Fatalf("expected self-test to find the agent which completed its handshake: %q %v", id, err)
+	}
+}
+
+func TestCapabilityReportFailsOnAnyCheck(t *testing.T) {
+	report := &capabilityReport{Passed: true}
+
+	report.check("first", func() error { return nil })
+	if !report.Passed {
+		t.Fatal("expected report to pass while every check passes")
+	}
+
+	report.check("second", func() error { return errors.New("boom") })
+	report.check("third", func() error { return nil })
+	if report.Passed || len(report.Checks) != 3 || report.Checks[1].Error != "boom" {
+		t.Fatalf("expected report to fail once a check fails: %+v", report)
+	}
+}
+
+func TestHandshakeFailuresEscalateOnlyWhenConsecutive(t *testing.T) {
+	w := &WorkloadManager{
+		config:     &models.NodeConfiguration{AgentHandshakeFailureThreshold: 2},
+		handshakes: make(map[string]string),
+		poolMutex:  &sync.Mutex{},
+	}
+
+	if _, escalate := w.recordHandshakeFailure(); escalate {
+		t.Fatal("expected a single failed handshake not to shut down the node")
+	}
+
+	w.agentHandshakeSucceeded("vm1")
+	if _, escalate := w.recordHandshakeFailure(); escalate {
+		t.Fatal("expected a successful handshake to reset the consecutive failures")
+	}
+
+	if failures, escalate := w.recordHandshakeFailure(); !escalate || failures != 2 {
+		t.Fatalf("expected consecutive failed handshakes to shut down the node, got %d failures", failures)
+	}
+}
@@ -0,0 +1,60 @@
+package nexnode
+
+import (
+	"testing"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+// Process manager handing each agent process an environment key
+type keyedProcessManager struct {
+	idleProcessManager
+	keys map[string][]byte
+}
+
+func (m keyedProcessManager) EnvironmentKey(id string) ([]byte, bool) {
+	key, ok := m.keys[id]
+	return key, ok
+}
+
+func TestDispatchedEnvironmentSealedWithAgentKey(t *testing.T) {
+	key, _ := agentapi.NewEnvironmentKey()
+	w := &WorkloadManager{
+		config:  &models.NodeConfiguration{RequireEnvironmentEncryption: true},
+		procMan: keyedProcessManager{keys: map[string][]byte{"vm1": key}},
+	}
+
+	request := &agentapi.DeployRequest{
+		Environment:  map[string]string{"SECRET": "hunter2", "ID": "${nex.workload_id}"},
+		Namespace:    agentapi.StringOrNil("default"),
+		WorkloadName: agentapi.StringOrNil("echo"),
+	}
+
+	dispatched, err := w.dispatchedRequest("vm1", request)
+	if err != nil {
+		t.Fatalf("expected the environment to be sealed, got %s", err)
+	}
+	if dispatched.Environment != nil || dispatched.SealedEnvironment == nil {
+		t.Fatal("expected only the sealed environment to be dispatched")
+	}
+
+	env, err := agentapi.OpenEnvironment(key, *dispatched.SealedEnvironment)
+	if err != nil || env["SECRET"] != "hunter2" || env["ID"] != "vm1" {
+		t.Fatalf("expected the agent's key to open the expanded environment: %v %v", env, err)
+	}
+
+	if request.Environment["SECRET"] != "hunter2" {
+		t.Fatal("expected the given request's environment to be left unmodified")
+	}
+
+	if _, err := w.dispatchedRequest("vm2", request); err == nil {
+		t.Fatal("expected dispatching to an agent without a key to fail when encryption is required")
+	}
+
+	w.config.RequireEnvironmentEncryption = false
+	dispatched, err = w.dispatchedRequest("vm2", request)
+	if err != nil || dispatched.SealedEnvironment != nil || dispatched.Environment["SECRET"] != "hunter2" {
+		t.Fatalf("expected a plaintext environment when encryption is not required: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package nexnode
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go"
+	"github.com/google/uuid"
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+// Returns true if the given workload tags contain every key/value pair in the selector
+func matchesTagSelector(tags map[string]string, selector map[string]string) bool {
+	for k, v := range selector {
+		if tag, ok := tags[k]; !ok || tag != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Stops every running workload whose tags match all of the key/value pairs in the given selector,
+// regardless of namespace, returning the number of workloads stopped. A workload stopped event
+// carrying the selector is published for each. Failing to stop one workload does not prevent the
+// remaining matches from being stopped
+func (w *WorkloadManager) StopByTagSelector(selector map[string]string) (stopped int, err error) {
+	if len(selector) == 0 {
+		return 0, errors.New("tag selector must not be empty")
+	}
+
+	procs, err := w.procMan.ListProcesses()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, p := range procs {
+		if !matchesTagSelector(p.DeployRequest.Tags, selector) {
+			continue
+		}
+
+		_err := w.StopWorkload(p.ID, true)
+		if _err != nil {
+			w.log.Warn("Failed to stop workload matching tag selector",
+				slog.String("workload_id", p.ID),
+				slog.Any("selector", selector),
+				slog.Any("err", _err),
+			)
+			err = errors.Join(err, fmt.Errorf("failed to stop workload %s: %s", p.ID, _err))
+			continue
+		}
+
+		w.teardownKeyValueBuckets(p.DeployRequest)
+
+		stopped++
+		_ = w.publishWorkloadSelectorStopped(p.Namespace, p.Name, p.ID, selector)
+	}
+
+	w.log.Info("Stopped workloads matching tag selector", slog.Any("selector", selector), slog.Int("stopped", stopped))
+
+	return stopped, err
+}
+
+func (w *WorkloadManager) publishWorkloadSelectorStopped(namespace, name, workloadId string, selector map[string]string) error {
+	evt := controlapi.WorkloadSelectorStoppedEvent{
+		Name:       name,
+		WorkloadId: workloadId,
+		Selector:   selector,
+	}
+
+	cloudevent := cloudevents.NewEvent()
+	cloudevent.SetSource(w.publicKey)
+	cloudevent.SetID(uuid.NewString())
+	cloudevent.SetTime(time.Now().UTC())
+	cloudevent.SetType(controlapi.WorkloadSelectorStoppedEventType)
+	cloudevent.SetDataContentType(cloudevents.ApplicationJSON)
+	_ = cloudevent.SetData(evt)
+
+	return w.publishCloudEvent(namespace, cloudevent)
+}
@@ -21,17 +21,20 @@ const hostServiceObjectStore = "objectstore"
 type HostServices struct {
 	log            *slog.Logger
 	mgr            *WorkloadManager
-	ncHostServices *nats.Conn
+	ncHostServices hs.ConnectionSource
 	ncint          *nats.Conn
 
 	hsServer *hs.HostServicesServer
 	config   *models.HostServicesConfig
+
+	// The key/value host service, if enabled
+	kv *builtins.KeyValueService
 }
 
 func NewHostServices(
 	mgr *WorkloadManager,
 	ncint *nats.Conn,
-	ncHostServices *nats.Conn,
+	ncHostServices hs.ConnectionSource,
 	config *models.HostServicesConfig,
 	log *slog.Logger,
 ) *HostServices {
@@ -80,6 +83,7 @@ func (h *HostServices) init() error {
 			if err != nil {
 				return err
 			}
+			h.kv = kv
 		}
 	}
 	if messagingConfig, ok := h.config.Services[hostServiceMessaging]; ok {
@@ -0,0 +1,117 @@
+package nexnode
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/node/processmanager"
+)
+
+// Time given to a machine created for a workload to boot, on top of the agent handshake timeout
+const sizedAgentBootTimeout = 30 * time.Second
+
+// Returns the id of a pending agent on a machine created for the given deploy request, if it declares
+// resources which no idle agent in the pool satisfies and the process manager can create machines
+// sized for a workload. Returns an empty id if the workload is to be deployed to the pool
+func (w *WorkloadManager) sizedAgent(request *agentapi.DeployRequest) (string, error) {
+	if request.Resources == nil || request.TargetVM != nil {
+		return "", nil
+	}
+
+	creator, ok := w.procMan.(processmanager.SizedProcessCreator)
+	if !ok {
+		return "", nil
+	}
+
+	w.poolMutex.Lock()
+	fits := w.bestFitAgent(request) != nil
+	w.poolMutex.Unlock()
+	if fits {
+		return "", nil
+	}
+
+	id, err := creator.CreateSizedProcess(request.Resources)
+	if err != nil {
+		return "", err
+	}
+
+	// reserved before its agent client is started, so that no other deployment can claim it
+	w.poolMutex.Lock()
+	w.sizedAgents[id] = struct{}{}
+	w.poolMutex.Unlock()
+
+	// the agent's handshake timeout also allows for its machine booting, so that a slow boot is not
+	// mistaken for a failed handshake
+	handshakeTimeout := w.handshakeTimeout + sizedAgentBootTimeout
+	w.startAgentClient(id, handshakeTimeout)
+
+	err = w.awaitSizedAgentHandshake(id, handshakeTimeout)
+	if err != nil {
+		w.releaseSizedAgent(id)
+		return "", err
+	}
+
+	return id, nil
+}
+
+// Waits up to the given timeout for the agent with the given id to complete its handshake
+func (w *WorkloadManager) awaitSizedAgentHandshake(id string, timeout time.Duration) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	ticker := time.NewTicker(runloopSleepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return fmt.Errorf("agent %s failed to complete its handshake", id)
+		case <-deadline.C:
+			return fmt.Errorf("agent %s did not complete its handshake within %s", id, timeout)
+		case <-ticker.C:
+			w.poolMutex.Lock()
+			_, handshaken := w.handshakes[id]
+			_, pending := w.pendingAgents[id]
+			w.poolMutex.Unlock()
+
+			if handshaken {
+				return nil
+			}
+			if !pending {
+				return fmt.Errorf("agent %s failed to complete its handshake", id)
+			}
+		}
+	}
+}
+
+// Stops the machine created for a workload with the given id, unless the workload has since been
+// handed to its agent
+func (w *WorkloadManager) releaseSizedAgent(id string) {
+	if id == "" {
+		return
+	}
+
+	w.poolMutex.Lock()
+	_, reserved := w.sizedAgents[id]
+	if reserved {
+		if agentClient, ok := w.pendingAgents[id]; ok {
+			_ = agentClient.Drain()
+		}
+		delete(w.pendingAgents, id)
+		delete(w.sizedAgents, id)
+		delete(w.stopMutex, id)
+		delete(w.handshakes, id)
+	}
+	w.poolMutex.Unlock()
+
+	if !reserved {
+		return
+	}
+
+	err := w.procMan.StopProcess(id)
+	if err != nil {
+		w.log.Warn("Failed to stop agent process sized for workload", slog.String("workload_id", id), slog.Any("err", err))
+	}
+}
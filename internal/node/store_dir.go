@@ -0,0 +1,33 @@
+package nexnode
+
+import (
+	"fmt"
+	"os"
+)
+
+// Returns an error unless the given directory, created if need be, is writable and has at least
+// the given free space, in MiB
+func checkStoreDir(dir string, minFreeMib int) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	probe, err := os.CreateTemp(dir, ".nex-probe-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %s", dir, err)
+	}
+	_ = probe.Close()
+	_ = os.Remove(probe.Name())
+
+	free, err := freeDiskSpace(dir)
+	if err != nil {
+		return fmt.Errorf("failed to determine free space of %s: %s", dir, err)
+	}
+
+	if freeMib := free / 1024 / 1024; freeMib < uint64(minFreeMib) {
+		return fmt.Errorf("%s has %d MiB free, less than the required %d MiB", dir, freeMib, minFreeMib)
+	}
+
+	return nil
+}
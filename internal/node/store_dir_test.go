@@ -0,0 +1,31 @@
+package nexnode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckStoreDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "pnats")
+
+	err := checkStoreDir(dir, 1)
+	if err != nil {
+		t.Fatalf("expected a writable store dir to be accepted, got %s", err)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatalf("expected the store dir to be created, got %s", err)
+	}
+
+	err = checkStoreDir(dir, 1<<40)
+	if err == nil || !strings.Contains(err.Error(), "less than the required") {
+		t.Fatalf("expected a store dir without enough free space to be rejected, got %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "file")
+	_ = os.WriteFile(file, nil, 0600)
+	if err := checkStoreDir(filepath.Join(file, "pnats"), 1); err == nil {
+		t.Fatal("expected a store dir which cannot be created to be rejected")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin
+
+package nexnode
+
+import "syscall"
+
+// Returns the space, in bytes, available to unprivileged users on the filesystem of the given path
+func freeDiskSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	err := syscall.Statfs(path, &stat)
+	if err != nil {
+		return 0, err
+	}
+
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
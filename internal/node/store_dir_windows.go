@@ -0,0 +1,21 @@
+//go:build windows
+
+package nexnode
+
+import "golang.org/x/sys/windows"
+
+// Returns the space, in bytes, available to the node's user on the volume of the given path
+func freeDiskSpace(path string) (uint64, error) {
+	dir, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var available, total, free uint64
+	err = windows.GetDiskFreeSpaceEx(dir, &available, &total, &free)
+	if err != nil {
+		return 0, err
+	}
+
+	return available, nil
+}
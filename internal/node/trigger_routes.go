@@ -0,0 +1,196 @@
+package nexnode
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+// Relative weight assigned to a workload when it joins a trigger route; workloads sharing a
+// trigger subject receive an even split of triggers until weights are explicitly adjusted
+const defaultTriggerRouteWeight = 100
+
+// A trigger route owns the single subscription to a (namespaced) trigger subject and routes each
+// trigger to one of the workloads sharing that subject using weighted random selection
+type triggerRoute struct {
+	mutex     *sync.Mutex
+	namespace string
+	subject   string
+	sub       *nats.Subscription
+
+	targets  map[string]*triggerRouteTarget
+	fallback *string
+}
+
+type triggerRouteTarget struct {
+	agentClient *agentapi.AgentClient
+	request     *agentapi.DeployRequest
+	weight      int
+}
+
+func newTriggerRoute(namespace, subject string) *triggerRoute {
+	return &triggerRoute{
+		mutex:     &sync.Mutex{},
+		namespace: namespace,
+		subject:   subject,
+		targets:   make(map[string]*triggerRouteTarget),
+	}
+}
+
+func triggerRouteKey(namespace, subject string) string {
+	return fmt.Sprintf("%s:%s", namespace, subject)
+}
+
+// Returns the trigger subjects of the given deploy request which fall outside its allowed trigger subjects
+func deniedTriggerSubjects(request *controlapi.DeployRequest) []string {
+	return (&agentapi.DeployRequest{
+		AllowedTriggerSubjects: request.AllowedTriggerSubjects,
+		TriggerSubjects:        request.TriggerSubjects,
+	}).DeniedTriggerSubjects()
+}
+
+func (r *triggerRoute) addTarget(workloadID string, agentClient *agentapi.AgentClient, request *agentapi.DeployRequest) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.targets[workloadID] = &triggerRouteTarget{
+		agentClient: agentClient,
+		request:     request,
+		weight:      defaultTriggerRouteWeight,
+	}
+}
+
+// Removes the given workload from the route, returning true if no targets remain
+func (r *triggerRoute) removeTarget(workloadID string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.targets, workloadID)
+	if r.fallback != nil && *r.fallback == workloadID {
+		r.fallback = nil
+	}
+
+	return len(r.targets) == 0
+}
+
+// Returns true if the target's workload is ready to receive triggers
+func (t *triggerRouteTarget) ready() bool {
+	return t.agentClient == nil || t.agentClient.WorkloadReady()
+}
+
+// Returns true if the target's workload is ready to receive and permitted to be triggered on the given subject
+func (t *triggerRouteTarget) accepts(subject string) bool {
+	return t.ready() && (t.request == nil || t.request.PermitsTriggerSubject(subject))
+}
+
+// Picks the workload that should receive the next trigger on the given subject from those which
+// are ready and permitted to be triggered on it
+func (r *triggerRoute) pick(subject string) (string, *triggerRouteTarget) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	total := 0
+	for _, t := range r.targets {
+		if t.accepts(subject) {
+			total += t.weight
+		}
+	}
+
+	if total == 0 {
+		return "", nil
+	}
+
+	n := rand.Intn(total)
+	for id, t := range r.targets {
+		if !t.accepts(subject) {
+			continue
+		}
+
+		if n < t.weight {
+			return id, t
+		}
+		n -= t.weight
+	}
+
+	return "", nil
+}
+
+// Returns true if none of the workloads sharing this route is permitted to be triggered on the given subject
+func (r *triggerRoute) denies(subject string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, t := range r.targets {
+		if t.request == nil || t.request.PermitsTriggerSubject(subject) {
+			return false
+		}
+	}
+
+	return len(r.targets) > 0
+}
+
+// Returns the fallback target for a trigger on the given subject that failed on the given workload, if any
+func (r *triggerRoute) fallbackFor(workloadID, subject string) (string, *triggerRouteTarget) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.fallback == nil || *r.fallback == workloadID {
+		return "", nil
+	}
+
+	target, ok := r.targets[*r.fallback]
+	if !ok || !target.accepts(subject) {
+		return "", nil
+	}
+
+	return *r.fallback, target
+}
+
+// Applies the given weights (and optional fallback) to the workloads sharing this route. Workloads
+// sharing the route but omitted from the given weights receive no triggers
+func (r *triggerRoute) setWeights(weights map[string]int, fallback *string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var err error
+	for id := range weights {
+		if _, ok := r.targets[id]; !ok {
+			err = errors.Join(err, fmt.Errorf("workload %s is not routed on trigger subject %s", id, r.subject))
+		}
+	}
+
+	if fallback != nil {
+		if _, ok := r.targets[*fallback]; !ok {
+			err = errors.Join(err, fmt.Errorf("fallback workload %s is not routed on trigger subject %s", *fallback, r.subject))
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for id, t := range r.targets {
+		t.weight = weights[id]
+	}
+	r.fallback = fallback
+
+	return nil
+}
+
+// Returns a copy of the current weights and fallback of this route
+func (r *triggerRoute) snapshot() (map[string]int, *string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	weights := make(map[string]int)
+	for id, t := range r.targets {
+		weights[id] = t.weight
+	}
+
+	return weights, r.fallback
+}
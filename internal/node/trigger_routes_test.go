@@ -0,0 +1,177 @@
+package nexnode
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+func TestTriggerRouteWeightedSelection(t *testing.T) {
+	route := newTriggerRoute("default", "hello.world")
+	route.addTarget("stable", nil, nil)
+	route.addTarget("canary", nil, nil)
+
+	err := route.setWeights(map[string]int{"stable": 1}, nil)
+	if err != nil {
+		t.Fatalf("failed to set weights: %s", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		id, target := route.pick("hello.world")
+		if target == nil || id != "stable" {
+			t.Fatalf("expected all triggers to route to the stable workload, got %s", id)
+		}
+	}
+
+	err = route.setWeights(map[string]int{"stable": 1, "bogus": 1}, nil)
+	if err == nil {
+		t.Fatal("expected weights referencing an unrouted workload to be rejected")
+	}
+
+	fallback := "stable"
+	err = route.setWeights(map[string]int{"stable": 90, "canary": 10}, &fallback)
+	if err != nil {
+		t.Fatalf("failed to set weights: %s", err)
+	}
+
+	if id, target := route.fallbackFor("canary", "hello.world"); target == nil || id != "stable" {
+		t.Fatal("expected failed canary triggers to fall back to the stable workload")
+	}
+
+	if _, target := route.fallbackFor("stable", "hello.world"); target != nil {
+		t.Fatal("expected no fallback for the fallback workload itself")
+	}
+
+	if route.removeTarget("stable") {
+		t.Fatal("expected route to retain the canary workload")
+	}
+
+	if _, target := route.fallbackFor("canary", "hello.world"); target != nil {
+		t.Fatal("expected fallback to be cleared when its workload is removed")
+	}
+
+	if !route.removeTarget("canary") {
+		t.Fatal("expected route to be empty after removing all workloads")
+	}
+}
+
+func TestTriggerRouteAwaitsWorkloadReady(t *testing.T) {
+	svr, _ := startObjectStoreTestServer(t, t.TempDir())
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	noop := func(string) {}
+
+	agentClient := agentapi.NewAgentClient(nc, log, time.Minute, time.Second, time.Second, 0, false, noop, noop, nil, nil, nil, nil, nil)
+	err = agentClient.Start("vm1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = agentClient.Stop() }()
+
+	handshake := func(phase *string) {
+		id := "vm1"
+		message := "up"
+		raw, _ := json.Marshal(agentapi.HandshakeRequest{ID: &id, StartTime: time.Now().UTC(), Message: &message, Phase: phase})
+		_, err := nc.Request("agentint.vm1.handshake", raw, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	route := newTriggerRoute("default", "hello.world")
+	route.addTarget("vm1", agentClient, nil)
+
+	// agents predating lifecycle phases handshake without one, indicating only that they are up
+	handshake(nil)
+	if agentClient.Phase() != agentapi.AgentPhaseUp {
+		t.Fatalf("expected handshake without a phase to indicate the agent is up, got %s", agentClient.Phase())
+	}
+
+	deployed := agentapi.AgentPhaseWorkloadDeployed
+	handshake(&deployed)
+	if _, target := route.pick("hello.world"); target != nil {
+		t.Fatal("expected no triggers to be routed to a workload which is deployed but not ready")
+	}
+
+	ready := agentapi.AgentPhaseWorkloadReady
+	handshake(&ready)
+	if id, target := route.pick("hello.world"); target == nil || id != "vm1" {
+		t.Fatal("expected triggers to be routed to the ready workload")
+	}
+
+	// a phase received late does not regress the agent
+	handshake(&deployed)
+	if !agentClient.WorkloadReady() {
+		t.Fatal("expected late deployed phase not to regress the ready workload")
+	}
+}
+
+func TestTriggerRouteHonorsAllowedTriggerSubjects(t *testing.T) {
+	route := newTriggerRoute("default", "orders.*")
+	route.addTarget("eu", nil, &agentapi.DeployRequest{
+		TriggerSubjects:        []string{"orders.*"},
+		AllowedTriggerSubjects: []string{"orders.eu"},
+	})
+	route.addTarget("any", nil, &agentapi.DeployRequest{TriggerSubjects: []string{"orders.*"}})
+
+	fallback := "eu"
+	err := route.setWeights(map[string]int{"eu": 1, "any": 0}, &fallback)
+	if err != nil {
+		t.Fatalf("failed to set weights: %s", err)
+	}
+
+	if id, target := route.pick("orders.eu"); target == nil || id != "eu" {
+		t.Fatal("expected triggers on an allowed subject to be routed to the restricted workload")
+	}
+
+	if _, target := route.pick("orders.us"); target != nil {
+		t.Fatal("expected no triggers to be routed to a workload not allowed to be triggered on the subject")
+	}
+
+	if _, target := route.fallbackFor("any", "orders.us"); target != nil {
+		t.Fatal("expected no fallback to a workload not allowed to be triggered on the subject")
+	}
+
+	if route.denies("orders.us") {
+		t.Fatal("expected the unrestricted workload to be allowed to be triggered on any subject")
+	}
+
+	route.removeTarget("any")
+	if !route.denies("orders.us") {
+		t.Fatal("expected the subject to be denied once no workload is allowed to be triggered on it")
+	}
+}
+
+func TestDeniedTriggerSubjects(t *testing.T) {
+	request := &controlapi.DeployRequest{
+		TriggerSubjects:        []string{"orders.eu", "orders.*", "orders.eu.>", "payments.new"},
+		AllowedTriggerSubjects: []string{"orders.*", "orders.eu.*"},
+	}
+
+	denied := deniedTriggerSubjects(request)
+	if len(denied) != 2 || denied[0] != "orders.eu.>" || denied[1] != "payments.new" {
+		t.Fatalf("expected trigger subjects outside the allowed subjects to be denied, got %v", denied)
+	}
+
+	request.AllowedTriggerSubjects = []string{">"}
+	if denied := deniedTriggerSubjects(request); len(denied) != 0 {
+		t.Fatalf("expected a full wildcard to allow every trigger subject, got %v", denied)
+	}
+
+	request.AllowedTriggerSubjects = nil
+	if denied := deniedTriggerSubjects(request); len(denied) != 0 {
+		t.Fatalf("expected trigger subjects to be unrestricted without allowed subjects, got %v", denied)
+	}
+}
@@ -0,0 +1,102 @@
+package nexnode
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Executes a single trigger, whose caller requested a streamed response, on the given workload,
+// forwarding each chunk of the response to the trigger's reply subject as it arrives and ending
+// the stream once the agent has. A failure is returned, so that the trigger may be retried on a
+// fallback, only if nothing has been forwarded; otherwise the stream is ended with the failure
+func (w *WorkloadManager) streamTrigger(ctx context.Context, span trace.Span, workloadID string, target *triggerRouteTarget, tsub string, msg *nats.Msg) error {
+	request := target.request
+
+	stream, err := target.agentClient.RunTriggerStream(ctx, w.t.Tracer, msg.Subject, msg.Data, request.ResolveTriggerTimeout())
+	if err == nil {
+		defer func() { _ = stream.Close() }()
+	}
+
+	forwarded := 0
+	buf := make([]byte, w.nc.MaxPayload())
+	for err == nil {
+		var n int
+		n, err = stream.Read(buf)
+		if n > 0 {
+			rerr := msg.Respond(buf[:n])
+			if rerr != nil {
+				err = rerr
+				break
+			}
+			forwarded += n
+		}
+	}
+
+	if !errors.Is(err, io.EOF) {
+		span.SetStatus(codes.Error, "Streamed trigger failed")
+		span.RecordError(err)
+		w.log.Error("Failed to stream agent execution via internal trigger subject",
+			slog.Any("err", err),
+			slog.String("trigger_subject", tsub),
+			slog.String("workload_type", *request.WorkloadType),
+			slog.String("workload_id", workloadID),
+			slog.Int("forwarded_bytes", forwarded),
+		)
+		w.recordFailedTrigger(workloadID, request, tsub, err)
+
+		if forwarded == 0 {
+			return err
+		}
+
+		_ = msg.RespondMsg(&nats.Msg{Header: nats.Header{
+			controlapi.TriggerStreamEndHeader:   []string{"true"},
+			controlapi.TriggerStreamErrorHeader: []string{err.Error()},
+		}})
+		return nil
+	}
+
+	trailer := stream.Trailer()
+	if reason := trailer.Get(controlapi.TriggerErrorHeader); reason != "" {
+		span.SetStatus(codes.Error, "Trigger refused by agent")
+		w.log.Warn("Agent refused trigger",
+			slog.String("workload_id", workloadID),
+			slog.String("trigger_subject", tsub),
+			slog.String("subject", msg.Subject),
+			slog.String("reason", reason),
+		)
+
+		_ = msg.RespondMsg(&nats.Msg{Header: nats.Header{controlapi.TriggerErrorHeader: []string{reason}}})
+		return nil
+	}
+
+	span.SetStatus(codes.Ok, "Streamed trigger succeeded")
+	runtimeNs, err := strconv.ParseInt(trailer.Get(agentapi.NexRuntimeNs), 10, 64)
+	if err != nil {
+		w.log.Warn("failed to log function runtime", slog.Any("err", err))
+	}
+	if span.SpanContext().IsSampled() {
+		_ = w.publishFunctionExecSucceeded(workloadID, tsub, runtimeNs)
+	}
+	target.agentClient.RecordExecTime(runtimeNs)
+	w.recordTriggerRuntime(request, runtimeNs)
+
+	err = msg.RespondMsg(&nats.Msg{Header: nats.Header{controlapi.TriggerStreamEndHeader: []string{"true"}}})
+	if err != nil {
+		w.log.Error("Failed to end streamed response to trigger subject subscription request for deployed workload",
+			slog.String("workload_id", workloadID),
+			slog.String("trigger_subject", tsub),
+			slog.Any("err", err),
+		)
+	}
+
+	return nil
+}
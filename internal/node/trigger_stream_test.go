@@ -0,0 +1,91 @@
+package nexnode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"go.opentelemetry.io/otel"
+)
+
+func TestRunTriggerStreamReadsChunkedResponse(t *testing.T) {
+	svr, _ := startObjectStoreTestServer(t, t.TempDir())
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	// a response spanning several chunks of the max payload
+	response := bytes.Repeat([]byte("chunk"), int(nc.MaxPayload())/2)
+
+	// an agent which streams the response, or fails partway through when triggered on fail
+	sub, err := nc.Subscribe("agentint.vm1.trigger", func(m *nats.Msg) {
+		if !agentapi.StreamingTrigger(m) {
+			t.Error("expected the trigger to request a streamed response")
+			return
+		}
+
+		stream := agentapi.NewTriggerStreamWriter(nc, m.Reply)
+		_, _ = stream.Write(response[:1024])
+		_, _ = stream.Write(response[1024:])
+
+		if m.Header.Get(agentapi.NexTriggerSubject) == "fail" {
+			_ = stream.End(nil, errors.New("boom"))
+			return
+		}
+		_ = stream.End(nats.Header{agentapi.NexRuntimeNs: []string{"42"}}, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	noop := func(string) {}
+
+	agentClient := agentapi.NewAgentClient(nc, log, time.Minute, time.Second, time.Second, 0, false, noop, noop, nil, nil, nil, nil, nil)
+	err = agentClient.Start("vm1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = agentClient.Stop() }()
+
+	tracer := otel.Tracer("nex-test")
+
+	stream, err := agentClient.RunTriggerStream(context.Background(), tracer, "hello.world", []byte("payload"), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := io.ReadAll(stream)
+	_ = stream.Close()
+	if err != nil {
+		t.Fatalf("failed to read streamed response: %s", err)
+	}
+	if !bytes.Equal(out, response) {
+		t.Fatalf("expected the %d-byte response to be streamed in full, got %d bytes", len(response), len(out))
+	}
+	if stream.Trailer().Get(agentapi.NexRuntimeNs) != "42" {
+		t.Fatal("expected the header ending the stream to be exposed once the stream has ended")
+	}
+
+	stream, err = agentClient.RunTriggerStream(context.Background(), tracer, "fail", nil, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = stream.Close() }()
+
+	_, err = io.ReadAll(stream)
+	if !errors.Is(err, agentapi.ErrTriggerStreamFailed) {
+		t.Fatalf("expected a workload failing partway through the stream to fail the read, got %v", err)
+	}
+}
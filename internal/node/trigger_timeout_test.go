@@ -0,0 +1,76 @@
+package nexnode
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"go.opentelemetry.io/otel"
+)
+
+func TestRunTriggerDistinguishesTimeoutFromFailure(t *testing.T) {
+	svr, _ := startObjectStoreTestServer(t, t.TempDir())
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	timeouts := make(chan string, 3)
+
+	// an agent which fails triggers on fail, times out on slow and otherwise echoes the payload
+	sub, err := nc.Subscribe("agentint.vm1.trigger", func(m *nats.Msg) {
+		timeouts <- m.Header.Get(agentapi.NexTriggerTimeoutMs)
+
+		switch m.Header.Get(agentapi.NexTriggerSubject) {
+		case "fail":
+			_ = agentapi.FailTrigger(m, errors.New("boom"), false)
+		case "slow":
+		default:
+			_ = m.Respond(m.Data)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	noop := func(string) {}
+
+	agentClient := agentapi.NewAgentClient(nc, log, time.Minute, time.Second, time.Second, 0, false, noop, noop, nil, nil, nil, nil, nil)
+	err = agentClient.Start("vm1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = agentClient.Stop() }()
+
+	tracer := otel.Tracer("nex-test")
+
+	resp, err := agentClient.RunTrigger(context.Background(), tracer, "echo", []byte("hi"), false, 0)
+	if err != nil || string(resp.Data) != "hi" {
+		t.Fatalf("expected the trigger to succeed: %v", err)
+	}
+	if timeout := <-timeouts; timeout != "10000" {
+		t.Fatalf("expected the default trigger timeout to be carried to the agent, got %q", timeout)
+	}
+
+	_, err = agentClient.RunTrigger(context.Background(), tracer, "fail", nil, false, time.Second)
+	if !errors.Is(err, agentapi.ErrTriggerFailed) || errors.Is(err, agentapi.ErrTriggerTimedOut) {
+		t.Fatalf("expected a workload failure to be reported as such, got %v", err)
+	}
+	if timeout := <-timeouts; timeout != "1000" {
+		t.Fatalf("expected the given trigger timeout to be carried to the agent, got %q", timeout)
+	}
+
+	_, err = agentClient.RunTrigger(context.Background(), tracer, "slow", nil, false, 50*time.Millisecond)
+	if !errors.Is(err, agentapi.ErrTriggerTimedOut) {
+		t.Fatalf("expected a workload which does not respond in time to time out, got %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package nexnode
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/synadia-io/nex/internal/node/processmanager"
+)
+
+// Returns the id of the idle agent in the pool running in the VM with the given id or IP address, to
+// which a deployment or prewarm is pinned for debugging. The pool mutex must be held
+func (w *WorkloadManager) pinnedAgentID(vm string) (string, error) {
+	if !w.config.AllowVMPinning {
+		return "", errors.New("pinning workloads to VMs is not allowed on this node")
+	}
+
+	if _, ok := w.pendingAgents[vm]; ok {
+		return vm, nil
+	}
+
+	if resolver, ok := w.procMan.(processmanager.ProcessAddressResolver); ok {
+		for id := range w.pendingAgents {
+			if ip, ok := resolver.ProcessIP(id); ok && ip == vm {
+				return id, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("target VM %s is not an idle VM in the pool", vm)
+}
@@ -0,0 +1,79 @@
+package nexnode
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go"
+	"github.com/nats-io/nats.go"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+)
+
+func TestAgentClientRoutesWorkloadExitedEvents(t *testing.T) {
+	svr, _ := startObjectStoreTestServer(t, t.TempDir())
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	noop := func(string) {}
+	events := make(chan string, 2)
+	exited := make(chan agentapi.WorkloadStatusEvent, 1)
+
+	agentClient := agentapi.NewAgentClient(nc, log, time.Minute, time.Second, time.Second, 0, false, noop, noop,
+		func(_ string, evt cloudevents.Event) { events <- evt.Type() },
+		nil, nil, nil,
+		func(_ string, status agentapi.WorkloadStatusEvent) { exited <- status },
+	)
+	err = agentClient.Start("vm1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = agentClient.Stop() }()
+
+	for _, evt := range []cloudevents.Event{
+		agentapi.NewAgentEvent("vm1", agentapi.WorkloadStartedEventType, agentapi.WorkloadStatusEvent{WorkloadName: "echo"}),
+		agentapi.NewAgentEvent("vm1", agentapi.WorkloadStoppedEventType, agentapi.WorkloadStatusEvent{
+			WorkloadName:       "echo",
+			Code:               137,
+			Signal:             "SIGKILL",
+			RuntimeMillisecond: 1500,
+			StdoutBytes:        12,
+			StderrBytes:        3,
+		}),
+	} {
+		raw, _ := json.Marshal(evt)
+		err = nc.Publish("agentint.vm1.events."+evt.Type(), raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case status := <-exited:
+		if status.Code != 137 || status.Signal != "SIGKILL" || status.RuntimeMillisecond != 1500 || status.StdoutBytes != 12 || status.StderrBytes != 3 {
+			t.Fatalf("unexpected workload status: %+v", status)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the workload exited callback to be called")
+	}
+
+	// every event, including the stopped event, is still handed to the event callback
+	for _, expected := range []string{agentapi.WorkloadStartedEventType, agentapi.WorkloadStoppedEventType} {
+		if typ := <-events; typ != expected {
+			t.Fatalf("expected %s event, got %s", expected, typ)
+		}
+	}
+
+	select {
+	case status := <-exited:
+		t.Fatalf("expected only the stopped event to be routed to the exited callback, got %+v", status)
+	default:
+	}
+}
@@ -0,0 +1,100 @@
+package nexnode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+// Returns the key of the object in the internal workload logs bucket into which the stdout and
+// stderr of the given workload are captured
+func workloadLogsKey(namespace, workloadID string) string {
+	return fmt.Sprintf("%s/%s", namespace, workloadID)
+}
+
+// Opens the stdout and stderr captured for the given workload in the given namespace. The object
+// only exists once the agent has finished capturing them, i.e. once the workload has stopped
+func (w *WorkloadManager) CapturedLogs(namespace, workloadID string) (nats.ObjectResult, error) {
+	js, err := w.ncInternal.JetStream()
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, err := js.ObjectStore(WorkloadLogsBucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := bucket.Get(workloadLogsKey(namespace, workloadID))
+	if errors.Is(err, nats.ErrObjectNotFound) {
+		return nil, fmt.Errorf("no captured logs for workload %s", workloadID)
+	}
+
+	return result, err
+}
+
+func (api *ApiListener) handleCapturedLogs(m *nats.Msg) {
+	if m.Reply == "" {
+		return
+	}
+
+	namespace, err := extractNamespace(m.Subject)
+	if err != nil {
+		api.log.Error("Invalid subject for captured logs", slog.Any("err", err))
+		api.endCapturedLogs(m, errors.New("invalid subject for captured logs"))
+		return
+	}
+
+	var request controlapi.CapturedLogsRequest
+	err = json.Unmarshal(m.Data, &request)
+	if err != nil {
+		api.log.Error("Failed to deserialize captured logs request", slog.Any("err", err))
+		api.endCapturedLogs(m, fmt.Errorf("unable to deserialize captured logs request: %s", err))
+		return
+	}
+
+	result, err := api.mgr.CapturedLogs(namespace, request.WorkloadId)
+	if err != nil {
+		api.endCapturedLogs(m, err)
+		return
+	}
+	defer func() { _ = result.Close() }()
+
+	buf := make([]byte, api.node.nc.MaxPayload())
+	for {
+		n, err := result.Read(buf)
+		if n > 0 {
+			rerr := m.Respond(buf[:n])
+			if rerr != nil {
+				api.log.Error("Failed to respond with captured logs", slog.String("workload_id", request.WorkloadId), slog.Any("err", rerr))
+				return
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			api.log.Error("Failed to read captured logs", slog.String("workload_id", request.WorkloadId), slog.Any("err", err))
+			api.endCapturedLogs(m, fmt.Errorf("failed to read captured logs: %s", err))
+			return
+		}
+	}
+
+	api.endCapturedLogs(m, nil)
+}
+
+// Ends the stream of captured logs sent in response to the given request, with the given failure
+func (api *ApiListener) endCapturedLogs(m *nats.Msg, failure error) {
+	header := nats.Header{controlapi.CapturedLogsEndHeader: []string{"true"}}
+	if failure != nil {
+		header.Set(controlapi.CapturedLogsErrorHeader, failure.Error())
+	}
+
+	_ = m.RespondMsg(&nats.Msg{Header: header})
+}
@@ -0,0 +1,64 @@
+package nexnode
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+func TestCapturedLogsAreStreamedToClientInChunks(t *testing.T) {
+	svr, js := startObjectStoreTestServer(t, t.TempDir())
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	api := &ApiListener{
+		log:  log,
+		node: &Node{nc: nc},
+		mgr:  &WorkloadManager{log: log, ncInternal: nc},
+	}
+
+	sub, err := nc.Subscribe(controlapi.APIPrefix+".CAPTUREDLOGS.*.node1", api.handleCapturedLogs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+
+	bucket, err := js.CreateObjectStore(&nats.ObjectStoreConfig{Bucket: WorkloadLogsBucketName})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// larger than a single message, so that the logs are streamed in several chunks
+	captured := strings.Repeat("a line of captured output\n", int(nc.MaxPayload())/10)
+	_, err = bucket.PutString(workloadLogsKey("default", "vm1"), captured)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := controlapi.NewApiClientWithNamespace(nc, 5*time.Second, "default", log)
+
+	var out bytes.Buffer
+	err = client.CapturedLogs("node1", "vm1", &out)
+	if err != nil {
+		t.Fatalf("expected captured logs to be fetched, got %s", err)
+	}
+	if out.String() != captured {
+		t.Fatalf("expected %d bytes of captured logs, got %d", len(captured), out.Len())
+	}
+
+	err = controlapi.NewApiClientWithNamespace(nc, 5*time.Second, "other", log).CapturedLogs("node1", "vm1", &out)
+	if err == nil || !strings.Contains(err.Error(), "no captured logs") {
+		t.Fatalf("expected logs captured in another namespace not to be found, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package nexnode
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+)
+
+// Maximum time given to this node to deploy each imported workload, in addition to the dependency
+// timeout for workloads held until their dependencies are running
+const importTimeout = 30 * time.Second
+
+// Returns a manifest of every workload running on this node, each with a deploy request signed by
+// this node with which it can be redeployed on the requested target node
+func (api *ApiListener) exportWorkloads(request *controlapi.ExportRequest) (*controlapi.WorkloadManifest, error) {
+	ttl := api.node.config.ResolveSignedRequestMaxTTL()
+	if request.SignatureTTLMillisecond > 0 {
+		requested := time.Duration(request.SignatureTTLMillisecond) * time.Millisecond
+		if requested > ttl {
+			return nil, fmt.Errorf("signature ttl %s exceeds the maximum of %s", requested, ttl)
+		}
+		ttl = requested
+	}
+
+	client := controlapi.NewApiClientWithNamespace(api.node.nc, migrationTimeout, systemNamespace, api.log)
+	info, err := client.NodeInfo(request.TargetNode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query target node: %s", err)
+	}
+
+	procs, err := api.mgr.procMan.ListProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	manifest := &controlapi.WorkloadManifest{
+		NodeId:     api.PublicKey(),
+		TargetNode: request.TargetNode,
+		ExportedAt: now,
+		ExpiresAt:  now.Add(ttl),
+		Workloads:  make([]controlapi.ManifestWorkload, 0, len(procs)),
+	}
+
+	for _, proc := range procs {
+		deployRequest, err := api.portableRequest(proc.DeployRequest, request.TargetNode, info.PublicXKey)
+		if err != nil {
+			return nil, err
+		}
+
+		err = deployRequest.Sign(api.mgr.kp, ttl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign exported workload %s: %s", proc.ID, err)
+		}
+
+		manifest.Workloads = append(manifest.Workloads, controlapi.ManifestWorkload{
+			Id:        proc.ID,
+			Name:      proc.Name,
+			Namespace: proc.Namespace,
+			Request:   deployRequest,
+		})
+	}
+
+	slices.SortFunc(manifest.Workloads, func(a, b controlapi.ManifestWorkload) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Name, b.Name), cmp.Compare(a.Id, b.Id))
+	})
+
+	return manifest, nil
+}
+
+// Deploys each of the workloads in the given manifest to this node through its own control API, as
+// though each had been requested individually. Workloads are deployed concurrently, so that those
+// held until their dependencies are running do not block the deployment of their dependencies
+func (api *ApiListener) importWorkloads(manifest *controlapi.WorkloadManifest) (*controlapi.ImportResponse, error) {
+	if manifest.TargetNode != api.PublicKey() {
+		return nil, fmt.Errorf("manifest was exported for node %s", manifest.TargetNode)
+	}
+
+	timeout := importTimeout + api.node.config.ResolveDependencyTimeout()
+	results := make([]controlapi.ImportResult, len(manifest.Workloads))
+
+	var wg sync.WaitGroup
+	for i, workload := range manifest.Workloads {
+		results[i] = controlapi.ImportResult{
+			SourceId:  workload.Id,
+			Name:      workload.Name,
+			Namespace: workload.Namespace,
+		}
+
+		if workload.Request.TargetNode == nil || *workload.Request.TargetNode != api.PublicKey() {
+			results[i].Error = controlapi.NewDeployError(controlapi.DeployErrorInvalidRequest, controlapi.DeployReasonInvalidField, "workload was not exported for this node")
+			results[i].Error.Field = "target_node"
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *controlapi.ImportResult, request *controlapi.DeployRequest) {
+			defer wg.Done()
+
+			client := controlapi.NewApiClientWithNamespace(api.node.nc, timeout, result.Namespace, api.log)
+			runResponse, err := client.StartWorkload(request)
+			if err != nil {
+				result.Error = deployError(err, controlapi.DeployErrorInternal, controlapi.DeployReasonDeploymentFailed, "Failed to import workload")
+				return
+			}
+
+			if !runResponse.Started {
+				result.Error = controlapi.NewDeployError(controlapi.DeployErrorInternal, controlapi.DeployReasonDeploymentFailed, "Failed to import workload: node did not start workload")
+				return
+			}
+
+			result.Imported = true
+			result.WorkloadId = runResponse.ID
+		}(&results[i], workload.Request)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if !result.Imported {
+			api.log.Warn("Failed to import workload",
+				slog.String("namespace", result.Namespace),
+				slog.String("workload", result.Name),
+				slog.String("source_workload_id", result.SourceId),
+				slog.Any("err", result.Error),
+			)
+		}
+	}
+
+	return &controlapi.ImportResponse{
+		NodeId:  api.PublicKey(),
+		Results: results,
+	}, nil
+}
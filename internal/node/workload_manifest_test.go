@@ -0,0 +1,92 @@
+package nexnode
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	controlapi "github.com/synadia-io/nex/control-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+func TestImportWorkloadsReportsEachWorkload(t *testing.T) {
+	svr, _ := startObjectStoreTestServer(t, t.TempDir())
+
+	nc, err := nats.Connect("", nats.InProcessServer(svr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	config := models.DefaultNodeConfiguration()
+	api := &ApiListener{
+		node: &Node{config: &config, nc: nc, log: log, publicKey: "NTESTNODE"},
+		log:  log,
+	}
+
+	// stands in for this node's deploy endpoint, rejecting the workload named "broken"
+	_, err = nc.Subscribe(controlapi.APIPrefix+".DEPLOY.*.NTESTNODE", func(m *nats.Msg) {
+		var request controlapi.DeployRequest
+		_ = json.Unmarshal(m.Data, &request)
+
+		var env controlapi.Envelope
+		if *request.Description == "broken" {
+			deployErr := controlapi.NewDeployError(controlapi.DeployErrorArtifact, controlapi.DeployReasonArtifactFetchFailed, "no such artifact")
+			env = controlapi.NewEnvelope(controlapi.RunResponseType, controlapi.RunResponse{Error: deployErr}, &deployErr.Message)
+		} else {
+			env = controlapi.NewEnvelope(controlapi.RunResponseType, controlapi.RunResponse{Started: true, ID: "new" + *request.Description}, nil)
+		}
+
+		raw, _ := json.Marshal(env)
+		_ = m.Respond(raw)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workload := func(id string, targetNode string) controlapi.ManifestWorkload {
+		name := id
+		return controlapi.ManifestWorkload{
+			Id:        id,
+			Name:      name,
+			Namespace: "default",
+			Request:   &controlapi.DeployRequest{Description: &name, TargetNode: &targetNode},
+		}
+	}
+
+	_, err = api.importWorkloads(&controlapi.WorkloadManifest{TargetNode: "NOTHERNODE"})
+	if err == nil {
+		t.Fatal("expected manifest exported for another node to be rejected")
+	}
+
+	resp, err := api.importWorkloads(&controlapi.WorkloadManifest{
+		TargetNode: "NTESTNODE",
+		Workloads: []controlapi.ManifestWorkload{
+			workload("echo", "NTESTNODE"),
+			workload("broken", "NTESTNODE"),
+			workload("stray", "NOTHERNODE"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Results) != 3 || resp.Imported() != 1 {
+		t.Fatalf("expected 1 of 3 workloads to be imported: %+v", resp.Results)
+	}
+
+	if echo := resp.Results[0]; !echo.Imported || echo.SourceId != "echo" || echo.WorkloadId != "newecho" {
+		t.Fatalf("unexpected result for imported workload: %+v", echo)
+	}
+
+	if broken := resp.Results[1]; broken.Imported || broken.Error == nil || broken.Error.Code != controlapi.DeployErrorArtifact {
+		t.Fatalf("expected the deploy error of the rejected workload to be reported: %+v", broken)
+	}
+
+	if stray := resp.Results[2]; stray.Imported || stray.Error == nil || stray.Error.Field != "target_node" {
+		t.Fatalf("expected workload exported for another node to be rejected: %+v", stray)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -16,8 +18,10 @@ import (
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nkeys"
 	controlapi "github.com/synadia-io/nex/control-api"
+	hs "github.com/synadia-io/nex/host-services"
 	agentapi "github.com/synadia-io/nex/internal/agent-api"
 	"github.com/synadia-io/nex/internal/models"
+	"github.com/synadia-io/nex/internal/node/natsint"
 	"github.com/synadia-io/nex/internal/node/observability"
 	"github.com/synadia-io/nex/internal/node/processmanager"
 
@@ -31,6 +35,7 @@ const (
 	EventSubjectPrefix      = "$NEX.events"
 	LogSubjectPrefix        = "$NEX.logs"
 	WorkloadCacheBucketName = "NEXCACHE"
+	WorkloadLogsBucketName  = agentapi.WorkloadLogsBucket
 )
 
 // The workload manager provides the high level strategy for the Nex node's workload management. It is responsible
@@ -46,6 +51,7 @@ type WorkloadManager struct {
 	ncInternal *nats.Conn
 	cancel     context.CancelFunc
 	ctx        context.Context
+	events     *eventHistory
 	t          *observability.Telemetry
 
 	procMan processmanager.ProcessManager
@@ -57,17 +63,50 @@ type WorkloadManager struct {
 	// successfully performed a handshake. Handshake failures are immediately removed
 	pendingAgents map[string]*agentapi.AgentClient
 
+	// Pending agents on which an artifact has been staged by a prewarm request
+	prewarmed map[string]*prewarmedAgent
+
+	// Pending agents on machines created for the workload being deployed, which are never selected
+	// for any other workload
+	sizedAgents map[string]struct{}
+
 	handshakes       map[string]string
 	handshakeTimeout time.Duration // TODO: make configurable...
 
+	// Number of consecutive agents which have failed to complete their handshake
+	handshakeFailures atomic.Int32
+
 	hostServices *HostServices
 
 	poolMutex *sync.Mutex
 	stopMutex map[string]*sync.Mutex
 
+	// Number of warm pool slots given up by reaping idle agents, restored as demand returns;
+	// guarded by the pool mutex
+	reapedAgents int
+
+	// Number of warm pool slots added above the pool target while the pool was below its low
+	// watermark, and when the last was added; guarded by the pool mutex
+	burstSlots int
+	lastBurst  time.Time
+
+	// Number of workloads still running since the node entered lame duck mode, and whether all
+	// have stopped
+	lameDuckRemaining atomic.Int32
+	lameDuckDrained   atomic.Bool
+
+	// Bucket in which deployed workloads are recorded, and the workloads it held when the node
+	// started, which were running when the node last stopped
+	deployments   nats.KeyValue
+	lostWorkloads []controlapi.LostWorkload
+
 	// Subscriptions created on behalf of functions that cannot subscribe internallly
 	subz map[string][]*nats.Subscription
 
+	// Trigger routes keyed by namespace and trigger subject, shared by all workloads registering the subject
+	triggerRoutes map[string]*triggerRoute
+	routesMutex   *sync.Mutex
+
 	natsStoreDir string
 	publicKey    string
 }
@@ -78,10 +117,13 @@ func NewWorkloadManager(
 	cancel context.CancelFunc,
 	nodeKeypair nkeys.KeyPair,
 	publicKey string,
-	nc, ncint, ncHostServices *nats.Conn,
+	nc, ncint *nats.Conn,
+	ncHostServices hs.ConnectionSource,
+	natsintAuth *natsint.Authenticator,
 	config *models.NodeConfiguration,
 	log *slog.Logger,
 	telemetry *observability.Telemetry,
+	events *eventHistory,
 ) (*WorkloadManager, error) {
 	// Validate the node config
 	if !config.Validate() {
@@ -92,11 +134,12 @@ func NewWorkloadManager(
 		config:           config,
 		cancel:           cancel,
 		ctx:              ctx,
+		events:           events,
 		handshakes:       make(map[string]string),
 		handshakeTimeout: time.Duration(config.AgentHandshakeTimeoutMillisecond) * time.Millisecond,
 		kp:               nodeKeypair,
 		log:              log,
-		natsStoreDir:     defaultInternalNatsStoreDir,
+		natsStoreDir:     config.ResolveInternalNatsStoreDir(),
 		nc:               nc,
 		ncInternal:       ncint,
 		poolMutex:        &sync.Mutex{},
@@ -105,19 +148,31 @@ func NewWorkloadManager(
 
 		pendingAgents: make(map[string]*agentapi.AgentClient),
 		activeAgents:  make(map[string]*agentapi.AgentClient),
+		prewarmed:     make(map[string]*prewarmedAgent),
+		sizedAgents:   make(map[string]struct{}),
 
 		stopMutex: make(map[string]*sync.Mutex),
 		subz:      make(map[string][]*nats.Subscription),
+
+		triggerRoutes: make(map[string]*triggerRoute),
+		routesMutex:   &sync.Mutex{},
 	}
 
 	var err error
 
-	w.procMan, err = processmanager.NewProcessManager(w.log, w.config, w.t, w.ctx)
+	w.procMan, err = processmanager.NewProcessManager(w.log, w.config, w.t, natsintAuth, w.ctx)
 	if err != nil {
 		w.log.Error("Failed to initialize agent process manager", slog.Any("error", err))
 		return nil, err
 	}
 
+	w.restoreDeployments()
+
+	err = w.t.ObserveWarmPool(w.warmAgents)
+	if err != nil {
+		w.log.Warn("Failed to register warm pool metric", slog.Any("err", err))
+	}
+
 	w.hostServices = NewHostServices(w, ncint, ncHostServices, config.HostServicesConfiguration, w.log)
 	err = w.hostServices.init()
 	if err != nil {
@@ -132,6 +187,17 @@ func NewWorkloadManager(
 func (w *WorkloadManager) Start() {
 	w.log.Info("Workload manager starting")
 
+	go w.reapPrewarmedAgents()
+	go w.reapIdleAgents()
+	go w.trimPoolBurst()
+	go w.prepullArtifacts()
+	go w.monitorAgentHeartbeats()
+	go w.monitorWorkloadLiveness()
+
+	if len(w.lostWorkloads) > 0 {
+		_ = w.publishWorkloadsLost()
+	}
+
 	err := w.procMan.Start(w)
 	if err != nil {
 		w.log.Error("Agent process manager failed to start", slog.Any("error", err))
@@ -139,38 +205,59 @@ func (w *WorkloadManager) Start() {
 	}
 }
 
-func (m *WorkloadManager) CacheWorkload(request *controlapi.DeployRequest) (uint64, *string, error) {
-	bucket := request.Location.Host
-	key := strings.Trim(request.Location.Path, "/")
-
-	m.log.Info("Attempting object store download", slog.String("bucket", bucket), slog.String("key", key), slog.String("url", m.nc.Opts.Url))
+// Caches the workload artifact indicated by the given deploy request for retrieval by the agent,
+// returning its size and hash, along with the means by which the agent is to decrypt it if it is
+// stored encrypted at rest. Artifacts the agent retrieves itself are not cached; only the hash
+// claimed for them is returned
+func (m *WorkloadManager) CacheWorkload(namespace string, request *controlapi.DeployRequest) (uint64, *string, *agentapi.ArtifactDecryption, error) {
+	var workload []byte
+	var decryption *agentapi.ArtifactDecryption
+	var err error
 
-	opts := []nats.JSOpt{}
-	if request.JsDomain != nil {
-		opts = append(opts, nats.APIPrefix(*request.JsDomain))
+	if request.GitSource == nil && controlapi.ArtifactRetrievedByAgent(request.Location) {
+		// the agent retrieves the artifact itself, verifying it against the hash claimed for it
+		hash, _ := request.DecodedClaims.Data["hash"].(string)
+		m.log.Info("Workload artifact to be retrieved by agent", slog.String("name", request.DecodedClaims.Subject), slog.String("location", request.Location.String()))
+		return 0, &hash, nil, nil
 	}
 
-	js, err := m.nc.JetStream(opts...)
-	if err != nil {
-		return 0, nil, err
-	}
+	if request.GitSource != nil {
+		workload, err = m.resolveGitSource(request.DecodedClaims.Subject, request.GitSource)
+		if err != nil {
+			m.log.Error("Failed to resolve workload from git source", slog.Any("err", err), slog.String("repository", request.GitSource.Repository))
+			return 0, nil, nil, err
+		}
+	} else {
+		var info *nats.ObjectInfo
+		var cached bool
+		workload, info, cached, err = m.downloadWorkload(request)
+		if err != nil {
+			return 0, nil, nil, err
+		}
 
-	store, err := js.ObjectStore(bucket)
-	if err != nil {
-		m.log.Error("Failed to bind to source object store", slog.Any("err", err), slog.String("bucket", bucket))
-		return 0, nil, err
-	}
+		decryption, err = m.artifactDecryption(namespace, request, info)
+		if err != nil {
+			m.log.Error("Workload artifact may not be decrypted", slog.Any("err", err), slog.String("namespace", namespace), slog.String("workload", request.DecodedClaims.Subject))
+			return 0, nil, nil, err
+		}
 
-	_, err = store.GetInfo(key)
-	if err != nil {
-		m.log.Error("Failed to locate workload binary in source object store", slog.Any("err", err), slog.String("key", key), slog.String("bucket", bucket))
-		return 0, nil, err
+		m.recordArtifactCache(artifactCacheNode, namespace, request.DecodedClaims.Subject, *request.WorkloadType, cached)
 	}
 
-	workload, err := store.GetBytes(key)
-	if err != nil {
-		m.log.Error("Failed to download bytes from source object store", slog.Any("err", err), slog.String("key", key))
-		return 0, nil, err
+	workloadHash := sha256.New()
+	workloadHash.Write(workload)
+	workloadHashString := hex.EncodeToString(workloadHash.Sum(nil))
+
+	if m.artifactDevices() != nil {
+		// the artifact bypasses the internal cache and is attached to the agent on deploy
+		err = buildArtifactImage(workload, stagedArtifactImagePath(workloadHashString))
+		if err != nil {
+			m.log.Error("Failed to build workload artifact image", slog.Any("err", err))
+			return 0, nil, nil, err
+		}
+
+		m.log.Info("Successfully staged workload artifact image", slog.String("name", request.DecodedClaims.Subject), slog.Int("bytes", len(workload)))
+		return uint64(len(workload)), &workloadHashString, decryption, nil
 	}
 
 	jsInternal, err := m.ncInternal.JetStream()
@@ -179,7 +266,12 @@ func (m *WorkloadManager) CacheWorkload(request *controlapi.DeployRequest) (uint
 		panic(err)
 	}
 
-	cache, err := jsInternal.ObjectStore(agentapi.WorkloadCacheBucket)
+	cacheBucket := agentapi.WorkloadCacheBucket
+	if request.ArtifactBucket != nil {
+		cacheBucket = *request.ArtifactBucket
+	}
+
+	cache, err := jsInternal.ObjectStore(cacheBucket)
 	if err != nil {
 		m.log.Error("Failed to get object store reference for internal cache.", slog.Any("err", err))
 		panic(err)
@@ -191,29 +283,150 @@ func (m *WorkloadManager) CacheWorkload(request *controlapi.DeployRequest) (uint
 		panic(err)
 	}
 
-	workloadHash := sha256.New()
-	workloadHash.Write(workload)
-	workloadHashString := hex.EncodeToString(workloadHash.Sum(nil))
+	m.log.Info("Successfully stored workload in internal object store", slog.String("name", request.DecodedClaims.Subject), slog.String("bucket", cacheBucket), slog.Int64("bytes", int64(obj.Size)))
+	return obj.Size, &workloadHashString, decryption, nil
+}
 
-	m.log.Info("Successfully stored workload in internal object store", slog.String("name", request.DecodedClaims.Subject), slog.Int64("bytes", int64(obj.Size)))
-	return obj.Size, &workloadHashString, nil
+// Downloads the workload artifact from the object store indicated by the request location
+func (m *WorkloadManager) downloadWorkload(request *controlapi.DeployRequest) ([]byte, *nats.ObjectInfo, bool, error) {
+	return m.downloadArtifactWithInfo(request.Location, request.JsDomain)
+}
+
+// Downloads an artifact from the object store bucket and key indicated by the given location,
+// indicating whether the artifact was served from the internal cache
+func (m *WorkloadManager) downloadArtifact(location *url.URL, jsDomain *string) ([]byte, bool, error) {
+	artifact, _, cached, err := m.downloadArtifactWithInfo(location, jsDomain)
+	return artifact, cached, err
+}
+
+// Downloads an artifact as per downloadArtifact, also returning its object store info
+func (m *WorkloadManager) downloadArtifactWithInfo(location *url.URL, jsDomain *string) ([]byte, *nats.ObjectInfo, bool, error) {
+	bucket := location.Host
+	key := strings.Trim(location.Path, "/")
+
+	m.log.Info("Attempting object store download", slog.String("bucket", bucket), slog.String("key", key), slog.String("url", m.nc.Opts.Url))
+
+	opts := []nats.JSOpt{}
+	if jsDomain != nil {
+		opts = append(opts, nats.APIPrefix(*jsDomain))
+	}
+
+	js, err := m.nc.JetStream(opts...)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	store, err := js.ObjectStore(bucket)
+	if err != nil {
+		m.log.Error("Failed to bind to source object store", slog.Any("err", err), slog.String("bucket", bucket))
+		return nil, nil, false, err
+	}
+
+	info, err := store.GetInfo(key)
+	if err != nil {
+		m.log.Error("Failed to locate workload binary in source object store", slog.Any("err", err), slog.String("key", key), slog.String("bucket", bucket))
+		return nil, nil, false, err
+	}
+
+	if cached := m.cachedArtifact(info); cached != nil {
+		m.log.Debug("Using artifact staged in internal cache", slog.String("bucket", bucket), slog.String("key", key))
+		return cached, info, true, nil
+	}
+
+	workload, err := store.GetBytes(key)
+	if err != nil {
+		m.log.Error("Failed to download bytes from source object store", slog.Any("err", err), slog.String("key", key))
+		return nil, nil, false, err
+	}
+
+	return workload, info, false, nil
 }
 
 // Deploy a workload as specified by the given deploy request to an available
-// agent in the configured pool
-func (w *WorkloadManager) DeployWorkload(request *agentapi.DeployRequest) (*string, error) {
+// agent in the configured pool. The trace context of the given context is propagated to the agent
+func (w *WorkloadManager) DeployWorkload(ctx context.Context, request *agentapi.DeployRequest) (*string, error) {
+	err := w.provisionKeyValueBuckets(request)
+	if err != nil {
+		return nil, controlapi.NewDeployError(controlapi.DeployErrorInternal, controlapi.DeployReasonKeyValueProvisioning, err.Error())
+	}
+
+	sizedID, err := w.sizedAgent(request)
+	if errors.Is(err, processmanager.ErrInsufficientHostResources) {
+		return nil, unschedulableError(controlapi.ConstraintHostResources, fmt.Sprintf("failed to deploy workload: %s", err))
+	} else if err != nil {
+		return nil, controlapi.NewDeployError(controlapi.DeployErrorAgent, controlapi.DeployReasonAgentPreparationFailed, fmt.Sprintf("failed to create agent process sized for workload: %s", err))
+	}
+	defer w.releaseSizedAgent(sizedID) // no-op once the workload has been handed to the agent
+
+	// a workload abandoned by a failed deployment is stopped once the pool mutex has been released,
+	// as stopping a workload takes the mutex
+	var stopAbandoned func()
+	defer func() {
+		if stopAbandoned != nil {
+			stopAbandoned()
+		}
+	}()
+
 	w.poolMutex.Lock()
 	defer w.poolMutex.Unlock()
 
-	agentClient, err := w.selectRandomAgent()
+	// admission checks capacity ahead of time, but concurrent deployments may have since filled the node
+	if w.atWorkloadCapacity() {
+		return nil, unschedulableError(controlapi.ConstraintMaxWorkloads, fmt.Sprintf("failed to deploy workload: node at workload capacity (max %d)", w.config.MaxWorkloads))
+	}
+
+	w.restoreReapedAgent()
+
+	replaced, err := w.duplicateWorkloads(*request.Namespace, *request.WorkloadName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to deploy workload: %s", err)
+		return nil, deployError(err, controlapi.DeployErrorInternal, controlapi.DeployReasonDeploymentFailed, "failed to deploy workload")
 	}
 
+	agentClient, ok := w.pendingAgents[sizedID]
+	if !ok {
+		agentClient, err = w.selectAgent(request)
+		if err != nil {
+			constraint := controlapi.ConstraintAgentPool
+			if request.TargetVM != nil {
+				constraint = controlapi.ConstraintVMPinning
+			}
+			return nil, unschedulableError(constraint, fmt.Sprintf("failed to deploy workload: %s", err))
+		}
+	}
+	delete(w.sizedAgents, sizedID)
+
 	workloadID := agentClient.ID()
-	err = w.procMan.PrepareWorkload(workloadID, request)
+	err = w.checkMemoryLimit(workloadID, request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare agent process for workload deployment: %s", err)
+		return nil, invalidRequestError(err, "failed to deploy workload")
+	}
+
+	if request.CaptureLogs {
+		request.LogCaptureKey = agentapi.StringOrNil(workloadLogsKey(*request.Namespace, workloadID))
+		request.LogCaptureMaxBytes = w.config.ResolveLogCaptureMaxBytes()
+	}
+
+	delete(w.prewarmed, workloadID)
+	err = w.procMan.PrepareWorkload(workloadID, request)
+	if errors.Is(err, processmanager.ErrInsufficientHostResources) {
+		return nil, unschedulableError(controlapi.ConstraintHostResources, fmt.Sprintf("failed to deploy workload: %s", err))
+	} else if errors.Is(err, processmanager.ErrNamespaceQuotaExceeded) {
+		return nil, unschedulableError(controlapi.ConstraintNamespaceQuota, fmt.Sprintf("failed to deploy workload: %s", err))
+	} else if err != nil {
+		return nil, controlapi.NewDeployError(controlapi.DeployErrorAgent, controlapi.DeployReasonAgentPreparationFailed, fmt.Sprintf("failed to prepare agent process for workload deployment: %s", err))
+	}
+
+	if attacher := w.artifactDevices(); attacher != nil && request.ArtifactLocation == nil {
+		imagePath := stagedArtifactImagePath(request.Hash)
+		defer os.Remove(imagePath) // no-op once the image has been attached
+
+		device, err := attacher.AttachArtifactDevice(workloadID, imagePath)
+		if err != nil {
+			stopAbandoned = func() { _ = w.StopWorkload(workloadID, false) }
+			return nil, controlapi.NewDeployError(controlapi.DeployErrorArtifact, controlapi.DeployReasonArtifactAttachFailed, fmt.Sprintf("failed to attach workload artifact to agent process: %s", err))
+		}
+
+		request.ArtifactDevice = &device
 	}
 
 	status := w.ncInternal.Status()
@@ -222,19 +435,30 @@ func (w *WorkloadManager) DeployWorkload(request *agentapi.DeployRequest) (*stri
 		slog.String("workload_id", workloadID),
 		slog.String("conn_status", status.String()))
 
-	deployResponse, err := agentClient.DeployWorkload(request)
+	dispatched, err := w.dispatchedRequest(workloadID, request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to submit request for workload deployment: %s", err)
+		stopAbandoned = func() { _ = w.StopWorkload(workloadID, false) }
+		return nil, controlapi.NewDeployError(controlapi.DeployErrorInternal, controlapi.DeployReasonEnvironmentSealing, fmt.Sprintf("failed to seal workload environment: %s", err))
+	}
+
+	deployResponse, err := agentClient.DeployWorkload(ctx, w.t.Tracer, dispatched)
+	if err != nil {
+		return nil, controlapi.NewDeployError(controlapi.DeployErrorAgent, controlapi.DeployReasonAgentUnreachable, fmt.Sprintf("failed to submit request for workload deployment: %s", err))
+	}
+
+	if deployResponse.ArtifactCached != nil {
+		w.recordArtifactCache(artifactCacheAgent, *request.Namespace, *request.WorkloadName, *request.WorkloadType, *deployResponse.ArtifactCached)
 	}
 
 	if deployResponse.Accepted {
 		// move the client from active to pending
 		w.activeAgents[workloadID] = agentClient
 		delete(w.pendingAgents, workloadID)
+		w.growPoolForBurst()
 
 		if request.SupportsTriggerSubjects() {
 			for _, tsub := range request.TriggerSubjects {
-				sub, err := w.nc.Subscribe(tsub, w.generateTriggerHandler(workloadID, tsub, request))
+				err := w.addTriggerRoute(workloadID, agentClient, tsub, request)
 				if err != nil {
 					w.log.Error("Failed to create trigger subject subscription for deployed workload",
 						slog.String("workload_id", workloadID),
@@ -242,8 +466,8 @@ func (w *WorkloadManager) DeployWorkload(request *agentapi.DeployRequest) (*stri
 						slog.String("workload_type", *request.WorkloadType),
 						slog.Any("err", err),
 					)
-					_ = w.StopWorkload(workloadID, true)
-					return nil, err
+					stopAbandoned = func() { _ = w.StopWorkload(workloadID, true) }
+					return nil, controlapi.NewDeployError(controlapi.DeployErrorInternal, controlapi.DeployReasonTriggerSubscription, err.Error())
 				}
 
 				w.log.Info("Created trigger subject subscription for deployed workload",
@@ -251,23 +475,69 @@ func (w *WorkloadManager) DeployWorkload(request *agentapi.DeployRequest) (*stri
 					slog.String("trigger_subject", tsub),
 					slog.String("workload_type", *request.WorkloadType),
 				)
-
-				w.subz[workloadID] = append(w.subz[workloadID], sub)
 			}
 		}
 	} else {
-		_ = w.StopWorkload(workloadID, false)
-		return nil, fmt.Errorf("workload rejected by agent: %s", *deployResponse.Message)
+		stopAbandoned = func() { _ = w.StopWorkload(workloadID, false) }
+		return nil, controlapi.NewDeployError(controlapi.DeployErrorAgent, controlapi.DeployReasonAgentRejected, fmt.Sprintf("workload rejected by agent: %s", *deployResponse.Message))
 	}
 
+	w.recordDeployment(workloadID, request)
+
 	w.t.WorkloadCounter.Add(w.ctx, 1, metric.WithAttributes(attribute.String("workload_type", *request.WorkloadType)))
 	w.t.WorkloadCounter.Add(w.ctx, 1, metric.WithAttributes(attribute.String("namespace", *request.Namespace)), metric.WithAttributes(attribute.String("workload_type", *request.WorkloadType)))
 	w.t.DeployedByteCounter.Add(w.ctx, request.TotalBytes)
 	w.t.DeployedByteCounter.Add(w.ctx, request.TotalBytes, metric.WithAttributes(attribute.String("namespace", *request.Namespace)))
 
+	if len(replaced) > 0 {
+		// stopped once the pool mutex is released; the replacement is already running
+		go w.replaceWorkloads(workloadID, replaced)
+	}
+
 	return &workloadID, nil
 }
 
+// Returns a copy of the given deploy request to be dispatched to the agent with the given id. Its
+// environment is merged over the node's default workload environment, the deploy request's values
+// winning on conflict, and any ${nex.<variable>} references are resolved. Both are applied only to
+// the request dispatched to the agent, so that they are not carried along on migration. When the
+// process manager hands the agent an environment key, the environment is sealed with it so that it
+// never crosses the internal NATS server in plaintext; failing that, dispatching fails if the node
+// requires environment encryption
+func (w *WorkloadManager) dispatchedRequest(workloadID string, request *agentapi.DeployRequest) (*agentapi.DeployRequest, error) {
+	env := make(map[string]string, len(w.config.DefaultWorkloadEnvironment)+len(request.Environment))
+	for key, value := range w.config.DefaultWorkloadEnvironment {
+		env[key] = value
+	}
+	for key, value := range request.Environment {
+		env[key] = value
+	}
+
+	dispatched := *request
+	dispatched.Environment = expandEnvironment(env, w.environmentVariables(workloadID, *request.Namespace, *request.WorkloadName))
+
+	var key []byte
+	if provider, ok := w.procMan.(processmanager.EnvironmentKeyProvider); ok {
+		key, _ = provider.EnvironmentKey(workloadID)
+	}
+
+	if key == nil {
+		if w.config.RequireEnvironmentEncryption {
+			return nil, fmt.Errorf("no environment key for agent %s", workloadID)
+		}
+		return &dispatched, nil
+	}
+
+	sealed, err := agentapi.SealEnvironment(key, dispatched.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	dispatched.Environment = nil
+	dispatched.SealedEnvironment = &sealed
+	return &dispatched, nil
+}
+
 // Locates a given workload by its workload ID and returns the deployment request associated with it
 // Note that this means "pending" workloads are not considered by lookups
 func (w *WorkloadManager) LookupWorkload(workloadID string) (*agentapi.DeployRequest, error) {
@@ -286,8 +556,14 @@ func (w *WorkloadManager) RunningWorkloads() ([]controlapi.MachineSummary, error
 	for i, p := range procs {
 		uptimeFriendly := "unknown"
 		runtimeFriendly := "unknown"
-		agentClient, ok := w.activeAgents[p.ID]
+		phase := ""
+		if !p.StartedAt.IsZero() {
+			uptimeFriendly = myUptime(p.Uptime)
+		}
+
+		agentClient, ok := w.activeAgent(p.ID)
 		if ok {
+			phase = agentClient.Phase()
 			uptimeFriendly = myUptime(agentClient.UptimeMillis())
 			if *p.DeployRequest.WorkloadType == "v8" || *p.DeployRequest.WorkloadType == "wasm" {
 				nanoTime := fmt.Sprintf("%dns", agentClient.ExecTimeNanos())
@@ -310,9 +586,10 @@ func (w *WorkloadManager) RunningWorkloads() ([]controlapi.MachineSummary, error
 
 		summaries[i] = controlapi.MachineSummary{
 			Id:        p.ID,
-			Healthy:   true,
+			Healthy:   !ok || !agentClient.Degraded(),
 			Uptime:    uptimeFriendly,
 			Namespace: p.Namespace,
+			Phase:     phase,
 			Workload: controlapi.WorkloadSummary{
 				Name:         p.Name,
 				Description:  *p.DeployRequest.Description,
@@ -321,6 +598,10 @@ func (w *WorkloadManager) RunningWorkloads() ([]controlapi.MachineSummary, error
 				Hash:         p.DeployRequest.Hash,
 			},
 		}
+
+		if reporter, ok := w.procMan.(processmanager.ProcessNetworkReporter); ok {
+			summaries[i].Network, _ = reporter.ProcessNetworkStats(p.ID)
+		}
 	}
 
 	return summaries, nil
@@ -332,12 +613,19 @@ func (w *WorkloadManager) Stop() error {
 	if atomic.AddUint32(&w.closing, 1) == 1 {
 		w.log.Info("Workload manager stopping")
 
+		w.poolMutex.Lock()
 		for id := range w.pendingAgents {
 			_ = w.pendingAgents[id].Stop()
 		}
 
+		active := make([]string, 0, len(w.activeAgents))
 		for id := range w.activeAgents {
-			err := w.StopWorkload(id, true)
+			active = append(active, id)
+		}
+		w.poolMutex.Unlock()
+
+		for _, id := range active {
+			err := w.stopWorkload(id, true, controlapi.WorkloadStopReasonNodeShutdown)
 			if err != nil {
 				w.log.Warn("Failed to stop agent", slog.String("workload_id", id), slog.String("error", err.Error()))
 			}
@@ -355,41 +643,34 @@ func (w *WorkloadManager) Stop() error {
 
 // Stop a workload, optionally attempting a graceful undeploy prior to termination
 func (w *WorkloadManager) StopWorkload(id string, undeploy bool) error {
+	return w.stopWorkload(id, undeploy, controlapi.WorkloadStopReasonRequested)
+}
+
+// Stop a workload, giving the reason for which it was stopped on the published stopped event
+func (w *WorkloadManager) stopWorkload(id string, undeploy bool, reason string) error {
 	deployRequest, err := w.procMan.Lookup(id)
 	if err != nil {
 		w.log.Warn("request to undeploy workload failed", slog.String("workload_id", id), slog.String("error", err.Error()))
 		return err
 	}
 
-	mutex := w.stopMutex[id]
+	mutex, err := w.workloadStopMutex(id)
+	if err != nil {
+		return err
+	}
 	mutex.Lock()
 	defer mutex.Unlock()
 
 	w.log.Debug("Attempting to stop workload", slog.String("workload_id", id), slog.Bool("undeploy", undeploy))
 
-	for _, sub := range w.subz[id] {
-		err := sub.Drain()
-		if err != nil {
-			w.log.Warn("failed to drain subscription to subject associated with workload",
-				slog.String("subject", sub.Subject),
-				slog.String("workload_id", id),
-				slog.String("err", err.Error()),
-			)
-		}
+	w.releaseSubscriptions(id, deployRequest)
 
-		w.log.Debug("drained subscription associated with workload",
-			slog.String("subject", sub.Subject),
-			slog.String("workload_id", id),
-		)
-	}
-
-	if deployRequest != nil && undeploy {
-		agentClient := w.activeAgents[id]
+	if agentClient, ok := w.activeAgent(id); ok && deployRequest != nil && undeploy {
 		defer func() {
 			_ = agentClient.Drain()
 		}()
 
-		err := agentClient.Undeploy()
+		err := agentClient.Undeploy(deployRequest.ResolveStopGracePeriod(w.config.StopGracePeriodMillisecond))
 		if err != nil {
 			w.log.Warn("request to undeploy workload via internal NATS connection failed", slog.String("workload_id", id), slog.String("error", err.Error()))
 		}
@@ -401,17 +682,66 @@ func (w *WorkloadManager) StopWorkload(id string, undeploy bool) error {
 		return err
 	}
 
-	delete(w.activeAgents, id)
-	delete(w.stopMutex, id)
+	w.forgetActiveAgent(id)
+	w.t.ForgetWorkloadMetrics(w.ctx, id)
 
-	_ = w.publishWorkloadStopped(id)
+	// workloads stopped by the node shutting down remain recorded, to be reported once it restarts
+	if reason != controlapi.WorkloadStopReasonNodeShutdown {
+		w.forgetDeployment(id)
+	}
+
+	_ = w.publishWorkloadStopped(id, reason)
 
 	return nil
 }
 
+// Drains the subscriptions associated with the workload with the given id and removes it from the
+// routes of its trigger subjects
+func (w *WorkloadManager) releaseSubscriptions(id string, deployRequest *agentapi.DeployRequest) {
+	for _, sub := range w.subz[id] {
+		err := sub.Drain()
+		if err != nil {
+			w.log.Warn("failed to drain subscription to subject associated with workload",
+				slog.String("subject", sub.Subject),
+				slog.String("workload_id", id),
+				slog.String("err", err.Error()),
+			)
+		}
+
+		w.log.Debug("drained subscription associated with workload",
+			slog.String("subject", sub.Subject),
+			slog.String("workload_id", id),
+		)
+	}
+	delete(w.subz, id)
+
+	if deployRequest != nil {
+		w.removeTriggerRoutes(id, deployRequest)
+	}
+}
+
+// Called by the agent process manager when it pauses or resumes refilling the warm pool
+// because the host's machine quota has been exhausted or capacity has been freed
+func (w *WorkloadManager) OnPoolRefillChanged(paused bool) {
+	_ = w.publishPoolRefillChanged(paused)
+}
+
+// Called by the agent process manager when a workload is prepared while the warm pool is empty,
+// so that its deployment waits for an agent to be created
+func (w *WorkloadManager) OnPoolStarved() {
+	w.log.Warn("Warm pool starved; deployment waiting for an agent to be created", slog.Int("pool_target", w.procMan.GetPoolTarget()))
+	_ = w.publishPoolStarved(false)
+}
+
 // Called by the agent process manager when an agent has been warmed and is ready
 // to receive workload deployment instructions
 func (w *WorkloadManager) OnProcessStarted(id string) {
+	w.startAgentClient(id, w.handshakeTimeout)
+}
+
+// Starts a client for the agent with the given id, which is pending until it completes its
+// handshake within the given timeout
+func (w *WorkloadManager) startAgentClient(id string, handshakeTimeout time.Duration) {
 	w.log.Debug("Process started", slog.String("workload_id", id))
 	w.poolMutex.Lock()
 	defer w.poolMutex.Unlock()
@@ -419,11 +749,18 @@ func (w *WorkloadManager) OnProcessStarted(id string) {
 	agentClient := agentapi.NewAgentClient(
 		w.ncInternal,
 		w.log,
-		w.handshakeTimeout,
+		handshakeTimeout,
+		w.config.ResolveAgentDeployTimeout(),
+		w.config.ResolveAgentUndeployTimeout(),
+		w.config.TriggerMaxPayloadBytes,
+		w.config.TriggerPayloadSpill,
 		w.agentHandshakeTimedOut,
 		w.agentHandshakeSucceeded,
 		w.agentEvent,
 		w.agentLog,
+		w.agentMetric,
+		w.agentSpans,
+		w.agentWorkloadExited,
 	)
 
 	err := agentClient.Start(id)
@@ -436,66 +773,334 @@ func (w *WorkloadManager) OnProcessStarted(id string) {
 	w.stopMutex[id] = &sync.Mutex{}
 }
 
+// Discards the agent with the given id, which failed to complete its handshake in time, stopping its
+// process so that it is replaced in the pool. The node only shuts down once the configured number of
+// consecutive agents have failed their handshakes, the host then being deemed unable to boot agents.
+// Agents on machines created for a single workload are not counted, as they boot machines of sizes
+// other than the pool's and their failures are reported to the deployment for which they were created
 func (w *WorkloadManager) agentHandshakeTimedOut(id string) {
+	w.t.HandshakeFailureCounter.Add(w.ctx, 1)
+
 	w.poolMutex.Lock()
-	defer w.poolMutex.Unlock()
+	_, sized := w.sizedAgents[id]
+	w.poolMutex.Unlock()
 
-	w.log.Error("Did not receive NATS handshake from agent within timeout.", slog.String("workload_id", id))
-	delete(w.pendingAgents, id)
+	if sized {
+		w.log.Error("Did not receive NATS handshake from agent sized for workload within timeout", slog.String("workload_id", id))
+		// stopped by the deployment for which it was created, which is failed once it is no longer pending
+		w.discardPendingAgent(id)
+		return
+	}
 
-	if len(w.handshakes) == 0 {
-		w.log.Error("First handshake failed, shutting down to avoid inconsistent behavior")
+	failures, escalate := w.recordHandshakeFailure()
+	if escalate {
+		w.log.Error("Consecutive agents failed to complete their handshakes, shutting down to avoid inconsistent behavior",
+			slog.String("workload_id", id),
+			slog.Int("failures", failures),
+		)
 		w.cancel()
+		return
+	}
+
+	w.log.Error("Did not receive NATS handshake from agent within timeout; replacing agent",
+		slog.String("workload_id", id),
+		slog.Int("consecutive_failures", failures),
+	)
+
+	w.discardPendingAgent(id)
+
+	err := w.procMan.StopProcess(id)
+	if err != nil {
+		w.log.Warn("Failed to stop agent which failed its handshake", slog.String("workload_id", id), slog.Any("err", err))
+	}
+}
+
+// Forgets the pending agent with the given id and drains its client
+func (w *WorkloadManager) discardPendingAgent(id string) {
+	w.poolMutex.Lock()
+	agentClient := w.pendingAgents[id]
+	delete(w.pendingAgents, id)
+	delete(w.prewarmed, id)
+	delete(w.stopMutex, id)
+	delete(w.handshakes, id)
+	w.poolMutex.Unlock()
+
+	if agentClient != nil {
+		_ = agentClient.Drain()
 	}
 }
 
+// Counts a failed agent handshake, returning the number of consecutive failures and whether they
+// have reached the configured threshold
+func (w *WorkloadManager) recordHandshakeFailure() (int, bool) {
+	failures := int(w.handshakeFailures.Add(1))
+	return failures, failures >= w.config.ResolveAgentHandshakeFailureThreshold()
+}
+
 func (w *WorkloadManager) agentHandshakeSucceeded(workloadID string) {
+	w.handshakeFailures.Store(0)
+
 	now := time.Now().UTC()
+
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
 	w.handshakes[workloadID] = now.Format(time.RFC3339)
 }
 
-// Generate a NATS subscriber function that is used to trigger function-type workloads
-func (w *WorkloadManager) generateTriggerHandler(workloadID string, tsub string, request *agentapi.DeployRequest) func(msg *nats.Msg) {
+// Returns true if the agent with the given id has completed its handshake
+func (w *WorkloadManager) handshakeCompleted(workloadID string) bool {
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
+	_, ok := w.handshakes[workloadID]
+	return ok
+}
+
+// Returns the agent running the workload with the given id, if any
+func (w *WorkloadManager) activeAgent(workloadID string) (*agentapi.AgentClient, bool) {
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
 	agentClient, ok := w.activeAgents[workloadID]
+	return agentClient, ok
+}
+
+// Returns the mutex serializing the stopping and restarting of the workload with the given id
+func (w *WorkloadManager) workloadStopMutex(workloadID string) (*sync.Mutex, error) {
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
+	mutex, ok := w.stopMutex[workloadID]
+	if !ok || mutex == nil {
+		return nil, fmt.Errorf("no agent running workload %s", workloadID)
+	}
+
+	return mutex, nil
+}
+
+// Forgets the agent which ran the workload with the given id, which has been stopped
+func (w *WorkloadManager) forgetActiveAgent(workloadID string) {
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
+	delete(w.activeAgents, workloadID)
+	delete(w.stopMutex, workloadID)
+	delete(w.handshakes, workloadID)
+}
+
+// Adds the given workload to the route for the given trigger subject, subscribing to the
+// subject if this is the first workload to register it
+func (w *WorkloadManager) addTriggerRoute(workloadID string, agentClient *agentapi.AgentClient, tsub string, request *agentapi.DeployRequest) error {
+	w.routesMutex.Lock()
+	defer w.routesMutex.Unlock()
+
+	key := triggerRouteKey(*request.Namespace, tsub)
+	route, ok := w.triggerRoutes[key]
 	if !ok {
-		w.log.Error("Attempted to generate trigger handler for non-existent agent client")
-		return nil
+		route = newTriggerRoute(*request.Namespace, tsub)
+
+		sub, err := w.nc.Subscribe(tsub, w.generateTriggerHandler(route))
+		if err != nil {
+			return err
+		}
+
+		route.sub = sub
+		w.triggerRoutes[key] = route
 	}
 
+	route.addTarget(workloadID, agentClient, request)
+	return nil
+}
+
+// Removes the given workload from each of its trigger routes, draining the subscriptions
+// of routes that no longer have any workloads
+func (w *WorkloadManager) removeTriggerRoutes(workloadID string, request *agentapi.DeployRequest) {
+	w.routesMutex.Lock()
+	defer w.routesMutex.Unlock()
+
+	for _, tsub := range request.TriggerSubjects {
+		key := triggerRouteKey(*request.Namespace, tsub)
+		route, ok := w.triggerRoutes[key]
+		if !ok {
+			continue
+		}
+
+		if route.removeTarget(workloadID) {
+			err := route.sub.Drain()
+			if err != nil {
+				w.log.Warn("failed to drain trigger subject subscription",
+					slog.String("subject", tsub),
+					slog.String("workload_id", workloadID),
+					slog.String("err", err.Error()),
+				)
+			}
+
+			delete(w.triggerRoutes, key)
+		}
+	}
+}
+
+// Adjusts the weighted routing of the given namespaced trigger subject among the workloads sharing it
+func (w *WorkloadManager) SetTrafficSplit(namespace string, request *controlapi.TrafficSplitRequest) (*controlapi.TrafficSplitResponse, error) {
+	w.routesMutex.Lock()
+	route, ok := w.triggerRoutes[triggerRouteKey(namespace, request.TriggerSubject)]
+	w.routesMutex.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("no workloads registered on trigger subject %s", request.TriggerSubject)
+	}
+
+	err := route.setWeights(request.Weights, request.FallbackWorkloadId)
+	if err != nil {
+		return nil, err
+	}
+
+	weights, fallback := route.snapshot()
+	w.log.Info("Updated trigger subject traffic split",
+		slog.String("namespace", namespace),
+		slog.String("trigger_subject", request.TriggerSubject),
+		slog.Any("weights", weights),
+	)
+
+	return &controlapi.TrafficSplitResponse{
+		TriggerSubject:     request.TriggerSubject,
+		Weights:            weights,
+		FallbackWorkloadId: fallback,
+	}, nil
+}
+
+// Generate a NATS subscriber function that is used to trigger function-type workloads sharing the given route
+func (w *WorkloadManager) generateTriggerHandler(route *triggerRoute) func(msg *nats.Msg) {
 	return func(msg *nats.Msg) {
-		ctx, parentSpan := w.t.Tracer.Start(
-			w.ctx,
-			"workload-trigger",
-			trace.WithNewRoot(),
-			trace.WithSpanKind(trace.SpanKindServer),
-			trace.WithAttributes(
-				attribute.String("name", *request.WorkloadName),
-				attribute.String("namespace", *request.Namespace),
-				attribute.String("trigger-subject", msg.Subject),
-			))
-
-		defer parentSpan.End()
-
-		resp, err := agentClient.RunTrigger(ctx, w.t.Tracer, msg.Subject, msg.Data)
-
-		parentSpan.AddEvent("Completed internal request")
+		workloadID, target := route.pick(msg.Subject)
+		if target == nil {
+			if route.denies(msg.Subject) {
+				w.log.Warn("No workload on trigger subject is allowed to be triggered on subject",
+					slog.String("trigger_subject", route.subject),
+					slog.String("subject", msg.Subject),
+				)
+				_ = agentapi.RefuseTrigger(msg, fmt.Sprintf("not authorized to trigger workloads on subject %s", msg.Subject))
+				return
+			}
+
+			w.log.Warn("No workload available to receive trigger", slog.String("trigger_subject", route.subject))
+			return
+		}
+
+		err := w.runTrigger(workloadID, target, route.subject, msg)
+		if err != nil && !errors.Is(err, agentapi.ErrTriggerPayloadTooLarge) {
+			fallbackID, fallback := route.fallbackFor(workloadID, msg.Subject)
+			if fallback != nil {
+				w.log.Info("Retrying failed trigger on fallback workload",
+					slog.String("trigger_subject", route.subject),
+					slog.String("workload_id", workloadID),
+					slog.String("fallback_workload_id", fallbackID),
+				)
+				err = w.runTrigger(fallbackID, fallback, route.subject, msg)
+			}
+		}
+
+		// a caller whose trigger timed out is told so rather than left to await its own timeout
+		if errors.Is(err, agentapi.ErrTriggerTimedOut) {
+			_ = msg.RespondMsg(&nats.Msg{Header: nats.Header{controlapi.TriggerTimedOutHeader: []string{"true"}}})
+		}
+	}
+}
+
+// Executes a single trigger on the given workload, responding to the trigger message on success
+func (w *WorkloadManager) runTrigger(workloadID string, target *triggerRouteTarget, tsub string, msg *nats.Msg) error {
+	agentClient := target.agentClient
+	request := target.request
+
+	spanCtx := w.ctx
+	if request.TraceSamplingRate != nil {
+		spanCtx = observability.WithTraceSamplingRate(w.ctx, *request.TraceSamplingRate)
+	}
+
+	ctx, parentSpan := w.t.Tracer.Start(
+		spanCtx,
+		"workload-trigger",
+		trace.WithNewRoot(),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("name", *request.WorkloadName),
+			attribute.String("namespace", *request.Namespace),
+			attribute.String("trigger-subject", msg.Subject),
+		))
+
+	defer parentSpan.End()
+
+	// per-trigger logs and events of successful triggers follow the sampling decision of the trace,
+	// so that a sampled trigger is fully observed; failures are always reported
+	sampled := parentSpan.SpanContext().IsSampled()
+
+	// tracks the triggers in flight for the workload; decremented on every path, including timeouts
+	activeAttrs := metric.WithAttributes(
+		attribute.String("namespace", *request.Namespace),
+		attribute.String("workload_name", *request.WorkloadName),
+	)
+	w.t.FunctionActiveTriggers.Add(w.ctx, 1, activeAttrs)
+	defer w.t.FunctionActiveTriggers.Add(w.ctx, -1, activeAttrs)
+
+	if agentapi.StreamingTrigger(msg) {
+		return w.streamTrigger(ctx, parentSpan, workloadID, target, tsub, msg)
+	}
+
+	partialResults := strings.EqualFold(msg.Header.Get(controlapi.TriggerPartialResultsHeader), "true")
+	resp, err := agentClient.RunTrigger(ctx, w.t.Tracer, msg.Subject, msg.Data, partialResults, request.ResolveTriggerTimeout())
+
+	parentSpan.AddEvent("Completed internal request")
+	if err != nil {
+		parentSpan.SetStatus(codes.Error, "Internal trigger request failed")
+		parentSpan.RecordError(err)
+		w.log.Error("Failed to request agent execution via internal trigger subject",
+			slog.Any("err", err),
+			slog.String("trigger_subject", tsub),
+			slog.String("workload_type", *request.WorkloadType),
+			slog.String("workload_id", workloadID),
+		)
+
+		w.recordFailedTrigger(workloadID, request, tsub, err)
+		return err
+	} else if resp != nil && resp.Header.Get(controlapi.TriggerErrorHeader) != "" {
+		parentSpan.SetStatus(codes.Error, "Trigger refused by agent")
+		w.log.Warn("Agent refused trigger",
+			slog.String("workload_id", workloadID),
+			slog.String("trigger_subject", tsub),
+			slog.String("subject", msg.Subject),
+			slog.String("reason", resp.Header.Get(controlapi.TriggerErrorHeader)),
+		)
+
+		// the agent refuses triggers on subjects its workload may not be triggered on, which no
+		// fallback would be expected to accept either
+		_ = msg.RespondMsg(&nats.Msg{Header: resp.Header})
+		return nil
+	} else if resp != nil && resp.Header.Get(controlapi.TriggerPartialResultsHeader) != "" {
+		parentSpan.SetStatus(codes.Error, "Trigger timed out with partial results")
+		w.log.Warn("Trigger timed out; responding with partial results",
+			slog.String("workload_id", workloadID),
+			slog.String("trigger_subject", tsub),
+			slog.String("workload_type", *request.WorkloadType),
+			slog.Int("payload_size", len(resp.Data)),
+		)
+
+		w.recordFailedTrigger(workloadID, request, tsub, errors.New("trigger timed out"))
+
+		// the timeout has been reported, so a failure to respond does not warrant retrying on a fallback
+		err = msg.RespondMsg(&nats.Msg{Data: resp.Data, Header: resp.Header})
 		if err != nil {
-			parentSpan.SetStatus(codes.Error, "Internal trigger request failed")
-			parentSpan.RecordError(err)
-			w.log.Error("Failed to request agent execution via internal trigger subject",
-				slog.Any("err", err),
+			w.log.Error("Failed to respond to trigger subject subscription request for deployed workload",
+				slog.String("workload_id", workloadID),
 				slog.String("trigger_subject", tsub),
 				slog.String("workload_type", *request.WorkloadType),
-				slog.String("workload_id", workloadID),
+				slog.Any("err", err),
 			)
-
-			w.t.FunctionFailedTriggers.Add(w.ctx, 1)
-			w.t.FunctionFailedTriggers.Add(w.ctx, 1, metric.WithAttributes(attribute.String("namespace", *request.Namespace)))
-			w.t.FunctionFailedTriggers.Add(w.ctx, 1, metric.WithAttributes(attribute.String("workload_name", *request.WorkloadName)))
-			_ = w.publishFunctionExecFailed(workloadID, *request.WorkloadName, tsub, err)
-		} else if resp != nil {
-			parentSpan.SetStatus(codes.Ok, "Trigger succeeded")
-			runtimeNs := resp.Header.Get(agentapi.NexRuntimeNs)
+		}
+	} else if resp != nil {
+		parentSpan.SetStatus(codes.Ok, "Trigger succeeded")
+		runtimeNs := resp.Header.Get(agentapi.NexRuntimeNs)
+		if sampled {
 			w.log.Debug("Received response from execution via trigger subject",
 				slog.String("workload_id", workloadID),
 				slog.String("trigger_subject", tsub),
@@ -503,49 +1108,121 @@ func (w *WorkloadManager) generateTriggerHandler(workloadID string, tsub string,
 				slog.String("function_run_time_nanosec", runtimeNs),
 				slog.Int("payload_size", len(resp.Data)),
 			)
+		}
 
-			runTimeNs64, err := strconv.ParseInt(runtimeNs, 10, 64)
-			if err != nil {
-				w.log.Warn("failed to log function runtime", slog.Any("err", err))
-			}
+		runTimeNs64, err := strconv.ParseInt(runtimeNs, 10, 64)
+		if err != nil {
+			w.log.Warn("failed to log function runtime", slog.Any("err", err))
+		}
+		if sampled {
 			_ = w.publishFunctionExecSucceeded(workloadID, tsub, runTimeNs64)
-			agentClient.RecordExecTime(runTimeNs64)
 			parentSpan.AddEvent("published success event")
+		}
+		agentClient.RecordExecTime(runTimeNs64)
 
-			w.t.FunctionTriggers.Add(w.ctx, 1)
-			w.t.FunctionTriggers.Add(w.ctx, 1, metric.WithAttributes(attribute.String("namespace", *request.Namespace)))
-			w.t.FunctionTriggers.Add(w.ctx, 1, metric.WithAttributes(attribute.String("workload_name", *request.WorkloadName)))
-			w.t.FunctionRunTimeNano.Add(w.ctx, runTimeNs64)
-			w.t.FunctionRunTimeNano.Add(w.ctx, runTimeNs64, metric.WithAttributes(attribute.String("namespace", *request.Namespace)))
-			w.t.FunctionRunTimeNano.Add(w.ctx, runTimeNs64, metric.WithAttributes(attribute.String("workload_name", *request.WorkloadName)))
+		w.recordTriggerRuntime(request, runTimeNs64)
 
-			err = msg.Respond(resp.Data)
+		err = msg.Respond(resp.Data)
 
-			if err != nil {
-				parentSpan.SetStatus(codes.Error, "Failed to respond to trigger subject")
-				parentSpan.RecordError(err)
-				w.log.Error("Failed to respond to trigger subject subscription request for deployed workload",
-					slog.String("workload_id", workloadID),
-					slog.String("trigger_subject", tsub),
-					slog.String("workload_type", *request.WorkloadType),
-					slog.Any("err", err),
-				)
-			}
+		if err != nil {
+			parentSpan.SetStatus(codes.Error, "Failed to respond to trigger subject")
+			parentSpan.RecordError(err)
+			w.log.Error("Failed to respond to trigger subject subscription request for deployed workload",
+				slog.String("workload_id", workloadID),
+				slog.String("trigger_subject", tsub),
+				slog.String("workload_type", *request.WorkloadType),
+				slog.Any("err", err),
+			)
 		}
 	}
+
+	return nil
 }
 
-// Picks a pending agent from the pool that will receive the next deployment
-func (w *WorkloadManager) selectRandomAgent() (*agentapi.AgentClient, error) {
+// Records a trigger which failed on the given workload, publishing a function execution failure
+func (w *WorkloadManager) recordFailedTrigger(workloadID string, request *agentapi.DeployRequest, tsub string, err error) {
+	w.t.FunctionFailedTriggers.Add(w.ctx, 1)
+	w.t.FunctionFailedTriggers.Add(w.ctx, 1, metric.WithAttributes(attribute.String("namespace", *request.Namespace)))
+	w.t.FunctionFailedTriggers.Add(w.ctx, 1, metric.WithAttributes(attribute.String("workload_name", *request.WorkloadName)))
+	_ = w.publishFunctionExecFailed(workloadID, *request.WorkloadName, tsub, err)
+}
+
+// Records a trigger which succeeded on the given workload along with the function's runtime
+func (w *WorkloadManager) recordTriggerRuntime(request *agentapi.DeployRequest, runtimeNs int64) {
+	w.t.FunctionTriggers.Add(w.ctx, 1)
+	w.t.FunctionTriggers.Add(w.ctx, 1, metric.WithAttributes(attribute.String("namespace", *request.Namespace)))
+	w.t.FunctionTriggers.Add(w.ctx, 1, metric.WithAttributes(attribute.String("workload_name", *request.WorkloadName)))
+	w.t.FunctionRunTimeNano.Add(w.ctx, runtimeNs)
+	w.t.FunctionRunTimeNano.Add(w.ctx, runtimeNs, metric.WithAttributes(attribute.String("namespace", *request.Namespace)))
+	w.t.FunctionRunTimeNano.Add(w.ctx, runtimeNs, metric.WithAttributes(attribute.String("workload_name", *request.WorkloadName)))
+}
+
+// Picks a pending agent from the pool that will receive the next deployment, preferring an agent
+// prewarmed with the requested artifact and otherwise avoiding agents prewarmed for other artifacts.
+// Among the remaining agents, the smallest machine satisfying the requested resources is preferred
+func (w *WorkloadManager) selectAgent(request *agentapi.DeployRequest) (*agentapi.AgentClient, error) {
+	if request.TargetVM != nil {
+		id, err := w.pinnedAgentID(*request.TargetVM)
+		if err != nil {
+			return nil, err
+		}
+
+		return w.pendingAgents[id], nil
+	}
+
 	if len(w.pendingAgents) == 0 {
 		return nil, errors.New("no available agent client in pool")
 	}
 
-	// there might be a slightly faster version of this, but this effectively
-	// gives us a random pick among the map elements
-	for _, v := range w.pendingAgents {
-		return v, nil
+	// iterating the map effectively gives us a random pick among its elements
+	var fallback *agentapi.AgentClient
+	for id, v := range w.pendingAgents {
+		if _, sized := w.sizedAgents[id]; sized {
+			continue
+		}
+
+		prewarmed, ok := w.prewarmed[id]
+		if ok && prewarmed.matches(request) {
+			return v, nil
+		}
+
+		if fallback == nil || (!ok && w.prewarmed[fallback.ID()] != nil) {
+			fallback = v
+		}
 	}
 
-	return nil, nil
+	if bestFit := w.bestFitAgent(request); bestFit != nil {
+		return bestFit, nil
+	}
+
+	if fallback == nil {
+		return nil, errors.New("no available agent client in pool")
+	}
+
+	return fallback, nil
+}
+
+// Returns true if the pool has a pending agent available to receive a deployment
+func (w *WorkloadManager) hasAvailableAgent() bool {
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
+	return len(w.pendingAgents) > 0
+}
+
+// Returns the number of workloads deployed on the node
+func (w *WorkloadManager) workloadCount() int {
+	w.poolMutex.Lock()
+	defer w.poolMutex.Unlock()
+
+	return len(w.activeAgents)
+}
+
+// Returns true if the node has reached its maximum number of workloads; the pool mutex must be held
+func (w *WorkloadManager) atWorkloadCapacity() bool {
+	return w.config.MaxWorkloads > 0 && len(w.activeAgents) >= w.config.MaxWorkloads
+}
+
+func (w *WorkloadManager) stopping() bool {
+	return (atomic.LoadUint32(&w.closing) > 0)
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	controlapi "github.com/synadia-io/nex/control-api"
 	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func (w *WorkloadManager) agentEvent(agentId string, evt cloudevents.Event) {
@@ -22,69 +23,103 @@ func (w *WorkloadManager) agentEvent(agentId string, evt cloudevents.Event) {
 		return
 	}
 
-	err := PublishCloudEvent(w.nc, *deployRequest.Namespace, evt, w.log)
+	err := w.publishCloudEvent(*deployRequest.Namespace, evt)
 	if err != nil {
 		w.log.Error("Failed to publish cloudevent", slog.Any("err", err))
-		return
 	}
+}
 
-	if evt.Type() == agentapi.WorkloadStoppedEventType {
-		_ = w.StopWorkload(agentId, false)
+// Called when the agent with the given id reports that its workload has exited, whereupon the
+// workload is stopped, or restarted if it is essential and exited with a non-zero exit code
+func (w *WorkloadManager) agentWorkloadExited(agentId string, status agentapi.WorkloadStatusEvent) {
+	deployRequest, _ := w.procMan.Lookup(agentId)
+	if deployRequest == nil {
+		return
+	}
 
-		evtData, err := evt.DataBytes()
-		if err != nil {
-			w.log.Error("Failed to read cloudevent data", slog.Any("err", err))
-			return
+	w.log.Info("Workload exited",
+		slog.String("vmid", agentId),
+		slog.String("workload", *deployRequest.WorkloadName),
+		slog.Int("exit_code", status.Code),
+		slog.String("signal", status.Signal),
+		slog.Int64("runtime_ms", status.RuntimeMillisecond),
+		slog.Int64("stdout_bytes", status.StdoutBytes),
+		slog.Int64("stderr_bytes", status.StderrBytes),
+	)
+
+	// recorded before stopping so that the exit code is reported in the workload stopped event
+	exitCode := status.Code
+	deployRequest.ExitCode = &exitCode
+
+	if deployRequest.IsEssential() && status.Code != 0 {
+		w.log.Debug("Essential workload stopped with non-zero exit code",
+			slog.String("vmid", agentId),
+			slog.String("namespace", *deployRequest.Namespace),
+			slog.String("workload", *deployRequest.WorkloadName),
+			slog.String("workload_type", *deployRequest.WorkloadType))
+
+		if deployRequest.RetryCount == nil {
+			retryCount := uint(0)
+			deployRequest.RetryCount = &retryCount
 		}
 
-		var workloadStatus *agentapi.WorkloadStatusEvent
-		err = json.Unmarshal(evtData, &workloadStatus)
-		if err != nil {
-			w.log.Error("Failed to unmarshal workload status from cloudevent data", slog.Any("err", err))
-			return
-		}
+		*deployRequest.RetryCount += 1
 
-		if deployRequest.IsEssential() && workloadStatus.Code != 0 {
-			w.log.Debug("Essential workload stopped with non-zero exit code",
-				slog.String("vmid", agentId),
-				slog.String("namespace", *deployRequest.Namespace),
-				slog.String("workload", *deployRequest.WorkloadName),
-				slog.String("workload_type", *deployRequest.WorkloadType))
-
-			if deployRequest.RetryCount == nil {
-				retryCount := uint(0)
-				deployRequest.RetryCount = &retryCount
-			}
-
-			*deployRequest.RetryCount += 1
-
-			retriedAt := time.Now().UTC()
-			deployRequest.RetriedAt = &retriedAt
-
-			req, _ := json.Marshal(&controlapi.DeployRequest{
-				Argv:            deployRequest.Argv,
-				Description:     deployRequest.Description,
-				WorkloadType:    deployRequest.WorkloadType,
-				Location:        deployRequest.Location,
-				WorkloadJwt:     deployRequest.WorkloadJwt,
-				Environment:     deployRequest.EncryptedEnvironment,
-				Essential:       deployRequest.Essential,
-				RetriedAt:       deployRequest.RetriedAt,
-				RetryCount:      deployRequest.RetryCount,
-				SenderPublicKey: deployRequest.SenderPublicKey,
-				TargetNode:      deployRequest.TargetNode,
-				TriggerSubjects: deployRequest.TriggerSubjects,
-				JsDomain:        deployRequest.JsDomain,
-			})
-
-			nodeID := w.publicKey
-			subject := fmt.Sprintf("%s.DEPLOY.%s.%s", controlapi.APIPrefix, *deployRequest.Namespace, nodeID)
-			_, err = w.nc.Request(subject, req, time.Millisecond*2500)
-			if err != nil {
-				w.log.Error("Failed to redeploy essential workload", slog.Any("err", err))
-			}
-		}
+		retriedAt := time.Now().UTC()
+		deployRequest.RetriedAt = &retriedAt
+
+		w.restartWorkloadOrRedeploy(agentId)
+		return
+	}
+
+	_ = w.StopWorkload(agentId, false)
+}
+
+// Resubmits the given workload to this node's deploy endpoint, so that it is deployed to a new agent
+// with the same request as it was originally deployed
+func (w *WorkloadManager) requestRedeploy(deployRequest *agentapi.DeployRequest) error {
+	request := &controlapi.DeployRequest{
+		Argv:                       deployRequest.Argv,
+		ArtifactBucket:             deployRequest.ArtifactBucket,
+		CaptureLogs:                deployRequest.CaptureLogs,
+		Dependencies:               deployRequest.Dependencies,
+		Description:                deployRequest.Description,
+		WorkloadType:               deployRequest.WorkloadType,
+		Location:                   deployRequest.Location,
+		MemoryLimitMib:             deployRequest.MemoryLimitMib,
+		Resources:                  deployRequest.Resources,
+		WorkloadJwt:                deployRequest.WorkloadJwt,
+		Environment:                deployRequest.EncryptedEnvironment,
+		GitSource:                  deployRequest.GitSource,
+		Essential:                  deployRequest.Essential,
+		RetriedAt:                  deployRequest.RetriedAt,
+		RetryCount:                 deployRequest.RetryCount,
+		SenderPublicKey:            deployRequest.SenderPublicKey,
+		StopGracePeriodMillisecond: deployRequest.StopGracePeriodMillisecond,
+		Tags:                       deployRequest.Tags,
+		TargetNode:                 deployRequest.TargetNode,
+		TraceSamplingRate:          deployRequest.TraceSamplingRate,
+		TriggerSubjects:            deployRequest.TriggerSubjects,
+		TriggerTimeoutMillisecond:  deployRequest.TriggerTimeoutMillisecond,
+		AllowedTriggerSubjects:     deployRequest.AllowedTriggerSubjects,
+		JsDomain:                   deployRequest.JsDomain,
+		KeyValueBuckets:            deployRequest.KeyValueBuckets,
+		Uid:                        deployRequest.Uid,
+		Gid:                        deployRequest.Gid,
+		WorkingDirectory:           deployRequest.WorkingDirectory,
+	}
+
+	err := w.signRequest(request)
+	if err != nil {
+		return err
 	}
+
+	req, _ := json.Marshal(request)
+
+	nodeID := w.publicKey
+	subject := fmt.Sprintf("%s.DEPLOY.%s.%s", controlapi.APIPrefix, *deployRequest.Namespace, nodeID)
+	_, err = w.nc.Request(subject, req, time.Millisecond*2500)
+	return err
 }
 
 func (w *WorkloadManager) agentLog(workloadId string, entry agentapi.LogEntry) {
@@ -96,9 +131,11 @@ func (w *WorkloadManager) agentLog(workloadId string, entry agentapi.LogEntry) {
 	}
 
 	bytes, err := json.Marshal(&emittedLog{
-		Text:  entry.Text,
-		Level: slog.Level(entry.Level),
-		ID:    workloadId,
+		Text:     entry.Text,
+		Level:    slog.Level(entry.Level),
+		ID:       workloadId,
+		LoggedAt: entry.Timestamp,
+		Dropped:  entry.Dropped,
 	})
 	if err != nil {
 		w.log.Error("Failed to marshal our own log entry", slog.Any("err", err))
@@ -109,6 +146,75 @@ func (w *WorkloadManager) agentLog(workloadId string, entry agentapi.LogEntry) {
 	_ = w.nc.Publish(subject, bytes)
 }
 
+// Attributes set by the node on workload-defined metric samples, which the workload cannot override
+var reservedMetricAttributes = map[string]struct{}{
+	"namespace":     {},
+	"workload_name": {},
+	"workload_id":   {},
+}
+
+func (w *WorkloadManager) agentMetric(workloadId string, sample agentapi.MetricSample) {
+	deployRequest, _ := w.procMan.Lookup(workloadId)
+	if deployRequest == nil {
+		// metric samples are only meaningful when associated with a deployed workload
+		return
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(sample.Attributes)+len(reservedMetricAttributes))
+	for k, v := range sample.Attributes {
+		if _, reserved := reservedMetricAttributes[k]; reserved {
+			// the workload may not impersonate another namespace or workload
+			continue
+		}
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	attrs = append(attrs,
+		attribute.String("namespace", *deployRequest.Namespace),
+		attribute.String("workload_name", *deployRequest.WorkloadName),
+		attribute.String("workload_id", deployRequest.TelemetryWorkloadID(workloadId)),
+	)
+
+	err := w.t.RecordWorkloadMetric(w.ctx, workloadId, sample.Name, sample.Kind, sample.Value, attrs...)
+	if err != nil {
+		w.log.Warn("Failed to record workload metric",
+			slog.String("workload_id", workloadId),
+			slog.String("metric", sample.Name),
+			slog.Any("err", err),
+		)
+	}
+}
+
+// Exports the spans recorded by the agent with the given id, attributing them to its workload
+func (w *WorkloadManager) agentSpans(workloadId string, spans []agentapi.AgentSpan) {
+	telemetryID := workloadId
+
+	deployRequest, _ := w.procMan.Lookup(workloadId)
+	if deployRequest != nil {
+		telemetryID = deployRequest.TelemetryWorkloadID(workloadId)
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("workload_id", telemetryID),
+	}
+	if deployRequest != nil {
+		attrs = append(attrs,
+			attribute.String("namespace", *deployRequest.Namespace),
+			attribute.String("workload_name", *deployRequest.WorkloadName),
+		)
+	}
+
+	res := w.t.AgentResource(attrs...)
+	for _, span := range spans {
+		snapshot, err := span.Snapshot(res)
+		if err != nil {
+			w.log.Warn("Received invalid span from agent", slog.String("workload_id", workloadId), slog.Any("err", err))
+			continue
+		}
+
+		w.t.ExportAgentSpans(snapshot)
+	}
+}
+
 func (w *WorkloadManager) publishFunctionExecFailed(workloadId string, workload string, tsub string, origErr error) error {
 	deployRequest, err := w.procMan.Lookup(workloadId)
 	if err != nil {
@@ -136,7 +242,7 @@ func (w *WorkloadManager) publishFunctionExecFailed(workloadId string, workload
 	cloudevent.SetDataContentType(cloudevents.ApplicationJSON)
 	_ = cloudevent.SetData(functionExecFailed)
 
-	err = PublishCloudEvent(w.nc, *deployRequest.Namespace, cloudevent, w.log)
+	err = w.publishCloudEvent(*deployRequest.Namespace, cloudevent)
 	if err != nil {
 		return err
 	}
@@ -184,7 +290,7 @@ func (w *WorkloadManager) publishFunctionExecSucceeded(workloadId string, tsub s
 	cloudevent.SetDataContentType(cloudevents.ApplicationJSON)
 	_ = cloudevent.SetData(functionExecPassed)
 
-	err = PublishCloudEvent(w.nc, *deployRequest.Namespace, cloudevent, w.log)
+	err = w.publishCloudEvent(*deployRequest.Namespace, cloudevent)
 	if err != nil {
 		return err
 	}
@@ -205,8 +311,9 @@ func (w *WorkloadManager) publishFunctionExecSucceeded(workloadId string, tsub s
 	return w.nc.Flush()
 }
 
-// publishWorkloadStopped writes a workload stopped event for the provided workload
-func (w *WorkloadManager) publishWorkloadStopped(workloadId string) error {
+// publishWorkloadStopped writes a workload stopped event for the provided workload, giving the
+// reason for which it was stopped
+func (w *WorkloadManager) publishWorkloadStopped(workloadId string, reason string) error {
 	deployRequest, err := w.procMan.Lookup(workloadId)
 	if err != nil {
 		w.log.Error("Failed to look up workload", slog.String("workload_id", workloadId), slog.Any("error", err))
@@ -219,14 +326,23 @@ func (w *WorkloadManager) publishWorkloadStopped(workloadId string) error {
 
 	workloadName := strings.TrimSpace(deployRequest.DecodedClaims.Subject)
 	if len(workloadName) > 0 {
+		exitCode := deployRequest.ExitCode
+		if exitCode == nil && deployRequest.SupportsTriggerSubjects() {
+			// function workloads never exit on their own, so stopping one is a clean exit
+			clean := 0
+			exitCode = &clean
+		}
+
 		workloadStopped := struct {
-			Name   string `json:"name"`
-			Reason string `json:"reason,omitempty"`
-			VmId   string `json:"vmid"`
+			Name     string `json:"name"`
+			Reason   string `json:"reason,omitempty"`
+			VmId     string `json:"vmid"`
+			ExitCode *int   `json:"exit_code,omitempty"`
 		}{
-			Name:   workloadName,
-			Reason: "Workload shutdown requested",
-			VmId:   workloadId,
+			Name:     workloadName,
+			Reason:   reason,
+			VmId:     workloadId,
+			ExitCode: exitCode,
 		}
 
 		cloudevent := cloudevents.NewEvent()
@@ -237,7 +353,7 @@ func (w *WorkloadManager) publishWorkloadStopped(workloadId string) error {
 		cloudevent.SetDataContentType(cloudevents.ApplicationJSON)
 		_ = cloudevent.SetData(workloadStopped)
 
-		err := PublishCloudEvent(w.nc, *deployRequest.Namespace, cloudevent, w.log)
+		err := w.publishCloudEvent(*deployRequest.Namespace, cloudevent)
 		if err != nil {
 			return err
 		}
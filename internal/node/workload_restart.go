@@ -0,0 +1,102 @@
+package nexnode
+
+import (
+	"fmt"
+	"log/slog"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+	agentapi "github.com/synadia-io/nex/internal/agent-api"
+	"github.com/synadia-io/nex/internal/node/processmanager"
+)
+
+// Restarts the workload with the given id on a fresh agent, using the deploy request with which it
+// was deployed. The workload's telemetry continues to be attributed to the id with which it was
+// first deployed
+func (w *WorkloadManager) RestartWorkload(id string) error {
+	deployRequest, err := w.procMan.Lookup(id)
+	if err != nil {
+		return err
+	}
+	if deployRequest == nil {
+		return fmt.Errorf("%w: %s", processmanager.ErrNoDeployRequest, id)
+	}
+
+	mutex, err := w.workloadStopMutex(id)
+	if err != nil {
+		return err
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	w.log.Debug("Attempting to restart workload", slog.String("workload_id", id))
+
+	w.releaseSubscriptions(id, deployRequest)
+
+	// published before the process is stopped, after which the workload can no longer be looked up
+	_ = w.publishWorkloadStopped(id, controlapi.WorkloadStopReasonRestarted)
+
+	err = w.procMan.Restart(id)
+	if err != nil {
+		w.log.Warn("failed to restart workload process", slog.String("workload_id", id), slog.String("error", err.Error()))
+		return err
+	}
+
+	if agentClient, ok := w.activeAgent(id); ok {
+		_ = agentClient.Drain()
+	}
+
+	w.forgetActiveAgent(id)
+	w.t.ForgetWorkloadMetrics(w.ctx, id)
+
+	return nil
+}
+
+// Restarts the workload with the given id on a fresh agent, falling back to stopping it and
+// resubmitting it to the node's deploy endpoint if it cannot be restarted
+func (w *WorkloadManager) restartWorkloadOrRedeploy(id string) {
+	deployRequest, _ := w.procMan.Lookup(id)
+	if deployRequest == nil {
+		// the workload was stopped in the meantime
+		return
+	}
+
+	err := w.RestartWorkload(id)
+	if err == nil {
+		return
+	}
+
+	w.log.Warn("Failed to restart workload; redeploying", slog.String("workload_id", id), slog.Any("err", err))
+
+	_ = w.StopWorkload(id, false)
+	err = w.requestRedeploy(deployRequest)
+	if err != nil {
+		w.log.Error("Failed to redeploy workload", slog.String("workload_id", id), slog.Any("err", err))
+	}
+}
+
+// Called by the agent process manager when the agent process running a workload has been stopped
+// in order to restart the workload, which is deployed to a fresh agent with the given request
+func (w *WorkloadManager) OnProcessRestarted(id string, request *agentapi.DeployRequest) {
+	request.ExitCode = nil
+
+	if w.artifactDevices() != nil && request.ArtifactLocation == nil {
+		// the artifact staged for the original deployment was removed once attached, so it must be
+		// fetched again by resubmitting the request
+		err := w.requestRedeploy(request)
+		if err != nil {
+			w.log.Error("Failed to redeploy restarted workload", slog.String("workload_id", id), slog.Any("err", err))
+		}
+		return
+	}
+
+	workloadID, err := w.DeployWorkload(w.ctx, request)
+	if err != nil {
+		w.log.Error("Failed to restart workload", slog.String("workload_id", id), slog.Any("err", err))
+		return
+	}
+
+	w.log.Info("Restarted workload on a fresh agent",
+		slog.String("workload_id", *workloadID),
+		slog.String("original_workload_id", request.TelemetryWorkloadID(id)),
+	)
+}
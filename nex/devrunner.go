@@ -108,7 +108,7 @@ func RunDevWorkload(ctx context.Context, logger *slog.Logger) error {
 		}
 	}
 
-	request, err := controlapi.NewDeployRequest(
+	opts := []controlapi.RequestOption{
 		controlapi.Argv(strings.Split(RunOpts.Argv, " ")),
 		controlapi.Location(workloadUrl),
 		controlapi.Environment(RunOpts.Env),
@@ -118,11 +118,22 @@ func RunDevWorkload(ctx context.Context, logger *slog.Logger) error {
 		controlapi.TargetNode(target.NodeId),
 		controlapi.TargetPublicXKey(targetPublicXkey),
 		controlapi.TriggerSubjects(RunOpts.TriggerSubjects),
+		controlapi.AllowedTriggerSubjects(RunOpts.AllowedTriggers),
 		controlapi.WorkloadName(workloadName),
 		controlapi.WorkloadType(workloadType),
 		controlapi.Checksum("abc12345TODOmakethisreal"),
 		controlapi.WorkloadDescription("Workload published in devmode"),
-	)
+	}
+
+	if RunOpts.TriggerTimeout > 0 {
+		opts = append(opts, controlapi.TriggerTimeout(RunOpts.TriggerTimeout))
+	}
+
+	if RunOpts.CaptureLogs {
+		opts = append(opts, controlapi.CaptureLogs())
+	}
+
+	request, err := controlapi.NewDeployRequest(opts...)
 	if err != nil {
 		return err
 	}
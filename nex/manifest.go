@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	controlapi "github.com/synadia-io/nex/control-api"
+	"github.com/synadia-io/nex/internal/models"
+)
+
+// Uses a control API client to export a manifest of the workloads running on a node, writing it
+// to the manifest file or standard output
+func ExportWorkloads(ctx context.Context, nodeId string) error {
+	log := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	nc, err := models.GenerateConnectionFromOpts(Opts, log)
+	if err != nil {
+		return err
+	}
+
+	nodeClient := controlapi.NewApiClient(nc, Opts.Timeout, log)
+	manifest, err := nodeClient.ExportWorkloads(nodeId, &controlapi.ExportRequest{
+		TargetNode:              ManifestOpts.TargetNode,
+		SignatureTTLMillisecond: int(ManifestOpts.SignatureTTL.Milliseconds()),
+	})
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if ManifestOpts.ManifestFile == "" {
+		fmt.Println(string(raw))
+		return nil
+	}
+
+	err = os.WriteFile(ManifestOpts.ManifestFile, raw, 0600)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d workloads to %s; import before %s\n", len(manifest.Workloads), ManifestOpts.ManifestFile, manifest.ExpiresAt.Local())
+	return nil
+}
+
+// Uses a control API client to deploy the workloads in the manifest file onto the node for which
+// it was exported, rendering the outcome for each
+func ImportWorkloads(ctx context.Context) error {
+	raw, err := os.ReadFile(ManifestOpts.ManifestFile)
+	if err != nil {
+		return err
+	}
+
+	var manifest controlapi.WorkloadManifest
+	err = json.Unmarshal(raw, &manifest)
+	if err != nil {
+		return fmt.Errorf("invalid manifest: %s", err)
+	}
+
+	log := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	nc, err := models.GenerateConnectionFromOpts(Opts, log)
+	if err != nil {
+		return err
+	}
+
+	nodeClient := controlapi.NewApiClient(nc, max(Opts.Timeout, ManifestOpts.ImportTimeout), log)
+	resp, err := nodeClient.ImportWorkloads(manifest.TargetNode, &manifest)
+	if err != nil {
+		return err
+	}
+
+	renderImportResults(resp)
+	return nil
+}
+
+func renderImportResults(resp *controlapi.ImportResponse) {
+	fmt.Printf("Imported %d of %d workloads onto %s\n", resp.Imported(), len(resp.Results), resp.NodeId)
+	if len(resp.Results) == 0 {
+		return
+	}
+
+	table := newTableWriter("Imported Workloads")
+	table.AddHeaders("Source ID", "Name", "Namespace", "Imported", "ID / Error")
+
+	for _, result := range resp.Results {
+		status := result.WorkloadId
+		if !result.Imported && result.Error != nil {
+			status = result.Error.Error()
+		}
+
+		table.AddRow(result.SourceId, result.Name, result.Namespace, result.Imported, status)
+	}
+
+	fmt.Println(table.Render())
+}
@@ -52,20 +52,32 @@ var (
 
 	nodesProbe = nodes.Command("probe", "Probe nodes for matching workloads")
 
+	nodesExport = nodes.Command("export", "Export a manifest of every workload running on a node, for import onto a target node")
+	nodesImport = nodes.Command("import", "Deploy the workloads in an exported manifest onto the node for which it was exported")
+	nodesLost   = nodes.Command("lost", "List the workloads a node was running when it last stopped")
+
+	nodesCapturedLogs = nodes.Command("capturedlogs", "Print the stdout and stderr captured for a workload deployed with --capture_logs")
+
 	// These two commands are GOOS/GOARCH dependent
 	nodeUp        *fisk.CmdClause
 	nodePreflight *fisk.CmdClause
 
-	node_info_id_arg = nodesInfo.Arg("id", "Public key of the node you're interested in").Required().String()
+	node_info_id_arg   = nodesInfo.Arg("id", "Public key of the node you're interested in").Required().String()
+	node_export_id_arg = nodesExport.Arg("id", "Public key of the node whose workloads are exported").Required().String()
+	node_lost_id_arg   = nodesLost.Arg("id", "Public key of the node whose lost workloads are listed").Required().String()
+
+	node_capturedlogs_id_arg       = nodesCapturedLogs.Arg("id", "Public key of the node on which the workload ran").Required().String()
+	node_capturedlogs_workload_arg = nodesCapturedLogs.Arg("workload_id", "Id of the workload whose logs were captured").Required().String()
 
-	Opts       = &models.Options{}
-	GuiOpts    = &models.UiOptions{}
-	RunOpts    = &models.RunOptions{Env: make(map[string]string)}
-	DevRunOpts = &models.DevRunOptions{}
-	StopOpts   = &models.StopOptions{}
-	WatchOpts  = &models.WatchOptions{}
-	NodeOpts   = &models.NodeOptions{}
-	RootfsOpts = &models.RootfsOptions{}
+	Opts         = &models.Options{}
+	GuiOpts      = &models.UiOptions{}
+	RunOpts      = &models.RunOptions{Env: make(map[string]string)}
+	DevRunOpts   = &models.DevRunOptions{}
+	StopOpts     = &models.StopOptions{}
+	WatchOpts    = &models.WatchOptions{}
+	NodeOpts     = &models.NodeOptions{}
+	ManifestOpts = &models.ManifestOptions{}
+	RootfsOpts   = &models.RootfsOptions{}
 )
 
 func init() {
@@ -106,12 +118,29 @@ func init() {
 	run.Flag("argv", "Arguments to pass to the workload, if applicable").StringVar(&RunOpts.Argv)
 	run.Flag("essential", "When true, workload is redeployed if it exits with a non-zero status").BoolVar(&RunOpts.Essential)
 	run.Flag("trigger_subject", "Trigger subjects to register for subsequent workload execution, if supported by the workload type").StringsVar(&RunOpts.TriggerSubjects)
+	run.Flag("allowed_trigger_subject", "Subjects, which may contain wildcards, on which the workload may be triggered. When set, trigger subjects must fall within them").StringsVar(&RunOpts.AllowedTriggers)
+	run.Flag("trigger_timeout", "Time within which each trigger of the workload must complete; defaults to 10s").DurationVar(&RunOpts.TriggerTimeout)
+	run.Flag("capture_logs", "Capture the workload's complete stdout and stderr on the node, to be fetched with 'nex node capturedlogs' once it stops").BoolVar(&RunOpts.CaptureLogs)
+	run.Flag("artifact_bucket", "Internal artifact bucket on the target node in which to cache the workload; must be allowed by the node configuration").StringVar(&RunOpts.ArtifactBucket)
+	run.Flag("uid", "Non-root uid as which to run the workload, if supported by the workload type").Default("-1").IntVar(&RunOpts.Uid)
+	run.Flag("gid", "Gid as which to run the workload; defaults to the uid").Default("-1").IntVar(&RunOpts.Gid)
+	run.Flag("workdir", "Absolute path of the working directory in which to run the workload, if supported by the workload type").StringVar(&RunOpts.WorkingDirectory)
+	run.Flag("vcpus", "Minimum vCPU count of the machine on which to run the workload").IntVar(&RunOpts.VcpuCount)
+	run.Flag("memory_mib", "Minimum memory size (MiB) of the machine on which to run the workload").IntVar(&RunOpts.MemSizeMib)
+	run.Flag("memory_limit_mib", "Memory limit (MiB) beyond which the workload is killed, below the memory of its machine, if supported by the workload type").IntVar(&RunOpts.MemoryLimitMib)
+	run.Flag("trace_sampling_rate", "Fraction (0.0-1.0) of the workload's triggers to trace; defaults to the node's sampling rate").Default("-1").Float64Var(&RunOpts.TraceSamplingRate)
+	run.Flag("signature_ttl", "Time after which the signed deploy request expires; 0 sends the request unsigned").Default("1m").DurationVar(&RunOpts.SignatureTTL)
+	run.Flag("target_vm", "Id or IP address of the idle VM on the target node to deploy to, for debugging (the node must allow VM pinning)").StringVar(&RunOpts.TargetVM)
+	run.Flag("depends_on", "Names of workloads in the same namespace which must be running on the target node before the workload is deployed").StringsVar(&RunOpts.Dependencies)
 
 	yeet.Arg("file", "File to run").Required().ExistingFileVar(&DevRunOpts.Filename)
 	yeet.Arg("env", "Environment variables to pass to workload").StringMapVar(&RunOpts.Env)
 	yeet.Flag("argv", "Arguments to pass to the workload, if applicable").StringVar(&RunOpts.Argv)
 	yeet.Flag("essential", "When true, workload is redeployed if it exits with a non-zero status").BoolVar(&RunOpts.Essential)
 	yeet.Flag("trigger_subject", "Trigger subjects to register for subsequent workload execution, if supported by the workload type").StringsVar(&RunOpts.TriggerSubjects)
+	yeet.Flag("allowed_trigger_subject", "Subjects, which may contain wildcards, on which the workload may be triggered. When set, trigger subjects must fall within them").StringsVar(&RunOpts.AllowedTriggers)
+	yeet.Flag("trigger_timeout", "Time within which each trigger of the workload must complete; defaults to 10s").DurationVar(&RunOpts.TriggerTimeout)
+	yeet.Flag("capture_logs", "Capture the workload's complete stdout and stderr on the node, to be fetched with 'nex node capturedlogs' once it stops").BoolVar(&RunOpts.CaptureLogs)
 	yeet.Flag("stop", "Indicates whether to stop pre-existing workloads during launch. Disable with caution").Default("true").BoolVar(&DevRunOpts.AutoStop)
 	yeet.Flag("bucketmaxbytes", "Overrides the default max bytes if the dev object store bucket is created").UintVar(&DevRunOpts.DevBucketMaxBytes)
 
@@ -135,6 +164,12 @@ func init() {
 
 	// one day when we refactor, let's get rid of all of these global structs. Such ugly
 	nodesProbe.Flag("workload", "Only query nodes currently running the given workload (id or name)").StringVar(&RunOpts.Name)
+
+	nodesExport.Flag("target", "Public key of the node onto which the manifest will be imported").Required().StringVar(&ManifestOpts.TargetNode)
+	nodesExport.Flag("output", "File to which the manifest is written; standard output when not given").Short('o').StringVar(&ManifestOpts.ManifestFile)
+	nodesExport.Flag("signature_ttl", "Time after which the exported deploy requests expire; the node's maximum when not given").DurationVar(&ManifestOpts.SignatureTTL)
+	nodesImport.Arg("manifest", "File containing the exported manifest").Required().ExistingFileVar(&ManifestOpts.ManifestFile)
+	nodesImport.Flag("import_timeout", "Time to wait for the node to deploy every workload in the manifest").Default("2m").DurationVar(&ManifestOpts.ImportTimeout)
 }
 
 func main() {
@@ -238,6 +273,26 @@ func main() {
 		if err != nil {
 			logger.Error("Failed to list workloads", slog.Any("err", err))
 		}
+	case nodesExport.FullCommand():
+		err := ExportWorkloads(ctx, *node_export_id_arg)
+		if err != nil {
+			logger.Error("Failed to export workloads", slog.Any("err", err))
+		}
+	case nodesImport.FullCommand():
+		err := ImportWorkloads(ctx)
+		if err != nil {
+			logger.Error("Failed to import workloads", slog.Any("err", err))
+		}
+	case nodesLost.FullCommand():
+		err := LostWorkloads(ctx, *node_lost_id_arg)
+		if err != nil {
+			logger.Error("Failed to list lost workloads", slog.Any("err", err))
+		}
+	case nodesCapturedLogs.FullCommand():
+		err := CapturedLogs(ctx, *node_capturedlogs_id_arg, *node_capturedlogs_workload_arg)
+		if err != nil {
+			logger.Error("Failed to fetch captured logs", slog.Any("err", err))
+		}
 	case nodesInfo.FullCommand():
 		err := NodeInfo(ctx, *node_info_id_arg)
 		if err != nil {
@@ -1,10 +1,12 @@
-//go:build linux || windows
+//go:build linux || windows || darwin
 
 package main
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"os"
 
 	"github.com/nats-io/nkeys"
 	nexnode "github.com/synadia-io/nex/internal/node"
@@ -18,6 +20,7 @@ func setConditionalCommands() {
 	nodeUp.Flag("otel_metrics_exporter", "OTel exporter for metrics").Default("file").EnumVar(&NodeOpts.OtelMetricsExporter, "file", "prometheus")
 	nodeUp.Flag("traces", "enable open telemetry traces").Default("false").UnNegatableBoolVar(&NodeOpts.OtelTraces)
 	nodeUp.Flag("otel_traces_exporter", "OTel exporter for traces").Default("file").EnumVar(&NodeOpts.OtelTracesExporter, "file", "grpc", "http")
+	nodeUp.Flag("safe-mode", "boots a single VM to prove the host is capable, reports the result and exits without accepting deploys").Default("false").UnNegatableBoolVar(&NodeOpts.SafeMode)
 
 	nodePreflight = nodes.Command("preflight", "Checks system for node requirements and installs missing")
 	nodePreflight.Flag("force", "installs missing dependencies without prompt").Default("false").BoolVar(&NodeOpts.ForceDepInstall)
@@ -28,6 +31,10 @@ func setConditionalCommands() {
 func RunNodeUp(ctx context.Context, logger *slog.Logger, keypair nkeys.KeyPair) error {
 	ctx, cancel := context.WithCancel(newContext(ctx))
 	err := nexnode.CmdUp(Opts, NodeOpts, ctx, cancel, keypair, logger)
+	if errors.Is(err, nexnode.ErrSelfTestFailed) {
+		logger.Error("Host failed the node self-test", slog.String("config_path", NodeOpts.ConfigFilepath))
+		os.Exit(1)
+	}
 	if err != nil {
 		return err
 	}
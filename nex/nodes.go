@@ -8,6 +8,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/nats-io/natscli/columns"
 	controlapi "github.com/synadia-io/nex/control-api"
@@ -57,12 +58,17 @@ func LameDuck(ctx context.Context, logger *slog.Logger) error {
 		return err
 	}
 	nodeClient := controlapi.NewApiClientWithNamespace(nc, Opts.Timeout, Opts.Namespace, log)
-	_, err = nodeClient.EnterLameDuck(nodeId)
+	res, err := nodeClient.EnterLameDuck(nodeId)
 	if err != nil {
 		fmt.Printf("Failed to issue lame duck command: %s\n", err)
 		return nil
 	}
 	fmt.Printf("Command to enter lame duck mode issued to %s\n", nodeId)
+	if res.Drained {
+		fmt.Println("Node has drained; no workloads remain")
+	} else {
+		fmt.Printf("Node is draining; %d workload(s) remain\n", res.Remaining)
+	}
 
 	return nil
 }
@@ -84,6 +90,34 @@ func NodeInfo(ctx context.Context, nodeid string) error {
 	return nil
 }
 
+// Uses a control API client to list the workloads a node was running when it last stopped
+func LostWorkloads(ctx context.Context, nodeid string) error {
+	log := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	nc, err := models.GenerateConnectionFromOpts(Opts, log)
+	if err != nil {
+		return err
+	}
+	nodeClient := controlapi.NewApiClientWithNamespace(nc, Opts.Timeout, Opts.Namespace, log)
+	lost, err := nodeClient.LostWorkloads(nodeid)
+	if err != nil {
+		return err
+	}
+	renderLostWorkloads(lost.Workloads)
+
+	return nil
+}
+
+// Uses a control API client to print the stdout and stderr captured for a workload
+func CapturedLogs(ctx context.Context, nodeid string, workloadid string) error {
+	log := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	nc, err := models.GenerateConnectionFromOpts(Opts, log)
+	if err != nil {
+		return err
+	}
+	nodeClient := controlapi.NewApiClientWithNamespace(nc, Opts.Timeout, Opts.Namespace, log)
+	return nodeClient.CapturedLogs(nodeid, workloadid, os.Stdout)
+}
+
 func render(cols *columns.Writer) {
 	_ = cols.Frender(os.Stdout)
 }
@@ -136,7 +170,7 @@ func renderNodeList(nodes []controlapi.PingResponse) {
 	}
 
 	table := newTableWriter("NATS Execution Nodes")
-	table.AddHeaders("ID", "Name", "Sandboxed", "Version", "Uptime", "Workloads")
+	table.AddHeaders("ID", "Name", "Sandboxed", "Healthy", "Version", "Uptime", "Workloads")
 
 	for _, node := range nodes {
 		nodeName, ok := node.Tags["node_name"]
@@ -149,7 +183,27 @@ func renderNodeList(nodes []controlapi.PingResponse) {
 			nodeUnsafe = "false"
 		}
 		nUnsafe, _ := strconv.ParseBool(nodeUnsafe)
-		table.AddRow(node.NodeId, nodeName, !nUnsafe, node.Version, node.Uptime, node.RunningMachines)
+		table.AddRow(node.NodeId, nodeName, !nUnsafe, node.Healthy, node.Version, node.Uptime, node.RunningMachines)
+	}
+
+	fmt.Println(table.Render())
+}
+
+func renderLostWorkloads(workloads []controlapi.LostWorkload) {
+	if len(workloads) == 0 {
+		fmt.Println("No lost workloads")
+		return
+	}
+
+	table := newTableWriter("Lost Workloads")
+	table.AddHeaders("ID", "Name", "Type", "Namespace", "Deployed")
+
+	for _, w := range workloads {
+		workloadType := ""
+		if w.Request != nil && w.Request.WorkloadType != nil {
+			workloadType = *w.Request.WorkloadType
+		}
+		table.AddRow(w.Id, w.Name, workloadType, w.Namespace, w.DeployedAt.Format(time.RFC3339))
 	}
 
 	fmt.Println(table.Render())
@@ -83,7 +83,7 @@ func RunWorkload(ctx context.Context, logger *slog.Logger) error {
 		return errors.New("cannot start a function-type workload without specifying at least one trigger subject")
 	}
 
-	request, err := controlapi.NewDeployRequest(
+	opts := []controlapi.RequestOption{
 		controlapi.Location(RunOpts.WorkloadUrl.String()),
 		controlapi.Environment(RunOpts.Env),
 		controlapi.Essential(RunOpts.Essential),
@@ -94,15 +94,60 @@ func RunWorkload(ctx context.Context, logger *slog.Logger) error {
 		controlapi.WorkloadName(RunOpts.Name),
 		controlapi.WorkloadType(RunOpts.WorkloadType),
 		controlapi.TriggerSubjects(RunOpts.TriggerSubjects),
+		controlapi.AllowedTriggerSubjects(RunOpts.AllowedTriggers),
 		controlapi.Checksum("abc12345TODOmakethisreal"),
 		controlapi.WorkloadDescription(RunOpts.Description),
-	)
+		controlapi.ArtifactBucket(RunOpts.ArtifactBucket),
+		controlapi.WorkingDirectory(RunOpts.WorkingDirectory),
+		controlapi.DependsOn(RunOpts.Dependencies...),
+		controlapi.Signed(RunOpts.SignatureTTL),
+	}
+
+	if RunOpts.TargetVM != "" {
+		opts = append(opts, controlapi.TargetVM(RunOpts.TargetVM))
+	}
+
+	if RunOpts.TriggerTimeout > 0 {
+		opts = append(opts, controlapi.TriggerTimeout(RunOpts.TriggerTimeout))
+	}
+
+	if RunOpts.CaptureLogs {
+		opts = append(opts, controlapi.CaptureLogs())
+	}
+
+	if RunOpts.Uid >= 0 {
+		gid := RunOpts.Gid
+		if gid < 0 {
+			gid = RunOpts.Uid
+		}
+		opts = append(opts, controlapi.RunAs(RunOpts.Uid, gid))
+	}
+
+	if RunOpts.VcpuCount > 0 || RunOpts.MemSizeMib > 0 {
+		opts = append(opts, controlapi.Resources(RunOpts.VcpuCount, RunOpts.MemSizeMib))
+	}
+
+	if RunOpts.MemoryLimitMib > 0 {
+		opts = append(opts, controlapi.MemoryLimit(RunOpts.MemoryLimitMib))
+	}
+
+	if RunOpts.TraceSamplingRate >= 0 {
+		opts = append(opts, controlapi.TraceSamplingRate(RunOpts.TraceSamplingRate))
+	}
+
+	request, err := controlapi.NewDeployRequest(opts...)
 	if err != nil {
 		return nil
 	}
 
 	resp, err := nodeClient.StartWorkload(request)
 	if err != nil {
+		var unschedulable *controlapi.UnschedulableError
+		if errors.As(err, &unschedulable) {
+			renderUnschedulable(unschedulable)
+			return err
+		}
+
 		fmt.Printf("⛔ Workload run request failed to submit: %s\n", err)
 		return err
 	}
@@ -114,11 +159,21 @@ func RunWorkload(ctx context.Context, logger *slog.Logger) error {
 func renderRunResponse(targetNode string, resp *controlapi.RunResponse) {
 	if resp.Started {
 		fmt.Printf("🚀 Workload '%s' accepted. You can now refer to this workload with ID: %s on node %s", resp.Name, resp.ID, targetNode)
+		if resp.LogObjectKey != nil {
+			fmt.Printf("\n📜 Capturing its logs as %s", *resp.LogObjectKey)
+		}
 	} else {
 		fmt.Println("⛔ Workload rejected")
 	}
 }
 
+func renderUnschedulable(unschedulable *controlapi.UnschedulableError) {
+	fmt.Printf("⛔ Workload unschedulable on node %s; %d constraint(s) not satisfied:\n", unschedulable.NodeId, len(unschedulable.Constraints))
+	for _, c := range unschedulable.Constraints {
+		fmt.Printf("   - %s: %s\n", c.Constraint, c.Reason)
+	}
+}
+
 func renderStopResponse(resp *controlapi.StopResponse) {
 	if resp.Stopped {
 		fmt.Printf("✅ Workload '%s' stopped.\n", resp.Name)
@@ -123,6 +123,15 @@ func handleEventEntry(log *slog.Logger, emittedEvent controlapi.EmittedEvent) {
 			attrs = append(attrs, slog.Any("err", err))
 		} else {
 			attrs = append(attrs, slog.String("message", evt.Message), slog.Int("code", evt.Code), slog.String("workload_name", evt.Name))
+			if evt.ExitCode != nil {
+				attrs = append(attrs, slog.Int("exit_code", *evt.ExitCode))
+			}
+			if evt.Signal != "" {
+				attrs = append(attrs, slog.String("signal", evt.Signal))
+			}
+			if evt.RuntimeMillisecond > 0 {
+				attrs = append(attrs, slog.Duration("runtime", time.Duration(evt.RuntimeMillisecond)*time.Millisecond))
+			}
 		}
 	case controlapi.NodeStartedEventType:
 		evt := &controlapi.NodeStartedEvent{}
@@ -130,6 +139,9 @@ func handleEventEntry(log *slog.Logger, emittedEvent controlapi.EmittedEvent) {
 			attrs = append(attrs, slog.Any("err", err))
 		} else {
 			attrs = append(attrs, slog.String("node_id", ellipsis.Centering(evt.Id, 25)), slog.String("version", evt.Version))
+			if evt.OS != "" {
+				attrs = append(attrs, slog.String("platform", evt.OS+"/"+evt.Arch), slog.Any("workload_types", evt.WorkloadTypes))
+			}
 		}
 	case controlapi.NodeStoppedEventType:
 		evt := &controlapi.NodeStoppedEvent{}